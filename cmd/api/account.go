@@ -10,8 +10,8 @@ import (
 	"time"
 
 	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/allocation"
 	"github.com/ebitezion/backend-framework/internal/data"
-	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
 	"github.com/ebitezion/backend-framework/internal/validator"
 	Validate "github.com/go-playground/validator/v10"
 )
@@ -170,9 +170,11 @@ func (app *application) Register(biodata data.AccountBioData) (accountNumber str
 		return "", err
 	}
 
-	// Generate the account number here (as you've done)
-	generator := ukaccountgen.New()
-	accountNumber = generator.GenerateUKAccountNumber()
+	// Allocate the account number from the registry
+	accountNumber, err = allocation.AllocateUK("internal account")
+	if err != nil {
+		return "", err
+	}
 	accountType := data.InternalAccount
 	currencyCode := data.Nigeria
 	currentBalance := 0.0
@@ -310,6 +312,7 @@ func (app *application) AccountCreate(w http.ResponseWriter, r *http.Request) {
 		req.Image,
 		req.AccountHolderIdentificationType,
 		req.Country,
+		req.CurrencyCode,
 	}
 
 	response, err := accounts.ProcessAccount(reqSlice)
@@ -406,6 +409,87 @@ func (app *application) AccountUpdate(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusOK, data, nil)
 
 }
+
+// OpenCurrencyAccount opens an account in another currency for the token user
+func (app *application) OpenCurrencyAccount(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	var req data.CurrencyAccountData
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateCurrencyAccountData(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1013", req.CurrencyCode})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// HolderAccounts lists the token user's accounts in every currency
+func (app *application) HolderAccounts(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1014"})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
 func (app *application) AccountGet(w http.ResponseWriter, r *http.Request) {
 	token, err := app.getTokenFromHeader(w, r)
 	if err != nil {
@@ -450,7 +534,7 @@ func (app *application) AccountGet(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
 func (app *application) BlockAccount(w http.ResponseWriter, r *http.Request) {
-	_, err := app.getTokenFromHeader(w, r)
+	token, err := app.getTokenFromHeader(w, r)
 	if err != nil {
 		// there was error
 		data := envelope{
@@ -479,7 +563,7 @@ func (app *application) BlockAccount(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
-	response, err := accounts.ProcessAccount([]string{"", "acmt", "1010", req.AccountNumber})
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1010", req.AccountNumber})
 	if err != nil {
 		app.errorJSON(w, err)
 		return
@@ -493,7 +577,7 @@ func (app *application) BlockAccount(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
 func (app *application) UnblockAccount(w http.ResponseWriter, r *http.Request) {
-	_, err := app.getTokenFromHeader(w, r)
+	token, err := app.getTokenFromHeader(w, r)
 	if err != nil {
 		// there was error
 		data := envelope{
@@ -522,7 +606,7 @@ func (app *application) UnblockAccount(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
-	response, err := accounts.ProcessAccount([]string{"", "acmt", "1011", req.AccountNumber})
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1011", req.AccountNumber})
 	if err != nil {
 		app.errorJSON(w, err)
 		return
@@ -761,6 +845,50 @@ func (app *application) AccountHistory(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
 
+// AccountStatement returns an account's details, including its IBAN and BIC, with its transactions
+func (app *application) AccountStatement(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1016", req.AccountNumber})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
 // func validateAccountRequest(req AccountRequest) error {
 // 	// Perform your validation checks here
 // 	// For instance, check if required fields are not empty, validate formats, etc.
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/agents"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// AgentCashIn credits a customer from the calling agent's float
+func (app *application) AgentCashIn(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	AgentCashInData := data.AgentCashInData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &AgentCashInData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateAgentCashInData(v, &AgentCashInData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	transaction, err := agents.CashIn(token, AgentCashInData.CustomerAccountNumber, AgentCashInData.Amount, AgentCashInData.LocationID)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(transaction.CustomerAccountNumber, fmt.Sprintf("Your account has been credited with %s by cash deposit at an agent", transaction.Amount.StringFixed(2)))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      transaction,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AgentCashOut lets a customer withdraw cash at an agent, paying the agent's float
+func (app *application) AgentCashOut(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	AgentCashOutData := data.AgentCashOutData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &AgentCashOutData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateAgentCashOutData(v, &AgentCashOutData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	transaction, err := agents.CashOut(token, AgentCashOutData.AgentCode, AgentCashOutData.CustomerAccountNumber, AgentCashOutData.Amount, AgentCashOutData.LocationID)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      transaction,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AgentTopUp requests a float top-up for the calling agent
+func (app *application) AgentTopUp(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	AgentTopUpData := data.AgentTopUpData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &AgentTopUpData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateAgentTopUpData(v, &AgentTopUpData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	topUp, err := agents.RequestTopUp(token, AgentTopUpData.Amount, AgentTopUpData.Reference)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      topUp,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AgentSettlement returns the calling agent's end of day report, for today when no date (YYYY-MM-DD) is given
+func (app *application) AgentSettlement(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	AgentSettlementData := data.AgentSettlementData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &AgentSettlementData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	day := time.Now().UTC()
+	if AgentSettlementData.Date != "" {
+		day, err = time.Parse("2006-01-02", AgentSettlementData.Date)
+		if err != nil {
+			// there was error
+			data := envelope{
+				"responseCode": "06",
+				"status":       "Failed",
+				"message":      "date must be in the format YYYY-MM-DD",
+			}
+
+			app.writeJSON(w, http.StatusBadRequest, data, nil)
+			return
+		}
+	}
+
+	agent, err := agents.AgentFromToken(token)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	report, err := agents.Settlement(agent.AgentCode, day)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      report,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AgentLocations lists the locations of active agents, optionally filtered by city
+func (app *application) AgentLocations(w http.ResponseWriter, r *http.Request) {
+	AgentLocationsData := data.AgentLocationsData{}
+	// read the incoming request body
+	err := app.readJSON(w, r, &AgentLocationsData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	locations, err := agents.FindLocations(AgentLocationsData.City)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      locations,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
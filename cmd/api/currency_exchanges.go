@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/ebitezion/backend-framework/internal/converter"
+	"github.com/shopspring/decimal"
 )
 
 func (app *application) ExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
@@ -70,13 +71,24 @@ func (app *application) ConvertCurrencyHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	amount, err := decimal.NewFromString(amountStr)
 	if err != nil {
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
 	}
 
-	result, err := converter.ConvertCurrency(from, to, amount)
+	// A snapshot ID repeats an earlier conversion with the same rates
+	var result converter.Conversion
+	if snapshotID := r.URL.Query().Get("snapshotId"); snapshotID != "" {
+		id, err := strconv.ParseInt(snapshotID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid snapshot ID", http.StatusBadRequest)
+			return
+		}
+		result, err = converter.ConvertWithSnapshot(id, from, to, amount)
+	} else {
+		result, err = converter.Convert(from, to, amount)
+	}
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
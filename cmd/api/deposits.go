@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/deposits"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// DepositProducts lists the term deposits on offer with today's rates
+func (app *application) DepositProducts(w http.ResponseWriter, r *http.Request) {
+	_, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	products, err := deposits.Products()
+	app.resultResponse(w, products, err)
+}
+
+// TermDeposits lists the term deposits booked from one of the customer's accounts
+func (app *application) TermDeposits(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = checkAccountHolder(token, req.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	accountDeposits, err := deposits.ForAccount(req.AccountNumber)
+	app.resultResponse(w, accountDeposits, err)
+}
+
+// NewTermDeposit locks an amount from one of the customer's accounts for a product's term
+func (app *application) NewTermDeposit(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	TermDepositData := data.TermDepositData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &TermDepositData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateTermDepositData(v, &TermDepositData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+	err = checkAccountHolder(token, TermDepositData.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	deposit, err := deposits.Open(TermDepositData.AccountNumber, TermDepositData.ProductID, TermDepositData.Amount, TermDepositData.RolloverOption, tokenUser)
+	app.resultResponse(w, deposit, err)
+}
+
+// TermDepositRollover changes what happens to a deposit on maturity
+func (app *application) TermDepositRollover(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	TermDepositActionData := data.TermDepositActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &TermDepositActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateTermDepositActionData(v, &TermDepositActionData)
+	v.Check(TermDepositActionData.RolloverOption != "", "rolloverOption", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = depositOwner(token, TermDepositActionData.DepositNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	deposit, err := deposits.SetRollover(TermDepositActionData.DepositNumber, TermDepositActionData.RolloverOption)
+	app.resultResponse(w, deposit, err)
+}
+
+// TermDepositBreak ends a deposit early, less the early-break penalty
+func (app *application) TermDepositBreak(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	TermDepositActionData := data.TermDepositActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &TermDepositActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateTermDepositActionData(v, &TermDepositActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tokenUser, err := depositOwner(token, TermDepositActionData.DepositNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	deposit, err := deposits.Break(TermDepositActionData.DepositNumber, TermDepositActionData.Reason, tokenUser)
+	app.resultResponse(w, deposit, err)
+}
+
+// TermDepositCertificate returns a deposit's certificate as a PDF
+func (app *application) TermDepositCertificate(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	TermDepositActionData := data.TermDepositActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &TermDepositActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateTermDepositActionData(v, &TermDepositActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = depositOwner(token, TermDepositActionData.DepositNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+	deposit, err := deposits.Get(TermDepositActionData.DepositNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"deposit-"+deposit.DepositNumber+".pdf\"")
+	err = writeDepositCertificate(w, deposit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// depositOwner checks the token user holds the account the deposit was booked from and
+// returns the user
+func depositOwner(token string, depositNumber string) (string, error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return "", err
+	}
+	deposit, err := deposits.Get(depositNumber)
+	if err != nil {
+		return "", err
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, deposit.AccountNumber)
+	if err != nil {
+		return "", err
+	}
+	if !holder {
+		return "", errors.New("Deposit not valid")
+	}
+	return tokenUser, nil
+}
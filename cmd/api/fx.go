@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/fx"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// FXQuote locks a rate for a transfer between two of the customer's accounts in different currencies
+func (app *application) FXQuote(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	FXQuoteData := data.FXQuoteData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &FXQuoteData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateFXQuoteData(v, &FXQuoteData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	quote, err := fx.RequestQuote(token, FXQuoteData.SourceAccountNumber, FXQuoteData.TargetAccountNumber, FXQuoteData.Amount)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      quote,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// FXAcceptQuote executes a quote at its locked rate before it expires
+func (app *application) FXAcceptQuote(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	FXAcceptQuoteData := data.FXAcceptQuoteData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &FXAcceptQuoteData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateFXAcceptQuoteData(v, &FXAcceptQuoteData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	quote, err := fx.AcceptQuote(token, FXAcceptQuoteData.Reference)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(quote.TargetAccountNumber, fmt.Sprintf("Your %s account has been credited with %s from your %s account", quote.BuyCurrency, currency.Format(quote.BuyCurrency, quote.BuyAmount), quote.SellCurrency))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      quote,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
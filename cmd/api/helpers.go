@@ -18,9 +18,14 @@ import (
 
 	"github.com/360EntSecGroup-Skylar/excelize"
 	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/deposits"
+	"github.com/ebitezion/backend-framework/internal/payments"
 	"github.com/ebitezion/backend-framework/internal/validator"
 	"github.com/julienschmidt/httprouter"
 	"github.com/jung-kurt/gofpdf"
+	"github.com/shopspring/decimal"
 )
 
 // Retrieve the "id" URL parameter from the current request context, then convert
@@ -217,6 +222,56 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// checkAccountHolder checks the token user holds the account
+func checkAccountHolder(token string, accountNumber string) error {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return err
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, accountNumber)
+	if err != nil {
+		return err
+	}
+	if !holder {
+		return errors.New("Account not valid")
+	}
+	return nil
+}
+
+// tokenErrorResponse reports a missing or invalid token
+func (app *application) tokenErrorResponse(w http.ResponseWriter, err error) {
+	// there was error
+	data := envelope{
+		"responseCode": "07",
+		"status":       "Failed",
+		"message":      err.Error(),
+	}
+
+	app.writeJSON(w, http.StatusBadRequest, data, nil)
+}
+
+// resultResponse reports an operation's outcome, message on success and err otherwise
+func (app *application) resultResponse(w http.ResponseWriter, message interface{}, err error) {
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      message,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
 // generateRandomNumber gives a random number of a given length
 func (app *application) generateRandomNumber(length int) (int, error) {
 	if length < 1 {
@@ -408,7 +463,7 @@ func createPdf(data interface{}) (string, error) {
 		pdf.Cell(0, 10, fmt.Sprintf("Transaction Type: %s", t.Transaction))
 		pdf.Ln(16)
 
-		pdf.Cell(0, 10, fmt.Sprintf("Transaction Amount: $%.2f", t.TransactionAmount))
+		pdf.Cell(0, 10, fmt.Sprintf("Transaction Amount: %s %s", t.CurrencyCode, currency.Format(t.CurrencyCode, decimal.NewFromFloat(t.TransactionAmount))))
 		pdf.Ln(16)
 
 		pdf.Cell(0, 10, fmt.Sprintf("Sender Account: %s, Bank: %s", t.SenderAccountNumber, t.SenderBankNumber))
@@ -417,7 +472,7 @@ func createPdf(data interface{}) (string, error) {
 		pdf.Cell(0, 10, fmt.Sprintf("Receiver Account: %s, Bank: %s", t.ReceiverAccountNumber, t.ReceiverBankNumber))
 		pdf.Ln(16)
 
-		pdf.Cell(0, 10, fmt.Sprintf("Fee Amount: $%.2f", t.FeeAmount))
+		pdf.Cell(0, 10, fmt.Sprintf("Fee Amount: %s %s", t.CurrencyCode, currency.Format(t.CurrencyCode, decimal.NewFromFloat(t.FeeAmount))))
 		pdf.Ln(16)
 
 		pdf.Cell(0, 10, fmt.Sprintf("Timestamp: %s", t.Timestamp))
@@ -435,6 +490,62 @@ func createPdf(data interface{}) (string, error) {
 	fmt.Println("PDF created successfully!")
 	return pdfPath, nil
 }
+
+// writeDepositCertificate writes a term deposit's certificate as a PDF
+func writeDepositCertificate(w io.Writer, deposit deposits.Deposit) error {
+	pdf := gofpdf.New(gofpdf.OrientationPortrait, gofpdf.UnitPoint, gofpdf.PageSizeA4, "")
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Arial", "B", 16)
+		pdf.Cell(0, 10, "Nouveau Mobile Term Deposit Certificate")
+		pdf.Ln(40)
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Arial", "", 12)
+
+	pdf.MultiCell(0, 16, fmt.Sprintf("This certifies that the deposit below was placed with us on %s.", deposit.StartDate), "", "", false)
+	pdf.Ln(16)
+
+	lines := [][]string{
+		{"Deposit Number", deposit.DepositNumber},
+		{"Product", deposit.ProductName},
+		{"Account Number", deposit.AccountNumber},
+		{"Principal", currency.Format(deposit.CurrencyCode, deposit.Principal)},
+		{"Currency", deposit.CurrencyCode},
+		{"Interest Rate", deposit.Rate.String() + "% a year, actual/365"},
+		{"Term", strconv.Itoa(deposit.TermDays) + " days"},
+		{"Start Date", deposit.StartDate},
+		{"Maturity Date", deposit.MaturityDate},
+		{"On Maturity", strings.ReplaceAll(deposit.RolloverOption, "_", " ")},
+		{"Status", strings.ReplaceAll(deposit.Status, "_", " ")},
+	}
+	if deposit.ClosedAt != "" {
+		lines = append(lines,
+			[]string{"Interest Paid", currency.Format(deposit.CurrencyCode, deposit.InterestPaid)},
+			[]string{"Early-break Penalty", currency.Format(deposit.CurrencyCode, deposit.Penalty)},
+			[]string{"Closed On", deposit.ClosedAt},
+		)
+	}
+	if deposit.RolledOverFrom != "" {
+		lines = append(lines, []string{"Rolled Over From", deposit.RolledOverFrom})
+	}
+	if deposit.RolledOverTo != "" {
+		lines = append(lines, []string{"Rolled Over To", deposit.RolledOverTo})
+	}
+	for _, line := range lines {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(160, 10, line[0]+":")
+		pdf.SetFont("Arial", "", 12)
+		pdf.Cell(0, 10, line[1])
+		pdf.Ln(20)
+	}
+
+	pdf.Ln(20)
+	pdf.MultiCell(0, 16, "Withdrawing the deposit before the maturity date forfeits part of the interest earned.", "", "", false)
+
+	return pdf.Output(w)
+}
+
 func createExcelSheet(data interface{}) (string, error) {
 
 	//Unmarshal JSON data
@@ -454,7 +565,7 @@ func createExcelSheet(data interface{}) (string, error) {
 	index := f.NewSheet("TransactionSheet")
 
 	// Set column headers
-	headers := []string{"ID", "Transaction", "Type", "SenderAccountNumber", "SenderBankNumber", "ReceiverAccountNumber", "ReceiverBankNumber", "TransactionAmount", "FeeAmount", "Timestamp"}
+	headers := []string{"ID", "Transaction", "Type", "SenderAccountNumber", "SenderBankNumber", "ReceiverAccountNumber", "ReceiverBankNumber", "CurrencyCode", "TransactionAmount", "FeeAmount", "Timestamp"}
 	for col, header := range headers {
 		cell := fmt.Sprintf("%c%d", 'A'+col, 1)
 		f.SetCellValue("TransactionSheet", cell, header)
@@ -483,6 +594,12 @@ func createExcelSheet(data interface{}) (string, error) {
 
 // Helper function to get field value using reflection
 func getFieldValue(transaction accounts.Transaction, field string) interface{} {
+	// Amounts are exported in the minor units of the transaction's currency
+	if field == "TransactionAmount" || field == "FeeAmount" {
+		amount := decimal.NewFromFloat(reflect.ValueOf(transaction).FieldByName(field).Float())
+		return currency.Round(transaction.CurrencyCode, amount).InexactFloat64()
+	}
+
 	r := reflect.ValueOf(transaction)
 	f := reflect.Indirect(r).FieldByName(field)
 	return f.Interface()
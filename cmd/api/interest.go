@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/interest"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// AccountInterest returns the interest one of the customer's accounts is earning and has been paid
+func (app *application) AccountInterest(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = checkAccountHolder(token, req.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	summary, err := interest.AccountSummary(req.AccountNumber)
+	app.resultResponse(w, summary, err)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/converter"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/deposits"
+	"github.com/ebitezion/backend-framework/internal/dormancy"
+	"github.com/ebitezion/backend-framework/internal/interest"
+	"github.com/ebitezion/backend-framework/internal/loans"
+	"github.com/ebitezion/backend-framework/internal/overdrafts"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/treasury"
+)
+
+// How often the background jobs run
+const backgroundJobInterval = 15 * time.Minute
+
+// runBackgroundJobs expires stale payment requests and unapproved mandate payments, refunds
+// uncollected cash pickups, applies scheduled treasury rate changes, moves inactive accounts
+// to dormant, accrues and settles term deposits, accrues and capitalises savings interest,
+// expires overdrafts and charges overdraft interest, collects loan instalments and charges
+// late fees, and refreshes the cached exchange rates.
+// It runs until the process exits.
+func (app *application) runBackgroundJobs() {
+	ticker := time.NewTicker(backgroundJobInterval)
+	defer ticker.Stop()
+
+	for {
+		app.runJobs()
+		<-ticker.C
+	}
+}
+
+func (app *application) runJobs() {
+	expired, err := payments.ExpirePaymentRequests()
+	if err != nil {
+		app.logger.Println(err)
+	} else if expired > 0 {
+		app.logger.Printf("expired %d payment requests", expired)
+	}
+
+	expired, err = payments.ExpireMandatePayments()
+	if err != nil {
+		app.logger.Println(err)
+	} else if expired > 0 {
+		app.logger.Printf("expired %d mandate payments", expired)
+	}
+
+	refunded, err := payments.ExpireCashPickups()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if refunded > 0 {
+		app.logger.Printf("refunded %d expired cash pickups", refunded)
+	}
+
+	applied, err := treasury.ApplyDueRateChanges()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if applied > 0 {
+		app.logger.Printf("applied %d scheduled rate changes", applied)
+	}
+
+	run, err := dormancy.CheckDormancy()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if run.Notified > 0 || run.MadeDormant > 0 {
+		app.logger.Printf("sent %d dormancy notices, made %d accounts dormant", run.Notified, run.MadeDormant)
+	}
+
+	accruals, err := deposits.RunAccruals()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if accruals.Accrued > 0 || accruals.Matured > 0 {
+		app.logger.Printf("accrued interest on %d term deposits, settled %d at maturity", accruals.Accrued, accruals.Matured)
+	}
+
+	interestRun, err := interest.RunAccruals()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if len(interestRun.Missed) > 0 {
+		app.logger.Printf("interest was not accrued on %s", strings.Join(interestRun.Missed, ", "))
+	}
+	if interestRun.Capitalised > 0 {
+		app.logger.Printf("capitalised interest on %d accounts", interestRun.Capitalised)
+	}
+
+	charges, err := overdrafts.RunCharges()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if charges.Expired > 0 || charges.Fees > 0 || charges.Interest > 0 {
+		app.logger.Printf("expired %d overdrafts, charged %d arrangement fees and interest on %d accounts", charges.Expired, charges.Fees, charges.Interest)
+	}
+
+	repayments, err := loans.RunRepayments()
+	if err != nil {
+		app.logger.Println(err)
+	}
+	if repayments.Collected > 0 || repayments.LateFees > 0 {
+		app.logger.Printf("collected instalments on %d loans, %d fully repaid, charged %d late fees", repayments.Collected, repayments.Repaid, repayments.LateFees)
+	}
+
+	bases := make([]string, 0)
+	for _, c := range currency.All() {
+		bases = append(bases, c.Code)
+	}
+	_, err = converter.RefreshRates(bases)
+	if err != nil {
+		app.logger.Println(err)
+	}
+}
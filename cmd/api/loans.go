@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/loans"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// Loans lists the loans applied for on one of the customer's accounts
+func (app *application) Loans(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = checkAccountHolder(token, req.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	accountLoans, err := loans.ForAccount(req.AccountNumber)
+	app.resultResponse(w, accountLoans, err)
+}
+
+// ApplyLoan asks for a loan to be paid into one of the customer's accounts, the bank decides the terms
+func (app *application) ApplyLoan(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	LoanApplicationData := data.LoanApplicationData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &LoanApplicationData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateLoanApplicationData(v, &LoanApplicationData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+	err = checkAccountHolder(token, LoanApplicationData.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	loan, err := loans.Apply(LoanApplicationData.AccountNumber, LoanApplicationData.Amount, LoanApplicationData.TenorMonths, LoanApplicationData.Purpose, tokenUser)
+	app.resultResponse(w, loan, err)
+}
+
+// LoanStatement returns a loan's schedule, repayments and what is owed and in arrears
+func (app *application) LoanStatement(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	LoanActionData := data.LoanActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &LoanActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateLoanActionData(v, &LoanActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = loanOwner(token, LoanActionData.LoanNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	statement, err := loans.LoanStatement(LoanActionData.LoanNumber)
+	app.resultResponse(w, statement, err)
+}
+
+// RepayLoan pays an amount off a loan from its account, ahead of the schedule if the
+// instalments due are already paid
+func (app *application) RepayLoan(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	LoanActionData := data.LoanActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &LoanActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateLoanActionData(v, &LoanActionData)
+	v.Check(LoanActionData.Amount != "", "amount", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tokenUser, err := loanOwner(token, LoanActionData.LoanNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	repayment, err := loans.Repay(LoanActionData.LoanNumber, LoanActionData.Amount, tokenUser)
+	app.resultResponse(w, repayment, err)
+}
+
+// loanOwner checks the token user holds the account the loan is on and returns the user
+func loanOwner(token string, loanNumber string) (string, error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return "", err
+	}
+	loan, err := loans.Get(loanNumber)
+	if err != nil {
+		return "", err
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, loan.AccountNumber)
+	if err != nil {
+		return "", err
+	}
+	if !holder {
+		return "", errors.New("Loan not valid")
+	}
+	return tokenUser, nil
+}
@@ -16,10 +16,30 @@ import (
 	"time"
 
 	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/agents"
+	"github.com/ebitezion/backend-framework/internal/allocation"
 	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/audit"
 	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/converter"
+	"github.com/ebitezion/backend-framework/internal/customers"
 	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/deposits"
+	"github.com/ebitezion/backend-framework/internal/dormancy"
+	"github.com/ebitezion/backend-framework/internal/fx"
+	"github.com/ebitezion/backend-framework/internal/interest"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/ebitezion/backend-framework/internal/loans"
+	"github.com/ebitezion/backend-framework/internal/mandates"
+	"github.com/ebitezion/backend-framework/internal/merchantqr"
+	"github.com/ebitezion/backend-framework/internal/nuban"
+	"github.com/ebitezion/backend-framework/internal/overdrafts"
 	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/pots"
+	"github.com/ebitezion/backend-framework/internal/rbac_2"
+	"github.com/ebitezion/backend-framework/internal/treasury"
+	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
+	"github.com/ebitezion/backend-framework/internal/usacctgen"
 	"github.com/gorilla/sessions"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
@@ -115,8 +135,28 @@ func main() {
 
 	}
 	appauth.SetConfig(&con)
+	rbac_2.SetConfig(&con)
 	payments.SetConfig(&con)
 	accounts.SetConfig(&con)
+	customers.SetConfig(&con)
+	merchantqr.SetConfig(&con)
+	nuban.SetConfig(&con)
+	ukaccountgen.SetConfig(&con)
+	usacctgen.SetConfig(&con)
+	allocation.SetConfig(&con)
+	lifecycle.SetConfig(&con)
+	dormancy.SetConfig(&con)
+	mandates.SetConfig(&con)
+	pots.SetConfig(&con)
+	deposits.SetConfig(&con)
+	interest.SetConfig(&con)
+	overdrafts.SetConfig(&con)
+	loans.SetConfig(&con)
+	agents.SetConfig(&con)
+	converter.SetConfig(&con)
+	fx.SetConfig(&con)
+	audit.SetConfig(&con)
+	treasury.SetConfig(&con)
 
 	// Call the openDB() helper function (see below) to create the connection pool,
 	// passing in the config struct. If this returns an error, we log it and exit the
@@ -153,6 +193,9 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Expire payment requests and refund uncollected cash pickups in the background
+	go app.runBackgroundJobs()
+
 	// // Start the HTTP server.
 	logger.Printf("starting %s server on %s", cfg.env, srv.Addr)
 	err = srv.ListenAndServe()
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// PendingMandatePayments lists the payments from a joint account waiting on its holders
+func (app *application) PendingMandatePayments(w http.ResponseWriter, r *http.Request) {
+	_, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	pending, err := payments.PendingMandatePayments(req.AccountNumber)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      pending,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// ApproveMandatePayment signs a pending payment as one of the account's holders.
+// The payment is made once its mandate has enough signatures.
+func (app *application) ApproveMandatePayment(w http.ResponseWriter, r *http.Request) {
+	app.mandatePaymentAction(w, r, payments.ApproveMandatePayment)
+}
+
+// RejectMandatePayment cancels a pending payment
+func (app *application) RejectMandatePayment(w http.ResponseWriter, r *http.Request) {
+	app.mandatePaymentAction(w, r, payments.RejectMandatePayment)
+}
+
+func (app *application) mandatePaymentAction(w http.ResponseWriter, r *http.Request, action func(token string, id int64) (payments.MandatePayment, error)) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MandatePaymentActionData := data.MandatePaymentActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MandatePaymentActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateMandatePaymentActionData(v, &MandatePaymentActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	payment, err := action(token, MandatePaymentActionData.PaymentID)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      payment,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/merchantqr"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// NewMerchantQR issues a static or dynamic QR code for a merchant account
+func (app *application) NewMerchantQR(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MerchantQRData := data.MerchantQRData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MerchantQRData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateMerchantQRData(v, &MerchantQRData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	code, err := merchantqr.CreateCode(token, MerchantQRData.MerchantAccountNumber, MerchantQRData.MerchantName, MerchantQRData.MerchantCity, MerchantQRData.Type, MerchantQRData.Amount, MerchantQRData.Reference)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	png, err := merchantqr.PNG(code.Payload, merchantqr.DEFAULT_QR_IMAGE_SIZE)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      code,
+		"qrCode":       base64.StdEncoding.EncodeToString(png),
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// MerchantQRImage returns the PNG image of an issued QR code
+func (app *application) MerchantQRImage(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MerchantQRReferenceData := data.MerchantQRReferenceData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MerchantQRReferenceData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateMerchantQRReferenceData(v, &MerchantQRReferenceData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkTokenUser(token, MerchantQRReferenceData.MerchantAccountNumber)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	code, err := merchantqr.GetCode(MerchantQRReferenceData.MerchantAccountNumber, MerchantQRReferenceData.Reference)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	png, err := merchantqr.PNG(code.Payload, MerchantQRReferenceData.Size)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s.png", code.Reference))
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+// CancelMerchantQR stops a QR code from accepting payments
+func (app *application) CancelMerchantQR(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MerchantQRReferenceData := data.MerchantQRReferenceData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MerchantQRReferenceData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateMerchantQRReferenceData(v, &MerchantQRReferenceData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	code, err := merchantqr.CancelCode(token, MerchantQRReferenceData.MerchantAccountNumber, MerchantQRReferenceData.Reference)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      code,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// ParseMerchantQR decodes a scanned payload so the wallet can show the payment before it is confirmed
+func (app *application) ParseMerchantQR(w http.ResponseWriter, r *http.Request) {
+	_, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MerchantQRPaymentData := data.MerchantQRPaymentData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MerchantQRPaymentData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	v.Check(MerchantQRPaymentData.Payload != "", "payload", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	payload, err := merchantqr.Parse(MerchantQRPaymentData.Payload)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      payload,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// PayMerchantQR pays a merchant from a scanned payload with a PAIN credit transfer
+func (app *application) PayMerchantQR(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MerchantQRPaymentData := data.MerchantQRPaymentData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MerchantQRPaymentData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateMerchantQRPaymentData(v, &MerchantQRPaymentData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	code, response, err := merchantqr.PayCode(token, MerchantQRPaymentData.PayerAccountNumber, MerchantQRPaymentData.Payload, MerchantQRPaymentData.Amount)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(code.MerchantAccountNumber, fmt.Sprintf("%s paid %s on QR code %s", MerchantQRPaymentData.PayerAccountNumber, code.Amount.StringFixed(2), code.Reference))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+		"qrCode":       code,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// MerchantQRReconciliation matches a merchant's QR payments to the codes they were made against
+func (app *application) MerchantQRReconciliation(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	MerchantReconciliationData := data.MerchantReconciliationData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &MerchantReconciliationData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateMerchantReconciliationData(v, &MerchantReconciliationData)
+	from, fromErr := time.Parse("2006-01-02", MerchantReconciliationData.From)
+	v.Check(fromErr == nil, "from", "must be a date in the format YYYY-MM-DD")
+	to, toErr := time.Parse("2006-01-02", MerchantReconciliationData.To)
+	v.Check(toErr == nil, "to", "must be a date in the format YYYY-MM-DD")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkTokenUser(token, MerchantReconciliationData.MerchantAccountNumber)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	// The end date is inclusive
+	reconciliation, err := merchantqr.Reconcile(MerchantReconciliationData.MerchantAccountNumber, from, to.Add(24*time.Hour-time.Second))
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      reconciliation,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// checkTokenUser makes sure the token belongs to the account being read
+func (app *application) checkTokenUser(token string, accountNumber string) error {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return err
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, accountNumber)
+	if err != nil {
+		return err
+	}
+	if !holder {
+		return fmt.Errorf("token does not belong to account %s", accountNumber)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/overdrafts"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// Overdrafts lists the overdrafts applied for on one of the customer's accounts
+func (app *application) Overdrafts(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = checkAccountHolder(token, req.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	facilities, err := overdrafts.ForAccount(req.AccountNumber)
+	app.resultResponse(w, facilities, err)
+}
+
+// ApplyOverdraft asks for an overdraft on one of the customer's accounts, the bank decides the limit
+func (app *application) ApplyOverdraft(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	OverdraftApplicationData := data.OverdraftApplicationData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &OverdraftApplicationData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateOverdraftApplicationData(v, &OverdraftApplicationData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+	err = checkAccountHolder(token, OverdraftApplicationData.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	facility, err := overdrafts.Apply(OverdraftApplicationData.AccountNumber, OverdraftApplicationData.Limit, OverdraftApplicationData.Reason, tokenUser)
+	app.resultResponse(w, facility, err)
+}
+
+// CancelOverdraft withdraws an application or gives up an overdraft. Whatever is overdrawn
+// stays owed and keeps being charged interest.
+func (app *application) CancelOverdraft(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	OverdraftActionData := data.OverdraftActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &OverdraftActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateOverdraftActionData(v, &OverdraftActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tokenUser, err := overdraftOwner(token, OverdraftActionData.OverdraftID)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	facility, err := overdrafts.Cancel(OverdraftActionData.OverdraftID, OverdraftActionData.Reason, tokenUser)
+	app.resultResponse(w, facility, err)
+}
+
+// overdraftOwner checks the token user holds the account the overdraft is on and returns the user
+func overdraftOwner(token string, id int64) (string, error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return "", err
+	}
+	facility, err := overdrafts.Get(id)
+	if err != nil {
+		return "", err
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, facility.AccountNumber)
+	if err != nil {
+		return "", err
+	}
+	if !holder {
+		return "", errors.New("Overdraft not valid")
+	}
+	return tokenUser, nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/notifications"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// NewPaymentRequest lets a customer request money from another customer
+func (app *application) NewPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	PaymentRequestData := data.PaymentRequestData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PaymentRequestData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidatePaymentRequestData(v, &PaymentRequestData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ttl := time.Duration(PaymentRequestData.ExpiresInHours) * time.Hour
+	request, err := payments.CreatePaymentRequest(token, PaymentRequestData.RequesterAccountNumber, PaymentRequestData.PayerAccountNumber, PaymentRequestData.Amount, PaymentRequestData.Reason, ttl)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(request.PayerAccountNumber, fmt.Sprintf("%s has requested %s from you: %s. The request expires on %s", request.RequesterAccountNumber, request.Amount.StringFixed(2), request.Reason, request.ExpiresAt.Format(time.RFC1123)))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      request,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// PendingPaymentRequests lists the requests waiting on the payer
+func (app *application) PendingPaymentRequests(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	requests, err := payments.PendingPaymentRequests(token, req.AccountNumber)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      requests,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AcceptPaymentRequest pays a pending request
+func (app *application) AcceptPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	PaymentRequestActionData := data.PaymentRequestActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PaymentRequestActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidatePaymentRequestActionData(v, &PaymentRequestActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	request, err := payments.AcceptPaymentRequest(token, PaymentRequestActionData.RequestID, PaymentRequestActionData.AccountNumber)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(request.RequesterAccountNumber, fmt.Sprintf("%s has paid your request of %s: %s", request.PayerAccountNumber, request.Amount.StringFixed(2), request.Reason))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      request,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// DeclinePaymentRequest rejects a pending request
+func (app *application) DeclinePaymentRequest(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	PaymentRequestActionData := data.PaymentRequestActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PaymentRequestActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidatePaymentRequestActionData(v, &PaymentRequestActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	request, err := payments.DeclinePaymentRequest(token, PaymentRequestActionData.RequestID, PaymentRequestActionData.AccountNumber)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(request.RequesterAccountNumber, fmt.Sprintf("%s has declined your request of %s: %s", request.PayerAccountNumber, request.Amount.StringFixed(2), request.Reason))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      request,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// NotifyAccountHolder sends a notification to the contact details held on an account
+func (app *application) NotifyAccountHolder(accountNumber string, message string) error {
+	holder, err := accounts.FetchAccountMeta(accountNumber)
+	if err != nil {
+		return err
+	}
+
+	ns := notifications.NotificationService{}
+	User := notifications.User{
+		Username: holder.GivenName + " " + holder.FamilyName,
+		Email:    holder.EmailAddress,
+		Phone:    holder.ContactNumber1,
+	}
+
+	notification := notifications.Notification{
+		User:    User,
+		Message: message,
+	}
+	notifications.SendNotification(ns, notification)
+
+	return nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/agents"
 	"github.com/ebitezion/backend-framework/internal/data"
 	"github.com/ebitezion/backend-framework/internal/notifications"
 	"github.com/ebitezion/backend-framework/internal/payments"
@@ -191,6 +194,17 @@ func (app *application) PaymentCreditInitiation(w http.ResponseWriter, r *http.R
 
 	response, err := payments.ProcessPAIN([]string{token, "pain", "1", sendersDetails, receiversDetails, amount, "CR"})
 
+	if errors.Is(err, payments.ErrPaymentHeld) {
+		// The transfer waits on the other account holders, it has not failed
+		data := envelope{
+			"responseCode": "00",
+			"status":       "Pending",
+			"message":      response,
+		}
+		app.writeJSON(w, http.StatusAccepted, data, nil)
+		return
+	}
+
 	if err != nil {
 		// there was error
 		data := envelope{
@@ -335,6 +349,16 @@ func (app *application) PaymentDebitInitiation(w http.ResponseWriter, r *http.Re
 	fmt.Println(sendersAccountNumber, receiversAccountNumber)
 
 	response, err := payments.ProcessPAIN([]string{token, "pain", "1", sendersDetails, receiversDetails, amount, "DR"})
+	if errors.Is(err, payments.ErrPaymentHeld) {
+		// The transfer waits on the other account holders, it has not failed
+		data := envelope{
+			"responseCode": "00",
+			"status":       "Pending",
+			"message":      response,
+		}
+		app.writeJSON(w, http.StatusAccepted, data, nil)
+		return
+	}
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -449,6 +473,8 @@ func (app *application) Notification(token string, sendersAccountNumber string,
 
 	return nil
 }
+
+// CashPickup debits the sender into suspense and issues a one-time pickup code and PIN
 func (app *application) CashPickup(w http.ResponseWriter, r *http.Request) {
 	token, err := app.getTokenFromHeader(w, r)
 	if err != nil {
@@ -463,8 +489,6 @@ func (app *application) CashPickup(w http.ResponseWriter, r *http.Request) {
 		app.writeJSON(w, http.StatusBadRequest, data, nil)
 		return
 	}
-	//for credit only  receivers account number and sender account number is required
-	//which is the number before the @ sign
 	CashPickupData := payments.CashPickup{}
 	// read the incoming request body
 	err = app.readJSON(w, r, &CashPickupData)
@@ -482,8 +506,65 @@ func (app *application) CashPickup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := payments.ProcessPAIN([]string{token, "pain", "14"})
+	pickup, pin, err := payments.CreateCashPickup(token, CashPickupData)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(pickup.SendersAccountNumber, fmt.Sprintf("Cash pickup of %s %s for %s %s created. Pickup code %s, PIN %s. Share both with the recipient only. It expires on %s", pickup.Currency, pickup.Amount.StringFixed(2), pickup.FirstName, pickup.LastName, pickup.PickupCode, pin, pickup.ExpiresAt.Format(time.RFC1123)))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      pickup,
+		"pin":          pin,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// CashPickupStatus returns one or all of the sender's cash pickups
+func (app *application) CashPickupStatus(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	CashPickupActionData := data.CashPickupActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &CashPickupActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateCashPickupActionData(v, &CashPickupActionData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
+	pickups, err := payments.CashPickupStatus(token, CashPickupActionData.SendersAccountNumber, CashPickupActionData.PickupCode)
 	if err != nil {
 		// there was error
 		data := envelope{
@@ -496,16 +577,139 @@ func (app *application) CashPickup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// //send notification
-	// err = app.Notification(token, sendersAccountNumber, receiversAccountNumber, amount)
-	// if err != nil {
-	// 	fmt.Println(err)
-	// }
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      pickups,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// CancelCashPickup cancels a pending cash pickup and refunds the sender
+func (app *application) CancelCashPickup(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	CashPickupActionData := data.CashPickupActionData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &CashPickupActionData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateCashPickupActionData(v, &CashPickupActionData)
+	v.Check(CashPickupActionData.PickupCode != "", "pickupCode", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	pickup, err := payments.CancelCashPickup(token, CashPickupActionData.SendersAccountNumber, CashPickupActionData.PickupCode)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(pickup.SendersAccountNumber, fmt.Sprintf("Cash pickup %s has been cancelled and %s %s refunded", pickup.PickupCode, pickup.Currency, pickup.Amount.Add(pickup.Charge).StringFixed(2)))
+	if err != nil {
+		fmt.Println(err)
+	}
 
 	data := envelope{
 		"responseCode": "00",
 		"status":       "Success",
-		"message":      response + "Deposit Made Sucessfully",
+		"message":      pickup,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// RedeemCashPickup lets an agent pay out a cash pickup after checking the PIN and the recipient's BVN or NIN
+func (app *application) RedeemCashPickup(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	CashPickupRedeemData := data.CashPickupRedeemData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &CashPickupRedeemData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateCashPickupRedeemData(v, &CashPickupRedeemData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Only agents pay out cash pickups, the cash is credited to their float
+	_, err = agents.AgentFromToken(token)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      "Only agents can pay out cash pickups. " + err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	pickup, _, err := agents.PayOutCashPickup(token, CashPickupRedeemData.PickupCode, CashPickupRedeemData.Pin, CashPickupRedeemData.IdentificationType, CashPickupRedeemData.IdentificationNumber, CashPickupRedeemData.LocationID)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	//send notification
+	err = app.NotifyAccountHolder(pickup.SendersAccountNumber, fmt.Sprintf("Cash pickup %s of %s %s has been collected by %s %s", pickup.PickupCode, pickup.Currency, pickup.Amount.StringFixed(2), pickup.FirstName, pickup.LastName))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      pickup,
 	}
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/pots"
+	"github.com/ebitezion/backend-framework/internal/validator"
+)
+
+// Pots lists an account's savings pots with its balances
+func (app *application) Pots(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	var req data.User
+	// read the incoming request body
+	err = app.readJSON(w, r, &req)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidateUser(v, &req)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The balance enquiry carries the pots alongside the main balance
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1003", req.AccountNumber})
+	app.resultResponse(w, response, err)
+}
+
+// NewPot opens a savings pot under one of the customer's accounts
+func (app *application) NewPot(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	PotData := data.PotData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PotData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidatePotData(v, &PotData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = checkAccountHolder(token, PotData.AccountNumber)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	pot, err := pots.Create(PotData.AccountNumber, PotData.Name, PotData.TargetAmount, PotData.TargetDate, PotData.RoundUpTo)
+	app.resultResponse(w, pot, err)
+}
+
+// UpdatePot changes a pot's name, target and round-up value
+func (app *application) UpdatePot(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	PotData := data.PotData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PotData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidatePotData(v, &PotData)
+	v.Check(PotData.PotID > 0, "potId", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = potOwner(token, PotData.AccountNumber, PotData.PotID)
+	if err != nil {
+		app.resultResponse(w, nil, err)
+		return
+	}
+
+	pot, err := pots.Update(PotData.PotID, PotData.Name, PotData.TargetAmount, PotData.TargetDate, PotData.RoundUpTo)
+	app.resultResponse(w, pot, err)
+}
+
+// PotDeposit moves money from the main balance into a pot
+func (app *application) PotDeposit(w http.ResponseWriter, r *http.Request) {
+	app.potTransfer(w, r, "1002")
+}
+
+// PotWithdrawal moves money from a pot back to the main balance
+func (app *application) PotWithdrawal(w http.ResponseWriter, r *http.Request) {
+	app.potTransfer(w, r, "1003")
+}
+
+// ClosePot empties a pot into the main balance and closes it
+func (app *application) ClosePot(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	PotTransferData := data.PotTransferData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PotTransferData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	v.Check(PotTransferData.PotID > 0, "potId", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	pot, err := payments.ClosePot(token, PotTransferData.PotID)
+	app.resultResponse(w, pot, err)
+}
+
+func (app *application) potTransfer(w http.ResponseWriter, r *http.Request, painType string) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		app.tokenErrorResponse(w, err)
+		return
+	}
+
+	PotTransferData := data.PotTransferData{}
+	// read the incoming request body
+	err = app.readJSON(w, r, &PotTransferData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate the request data
+	v := validator.New()
+	data.ValidatePotTransferData(v, &PotTransferData)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	result, err := payments.ProcessPAIN([]string{token, "pain", painType, PotTransferData.AccountNumber, strconv.FormatInt(PotTransferData.PotID, 10), PotTransferData.Amount})
+	app.resultResponse(w, result, err)
+}
+
+// potOwner checks the token user holds the account and the pot belongs to it
+func potOwner(token string, accountNumber string, potID int64) error {
+	err := checkAccountHolder(token, accountNumber)
+	if err != nil {
+		return err
+	}
+	pot, err := pots.Get(potID)
+	if err != nil {
+		return err
+	}
+	if pot.AccountNumber != accountNumber {
+		return errors.New("Pot does not belong to account " + accountNumber)
+	}
+	return nil
+}
@@ -48,11 +48,71 @@ func (app *application) routes() *httprouter.Router {
 	router.HandlerFunc(http.MethodPost, "/v1/api/fullAccessDeposit", app.FullAccessDepositInitiation)
 	router.HandlerFunc(http.MethodPost, "/v1/api/balanceEnquiry", app.BalanceEnquiry)
 	router.HandlerFunc(http.MethodPost, "/v1/api/accountHistory", app.AccountHistory)
+	router.HandlerFunc(http.MethodPost, "/v1/api/accountStatement", app.AccountStatement)
 	router.HandlerFunc(http.MethodGet, "/v1/api/allTransactions", app.AllTransactions)
 	router.HandlerFunc(http.MethodGet, "/v1/api/pdfTransactions", app.PdfTransactions)
 	router.HandlerFunc(http.MethodGet, "/v1/api/excelTransactions", app.ExcelTransactions)
 	router.HandlerFunc(http.MethodPost, "/v1/api/proofOfAddress", app.ProofOfAddress)
 	router.HandlerFunc(http.MethodPost, "/v1/api/cashPickup", app.CashPickup)
+	router.HandlerFunc(http.MethodPost, "/v1/api/cashPickup/status", app.CashPickupStatus)
+	router.HandlerFunc(http.MethodPost, "/v1/api/cashPickup/cancel", app.CancelCashPickup)
+	router.HandlerFunc(http.MethodPost, "/v1/api/cashPickup/redeem", app.RedeemCashPickup)
+
+	//Payment requests
+	router.HandlerFunc(http.MethodPost, "/v1/api/paymentRequests/new", app.NewPaymentRequest)
+	router.HandlerFunc(http.MethodPost, "/v1/api/paymentRequests/pending", app.PendingPaymentRequests)
+	router.HandlerFunc(http.MethodPost, "/v1/api/paymentRequests/accept", app.AcceptPaymentRequest)
+	router.HandlerFunc(http.MethodPost, "/v1/api/paymentRequests/decline", app.DeclinePaymentRequest)
+
+	//Savings pots
+	router.HandlerFunc(http.MethodPost, "/v1/api/pots", app.Pots)
+	router.HandlerFunc(http.MethodPost, "/v1/api/pots/new", app.NewPot)
+	router.HandlerFunc(http.MethodPost, "/v1/api/pots/update", app.UpdatePot)
+	router.HandlerFunc(http.MethodPost, "/v1/api/pots/deposit", app.PotDeposit)
+	router.HandlerFunc(http.MethodPost, "/v1/api/pots/withdraw", app.PotWithdrawal)
+	router.HandlerFunc(http.MethodPost, "/v1/api/pots/close", app.ClosePot)
+	//Savings interest
+	router.HandlerFunc(http.MethodPost, "/v1/api/interest", app.AccountInterest)
+	//Term deposits
+	router.HandlerFunc(http.MethodPost, "/v1/api/deposits", app.TermDeposits)
+	router.HandlerFunc(http.MethodPost, "/v1/api/deposits/products", app.DepositProducts)
+	router.HandlerFunc(http.MethodPost, "/v1/api/deposits/new", app.NewTermDeposit)
+	router.HandlerFunc(http.MethodPost, "/v1/api/deposits/rollover", app.TermDepositRollover)
+	router.HandlerFunc(http.MethodPost, "/v1/api/deposits/break", app.TermDepositBreak)
+	router.HandlerFunc(http.MethodPost, "/v1/api/deposits/certificate", app.TermDepositCertificate)
+	//Overdrafts
+	router.HandlerFunc(http.MethodPost, "/v1/api/overdrafts", app.Overdrafts)
+	router.HandlerFunc(http.MethodPost, "/v1/api/overdrafts/apply", app.ApplyOverdraft)
+	router.HandlerFunc(http.MethodPost, "/v1/api/overdrafts/cancel", app.CancelOverdraft)
+	//Loans
+	router.HandlerFunc(http.MethodPost, "/v1/api/loans", app.Loans)
+	router.HandlerFunc(http.MethodPost, "/v1/api/loans/apply", app.ApplyLoan)
+	router.HandlerFunc(http.MethodPost, "/v1/api/loans/statement", app.LoanStatement)
+	router.HandlerFunc(http.MethodPost, "/v1/api/loans/repay", app.RepayLoan)
+
+	//Joint account payments awaiting the holders' approval
+	router.HandlerFunc(http.MethodPost, "/v1/api/mandatePayments/pending", app.PendingMandatePayments)
+	router.HandlerFunc(http.MethodPost, "/v1/api/mandatePayments/approve", app.ApproveMandatePayment)
+	router.HandlerFunc(http.MethodPost, "/v1/api/mandatePayments/reject", app.RejectMandatePayment)
+
+	//Merchant QR codes
+	router.HandlerFunc(http.MethodPost, "/v1/api/merchantQR/new", app.NewMerchantQR)
+	router.HandlerFunc(http.MethodPost, "/v1/api/merchantQR/image", app.MerchantQRImage)
+	router.HandlerFunc(http.MethodPost, "/v1/api/merchantQR/cancel", app.CancelMerchantQR)
+	router.HandlerFunc(http.MethodPost, "/v1/api/merchantQR/parse", app.ParseMerchantQR)
+	router.HandlerFunc(http.MethodPost, "/v1/api/merchantQR/pay", app.PayMerchantQR)
+	router.HandlerFunc(http.MethodPost, "/v1/api/merchantQR/reconciliation", app.MerchantQRReconciliation)
+
+	//Cash agents
+	router.HandlerFunc(http.MethodPost, "/v1/api/agents/cashIn", app.AgentCashIn)
+	router.HandlerFunc(http.MethodPost, "/v1/api/agents/cashOut", app.AgentCashOut)
+	router.HandlerFunc(http.MethodPost, "/v1/api/agents/topUp", app.AgentTopUp)
+	router.HandlerFunc(http.MethodPost, "/v1/api/agents/settlement", app.AgentSettlement)
+	router.HandlerFunc(http.MethodPost, "/v1/api/agents/locations", app.AgentLocations)
+
+	//Cross-currency transfers
+	router.HandlerFunc(http.MethodPost, "/v1/api/fx/quote", app.FXQuote)
+	router.HandlerFunc(http.MethodPost, "/v1/api/fx/accept", app.FXAcceptQuote)
 
 	//ACCOUNT V2
 	router.HandlerFunc(http.MethodPost, "/v1/api/accounts/create", app.AccountCreate)
@@ -60,6 +120,8 @@ func (app *application) routes() *httprouter.Router {
 	router.HandlerFunc(http.MethodGet, "/v1/api/accounts", app.AccountGet)
 	router.HandlerFunc(http.MethodPost, "/v1/api/accounts/block", app.BlockAccount)
 	router.HandlerFunc(http.MethodPost, "/v1/api/accounts/unblock", app.UnblockAccount)
+	router.HandlerFunc(http.MethodPost, "/v1/api/accounts/currency", app.OpenCurrencyAccount)
+	router.HandlerFunc(http.MethodGet, "/v1/api/accounts/holder", app.HolderAccounts)
 	router.HandlerFunc(http.MethodPost, "/v1/api/beneficiary/new", app.NewBeneficiary)
 	router.HandlerFunc(http.MethodPost, "/v1/api/beneficiary", app.GetBeneficiaries)
 
@@ -8,8 +8,8 @@ import (
 	"os"
 
 	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/allocation"
 	"github.com/ebitezion/backend-framework/internal/data"
-	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
 	"github.com/ebitezion/backend-framework/internal/validator"
 )
 
@@ -168,9 +168,11 @@ func (app *application) Register(biodata data.AccountBioData) (accountNumber str
 		return "", err
 	}
 
-	// Generate the account number here (as you've done)
-	generator := ukaccountgen.New()
-	accountNumber = generator.GenerateUKAccountNumber()
+	// Allocate the account number from the registry
+	accountNumber, err = allocation.AllocateUK("internal account")
+	if err != nil {
+		return "", err
+	}
 	accountType := data.InternalAccount
 	currencyCode := data.Nigeria
 	currentBalance := 0.0
@@ -308,6 +310,7 @@ func (app *application) AccountCreate(w http.ResponseWriter, r *http.Request) {
 		profileImage,
 		accountHolderIdentificationType,
 		accountHolderCountry,
+		r.FormValue("currencyCode"),
 	}
 
 	_, err = accounts.ProcessAccount(req)
@@ -365,6 +368,8 @@ func (app *application) AccountCreateSpecial(w http.ResponseWriter, r *http.Requ
 		accountHolderName,
 		creator,
 		purpose,
+		r.FormValue("currencyCode"),
+		r.FormValue("accountNumber"),
 	}
 
 	response, err := accounts.ProcessAccount(req)
@@ -624,6 +629,43 @@ func (app *application) AccountHistory(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
 
+// AccountStatement retrieves an account's details, including its IBAN and BIC, with its transactions
+func (app *application) AccountStatement(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	accountNumber := r.FormValue("accountNumber")
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1016", accountNumber})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
 func (app *application) AllTransactions(w http.ResponseWriter, r *http.Request) {
 	token, err := app.getTokenFromHeader(w, r)
 
@@ -780,7 +822,7 @@ func (app *application) PdfTransactions(w http.ResponseWriter, r *http.Request)
 }
 func (app *application) BlockAccount(w http.ResponseWriter, r *http.Request) {
 
-	_, err := app.getTokenFromHeader(w, r)
+	token, err := app.getTokenFromHeader(w, r)
 
 	if err != nil {
 
@@ -797,7 +839,7 @@ func (app *application) BlockAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	accountNumber := r.FormValue("accountNumber")
-	response, err := accounts.ProcessAccount([]string{"", "acmt", "1010", accountNumber})
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1010", accountNumber, r.FormValue("reason")})
 	if err != nil {
 		//there was error
 		data := envelope{
@@ -817,7 +859,7 @@ func (app *application) BlockAccount(w http.ResponseWriter, r *http.Request) {
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
 func (app *application) UnblockAccount(w http.ResponseWriter, r *http.Request) {
-	_, err := app.getTokenFromHeader(w, r)
+	token, err := app.getTokenFromHeader(w, r)
 
 	if err != nil {
 
@@ -834,7 +876,7 @@ func (app *application) UnblockAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	accountNumber := r.FormValue("accountNumber")
-	response, err := accounts.ProcessAccount([]string{"", "acmt", "1011", accountNumber})
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1011", accountNumber, r.FormValue("reason")})
 	if err != nil {
 		//there was error
 		data := envelope{
@@ -853,3 +895,157 @@ func (app *application) UnblockAccount(w http.ResponseWriter, r *http.Request) {
 	}
 	app.writeJSON(w, http.StatusOK, data, nil)
 }
+
+// AccountClose closes an account, sweeping its balance to the nominated beneficiary or suspense
+func (app *application) AccountClose(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	token := r.Header.Get("X-Auth-Token")
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "19", r.FormValue("accountNumber"), r.FormValue("beneficiaryAccountNumber"), r.FormValue("reason")})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AccountClosureCertificate downloads the closure certificate of a closed account
+func (app *application) AccountClosureCertificate(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	accountNumber := r.URL.Query().Get("accountNumber")
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1019", accountNumber})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"closure-"+accountNumber+".pdf\"")
+	err = writeClosureCertificate(w, response.(accounts.AccountClosure))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// IssueUSAccount assigns US routing and account numbers to a USD account
+func (app *application) IssueUSAccount(w http.ResponseWriter, r *http.Request) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	accountNumber := r.FormValue("accountNumber")
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1015", accountNumber})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AccountStatus moves an account through its lifecycle, e.g. to Dormant or PostNoDebit
+func (app *application) AccountStatus(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	token := r.Header.Get("X-Auth-Token")
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1017", r.FormValue("accountNumber"), r.FormValue("status"), r.FormValue("reason")})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
+// AccountStatusHistory lists an account's lifecycle changes with their reasons and actors
+func (app *application) AccountStatusHistory(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	token := r.Header.Get("X-Auth-Token")
+	accountNumber := r.URL.Query().Get("accountNumber")
+	response, err := accounts.ProcessAccount([]string{token, "acmt", "1018", accountNumber})
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      response,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
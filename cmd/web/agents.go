@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/agents"
+	"github.com/shopspring/decimal"
+)
+
+// AgentCreate registers an agent and opens its float account
+func (app *application) AgentCreate(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	agent, err := agents.CreateAgent(r.FormValue("name"), r.FormValue("ownerAccountNumber"), r.FormValue("cashInDailyLimit"), r.FormValue("cashOutDailyLimit"), creator)
+	app.adminResponse(w, agent, err)
+}
+
+// AgentAddLocation adds a location to an agent
+func (app *application) AgentAddLocation(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	location := agents.Location{
+		Name:    r.FormValue("name"),
+		Address: r.FormValue("address"),
+		City:    r.FormValue("city"),
+		State:   r.FormValue("state"),
+	}
+	if r.FormValue("latitude") != "" || r.FormValue("longitude") != "" {
+		latitude, err := decimal.NewFromString(r.FormValue("latitude"))
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+		longitude, err := decimal.NewFromString(r.FormValue("longitude"))
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+		location.Latitude = decimal.NewNullDecimal(latitude)
+		location.Longitude = decimal.NewNullDecimal(longitude)
+	}
+
+	location, err := agents.AddLocation(r.FormValue("agentCode"), location)
+	app.adminResponse(w, location, err)
+}
+
+// AgentCommissionRule sets an agent's commission rule for an operation, or the default rule when no agent code is given
+func (app *application) AgentCommissionRule(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	rule, err := agents.SetCommissionRule(r.FormValue("agentCode"), r.FormValue("operation"), r.FormValue("percentage"), r.FormValue("flatAmount"), r.FormValue("maxAmount"))
+	app.adminResponse(w, rule, err)
+}
+
+// AgentLimits changes an agent's daily limits
+func (app *application) AgentLimits(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	agent, err := agents.SetDailyLimits(r.FormValue("agentCode"), r.FormValue("cashInDailyLimit"), r.FormValue("cashOutDailyLimit"))
+	app.adminResponse(w, agent, err)
+}
+
+// AgentStatus activates or suspends an agent
+func (app *application) AgentStatus(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	agent, err := agents.SetAgentStatus(r.FormValue("agentCode"), r.FormValue("status"))
+	app.adminResponse(w, agent, err)
+}
+
+// AllAgents lists every agent
+func (app *application) AllAgents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	allAgents, err := agents.AllAgents()
+	app.adminResponse(w, allAgents, err)
+}
+
+// AgentPendingTopUps lists the float top-ups awaiting review
+func (app *application) AgentPendingTopUps(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	topUps, err := agents.PendingTopUps()
+	app.adminResponse(w, topUps, err)
+}
+
+// AgentApproveTopUp approves a float top-up and credits the agent's float
+func (app *application) AgentApproveTopUp(w http.ResponseWriter, r *http.Request) {
+	reviewer, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("topUpId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	topUp, err := agents.ApproveTopUp(id, reviewer)
+	app.adminResponse(w, topUp, err)
+}
+
+// AgentRejectTopUp rejects a float top-up
+func (app *application) AgentRejectTopUp(w http.ResponseWriter, r *http.Request) {
+	reviewer, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("topUpId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	topUp, err := agents.RejectTopUp(id, reviewer, r.FormValue("note"))
+	app.adminResponse(w, topUp, err)
+}
+
+// AgentSettlements returns the end of day report for one agent, or every agent when no agent code is given.
+// The date (YYYY-MM-DD) defaults to today.
+func (app *application) AgentSettlements(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	day := time.Now().UTC()
+	if r.FormValue("date") != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", r.FormValue("date"))
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+	}
+
+	if agentCode := r.FormValue("agentCode"); agentCode != "" {
+		report, err := agents.Settlement(agentCode, day)
+		app.adminResponse(w, report, err)
+		return
+	}
+
+	reports, err := agents.Settlements(day)
+	app.adminResponse(w, reports, err)
+}
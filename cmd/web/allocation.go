@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/allocation"
+)
+
+// AccountNumberReservations lists the account number ranges reserved for system accounts
+func (app *application) AccountNumberReservations(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	reservations, err := allocation.Reservations()
+	app.adminResponse(w, reservations, err)
+}
+
+// AccountNumberReserve reserves a range of account numbers for system accounts
+func (app *application) AccountNumberReserve(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	reservation, err := allocation.Reserve(r.FormValue("scheme"), r.FormValue("rangeStart"), r.FormValue("rangeEnd"), r.FormValue("purpose"), creator)
+	app.adminResponse(w, reservation, err)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/customers"
+)
+
+// CustomerFile returns a customer's details with their accounts, KYC documents and beneficiaries
+func (app *application) CustomerFile(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	token := r.Header.Get("X-Auth-Token")
+	file, err := accounts.ProcessAccount([]string{token, "acmt", "1021", r.URL.Query().Get("customerNumber")})
+	app.adminResponse(w, file, err)
+}
+
+// CustomerOpenAccount opens a further account for an existing customer
+func (app *application) CustomerOpenAccount(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	token := r.Header.Get("X-Auth-Token")
+	accountNumber, err := accounts.ProcessAccount([]string{token, "acmt", "1020", r.FormValue("customerNumber"), r.FormValue("currencyCode")})
+	app.adminResponse(w, accountNumber, err)
+}
+
+// CustomerKYCDocumentAdd files an identity document against a customer
+func (app *application) CustomerKYCDocumentAdd(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	document, err := customers.AddKYCDocument(customers.KYCDocument{
+		CustomerNumber:    r.FormValue("customerNumber"),
+		DocumentType:      r.FormValue("documentType"),
+		DocumentNumber:    r.FormValue("documentNumber"),
+		DocumentImagePath: r.FormValue("documentImagePath"),
+		ExpiryDate:        r.FormValue("expiryDate"),
+	})
+	app.adminResponse(w, document, err)
+}
+
+// CustomerKYCApprove approves a customer's KYC, moving their accounts out of PendingKYC
+func (app *application) CustomerKYCApprove(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	changes, err := customers.ApproveKYC(r.FormValue("customerNumber"), actor)
+	app.adminResponse(w, changes, err)
+}
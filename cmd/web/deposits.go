@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/deposits"
+	"github.com/shopspring/decimal"
+)
+
+// DepositProducts lists the term deposit products on offer with today's rates
+func (app *application) DepositProducts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	products, err := deposits.Products()
+	app.adminResponse(w, products, err)
+}
+
+// DepositProductCreate adds a term deposit product with its opening rate
+func (app *application) DepositProductCreate(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	var err error
+	product := deposits.Product{
+		Code:         r.FormValue("code"),
+		Name:         r.FormValue("name"),
+		CurrencyCode: r.FormValue("currencyCode"),
+	}
+	product.TermDays, err = strconv.Atoi(r.FormValue("termDays"))
+	if err != nil {
+		app.adminResponse(w, nil, errors.New("termDays must be a whole number of days"))
+		return
+	}
+	for field, value := range map[string]*decimal.Decimal{
+		"minAmount":        &product.MinAmount,
+		"maxAmount":        &product.MaxAmount,
+		"breakPenaltyRate": &product.BreakPenaltyRate,
+		"rate":             &product.Rate,
+	} {
+		*value, err = formDecimal(r, field)
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+	}
+
+	product, err = deposits.CreateProduct(product, creator)
+	app.adminResponse(w, product, err)
+}
+
+// DepositProductWithdraw stops a product being offered, booked deposits run on
+func (app *application) DepositProductWithdraw(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("productId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	err = deposits.WithdrawProduct(id)
+	app.adminResponse(w, "Product withdrawn", err)
+}
+
+// DepositProductRates lists a product's rates, the latest first
+func (app *application) DepositProductRates(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("productId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	rates, err := deposits.Rates(id)
+	app.adminResponse(w, rates, err)
+}
+
+// DepositProductRateSet changes a product's rate from effectiveFrom (YYYY-MM-DD), today when empty
+func (app *application) DepositProductRateSet(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("productId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	rate, err := deposits.SetRate(id, r.FormValue("rate"), r.FormValue("effectiveFrom"), creator)
+	app.adminResponse(w, rate, err)
+}
+
+// TermDeposits lists the term deposits booked from an account
+func (app *application) TermDeposits(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	accountDeposits, err := deposits.ForAccount(r.FormValue("accountNumber"))
+	app.adminResponse(w, accountDeposits, err)
+}
+
+// TermDepositBreak ends a term deposit early on the customer's behalf
+func (app *application) TermDepositBreak(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	deposit, err := deposits.Break(r.FormValue("depositNumber"), r.FormValue("reason"), actor)
+	app.adminResponse(w, deposit, err)
+}
+
+// formDecimal reads an optional decimal form value, empty is zero
+func formDecimal(r *http.Request, field string) (decimal.Decimal, error) {
+	value := strings.TrimSpace(r.FormValue(field))
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, errors.New(field + " is not a valid number")
+	}
+	return amount, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/dormancy"
+)
+
+// DormancyReport lists dormant accounts and their balances for unclaimed funds reporting
+func (app *application) DormancyReport(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	report, err := dormancy.Report()
+	app.adminResponse(w, report, err)
+}
+
+// DormancyNotices lists the dormancy notices sent for an account
+func (app *application) DormancyNotices(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	notices, err := dormancy.Notices(r.URL.Query().Get("accountNumber"))
+	app.adminResponse(w, notices, err)
+}
+
+// DormancyCheck runs the dormancy check now rather than waiting for the background job
+func (app *application) DormancyCheck(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	run, err := dormancy.CheckDormancy()
+	app.adminResponse(w, run, err)
+}
+
+// DormancyReactivate reactivates a dormant account once the holder's identity is re-verified
+func (app *application) DormancyReactivate(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	change, err := dormancy.Reactivate(r.FormValue("accountNumber"), r.FormValue("identificationNumber"), r.FormValue("dateOfBirth"), r.FormValue("reason"), actor)
+	app.adminResponse(w, change, err)
+}
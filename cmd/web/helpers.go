@@ -24,9 +24,13 @@ import (
 
 	"github.com/360EntSecGroup-Skylar/excelize"
 	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/rbac_2"
 	"github.com/ebitezion/backend-framework/internal/validator"
 	"github.com/julienschmidt/httprouter"
 	"github.com/jung-kurt/gofpdf"
+	"github.com/shopspring/decimal"
 )
 
 // Retrieve the "id" URL parameter from the current request context, then convert
@@ -223,6 +227,84 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// Privileges a back-office action needs, staff roles are held against the user in accounts_auth
+const (
+	privilegeOperations rbac_2.Privilege = "operations"
+	privilegeCredit     rbac_2.Privilege = "credit"
+	privilegeTreasury   rbac_2.Privilege = "treasury"
+)
+
+// staffRoles are the privileges each staff role holds. Customers hold none.
+var staffRoles = RolePrivileges{
+	"admin":      {privilegeOperations, privilegeCredit, privilegeTreasury},
+	"operations": {privilegeOperations},
+	"credit":     {privilegeCredit},
+	"treasury":   {privilegeTreasury},
+}
+
+func newStaffRBAC() *rbac_2.RBAC {
+	rbac := rbac_2.NewRBACWithDB()
+	for role, privileges := range staffRoles {
+		rbac.AddRole(role, privileges)
+	}
+	return rbac
+}
+
+// adminRequest checks the token belongs to staff holding privilege, parses the submitted form
+// and returns the staff user. Actions are recorded against the token user rather than a form
+// field so they can't be made under someone else's name.
+func (app *application) adminRequest(w http.ResponseWriter, r *http.Request, privilege rbac_2.Privilege) (user string, ok bool) {
+	token, err := app.getTokenFromHeader(w, r)
+	if err == nil {
+		user, err = appauth.GetUserFromToken(token)
+	}
+	if err == nil && !app.rbac.CheckPermission(user, privilege) {
+		err = errors.New("User does not have the required privilege")
+	}
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "07",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return "", false
+	}
+
+	// Parse form data
+	err = r.ParseMultipartForm(10 << 20)
+	if err != nil && err != http.ErrNotMultipart {
+		http.Error(w, "Failed to parse form data", http.StatusInternalServerError)
+		return "", false
+	}
+
+	return user, true
+}
+
+// adminResponse writes the result of a back-office action
+func (app *application) adminResponse(w http.ResponseWriter, message interface{}, err error) {
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
+
+	data := envelope{
+		"responseCode": "00",
+		"status":       "Success",
+		"message":      message,
+	}
+	app.writeJSON(w, http.StatusOK, data, nil)
+}
+
 // generateRandomNumber gives a random number of a given length
 func (app *application) generateRandomNumber(length int) (int, error) {
 	if length < 1 {
@@ -414,6 +496,7 @@ func RemoveSlashFromPath(path string) string {
 func newApplication() *application {
 	return &application{
 		templates: make(map[string]*template.Template),
+		rbac:      newStaffRBAC(),
 	}
 }
 func UploadImagesFromFile(Filename []string, w http.ResponseWriter, r *http.Request) (string, error) {
@@ -545,7 +628,7 @@ func createPdf(data interface{}) (string, error) {
 		pdf.Cell(0, 10, fmt.Sprintf("Transaction Type: %s", t.Transaction))
 		pdf.Ln(16)
 
-		pdf.Cell(0, 10, fmt.Sprintf("Transaction Amount: $%.2f", t.TransactionAmount))
+		pdf.Cell(0, 10, fmt.Sprintf("Transaction Amount: %s %s", t.CurrencyCode, currency.Format(t.CurrencyCode, decimal.NewFromFloat(t.TransactionAmount))))
 		pdf.Ln(16)
 
 		pdf.Cell(0, 10, fmt.Sprintf("Sender Account: %s, Bank: %s", t.SenderAccountNumber, t.SenderBankNumber))
@@ -554,7 +637,7 @@ func createPdf(data interface{}) (string, error) {
 		pdf.Cell(0, 10, fmt.Sprintf("Receiver Account: %s, Bank: %s", t.ReceiverAccountNumber, t.ReceiverBankNumber))
 		pdf.Ln(16)
 
-		pdf.Cell(0, 10, fmt.Sprintf("Fee Amount: $%.2f", t.FeeAmount))
+		pdf.Cell(0, 10, fmt.Sprintf("Fee Amount: %s %s", t.CurrencyCode, currency.Format(t.CurrencyCode, decimal.NewFromFloat(t.FeeAmount))))
 		pdf.Ln(16)
 
 		pdf.Cell(0, 10, fmt.Sprintf("Fee Amount: $%s", t.Narration))
@@ -575,6 +658,48 @@ func createPdf(data interface{}) (string, error) {
 	fmt.Println("PDF created successfully!")
 	return pdfPath, nil
 }
+
+// writeClosureCertificate writes the certificate confirming an account's closure as a PDF
+func writeClosureCertificate(w io.Writer, closure accounts.AccountClosure) error {
+	pdf := gofpdf.New(gofpdf.OrientationPortrait, gofpdf.UnitPoint, gofpdf.PageSizeA4, "")
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Arial", "B", 16)
+		pdf.Cell(0, 10, "Nouveau Mobile Account Closure Certificate")
+		pdf.Ln(40)
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Arial", "", 12)
+
+	pdf.MultiCell(0, 16, fmt.Sprintf("This certifies that the account below was closed on %s.", closure.ClosedAt.Format("2 January 2006")), "", "", false)
+	pdf.Ln(16)
+
+	lines := [][]string{
+		{"Account Holder", closure.AccountHolderName},
+		{"Account Number", closure.AccountNumber},
+		{"Currency", closure.CurrencyCode},
+		{"Balance at Closure", currency.Format(closure.CurrencyCode, closure.ClosingBalance)},
+		{"Closure Fee", currency.Format(closure.CurrencyCode, closure.FeeAmount)},
+		{"Balance Transferred", currency.Format(closure.CurrencyCode, closure.SweptAmount)},
+		{"Transferred To", closure.SweptTo},
+		{"Reason", closure.Reason},
+		{"Closed By", closure.Actor},
+		{"Closed At", closure.ClosedAt.Format("2006-01-02 15:04:05")},
+	}
+	for _, line := range lines {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(160, 10, line[0]+":")
+		pdf.SetFont("Arial", "", 12)
+		pdf.Cell(0, 10, line[1])
+		pdf.Ln(20)
+	}
+
+	pdf.Ln(20)
+	pdf.MultiCell(0, 16, "No further transactions can be made on this account.", "", "", false)
+
+	return pdf.Output(w)
+}
+
 func createExcelSheet(data interface{}) (string, error) {
 
 	//Unmarshal JSON data
@@ -594,7 +719,7 @@ func createExcelSheet(data interface{}) (string, error) {
 	index := f.NewSheet("TransactionSheet")
 
 	// Set column headers
-	headers := []string{"ID", "Transaction", "Type", "SenderAccountNumber", "SenderBankNumber", "ReceiverAccountNumber", "ReceiverBankNumber", "TransactionAmount", "FeeAmount", "Timestamp", "Narration"}
+	headers := []string{"ID", "Transaction", "Type", "SenderAccountNumber", "SenderBankNumber", "ReceiverAccountNumber", "ReceiverBankNumber", "CurrencyCode", "TransactionAmount", "FeeAmount", "Timestamp", "Narration"}
 	for col, header := range headers {
 		cell := fmt.Sprintf("%c%d", 'A'+col, 1)
 		f.SetCellValue("TransactionSheet", cell, header)
@@ -623,6 +748,12 @@ func createExcelSheet(data interface{}) (string, error) {
 
 // Helper function to get field value using reflection
 func getFieldValue(transaction accounts.Transaction, field string) interface{} {
+	// Amounts are exported in the minor units of the transaction's currency
+	if field == "TransactionAmount" || field == "FeeAmount" {
+		amount := decimal.NewFromFloat(reflect.ValueOf(transaction).FieldByName(field).Float())
+		return currency.Round(transaction.CurrencyCode, amount).InexactFloat64()
+	}
+
 	r := reflect.ValueOf(transaction)
 	f := reflect.Indirect(r).FieldByName(field)
 	return f.Interface()
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/interest"
+	"github.com/shopspring/decimal"
+)
+
+// InterestProducts lists the savings interest products on offer with today's rate tables
+func (app *application) InterestProducts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	products, err := interest.Products()
+	app.adminResponse(w, products, err)
+}
+
+// InterestProductCreate adds a savings interest product. Tiers are given as
+// minBalance:rate pairs separated by commas, e.g. 0:1.5,100000:3
+func (app *application) InterestProductCreate(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	var err error
+	product := interest.Product{
+		Code:         r.FormValue("code"),
+		Name:         r.FormValue("name"),
+		CurrencyCode: r.FormValue("currencyCode"),
+	}
+	product.WithholdingTaxRate, err = formDecimal(r, "withholdingTaxRate")
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+	product.Tiers, err = formTiers(r)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	product, err = interest.CreateProduct(product, creator)
+	app.adminResponse(w, product, err)
+}
+
+// InterestProductWithdraw stops a product taking new accounts
+func (app *application) InterestProductWithdraw(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("productId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	err = interest.WithdrawProduct(id)
+	app.adminResponse(w, "Product withdrawn", err)
+}
+
+// InterestProductTiers replaces a product's rate table from effectiveFrom (YYYY-MM-DD), today when empty
+func (app *application) InterestProductTiers(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("productId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+	tiers, err := formTiers(r)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	product, err := interest.SetTiers(id, tiers, r.FormValue("effectiveFrom"), creator)
+	app.adminResponse(w, product, err)
+}
+
+// InterestAccount returns an account's interest product, this month's accruals and the interest paid
+func (app *application) InterestAccount(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	summary, err := interest.AccountSummary(r.FormValue("accountNumber"))
+	app.adminResponse(w, summary, err)
+}
+
+// InterestAccountEnrol starts an account earning interest under a product
+func (app *application) InterestAccountEnrol(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("productId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	product, err := interest.Enrol(r.FormValue("accountNumber"), id, creator)
+	app.adminResponse(w, product, err)
+}
+
+// InterestAccruals lists an account's daily accruals between from and to (YYYY-MM-DD, inclusive)
+func (app *application) InterestAccruals(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	accruals, err := interest.Accruals(r.FormValue("accountNumber"), r.FormValue("from"), r.FormValue("to"))
+	app.adminResponse(w, accruals, err)
+}
+
+// InterestRun accrues today's interest and capitalises the months before it. Months that
+// have already been capitalised are not posted twice.
+func (app *application) InterestRun(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	run, err := interest.RunAccruals()
+	app.adminResponse(w, run, err)
+}
+
+// formTiers reads minBalance:rate pairs separated by commas from the tiers form value
+func formTiers(r *http.Request) ([]interest.Tier, error) {
+	tiers := make([]interest.Tier, 0)
+	for _, pair := range strings.Split(r.FormValue("tiers"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, errors.New("tiers must be minBalance:rate pairs separated by commas")
+		}
+		minBalance, err := decimal.NewFromString(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, errors.New("tier minimum balance " + parts[0] + " is not a valid number")
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, errors.New("tier rate " + parts[1] + " is not a valid number")
+		}
+		tiers = append(tiers, interest.Tier{MinBalance: minBalance, Rate: rate})
+	}
+	return tiers, nil
+}
@@ -3,21 +3,34 @@ package main
 import (
 	"net/http"
 
+	"github.com/ebitezion/backend-framework/internal/allocation"
 	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
 )
 
 // GenerateUKAccountNumberHandler to create an internal account no address system compliant with the UK
 func (app *application) GenerateUKAccountNumberHandler(w http.ResponseWriter, r *http.Request) {
 	generator := ukaccountgen.New()
-	accountNumber := generator.GenerateUKAccountNumber()
+	accountNumber, err := allocation.AllocateUK("internal account")
+	if err != nil {
+		// there was error
+		data := envelope{
+			"responseCode": "06",
+			"status":       "Failed",
+			"message":      err.Error(),
+		}
+
+		app.writeJSON(w, http.StatusBadRequest, data, nil)
+		return
+	}
 
 	env := envelope{
 		"responseCode":          "00",
 		"status":                "success",
 		"internalAccountNumber": accountNumber,
+		"sortCode":              generator.SortCode,
 	}
 
-	err := app.writeJSON(w, http.StatusOK, env, nil)
+	err = app.writeJSON(w, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
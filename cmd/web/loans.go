@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ebitezion/backend-framework/internal/loans"
+)
+
+// Loans lists an account's loans when accountNumber is given, otherwise the loans in a
+// status, applications waiting for a decision by default
+func (app *application) Loans(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeCredit); !ok {
+		return
+	}
+
+	if r.FormValue("accountNumber") != "" {
+		accountLoans, err := loans.ForAccount(r.FormValue("accountNumber"))
+		app.adminResponse(w, accountLoans, err)
+		return
+	}
+	statusLoans, err := loans.Loans(r.FormValue("status"))
+	app.adminResponse(w, statusLoans, err)
+}
+
+// LoanApprove grants an application with an amount, an annual rate, a tenor in months, a
+// repayment method (reducing_balance or flat) and a late fee, and disburses it. The amount
+// and tenor default to what was asked for.
+func (app *application) LoanApprove(w http.ResponseWriter, r *http.Request) {
+	approver, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	var err error
+	tenorMonths := 0
+	if r.FormValue("tenorMonths") != "" {
+		tenorMonths, err = strconv.Atoi(r.FormValue("tenorMonths"))
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+	}
+
+	loan, err := loans.Approve(r.FormValue("loanNumber"), r.FormValue("amount"), r.FormValue("rate"), tenorMonths, r.FormValue("method"), r.FormValue("lateFee"), approver)
+	app.adminResponse(w, loan, err)
+}
+
+// LoanDecline turns down an application
+func (app *application) LoanDecline(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	loan, err := loans.Decline(r.FormValue("loanNumber"), r.FormValue("reason"), actor)
+	app.adminResponse(w, loan, err)
+}
+
+// LoanCancel withdraws an application or an approved loan that hasn't been disbursed
+func (app *application) LoanCancel(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	loan, err := loans.Cancel(r.FormValue("loanNumber"), r.FormValue("reason"), actor)
+	app.adminResponse(w, loan, err)
+}
+
+// LoanDisburse pays out an approved loan whose disbursal failed when it was approved
+func (app *application) LoanDisburse(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	loan, err := loans.Disburse(r.FormValue("loanNumber"), actor)
+	app.adminResponse(w, loan, err)
+}
+
+// LoanStatement returns a loan's schedule, repayments and what is owed and in arrears
+func (app *application) LoanStatement(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeCredit); !ok {
+		return
+	}
+
+	statement, err := loans.LoanStatement(r.FormValue("loanNumber"))
+	app.adminResponse(w, statement, err)
+}
+
+// LoanRepay takes a repayment from the loan's account on the customer's behalf
+func (app *application) LoanRepay(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	repayment, err := loans.Repay(r.FormValue("loanNumber"), r.FormValue("amount"), actor)
+	app.adminResponse(w, repayment, err)
+}
+
+// LoanRun collects the instalments that have fallen due and charges late fees now rather
+// than waiting for the background job
+func (app *application) LoanRun(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeCredit); !ok {
+		return
+	}
+
+	run, err := loans.RunRepayments()
+	app.adminResponse(w, run, err)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ebitezion/backend-framework/internal/mandates"
+)
+
+// AccountMandate returns an account's holders and signing rule
+func (app *application) AccountMandate(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	mandate, err := mandates.Get(r.URL.Query().Get("accountNumber"))
+	app.adminResponse(w, mandate, err)
+}
+
+// AccountMandateSet changes an account's signing rule to any_one, any_two or all
+func (app *application) AccountMandateSet(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	mandate, err := mandates.Set(r.FormValue("accountNumber"), r.FormValue("rule"), creator)
+	app.adminResponse(w, mandate, err)
+}
+
+// AccountHolderAdd links a customer to an account as a secondary holder or signatory
+func (app *application) AccountHolderAdd(w http.ResponseWriter, r *http.Request) {
+	creator, ok := app.adminRequest(w, r, privilegeOperations)
+	if !ok {
+		return
+	}
+
+	holder, err := mandates.AddHolder(r.FormValue("accountNumber"), r.FormValue("customerNumber"), r.FormValue("role"), creator)
+	app.adminResponse(w, holder, err)
+}
+
+// AccountHolderRemove unlinks a secondary holder or signatory from an account
+func (app *application) AccountHolderRemove(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeOperations); !ok {
+		return
+	}
+
+	err := mandates.RemoveHolder(r.FormValue("accountNumber"), r.FormValue("customerNumber"))
+	app.adminResponse(w, "Holder removed", err)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ebitezion/backend-framework/internal/overdrafts"
+)
+
+// Overdrafts lists an account's overdrafts when accountNumber is given, otherwise the
+// overdrafts in a status, applications waiting for a decision by default
+func (app *application) Overdrafts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeCredit); !ok {
+		return
+	}
+
+	if r.FormValue("accountNumber") != "" {
+		facilities, err := overdrafts.ForAccount(r.FormValue("accountNumber"))
+		app.adminResponse(w, facilities, err)
+		return
+	}
+	facilities, err := overdrafts.Facilities(r.FormValue("status"))
+	app.adminResponse(w, facilities, err)
+}
+
+// OverdraftApprove grants an application with a limit, an annual debit interest rate, an
+// arrangement fee and an expiry date (YYYY-MM-DD)
+func (app *application) OverdraftApprove(w http.ResponseWriter, r *http.Request) {
+	approver, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("overdraftId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	facility, err := overdrafts.Approve(id, r.FormValue("limit"), r.FormValue("rate"), r.FormValue("arrangementFee"), r.FormValue("expiryDate"), approver)
+	app.adminResponse(w, facility, err)
+}
+
+// OverdraftDecline turns down an application
+func (app *application) OverdraftDecline(w http.ResponseWriter, r *http.Request) {
+	approver, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("overdraftId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	facility, err := overdrafts.Decline(id, r.FormValue("reason"), approver)
+	app.adminResponse(w, facility, err)
+}
+
+// OverdraftAmend changes an active overdraft's limit and, when given, its expiry date
+func (app *application) OverdraftAmend(w http.ResponseWriter, r *http.Request) {
+	approver, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("overdraftId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	facility, err := overdrafts.Amend(id, r.FormValue("limit"), r.FormValue("expiryDate"), approver)
+	app.adminResponse(w, facility, err)
+}
+
+// OverdraftCancel withdraws an overdraft, the account's limit drops to zero straight away
+func (app *application) OverdraftCancel(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.adminRequest(w, r, privilegeCredit)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("overdraftId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	facility, err := overdrafts.Cancel(id, r.FormValue("reason"), actor)
+	app.adminResponse(w, facility, err)
+}
+
+// OverdraftCharges lists the arrangement fee and interest charged under an overdraft
+func (app *application) OverdraftCharges(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeCredit); !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("overdraftId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	charges, err := overdrafts.Charges(id)
+	app.adminResponse(w, charges, err)
+}
+
+// OverdraftRun expires overdrafts past their expiry date and charges today's interest. A day
+// already charged is not charged twice.
+func (app *application) OverdraftRun(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeCredit); !ok {
+		return
+	}
+
+	run, err := overdrafts.RunCharges()
+	app.adminResponse(w, run, err)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -197,6 +198,17 @@ func (app *application) PaymentCreditInitiation(w http.ResponseWriter, r *http.R
 
 	response, err := payments.ProcessPAIN([]string{token, "pain", "1", sendersDetails, receiversDetails, amount, "CR"})
 
+	if errors.Is(err, payments.ErrPaymentHeld) {
+		// The transfer waits on the other account holders, it has not failed
+		data := envelope{
+			"responseCode": "00",
+			"status":       "Pending",
+			"message":      response,
+		}
+		app.writeJSON(w, http.StatusAccepted, data, nil)
+		return
+	}
+
 	if err != nil {
 		// there was error
 		data := envelope{
@@ -249,6 +261,16 @@ func (app *application) PaymentDebitInitiation(w http.ResponseWriter, r *http.Re
 	fmt.Println(sendersAccountNumber, receiversAccountNumber)
 
 	response, err := payments.ProcessPAIN([]string{token, "pain", "1", sendersDetails, receiversDetails, amount, "DR"})
+	if errors.Is(err, payments.ErrPaymentHeld) {
+		// The transfer waits on the other account holders, it has not failed
+		data := envelope{
+			"responseCode": "00",
+			"status":       "Pending",
+			"message":      response,
+		}
+		app.writeJSON(w, http.StatusAccepted, data, nil)
+		return
+	}
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -157,6 +157,9 @@ func (app *application) RenderUkAccountPage(w http.ResponseWriter, r *http.Reque
 func (app *application) RenderUsAccountPage(w http.ResponseWriter, r *http.Request) {
 	app.RenderTemplate(w, []string{"cmd/web/views/usAccountPage.html", "cmd/web/views/header.html", "cmd/web/views/footer.html"}, nil, "cmd/web/views/usAccountPage.html", nil)
 }
+func (app *application) RenderTreasuryPage(w http.ResponseWriter, r *http.Request) {
+	app.RenderTemplate(w, []string{"cmd/web/views/treasury.html", "cmd/web/views/header.html", "cmd/web/views/footer.html"}, nil, "cmd/web/views/treasury.html", nil)
+}
 func (app *application) RenderCashPickupPage(w http.ResponseWriter, r *http.Request) {
 	app.RenderTemplate(w, []string{"cmd/web/views/cashPickup.html", "cmd/web/views/header.html", "cmd/web/views/footer.html"}, nil, "cmd/web/views/cashPickup.html", nil)
 }
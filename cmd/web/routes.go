@@ -44,10 +44,11 @@ func (app *application) routes() *httprouter.Router {
 	router.HandlerFunc(http.MethodGet, "/v1/teamsPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderTeamsPage)))
 	router.HandlerFunc(http.MethodGet, "/v1/rolesPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderRolesPage)))
 	router.HandlerFunc(http.MethodGet, "/v1/systemLogsPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderSystemLogsPage)))
-	router.HandlerFunc(http.MethodGet, "/v1/usAccountPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderUkAccountPage)))
-	router.HandlerFunc(http.MethodGet, "/v1/ukAccountPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderUsAccountPage)))
+	router.HandlerFunc(http.MethodGet, "/v1/usAccountPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderUsAccountPage)))
+	router.HandlerFunc(http.MethodGet, "/v1/ukAccountPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderUkAccountPage)))
 	router.HandlerFunc(http.MethodGet, "/v1/cashPickupPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderCashPickupPage)))
 	router.HandlerFunc(http.MethodGet, "/v1/withdrawalPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderWithdrawalPage)))
+	router.HandlerFunc(http.MethodGet, "/v1/treasuryPage", app.AuthenticationMiddleware(http.HandlerFunc(app.RenderTreasuryPage)))
 	// Likewise, convert the methodNotAllowedResponse() helper to a http.Handler and set
 	// it as the custom error handler for 405 Method Not Allowed responses.
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
@@ -73,6 +74,7 @@ func (app *application) routes() *httprouter.Router {
 	router.HandlerFunc(http.MethodPost, "/v1/debit", app.PaymentDebitInitiation)
 	router.HandlerFunc(http.MethodPost, "/v1/balanceEnquiry", app.BalanceEnquiry)
 	router.HandlerFunc(http.MethodPost, "/v1/accountHistory", app.AccountHistory)
+	router.HandlerFunc(http.MethodPost, "/v1/accountStatement", app.AccountStatement)
 	router.HandlerFunc(http.MethodGet, "/v1/allTransactions", app.AllTransactions)
 
 	//@TODO i have to update the frontend to call the backend then it should be able to download
@@ -86,8 +88,82 @@ func (app *application) routes() *httprouter.Router {
 	router.HandlerFunc(http.MethodPost, "/v1/accounts/update", app.AccountUpdate)
 	router.HandlerFunc(http.MethodPost, "/v1/accounts/block", app.BlockAccount)
 	router.HandlerFunc(http.MethodPost, "/v1/accounts/unblock", app.UnblockAccount)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/status", app.AccountStatus)
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/statusHistory", app.AccountStatusHistory)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/close", app.AccountClose)
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/closureCertificate", app.AccountClosureCertificate)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/us", app.IssueUSAccount)
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/reservations", app.AccountNumberReservations)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/reservations", app.AccountNumberReserve)
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/dormancy/report", app.DormancyReport)
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/dormancy/notices", app.DormancyNotices)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/dormancy/check", app.DormancyCheck)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/dormancy/reactivate", app.DormancyReactivate)
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/mandate", app.AccountMandate)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/mandate", app.AccountMandateSet)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/holders", app.AccountHolderAdd)
+	router.HandlerFunc(http.MethodPost, "/v1/accounts/holders/remove", app.AccountHolderRemove)
+	//Customers
+	router.HandlerFunc(http.MethodGet, "/v1/customers", app.CustomerFile)
+	router.HandlerFunc(http.MethodPost, "/v1/customers/accounts", app.CustomerOpenAccount)
+	router.HandlerFunc(http.MethodPost, "/v1/customers/kyc", app.CustomerKYCDocumentAdd)
+	router.HandlerFunc(http.MethodPost, "/v1/customers/kyc/approve", app.CustomerKYCApprove)
+	//Term deposits
+	router.HandlerFunc(http.MethodGet, "/v1/deposits", app.TermDeposits)
+	router.HandlerFunc(http.MethodPost, "/v1/deposits/break", app.TermDepositBreak)
+	router.HandlerFunc(http.MethodGet, "/v1/deposits/products", app.DepositProducts)
+	router.HandlerFunc(http.MethodPost, "/v1/deposits/products", app.DepositProductCreate)
+	router.HandlerFunc(http.MethodPost, "/v1/deposits/products/withdraw", app.DepositProductWithdraw)
+	router.HandlerFunc(http.MethodGet, "/v1/deposits/products/rates", app.DepositProductRates)
+	router.HandlerFunc(http.MethodPost, "/v1/deposits/products/rates", app.DepositProductRateSet)
+	//Savings interest
+	router.HandlerFunc(http.MethodGet, "/v1/interest/products", app.InterestProducts)
+	router.HandlerFunc(http.MethodPost, "/v1/interest/products", app.InterestProductCreate)
+	router.HandlerFunc(http.MethodPost, "/v1/interest/products/withdraw", app.InterestProductWithdraw)
+	router.HandlerFunc(http.MethodPost, "/v1/interest/products/tiers", app.InterestProductTiers)
+	router.HandlerFunc(http.MethodGet, "/v1/interest/accounts", app.InterestAccount)
+	router.HandlerFunc(http.MethodPost, "/v1/interest/accounts", app.InterestAccountEnrol)
+	router.HandlerFunc(http.MethodGet, "/v1/interest/accruals", app.InterestAccruals)
+	router.HandlerFunc(http.MethodPost, "/v1/interest/run", app.InterestRun)
+	//Overdrafts
+	router.HandlerFunc(http.MethodGet, "/v1/overdrafts", app.Overdrafts)
+	router.HandlerFunc(http.MethodPost, "/v1/overdrafts/approve", app.OverdraftApprove)
+	router.HandlerFunc(http.MethodPost, "/v1/overdrafts/decline", app.OverdraftDecline)
+	router.HandlerFunc(http.MethodPost, "/v1/overdrafts/amend", app.OverdraftAmend)
+	router.HandlerFunc(http.MethodPost, "/v1/overdrafts/cancel", app.OverdraftCancel)
+	router.HandlerFunc(http.MethodGet, "/v1/overdrafts/charges", app.OverdraftCharges)
+	router.HandlerFunc(http.MethodPost, "/v1/overdrafts/run", app.OverdraftRun)
+	//Loans
+	router.HandlerFunc(http.MethodGet, "/v1/loans", app.Loans)
+	router.HandlerFunc(http.MethodPost, "/v1/loans/approve", app.LoanApprove)
+	router.HandlerFunc(http.MethodPost, "/v1/loans/decline", app.LoanDecline)
+	router.HandlerFunc(http.MethodPost, "/v1/loans/cancel", app.LoanCancel)
+	router.HandlerFunc(http.MethodPost, "/v1/loans/disburse", app.LoanDisburse)
+	router.HandlerFunc(http.MethodGet, "/v1/loans/statement", app.LoanStatement)
+	router.HandlerFunc(http.MethodPost, "/v1/loans/repay", app.LoanRepay)
+	router.HandlerFunc(http.MethodPost, "/v1/loans/run", app.LoanRun)
 	//Currency Exchange
 	router.HandlerFunc(http.MethodGet, "/v1/availableCurrencies", app.AvailableCurrenciesHandler)
+	//Cash agents
+	router.HandlerFunc(http.MethodPost, "/v1/agents/create", app.AgentCreate)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/location", app.AgentAddLocation)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/commission", app.AgentCommissionRule)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/limits", app.AgentLimits)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/status", app.AgentStatus)
+	router.HandlerFunc(http.MethodGet, "/v1/agents", app.AllAgents)
+	router.HandlerFunc(http.MethodGet, "/v1/agents/topUps", app.AgentPendingTopUps)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/topUps/approve", app.AgentApproveTopUp)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/topUps/reject", app.AgentRejectTopUp)
+	router.HandlerFunc(http.MethodPost, "/v1/agents/settlement", app.AgentSettlements)
+	//Treasury
+	router.HandlerFunc(http.MethodGet, "/v1/treasury/rates", app.TreasuryRates)
+	router.HandlerFunc(http.MethodPost, "/v1/treasury/rateChanges/propose", app.TreasuryProposeRateChange)
+	router.HandlerFunc(http.MethodGet, "/v1/treasury/rateChanges", app.TreasuryRateChanges)
+	router.HandlerFunc(http.MethodPost, "/v1/treasury/rateChanges/approve", app.TreasuryApproveRateChange)
+	router.HandlerFunc(http.MethodPost, "/v1/treasury/rateChanges/reject", app.TreasuryRejectRateChange)
+	router.HandlerFunc(http.MethodPost, "/v1/treasury/rateChanges/cancel", app.TreasuryCancelRateChange)
+	router.HandlerFunc(http.MethodGet, "/v1/treasury/fxTransactions", app.TreasuryFXTransactions)
+	router.HandlerFunc(http.MethodGet, "/v1/treasury/audit", app.TreasuryAuditTrail)
 
 	return router
 }
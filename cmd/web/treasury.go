@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/treasury"
+)
+
+// TreasuryRates lists the manual rates currently in force with their buy and sell rates
+func (app *application) TreasuryRates(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeTreasury); !ok {
+		return
+	}
+
+	rates, err := treasury.CurrentRates()
+	app.adminResponse(w, rates, err)
+}
+
+// TreasuryProposeRateChange submits a rate change for approval. Either rate and margin or
+// buyRate and sellRate are given, effectiveAt (YYYY-MM-DDTHH:MM, UTC) schedules it for later.
+func (app *application) TreasuryProposeRateChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminRequest(w, r, privilegeTreasury)
+	if !ok {
+		return
+	}
+
+	change, err := treasury.NewRateChange(r.FormValue("baseCurrency"), r.FormValue("quoteCurrency"), r.FormValue("rate"), r.FormValue("margin"), r.FormValue("buyRate"), r.FormValue("sellRate"), r.FormValue("effectiveAt"))
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	change, err = treasury.ProposeRateChange(user, change)
+	app.adminResponse(w, change, err)
+}
+
+// TreasuryRateChanges lists rate changes, optionally filtered by status
+func (app *application) TreasuryRateChanges(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeTreasury); !ok {
+		return
+	}
+
+	changes, err := treasury.RateChanges(r.FormValue("status"))
+	app.adminResponse(w, changes, err)
+}
+
+// TreasuryApproveRateChange approves a pending rate change, applying it straight away when it is already effective
+func (app *application) TreasuryApproveRateChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminRequest(w, r, privilegeTreasury)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("changeId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	change, err := treasury.ApproveRateChange(user, id, r.FormValue("note"))
+	app.adminResponse(w, change, err)
+}
+
+// TreasuryRejectRateChange rejects a pending rate change
+func (app *application) TreasuryRejectRateChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminRequest(w, r, privilegeTreasury)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("changeId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	change, err := treasury.RejectRateChange(user, id, r.FormValue("note"))
+	app.adminResponse(w, change, err)
+}
+
+// TreasuryCancelRateChange withdraws a rate change the caller proposed
+func (app *application) TreasuryCancelRateChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminRequest(w, r, privilegeTreasury)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("changeId"), 10, 64)
+	if err != nil {
+		app.adminResponse(w, nil, err)
+		return
+	}
+
+	change, err := treasury.CancelRateChange(user, id)
+	app.adminResponse(w, change, err)
+}
+
+// TreasuryFXTransactions lists the FX quotes accepted between from and to (YYYY-MM-DD, inclusive)
+// with the rate, spread and rate snapshot each one used. Both default to today.
+func (app *application) TreasuryFXTransactions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeTreasury); !ok {
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start, end := today, today
+	var err error
+	if r.FormValue("from") != "" {
+		start, err = time.Parse("2006-01-02", r.FormValue("from"))
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+	}
+	if r.FormValue("to") != "" {
+		end, err = time.Parse("2006-01-02", r.FormValue("to"))
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+	}
+
+	quotes, err := treasury.FXTransactions(start, end.AddDate(0, 0, 1))
+	app.adminResponse(w, quotes, err)
+}
+
+// TreasuryAuditTrail returns the audit log for one rate change, or every rate change when no id is given
+func (app *application) TreasuryAuditTrail(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.adminRequest(w, r, privilegeTreasury); !ok {
+		return
+	}
+
+	var id int64
+	if r.FormValue("changeId") != "" {
+		var err error
+		id, err = strconv.ParseInt(r.FormValue("changeId"), 10, 64)
+		if err != nil {
+			app.adminResponse(w, nil, err)
+			return
+		}
+	}
+
+	entries, err := treasury.AuditTrail(id)
+	app.adminResponse(w, entries, err)
+}
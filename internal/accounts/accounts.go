@@ -16,10 +16,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ebitezion/backend-framework/internal/allocation"
 	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/customers"
 	"github.com/ebitezion/backend-framework/internal/data"
-	"github.com/ebitezion/backend-framework/internal/nuban"
-	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
+	"github.com/ebitezion/backend-framework/internal/iban"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/pots"
+	"github.com/ebitezion/backend-framework/internal/usacctgen"
 	"github.com/shopspring/decimal"
 )
 
@@ -70,6 +76,15 @@ Accounts (acmt) transactions are as follows:
 1010 - BlockAccount
 1011 - UnblockAccount
 1012 - OutflowHistory
+1013 - OpenCurrencyAccount
+1014 - HolderAccounts
+1015 - IssueUSAccount
+1016 - AccountStatement
+1017 - UpdateAccountStatus
+1018 - AccountStatusHistory
+1019 - AccountClosure
+1020 - OpenCustomerAccount
+1021 - CustomerFile
 
 */
 
@@ -115,6 +130,7 @@ type SpecialAccountDetails struct {
 	AccountNumber     string
 	BankNumber        string
 	AccountHolderName string
+	CurrencyCode      string
 	Status            string
 	AccountBalance    decimal.Decimal
 	Overdraft         decimal.Decimal
@@ -129,10 +145,16 @@ type AccountProfile struct {
 	BankNumber    string
 }
 
+// AccountDetails describes a single account. A customer holds a primary account and may open
+// further accounts in other currencies, which point back to it with PrimaryAccountNumber.
+// Every account belongs to the customer with CustomerNumber.
 type AccountDetails struct {
 	AccountNumber        string
 	BankNumber           string
+	CustomerNumber       string
 	AccountHolderName    string
+	CurrencyCode         string
+	PrimaryAccountNumber string
 	Status               string
 	AccountBalance       decimal.Decimal
 	Overdraft            decimal.Decimal
@@ -141,10 +163,34 @@ type AccountDetails struct {
 }
 
 type BalanceEnquiry struct {
-	AccountHolderName string `json:"accountHolderName"`
-	AccountNumber     string `json:"accountNumber"`
-	LedgerBalance     string `json:"ledgerBalance"`
-	Status            string `json:"status"`
+	AccountHolderName string       `json:"accountHolderName"`
+	AccountNumber     string       `json:"accountNumber"`
+	LedgerBalance     string       `json:"ledgerBalance"`
+	AvailableBalance  string       `json:"availableBalance"`
+	OverdraftLimit    string       `json:"overdraftLimit,omitempty"`
+	CurrencyCode      string       `json:"currencyCode"`
+	Status            string       `json:"status"`
+	IBAN              string       `json:"iban,omitempty"`
+	BIC               string       `json:"bic,omitempty"`
+	Pots              []PotBalance `json:"pots,omitempty"`
+}
+
+// PotBalance is a savings pot as shown on a balance enquiry. Pot balances are part of the
+// ledger balance but not the available balance.
+type PotBalance struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Balance      string `json:"balance"`
+	TargetAmount string `json:"targetAmount,omitempty"`
+	TargetDate   string `json:"targetDate,omitempty"`
+	Progress     string `json:"progress,omitempty"`
+	RoundUpTo    string `json:"roundUpTo,omitempty"`
+}
+
+// AccountStatement is an account's details with its transactions
+type AccountStatement struct {
+	BalanceEnquiry
+	Transactions []Transaction `json:"transactions"`
 }
 
 type Transaction struct {
@@ -155,6 +201,7 @@ type Transaction struct {
 	SenderBankNumber      string  `json:"senderBankNumber"`
 	ReceiverAccountNumber string  `json:"receiverAccountNumber"`
 	ReceiverBankNumber    string  `json:"receiverBankNumber"`
+	CurrencyCode          string  `json:"currencyCode"`
 	TransactionAmount     float64 `json:"transactionAmount"`
 	Narration             string  `json:"narration"`
 	FeeAmount             float64 `json:"feeAmount"`
@@ -299,6 +346,102 @@ func ProcessAccount(data []string) (result interface{}, err error) {
 			return "", errors.New("accounts.ProcessAccount: " + err.Error())
 		}
 		break
+	case 1013:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = openCurrencyAccount(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1014:
+		result, err = fetchHolderAccounts(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1015:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = issueUSAccount(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1016:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = fetchAccountStatement(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 19:
+		if len(data) < 6 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = closeAccount(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1017:
+		if len(data) < 6 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = updateAccountStatus(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1018:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = fetchAccountStatusHistory(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1019:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = fetchAccountClosure(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1020:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = openCustomerAccount(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
+	case 1021:
+		if len(data) < 4 {
+			err = errors.New("accounts.ProcessAccount: Not all fields present")
+			return
+		}
+		result, err = fetchCustomer(data)
+		if err != nil {
+			return "", errors.New("accounts.ProcessAccount: " + err.Error())
+		}
+		break
 
 	default:
 		err = errors.New("accounts.ProcessAccount: ACMT transaction code invalid")
@@ -308,93 +451,128 @@ func ProcessAccount(data []string) (result interface{}, err error) {
 
 	return
 }
-func unblockAccount(data []string) (result string, err error) {
-	// Validate string against required info/length
-	if len(data) < 2 {
-		err := errors.New("accounts.closeAccount: Not all fields present")
-		return "", err
-	}
 
-	// Check if account already exists, check on ID number
-	accountHolder, _ := getAccountMeta(data[3])
-	if accountHolder.AccountNumber == "" {
-		return "", errors.New("accounts.closeAccount: Account does not exist. " + accountHolder.AccountNumber)
+// unblockAccount returns a frozen account to Active.
+// Format: token~acmt~1011~accountNumber~reason
+func unblockAccount(data []string) (result string, err error) {
+	if len(data) < 4 {
+		return "", errors.New("accounts.unblockAccount: Not all fields present")
 	}
 
-	err = doUnblockAccount(accountHolder.AccountNumber)
+	_, err = changeAccountStatus(data[0], data[3], lifecycle.Active, reasonFromData(data, 4, "Unblocked"))
 	if err != nil {
-		return "", errors.New("accounts.closeAccount: " + err.Error())
+		return "", errors.New("accounts.unblockAccount: " + err.Error())
 	}
 
 	return "Account Unblocked Successfully", nil
 }
+
+// blockAccount freezes an account so nothing can be posted to it.
+// Format: token~acmt~1010~accountNumber~reason
 func blockAccount(data []string) (result string, err error) {
-	// Validate string against required info/length
-	if len(data) < 2 {
-		err := errors.New("accounts.closeAccount: Not all fields present")
-		return "", err
-	}
-	fmt.Println(data[3])
-	// Check if account already exists, check on ID number
-	accountHolder, _ := getAccountMeta(data[3])
-	if accountHolder.AccountNumber == "" {
-		return "", errors.New("accounts.closeAccount: Account does not exist. " + accountHolder.AccountNumber)
+	if len(data) < 4 {
+		return "", errors.New("accounts.blockAccount: Not all fields present")
 	}
 
-	err = doBlockAccount(accountHolder.AccountNumber)
+	_, err = changeAccountStatus(data[0], data[3], lifecycle.Frozen, reasonFromData(data, 4, "Blocked"))
 	if err != nil {
-		return "", errors.New("accounts.closeAccount: " + err.Error())
+		return "", errors.New("accounts.blockAccount: " + err.Error())
 	}
 
 	return "Account Blocked Successfully", nil
 }
 
-// GetBenefciaries gets all bebeficiaries related to a particular user
-func GetBenefciaries(accountNumber string) (beneficiaries []data.Beneficiary, err error) {
+// updateAccountStatus moves an account through its lifecycle.
+// Format: token~acmt~1017~accountNumber~status~reason
+func updateAccountStatus(data []string) (result lifecycle.StatusChange, err error) {
+	result, err = changeAccountStatus(data[0], data[3], data[4], data[5])
+	if err != nil {
+		return lifecycle.StatusChange{}, errors.New("accounts.updateAccountStatus: " + err.Error())
+	}
+	return
+}
 
-	//get user_id
-	userId, err := GetUserId(accountNumber)
+// fetchAccountStatusHistory lists an account's lifecycle changes.
+// Format: token~acmt~1018~accountNumber
+func fetchAccountStatusHistory(data []string) (result []lifecycle.StatusChange, err error) {
+	exists, err := CheckIfAccountNumberExists(data[3])
 	if err != nil {
-		if err == data.ErrRecordNotFound {
-			return nil, errors.New("accounts.CreateBeneficiary: No records found")
+		return nil, errors.New("accounts.fetchAccountStatusHistory: " + err.Error())
+	}
+	if !exists {
+		return nil, errors.New("accounts.fetchAccountStatusHistory: Account not found")
+	}
 
-		} else {
-			return nil, err
-		}
+	result, err = lifecycle.History(data[3])
+	if err != nil {
+		return nil, errors.New("accounts.fetchAccountStatusHistory: " + err.Error())
 	}
+	return
+}
 
-	//get beneficiaries
-	beneficiaries, err = FetchBenefciaries(userId)
+// changeAccountStatus records the token user as the one making the change
+func changeAccountStatus(token string, accountNumber string, status string, reason string) (lifecycle.StatusChange, error) {
+	actor, err := appauth.GetUserFromToken(token)
 	if err != nil {
-		return nil, err
+		return lifecycle.StatusChange{}, errors.New("accounts.changeAccountStatus: " + err.Error())
+	}
 
+	// Closing settles holds, charges the closure fee and sweeps the balance, so it only goes through acmt 19
+	if status == lifecycle.Closed {
+		return lifecycle.StatusChange{}, errors.New("accounts.changeAccountStatus: Accounts are closed through account closure (acmt 19)")
 	}
 
-	return beneficiaries, nil
+	// Dormant accounts only come back through dormancy.Reactivate, which re-verifies the holder
+	if status == lifecycle.Active {
+		current, err := lifecycle.Status(accountNumber)
+		if err != nil {
+			return lifecycle.StatusChange{}, errors.New("accounts.changeAccountStatus: " + err.Error())
+		}
+		if current == lifecycle.Dormant {
+			return lifecycle.StatusChange{}, errors.New("accounts.changeAccountStatus: Dormant accounts must be reactivated with KYC re-verification")
+		}
+		if current == lifecycle.PendingKYC {
+			return lifecycle.StatusChange{}, errors.New("accounts.changeAccountStatus: Accounts pending KYC are activated by approving the customer's KYC")
+		}
+	}
+
+	return lifecycle.Transition(accountNumber, status, reason, actor)
 }
 
-// CreateBeneficiary creates a new beneficiary tied to an account
-func CreateBeneficiary(beneficiary *data.Beneficiary) error {
+func reasonFromData(data []string, index int, fallback string) string {
+	if len(data) > index && strings.TrimSpace(data[index]) != "" {
+		return data[index]
+	}
+	return fallback
+}
 
-	//get user_id
-	userId, err := GetUserId(beneficiary.UserAccountNumber)
+// GetBenefciaries gets the beneficiaries saved by the customer who owns an account
+func GetBenefciaries(accountNumber string) (beneficiaries []data.Beneficiary, err error) {
+	customer, err := customers.ForAccount(accountNumber)
 	if err != nil {
-		if err == data.ErrRecordNotFound {
-			return errors.New("accounts.CreateBeneficiary: No records found")
+		return nil, errors.New("accounts.GetBenefciaries: " + err.Error())
+	}
 
-		} else {
-			return err
-		}
+	beneficiaries, err = customers.Beneficiaries(customer.CustomerNumber)
+	if err != nil {
+		return nil, errors.New("accounts.GetBenefciaries: " + err.Error())
 	}
 
-	//create new beneficiary
-	err = StoreBeneficiary(beneficiary, userId)
+	return beneficiaries, nil
+}
+
+// CreateBeneficiary saves a beneficiary for the customer who owns the account
+func CreateBeneficiary(beneficiary *data.Beneficiary) error {
+	customer, err := customers.ForAccount(beneficiary.UserAccountNumber)
 	if err != nil {
-		return err
+		return errors.New("accounts.CreateBeneficiary: " + err.Error())
+	}
 
+	err = customers.AddBeneficiary(customer.CustomerNumber, *beneficiary)
+	if err != nil {
+		return errors.New("accounts.CreateBeneficiary: " + err.Error())
 	}
 	return nil
-
 }
 func FetchAccountNumber(username string) (AccountNumber string, Fullname string, err error) {
 
@@ -456,8 +634,13 @@ func openSpecialAccount(data []string) (result string, err error) {
 	// @FIXME: Remove new line from data
 	data[len(data)-1] = strings.Replace(data[len(data)-1], "\n", "", -1)
 
+	numbers, err := allocateSpecialAccountNumbers(data)
+	if err != nil {
+		return "", errors.New("accounts.openAccount: " + err.Error())
+	}
+
 	// Create account
-	accountDetails, err := setSpecialAccountDetails(data)
+	accountDetails, err := setSpecialAccountDetails(data, numbers)
 	if err != nil {
 		return "", errors.New("accounts.openAccount: " + err.Error())
 	}
@@ -470,6 +653,32 @@ func openSpecialAccount(data []string) (result string, err error) {
 	result = accountDetails.AccountNumber
 	return
 }
+
+// allocateSpecialAccountNumbers allocates a special account's numbers. A system account can
+// ask for a number from a reserved range in the optional 8th field.
+// Format: token~acmt~1009~accountName~creator~purpose~currencyCode~accountNumber
+func allocateSpecialAccountNumbers(data []string) (numbers allocation.AccountNumbers, err error) {
+	purpose := "special account"
+	if len(data) > 5 && data[5] != "" {
+		purpose = data[5]
+	}
+	if len(data) < 8 || strings.TrimSpace(data[7]) == "" {
+		return allocation.AllocateAccountNumbers(purpose)
+	}
+
+	numbers.AccountNumber = strings.TrimSpace(data[7])
+	err = allocation.AllocateReserved(allocation.SCHEME_UK, numbers.AccountNumber, purpose)
+	if err != nil {
+		return allocation.AccountNumbers{}, errors.New("accounts.allocateSpecialAccountNumbers: " + err.Error())
+	}
+	numbers.BankNumber, err = allocation.AllocateNUBAN(purpose)
+	if err != nil {
+		return allocation.AccountNumbers{}, errors.New("accounts.allocateSpecialAccountNumbers: " + err.Error())
+	}
+
+	return numbers, nil
+}
+
 func openAccount(data []string) (result string, err error) {
 	// Validate string against required info/length
 	if len(data) < 14 {
@@ -480,25 +689,36 @@ func openAccount(data []string) (result string, err error) {
 	}
 
 	// Test: acmt~1~Kyle~Redelinghuys~19000101~190001011234098~1112223456~~email@domain.com~Physical Address 1~~~1000
-	// Check if account already exists, check on ID number
-	accountHolder, _ := getAccountMeta(data[6])
-
-	if accountHolder.AccountNumber != "" {
-		return "", errors.New("accounts.openAccount: Account already open. " + accountHolder.AccountNumber)
-	}
-
 	// @FIXME: Remove new line from data
 	data[len(data)-1] = strings.Replace(data[len(data)-1], "\n", "", -1)
 
+	// Allocate the numbers once, the account and its holder's details share them
+	numbers, err := allocation.AllocateAccountNumbers("account")
+	if err != nil {
+		return "", errors.New("accounts.openAccount: " + err.Error())
+	}
+
 	// Create account
-	accountHolderObject, err := setAccountDetails(data)
+	accountHolderObject, err := setAccountDetails(data, numbers)
 	if err != nil {
 		return "", errors.New("accounts.openAccount: " + err.Error())
 	}
-	accountHolderDetailsObject, err := setAccountHolderDetails(data)
+	accountHolderDetailsObject, err := setAccountHolderDetails(data, numbers)
 	if err != nil {
 		return "", errors.New("accounts.openAccount: " + err.Error())
 	}
+
+	// A holder who is already a customer gets the account added to their customer file
+	customer, err := holderCustomer(accountHolderDetailsObject)
+	if err != nil {
+		return "", errors.New("accounts.openAccount: " + err.Error())
+	}
+	accountHolderObject.CustomerNumber = customer.CustomerNumber
+	accountHolderObject.Status, err = kycStatus(customer.CustomerNumber)
+	if err != nil {
+		return "", errors.New("accounts.openAccount: " + err.Error())
+	}
+
 	err = createAccount(&accountHolderObject, &accountHolderDetailsObject)
 	if err != nil {
 		return "", errors.New("accounts.openAccount: " + err.Error())
@@ -529,44 +749,17 @@ func updateAccountInformation(data []string) (result string, err error) {
 		return "", errors.New("accounts.openAccount: " + err.Error())
 	}
 
-	result = "Update Successful"
-	return
-
-}
-func closeAccount(data []string) (result string, err error) {
-	// Validate string against required info/length
-	if len(data) < 14 {
-		err = errors.New("accounts.closeAccount: Not all fields present")
-		return
-	}
-
-	// Check if account already exists, check on ID number
-	accountHolder, _ := getAccountMeta(data[6])
-	if accountHolder.AccountNumber == "" {
-		return "", errors.New("accounts.closeAccount: Account does not exist. " + accountHolder.AccountNumber)
-	}
-
-	// @FIXME: Remove new line from data
-	data[len(data)-1] = strings.Replace(data[len(data)-1], "\n", "", -1)
-
-	// Delete account
-	accountHolderObject, err := setAccountDetails(data)
-	if err != nil {
-		return "", errors.New("accounts.closeAccount: " + err.Error())
-	}
-	accountHolderDetailsObject, err := setAccountHolderDetails(data)
+	err = updateAccountCustomer(accountHolderDetailsObject)
 	if err != nil {
-		return "", errors.New("accounts.closeAccount: " + err.Error())
-	}
-	err = deleteAccount(&accountHolderObject, &accountHolderDetailsObject)
-	if err != nil {
-		return "", errors.New("accounts.closeAccount: " + err.Error())
+		return "", errors.New("accounts.updateAccountInformation: " + err.Error())
 	}
 
+	result = "Update Successful"
 	return
+
 }
 
-func setAccountDetails(data []string) (accountDetails AccountDetails, err error) {
+func setAccountDetails(data []string, numbers allocation.AccountNumbers) (accountDetails AccountDetails, err error) {
 	fmt.Println(data)
 	if data[4] == "" {
 		return AccountDetails{}, errors.New("accounts.setAccountDetails: Family name cannot be empty")
@@ -574,14 +767,15 @@ func setAccountDetails(data []string) (accountDetails AccountDetails, err error)
 	if data[3] == "" {
 		return AccountDetails{}, errors.New("accounts.setAccountDetails: Given name cannot be empty")
 	}
-	nubanGenerator := nuban.NewNUBANGenerator()
-	nuban := nubanGenerator.GenerateNUBAN()
-
-	ukaccountgenerator := ukaccountgen.New().GenerateUKAccountNumber()
+	currencyCode, err := currencyFromData(data, 17)
+	if err != nil {
+		return AccountDetails{}, errors.New("accounts.setAccountDetails: " + err.Error())
+	}
 
-	accountDetails.AccountNumber = ukaccountgenerator
-	accountDetails.BankNumber = nuban
+	accountDetails.AccountNumber = numbers.AccountNumber
+	accountDetails.BankNumber = numbers.BankNumber
 	accountDetails.AccountHolderName = data[4] + "," + data[3] // Family Name, Given Name
+	accountDetails.CurrencyCode = currencyCode
 	accountDetails.AccountBalance = decimal.NewFromFloat(OPENING_BALANCE)
 	accountDetails.Overdraft = decimal.NewFromFloat(OPENING_OVERDRAFT)
 	accountDetails.AvailableBalance = decimal.NewFromFloat(OPENING_BALANCE + OPENING_OVERDRAFT)
@@ -589,7 +783,7 @@ func setAccountDetails(data []string) (accountDetails AccountDetails, err error)
 	return
 }
 
-func setSpecialAccountDetails(data []string) (specialAccountDetails SpecialAccountDetails, err error) {
+func setSpecialAccountDetails(data []string, numbers allocation.AccountNumbers) (specialAccountDetails SpecialAccountDetails, err error) {
 	if len(data) < 3 {
 		return SpecialAccountDetails{}, errors.New("accounts.setAccountHolderDetails: Not all field values present")
 	}
@@ -601,15 +795,15 @@ func setSpecialAccountDetails(data []string) (specialAccountDetails SpecialAccou
 		return SpecialAccountDetails{}, errors.New("accounts.setAccountHolderDetails: accountName name cannot be empty")
 	}
 
-	// @TODO Integrity checks
-	nubanGenerator := nuban.NewNUBANGenerator()
-	nuban := nubanGenerator.GenerateNUBAN()
-
-	ukaccountgenerator := ukaccountgen.New().GenerateUKAccountNumber()
+	currencyCode, err := currencyFromData(data, 6)
+	if err != nil {
+		return SpecialAccountDetails{}, errors.New("accounts.setAccountHolderDetails: " + err.Error())
+	}
 
-	specialAccountDetails.AccountNumber = ukaccountgenerator
-	specialAccountDetails.BankNumber = nuban
+	specialAccountDetails.AccountNumber = numbers.AccountNumber
+	specialAccountDetails.BankNumber = numbers.BankNumber
 	specialAccountDetails.AccountHolderName = data[3] //AcccountName
+	specialAccountDetails.CurrencyCode = currencyCode
 	specialAccountDetails.AccountBalance = decimal.NewFromFloat(OPENING_BALANCE)
 	specialAccountDetails.Overdraft = decimal.NewFromFloat(OPENING_OVERDRAFT)
 	specialAccountDetails.AvailableBalance = decimal.NewFromFloat(OPENING_BALANCE + OPENING_OVERDRAFT)
@@ -619,7 +813,7 @@ func setSpecialAccountDetails(data []string) (specialAccountDetails SpecialAccou
 
 	return
 }
-func setAccountHolderDetails(data []string) (accountHolderDetails AccountHolderDetails, err error) {
+func setAccountHolderDetails(data []string, numbers allocation.AccountNumbers) (accountHolderDetails AccountHolderDetails, err error) {
 	if len(data) < 14 {
 		return AccountHolderDetails{}, errors.New("accounts.setAccountHolderDetails: Not all field values present")
 	}
@@ -631,14 +825,8 @@ func setAccountHolderDetails(data []string) (accountHolderDetails AccountHolderD
 		return AccountHolderDetails{}, errors.New("accounts.setAccountHolderDetails: Given name cannot be empty")
 	}
 
-	// @TODO Integrity checks
-	nubanGenerator := nuban.NewNUBANGenerator()
-	nuban := nubanGenerator.GenerateNUBAN()
-
-	ukaccountgenerator := ukaccountgen.New().GenerateUKAccountNumber()
-
-	accountHolderDetails.AccountNumber = ukaccountgenerator
-	accountHolderDetails.BankNumber = nuban
+	accountHolderDetails.AccountNumber = numbers.AccountNumber
+	accountHolderDetails.BankNumber = numbers.BankNumber
 	accountHolderDetails.GivenName = data[3]
 	accountHolderDetails.FamilyName = data[4]
 	accountHolderDetails.DateOfBirth = data[5]
@@ -688,6 +876,163 @@ func setAccountDetailsForUpdate(data []string) (accountHolderDetails AccountHold
 	return
 }
 
+// currencyFromData reads the optional currency code at index, defaulting to the bank's base currency
+func currencyFromData(data []string, index int) (string, error) {
+	if len(data) <= index || strings.TrimSpace(data[index]) == "" {
+		return currency.DEFAULT_CURRENCY, nil
+	}
+
+	c, err := currency.Get(data[index])
+	if err != nil {
+		return "", errors.New("accounts.currencyFromData: " + err.Error())
+	}
+	return c.Code, nil
+}
+
+// openCurrencyAccount opens an additional account in another currency for the token user.
+// Format: token~acmt~1013~CURRENCYCODE
+func openCurrencyAccount(data []string) (result string, err error) {
+	tokenUser, err := appauth.GetUserFromToken(data[0])
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+	c, err := currency.Get(data[3])
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+
+	primary, err := getAccountDetails(tokenUser)
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+	if primary.PrimaryAccountNumber != "" {
+		return "", errors.New("accounts.openCurrencyAccount: Currency accounts must be opened from the primary account")
+	}
+
+	holderAccounts, err := getHolderAccounts(primary.AccountNumber)
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+	for _, account := range holderAccounts {
+		if account.CurrencyCode == c.Code {
+			return "", errors.New("accounts.openCurrencyAccount: Account already open in " + c.Code + ". " + account.AccountNumber)
+		}
+	}
+
+	// Currency accounts share the primary account's KYC
+	status, err := lifecycle.Status(primary.AccountNumber)
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+	if status != lifecycle.PendingKYC {
+		status = lifecycle.Active
+	}
+
+	numbers, err := allocation.AllocateAccountNumbers("currency account")
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+
+	// Currency accounts open empty, the opening balance only applies to the primary account
+	accountDetails := AccountDetails{
+		AccountNumber:        numbers.AccountNumber,
+		BankNumber:           numbers.BankNumber,
+		CustomerNumber:       primary.CustomerNumber,
+		AccountHolderName:    primary.AccountHolderName,
+		CurrencyCode:         c.Code,
+		PrimaryAccountNumber: primary.AccountNumber,
+		Status:               status,
+		AccountBalance:       decimal.Zero,
+		Overdraft:            decimal.NewFromFloat(OPENING_OVERDRAFT),
+		AvailableBalance:     decimal.NewFromFloat(OPENING_OVERDRAFT),
+	}
+	err = createCurrencyAccount(&accountDetails)
+	if err != nil {
+		return "", errors.New("accounts.openCurrencyAccount: " + err.Error())
+	}
+
+	result = accountDetails.AccountNumber
+	return
+}
+
+// fetchHolderAccounts lists the token user's primary account and its currency accounts.
+// Format: token~acmt~1014
+func fetchHolderAccounts(data []string) (result []AccountDetails, err error) {
+	tokenUser, err := appauth.GetUserFromToken(data[0])
+	if err != nil {
+		return nil, errors.New("accounts.fetchHolderAccounts: " + err.Error())
+	}
+
+	account, err := getAccountDetails(tokenUser)
+	if err != nil {
+		return nil, errors.New("accounts.fetchHolderAccounts: " + err.Error())
+	}
+	primaryAccountNumber := account.AccountNumber
+	if account.PrimaryAccountNumber != "" {
+		primaryAccountNumber = account.PrimaryAccountNumber
+	}
+
+	result, err = getHolderAccounts(primaryAccountNumber)
+	if err != nil {
+		return nil, errors.New("accounts.fetchHolderAccounts: " + err.Error())
+	}
+
+	return result, nil
+}
+
+// issueUSAccount assigns a US routing and account number pair to a USD account.
+// Format: token~acmt~1015~accountNumber
+func issueUSAccount(data []string) (result usacctgen.USAccount, err error) {
+	tokenUser, err := appauth.GetUserFromToken(data[0])
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+	account, err := getAccountDetails(data[3])
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, account.AccountNumber)
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+	err = checkUSAccountIssue(account, holder)
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+
+	existing, err := getUSAccount(account.AccountNumber)
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+	if existing.AccountNumber != "" {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: US account details already issued. " + existing.RoutingNumber + " " + existing.AccountNumber)
+	}
+
+	result, err = allocation.AllocateUS("us account")
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+
+	err = createUSAccount(account.AccountNumber, result)
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.issueUSAccount: " + err.Error())
+	}
+
+	return result, nil
+}
+
+// checkUSAccountIssue checks US account details are only issued for a USD account and only to
+// one of its holders
+func checkUSAccountIssue(account AccountDetails, holder bool) error {
+	if !holder {
+		return errors.New("Account not valid")
+	}
+	if account.CurrencyCode != "USD" {
+		return errors.New("US account details can only be issued for USD accounts")
+	}
+	return nil
+}
+
 // @TODO Remove this after testing, security risk
 
 func fetchSingleAccount(data []string) (result string, err error) {
@@ -736,10 +1081,84 @@ func fetchAccountDetails(data []string) (result *BalanceEnquiry, err error) {
 	if err != nil {
 		return nil, errors.New("accounts.fetchSingleAccountByID: " + err.Error())
 	}
+	err = setBankIdentifiers(&balanceEnquiry)
+	if err != nil {
+		return nil, errors.New("accounts.fetchAccountDetails: " + err.Error())
+	}
+	err = setPotBalances(&balanceEnquiry)
+	if err != nil {
+		return nil, errors.New("accounts.fetchAccountDetails: " + err.Error())
+	}
 
 	return &balanceEnquiry, nil
 }
 
+// fetchAccountStatement returns the account's details, including its IBAN, and its transactions.
+// Format: token~acmt~1016~accountNumber
+func fetchAccountStatement(data []string) (result AccountStatement, err error) {
+	accountNumber := data[3]
+	if accountNumber == "" {
+		return AccountStatement{}, errors.New("accounts.fetchAccountStatement: Account number not present")
+	}
+
+	result.BalanceEnquiry, err = GetBalanceDetails(accountNumber)
+	if err != nil {
+		return AccountStatement{}, errors.New("accounts.fetchAccountStatement: " + err.Error())
+	}
+	err = setBankIdentifiers(&result.BalanceEnquiry)
+	if err != nil {
+		return AccountStatement{}, errors.New("accounts.fetchAccountStatement: " + err.Error())
+	}
+
+	result.Transactions, err = GetAccountHistory(accountNumber)
+	if err != nil {
+		return AccountStatement{}, errors.New("accounts.fetchAccountStatement: " + err.Error())
+	}
+	if result.Transactions == nil {
+		result.Transactions = make([]Transaction, 0)
+	}
+
+	return result, nil
+}
+
+// setPotBalances adds the account's savings pots
+func setPotBalances(balanceEnquiry *BalanceEnquiry) error {
+	accountPots, err := pots.ForAccount(balanceEnquiry.AccountNumber)
+	if err != nil {
+		return errors.New("accounts.setPotBalances: " + err.Error())
+	}
+
+	for _, pot := range accountPots {
+		potBalance := PotBalance{
+			ID:         pot.ID,
+			Name:       pot.Name,
+			Balance:    currency.Format(pot.CurrencyCode, pot.Balance),
+			TargetDate: pot.TargetDate,
+		}
+		if pot.TargetAmount.IsPositive() {
+			potBalance.TargetAmount = currency.Format(pot.CurrencyCode, pot.TargetAmount)
+			potBalance.Progress = pot.Progress().String()
+		}
+		if pot.RoundUpTo.IsPositive() {
+			potBalance.RoundUpTo = currency.Format(pot.CurrencyCode, pot.RoundUpTo)
+		}
+		balanceEnquiry.Pots = append(balanceEnquiry.Pots, potBalance)
+	}
+
+	return nil
+}
+
+// setBankIdentifiers adds the IBAN and BIC customers give payers outside the bank
+func setBankIdentifiers(balanceEnquiry *BalanceEnquiry) (err error) {
+	balanceEnquiry.IBAN, err = iban.ForAccount(balanceEnquiry.AccountNumber)
+	if err != nil {
+		return errors.New("accounts.setBankIdentifiers: " + err.Error())
+	}
+	balanceEnquiry.BIC = iban.BankBIC()
+
+	return nil
+}
+
 func fetchAccountHistory(data []string) (result []Transaction, err error) {
 
 	// Format: token~acmt~1002~USERID
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/ebitezion/backend-framework/internal/allocation"
 	"github.com/shopspring/decimal"
 )
 
@@ -51,9 +52,24 @@ func TestOpenCloseAccount(t *testing.T) {
 	}
 }
 
+func TestCheckUSAccountIssue(t *testing.T) {
+	usd := AccountDetails{AccountNumber: "1234567890", CurrencyCode: "USD"}
+	gbp := AccountDetails{AccountNumber: "1234567891", CurrencyCode: "GBP"}
+
+	if err := checkUSAccountIssue(usd, true); err != nil {
+		t.Errorf("CheckUSAccountIssue does not pass. Looking for %v, got %v", nil, err)
+	}
+	if err := checkUSAccountIssue(usd, false); err == nil {
+		t.Errorf("CheckUSAccountIssue does not pass. Looking for %v, got %v", "Account not valid", nil)
+	}
+	if err := checkUSAccountIssue(gbp, true); err == nil {
+		t.Errorf("CheckUSAccountIssue does not pass. Looking for %v, got %v", "US account details can only be issued for USD accounts", nil)
+	}
+}
+
 func TestSetAccountDetails(t *testing.T) {
 	tst := []string{"", "", "", "John", "Doe"}
-	accountDetails, err := setAccountDetails(tst)
+	accountDetails, err := setAccountDetails(tst, allocation.AccountNumbers{BankNumber: BANK_NUMBER})
 
 	if err != nil {
 		t.Errorf("SetAccountDetails does not pass. ERROR. Looking for %v, got %v", nil, err)
@@ -86,7 +102,7 @@ func TestSetAccountDetails(t *testing.T) {
 
 func TestSetAccountHolderDetailsFailure(t *testing.T) {
 	tst := []string{"", "", "", "John", "Doe"}
-	_, err := setAccountHolderDetails(tst)
+	_, err := setAccountHolderDetails(tst, allocation.AccountNumbers{})
 	if err == nil {
 		t.Errorf("etAccountHolderDetailsFailure does not pass. Should fail. Looking for %v, got %v", "Not all field values present", nil)
 	}
@@ -94,7 +110,7 @@ func TestSetAccountHolderDetailsFailure(t *testing.T) {
 
 func TestSetAccountHolderDetails(t *testing.T) {
 	tst := []string{"", "", "", "John", "Doe", "01011900", "010119001234123", "111", "222", "user@domain.com", "address 1", "address 2", "address 3", "2000"}
-	accountHolderDetails, err := setAccountHolderDetails(tst)
+	accountHolderDetails, err := setAccountHolderDetails(tst, allocation.AccountNumbers{})
 
 	if err != nil {
 		t.Errorf("SetAccountHolderDetails does not pass.  Looking for %v, got %v", nil, err)
@@ -156,6 +172,6 @@ func TestSetAccountHolderDetails(t *testing.T) {
 func BenchmarkSetAccountHolderDetails(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		tst := []string{"", "", "", "John", "Doe", "01011900", "010119001234123", "111", "222", "user@domain.com", "address 1", "address 2", "address 3", "2000"}
-		_, _ = setAccountHolderDetails(tst)
+		_, _ = setAccountHolderDetails(tst, allocation.AccountNumbers{})
 	}
 }
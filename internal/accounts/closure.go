@@ -0,0 +1,372 @@
+package accounts
+
+/*
+Account closure (acmt 19, AccountClosingRequestV02)
+
+Closing an account:
+
+ 1. settles anything still pending against it: pending cash pickups it sent are refunded,
+    pending payment requests to or from it are declined and interest accrued so far is paid
+ 2. moves it to CreditFrozen so nothing new lands while the balance is swept
+ 3. charges the closure fee to CLOSURE_FEES_ACCOUNT_NUMBER_<CODE>
+ 4. sweeps what's left to the nominated beneficiary, which must be another account of the same
+    customer, or CLOSURE_SUSPENSE_ACCOUNT_NUMBER_<CODE> when none is given
+ 5. moves it to Closed. The rows stay, so its history is kept.
+
+The closure is recorded in account_closures as it goes. A closure that fails part way is
+resumed by requesting it again, the fee is only ever charged once. An account can be closed
+by one of its holders or by staff. Accounts with active term
+deposits can't be closed until the deposits mature or are broken, accounts with loans being
+repaid can't be closed until they're repaid, and overdrawn accounts can't be closed until the
+overdraft is repaid. The account's overdraft facility and loan applications are cancelled.
+*/
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/deposits"
+	"github.com/ebitezion/backend-framework/internal/interest"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/ebitezion/backend-framework/internal/loans"
+	"github.com/ebitezion/backend-framework/internal/overdrafts"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/rbac_2"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ACCOUNT_CLOSURE_FEE = 5.
+
+	ClosurePending = "pending"
+	ClosureClosed  = "closed"
+
+	closureFeesAccountPrefix     = "CLOSURE_FEES_ACCOUNT_NUMBER_"
+	closureSuspenseAccountPrefix = "CLOSURE_SUSPENSE_ACCOUNT_NUMBER_"
+)
+
+// AccountClosure is the record of an account's closure, and what the certificate shows
+type AccountClosure struct {
+	ID                  int64           `json:"id"`
+	AccountNumber       string          `json:"accountNumber"`
+	AccountHolderName   string          `json:"accountHolderName"`
+	CurrencyCode        string          `json:"currencyCode"`
+	Status              string          `json:"status"`
+	Reason              string          `json:"reason"`
+	Actor               string          `json:"actor"`
+	ClosingBalance      decimal.Decimal `json:"closingBalance"`
+	FeeAmount           decimal.Decimal `json:"feeAmount"`
+	FeeCharged          bool            `json:"feeCharged"`
+	SweptAmount         decimal.Decimal `json:"sweptAmount"`
+	SweptTo             string          `json:"sweptTo"`
+	CashPickupsRefunded int64           `json:"cashPickupsRefunded"`
+	RequestsDeclined    int64           `json:"requestsDeclined"`
+	Timestamp           time.Time       `json:"timestamp"`
+	ClosedAt            time.Time       `json:"closedAt"`
+}
+
+// closeAccount runs an AccountClosingRequest.
+// Format: token~acmt~19~accountNumber~beneficiaryAccountNumber~reason
+func closeAccount(data []string) (result AccountClosure, err error) {
+	if len(data) < 6 {
+		return AccountClosure{}, errors.New("accounts.closeAccount: Not all fields present")
+	}
+	accountNumber := strings.TrimSpace(data[3])
+	beneficiary := strings.TrimSpace(data[4])
+	reason := strings.TrimSpace(data[5])
+	if reason == "" {
+		return AccountClosure{}, errors.New("accounts.closeAccount: Reason cannot be empty")
+	}
+	if beneficiary == accountNumber {
+		return AccountClosure{}, errors.New("accounts.closeAccount: Cannot sweep an account to itself")
+	}
+
+	actor, err := appauth.GetUserFromToken(data[0])
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+
+	err = checkClosureActor(actor, accountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+
+	account, err := getAccountDetails(accountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+	if account.AvailableBalance.IsNegative() {
+		return AccountClosure{}, errors.New("accounts.closeAccount: Account is overdrawn, it must be brought to zero before closing")
+	}
+
+	closure, found, err := getAccountClosure(accountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+	if found && closure.Status == ClosureClosed {
+		return AccountClosure{}, errors.New("accounts.closeAccount: Account already closed")
+	}
+	if !found {
+		closure, err = startAccountClosure(account, reason, actor)
+		if err != nil {
+			return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+		}
+	}
+
+	closure.SweptTo, err = closureDestination(beneficiary, account)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+
+	if !closure.FeeCharged {
+		err = chargeClosureFee(&closure, actor)
+		if err != nil {
+			return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+		}
+	}
+
+	err = sweepClosingBalance(&closure, actor)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+
+	_, err = lifecycle.Transition(accountNumber, lifecycle.Closed, closure.Reason, actor)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: " + err.Error())
+	}
+
+	closure.Status = ClosureClosed
+	closure.ClosedAt = time.Now()
+	err = updateAccountClosure(closure)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.closeAccount: Account closed but the closure record was not updated. " + err.Error())
+	}
+
+	return closure, nil
+}
+
+// fetchAccountClosure returns a closed account's closure record, for its certificate.
+// Format: token~acmt~1019~accountNumber
+func fetchAccountClosure(data []string) (result AccountClosure, err error) {
+	closure, found, err := getAccountClosure(data[3])
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.fetchAccountClosure: " + err.Error())
+	}
+	if !found || closure.Status != ClosureClosed {
+		return AccountClosure{}, errors.New("accounts.fetchAccountClosure: Account has not been closed")
+	}
+
+	return closure, nil
+}
+
+// startAccountClosure settles what's pending against the account, stops further credits
+// and records the closure
+func startAccountClosure(account AccountDetails, reason string, actor string) (AccountClosure, error) {
+	status, err := lifecycle.Status(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	if !lifecycle.CanTransition(status, lifecycle.CreditFrozen) || !lifecycle.CanTransition(lifecycle.CreditFrozen, lifecycle.Closed) {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + status + " accounts cannot be closed")
+	}
+
+	// A primary account can only close once its currency accounts have been closed
+	holderAccounts, err := getHolderAccounts(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	for _, holderAccount := range holderAccounts {
+		if holderAccount.AccountNumber == account.AccountNumber {
+			continue
+		}
+		holderStatus, err := lifecycle.Status(holderAccount.AccountNumber)
+		if err != nil {
+			return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+		}
+		if holderStatus != lifecycle.Closed {
+			return AccountClosure{}, errors.New("accounts.startAccountClosure: Currency account " + holderAccount.AccountNumber + " must be closed first")
+		}
+	}
+
+	// Term deposits pay back to the account, so they have to end before it closes
+	activeDeposits, err := deposits.ActiveForAccount(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	if activeDeposits > 0 {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: The account has " + strconv.Itoa(activeDeposits) + " active term deposits, they must mature or be broken first")
+	}
+	// Loans are collected from the account, so they have to be repaid before it closes
+	outstandingLoans, err := loans.OutstandingForAccount(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	if outstandingLoans > 0 {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: The account has " + strconv.Itoa(outstandingLoans) + " loans outstanding, they must be repaid or cancelled first")
+	}
+
+	closure := AccountClosure{
+		AccountNumber:     account.AccountNumber,
+		AccountHolderName: account.AccountHolderName,
+		CurrencyCode:      account.CurrencyCode,
+		Status:            ClosurePending,
+		Reason:            reason,
+		Actor:             actor,
+	}
+
+	closure.CashPickupsRefunded, err = payments.CancelAccountCashPickups(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	closure.RequestsDeclined, err = payments.DeclineAccountPaymentRequests(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	_, err = payments.RejectAccountMandatePayments(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	err = overdrafts.CancelAccountFacilities(account.AccountNumber, actor)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	_, err = loans.CancelAccountApplications(account.AccountNumber, actor)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	// Interest accrued so far is paid in before credits stop
+	_, err = interest.SettleAccount(account.AccountNumber)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	// Pot balances go back to the main balance so they're swept with the rest
+	_, err = payments.EmptyAccountPots(account.AccountNumber, actor)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+
+	_, err = lifecycle.Transition(account.AccountNumber, lifecycle.CreditFrozen, "Closing: "+reason, actor)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+
+	closure.ID, err = saveAccountClosure(closure)
+	if err != nil {
+		return AccountClosure{}, errors.New("accounts.startAccountClosure: " + err.Error())
+	}
+	closure.Timestamp = time.Now()
+
+	return closure, nil
+}
+
+// chargeClosureFee takes the closure fee, or the whole balance when it's less than the fee
+func chargeClosureFee(closure *AccountClosure, actor string) error {
+	account, err := getAccountDetails(closure.AccountNumber)
+	if err != nil {
+		return errors.New("accounts.chargeClosureFee: " + err.Error())
+	}
+	closure.ClosingBalance = account.AvailableBalance
+
+	fee := currency.Round(closure.CurrencyCode, decimal.NewFromFloat(ACCOUNT_CLOSURE_FEE))
+	if account.AvailableBalance.LessThan(fee) {
+		fee = account.AvailableBalance
+	}
+
+	if fee.IsPositive() {
+		feesAccount, err := closureAccount(closureFeesAccountPrefix, closure.CurrencyCode)
+		if err != nil {
+			return errors.New("accounts.chargeClosureFee: " + err.Error())
+		}
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", closure.AccountNumber + "@", feesAccount + "@", fee.String(), "Account closure fee", actor})
+		if err != nil {
+			return errors.New("accounts.chargeClosureFee: " + err.Error())
+		}
+	}
+
+	closure.FeeAmount = fee
+	closure.FeeCharged = true
+	err = updateAccountClosure(*closure)
+	if err != nil {
+		return errors.New("accounts.chargeClosureFee: Fee charged but the closure record was not updated. " + err.Error())
+	}
+
+	return nil
+}
+
+// sweepClosingBalance moves everything left in the account to the closure's destination
+func sweepClosingBalance(closure *AccountClosure, actor string) error {
+	account, err := getAccountDetails(closure.AccountNumber)
+	if err != nil {
+		return errors.New("accounts.sweepClosingBalance: " + err.Error())
+	}
+
+	if account.AvailableBalance.IsPositive() {
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", closure.AccountNumber + "@", closure.SweptTo + "@", account.AvailableBalance.String(), "Account closure balance", actor})
+		if err != nil {
+			return errors.New("accounts.sweepClosingBalance: " + err.Error())
+		}
+		closure.SweptAmount = closure.SweptAmount.Add(account.AvailableBalance)
+	}
+
+	err = updateAccountClosure(*closure)
+	if err != nil {
+		return errors.New("accounts.sweepClosingBalance: Balance swept but the closure record was not updated. " + err.Error())
+	}
+
+	return nil
+}
+
+// checkClosureActor checks the token user holds the account or has a staff role
+func checkClosureActor(actor string, accountNumber string) error {
+	holder, err := payments.IsAccountHolder(actor, accountNumber)
+	if err != nil {
+		return errors.New("accounts.checkClosureActor: " + err.Error())
+	}
+	if holder {
+		return nil
+	}
+
+	user, err := rbac_2.NewRBACWithDB().GetUserByUsername(actor)
+	if err != nil || user.Role == "" || user.Role == rbac_2.CustomerRole {
+		return errors.New("accounts.checkClosureActor: Account not valid")
+	}
+	return nil
+}
+
+// closureDestination is the nominated beneficiary, another account of the same customer,
+// or the currency's closure suspense account
+func closureDestination(beneficiary string, account AccountDetails) (string, error) {
+	if beneficiary == "" {
+		return closureAccount(closureSuspenseAccountPrefix, account.CurrencyCode)
+	}
+
+	exists, err := CheckIfAccountNumberExists(beneficiary)
+	if err != nil {
+		return "", errors.New("accounts.closureDestination: " + err.Error())
+	}
+	if !exists {
+		return "", errors.New("accounts.closureDestination: Beneficiary account not found")
+	}
+	destination, err := getAccountDetails(beneficiary)
+	if err != nil {
+		return "", errors.New("accounts.closureDestination: " + err.Error())
+	}
+	if account.CustomerNumber == "" || destination.CustomerNumber != account.CustomerNumber {
+		return "", errors.New("accounts.closureDestination: Beneficiary account must belong to the same customer, leave it empty to sweep to suspense")
+	}
+
+	return beneficiary, nil
+}
+
+func closureAccount(prefix string, currencyCode string) (string, error) {
+	accountNumber := strings.TrimSpace(os.Getenv(prefix + currencyCode))
+	if accountNumber == "" {
+		return "", errors.New("accounts.closureAccount: " + prefix + currencyCode + " is not configured")
+	}
+	return accountNumber, nil
+}
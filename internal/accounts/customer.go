@@ -0,0 +1,162 @@
+package accounts
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/allocation"
+	"github.com/ebitezion/backend-framework/internal/customers"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/shopspring/decimal"
+)
+
+// openCustomerAccount opens a further account for an existing customer, in any currency.
+// It opens empty, the opening balance only applies to the customer's first account.
+// Format: token~acmt~1020~customerNumber~currencyCode
+func openCustomerAccount(data []string) (result string, err error) {
+	customer, err := customers.Get(data[3])
+	if err != nil {
+		return "", errors.New("accounts.openCustomerAccount: " + err.Error())
+	}
+	currencyCode, err := currencyFromData(data, 4)
+	if err != nil {
+		return "", errors.New("accounts.openCustomerAccount: " + err.Error())
+	}
+
+	status, err := kycStatus(customer.CustomerNumber)
+	if err != nil {
+		return "", errors.New("accounts.openCustomerAccount: " + err.Error())
+	}
+
+	numbers, err := allocation.AllocateAccountNumbers("customer account")
+	if err != nil {
+		return "", errors.New("accounts.openCustomerAccount: " + err.Error())
+	}
+
+	accountDetails := AccountDetails{
+		AccountNumber:     numbers.AccountNumber,
+		BankNumber:        numbers.BankNumber,
+		CustomerNumber:    customer.CustomerNumber,
+		AccountHolderName: customer.FamilyName + "," + customer.GivenName, // Family Name, Given Name
+		CurrencyCode:      currencyCode,
+		Status:            status,
+		AccountBalance:    decimal.Zero,
+		Overdraft:         decimal.NewFromFloat(OPENING_OVERDRAFT),
+		AvailableBalance:  decimal.NewFromFloat(OPENING_OVERDRAFT),
+	}
+	accountHolderDetails := holderFromCustomer(customer, numbers)
+
+	err = createAccount(&accountDetails, &accountHolderDetails)
+	if err != nil {
+		return "", errors.New("accounts.openCustomerAccount: " + err.Error())
+	}
+
+	result = accountDetails.AccountNumber
+	return
+}
+
+// kycStatus is the state a customer's new account opens in. A customer who has passed KYC on
+// an account they own opens straight into Active, anyone else waits in PendingKYC.
+func kycStatus(customerNumber string) (string, error) {
+	if customerNumber == "" {
+		return lifecycle.PendingKYC, nil
+	}
+	accounts, err := customers.Accounts(customerNumber)
+	if err != nil {
+		return "", errors.New("accounts.kycStatus: " + err.Error())
+	}
+	for _, account := range accounts {
+		if account.Role == customers.RolePrimary && account.Status != lifecycle.PendingKYC {
+			return lifecycle.Active, nil
+		}
+	}
+	return lifecycle.PendingKYC, nil
+}
+
+// fetchCustomer returns a customer's file: their details, accounts, KYC documents and beneficiaries.
+// Format: token~acmt~1021~customerNumber
+func fetchCustomer(data []string) (result customers.File, err error) {
+	result, err = customers.Lookup(data[3])
+	if err != nil {
+		return customers.File{}, errors.New("accounts.fetchCustomer: " + err.Error())
+	}
+	return
+}
+
+// holderCustomer finds the customer an account is being opened for by their identification
+// number, or records a new customer. The date of birth must match an existing customer's.
+func holderCustomer(holder AccountHolderDetails) (customers.Customer, error) {
+	customer, found, err := customers.FindByIdentification(holder.IdentificationNumber)
+	if err != nil {
+		return customers.Customer{}, errors.New("accounts.holderCustomer: " + err.Error())
+	}
+	if found {
+		if strings.TrimSpace(customer.DateOfBirth) != strings.TrimSpace(holder.DateOfBirth) {
+			return customers.Customer{}, errors.New("accounts.holderCustomer: Identification number is held by customer " + customer.CustomerNumber + " with a different date of birth")
+		}
+		return customer, nil
+	}
+
+	customer, err = customers.Create(customerFromHolder(holder))
+	if err != nil {
+		return customers.Customer{}, errors.New("accounts.holderCustomer: " + err.Error())
+	}
+	return customer, nil
+}
+
+// updateAccountCustomer copies updated holder details to the account's customer, and from
+// there to the customer's other accounts
+func updateAccountCustomer(holder AccountHolderDetails) error {
+	customer, err := customers.ForAccount(holder.AccountNumber)
+	if err != nil {
+		return errors.New("accounts.updateAccountCustomer: " + err.Error())
+	}
+
+	updated := customerFromHolder(holder)
+	updated.CustomerNumber = customer.CustomerNumber
+	_, err = customers.Update(updated)
+	if err != nil {
+		return errors.New("accounts.updateAccountCustomer: " + err.Error())
+	}
+	return nil
+}
+
+func customerFromHolder(holder AccountHolderDetails) customers.Customer {
+	return customers.Customer{
+		GivenName:            holder.GivenName,
+		FamilyName:           holder.FamilyName,
+		DateOfBirth:          holder.DateOfBirth,
+		IdentificationNumber: holder.IdentificationNumber,
+		IdentificationType:   holder.IdentificationType,
+		Country:              holder.Country,
+		ContactNumber1:       holder.ContactNumber1,
+		ContactNumber2:       holder.ContactNumber2,
+		EmailAddress:         holder.EmailAddress,
+		AddressLine1:         holder.AddressLine1,
+		AddressLine2:         holder.AddressLine2,
+		AddressLine3:         holder.AddressLine3,
+		PostalCode:           holder.PostalCode,
+		Image:                holder.Image,
+	}
+}
+
+func holderFromCustomer(customer customers.Customer, numbers allocation.AccountNumbers) AccountHolderDetails {
+	return AccountHolderDetails{
+		AccountNumber:        numbers.AccountNumber,
+		BankNumber:           numbers.BankNumber,
+		GivenName:            customer.GivenName,
+		FamilyName:           customer.FamilyName,
+		DateOfBirth:          customer.DateOfBirth,
+		IdentificationNumber: customer.IdentificationNumber,
+		IdentificationType:   customer.IdentificationType,
+		ContactNumber1:       customer.ContactNumber1,
+		ContactNumber2:       customer.ContactNumber2,
+		EmailAddress:         customer.EmailAddress,
+		AddressLine1:         customer.AddressLine1,
+		AddressLine2:         customer.AddressLine2,
+		AddressLine3:         customer.AddressLine3,
+		PostalCode:           customer.PostalCode,
+		Image:                customer.Image,
+		Country:              customer.Country,
+	}
+}
@@ -13,12 +13,10 @@ import (
 	"time"
 
 	"github.com/ebitezion/backend-framework/internal/configuration"
-	"github.com/ebitezion/backend-framework/internal/data"
-)
-
-const (
-	BlockedStatus string = "Deactivated"
-	ActiveStatus  string = "Active"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/ebitezion/backend-framework/internal/usacctgen"
+	"github.com/shopspring/decimal"
 )
 
 var Config configuration.Configuration
@@ -70,6 +68,37 @@ func createSpecialAccount(accountDetails *SpecialAccountDetails) (err error) {
 
 	return
 }
+
+// createCurrencyAccount opens an account linked to the holder's primary account and copies
+// the holder's details from the primary account
+func createCurrencyAccount(accountDetails *AccountDetails) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("accounts.createCurrencyAccount: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	insertStatement := "INSERT INTO accounts (`accountNumber`, `bankNumber`, `customerNumber`, `accountHolderName`, `currencyCode`, `primaryAccountNumber`, `status`, `accountBalance`, `overdraft`, `availableBalance`) "
+	insertStatement += "VALUES(?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)"
+	_, err = tx.Exec(insertStatement, accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.CustomerNumber, accountDetails.AccountHolderName, accountDetails.CurrencyCode, accountDetails.PrimaryAccountNumber, openingStatus(accountDetails), accountDetails.AccountBalance, accountDetails.Overdraft, accountDetails.AvailableBalance)
+	if err != nil {
+		return errors.New("accounts.createCurrencyAccount: " + err.Error())
+	}
+
+	metaColumns := "`accountHolderGivenName`, `accountHolderFamilyName`, `accountHolderDateOfBirth`, `accountHolderIdentificationNumber`, `accountHolderIdentificationType`, `country`, `accountHolderContactNumber1`, `accountHolderContactNumber2`, `accountHolderEmailAddress`, `accountHolderAddressLine1`, `accountHolderAddressLine2`, `accountHolderAddressLine3`, `accountHolderPostalCode`, `image`"
+	_, err = tx.Exec("INSERT INTO accounts_meta (`accountNumber`, `bankNumber`, "+metaColumns+") SELECT ?, ?, "+metaColumns+" FROM accounts_meta WHERE `accountNumber` = ?",
+		accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.PrimaryAccountNumber)
+	if err != nil {
+		return errors.New("accounts.createCurrencyAccount: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("accounts.createCurrencyAccount: " + err.Error())
+	}
+	return
+}
+
 func updateAccount(accountHolderDetails *AccountHolderDetails) (err error) {
 	// Convert variables
 
@@ -87,24 +116,18 @@ func updateAccount(accountHolderDetails *AccountHolderDetails) (err error) {
 	return
 }
 
-func deleteAccount(accountDetails *AccountDetails, accountHolderDetails *AccountHolderDetails) (err error) {
-	err = doDeleteAccount(accountDetails)
-	if err != nil {
-		return errors.New("accounts.deleteAccount: " + err.Error())
-	}
-
-	err = doDeleteAccountMeta(accountHolderDetails, accountDetails)
-	if err != nil {
-		return errors.New("accounts.deleteAccount: " + err.Error())
+// openingStatus is the state an account is created in, PendingKYC unless the caller set one
+func openingStatus(accountDetails *AccountDetails) string {
+	if accountDetails.Status == "" {
+		return lifecycle.PendingKYC
 	}
-
-	return
+	return accountDetails.Status
 }
 
 func doCreateAccount(accountDetails *AccountDetails) (err error) {
 	// Create account
-	insertStatement := "INSERT INTO accounts (`accountNumber`, `bankNumber`, `accountHolderName`, `accountBalance`, `overdraft`, `availableBalance`) "
-	insertStatement += "VALUES(?, ?, ?, ?, ?, ?)"
+	insertStatement := "INSERT INTO accounts (`accountNumber`, `bankNumber`, `customerNumber`, `accountHolderName`, `currencyCode`, `status`, `accountBalance`, `overdraft`, `availableBalance`) "
+	insertStatement += "VALUES(?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?)"
 	stmtIns, err := Config.Db.Prepare(insertStatement)
 	if err != nil {
 		return errors.New("accounts.doCreateAccount: " + err.Error())
@@ -113,7 +136,7 @@ func doCreateAccount(accountDetails *AccountDetails) (err error) {
 	// Prepare statement for inserting data
 	defer stmtIns.Close() // Close the statement when we leave main() / the program terminates
 
-	_, err = stmtIns.Exec(accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.AccountHolderName, accountDetails.AccountBalance, accountDetails.Overdraft, accountDetails.AvailableBalance)
+	_, err = stmtIns.Exec(accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.CustomerNumber, accountDetails.AccountHolderName, accountDetails.CurrencyCode, openingStatus(accountDetails), accountDetails.AccountBalance, accountDetails.Overdraft, accountDetails.AvailableBalance)
 	if err != nil {
 		return errors.New("accounts.doCreateAccount: " + err.Error())
 	}
@@ -121,8 +144,9 @@ func doCreateAccount(accountDetails *AccountDetails) (err error) {
 }
 func doCreateSpecialAccount(accountDetails *SpecialAccountDetails) (err error) {
 	// Create account
-	insertStatement := "INSERT INTO accounts (`accountNumber`, `bankNumber`, `accountHolderName`, `accountBalance`, `overdraft`, `availableBalance`) "
-	insertStatement += "VALUES(?, ?, ?, ?, ?, ?)"
+	// Special accounts are the bank's own and need no KYC
+	insertStatement := "INSERT INTO accounts (`accountNumber`, `bankNumber`, `accountHolderName`, `currencyCode`, `status`, `accountBalance`, `overdraft`, `availableBalance`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
 	stmtIns, err := Config.Db.Prepare(insertStatement)
 	if err != nil {
 		return errors.New("accounts.doCreateSpecialAccount: " + err.Error())
@@ -131,7 +155,7 @@ func doCreateSpecialAccount(accountDetails *SpecialAccountDetails) (err error) {
 	// Prepare statement for inserting data
 	defer stmtIns.Close() // Close the statement when we leave main() / the program terminates
 
-	_, err = stmtIns.Exec(accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.AccountHolderName, accountDetails.AccountBalance, accountDetails.Overdraft, accountDetails.AvailableBalance)
+	_, err = stmtIns.Exec(accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.AccountHolderName, accountDetails.CurrencyCode, lifecycle.Active, accountDetails.AccountBalance, accountDetails.Overdraft, accountDetails.AvailableBalance)
 	if err != nil {
 		return errors.New("accounts.doCreateSpecialAccount: " + err.Error())
 	}
@@ -155,42 +179,6 @@ func doCreateSpecialAccountProfile(accountDetails *SpecialAccountDetails) (err e
 	}
 	return
 }
-func doBlockAccount(AccountNumber string) (err error) {
-	status := BlockedStatus
-	// Create account
-	updateStatement := "UPDATE accounts SET `status`=? WHERE `accountNumber`=?"
-	stmtUpdate, err := Config.Db.Prepare(updateStatement)
-	if err != nil {
-		return errors.New("accounts.doUpdateAccount: " + err.Error())
-	}
-
-	// Prepare statement for updating data
-	defer stmtUpdate.Close() // Close the statement when we leave main() / the program terminates
-
-	_, err = stmtUpdate.Exec(status, AccountNumber)
-	if err != nil {
-		return errors.New("accounts.doUpdateAccount: " + err.Error())
-	}
-	return
-}
-func doUnblockAccount(AccountNumber string) (err error) {
-	status := ActiveStatus
-	// Create account
-	updateStatement := "UPDATE accounts SET `status`=? WHERE `accountNumber`=?"
-	stmtUpdate, err := Config.Db.Prepare(updateStatement)
-	if err != nil {
-		return errors.New("accounts.doUpdateAccount: " + err.Error())
-	}
-
-	// Prepare statement for updating data
-	defer stmtUpdate.Close() // Close the statement when we leave main() / the program terminates
-
-	_, err = stmtUpdate.Exec(status, AccountNumber)
-	if err != nil {
-		return errors.New("accounts.doUpdateAccount: " + err.Error())
-	}
-	return
-}
 func doUpdateAccount(accountDetails *AccountHolderDetails) (err error) {
 	AccountName := accountDetails.FamilyName + "," + accountDetails.GivenName
 	// Create account
@@ -210,25 +198,6 @@ func doUpdateAccount(accountDetails *AccountHolderDetails) (err error) {
 	return
 }
 
-func doDeleteAccount(accountDetails *AccountDetails) (err error) {
-	// Create account
-	deleteStatement := "DELETE FROM accounts WHERE `accountNumber` = ? AND `bankNumber` = ? AND `accountHolderName` = ? "
-	stmtDel, err := Config.Db.Prepare(deleteStatement)
-	if err != nil {
-		return errors.New("accounts.doDeleteAccount: " + err.Error())
-	}
-
-	// Prepare statement for inserting data
-	defer stmtDel.Close() // Close the statement when we leave main() / the program terminates
-
-	_, err = stmtDel.Exec(accountDetails.AccountNumber, accountDetails.BankNumber, accountDetails.AccountHolderName)
-	if err != nil {
-		return errors.New("accounts.doDeleteAccount: " + err.Error())
-	}
-	// Can use db.RowsAffected() to check
-	return
-}
-
 // Full texts
 // id
 // accountNumber
@@ -293,29 +262,8 @@ func doUpdateAccountMeta(accountHolderDetails *AccountHolderDetails) (err error)
 	return
 }
 
-func doDeleteAccountMeta(accountHolderDetails *AccountHolderDetails, accountDetails *AccountDetails) (err error) {
-	// Create account meta
-	deleteStatement := "DELETE FROM accounts_meta WHERE `accountNumber` = ? AND `bankNumber` = ? AND `accountHolderGivenName` = ? AND `accountHolderFamilyName` = ? AND `accountHolderDateOfBirth` = ? AND `accountHolderIdentificationNumber` = ? AND `accountHolderContactNumber1` = ? AND `accountHolderContactNumber2` = ? AND `accountHolderEmailAddress` = ? AND `accountHolderAddressLine1` = ? AND `accountHolderAddressLine2` = ? AND `accountHolderAddressLine3` = ? AND `accountHolderPostalCode` = ? "
-	stmtDel, err := Config.Db.Prepare(deleteStatement)
-	if err != nil {
-		return errors.New("accounts.doDeleteAccountMeta: " + err.Error())
-	}
-	defer stmtDel.Close() // Close the statement when we leave main() / the program terminates
-
-	accountHolderDetails.AccountNumber = accountDetails.AccountNumber
-
-	_, err = stmtDel.Exec(accountHolderDetails.AccountNumber, accountHolderDetails.BankNumber, accountHolderDetails.GivenName, accountHolderDetails.FamilyName, accountHolderDetails.DateOfBirth, accountHolderDetails.IdentificationNumber, accountHolderDetails.ContactNumber1, accountHolderDetails.ContactNumber2, accountHolderDetails.EmailAddress, accountHolderDetails.AddressLine1, accountHolderDetails.AddressLine2, accountHolderDetails.AddressLine3,
-		accountHolderDetails.PostalCode)
-
-	if err != nil {
-		return errors.New("accounts.doDeleteAccountMeta: " + err.Error())
-	}
-
-	return
-}
-
 func getAccountDetails(id string) (accountDetails AccountDetails, err error) {
-	rows, err := Config.Db.Query("SELECT `accountNumber`, `bankNumber`, `accountHolderName`, `accountBalance`, `overdraft`, `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", id)
+	rows, err := Config.Db.Query("SELECT `accountNumber`, `bankNumber`, IFNULL(`customerNumber`, ''), `accountHolderName`, `currencyCode`, IFNULL(`primaryAccountNumber`, ''), `accountBalance`, `overdraft`, `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", id)
 	if err != nil {
 		return AccountDetails{}, errors.New("accounts.getAccountDetails: " + err.Error())
 	}
@@ -323,7 +271,7 @@ func getAccountDetails(id string) (accountDetails AccountDetails, err error) {
 
 	count := 0
 	for rows.Next() {
-		err := rows.Scan(&accountDetails.AccountNumber, &accountDetails.BankNumber, &accountDetails.AccountHolderName, &accountDetails.AccountBalance, &accountDetails.Overdraft, &accountDetails.AvailableBalance)
+		err := rows.Scan(&accountDetails.AccountNumber, &accountDetails.BankNumber, &accountDetails.CustomerNumber, &accountDetails.AccountHolderName, &accountDetails.CurrencyCode, &accountDetails.PrimaryAccountNumber, &accountDetails.AccountBalance, &accountDetails.Overdraft, &accountDetails.AvailableBalance)
 		if err != nil {
 			break
 		}
@@ -342,6 +290,30 @@ func getAccountDetails(id string) (accountDetails AccountDetails, err error) {
 	return
 }
 
+// getHolderAccounts returns the primary account and every currency account opened under it
+func getHolderAccounts(primaryAccountNumber string) (holderAccounts []AccountDetails, err error) {
+	rows, err := Config.Db.Query("SELECT `accountNumber`, `bankNumber`, IFNULL(`customerNumber`, ''), `accountHolderName`, `currencyCode`, IFNULL(`primaryAccountNumber`, ''), `accountBalance`, `overdraft`, `availableBalance` FROM `accounts` WHERE `accountNumber` = ? OR `primaryAccountNumber` = ? ORDER BY `id`", primaryAccountNumber, primaryAccountNumber)
+	if err != nil {
+		return nil, errors.New("accounts.getHolderAccounts: " + err.Error())
+	}
+	defer rows.Close()
+
+	holderAccounts = make([]AccountDetails, 0)
+	for rows.Next() {
+		account := AccountDetails{}
+		if err := rows.Scan(&account.AccountNumber, &account.BankNumber, &account.CustomerNumber, &account.AccountHolderName, &account.CurrencyCode, &account.PrimaryAccountNumber, &account.AccountBalance, &account.Overdraft, &account.AvailableBalance); err != nil {
+			return nil, errors.New("accounts.getHolderAccounts: " + err.Error())
+		}
+		holderAccounts = append(holderAccounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("accounts.getHolderAccounts: " + err.Error())
+	}
+
+	return holderAccounts, nil
+}
+
 func getAccountMeta(id string) (accountDetails AccountHolderDetails, err error) {
 	rows, err := Config.Db.Query("SELECT `accountNumber`, `bankNumber`, `accountHolderGivenName`, `accountHolderFamilyName`, `accountHolderDateOfBirth`, `accountHolderIdentificationNumber`, `accountHolderContactNumber1`, `accountHolderContactNumber2`, `accountHolderEmailAddress`, `accountHolderAddressLine1`, `accountHolderAddressLine2`, `accountHolderAddressLine3`, `accountHolderPostalCode` FROM `accounts_meta` WHERE `accountNumber` = ?", id)
 	if err != nil {
@@ -371,7 +343,7 @@ func getAccountMeta(id string) (accountDetails AccountHolderDetails, err error)
 	return
 }
 func getAllTransactions() ([]Transaction, error) {
-	query := "SELECT transaction, type, senderAccountNumber, senderBankNumber, receiverAccountNumber, receiverBankNumber, transactionAmount, feeAmount, timestamp,narration,initiator FROM transactions "
+	query := "SELECT transaction, type, senderAccountNumber, senderBankNumber, receiverAccountNumber, receiverBankNumber, currencyCode, transactionAmount, feeAmount, timestamp,narration,initiator FROM transactions "
 
 	var transactions []Transaction // Slice to hold multiple transaction records.
 
@@ -388,7 +360,7 @@ func getAllTransactions() ([]Transaction, error) {
 	// Iterate through the result set and scan each row into a Transaction struct.
 	for rows.Next() {
 		var t Transaction
-		err := rows.Scan(&t.Transaction, &t.Type, &t.SenderAccountNumber, &t.SenderBankNumber, &t.ReceiverAccountNumber, &t.ReceiverBankNumber, &t.TransactionAmount, &t.FeeAmount, &t.Timestamp, &t.Narration, &t.Initiator)
+		err := rows.Scan(&t.Transaction, &t.Type, &t.SenderAccountNumber, &t.SenderBankNumber, &t.ReceiverAccountNumber, &t.ReceiverBankNumber, &t.CurrencyCode, &t.TransactionAmount, &t.FeeAmount, &t.Timestamp, &t.Narration, &t.Initiator)
 		if err != nil {
 			return nil, err
 		}
@@ -406,7 +378,10 @@ func getAllAccountDetails() (allAccounts []AccountDetails, err error) {
 		SELECT 
 			a.accountNumber, 
 			a.bankNumber,
+			IFNULL(a.customerNumber, ''),
 			a.accountHolderName, 
+			a.currencyCode,
+			IFNULL(a.primaryAccountNumber, ''),
 			a.accountBalance, 
 			a.overdraft, 
 			a.availableBalance, 
@@ -444,7 +419,10 @@ func getAllAccountDetails() (allAccounts []AccountDetails, err error) {
 		if err := rows.Scan(
 			&accountDetailsSingle.AccountNumber,
 			&accountDetailsSingle.BankNumber,
+			&accountDetailsSingle.CustomerNumber,
 			&accountDetailsSingle.AccountHolderName,
+			&accountDetailsSingle.CurrencyCode,
+			&accountDetailsSingle.PrimaryAccountNumber,
 			&accountDetailsSingle.AccountBalance,
 			&accountDetailsSingle.Overdraft,
 			&accountDetailsSingle.AvailableBalance,
@@ -477,7 +455,7 @@ func getAllAccountDetails() (allAccounts []AccountDetails, err error) {
 }
 
 func getSingleAccountDetail(accountNumber string) (account AccountDetails, err error) {
-	rows, err := Config.Db.Query("SELECT `accountNumber`, `bankNumber`, `accountHolderName`, `accountBalance`, `overdraft`, `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", accountNumber)
+	rows, err := Config.Db.Query("SELECT `accountNumber`, `bankNumber`, IFNULL(`customerNumber`, ''), `accountHolderName`, `currencyCode`, IFNULL(`primaryAccountNumber`, ''), `accountBalance`, `overdraft`, `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", accountNumber)
 	if err != nil {
 		return AccountDetails{}, errors.New("accounts.getSingleAccountDetail: " + err.Error())
 	}
@@ -485,7 +463,7 @@ func getSingleAccountDetail(accountNumber string) (account AccountDetails, err e
 
 	count := 0
 	for rows.Next() {
-		if err := rows.Scan(&account.AccountNumber, &account.BankNumber, &account.AccountHolderName, &account.AccountBalance, &account.Overdraft, &account.AvailableBalance); err != nil {
+		if err := rows.Scan(&account.AccountNumber, &account.BankNumber, &account.CustomerNumber, &account.AccountHolderName, &account.CurrencyCode, &account.PrimaryAccountNumber, &account.AccountBalance, &account.Overdraft, &account.AvailableBalance); err != nil {
 			break
 		}
 
@@ -551,17 +529,18 @@ func getSingleAccountNumberByUsername(username string) (accountNumber string, fu
 
 func GetBalanceDetails(accountNumber string) (BalanceEnquiry, error) {
 
-	query := "SELECT `accountHolderName`, `accountNumber`, `accountBalance`,`status` FROM `accounts` WHERE `accountNumber` = ?"
+	query := "SELECT `accountHolderName`, `accountNumber`, `accountBalance`, `availableBalance`, `overdraft`, `currencyCode`, `status` FROM `accounts` WHERE `accountNumber` = ?"
 
 	// Declare a Users struct to hold the data returned by the query.
 	var BalanceEnquiry BalanceEnquiry
+	var ledgerBalance, availableBalance, overdraftLimit decimal.Decimal
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	// Use the QueryRowContext() method to execute the query, passing in the context
 	// with the deadline as the first argument.
-	err := Config.Db.QueryRowContext(ctx, query, accountNumber).Scan(&BalanceEnquiry.AccountHolderName, &BalanceEnquiry.AccountNumber, &BalanceEnquiry.LedgerBalance, &BalanceEnquiry.Status)
+	err := Config.Db.QueryRowContext(ctx, query, accountNumber).Scan(&BalanceEnquiry.AccountHolderName, &BalanceEnquiry.AccountNumber, &ledgerBalance, &availableBalance, &overdraftLimit, &BalanceEnquiry.CurrencyCode, &BalanceEnquiry.Status)
 
 	// Handle any errors. If there was no matching referralcode found, Scan() will return
 	// a sql.ErrNoRows error. We check for this and return our custom ErrRecordNotFound
@@ -576,13 +555,20 @@ func GetBalanceDetails(accountNumber string) (BalanceEnquiry, error) {
 		return BalanceEnquiry, err
 	}
 
+	// Balances are shown in the currency's minor units
+	BalanceEnquiry.LedgerBalance = currency.Format(BalanceEnquiry.CurrencyCode, ledgerBalance)
+	BalanceEnquiry.AvailableBalance = currency.Format(BalanceEnquiry.CurrencyCode, availableBalance)
+	if overdraftLimit.IsPositive() {
+		BalanceEnquiry.OverdraftLimit = currency.Format(BalanceEnquiry.CurrencyCode, overdraftLimit)
+	}
+
 	// Otherwise, return a pointer to the referrer struct.
 	return BalanceEnquiry, nil
 }
 
 // Get method for fetching all records from the transactions table for a specific account number.
 func GetAccountHistory(accountNumber string) ([]Transaction, error) {
-	query := "SELECT transaction, type, senderAccountNumber, senderBankNumber, receiverAccountNumber, receiverBankNumber, transactionAmount, feeAmount, timestamp,narration,initiator FROM transactions WHERE senderAccountNumber = ?"
+	query := "SELECT transaction, type, senderAccountNumber, senderBankNumber, receiverAccountNumber, receiverBankNumber, currencyCode, transactionAmount, feeAmount, timestamp,narration,initiator FROM transactions WHERE senderAccountNumber = ?"
 
 	var transactions []Transaction // Slice to hold multiple transaction records.
 
@@ -599,7 +585,7 @@ func GetAccountHistory(accountNumber string) ([]Transaction, error) {
 	// Iterate through the result set and scan each row into a Transaction struct.
 	for rows.Next() {
 		var t Transaction
-		err := rows.Scan(&t.Transaction, &t.Type, &t.SenderAccountNumber, &t.SenderBankNumber, &t.ReceiverAccountNumber, &t.ReceiverBankNumber, &t.TransactionAmount, &t.FeeAmount, &t.Timestamp, &t.Narration, &t.Initiator)
+		err := rows.Scan(&t.Transaction, &t.Type, &t.SenderAccountNumber, &t.SenderBankNumber, &t.ReceiverAccountNumber, &t.ReceiverBankNumber, &t.CurrencyCode, &t.TransactionAmount, &t.FeeAmount, &t.Timestamp, &t.Narration, &t.Initiator)
 		if err != nil {
 			return nil, err
 		}
@@ -615,7 +601,7 @@ func GetAccountHistory(accountNumber string) ([]Transaction, error) {
 
 // Get method for fetching all records from the transactions table for a specific account number.
 func GetOutflowHistory(accountNumber string) ([]Transaction, error) {
-	query := "SELECT transaction, type, senderAccountNumber, senderBankNumber, receiverAccountNumber, receiverBankNumber, transactionAmount, feeAmount, timestamp,narration,initiator FROM transactions WHERE receiverAccountNumber = ?"
+	query := "SELECT transaction, type, senderAccountNumber, senderBankNumber, receiverAccountNumber, receiverBankNumber, currencyCode, transactionAmount, feeAmount, timestamp,narration,initiator FROM transactions WHERE receiverAccountNumber = ?"
 
 	var transactions []Transaction // Slice to hold multiple transaction records.
 
@@ -632,7 +618,7 @@ func GetOutflowHistory(accountNumber string) ([]Transaction, error) {
 	// Iterate through the result set and scan each row into a Transaction struct.
 	for rows.Next() {
 		var t Transaction
-		err := rows.Scan(&t.Transaction, &t.Type, &t.SenderAccountNumber, &t.SenderBankNumber, &t.ReceiverAccountNumber, &t.ReceiverBankNumber, &t.TransactionAmount, &t.FeeAmount, &t.Timestamp, &t.Narration, &t.Initiator)
+		err := rows.Scan(&t.Transaction, &t.Type, &t.SenderAccountNumber, &t.SenderBankNumber, &t.ReceiverAccountNumber, &t.ReceiverBankNumber, &t.CurrencyCode, &t.TransactionAmount, &t.FeeAmount, &t.Timestamp, &t.Narration, &t.Initiator)
 		if err != nil {
 			return nil, err
 		}
@@ -645,75 +631,122 @@ func GetOutflowHistory(accountNumber string) ([]Transaction, error) {
 
 	return transactions, nil
 }
-func StoreBeneficiary(Data *data.Beneficiary, UserId string) error {
-	insertStatement := "INSERT INTO beneficiaries (userId, fullName, bankName, bankAccountNumber, bankRoutingNumber, swiftCode) VALUES (?, ?, ?, ?, ?, ?)"
 
+// createUSAccount records the US details issued for an account against its accounts_meta row.
+// The routing number is kept in the table's sort_code column.
+func createUSAccount(accountNumber string, usAccount usacctgen.USAccount) (err error) {
+	insertStatement := "INSERT INTO accounts_meta_usa (`accounts_meta_id`, `us_bank_number`, `sort_code`, `accounts_id`) "
+	insertStatement += "SELECT `id`, ?, ?, `accountNumber` FROM accounts_meta WHERE `accountNumber` = ? ORDER BY `id` LIMIT 1"
 	stmtIns, err := Config.Db.Prepare(insertStatement)
 	if err != nil {
-		return errors.New("accounts.StoreBeneficiary: " + err.Error())
+		return errors.New("accounts.createUSAccount: " + err.Error())
 	}
-
 	defer stmtIns.Close()
 
-	_, err = stmtIns.Exec(UserId, Data.FullName, Data.BankName, Data.BankAccountNumber, Data.BankRoutingNumber, Data.SwiftCode)
+	res, err := stmtIns.Exec(usAccount.AccountNumber, usAccount.RoutingNumber, accountNumber)
 	if err != nil {
-		return errors.New("accounts.doCreateAccount: " + err.Error())
+		return errors.New("accounts.createUSAccount: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("accounts.createUSAccount: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("accounts.createUSAccount: Account holder details not found")
 	}
+
 	return nil
 }
 
-func GetUserId(userID string) (accountID string, err error) {
-	rows, err := Config.Db.Query("SELECT id FROM accounts WHERE accountNumber = ? ", userID)
+// getUSAccount returns the US details issued for an account, empty when none have been issued
+func getUSAccount(accountNumber string) (usAccount usacctgen.USAccount, err error) {
+	rows, err := Config.Db.Query("SELECT `sort_code`, `us_bank_number` FROM `accounts_meta_usa` WHERE `accounts_id` = ?", accountNumber)
 	if err != nil {
-		return "", errors.New("accounts.getSingleAccountNumberByID: " + err.Error())
+		return usacctgen.USAccount{}, errors.New("accounts.getUSAccount: " + err.Error())
 	}
 	defer rows.Close()
 
-	count := 0
-	// @TODO Right now this will return the latest account only, if there are two accounts
-	for rows.Next() {
-		if err := rows.Scan(&accountID); err != nil {
-			break
+	if rows.Next() {
+		if err := rows.Scan(&usAccount.RoutingNumber, &usAccount.AccountNumber); err != nil {
+			return usacctgen.USAccount{}, errors.New("accounts.getUSAccount: " + err.Error())
 		}
-		count++
+	}
+	if err := rows.Err(); err != nil {
+		return usacctgen.USAccount{}, errors.New("accounts.getUSAccount: " + err.Error())
 	}
 
-	if count == 0 {
-		return "", errors.New("accounts.getSingleAccountNumberByID: Account not found")
+	return usAccount, nil
+}
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const accountClosureColumns = "`id`, `accountNumber`, `accountHolderName`, `currencyCode`, `status`, `reason`, `actor`, `closingBalance`, `feeAmount`, `feeCharged`, `sweptAmount`, `sweptTo`, `cashPickupsRefunded`, `requestsDeclined`, `timestamp`, IFNULL(`closedAt`, '')"
+
+func saveAccountClosure(closure AccountClosure) (id int64, err error) {
+	insertStatement := "INSERT INTO account_closures (`accountNumber`, `accountHolderName`, `currencyCode`, `status`, `reason`, `actor`, `closingBalance`, `feeAmount`, `feeCharged`, `sweptAmount`, `sweptTo`, `cashPickupsRefunded`, `requestsDeclined`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("accounts.saveAccountClosure: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(closure.AccountNumber, closure.AccountHolderName, closure.CurrencyCode, closure.Status, closure.Reason, closure.Actor, closure.ClosingBalance, closure.FeeAmount, closure.FeeCharged, closure.SweptAmount, closure.SweptTo, closure.CashPickupsRefunded, closure.RequestsDeclined)
+	if err != nil {
+		return 0, errors.New("accounts.saveAccountClosure: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("accounts.saveAccountClosure: " + err.Error())
 	}
 
 	return
 }
-func FetchBenefciaries(UserId string) ([]data.Beneficiary, error) {
 
-	query := `SELECT  fullName, bankName, bankAccountNumber, bankRoutingNumber, swiftCode	FROM beneficiaries WHERE userId  = ?`
-
-	var Beneficiaries []data.Beneficiary // Slice to hold multiple transaction records.
+func updateAccountClosure(closure AccountClosure) (err error) {
+	var closedAt interface{}
+	if !closure.ClosedAt.IsZero() {
+		closedAt = closure.ClosedAt.UTC()
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	updateStatement := "UPDATE account_closures SET `status` = ?, `closingBalance` = ?, `feeAmount` = ?, `feeCharged` = ?, `sweptAmount` = ?, `sweptTo` = ?, `closedAt` = ? WHERE `id` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("accounts.updateAccountClosure: " + err.Error())
+	}
+	defer stmtUpd.Close()
 
-	// Use the QueryContext method to execute the query, passing in the context.
-	rows, err := Config.Db.QueryContext(ctx, query, UserId)
+	_, err = stmtUpd.Exec(closure.Status, closure.ClosingBalance, closure.FeeAmount, closure.FeeCharged, closure.SweptAmount, closure.SweptTo, closedAt, closure.ID)
 	if err != nil {
-		return nil, err
+		return errors.New("accounts.updateAccountClosure: " + err.Error())
 	}
-	defer rows.Close()
 
-	// Iterate through the result set and scan each row into a Transaction struct.
-	for rows.Next() {
-		var B data.Beneficiary
-		err := rows.Scan(&B.FullName, &B.BankName, &B.BankAccountNumber, &B.BankRoutingNumber, &B.SwiftCode)
-		if err != nil {
-			return nil, err
+	return
+}
+
+// getAccountClosure returns the account's closure record, found is false when it has none
+func getAccountClosure(accountNumber string) (closure AccountClosure, found bool, err error) {
+	var timestamp, closedAt string
+	err = Config.Db.QueryRow("SELECT "+accountClosureColumns+" FROM `account_closures` WHERE `accountNumber` = ?", accountNumber).Scan(
+		&closure.ID, &closure.AccountNumber, &closure.AccountHolderName, &closure.CurrencyCode, &closure.Status, &closure.Reason, &closure.Actor,
+		&closure.ClosingBalance, &closure.FeeAmount, &closure.FeeCharged, &closure.SweptAmount, &closure.SweptTo, &closure.CashPickupsRefunded, &closure.RequestsDeclined,
+		&timestamp, &closedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AccountClosure{}, false, nil
 		}
-		Beneficiaries = append(Beneficiaries, B)
+		return AccountClosure{}, false, errors.New("accounts.getAccountClosure: " + err.Error())
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if closure.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+		return AccountClosure{}, false, errors.New("accounts.getAccountClosure: " + err.Error())
+	}
+	if closedAt != "" {
+		if closure.ClosedAt, err = time.Parse(SQL_TIME_LAYOUT, closedAt); err != nil {
+			return AccountClosure{}, false, errors.New("accounts.getAccountClosure: " + err.Error())
+		}
 	}
 
-	return Beneficiaries, nil
-
+	return closure, true, nil
 }
@@ -0,0 +1,351 @@
+package agents
+
+/*
+Cash agents
+
+An agent is a registered business that pays cash in and out on behalf of the bank.
+Each agent is owned by a customer account (the owner authenticates and earns the
+commission) and operates a dedicated float account opened as a special account.
+
+Cash-in:      customer hands the agent cash, the agent's float pays the customer's account
+Cash-out:     customer pays the agent's float, the agent hands out cash
+Cash pickup:  the pickup is paid from suspense into the agent's float, the agent hands out cash
+
+Agents request float top-ups which are approved by back office staff. Daily limits cap
+the cash-in and cash-out volume per agent and commission rules (agent specific, falling
+back to the defaults) decide what each operation earns.
+*/
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	AgentActive    = "active"
+	AgentSuspended = "suspended"
+
+	OperationCashIn     = "cash_in"
+	OperationCashOut    = "cash_out"
+	OperationCashPickup = "cash_pickup"
+
+	AGENT_CODE_PREFIX = "AG"
+	AGENT_CODE_DIGITS = 6
+)
+
+type Agent struct {
+	ID                 int64           `json:"id"`
+	AgentCode          string          `json:"agentCode"`
+	Name               string          `json:"name"`
+	OwnerAccountNumber string          `json:"ownerAccountNumber"`
+	FloatAccountNumber string          `json:"floatAccountNumber"`
+	Status             string          `json:"status"`
+	CashInDailyLimit   decimal.Decimal `json:"cashInDailyLimit"`
+	CashOutDailyLimit  decimal.Decimal `json:"cashOutDailyLimit"`
+	Creator            string          `json:"creator"`
+	Timestamp          time.Time       `json:"timestamp"`
+	Locations          []Location      `json:"locations,omitempty"`
+}
+
+type Location struct {
+	ID        int64               `json:"id"`
+	AgentID   int64               `json:"agentId"`
+	AgentCode string              `json:"agentCode,omitempty"`
+	Name      string              `json:"name"`
+	Address   string              `json:"address"`
+	City      string              `json:"city"`
+	State     string              `json:"state"`
+	Latitude  decimal.NullDecimal `json:"latitude"`
+	Longitude decimal.NullDecimal `json:"longitude"`
+}
+
+// CommissionRule pays FlatAmount plus Percentage of the amount, capped at MaxAmount when it is set.
+// AgentID is zero for the default rules.
+type CommissionRule struct {
+	ID         int64           `json:"id"`
+	AgentID    int64           `json:"agentId"`
+	Operation  string          `json:"operation"`
+	Percentage decimal.Decimal `json:"percentage"`
+	FlatAmount decimal.Decimal `json:"flatAmount"`
+	MaxAmount  decimal.Decimal `json:"maxAmount"`
+}
+
+// Commission is the commission earned on amount, rounded to two decimal places
+func (rule CommissionRule) Commission(amount decimal.Decimal) decimal.Decimal {
+	commission := rule.FlatAmount.Add(amount.Mul(rule.Percentage).Div(decimal.NewFromInt(100)))
+	if rule.MaxAmount.IsPositive() && commission.GreaterThan(rule.MaxAmount) {
+		commission = rule.MaxAmount
+	}
+	if commission.IsNegative() {
+		return decimal.Zero
+	}
+	return commission.Round(2)
+}
+
+// CreateAgent registers the owner account as an agent and opens its float account
+func CreateAgent(name string, owner string, cashInDailyLimit string, cashOutDailyLimit string, creator string) (agent Agent, err error) {
+	if strings.TrimSpace(name) == "" {
+		return Agent{}, errors.New("agents.CreateAgent: Name cannot be empty")
+	}
+
+	cashInLimit, err := parsePositiveAmount(cashInDailyLimit)
+	if err != nil {
+		return Agent{}, errors.New("agents.CreateAgent: Cash in daily limit. " + err.Error())
+	}
+	cashOutLimit, err := parsePositiveAmount(cashOutDailyLimit)
+	if err != nil {
+		return Agent{}, errors.New("agents.CreateAgent: Cash out daily limit. " + err.Error())
+	}
+
+	active, err := payments.CheckIfAccountIsActive(owner)
+	if err != nil {
+		return Agent{}, errors.New("agents.CreateAgent: " + err.Error())
+	}
+	if !active {
+		return Agent{}, errors.New("agents.CreateAgent: Owners Account Not valid")
+	}
+	if _, err := getAgentByOwner(owner); err == nil {
+		return Agent{}, errors.New("agents.CreateAgent: Account is already an agent")
+	}
+
+	agent = Agent{
+		Name:               name,
+		OwnerAccountNumber: owner,
+		Status:             AgentActive,
+		CashInDailyLimit:   cashInLimit,
+		CashOutDailyLimit:  cashOutLimit,
+		Creator:            creator,
+		Timestamp:          time.Now(),
+	}
+
+	agent.AgentCode, err = newAgentCode()
+	if err != nil {
+		return Agent{}, errors.New("agents.CreateAgent: " + err.Error())
+	}
+
+	floatAccount, err := accounts.ProcessAccount([]string{"0", "acmt", "1009", "Agent float " + agent.AgentCode + " " + name, creator, "Agent float"})
+	if err != nil {
+		return Agent{}, errors.New("agents.CreateAgent: " + err.Error())
+	}
+	agent.FloatAccountNumber = fmt.Sprint(floatAccount)
+
+	agent.ID, err = saveAgent(agent)
+	if err != nil {
+		return Agent{}, errors.New("agents.CreateAgent: " + err.Error())
+	}
+
+	return agent, nil
+}
+
+// GetAgent returns the agent with its locations
+func GetAgent(agentCode string) (agent Agent, err error) {
+	agent, err = getAgentByCode(agentCode)
+	if err != nil {
+		return Agent{}, errors.New("agents.GetAgent: " + err.Error())
+	}
+	agent.Locations, err = getLocations(agent.ID, "")
+	if err != nil {
+		return Agent{}, errors.New("agents.GetAgent: " + err.Error())
+	}
+
+	return agent, nil
+}
+
+// AllAgents lists every registered agent
+func AllAgents() (agents []Agent, err error) {
+	agents, err = getAgents()
+	if err != nil {
+		return nil, errors.New("agents.AllAgents: " + err.Error())
+	}
+
+	return agents, nil
+}
+
+// SetAgentStatus activates or suspends an agent. Suspended agents cannot transact.
+func SetAgentStatus(agentCode string, status string) (agent Agent, err error) {
+	if status != AgentActive && status != AgentSuspended {
+		return Agent{}, errors.New("agents.SetAgentStatus: Invalid status " + status)
+	}
+
+	agent, err = getAgentByCode(agentCode)
+	if err != nil {
+		return Agent{}, errors.New("agents.SetAgentStatus: " + err.Error())
+	}
+
+	err = updateAgentStatus(agent.ID, status)
+	if err != nil {
+		return Agent{}, errors.New("agents.SetAgentStatus: " + err.Error())
+	}
+
+	agent.Status = status
+	return agent, nil
+}
+
+// SetDailyLimits changes an agent's daily cash-in and cash-out limits
+func SetDailyLimits(agentCode string, cashInDailyLimit string, cashOutDailyLimit string) (agent Agent, err error) {
+	cashInLimit, err := parsePositiveAmount(cashInDailyLimit)
+	if err != nil {
+		return Agent{}, errors.New("agents.SetDailyLimits: Cash in daily limit. " + err.Error())
+	}
+	cashOutLimit, err := parsePositiveAmount(cashOutDailyLimit)
+	if err != nil {
+		return Agent{}, errors.New("agents.SetDailyLimits: Cash out daily limit. " + err.Error())
+	}
+
+	agent, err = getAgentByCode(agentCode)
+	if err != nil {
+		return Agent{}, errors.New("agents.SetDailyLimits: " + err.Error())
+	}
+
+	err = updateAgentLimits(agent.ID, cashInLimit, cashOutLimit)
+	if err != nil {
+		return Agent{}, errors.New("agents.SetDailyLimits: " + err.Error())
+	}
+
+	agent.CashInDailyLimit = cashInLimit
+	agent.CashOutDailyLimit = cashOutLimit
+	return agent, nil
+}
+
+// AddLocation records a branch or shop where the agent operates
+func AddLocation(agentCode string, location Location) (Location, error) {
+	agent, err := getAgentByCode(agentCode)
+	if err != nil {
+		return Location{}, errors.New("agents.AddLocation: " + err.Error())
+	}
+	if strings.TrimSpace(location.Name) == "" || strings.TrimSpace(location.City) == "" {
+		return Location{}, errors.New("agents.AddLocation: Name and city cannot be empty")
+	}
+
+	location.AgentID = agent.ID
+	location.AgentCode = agent.AgentCode
+	location.ID, err = saveLocation(location)
+	if err != nil {
+		return Location{}, errors.New("agents.AddLocation: " + err.Error())
+	}
+
+	return location, nil
+}
+
+// FindLocations lists the locations of active agents, optionally in a city
+func FindLocations(city string) (locations []Location, err error) {
+	locations, err = getLocations(0, city)
+	if err != nil {
+		return nil, errors.New("agents.FindLocations: " + err.Error())
+	}
+
+	return locations, nil
+}
+
+// SetCommissionRule creates or replaces the rule for an operation. An empty agentCode sets the default rule.
+func SetCommissionRule(agentCode string, operation string, percentage string, flatAmount string, maxAmount string) (rule CommissionRule, err error) {
+	if !validOperation(operation) {
+		return CommissionRule{}, errors.New("agents.SetCommissionRule: Invalid operation " + operation)
+	}
+
+	if agentCode != "" {
+		agent, err := getAgentByCode(agentCode)
+		if err != nil {
+			return CommissionRule{}, errors.New("agents.SetCommissionRule: " + err.Error())
+		}
+		rule.AgentID = agent.ID
+	}
+
+	rule.Operation = operation
+	if rule.Percentage, err = parseOptionalAmount(percentage); err != nil {
+		return CommissionRule{}, errors.New("agents.SetCommissionRule: Percentage. " + err.Error())
+	}
+	if rule.FlatAmount, err = parseOptionalAmount(flatAmount); err != nil {
+		return CommissionRule{}, errors.New("agents.SetCommissionRule: Flat amount. " + err.Error())
+	}
+	if rule.MaxAmount, err = parseOptionalAmount(maxAmount); err != nil {
+		return CommissionRule{}, errors.New("agents.SetCommissionRule: Max amount. " + err.Error())
+	}
+	if rule.Percentage.GreaterThan(decimal.NewFromInt(100)) {
+		return CommissionRule{}, errors.New("agents.SetCommissionRule: Percentage cannot be more than 100")
+	}
+
+	rule.ID, err = saveCommissionRule(rule)
+	if err != nil {
+		return CommissionRule{}, errors.New("agents.SetCommissionRule: " + err.Error())
+	}
+
+	return rule, nil
+}
+
+// commissionRule returns the agent's rule for the operation, falling back to the default
+func commissionRule(agentID int64, operation string) (CommissionRule, error) {
+	rules, err := getCommissionRules(agentID, operation)
+	if err != nil {
+		return CommissionRule{}, errors.New("agents.commissionRule: " + err.Error())
+	}
+	return selectCommissionRule(rules, agentID, operation), nil
+}
+
+func selectCommissionRule(rules []CommissionRule, agentID int64, operation string) CommissionRule {
+	var fallback *CommissionRule
+	for i := range rules {
+		if rules[i].Operation != operation {
+			continue
+		}
+		if rules[i].AgentID == agentID {
+			return rules[i]
+		}
+		if rules[i].AgentID == 0 && fallback == nil {
+			fallback = &rules[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback
+	}
+	return CommissionRule{AgentID: agentID, Operation: operation, Percentage: decimal.Zero, FlatAmount: decimal.Zero, MaxAmount: decimal.Zero}
+}
+
+func validOperation(operation string) bool {
+	return operation == OperationCashIn || operation == OperationCashOut || operation == OperationCashPickup
+}
+
+func parsePositiveAmount(amount string) (decimal.Decimal, error) {
+	value, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return decimal.Zero, errors.New("Could not convert amount to decimal. " + err.Error())
+	}
+	if !value.IsPositive() {
+		return decimal.Zero, errors.New("Amount must be greater than zero")
+	}
+	return value, nil
+}
+
+func parseOptionalAmount(amount string) (decimal.Decimal, error) {
+	if strings.TrimSpace(amount) == "" {
+		return decimal.Zero, nil
+	}
+	value, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return decimal.Zero, errors.New("Could not convert amount to decimal. " + err.Error())
+	}
+	if value.IsNegative() {
+		return decimal.Zero, errors.New("Amount cannot be negative")
+	}
+	return value, nil
+}
+
+func newAgentCode() (string, error) {
+	code := AGENT_CODE_PREFIX
+	for i := 0; i < AGENT_CODE_DIGITS; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", errors.New("agents.newAgentCode: " + err.Error())
+		}
+		code += n.String()
+	}
+	return code, nil
+}
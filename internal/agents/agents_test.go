@@ -0,0 +1,99 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCommission(t *testing.T) {
+	rule := CommissionRule{
+		Percentage: decimal.NewFromFloat(0.5),
+		FlatAmount: decimal.NewFromInt(10),
+		MaxAmount:  decimal.NewFromInt(100),
+	}
+
+	commission := rule.Commission(decimal.NewFromInt(1000))
+	if !commission.Equal(decimal.NewFromInt(15)) {
+		t.Errorf("Commission does not pass. Looking for %v, got %v", 15, commission)
+	}
+
+	// Capped at the max amount
+	commission = rule.Commission(decimal.NewFromInt(100000))
+	if !commission.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Commission does not pass. Looking for %v, got %v", 100, commission)
+	}
+
+	// No cap when max amount is zero
+	rule.MaxAmount = decimal.Zero
+	commission = rule.Commission(decimal.NewFromInt(100000))
+	if !commission.Equal(decimal.NewFromInt(510)) {
+		t.Errorf("Commission does not pass. Looking for %v, got %v", 510, commission)
+	}
+}
+
+func TestSelectCommissionRule(t *testing.T) {
+	rules := []CommissionRule{
+		{ID: 1, AgentID: 0, Operation: OperationCashIn, FlatAmount: decimal.NewFromInt(5)},
+		{ID: 2, AgentID: 7, Operation: OperationCashIn, FlatAmount: decimal.NewFromInt(8)},
+	}
+
+	rule := selectCommissionRule(rules, 7, OperationCashIn)
+	if rule.ID != 2 {
+		t.Errorf("SelectCommissionRule does not pass. Looking for %v, got %v", 2, rule.ID)
+	}
+
+	rule = selectCommissionRule(rules, 9, OperationCashIn)
+	if rule.ID != 1 {
+		t.Errorf("SelectCommissionRule does not pass. Looking for %v, got %v", 1, rule.ID)
+	}
+
+	rule = selectCommissionRule(rules, 9, OperationCashOut)
+	if !rule.Commission(decimal.NewFromInt(1000)).IsZero() {
+		t.Errorf("SelectCommissionRule does not pass. Looking for %v, got %v", 0, rule.Commission(decimal.NewFromInt(1000)))
+	}
+}
+
+func TestWithinDailyLimit(t *testing.T) {
+	limit := decimal.NewFromInt(1000)
+
+	if !withinDailyLimit(limit, decimal.NewFromInt(400), decimal.NewFromInt(600)) {
+		t.Errorf("WithinDailyLimit does not pass. Looking for %v, got %v", true, false)
+	}
+	if withinDailyLimit(limit, decimal.NewFromInt(400), decimal.NewFromInt(601)) {
+		t.Errorf("WithinDailyLimit does not pass. Looking for %v, got %v", false, true)
+	}
+}
+
+func TestBuildSettlementReport(t *testing.T) {
+	agent := Agent{AgentCode: "AG123456", Name: "Corner Shop"}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	transactions := []Transaction{
+		{Operation: OperationCashIn, Amount: decimal.NewFromInt(5000), Commission: decimal.NewFromInt(25)},
+		{Operation: OperationCashIn, Amount: decimal.NewFromInt(2000), Commission: decimal.NewFromInt(10)},
+		{Operation: OperationCashOut, Amount: decimal.NewFromInt(3000), Commission: decimal.NewFromInt(15)},
+		{Operation: OperationCashPickup, Amount: decimal.NewFromInt(1000), Commission: decimal.NewFromInt(5)},
+	}
+	topUps := []TopUp{{Amount: decimal.NewFromInt(10000)}}
+
+	report := buildSettlementReport(agent, day, transactions, topUps)
+
+	if report.Date != "2024-03-01" {
+		t.Errorf("BuildSettlementReport does not pass. Looking for %v, got %v", "2024-03-01", report.Date)
+	}
+	if len(report.Lines) != 3 || report.Lines[0].Count != 2 || !report.Lines[0].Amount.Equal(decimal.NewFromInt(7000)) {
+		t.Errorf("BuildSettlementReport does not pass. Looking for %v, got %v", "2 cash in totalling 7000", report.Lines)
+	}
+	if !report.TotalCommission.Equal(decimal.NewFromInt(55)) {
+		t.Errorf("BuildSettlementReport does not pass. Looking for %v, got %v", 55, report.TotalCommission)
+	}
+	// 7000 taken in, 4000 paid out
+	if !report.CashPosition.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("BuildSettlementReport does not pass. Looking for %v, got %v", 3000, report.CashPosition)
+	}
+	// -7000 cash in, +4000 cash paid out, +10000 top up
+	if !report.FloatMovement.Equal(decimal.NewFromInt(7000)) {
+		t.Errorf("BuildSettlementReport does not pass. Looking for %v, got %v", 7000, report.FloatMovement)
+	}
+}
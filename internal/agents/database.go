@@ -0,0 +1,459 @@
+package agents
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/shopspring/decimal"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+const agentColumns = "`id`, `agentCode`, `name`, `ownerAccountNumber`, `floatAccountNumber`, `status`, `cashInDailyLimit`, `cashOutDailyLimit`, `creator`, `timestamp`"
+
+func saveAgent(agent Agent) (id int64, err error) {
+	insertStatement := "INSERT INTO agents (`agentCode`, `name`, `ownerAccountNumber`, `floatAccountNumber`, `status`, `cashInDailyLimit`, `cashOutDailyLimit`, `creator`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("agents.saveAgent: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(agent.AgentCode, agent.Name, agent.OwnerAccountNumber, agent.FloatAccountNumber, agent.Status, agent.CashInDailyLimit, agent.CashOutDailyLimit, agent.Creator)
+	if err != nil {
+		return 0, errors.New("agents.saveAgent: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("agents.saveAgent: " + err.Error())
+	}
+
+	return
+}
+
+func getAgentByCode(agentCode string) (Agent, error) {
+	agent, err := getAgentWhere("`agentCode` = ?", agentCode)
+	if err != nil {
+		return Agent{}, errors.New("agents.getAgentByCode: " + err.Error())
+	}
+	return agent, nil
+}
+
+func getAgentByOwner(owner string) (Agent, error) {
+	agent, err := getAgentWhere("`ownerAccountNumber` = ?", owner)
+	if err != nil {
+		return Agent{}, errors.New("agents.getAgentByOwner: " + err.Error())
+	}
+	return agent, nil
+}
+
+func getAgentByID(id int64) (Agent, error) {
+	agent, err := getAgentWhere("`id` = ?", id)
+	if err != nil {
+		return Agent{}, errors.New("agents.getAgentByID: " + err.Error())
+	}
+	return agent, nil
+}
+
+func getAgentWhere(condition string, value interface{}) (Agent, error) {
+	rows, err := Config.Db.Query("SELECT "+agentColumns+" FROM `agents` WHERE "+condition, value)
+	if err != nil {
+		return Agent{}, errors.New("agents.getAgentWhere: " + err.Error())
+	}
+	defer rows.Close()
+
+	agents, err := scanAgents(rows)
+	if err != nil {
+		return Agent{}, errors.New("agents.getAgentWhere: " + err.Error())
+	}
+	if len(agents) == 0 {
+		return Agent{}, errors.New("agents.getAgentWhere: Agent not found")
+	}
+
+	return agents[0], nil
+}
+
+func getAgents() (agents []Agent, err error) {
+	rows, err := Config.Db.Query("SELECT " + agentColumns + " FROM `agents` ORDER BY `name`")
+	if err != nil {
+		return nil, errors.New("agents.getAgents: " + err.Error())
+	}
+	defer rows.Close()
+
+	agents, err = scanAgents(rows)
+	if err != nil {
+		return nil, errors.New("agents.getAgents: " + err.Error())
+	}
+
+	return agents, nil
+}
+
+func scanAgents(rows *sql.Rows) (agents []Agent, err error) {
+	agents = make([]Agent, 0)
+	for rows.Next() {
+		var agent Agent
+		var timestamp string
+		if err := rows.Scan(&agent.ID, &agent.AgentCode, &agent.Name, &agent.OwnerAccountNumber, &agent.FloatAccountNumber, &agent.Status, &agent.CashInDailyLimit, &agent.CashOutDailyLimit, &agent.Creator, &timestamp); err != nil {
+			return nil, errors.New("agents.scanAgents: " + err.Error())
+		}
+		if agent.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("agents.scanAgents: " + err.Error())
+		}
+		agents = append(agents, agent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("agents.scanAgents: " + err.Error())
+	}
+
+	return agents, nil
+}
+
+func updateAgentStatus(id int64, status string) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE agents SET `status` = ? WHERE `id` = ?")
+	if err != nil {
+		return errors.New("agents.updateAgentStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	_, err = stmtUpd.Exec(status, id)
+	if err != nil {
+		return errors.New("agents.updateAgentStatus: " + err.Error())
+	}
+
+	return nil
+}
+
+func updateAgentLimits(id int64, cashInDailyLimit decimal.Decimal, cashOutDailyLimit decimal.Decimal) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE agents SET `cashInDailyLimit` = ?, `cashOutDailyLimit` = ? WHERE `id` = ?")
+	if err != nil {
+		return errors.New("agents.updateAgentLimits: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	_, err = stmtUpd.Exec(cashInDailyLimit, cashOutDailyLimit, id)
+	if err != nil {
+		return errors.New("agents.updateAgentLimits: " + err.Error())
+	}
+
+	return nil
+}
+
+func saveLocation(location Location) (id int64, err error) {
+	insertStatement := "INSERT INTO agent_locations (`agentId`, `name`, `address`, `city`, `state`, `latitude`, `longitude`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("agents.saveLocation: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(location.AgentID, location.Name, location.Address, location.City, location.State, location.Latitude, location.Longitude)
+	if err != nil {
+		return 0, errors.New("agents.saveLocation: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("agents.saveLocation: " + err.Error())
+	}
+
+	return
+}
+
+// getLocations returns one agent's locations, or the locations of every active agent when agentID is zero
+func getLocations(agentID int64, city string) (locations []Location, err error) {
+	query := "SELECT l.`id`, l.`agentId`, a.`agentCode`, l.`name`, l.`address`, l.`city`, l.`state`, l.`latitude`, l.`longitude` FROM `agent_locations` l JOIN `agents` a ON a.`id` = l.`agentId` WHERE "
+	args := []interface{}{}
+	if agentID != 0 {
+		query += "l.`agentId` = ?"
+		args = append(args, agentID)
+	} else {
+		query += "a.`status` = ?"
+		args = append(args, AgentActive)
+	}
+	if city != "" {
+		query += " AND l.`city` = ?"
+		args = append(args, city)
+	}
+	query += " ORDER BY l.`city`, l.`name`"
+
+	rows, err := Config.Db.Query(query, args...)
+	if err != nil {
+		return nil, errors.New("agents.getLocations: " + err.Error())
+	}
+	defer rows.Close()
+
+	locations = make([]Location, 0)
+	for rows.Next() {
+		var location Location
+		if err := rows.Scan(&location.ID, &location.AgentID, &location.AgentCode, &location.Name, &location.Address, &location.City, &location.State, &location.Latitude, &location.Longitude); err != nil {
+			return nil, errors.New("agents.getLocations: " + err.Error())
+		}
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("agents.getLocations: " + err.Error())
+	}
+
+	return locations, nil
+}
+
+// saveCommissionRule replaces any existing rule for the agent and operation
+func saveCommissionRule(rule CommissionRule) (id int64, err error) {
+	agentID := sql.NullInt64{Int64: rule.AgentID, Valid: rule.AgentID != 0}
+
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("agents.saveCommissionRule: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM agent_commission_rules WHERE `agentId` <=> ? AND `operation` = ?", agentID, rule.Operation)
+	if err != nil {
+		return 0, errors.New("agents.saveCommissionRule: " + err.Error())
+	}
+
+	res, err := tx.Exec("INSERT INTO agent_commission_rules (`agentId`, `operation`, `percentage`, `flatAmount`, `maxAmount`) VALUES(?, ?, ?, ?, ?)",
+		agentID, rule.Operation, rule.Percentage, rule.FlatAmount, rule.MaxAmount)
+	if err != nil {
+		return 0, errors.New("agents.saveCommissionRule: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("agents.saveCommissionRule: " + err.Error())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, errors.New("agents.saveCommissionRule: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// getCommissionRules returns the agent's rule and the default rule for the operation
+func getCommissionRules(agentID int64, operation string) (rules []CommissionRule, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, IFNULL(`agentId`, 0), `operation`, `percentage`, `flatAmount`, `maxAmount` FROM `agent_commission_rules` WHERE (`agentId` = ? OR `agentId` IS NULL) AND `operation` = ?", agentID, operation)
+	if err != nil {
+		return nil, errors.New("agents.getCommissionRules: " + err.Error())
+	}
+	defer rows.Close()
+
+	rules = make([]CommissionRule, 0)
+	for rows.Next() {
+		var rule CommissionRule
+		if err := rows.Scan(&rule.ID, &rule.AgentID, &rule.Operation, &rule.Percentage, &rule.FlatAmount, &rule.MaxAmount); err != nil {
+			return nil, errors.New("agents.getCommissionRules: " + err.Error())
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("agents.getCommissionRules: " + err.Error())
+	}
+
+	return rules, nil
+}
+
+func saveTransaction(transaction Transaction) (id int64, err error) {
+	insertStatement := "INSERT INTO agent_transactions (`agentId`, `locationId`, `operation`, `customerAccountNumber`, `amount`, `commission`, `reference`, `timestamp`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("agents.saveTransaction: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	locationID := sql.NullInt64{Int64: transaction.LocationID, Valid: transaction.LocationID != 0}
+	res, err := stmtIns.Exec(transaction.AgentID, locationID, transaction.Operation, transaction.CustomerAccountNumber, transaction.Amount, transaction.Commission, transaction.Reference, transaction.Timestamp.UTC())
+	if err != nil {
+		return 0, errors.New("agents.saveTransaction: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("agents.saveTransaction: " + err.Error())
+	}
+
+	return
+}
+
+func getTransactionVolume(agentID int64, operations []string, start time.Time, end time.Time) (volume decimal.Decimal, err error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(operations)), ", ")
+	args := []interface{}{agentID}
+	for _, operation := range operations {
+		args = append(args, operation)
+	}
+	args = append(args, start.UTC(), end.UTC())
+
+	row := Config.Db.QueryRow("SELECT IFNULL(SUM(`amount`), 0) FROM `agent_transactions` WHERE `agentId` = ? AND `operation` IN ("+placeholders+") AND `timestamp` >= ? AND `timestamp` < ?", args...)
+	if err := row.Scan(&volume); err != nil {
+		return decimal.Zero, errors.New("agents.getTransactionVolume: " + err.Error())
+	}
+
+	return volume, nil
+}
+
+func getTransactions(agentID int64, start time.Time, end time.Time) (transactions []Transaction, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `agentId`, IFNULL(`locationId`, 0), `operation`, `customerAccountNumber`, `amount`, `commission`, `reference`, `timestamp` FROM `agent_transactions` WHERE `agentId` = ? AND `timestamp` >= ? AND `timestamp` < ? ORDER BY `timestamp`", agentID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, errors.New("agents.getTransactions: " + err.Error())
+	}
+	defer rows.Close()
+
+	transactions = make([]Transaction, 0)
+	for rows.Next() {
+		var transaction Transaction
+		var timestamp string
+		if err := rows.Scan(&transaction.ID, &transaction.AgentID, &transaction.LocationID, &transaction.Operation, &transaction.CustomerAccountNumber, &transaction.Amount, &transaction.Commission, &transaction.Reference, &timestamp); err != nil {
+			return nil, errors.New("agents.getTransactions: " + err.Error())
+		}
+		if transaction.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("agents.getTransactions: " + err.Error())
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("agents.getTransactions: " + err.Error())
+	}
+
+	return transactions, nil
+}
+
+func saveTopUp(topUp TopUp) (id int64, err error) {
+	stmtIns, err := Config.Db.Prepare("INSERT INTO agent_float_topups (`agentId`, `amount`, `reference`, `status`) VALUES(?, ?, ?, ?)")
+	if err != nil {
+		return 0, errors.New("agents.saveTopUp: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(topUp.AgentID, topUp.Amount, topUp.Reference, topUp.Status)
+	if err != nil {
+		return 0, errors.New("agents.saveTopUp: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("agents.saveTopUp: " + err.Error())
+	}
+
+	return
+}
+
+const topUpColumns = "t.`id`, t.`agentId`, a.`agentCode`, t.`amount`, t.`reference`, t.`status`, IFNULL(t.`reviewer`, ''), IFNULL(t.`reviewNote`, ''), t.`timestamp`"
+
+func getTopUp(id int64) (TopUp, error) {
+	rows, err := Config.Db.Query("SELECT "+topUpColumns+" FROM `agent_float_topups` t JOIN `agents` a ON a.`id` = t.`agentId` WHERE t.`id` = ?", id)
+	if err != nil {
+		return TopUp{}, errors.New("agents.getTopUp: " + err.Error())
+	}
+	defer rows.Close()
+
+	topUps, err := scanTopUps(rows)
+	if err != nil {
+		return TopUp{}, errors.New("agents.getTopUp: " + err.Error())
+	}
+	if len(topUps) == 0 {
+		return TopUp{}, errors.New("agents.getTopUp: Top up not found")
+	}
+
+	return topUps[0], nil
+}
+
+func getTopUpsByStatus(status string) (topUps []TopUp, err error) {
+	rows, err := Config.Db.Query("SELECT "+topUpColumns+" FROM `agent_float_topups` t JOIN `agents` a ON a.`id` = t.`agentId` WHERE t.`status` = ? ORDER BY t.`timestamp`", status)
+	if err != nil {
+		return nil, errors.New("agents.getTopUpsByStatus: " + err.Error())
+	}
+	defer rows.Close()
+
+	topUps, err = scanTopUps(rows)
+	if err != nil {
+		return nil, errors.New("agents.getTopUpsByStatus: " + err.Error())
+	}
+
+	return topUps, nil
+}
+
+// getApprovedTopUps returns the top-ups approved in the period
+func getApprovedTopUps(agentID int64, start time.Time, end time.Time) (topUps []TopUp, err error) {
+	rows, err := Config.Db.Query("SELECT "+topUpColumns+" FROM `agent_float_topups` t JOIN `agents` a ON a.`id` = t.`agentId` WHERE t.`agentId` = ? AND t.`status` = ? AND t.`updated_at` >= ? AND t.`updated_at` < ?", agentID, TopUpApproved, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, errors.New("agents.getApprovedTopUps: " + err.Error())
+	}
+	defer rows.Close()
+
+	topUps, err = scanTopUps(rows)
+	if err != nil {
+		return nil, errors.New("agents.getApprovedTopUps: " + err.Error())
+	}
+
+	return topUps, nil
+}
+
+func scanTopUps(rows *sql.Rows) (topUps []TopUp, err error) {
+	topUps = make([]TopUp, 0)
+	for rows.Next() {
+		var topUp TopUp
+		var timestamp string
+		if err := rows.Scan(&topUp.ID, &topUp.AgentID, &topUp.AgentCode, &topUp.Amount, &topUp.Reference, &topUp.Status, &topUp.Reviewer, &topUp.ReviewNote, &timestamp); err != nil {
+			return nil, errors.New("agents.scanTopUps: " + err.Error())
+		}
+		if topUp.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("agents.scanTopUps: " + err.Error())
+		}
+		topUps = append(topUps, topUp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("agents.scanTopUps: " + err.Error())
+	}
+
+	return topUps, nil
+}
+
+// reviewTopUp only moves a request that is still in the expected status
+func reviewTopUp(id int64, from string, to string, reviewer string, note string) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE agent_float_topups SET `status` = ?, `reviewer` = NULLIF(?, ''), `reviewNote` = NULLIF(?, ''), `updated_at` = ? WHERE `id` = ? AND `status` = ?")
+	if err != nil {
+		return errors.New("agents.reviewTopUp: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, reviewer, note, time.Now().UTC(), id, from)
+	if err != nil {
+		return errors.New("agents.reviewTopUp: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("agents.reviewTopUp: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("agents.reviewTopUp: Top up is no longer " + from)
+	}
+
+	return nil
+}
+
+func getFloatBalance(accountNumber string) (balance decimal.Decimal, err error) {
+	row := Config.Db.QueryRow("SELECT `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", accountNumber)
+	if err := row.Scan(&balance); err != nil {
+		return decimal.Zero, errors.New("agents.getFloatBalance: " + err.Error())
+	}
+
+	return balance, nil
+}
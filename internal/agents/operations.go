@@ -0,0 +1,400 @@
+package agents
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	TopUpPending  = "pending"
+	TopUpApproved = "approved"
+	TopUpRejected = "rejected"
+)
+
+// limitMutex is held from the daily limit check until the operation is recorded, so two
+// operations at once can't both fit in what is left of the limit
+var limitMutex sync.Mutex
+
+type Transaction struct {
+	ID                    int64           `json:"id"`
+	AgentID               int64           `json:"agentId"`
+	LocationID            int64           `json:"locationId"`
+	Operation             string          `json:"operation"`
+	CustomerAccountNumber string          `json:"customerAccountNumber"`
+	Amount                decimal.Decimal `json:"amount"`
+	Commission            decimal.Decimal `json:"commission"`
+	Reference             string          `json:"reference"`
+	Timestamp             time.Time       `json:"timestamp"`
+}
+
+type TopUp struct {
+	ID         int64           `json:"id"`
+	AgentID    int64           `json:"agentId"`
+	AgentCode  string          `json:"agentCode"`
+	Amount     decimal.Decimal `json:"amount"`
+	Reference  string          `json:"reference"`
+	Status     string          `json:"status"`
+	Reviewer   string          `json:"reviewer"`
+	ReviewNote string          `json:"reviewNote"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
+
+// CashIn credits the customer from the agent's float after the agent has taken the cash.
+// The token user must be the agent's owner.
+func CashIn(token string, customer string, amount string, locationID int64) (transaction Transaction, err error) {
+	agent, err := agentFromToken(token)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+
+	err = checkLocation(agent, locationID)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+
+	amountDecimal, err := parsePositiveAmount(amount)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+	err = checkDailyLimit(agent, OperationCashIn, amountDecimal)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+	exists, err := payments.CheckIfAccountIsActive(customer)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+	if !exists {
+		return Transaction{}, errors.New("agents.CashIn: Customers Account Not valid")
+	}
+
+	reference := "Agent " + agent.AgentCode + " cash in"
+	_, err = payments.ProcessPAIN([]string{token, "pain", "1001", agent.FloatAccountNumber + "@", customer + "@", amountDecimal.String(), reference, agent.OwnerAccountNumber})
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+
+	transaction, err = recordTransaction(agent, OperationCashIn, customer, amountDecimal, reference, locationID)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashIn: " + err.Error())
+	}
+
+	return transaction, nil
+}
+
+// CashOut debits the customer into the agent's float so the agent can hand out the cash.
+// The token user must be the customer, who authorises the withdrawal on their own device.
+func CashOut(token string, agentCode string, customer string, amount string, locationID int64) (transaction Transaction, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, customer)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+	if !holder {
+		return Transaction{}, errors.New("agents.CashOut: Customer not valid")
+	}
+	err = payments.RequireSingleSignature(customer)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+
+	agent, err := getAgentByCode(agentCode)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+	if agent.Status != AgentActive {
+		return Transaction{}, errors.New("agents.CashOut: Agent is " + agent.Status)
+	}
+	if agent.OwnerAccountNumber == customer {
+		return Transaction{}, errors.New("agents.CashOut: Agents cannot cash out to themselves")
+	}
+	err = checkLocation(agent, locationID)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+
+	amountDecimal, err := parsePositiveAmount(amount)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+	err = checkDailyLimit(agent, OperationCashOut, amountDecimal)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+
+	reference := "Agent " + agent.AgentCode + " cash out"
+	_, err = payments.ProcessPAIN([]string{token, "pain", "1", customer + "@", agent.FloatAccountNumber + "@", amountDecimal.String(), reference, customer})
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+
+	transaction, err = recordTransaction(agent, OperationCashOut, customer, amountDecimal, reference, locationID)
+	if err != nil {
+		return Transaction{}, errors.New("agents.CashOut: " + err.Error())
+	}
+
+	return transaction, nil
+}
+
+// PayOutCashPickup redeems a cash pickup into the agent's float.
+// The token user must be the agent's owner.
+func PayOutCashPickup(token string, code string, pin string, identificationType string, identificationNumber string, locationID int64) (pickup payments.CashPickup, transaction Transaction, err error) {
+	agent, err := agentFromToken(token)
+	if err != nil {
+		return payments.CashPickup{}, Transaction{}, errors.New("agents.PayOutCashPickup: " + err.Error())
+	}
+	err = checkLocation(agent, locationID)
+	if err != nil {
+		return payments.CashPickup{}, Transaction{}, errors.New("agents.PayOutCashPickup: " + err.Error())
+	}
+
+	pickup, err = payments.FindCashPickup(code)
+	if err != nil {
+		return payments.CashPickup{}, Transaction{}, errors.New("agents.PayOutCashPickup: " + err.Error())
+	}
+
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+	err = checkDailyLimit(agent, OperationCashPickup, pickup.Amount)
+	if err != nil {
+		return payments.CashPickup{}, Transaction{}, errors.New("agents.PayOutCashPickup: " + err.Error())
+	}
+
+	pickup, err = payments.RedeemCashPickup(token, code, pin, identificationType, identificationNumber, agent.FloatAccountNumber)
+	if err != nil {
+		return payments.CashPickup{}, Transaction{}, errors.New("agents.PayOutCashPickup: " + err.Error())
+	}
+
+	transaction, err = recordTransaction(agent, OperationCashPickup, pickup.SendersAccountNumber, pickup.Amount, "Cash pickup "+pickup.PickupCode, locationID)
+	if err != nil {
+		return payments.CashPickup{}, Transaction{}, errors.New("agents.PayOutCashPickup: " + err.Error())
+	}
+
+	return pickup, transaction, nil
+}
+
+// RequestTopUp asks the back office to credit the agent's float once the cash has been lodged.
+// The token user must be the agent's owner.
+func RequestTopUp(token string, amount string, reference string) (topUp TopUp, err error) {
+	agent, err := agentFromToken(token)
+	if err != nil {
+		return TopUp{}, errors.New("agents.RequestTopUp: " + err.Error())
+	}
+
+	topUp.Amount, err = parsePositiveAmount(amount)
+	if err != nil {
+		return TopUp{}, errors.New("agents.RequestTopUp: " + err.Error())
+	}
+	if strings.TrimSpace(reference) == "" {
+		return TopUp{}, errors.New("agents.RequestTopUp: Reference cannot be empty")
+	}
+
+	topUp.AgentID = agent.ID
+	topUp.AgentCode = agent.AgentCode
+	topUp.Reference = reference
+	topUp.Status = TopUpPending
+	topUp.Timestamp = time.Now()
+
+	topUp.ID, err = saveTopUp(topUp)
+	if err != nil {
+		return TopUp{}, errors.New("agents.RequestTopUp: " + err.Error())
+	}
+
+	return topUp, nil
+}
+
+// PendingTopUps lists the top-up requests awaiting review
+func PendingTopUps() (topUps []TopUp, err error) {
+	topUps, err = getTopUpsByStatus(TopUpPending)
+	if err != nil {
+		return nil, errors.New("agents.PendingTopUps: " + err.Error())
+	}
+
+	return topUps, nil
+}
+
+// ApproveTopUp credits the agent's float from the deposit account
+func ApproveTopUp(id int64, reviewer string) (topUp TopUp, err error) {
+	topUp, err = getTopUp(id)
+	if err != nil {
+		return TopUp{}, errors.New("agents.ApproveTopUp: " + err.Error())
+	}
+	agent, err := getAgentByID(topUp.AgentID)
+	if err != nil {
+		return TopUp{}, errors.New("agents.ApproveTopUp: " + err.Error())
+	}
+	if reviewer == "" || reviewer == agent.OwnerAccountNumber {
+		return TopUp{}, errors.New("agents.ApproveTopUp: Reviewer not valid")
+	}
+
+	deposit := strings.TrimSpace(os.Getenv("DEPOSIT_ACCOUNT_NUMBER"))
+	if deposit == "" {
+		return TopUp{}, errors.New("agents.ApproveTopUp: DEPOSIT_ACCOUNT_NUMBER is not configured")
+	}
+
+	// Claim the request first so it cannot be credited twice
+	err = reviewTopUp(id, TopUpPending, TopUpApproved, reviewer, "")
+	if err != nil {
+		return TopUp{}, errors.New("agents.ApproveTopUp: " + err.Error())
+	}
+
+	_, err = payments.ProcessPAIN([]string{"", "pain", "1001", deposit + "@", agent.FloatAccountNumber + "@", topUp.Amount.String(), "Agent " + agent.AgentCode + " float top up " + topUp.Reference, reviewer})
+	if err != nil {
+		if revertErr := reviewTopUp(id, TopUpApproved, TopUpPending, "", ""); revertErr != nil {
+			return TopUp{}, errors.New("agents.ApproveTopUp: " + err.Error() + ". " + revertErr.Error())
+		}
+		return TopUp{}, errors.New("agents.ApproveTopUp: " + err.Error())
+	}
+
+	topUp.Status = TopUpApproved
+	topUp.Reviewer = reviewer
+	return topUp, nil
+}
+
+// RejectTopUp declines a top-up request
+func RejectTopUp(id int64, reviewer string, note string) (topUp TopUp, err error) {
+	topUp, err = getTopUp(id)
+	if err != nil {
+		return TopUp{}, errors.New("agents.RejectTopUp: " + err.Error())
+	}
+	if reviewer == "" {
+		return TopUp{}, errors.New("agents.RejectTopUp: Reviewer not valid")
+	}
+
+	err = reviewTopUp(id, TopUpPending, TopUpRejected, reviewer, note)
+	if err != nil {
+		return TopUp{}, errors.New("agents.RejectTopUp: " + err.Error())
+	}
+
+	topUp.Status = TopUpRejected
+	topUp.Reviewer = reviewer
+	topUp.ReviewNote = note
+	return topUp, nil
+}
+
+// AgentFromToken returns the active agent owned by the token user
+func AgentFromToken(token string) (Agent, error) {
+	agent, err := agentFromToken(token)
+	if err != nil {
+		return Agent{}, errors.New("agents.AgentFromToken: " + err.Error())
+	}
+	return agent, nil
+}
+
+func agentFromToken(token string) (Agent, error) {
+	owner, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Agent{}, errors.New("agents.agentFromToken: " + err.Error())
+	}
+	agent, err := getAgentByOwner(owner)
+	if err != nil {
+		return Agent{}, errors.New("agents.agentFromToken: " + err.Error())
+	}
+	if agent.Status != AgentActive {
+		return Agent{}, errors.New("agents.agentFromToken: Agent is " + agent.Status)
+	}
+	return agent, nil
+}
+
+// checkLocation checks the location an operation is recorded at is one of the agent's own,
+// no location is zero
+func checkLocation(agent Agent, locationID int64) error {
+	if locationID == 0 {
+		return nil
+	}
+
+	locations, err := getLocations(agent.ID, "")
+	if err != nil {
+		return errors.New("agents.checkLocation: " + err.Error())
+	}
+	for _, location := range locations {
+		if location.ID == locationID {
+			return nil
+		}
+	}
+	return errors.New("agents.checkLocation: Location does not belong to agent " + agent.AgentCode)
+}
+
+// checkDailyLimit compares today's volume for the limit the operation counts against.
+// Cash pickups are paid in cash so they share the cash-out limit. Callers hold limitMutex
+// until the operation is recorded.
+func checkDailyLimit(agent Agent, operation string, amount decimal.Decimal) error {
+	limit := agent.CashOutDailyLimit
+	operations := []string{OperationCashOut, OperationCashPickup}
+	if operation == OperationCashIn {
+		limit = agent.CashInDailyLimit
+		operations = []string{OperationCashIn}
+	}
+
+	start, end := dayBounds(time.Now())
+	used, err := getTransactionVolume(agent.ID, operations, start, end)
+	if err != nil {
+		return errors.New("agents.checkDailyLimit: " + err.Error())
+	}
+	if !withinDailyLimit(limit, used, amount) {
+		return errors.New("agents.checkDailyLimit: Daily limit of " + limit.StringFixed(2) + " exceeded, " + limit.Sub(used).StringFixed(2) + " remaining")
+	}
+	return nil
+}
+
+func withinDailyLimit(limit decimal.Decimal, used decimal.Decimal, amount decimal.Decimal) bool {
+	return used.Add(amount).LessThanOrEqual(limit)
+}
+
+// recordTransaction stores the agent operation and pays the commission from the fees
+// account into the owner's account. A failed commission payment is kept as zero commission.
+func recordTransaction(agent Agent, operation string, customer string, amount decimal.Decimal, reference string, locationID int64) (Transaction, error) {
+	transaction := Transaction{
+		AgentID:               agent.ID,
+		LocationID:            locationID,
+		Operation:             operation,
+		CustomerAccountNumber: customer,
+		Amount:                amount,
+		Commission:            decimal.Zero,
+		Reference:             reference,
+		Timestamp:             time.Now(),
+	}
+
+	rule, err := commissionRule(agent.ID, operation)
+	if err != nil {
+		return Transaction{}, errors.New("agents.recordTransaction: " + err.Error())
+	}
+	commission := rule.Commission(amount)
+
+	fees := strings.TrimSpace(os.Getenv("FEES_ACCOUNT_NUMBER"))
+	if commission.IsPositive() && fees != "" {
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", fees + "@", agent.OwnerAccountNumber + "@", commission.String(), "Agent " + agent.AgentCode + " commission " + reference, "system"})
+		if err == nil {
+			transaction.Commission = commission
+		}
+	}
+
+	transaction.ID, err = saveTransaction(transaction)
+	if err != nil {
+		return Transaction{}, errors.New("agents.recordTransaction: " + err.Error())
+	}
+
+	return transaction, nil
+}
+
+// dayBounds returns the start of the day t falls on and the start of the next day
+func dayBounds(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,132 @@
+package agents
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type SettlementLine struct {
+	Operation  string          `json:"operation"`
+	Count      int             `json:"count"`
+	Amount     decimal.Decimal `json:"amount"`
+	Commission decimal.Decimal `json:"commission"`
+}
+
+// SettlementReport is an agent's end of day position.
+// CashPosition is the cash the agent should be holding from the day's operations
+// (cash taken in less cash paid out). FloatMovement is the change in the float account
+// from operations and approved top-ups.
+type SettlementReport struct {
+	AgentCode       string           `json:"agentCode"`
+	AgentName       string           `json:"agentName"`
+	Date            string           `json:"date"`
+	Lines           []SettlementLine `json:"lines"`
+	TotalCommission decimal.Decimal  `json:"totalCommission"`
+	TopUps          decimal.Decimal  `json:"topUps"`
+	CashPosition    decimal.Decimal  `json:"cashPosition"`
+	FloatMovement   decimal.Decimal  `json:"floatMovement"`
+	FloatBalance    decimal.Decimal  `json:"floatBalance"`
+}
+
+// Settlement builds the end of day report for one agent
+func Settlement(agentCode string, day time.Time) (report SettlementReport, err error) {
+	agent, err := getAgentByCode(agentCode)
+	if err != nil {
+		return SettlementReport{}, errors.New("agents.Settlement: " + err.Error())
+	}
+
+	report, err = settlementForAgent(agent, day)
+	if err != nil {
+		return SettlementReport{}, errors.New("agents.Settlement: " + err.Error())
+	}
+
+	return report, nil
+}
+
+// Settlements builds the end of day report for every agent
+func Settlements(day time.Time) (reports []SettlementReport, err error) {
+	agents, err := getAgents()
+	if err != nil {
+		return nil, errors.New("agents.Settlements: " + err.Error())
+	}
+
+	reports = make([]SettlementReport, 0, len(agents))
+	for _, agent := range agents {
+		report, err := settlementForAgent(agent, day)
+		if err != nil {
+			return nil, errors.New("agents.Settlements: " + err.Error())
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func settlementForAgent(agent Agent, day time.Time) (SettlementReport, error) {
+	start, end := dayBounds(day)
+
+	transactions, err := getTransactions(agent.ID, start, end)
+	if err != nil {
+		return SettlementReport{}, errors.New("agents.settlementForAgent: " + err.Error())
+	}
+	topUps, err := getApprovedTopUps(agent.ID, start, end)
+	if err != nil {
+		return SettlementReport{}, errors.New("agents.settlementForAgent: " + err.Error())
+	}
+	balance, err := getFloatBalance(agent.FloatAccountNumber)
+	if err != nil {
+		return SettlementReport{}, errors.New("agents.settlementForAgent: " + err.Error())
+	}
+
+	report := buildSettlementReport(agent, start, transactions, topUps)
+	report.FloatBalance = balance
+	return report, nil
+}
+
+func buildSettlementReport(agent Agent, day time.Time, transactions []Transaction, topUps []TopUp) SettlementReport {
+	report := SettlementReport{
+		AgentCode:       agent.AgentCode,
+		AgentName:       agent.Name,
+		Date:            day.Format("2006-01-02"),
+		TotalCommission: decimal.Zero,
+		TopUps:          decimal.Zero,
+		CashPosition:    decimal.Zero,
+		FloatMovement:   decimal.Zero,
+		FloatBalance:    decimal.Zero,
+	}
+
+	lines := map[string]*SettlementLine{}
+	for _, operation := range []string{OperationCashIn, OperationCashOut, OperationCashPickup} {
+		lines[operation] = &SettlementLine{Operation: operation, Amount: decimal.Zero, Commission: decimal.Zero}
+	}
+
+	for _, transaction := range transactions {
+		line, ok := lines[transaction.Operation]
+		if !ok {
+			continue
+		}
+		line.Count++
+		line.Amount = line.Amount.Add(transaction.Amount)
+		line.Commission = line.Commission.Add(transaction.Commission)
+		report.TotalCommission = report.TotalCommission.Add(transaction.Commission)
+
+		// Cash in takes cash and pays out of float, the others pay cash and fill the float
+		if transaction.Operation == OperationCashIn {
+			report.CashPosition = report.CashPosition.Add(transaction.Amount)
+			report.FloatMovement = report.FloatMovement.Sub(transaction.Amount)
+		} else {
+			report.CashPosition = report.CashPosition.Sub(transaction.Amount)
+			report.FloatMovement = report.FloatMovement.Add(transaction.Amount)
+		}
+	}
+
+	for _, topUp := range topUps {
+		report.TopUps = report.TopUps.Add(topUp.Amount)
+		report.FloatMovement = report.FloatMovement.Add(topUp.Amount)
+	}
+
+	report.Lines = []SettlementLine{*lines[OperationCashIn], *lines[OperationCashOut], *lines[OperationCashPickup]}
+	return report
+}
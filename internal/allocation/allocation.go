@@ -0,0 +1,262 @@
+package allocation
+
+/*
+Account number allocation
+
+Every account number we issue, whatever the scheme, is recorded once in the account_numbers
+registry. The number is its primary key, so a number can only ever be handed out once and
+never to two schemes. Numbers issued before the registry existed are loaded into it by the
+migration that creates it.
+
+Opening an account allocates its UK account number and NUBAN together, once, and the same
+numbers are written to the account and its holder's details.
+
+Ranges of numbers can be reserved for system accounts (fees, settlement, vanity numbers).
+General allocation skips reserved numbers, they're only issued when asked for by number
+with AllocateReserved.
+*/
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/nuban"
+	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
+	"github.com/ebitezion/backend-framework/internal/usacctgen"
+)
+
+const (
+	SCHEME_UK    = "uk"
+	SCHEME_NUBAN = "nuban"
+	SCHEME_US    = "us"
+
+	// Attempts before giving up on finding a free number, each attempt uses a fresh serial
+	MAX_ATTEMPTS = 100
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// AccountNumbers are the numbers allocated to one account opening
+type AccountNumbers struct {
+	AccountNumber string
+	BankNumber    string
+}
+
+// Reservation is a range of numbers held back from general allocation
+type Reservation struct {
+	ID         int64     `json:"id"`
+	Scheme     string    `json:"scheme"`
+	RangeStart string    `json:"rangeStart"`
+	RangeEnd   string    `json:"rangeEnd"`
+	Purpose    string    `json:"purpose"`
+	Creator    string    `json:"creator"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Contains checks whether a number falls within the reservation.
+// Numbers are compared as digit strings of the range's length.
+func (r Reservation) Contains(number string) bool {
+	return len(number) == len(r.RangeStart) && number >= r.RangeStart && number <= r.RangeEnd
+}
+
+// AllocateAccountNumbers allocates the UK account number and NUBAN for an account opening
+func AllocateAccountNumbers(purpose string) (AccountNumbers, error) {
+	accountNumber, err := AllocateUK(purpose)
+	if err != nil {
+		return AccountNumbers{}, errors.New("allocation.AllocateAccountNumbers: " + err.Error())
+	}
+	bankNumber, err := AllocateNUBAN(purpose)
+	if err != nil {
+		return AccountNumbers{}, errors.New("allocation.AllocateAccountNumbers: " + err.Error())
+	}
+
+	return AccountNumbers{AccountNumber: accountNumber, BankNumber: bankNumber}, nil
+}
+
+// AllocateUK allocates the next free UK account number under the configured sort code
+func AllocateUK(purpose string) (string, error) {
+	number, err := allocate(SCHEME_UK, purpose, ukaccountgen.New().GenerateUKAccountNumber)
+	if err != nil {
+		return "", errors.New("allocation.AllocateUK: " + err.Error())
+	}
+	return number, nil
+}
+
+// AllocateNUBAN allocates the next free NUBAN for the configured bank code
+func AllocateNUBAN(purpose string) (string, error) {
+	number, err := allocate(SCHEME_NUBAN, purpose, nuban.NewNUBANGenerator().GenerateNUBAN)
+	if err != nil {
+		return "", errors.New("allocation.AllocateNUBAN: " + err.Error())
+	}
+	return number, nil
+}
+
+// AllocateUS allocates the next free US account number under the configured routing number
+func AllocateUS(purpose string) (usacctgen.USAccount, error) {
+	generator := usacctgen.NewUSAccountGenerator()
+	number, err := allocate(SCHEME_US, purpose, func() (string, error) {
+		account, err := generator.GenerateUSAccountNumber()
+		return account.AccountNumber, err
+	})
+	if err != nil {
+		return usacctgen.USAccount{}, errors.New("allocation.AllocateUS: " + err.Error())
+	}
+	return usacctgen.USAccount{RoutingNumber: generator.RoutingNumber, AccountNumber: number}, nil
+}
+
+// AllocateReserved issues a specific number from a reserved range, for system and vanity accounts
+func AllocateReserved(scheme string, number string, purpose string) error {
+	number = strings.TrimSpace(number)
+	err := validateNumber(scheme, number)
+	if err != nil {
+		return errors.New("allocation.AllocateReserved: " + err.Error())
+	}
+
+	reservations, err := getReservations(scheme)
+	if err != nil {
+		return errors.New("allocation.AllocateReserved: " + err.Error())
+	}
+	if !isReserved(reservations, number) {
+		return errors.New("allocation.AllocateReserved: " + number + " is not in a reserved range")
+	}
+
+	registered, err := registerNumber(scheme, number, purpose)
+	if err != nil {
+		return errors.New("allocation.AllocateReserved: " + err.Error())
+	}
+	if !registered {
+		return errors.New("allocation.AllocateReserved: " + number + " has already been allocated")
+	}
+
+	return nil
+}
+
+// Reserve holds a range of numbers back from general allocation.
+// The range can't contain numbers that have already been allocated.
+func Reserve(scheme string, rangeStart string, rangeEnd string, purpose string, creator string) (Reservation, error) {
+	reservation := Reservation{
+		Scheme:     scheme,
+		RangeStart: strings.TrimSpace(rangeStart),
+		RangeEnd:   strings.TrimSpace(rangeEnd),
+		Purpose:    strings.TrimSpace(purpose),
+		Creator:    creator,
+	}
+	length, err := numberLength(scheme)
+	if err != nil {
+		return Reservation{}, errors.New("allocation.Reserve: " + err.Error())
+	}
+	if len(reservation.RangeStart) != length || len(reservation.RangeEnd) != length || !isDigits(reservation.RangeStart) || !isDigits(reservation.RangeEnd) {
+		return Reservation{}, errors.New("allocation.Reserve: " + scheme + " ranges must be " + strconv.Itoa(length) + " digits")
+	}
+	if reservation.RangeStart > reservation.RangeEnd {
+		return Reservation{}, errors.New("allocation.Reserve: Range start must not be after range end")
+	}
+	if reservation.Purpose == "" {
+		return Reservation{}, errors.New("allocation.Reserve: Purpose cannot be empty")
+	}
+
+	allocated, err := countAllocatedInRange(scheme, reservation.RangeStart, reservation.RangeEnd)
+	if err != nil {
+		return Reservation{}, errors.New("allocation.Reserve: " + err.Error())
+	}
+	if allocated > 0 {
+		return Reservation{}, errors.New("allocation.Reserve: " + strconv.FormatInt(allocated, 10) + " numbers in the range have already been allocated")
+	}
+
+	reservation.ID, err = saveReservation(reservation)
+	if err != nil {
+		return Reservation{}, errors.New("allocation.Reserve: " + err.Error())
+	}
+	reservation.Timestamp = time.Now()
+
+	return reservation, nil
+}
+
+// Reservations lists the reserved ranges for every scheme
+func Reservations() ([]Reservation, error) {
+	reservations, err := getReservations("")
+	if err != nil {
+		return nil, errors.New("allocation.Reservations: " + err.Error())
+	}
+	return reservations, nil
+}
+
+// allocate takes numbers from the scheme's generator until one is neither reserved nor
+// already in the registry, and registers it
+func allocate(scheme string, purpose string, generate func() (string, error)) (string, error) {
+	reservations, err := getReservations(scheme)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < MAX_ATTEMPTS; attempt++ {
+		number, err := generate()
+		if err != nil {
+			return "", err
+		}
+		if isReserved(reservations, number) {
+			continue
+		}
+
+		registered, err := registerNumber(scheme, number, purpose)
+		if err != nil {
+			return "", err
+		}
+		if registered {
+			return number, nil
+		}
+	}
+
+	return "", errors.New("No free " + scheme + " number after " + strconv.Itoa(MAX_ATTEMPTS) + " attempts")
+}
+
+func isReserved(reservations []Reservation, number string) bool {
+	for _, reservation := range reservations {
+		if reservation.Contains(number) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNumber checks a number is well formed for its scheme under our configured codes
+func validateNumber(scheme string, number string) error {
+	switch scheme {
+	case SCHEME_UK:
+		return ukaccountgen.ValidateUKAccount(os.Getenv(ukaccountgen.SORT_CODE_ENV), number)
+	case SCHEME_NUBAN:
+		return nuban.ValidateNUBAN(os.Getenv(nuban.BANK_CODE_ENV), number)
+	case SCHEME_US:
+		return usacctgen.ValidateAccountNumber(number)
+	}
+	return errors.New("Unknown scheme " + scheme)
+}
+
+func numberLength(scheme string) (int, error) {
+	switch scheme {
+	case SCHEME_UK:
+		return ukaccountgen.ACCOUNT_NUMBER_LENGTH, nil
+	case SCHEME_NUBAN:
+		return nuban.NUBAN_LENGTH, nil
+	case SCHEME_US:
+		return usacctgen.ACCOUNT_NUMBER_LENGTH, nil
+	}
+	return 0, errors.New("Unknown scheme " + scheme)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
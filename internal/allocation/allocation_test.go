@@ -0,0 +1,50 @@
+package allocation
+
+import (
+	"testing"
+)
+
+func TestReservationContains(t *testing.T) {
+	reservation := Reservation{Scheme: SCHEME_UK, RangeStart: "00000100", RangeEnd: "00000199"}
+
+	for _, number := range []string{"00000100", "00000150", "00000199"} {
+		if !reservation.Contains(number) {
+			t.Errorf("Contains does not pass. Looking for %v, got %v for %v", true, false, number)
+		}
+	}
+	for _, number := range []string{"00000099", "00000200", "0000015", "000001500"} {
+		if reservation.Contains(number) {
+			t.Errorf("Contains does not pass. Looking for %v, got %v for %v", false, true, number)
+		}
+	}
+}
+
+func TestIsReserved(t *testing.T) {
+	reservations := []Reservation{
+		{Scheme: SCHEME_UK, RangeStart: "00000100", RangeEnd: "00000199"},
+		{Scheme: SCHEME_UK, RangeStart: "99999000", RangeEnd: "99999999"},
+	}
+
+	if !isReserved(reservations, "99999123") {
+		t.Errorf("isReserved does not pass. Looking for %v, got %v", true, false)
+	}
+	if isReserved(reservations, "00000200") {
+		t.Errorf("isReserved does not pass. Looking for %v, got %v", false, true)
+	}
+	if isReserved(nil, "00000150") {
+		t.Errorf("isReserved does not pass. Looking for %v, got %v", false, true)
+	}
+}
+
+func TestNumberLength(t *testing.T) {
+	cases := map[string]int{SCHEME_UK: 8, SCHEME_NUBAN: 10, SCHEME_US: 10}
+	for scheme, length := range cases {
+		got, err := numberLength(scheme)
+		if err != nil || got != length {
+			t.Errorf("numberLength does not pass. Looking for %v, got %v (%v)", length, got, err)
+		}
+	}
+	if _, err := numberLength("iban"); err == nil {
+		t.Errorf("numberLength does not pass. Looking for %v, got %v", "error", err)
+	}
+}
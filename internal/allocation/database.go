@@ -0,0 +1,96 @@
+package allocation
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const reservationColumns = "`id`, `scheme`, `rangeStart`, `rangeEnd`, `purpose`, `creator`, `timestamp`"
+
+// registerNumber records a number in the registry. It returns false when the number has
+// already been allocated, under any scheme.
+func registerNumber(scheme string, number string, purpose string) (registered bool, err error) {
+	insertStatement := "INSERT IGNORE INTO account_numbers (`number`, `scheme`, `purpose`) VALUES(?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return false, errors.New("allocation.registerNumber: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(number, scheme, purpose)
+	if err != nil {
+		return false, errors.New("allocation.registerNumber: " + err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.New("allocation.registerNumber: " + err.Error())
+	}
+
+	return rows == 1, nil
+}
+
+func countAllocatedInRange(scheme string, rangeStart string, rangeEnd string) (count int64, err error) {
+	err = Config.Db.QueryRow("SELECT COUNT(*) FROM `account_numbers` WHERE `scheme` = ? AND LENGTH(`number`) = ? AND `number` BETWEEN ? AND ?",
+		scheme, len(rangeStart), rangeStart, rangeEnd).Scan(&count)
+	if err != nil {
+		return 0, errors.New("allocation.countAllocatedInRange: " + err.Error())
+	}
+	return
+}
+
+func saveReservation(reservation Reservation) (id int64, err error) {
+	insertStatement := "INSERT INTO account_number_reservations (`scheme`, `rangeStart`, `rangeEnd`, `purpose`, `creator`) VALUES(?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("allocation.saveReservation: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(reservation.Scheme, reservation.RangeStart, reservation.RangeEnd, reservation.Purpose, reservation.Creator)
+	if err != nil {
+		return 0, errors.New("allocation.saveReservation: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("allocation.saveReservation: " + err.Error())
+	}
+
+	return
+}
+
+// getReservations lists the reserved ranges for a scheme, or every scheme when it's empty
+func getReservations(scheme string) (reservations []Reservation, err error) {
+	rows, err := Config.Db.Query("SELECT "+reservationColumns+" FROM `account_number_reservations` WHERE ? = '' OR `scheme` = ? ORDER BY `scheme`, `rangeStart`", scheme, scheme)
+	if err != nil {
+		return nil, errors.New("allocation.getReservations: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanReservations(rows)
+}
+
+func scanReservations(rows *sql.Rows) (reservations []Reservation, err error) {
+	reservations = make([]Reservation, 0)
+	for rows.Next() {
+		var reservation Reservation
+		var timestamp string
+		if err := rows.Scan(&reservation.ID, &reservation.Scheme, &reservation.RangeStart, &reservation.RangeEnd, &reservation.Purpose, &reservation.Creator, &timestamp); err != nil {
+			return nil, errors.New("allocation.scanReservations: " + err.Error())
+		}
+		if reservation.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("allocation.scanReservations: " + err.Error())
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("allocation.scanReservations: " + err.Error())
+	}
+
+	return reservations, nil
+}
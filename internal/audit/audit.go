@@ -0,0 +1,76 @@
+package audit
+
+/*
+Audit log
+
+Staff actions that change money movement settings are recorded with who did what, to
+which record, and the details at the time. Entries are only ever appended.
+*/
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+type Entry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Entity    string    `json:"entity"`
+	EntityID  string    `json:"entityId"`
+	Details   string    `json:"details"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Record appends an entry to the audit log
+func Record(actor string, action string, entity string, entityID string, details string) (err error) {
+	stmtIns, err := Config.Db.Prepare("INSERT INTO audit_logs (`actor`, `action`, `entity`, `entityId`, `details`, `timestamp`) VALUES(?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return errors.New("audit.Record: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(actor, action, entity, entityID, details, time.Now().UTC())
+	if err != nil {
+		return errors.New("audit.Record: " + err.Error())
+	}
+
+	return nil
+}
+
+// Entries returns the audit trail for one record, or for every record of the entity when entityID is empty, newest first
+func Entries(entity string, entityID string) (entries []Entry, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `actor`, `action`, `entity`, `entityId`, `details`, `timestamp` FROM `audit_logs` WHERE `entity` = ? AND (? = '' OR `entityId` = ?) ORDER BY `timestamp` DESC, `id` DESC", entity, entityID, entityID)
+	if err != nil {
+		return nil, errors.New("audit.Entries: " + err.Error())
+	}
+	defer rows.Close()
+
+	entries = make([]Entry, 0)
+	for rows.Next() {
+		var entry Entry
+		var timestamp string
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Entity, &entry.EntityID, &entry.Details, &timestamp); err != nil {
+			return nil, errors.New("audit.Entries: " + err.Error())
+		}
+		if entry.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("audit.Entries: " + err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("audit.Entries: " + err.Error())
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,196 @@
+package converter
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gopkg.in/redis.v3"
+)
+
+const (
+	RATES_CACHE_TTL      = 30 * time.Minute
+	CURRENCIES_CACHE_TTL = 24 * time.Hour
+	ratesCachePrefix     = "converter:rates:"
+	currenciesCacheKey   = "converter:currencies"
+)
+
+// StoredProvider keeps the upstream quota down. Latest rates are cached in Redis for
+// RATES_CACHE_TTL, and every set of rates fetched upstream is saved as a snapshot so
+// historical requests are answered from our own history when we have the date.
+// A nil Redis or Db skips the cache or the history. Rates that could not be saved are still
+// returned, without a snapshot ID, and are not cached so the next lookup saves them.
+type StoredProvider struct {
+	Provider RateProvider
+	Redis    *redis.Client
+	Db       *sql.DB
+}
+
+func (p StoredProvider) Name() string {
+	return p.Provider.Name()
+}
+
+func (p StoredProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	baseCurrency = strings.ToUpper(baseCurrency)
+	if rates, ok := p.cachedRates(baseCurrency); ok {
+		return rates, nil
+	}
+	return p.Refresh(baseCurrency)
+}
+
+// Refresh fetches the latest rates upstream, bypassing the cache, and stores them
+func (p StoredProvider) Refresh(baseCurrency string) (ExchangeRates, error) {
+	baseCurrency = strings.ToUpper(baseCurrency)
+	rates, err := p.Provider.Rates(baseCurrency)
+	if err != nil {
+		return ExchangeRates{}, err
+	}
+
+	rates.SnapshotID, err = p.store(rates, time.Now().UTC().Format(DATE_LAYOUT))
+	if err != nil {
+		log.Println(err)
+		return rates, nil
+	}
+	if p.Redis != nil {
+		if encoded, err := json.Marshal(rates); err == nil {
+			p.Redis.Set(ratesCachePrefix+baseCurrency, string(encoded), RATES_CACHE_TTL)
+		}
+	}
+
+	return rates, nil
+}
+
+func (p StoredProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	baseCurrency = strings.ToUpper(baseCurrency)
+	if p.Db != nil {
+		snapshot, err := getSnapshotForDate(p.Db, date)
+		if err != nil && err != sql.ErrNoRows {
+			return ExchangeRates{}, errors.New("converter.StoredProvider: " + err.Error())
+		}
+		if err == nil {
+			// Snapshots may be in another base, cross them like a static table
+			rates, err := NewStaticRateProvider(ExchangeRates{Base: snapshot.Base, Rates: snapshot.Rates}).Rates(baseCurrency)
+			if err == nil {
+				rates.SnapshotID = snapshot.ID
+				return rates, nil
+			}
+		}
+	}
+
+	rates, err := p.Provider.HistoricalRates(date, baseCurrency)
+	if err != nil {
+		return ExchangeRates{}, err
+	}
+	rates.SnapshotID, err = p.store(rates, date)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return rates, nil
+}
+
+func (p StoredProvider) cachedRates(baseCurrency string) (ExchangeRates, bool) {
+	if p.Redis == nil {
+		return ExchangeRates{}, false
+	}
+	cached, err := p.Redis.Get(ratesCachePrefix + baseCurrency).Result()
+	if err != nil {
+		return ExchangeRates{}, false
+	}
+
+	var rates ExchangeRates
+	if err := json.Unmarshal([]byte(cached), &rates); err != nil {
+		return ExchangeRates{}, false
+	}
+	return rates, true
+}
+
+func (p StoredProvider) store(rates ExchangeRates, date string) (snapshotID int64, err error) {
+	if p.Db == nil {
+		return 0, nil
+	}
+	provider := rates.Provider
+	if provider == "" {
+		provider = p.Provider.Name()
+	}
+	snapshotID, err = saveSnapshot(p.Db, RateSnapshot{
+		Provider:  provider,
+		Base:      rates.Base,
+		RateDate:  date,
+		Rates:     rates.Rates,
+		FetchedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, errors.New("converter.StoredProvider: " + err.Error())
+	}
+	return snapshotID, nil
+}
+
+// RefreshRates fetches the latest rates for each base upstream so requests are served from
+// the cache. It is run by the background jobs.
+func RefreshRates(bases []string) (refreshed int, err error) {
+	stored, ok := Provider().(StoredProvider)
+	if !ok {
+		return 0, nil
+	}
+
+	failures := make([]string, 0)
+	for _, base := range bases {
+		_, err := stored.Refresh(base)
+		if err != nil {
+			failures = append(failures, base+": "+err.Error())
+			continue
+		}
+		refreshed++
+	}
+	if len(failures) > 0 {
+		return refreshed, errors.New("converter.RefreshRates: " + strings.Join(failures, "; "))
+	}
+
+	return refreshed, nil
+}
+
+// ClearCachedRates drops the cached latest rates for each base so the next lookup goes to the provider
+func ClearCachedRates(bases []string) error {
+	if Config.Redis == nil || len(bases) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(bases))
+	for i, base := range bases {
+		keys[i] = ratesCachePrefix + strings.ToUpper(base)
+	}
+	err := Config.Redis.Del(keys...).Err()
+	if err != nil {
+		return errors.New("converter.ClearCachedRates: " + err.Error())
+	}
+
+	return nil
+}
+
+// cachedCurrencies returns the openexchangerates.org currency list, cached for CURRENCIES_CACHE_TTL
+func cachedCurrencies(fetch func() (map[string]string, error)) (map[string]string, error) {
+	if Config.Redis != nil {
+		if cached, err := Config.Redis.Get(currenciesCacheKey).Result(); err == nil {
+			var currencies map[string]string
+			if err := json.Unmarshal([]byte(cached), &currencies); err == nil {
+				return currencies, nil
+			}
+		}
+	}
+
+	currencies, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if Config.Redis != nil {
+		if encoded, err := json.Marshal(currencies); err == nil {
+			Config.Redis.Set(currenciesCacheKey, string(encoded), CURRENCIES_CACHE_TTL)
+		}
+	}
+
+	return currencies, nil
+}
@@ -0,0 +1,131 @@
+package converter
+
+/*
+Conversion
+
+Amounts are converted locally from a rate table quoted against a single base currency,
+never by the provider. Cross rates are triangulated through the base (USD), e.g.
+
+	GBP -> NGN = (USD -> NGN) / (USD -> GBP)
+
+Rates are taken at SNAPSHOT_RATE_PLACES, the precision they are stored with in the rate
+history, so a conversion can be repeated exactly from its snapshot ID. The cross rate is
+kept to CONVERSION_RATE_PLACES and the result is rounded to the target currency's minor units.
+*/
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	TRIANGULATION_CURRENCY = "USD"
+	CONVERSION_RATE_PLACES = 10
+)
+
+type Conversion struct {
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Amount     decimal.Decimal `json:"amount"`
+	Result     decimal.Decimal `json:"result"`
+	Rate       decimal.Decimal `json:"rate"`
+	SnapshotID int64           `json:"snapshotId"`
+}
+
+// Convert converts amount at the latest rates from the configured provider
+func Convert(from, to string, amount decimal.Decimal) (Conversion, error) {
+	from = strings.ToUpper(strings.TrimSpace(from))
+	to = strings.ToUpper(strings.TrimSpace(to))
+
+	rates, err := Provider().Rates(TRIANGULATION_CURRENCY)
+	if err == nil {
+		conversion, crossErr := convert(rateTable(rates), from, to, amount)
+		if crossErr == nil {
+			conversion.SnapshotID = rates.SnapshotID
+			return conversion, nil
+		}
+		err = crossErr
+	}
+
+	// Providers such as the manual rate table may only quote the pair directly
+	direct, directErr := Provider().Rates(from)
+	if directErr != nil {
+		return Conversion{}, errors.New("converter.Convert: " + err.Error())
+	}
+	conversion, err := convert(rateTable(direct), from, to, amount)
+	if err != nil {
+		return Conversion{}, errors.New("converter.Convert: " + err.Error())
+	}
+	conversion.SnapshotID = direct.SnapshotID
+
+	return conversion, nil
+}
+
+// ConvertWithSnapshot repeats a conversion with the rates stored as snapshotID
+func ConvertWithSnapshot(snapshotID int64, from, to string, amount decimal.Decimal) (Conversion, error) {
+	if Config.Db == nil {
+		return Conversion{}, errors.New("converter.ConvertWithSnapshot: Rate history is not configured")
+	}
+	snapshot, err := getSnapshot(Config.Db, snapshotID)
+	if err != nil {
+		return Conversion{}, errors.New("converter.ConvertWithSnapshot: " + err.Error())
+	}
+	table, err := getSnapshotRateTable(Config.Db, snapshot.ID)
+	if err != nil {
+		return Conversion{}, errors.New("converter.ConvertWithSnapshot: " + err.Error())
+	}
+	table[strings.ToUpper(snapshot.Base)] = decimal.NewFromInt(1)
+
+	conversion, err := convert(table, strings.ToUpper(strings.TrimSpace(from)), strings.ToUpper(strings.TrimSpace(to)), amount)
+	if err != nil {
+		return Conversion{}, errors.New("converter.ConvertWithSnapshot: " + err.Error())
+	}
+	conversion.SnapshotID = snapshot.ID
+
+	return conversion, nil
+}
+
+func convert(table map[string]decimal.Decimal, from, to string, amount decimal.Decimal) (Conversion, error) {
+	rate, err := crossRate(table, from, to)
+	if err != nil {
+		return Conversion{}, errors.New("converter.convert: " + err.Error())
+	}
+
+	return Conversion{
+		From:   from,
+		To:     to,
+		Amount: amount,
+		Result: currency.Round(to, amount.Mul(rate)),
+		Rate:   rate,
+	}, nil
+}
+
+// crossRate returns the rate for one unit of from in to. Both are quoted against the table's base.
+func crossRate(table map[string]decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	fromRate, ok := table[from]
+	if !ok || !fromRate.IsPositive() {
+		return decimal.Zero, errors.New("converter.crossRate: No rate for " + from)
+	}
+	toRate, ok := table[to]
+	if !ok || !toRate.IsPositive() {
+		return decimal.Zero, errors.New("converter.crossRate: No rate for " + to)
+	}
+
+	return toRate.DivRound(fromRate, CONVERSION_RATE_PLACES), nil
+}
+
+// rateTable takes provider rates at the precision they are stored with in the rate history
+func rateTable(rates ExchangeRates) map[string]decimal.Decimal {
+	table := make(map[string]decimal.Decimal, len(rates.Rates)+1)
+	for code, rate := range rates.Rates {
+		table[strings.ToUpper(code)] = rate.Round(SNAPSHOT_RATE_PLACES)
+	}
+	table[strings.ToUpper(rates.Base)] = decimal.NewFromInt(1)
+	return table
+}
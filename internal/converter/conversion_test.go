@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCrossRate(t *testing.T) {
+	table := rateTable(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.8"), "NGN": decimal.RequireFromString("1600")}})
+
+	rate, err := crossRate(table, "GBP", "NGN")
+	if err != nil || !rate.Equal(decimal.NewFromInt(2000)) {
+		t.Errorf("crossRate does not pass. Looking for %v, got %v (%v)", 2000, rate, err)
+	}
+	rate, err = crossRate(table, "NGN", "USD")
+	if err != nil || !rate.Equal(decimal.RequireFromString("0.000625")) {
+		t.Errorf("crossRate does not pass. Looking for %v, got %v (%v)", "0.000625", rate, err)
+	}
+	rate, err = crossRate(table, "EUR", "EUR")
+	if err != nil || !rate.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("crossRate does not pass. Looking for %v, got %v (%v)", 1, rate, err)
+	}
+
+	_, err = crossRate(table, "GBP", "EUR")
+	if err == nil {
+		t.Errorf("crossRate does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestConvertRounding(t *testing.T) {
+	table := rateTable(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.79"), "NGN": decimal.RequireFromString("1550")}})
+
+	// 1000 NGN is 0.5096774193548... GBP, rounded to pence
+	conversion, err := convert(table, "NGN", "GBP", decimal.NewFromInt(1000))
+	if err != nil {
+		t.Errorf("convert does not pass. Looking for %v, got %v", nil, err)
+	}
+	if !conversion.Result.Equal(decimal.RequireFromString("0.51")) {
+		t.Errorf("convert does not pass. Looking for %v, got %v", "0.51", conversion.Result)
+	}
+	if !conversion.Rate.Equal(decimal.RequireFromString("0.0005096774")) {
+		t.Errorf("convert does not pass. Looking for %v, got %v", "0.0005096774", conversion.Rate)
+	}
+}
+
+func TestConvertFromStoredPrecision(t *testing.T) {
+	rates := ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.7912345678901234"), "NGN": decimal.RequireFromString("1550.123456789012")}}
+
+	// The snapshot holds the rates as stored in fx_rate_snapshot_rates
+	stored := map[string]decimal.Decimal{
+		"USD": decimal.NewFromInt(1),
+		"GBP": decimal.RequireFromString("0.791234567890"),
+		"NGN": decimal.RequireFromString("1550.123456789012"),
+	}
+
+	live, err := convert(rateTable(rates), "GBP", "NGN", decimal.NewFromInt(250))
+	if err != nil {
+		t.Errorf("convert does not pass. Looking for %v, got %v", nil, err)
+	}
+	replayed, err := convert(stored, "GBP", "NGN", decimal.NewFromInt(250))
+	if err != nil {
+		t.Errorf("convert does not pass. Looking for %v, got %v", nil, err)
+	}
+	if !live.Rate.Equal(replayed.Rate) || !live.Result.Equal(replayed.Result) {
+		t.Errorf("convert does not pass. Looking for %v, got %v", replayed, live)
+	}
+}
+
+func TestConvertWithProvider(t *testing.T) {
+	SetProvider(NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.8"), "NGN": decimal.RequireFromString("1600")}}))
+	defer SetProvider(nil)
+
+	conversion, err := Convert("gbp", "ngn", decimal.RequireFromString("10.50"))
+	if err != nil || !conversion.Result.Equal(decimal.NewFromInt(21000)) {
+		t.Errorf("Convert does not pass. Looking for %v, got %v (%v)", 21000, conversion.Result, err)
+	}
+	if conversion.From != "GBP" || conversion.To != "NGN" {
+		t.Errorf("Convert does not pass. Looking for %v, got %v", "GBP/NGN", conversion.From+"/"+conversion.To)
+	}
+}
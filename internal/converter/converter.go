@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/shopspring/decimal"
 	"github.com/valyala/fasthttp"
 )
 
@@ -13,146 +14,174 @@ var (
 	appID   = os.Getenv("OPEN_EXCHANGE_RATES_APP_ID") // Use environment variable for App ID
 )
 
+// ExchangeRates are parsed straight into decimals so rates keep every digit the provider sent
 type ExchangeRates struct {
-	Rates map[string]float64 `json:"rates"`
-	Base  string             `json:"base"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+	Base  string                     `json:"base"`
+	// Provider is the provider that served the rates when they came through a FallbackProvider
+	Provider string `json:"provider,omitempty"`
+	// SnapshotID is the rate history snapshot the rates were stored as, 0 when they were not stored
+	SnapshotID int64 `json:"snapshotId,omitempty"`
 }
 
 type ErrorResponse struct {
 	Message string `json:"error"`
 }
 
-func createRequest(url string) (*fasthttp.Request, error) {
-	req := fasthttp.AcquireRequest()
-	req.SetRequestURI(url)
-	return req, nil
+// OpenExchangeRates fetches rates from openexchangerates.org.
+// Empty fields fall back to the package defaults and OPEN_EXCHANGE_RATES_APP_ID.
+type OpenExchangeRates struct {
+	BaseURL string
+	AppID   string
+	Client  *fasthttp.Client
 }
 
-func doRequest(req *fasthttp.Request) (*fasthttp.Response, error) {
-	resp := fasthttp.AcquireResponse()
-	err := fasthttp.Do(req, resp)
-	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
-	}
-	return resp, nil
-}
-
-func handleResponse(resp *fasthttp.Response, target interface{}) error {
-	if resp.StatusCode() != fasthttp.StatusOK {
-		var errorResponse ErrorResponse
-		if err := json.Unmarshal(resp.Body(), &errorResponse); err == nil {
-			return fmt.Errorf("API error: %s", errorResponse.Message)
-		}
-		return fmt.Errorf("API error: status code %d", resp.StatusCode())
-	}
-
-	if err := json.Unmarshal(resp.Body(), target); err != nil {
-		return fmt.Errorf("failed to parse response: %v", err)
-	}
-	return nil
+func (p OpenExchangeRates) Name() string {
+	return ProviderOpenExchangeRates
 }
 
-func GetExchangeRates(baseCurrency string) (ExchangeRates, error) {
-	url := fmt.Sprintf("%s/latest.json?app_id=%s&base=%s", baseURL, appID, baseCurrency)
-	req, err := createRequest(url)
-	if err != nil {
-		return ExchangeRates{}, err
-	}
-
-	resp, err := doRequest(req)
+func (p OpenExchangeRates) Rates(baseCurrency string) (ExchangeRates, error) {
+	var rates ExchangeRates
+	err := p.get(fmt.Sprintf("%s/latest.json?app_id=%s&base=%s", p.baseURL(), p.appID(), baseCurrency), &rates)
 	if err != nil {
 		return ExchangeRates{}, err
 	}
-	defer fasthttp.ReleaseResponse(resp)
+	return rates, nil
+}
 
+func (p OpenExchangeRates) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
 	var rates ExchangeRates
-	if err := handleResponse(resp, &rates); err != nil {
+	err := p.get(fmt.Sprintf("%s/historical/%s.json?app_id=%s&base=%s", p.baseURL(), date, p.appID(), baseCurrency), &rates)
+	if err != nil {
 		return ExchangeRates{}, err
 	}
-
 	return rates, nil
 }
 
-func GetHistoricalExchangeRates(date, baseCurrency string) (ExchangeRates, error) {
-	url := fmt.Sprintf("%s/historical/%s.json?app_id=%s&base=%s", baseURL, date, appID, baseCurrency)
-	req, err := createRequest(url)
+func (p OpenExchangeRates) Usage() (map[string]interface{}, error) {
+	var usage map[string]interface{}
+	err := p.get(fmt.Sprintf("%s/usage.json?app_id=%s", p.baseURL(), p.appID()), &usage)
 	if err != nil {
-		return ExchangeRates{}, err
+		return nil, err
 	}
+	return usage, nil
+}
 
-	resp, err := doRequest(req)
+func (p OpenExchangeRates) Currencies() (map[string]string, error) {
+	var currencies map[string]string
+	err := p.get(fmt.Sprintf("%s/currencies.json", p.baseURL()), &currencies)
 	if err != nil {
-		return ExchangeRates{}, err
+		return nil, err
 	}
-	defer fasthttp.ReleaseResponse(resp)
+	return currencies, nil
+}
 
-	var rates ExchangeRates
-	if err := handleResponse(resp, &rates); err != nil {
-		return ExchangeRates{}, err
+func (p OpenExchangeRates) baseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
 	}
+	return baseURL
+}
 
-	return rates, nil
+func (p OpenExchangeRates) appID() string {
+	if p.AppID != "" {
+		return p.AppID
+	}
+	if appID != "" {
+		return appID
+	}
+	return os.Getenv("OPEN_EXCHANGE_RATES_APP_ID")
 }
 
-func ConvertCurrency(from, to string, amount float64) (float64, error) {
-	url := fmt.Sprintf("%s/convert?app_id=%s&from=%s&to=%s&amount=%f", baseURL, appID, from, to, amount)
+func (p OpenExchangeRates) get(url string, target interface{}) error {
 	req, err := createRequest(url)
 	if err != nil {
-		return 0, err
+		return err
 	}
+	defer fasthttp.ReleaseRequest(req)
 
-	resp, err := doRequest(req)
+	resp, err := p.doRequest(req)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer fasthttp.ReleaseResponse(resp)
 
-	var result map[string]float64
-	if err := handleResponse(resp, &result); err != nil {
-		return 0, err
+	return handleResponse(resp, target)
+}
+
+func (p OpenExchangeRates) doRequest(req *fasthttp.Request) (*fasthttp.Response, error) {
+	if p.Client == nil {
+		return doRequest(req)
+	}
+	resp := fasthttp.AcquireResponse()
+	err := p.Client.Do(req, resp)
+	if err != nil {
+		fasthttp.ReleaseResponse(resp)
+		return nil, fmt.Errorf("failed to perform request: %v", err)
 	}
+	return resp, nil
+}
 
-	return result["result"], nil
+func createRequest(url string) (*fasthttp.Request, error) {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(url)
+	return req, nil
 }
 
-func GetUsage() (map[string]interface{}, error) {
-	url := fmt.Sprintf("%s/usage.json?app_id=%s", baseURL, appID)
-	req, err := createRequest(url)
+func doRequest(req *fasthttp.Request) (*fasthttp.Response, error) {
+	resp := fasthttp.AcquireResponse()
+	err := fasthttp.Do(req, resp)
 	if err != nil {
-		return nil, err
+		fasthttp.ReleaseResponse(resp)
+		return nil, fmt.Errorf("failed to perform request: %v", err)
 	}
+	return resp, nil
+}
 
-	resp, err := doRequest(req)
-	if err != nil {
-		return nil, err
+func handleResponse(resp *fasthttp.Response, target interface{}) error {
+	if resp.StatusCode() != fasthttp.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.Unmarshal(resp.Body(), &errorResponse); err == nil {
+			return fmt.Errorf("API error: %s", errorResponse.Message)
+		}
+		return fmt.Errorf("API error: status code %d", resp.StatusCode())
 	}
-	defer fasthttp.ReleaseResponse(resp)
 
-	var usage map[string]interface{}
-	if err := handleResponse(resp, &usage); err != nil {
-		return nil, err
+	if err := json.Unmarshal(resp.Body(), target); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
 	}
+	return nil
+}
 
-	return usage, nil
+// GetExchangeRates returns the latest rates from the configured provider
+func GetExchangeRates(baseCurrency string) (ExchangeRates, error) {
+	return Provider().Rates(baseCurrency)
 }
 
-func GetAvailableCurrencies() (map[string]string, error) {
-	url := fmt.Sprintf("%s/currencies.json", baseURL)
-	req, err := createRequest(url)
-	if err != nil {
-		return nil, err
-	}
+// GetHistoricalExchangeRates returns the rates on date (YYYY-MM-DD) from the configured provider
+func GetHistoricalExchangeRates(date, baseCurrency string) (ExchangeRates, error) {
+	return Provider().HistoricalRates(date, baseCurrency)
+}
 
-	resp, err := doRequest(req)
+// ConvertCurrency converts amount at the latest rate from the configured provider.
+//
+// Deprecated: use Convert, which works in decimals and records the rate snapshot.
+func ConvertCurrency(from, to string, amount float64) (float64, error) {
+	conversion, err := Convert(from, to, decimal.NewFromFloat(amount))
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer fasthttp.ReleaseResponse(resp)
 
-	var currencies map[string]string
-	if err := handleResponse(resp, &currencies); err != nil {
-		return nil, err
-	}
+	result, _ := conversion.Result.Float64()
+	return result, nil
+}
 
-	return currencies, nil
+// GetUsage returns the openexchangerates.org plan usage
+func GetUsage() (map[string]interface{}, error) {
+	return OpenExchangeRates{}.Usage()
+}
+
+// GetAvailableCurrencies returns the currencies openexchangerates.org publishes rates for
+func GetAvailableCurrencies() (map[string]string, error) {
+	return cachedCurrencies(OpenExchangeRates{}.Currencies)
 }
@@ -0,0 +1,141 @@
+package converter
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+	DATE_LAYOUT     = "2006-01-02"
+	// Decimal places snapshot rates are stored with, see fx_rate_snapshot_rates.rate
+	SNAPSHOT_RATE_PLACES = 12
+)
+
+// RateSnapshot is one set of rates as fetched from a provider
+type RateSnapshot struct {
+	ID        int64                      `json:"id"`
+	Provider  string                     `json:"provider"`
+	Base      string                     `json:"base"`
+	RateDate  string                     `json:"rateDate"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+	FetchedAt time.Time                  `json:"fetchedAt"`
+}
+
+func saveSnapshot(db *sql.DB, snapshot RateSnapshot) (id int64, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, errors.New("converter.saveSnapshot: " + err.Error())
+	}
+
+	res, err := tx.Exec("INSERT INTO fx_rate_snapshots (`provider`, `baseCurrency`, `rateDate`, `fetchedAt`) VALUES(?, ?, ?, ?)", snapshot.Provider, snapshot.Base, snapshot.RateDate, snapshot.FetchedAt)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.New("converter.saveSnapshot: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.New("converter.saveSnapshot: " + err.Error())
+	}
+
+	stmtIns, err := tx.Prepare("INSERT INTO fx_rate_snapshot_rates (`snapshotId`, `currencyCode`, `rate`) VALUES(?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.New("converter.saveSnapshot: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	for code, rate := range snapshot.Rates {
+		_, err = stmtIns.Exec(id, code, rate)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.New("converter.saveSnapshot: " + err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("converter.saveSnapshot: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// getSnapshotForDate returns the last snapshot fetched for date, in any base.
+// sql.ErrNoRows is returned when there is none.
+func getSnapshotForDate(db *sql.DB, date string) (snapshot RateSnapshot, err error) {
+	snapshot, err = getSnapshotWhere(db, "`rateDate` = ? ORDER BY `fetchedAt` DESC, `id` DESC LIMIT 1", date)
+	if err == sql.ErrNoRows {
+		return RateSnapshot{}, err
+	}
+	if err != nil {
+		return RateSnapshot{}, errors.New("converter.getSnapshotForDate: " + err.Error())
+	}
+	return snapshot, nil
+}
+
+// getSnapshot returns a snapshot by its ID
+func getSnapshot(db *sql.DB, id int64) (snapshot RateSnapshot, err error) {
+	snapshot, err = getSnapshotWhere(db, "`id` = ?", id)
+	if err == sql.ErrNoRows {
+		return RateSnapshot{}, errors.New("converter.getSnapshot: Rate snapshot not found")
+	}
+	if err != nil {
+		return RateSnapshot{}, errors.New("converter.getSnapshot: " + err.Error())
+	}
+	return snapshot, nil
+}
+
+func getSnapshotWhere(db *sql.DB, condition string, value interface{}) (snapshot RateSnapshot, err error) {
+	row := db.QueryRow("SELECT `id`, `provider`, `baseCurrency`, `rateDate`, `fetchedAt` FROM `fx_rate_snapshots` WHERE "+condition, value)
+
+	var rateDate, fetchedAt string
+	err = row.Scan(&snapshot.ID, &snapshot.Provider, &snapshot.Base, &rateDate, &fetchedAt)
+	if err == sql.ErrNoRows {
+		return RateSnapshot{}, err
+	}
+	if err != nil {
+		return RateSnapshot{}, errors.New("converter.getSnapshotWhere: " + err.Error())
+	}
+	// DATE columns come back as YYYY-MM-DD
+	snapshot.RateDate = rateDate
+	if snapshot.FetchedAt, err = time.Parse(SQL_TIME_LAYOUT, fetchedAt); err != nil {
+		return RateSnapshot{}, errors.New("converter.getSnapshotWhere: " + err.Error())
+	}
+
+	snapshot.Rates, err = getSnapshotRateTable(db, snapshot.ID)
+	if err != nil {
+		return RateSnapshot{}, errors.New("converter.getSnapshotWhere: " + err.Error())
+	}
+
+	return snapshot, nil
+}
+
+// getSnapshotRateTable returns the snapshot's rates exactly as stored
+func getSnapshotRateTable(db *sql.DB, snapshotID int64) (table map[string]decimal.Decimal, err error) {
+	rows, err := db.Query("SELECT `currencyCode`, `rate` FROM `fx_rate_snapshot_rates` WHERE `snapshotId` = ?", snapshotID)
+	if err != nil {
+		return nil, errors.New("converter.getSnapshotRateTable: " + err.Error())
+	}
+	defer rows.Close()
+
+	table = make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var code string
+		var rate decimal.Decimal
+		if err := rows.Scan(&code, &rate); err != nil {
+			return nil, errors.New("converter.getSnapshotRateTable: " + err.Error())
+		}
+		table[strings.ToUpper(code)] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("converter.getSnapshotRateTable: " + err.Error())
+	}
+
+	return table, nil
+}
@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ManualRateProvider serves the rates treasury maintains in fx_manual_rates.
+// A pair can be entered in either direction, the inverse is used when only the opposite pair is set.
+type ManualRateProvider struct {
+	Db *sql.DB
+}
+
+// ManualRate is the mid rate for one unit of base in quote. The bank buys base at
+// BuyRate and sells it at SellRate, the mid rate less and plus the margin.
+type ManualRate struct {
+	BaseCurrency  string              `json:"baseCurrency"`
+	QuoteCurrency string              `json:"quoteCurrency"`
+	Rate          decimal.Decimal     `json:"rate"`
+	Margin        decimal.NullDecimal `json:"margin"`
+	BuyRate       decimal.Decimal     `json:"buyRate"`
+	SellRate      decimal.Decimal     `json:"sellRate"`
+	SetBy         string              `json:"setBy"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// BuySellRates spreads a mid rate by margin either side
+func BuySellRates(rate decimal.Decimal, margin decimal.Decimal) (buyRate decimal.Decimal, sellRate decimal.Decimal) {
+	one := decimal.NewFromInt(1)
+	buyRate = rate.Mul(one.Sub(margin)).Round(CONVERSION_RATE_PLACES)
+	sellRate = rate.Mul(one.Add(margin)).Round(CONVERSION_RATE_PLACES)
+	return buyRate, sellRate
+}
+
+func (p ManualRateProvider) Name() string {
+	return ProviderManual
+}
+
+func (p ManualRateProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	baseCurrency = strings.ToUpper(baseCurrency)
+	rows, err := p.Db.Query("SELECT `baseCurrency`, `quoteCurrency`, `rate` FROM `fx_manual_rates` WHERE `baseCurrency` = ? OR `quoteCurrency` = ?", baseCurrency, baseCurrency)
+	if err != nil {
+		return ExchangeRates{}, errors.New("converter.ManualRateProvider: " + err.Error())
+	}
+	defer rows.Close()
+
+	rates := ExchangeRates{Base: baseCurrency, Rates: map[string]decimal.Decimal{baseCurrency: decimal.NewFromInt(1)}}
+	inverses := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var base, quote string
+		var rate decimal.Decimal
+		if err := rows.Scan(&base, &quote, &rate); err != nil {
+			return ExchangeRates{}, errors.New("converter.ManualRateProvider: " + err.Error())
+		}
+		if !rate.IsPositive() {
+			continue
+		}
+		if base == baseCurrency {
+			rates.Rates[quote] = rate
+		} else {
+			inverses[base] = decimal.NewFromInt(1).DivRound(rate, SNAPSHOT_RATE_PLACES)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ExchangeRates{}, errors.New("converter.ManualRateProvider: " + err.Error())
+	}
+
+	// Pairs entered in this direction take precedence over inverted ones
+	for code, rate := range inverses {
+		if _, ok := rates.Rates[code]; !ok {
+			rates.Rates[code] = rate
+		}
+	}
+	if len(rates.Rates) == 1 {
+		return ExchangeRates{}, errors.New("converter.ManualRateProvider: No manual rates for " + baseCurrency)
+	}
+
+	return rates, nil
+}
+
+// HistoricalRates is not supported, the table only holds the current rates
+func (p ManualRateProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	return ExchangeRates{}, errors.New("converter.ManualRateProvider: Historical rates are not kept")
+}
+
+// AllRates returns every manual rate pair
+func (p ManualRateProvider) AllRates() (rates []ManualRate, err error) {
+	rows, err := p.Db.Query("SELECT `baseCurrency`, `quoteCurrency`, `rate`, `margin`, `setBy`, `updated_at` FROM `fx_manual_rates` ORDER BY `baseCurrency`, `quoteCurrency`")
+	if err != nil {
+		return nil, errors.New("converter.ManualRateProvider.AllRates: " + err.Error())
+	}
+	defer rows.Close()
+
+	rates = make([]ManualRate, 0)
+	for rows.Next() {
+		var rate ManualRate
+		var updatedAt string
+		if err := rows.Scan(&rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate, &rate.Margin, &rate.SetBy, &updatedAt); err != nil {
+			return nil, errors.New("converter.ManualRateProvider.AllRates: " + err.Error())
+		}
+		if rate.UpdatedAt, err = time.Parse(SQL_TIME_LAYOUT, updatedAt); err != nil {
+			return nil, errors.New("converter.ManualRateProvider.AllRates: " + err.Error())
+		}
+		if rate.Margin.Valid {
+			rate.BuyRate, rate.SellRate = BuySellRates(rate.Rate, rate.Margin.Decimal)
+		} else {
+			rate.BuyRate, rate.SellRate = rate.Rate, rate.Rate
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("converter.ManualRateProvider.AllRates: " + err.Error())
+	}
+
+	return rates, nil
+}
+
+// SetRate creates or replaces the manual rate and margin for one unit of base in quote
+func (p ManualRateProvider) SetRate(rate ManualRate) (err error) {
+	if !rate.Rate.IsPositive() {
+		return errors.New("converter.ManualRateProvider.SetRate: Rate must be greater than zero")
+	}
+
+	insertStatement := "INSERT INTO fx_manual_rates (`baseCurrency`, `quoteCurrency`, `rate`, `margin`, `setBy`, `updated_at`) VALUES(?, ?, ?, ?, ?, ?) "
+	insertStatement += "ON DUPLICATE KEY UPDATE `rate` = VALUES(`rate`), `margin` = VALUES(`margin`), `setBy` = VALUES(`setBy`), `updated_at` = VALUES(`updated_at`)"
+	stmtIns, err := p.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("converter.ManualRateProvider.SetRate: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(strings.ToUpper(rate.BaseCurrency), strings.ToUpper(rate.QuoteCurrency), rate.Rate, rate.Margin, rate.SetBy, time.Now().UTC())
+	if err != nil {
+		return errors.New("converter.ManualRateProvider.SetRate: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package converter
+
+/*
+Rate providers
+
+Rates come from a RateProvider so the converter can run against openexchangerates.org,
+the treasury's manual rate table in MySQL, or a static rate file (or in-memory rates in
+tests). EXCHANGE_RATE_PROVIDERS lists the providers to use in order of preference, e.g.
+
+	EXCHANGE_RATE_PROVIDERS=openexchangerates,manual,static
+	EXCHANGE_RATES_FILE=internal/converter/rates.sample.json
+
+When more than one provider is listed the next one is tried whenever the one before it
+fails. openexchangerates is used when nothing is configured.
+
+The manual provider is not a step in that chain. Treasury's approved rates are laid over
+whatever the other providers return (see OverrideProvider), so a pair treasury has set
+always wins and every other pair still comes from upstream. Listed on its own it serves
+only the manual table.
+
+Whichever providers are configured, latest rates are cached in Redis and every fetched
+set of rates is kept in the rate history (see StoredProvider).
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ProviderOpenExchangeRates = "openexchangerates"
+	ProviderManual            = "manual"
+	ProviderStatic            = "static"
+	providersEnv              = "EXCHANGE_RATE_PROVIDERS"
+	ratesFileEnv              = "EXCHANGE_RATES_FILE"
+)
+
+type RateProvider interface {
+	// Name identifies the provider in configuration and errors
+	Name() string
+	// Rates returns the latest rates for one unit of baseCurrency
+	Rates(baseCurrency string) (ExchangeRates, error)
+	// HistoricalRates returns the rates for one unit of baseCurrency on date (YYYY-MM-DD)
+	HistoricalRates(date, baseCurrency string) (ExchangeRates, error)
+}
+
+var Config configuration.Configuration
+
+var (
+	provider      RateProvider
+	providerMutex sync.Mutex
+)
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// SetProvider replaces the configured provider, e.g. with a StaticRateProvider when running offline
+func SetProvider(p RateProvider) {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+	provider = p
+}
+
+// Provider returns the provider selected by EXCHANGE_RATE_PROVIDERS, behind the rate cache and history.
+// If the configuration is invalid the error is reported by every rate lookup.
+func Provider() RateProvider {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+
+	if provider == nil {
+		p, err := NewProvider(strings.Split(os.Getenv(providersEnv), ","))
+		if err != nil {
+			return unavailableProvider{err}
+		}
+		provider = StoredProvider{Provider: p, Redis: Config.Redis, Db: Config.Db}
+	}
+	return provider
+}
+
+// NewProvider builds the named providers in fallback order
+func NewProvider(names []string) (RateProvider, error) {
+	providers := make([]RateProvider, 0, len(names))
+	var manual RateProvider
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case ProviderOpenExchangeRates:
+			providers = append(providers, OpenExchangeRates{})
+		case ProviderManual:
+			if Config.Db == nil {
+				return nil, errors.New("converter.NewProvider: The manual provider needs a database")
+			}
+			manual = ManualRateProvider{Db: Config.Db}
+		case ProviderStatic:
+			static, err := LoadStaticRates(os.Getenv(ratesFileEnv))
+			if err != nil {
+				return nil, errors.New("converter.NewProvider: " + err.Error())
+			}
+			providers = append(providers, static)
+		default:
+			return nil, errors.New("converter.NewProvider: Unknown rate provider " + name)
+		}
+	}
+
+	var upstream RateProvider
+	switch len(providers) {
+	case 0:
+		if manual != nil {
+			return manual, nil
+		}
+		upstream = OpenExchangeRates{}
+	case 1:
+		upstream = providers[0]
+	default:
+		upstream = FallbackProvider{Providers: providers}
+	}
+	if manual != nil {
+		return OverrideProvider{Manual: manual, Provider: upstream}, nil
+	}
+	return upstream, nil
+}
+
+// OverrideProvider lays treasury's manual rates over the rates from Provider. Pairs in the
+// manual table replace the upstream ones, either on their own serves when the other fails.
+type OverrideProvider struct {
+	Manual   RateProvider
+	Provider RateProvider
+}
+
+func (p OverrideProvider) Name() string {
+	return ProviderManual + "+" + p.Provider.Name()
+}
+
+func (p OverrideProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	rates, err := p.Provider.Rates(baseCurrency)
+	manual, manualErr := p.Manual.Rates(baseCurrency)
+	if err != nil {
+		if manualErr != nil {
+			return ExchangeRates{}, errors.New("converter.OverrideProvider: " + err.Error() + ". " + manualErr.Error())
+		}
+		manual.Provider = ProviderManual
+		return manual, nil
+	}
+	if manualErr != nil {
+		// No manual rates for this base, or none that can be read, the upstream rates stand
+		return rates, nil
+	}
+
+	merged := make(map[string]decimal.Decimal, len(rates.Rates)+len(manual.Rates))
+	for code, rate := range rates.Rates {
+		merged[strings.ToUpper(code)] = rate
+	}
+	for code, rate := range manual.Rates {
+		merged[strings.ToUpper(code)] = rate
+	}
+	if rates.Provider == "" {
+		rates.Provider = p.Provider.Name()
+	}
+	return ExchangeRates{Base: rates.Base, Rates: merged, Provider: ProviderManual + "+" + rates.Provider}, nil
+}
+
+// HistoricalRates comes from Provider, the manual table only holds the current rates
+func (p OverrideProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	return p.Provider.HistoricalRates(date, baseCurrency)
+}
+
+// FallbackProvider asks each provider in turn until one succeeds
+type FallbackProvider struct {
+	Providers []RateProvider
+}
+
+func (p FallbackProvider) Name() string {
+	names := make([]string, len(p.Providers))
+	for i, provider := range p.Providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (p FallbackProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	return p.first(func(provider RateProvider) (ExchangeRates, error) {
+		return provider.Rates(baseCurrency)
+	})
+}
+
+func (p FallbackProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	return p.first(func(provider RateProvider) (ExchangeRates, error) {
+		return provider.HistoricalRates(date, baseCurrency)
+	})
+}
+
+func (p FallbackProvider) first(lookup func(RateProvider) (ExchangeRates, error)) (ExchangeRates, error) {
+	failures := make([]string, 0, len(p.Providers))
+	for _, provider := range p.Providers {
+		rates, err := lookup(provider)
+		if err == nil {
+			// Snapshots record the provider that answered, not the whole chain
+			if rates.Provider == "" {
+				rates.Provider = provider.Name()
+			}
+			return rates, nil
+		}
+		failures = append(failures, provider.Name()+": "+err.Error())
+	}
+	return ExchangeRates{}, errors.New("converter.FallbackProvider: No provider has rates. " + strings.Join(failures, "; "))
+}
+
+// StaticRateProvider serves a fixed rate table. Rates are quoted against a single base and
+// crossed for every other base, so one table covers every pair.
+type StaticRateProvider struct {
+	table ExchangeRates
+}
+
+func NewStaticRateProvider(table ExchangeRates) StaticRateProvider {
+	rates := make(map[string]decimal.Decimal, len(table.Rates)+1)
+	for code, rate := range table.Rates {
+		rates[strings.ToUpper(code)] = rate
+	}
+	base := strings.ToUpper(table.Base)
+	rates[base] = decimal.NewFromInt(1)
+	return StaticRateProvider{table: ExchangeRates{Base: base, Rates: rates}}
+}
+
+// LoadStaticRates reads a rate table in the openexchangerates.org latest.json format
+func LoadStaticRates(path string) (StaticRateProvider, error) {
+	if path == "" {
+		return StaticRateProvider{}, errors.New("converter.LoadStaticRates: " + ratesFileEnv + " is not configured")
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return StaticRateProvider{}, errors.New("converter.LoadStaticRates: " + err.Error())
+	}
+
+	var table ExchangeRates
+	err = json.Unmarshal(file, &table)
+	if err != nil {
+		return StaticRateProvider{}, errors.New("converter.LoadStaticRates: " + err.Error())
+	}
+	if table.Base == "" || len(table.Rates) == 0 {
+		return StaticRateProvider{}, errors.New("converter.LoadStaticRates: " + path + " has no rates")
+	}
+
+	return NewStaticRateProvider(table), nil
+}
+
+func (p StaticRateProvider) Name() string {
+	return ProviderStatic
+}
+
+func (p StaticRateProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	baseCurrency = strings.ToUpper(baseCurrency)
+	baseRate, ok := p.table.Rates[baseCurrency]
+	if !ok || !baseRate.IsPositive() {
+		return ExchangeRates{}, errors.New("converter.StaticRateProvider: No rate for " + baseCurrency)
+	}
+
+	rates := make(map[string]decimal.Decimal, len(p.table.Rates))
+	for code, rate := range p.table.Rates {
+		rates[code] = rate.DivRound(baseRate, SNAPSHOT_RATE_PLACES)
+	}
+	return ExchangeRates{Base: baseCurrency, Rates: rates}, nil
+}
+
+// HistoricalRates returns the fixed table for every date
+func (p StaticRateProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	return p.Rates(baseCurrency)
+}
+
+type unavailableProvider struct {
+	err error
+}
+
+func (p unavailableProvider) Name() string {
+	return "unavailable"
+}
+
+func (p unavailableProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	return ExchangeRates{}, p.err
+}
+
+func (p unavailableProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	return ExchangeRates{}, p.err
+}
@@ -0,0 +1,173 @@
+package converter
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type failingProvider struct{}
+
+func (p failingProvider) Name() string {
+	return "failing"
+}
+
+func (p failingProvider) Rates(baseCurrency string) (ExchangeRates, error) {
+	return ExchangeRates{}, errors.New("offline")
+}
+
+func (p failingProvider) HistoricalRates(date, baseCurrency string) (ExchangeRates, error) {
+	return ExchangeRates{}, errors.New("offline")
+}
+
+func TestStaticRateProvider(t *testing.T) {
+	static := NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.8"), "NGN": decimal.RequireFromString("1600")}})
+
+	rates, err := static.Rates("gbp")
+	if err != nil {
+		t.Errorf("StaticRateProvider.Rates does not pass. Looking for %v, got %v", nil, err)
+	}
+	if rates.Base != "GBP" {
+		t.Errorf("StaticRateProvider.Rates does not pass. Looking for %v, got %v", "GBP", rates.Base)
+	}
+	if !rates.Rates["NGN"].Equal(decimal.RequireFromString("2000")) {
+		t.Errorf("StaticRateProvider.Rates does not pass. Looking for %v, got %v", 2000, rates.Rates["NGN"])
+	}
+	if !rates.Rates["USD"].Equal(decimal.RequireFromString("1.25")) {
+		t.Errorf("StaticRateProvider.Rates does not pass. Looking for %v, got %v", 1.25, rates.Rates["USD"])
+	}
+
+	_, err = static.Rates("EUR")
+	if err == nil {
+		t.Errorf("StaticRateProvider.Rates does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestLoadStaticRates(t *testing.T) {
+	static, err := LoadStaticRates("rates.sample.json")
+	if err != nil {
+		t.Errorf("LoadStaticRates does not pass. Looking for %v, got %v", nil, err)
+	}
+	rates, err := static.Rates("USD")
+	if err != nil || !rates.Rates["NGN"].IsPositive() {
+		t.Errorf("LoadStaticRates does not pass. Looking for %v, got %v (%v)", "NGN rate", rates.Rates["NGN"], err)
+	}
+
+	_, err = LoadStaticRates("")
+	if err == nil {
+		t.Errorf("LoadStaticRates does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestFallbackProvider(t *testing.T) {
+	static := NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.8")}})
+	fallback := FallbackProvider{Providers: []RateProvider{failingProvider{}, static}}
+
+	rates, err := fallback.Rates("USD")
+	if err != nil || !rates.Rates["GBP"].Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("FallbackProvider.Rates does not pass. Looking for %v, got %v (%v)", 0.8, rates.Rates["GBP"], err)
+	}
+	if rates.Provider != ProviderStatic {
+		t.Errorf("FallbackProvider.Rates does not pass. Looking for %v, got %v", ProviderStatic, rates.Provider)
+	}
+	if fallback.Name() != "failing,static" {
+		t.Errorf("FallbackProvider.Name does not pass. Looking for %v, got %v", "failing,static", fallback.Name())
+	}
+
+	fallback = FallbackProvider{Providers: []RateProvider{failingProvider{}, failingProvider{}}}
+	_, err = fallback.HistoricalRates("2023-10-18", "USD")
+	if err == nil {
+		t.Errorf("FallbackProvider.HistoricalRates does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	p, err := NewProvider([]string{""})
+	if err != nil || p.Name() != ProviderOpenExchangeRates {
+		t.Errorf("NewProvider does not pass. Looking for %v, got %v (%v)", ProviderOpenExchangeRates, p, err)
+	}
+
+	_, err = NewProvider([]string{"bloomberg"})
+	if err == nil {
+		t.Errorf("NewProvider does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestConvertCurrencyWithProvider(t *testing.T) {
+	SetProvider(NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.9")}}))
+	defer SetProvider(nil)
+
+	result, err := ConvertCurrency("USD", "EUR", 100)
+	if err != nil || math.Abs(result-90) > 1e-9 {
+		t.Errorf("ConvertCurrency does not pass. Looking for %v, got %v (%v)", 90, result, err)
+	}
+}
+
+func TestOpenExchangeRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("app_id") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_app_id"}`))
+			return
+		}
+		w.Write([]byte(`{"base":"USD","rates":{"GBP":0.8}}`))
+	}))
+	defer server.Close()
+
+	rates, err := OpenExchangeRates{BaseURL: server.URL, AppID: "test"}.Rates("USD")
+	if err != nil || !rates.Rates["GBP"].Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("OpenExchangeRates.Rates does not pass. Looking for %v, got %v (%v)", 0.8, rates.Rates["GBP"], err)
+	}
+
+	_, err = OpenExchangeRates{BaseURL: server.URL, AppID: "wrong"}.Rates("USD")
+	if err == nil {
+		t.Errorf("OpenExchangeRates.Rates does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestStoredProviderWithoutStore(t *testing.T) {
+	static := NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.8")}})
+	stored := StoredProvider{Provider: static}
+
+	rates, err := stored.Rates("usd")
+	if err != nil || !rates.Rates["GBP"].Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("StoredProvider.Rates does not pass. Looking for %v, got %v (%v)", 0.8, rates.Rates["GBP"], err)
+	}
+	rates, err = stored.HistoricalRates("2023-10-18", "USD")
+	if err != nil || !rates.Rates["GBP"].Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("StoredProvider.HistoricalRates does not pass. Looking for %v, got %v (%v)", 0.8, rates.Rates["GBP"], err)
+	}
+	if stored.Name() != ProviderStatic {
+		t.Errorf("StoredProvider.Name does not pass. Looking for %v, got %v", ProviderStatic, stored.Name())
+	}
+}
+
+func TestOverrideProvider(t *testing.T) {
+	upstream := NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"GBP": decimal.RequireFromString("0.8"), "NGN": decimal.RequireFromString("1600")}})
+	manual := NewStaticRateProvider(ExchangeRates{Base: "USD", Rates: map[string]decimal.Decimal{"NGN": decimal.RequireFromString("1500")}})
+	override := OverrideProvider{Manual: manual, Provider: upstream}
+
+	rates, err := override.Rates("USD")
+	if err != nil || !rates.Rates["NGN"].Equal(decimal.RequireFromString("1500")) || !rates.Rates["GBP"].Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("OverrideProvider.Rates does not pass. Looking for %v, got %v (%v)", "NGN 1500 and GBP 0.8", rates.Rates, err)
+	}
+	if rates.Provider != "manual+static" {
+		t.Errorf("OverrideProvider.Rates does not pass. Looking for %v, got %v", "manual+static", rates.Provider)
+	}
+
+	override = OverrideProvider{Manual: manual, Provider: failingProvider{}}
+	rates, err = override.Rates("USD")
+	if err != nil || rates.Provider != ProviderManual {
+		t.Errorf("OverrideProvider.Rates does not pass. Looking for %v, got %v (%v)", ProviderManual, rates.Provider, err)
+	}
+
+	override = OverrideProvider{Manual: failingProvider{}, Provider: upstream}
+	rates, err = override.Rates("USD")
+	if err != nil || !rates.Rates["NGN"].Equal(decimal.RequireFromString("1600")) {
+		t.Errorf("OverrideProvider.Rates does not pass. Looking for %v, got %v (%v)", 1600, rates.Rates["NGN"], err)
+	}
+}
@@ -0,0 +1,117 @@
+package currency
+
+/*
+Currency definitions
+
+Every account holds a single currency. Amounts are stored as decimals and must never
+carry more decimal places than the currency's minor units (kobo, pence, cents).
+Fees and other calculated amounts are rounded to the minor units before they are posted.
+*/
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const DEFAULT_CURRENCY = "NGN"
+
+type Currency struct {
+	Code        string `json:"code"`
+	NumericCode string `json:"numericCode"`
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	MinorUnits  int32  `json:"minorUnits"`
+}
+
+// ISO 4217 currencies accounts can be opened in
+var supported = map[string]Currency{
+	"NGN": {Code: "NGN", NumericCode: "566", Name: "Nigerian Naira", Symbol: "₦", MinorUnits: 2},
+	"GBP": {Code: "GBP", NumericCode: "826", Name: "Pound Sterling", Symbol: "£", MinorUnits: 2},
+	"EUR": {Code: "EUR", NumericCode: "978", Name: "Euro", Symbol: "€", MinorUnits: 2},
+	"USD": {Code: "USD", NumericCode: "840", Name: "US Dollar", Symbol: "$", MinorUnits: 2},
+}
+
+// Get returns the currency for an alphabetic code
+func Get(code string) (Currency, error) {
+	c, ok := supported[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return Currency{}, errors.New("currency.Get: Unsupported currency " + code)
+	}
+	return c, nil
+}
+
+// GetByNumericCode returns the currency for an ISO 4217 numeric code
+func GetByNumericCode(numericCode string) (Currency, error) {
+	for _, c := range supported {
+		if c.NumericCode == numericCode {
+			return c, nil
+		}
+	}
+	return Currency{}, errors.New("currency.GetByNumericCode: Unsupported currency " + numericCode)
+}
+
+// IsSupported reports whether accounts can be opened in the currency
+func IsSupported(code string) bool {
+	_, err := Get(code)
+	return err == nil
+}
+
+// All returns the supported currencies ordered by code
+func All() []Currency {
+	currencies := make([]Currency, 0, len(supported))
+	for _, c := range supported {
+		currencies = append(currencies, c)
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+	return currencies
+}
+
+// Round rounds an amount to the currency's minor units
+func (c Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(c.MinorUnits)
+}
+
+// Format renders an amount with exactly the currency's minor units
+func (c Currency) Format(amount decimal.Decimal) string {
+	return amount.StringFixed(c.MinorUnits)
+}
+
+// ValidAmount reports whether an amount fits in the currency's minor units
+func (c Currency) ValidAmount(amount decimal.Decimal) bool {
+	return amount.Equal(c.Round(amount))
+}
+
+// ToMinor converts an amount to minor units, e.g. 12.34 GBP to 1234 pence
+func (c Currency) ToMinor(amount decimal.Decimal) int64 {
+	return c.Round(amount).Shift(c.MinorUnits).IntPart()
+}
+
+// FromMinor converts minor units back to an amount
+func (c Currency) FromMinor(minor int64) decimal.Decimal {
+	return decimal.New(minor, -c.MinorUnits)
+}
+
+// Round rounds an amount to the minor units of the currency code, falling back to
+// two decimal places for an unknown code
+func Round(code string, amount decimal.Decimal) decimal.Decimal {
+	c, err := Get(code)
+	if err != nil {
+		return amount.Round(2)
+	}
+	return c.Round(amount)
+}
+
+// Format renders an amount with the minor units of the currency code, falling back to
+// two decimal places for an unknown code
+func Format(code string, amount decimal.Decimal) string {
+	c, err := Get(code)
+	if err != nil {
+		return amount.StringFixed(2)
+	}
+	return c.Format(amount)
+}
@@ -0,0 +1,76 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGet(t *testing.T) {
+	c, err := Get("gbp")
+	if err != nil || c.Code != "GBP" || c.MinorUnits != 2 {
+		t.Errorf("Get does not pass. Looking for %v, got %v (%v)", "GBP", c, err)
+	}
+
+	_, err = Get("XYZ")
+	if err == nil {
+		t.Errorf("Get does not pass. Looking for %v, got %v", "error", err)
+	}
+
+	c, err = GetByNumericCode("566")
+	if err != nil || c.Code != "NGN" {
+		t.Errorf("GetByNumericCode does not pass. Looking for %v, got %v (%v)", "NGN", c, err)
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	c, _ := Get("NGN")
+	amount := decimal.RequireFromString("1234.5")
+
+	if c.Format(amount) != "1234.50" {
+		t.Errorf("Format does not pass. Looking for %v, got %v", "1234.50", c.Format(amount))
+	}
+	if !c.ValidAmount(amount) {
+		t.Errorf("ValidAmount does not pass. Looking for %v, got %v", true, false)
+	}
+	if c.ValidAmount(decimal.RequireFromString("10.001")) {
+		t.Errorf("ValidAmount does not pass. Looking for %v, got %v", false, true)
+	}
+	if c.ToMinor(amount) != 123450 {
+		t.Errorf("ToMinor does not pass. Looking for %v, got %v", 123450, c.ToMinor(amount))
+	}
+	if !c.FromMinor(123450).Equal(amount) {
+		t.Errorf("FromMinor does not pass. Looking for %v, got %v", amount, c.FromMinor(123450))
+	}
+}
+
+func TestOtherMinorUnits(t *testing.T) {
+	yen := Currency{Code: "JPY", NumericCode: "392", Name: "Yen", Symbol: "¥", MinorUnits: 0}
+	dinar := Currency{Code: "KWD", NumericCode: "414", Name: "Kuwaiti Dinar", Symbol: "KD", MinorUnits: 3}
+	amount := decimal.RequireFromString("1234.5678")
+
+	if !yen.Round(amount).Equal(decimal.NewFromInt(1235)) || yen.Format(amount) != "1235" || yen.ToMinor(amount) != 1235 {
+		t.Errorf("JPY does not pass. Got %v, %v, %v", yen.Round(amount), yen.Format(amount), yen.ToMinor(amount))
+	}
+	if yen.ValidAmount(decimal.RequireFromString("10.5")) || !yen.FromMinor(1235).Equal(decimal.NewFromInt(1235)) {
+		t.Errorf("JPY does not pass. Accepted 10.5 or got %v from minor units", yen.FromMinor(1235))
+	}
+	if !dinar.Round(amount).Equal(decimal.RequireFromString("1234.568")) || dinar.Format(amount) != "1234.568" || dinar.ToMinor(amount) != 1234568 {
+		t.Errorf("KWD does not pass. Got %v, %v, %v", dinar.Round(amount), dinar.Format(amount), dinar.ToMinor(amount))
+	}
+	if !dinar.ValidAmount(decimal.RequireFromString("10.001")) || !dinar.FromMinor(1234568).Equal(decimal.RequireFromString("1234.568")) {
+		t.Errorf("KWD does not pass. Rejected 10.001 or got %v from minor units", dinar.FromMinor(1234568))
+	}
+}
+
+func TestRound(t *testing.T) {
+	fee := decimal.RequireFromString("0.12345")
+
+	if !Round("GBP", fee).Equal(decimal.RequireFromString("0.12")) {
+		t.Errorf("Round does not pass. Looking for %v, got %v", "0.12", Round("GBP", fee))
+	}
+	// Unknown codes fall back to two decimal places
+	if Format("XYZ", fee) != "0.12" {
+		t.Errorf("Format does not pass. Looking for %v, got %v", "0.12", Format("XYZ", fee))
+	}
+}
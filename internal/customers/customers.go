@@ -0,0 +1,307 @@
+package customers
+
+/*
+Customer information file (CIF)
+
+A customer is one person known to the bank, identified by a customer number. A customer owns
+any number of accounts, their KYC documents and their saved beneficiaries. Accounts waiting on
+KYC (PendingKYC) become Active when operations approve the customer's KYC. Each customer's
+identification number is unique, so opening an account for someone who already banks with
+us adds the account to their existing customer rather than creating a second one.
+
+accounts_meta still holds a copy of the holder's details for each account, it's written
+from the customer when the account is opened and kept in step when the customer is updated.
+
+Customer numbers are C followed by the customer's zero-padded nine digit ID.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/data"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	CUSTOMER_NUMBER_PREFIX = "C"
+	// RolePrimary is the role on accounts the customer owns, joint holders have their account_holders role
+	RolePrimary = "primary"
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Customer is a person known to the bank
+type Customer struct {
+	ID                   int64     `json:"-"`
+	CustomerNumber       string    `json:"customerNumber"`
+	GivenName            string    `json:"givenName"`
+	FamilyName           string    `json:"familyName"`
+	DateOfBirth          string    `json:"dateOfBirth"`
+	IdentificationNumber string    `json:"identificationNumber"`
+	IdentificationType   string    `json:"identificationType"`
+	Country              string    `json:"country"`
+	ContactNumber1       string    `json:"contactNumber1"`
+	ContactNumber2       string    `json:"contactNumber2"`
+	EmailAddress         string    `json:"emailAddress"`
+	AddressLine1         string    `json:"addressLine1"`
+	AddressLine2         string    `json:"addressLine2"`
+	AddressLine3         string    `json:"addressLine3"`
+	PostalCode           string    `json:"postalCode"`
+	Image                string    `json:"-"`
+	Timestamp            time.Time `json:"timestamp"`
+}
+
+// Account is a summary of an account the customer holds, with the customer's role on it
+type Account struct {
+	AccountNumber        string          `json:"accountNumber"`
+	BankNumber           string          `json:"bankNumber"`
+	CurrencyCode         string          `json:"currencyCode"`
+	PrimaryAccountNumber string          `json:"primaryAccountNumber,omitempty"`
+	Status               string          `json:"status"`
+	AvailableBalance     decimal.Decimal `json:"availableBalance"`
+	Role                 string          `json:"role"`
+}
+
+// KYCDocument is an identity document held on file for the customer
+type KYCDocument struct {
+	ID                int64  `json:"id"`
+	CustomerNumber    string `json:"customerNumber"`
+	DocumentType      string `json:"documentType"`
+	DocumentNumber    string `json:"documentNumber"`
+	DocumentImagePath string `json:"documentImagePath"`
+	ExpiryDate        string `json:"expiryDate"`
+}
+
+// File is everything held on a customer
+type File struct {
+	Customer      Customer           `json:"customer"`
+	Accounts      []Account          `json:"accounts"`
+	KYCDocuments  []KYCDocument      `json:"kycDocuments"`
+	Beneficiaries []data.Beneficiary `json:"beneficiaries"`
+}
+
+// FormatCustomerNumber builds the customer number for a customer ID
+func FormatCustomerNumber(id int64) string {
+	return fmt.Sprintf("%s%09d", CUSTOMER_NUMBER_PREFIX, id)
+}
+
+func validateCustomer(customer Customer) error {
+	if strings.TrimSpace(customer.GivenName) == "" {
+		return errors.New("Given name cannot be empty")
+	}
+	if strings.TrimSpace(customer.FamilyName) == "" {
+		return errors.New("Family name cannot be empty")
+	}
+	if strings.TrimSpace(customer.IdentificationNumber) == "" {
+		return errors.New("Identification number cannot be empty")
+	}
+	return nil
+}
+
+// Create records a new customer. A customer with the same identification number must not
+// already exist.
+func Create(customer Customer) (Customer, error) {
+	customer.IdentificationNumber = strings.TrimSpace(customer.IdentificationNumber)
+	err := validateCustomer(customer)
+	if err != nil {
+		return Customer{}, errors.New("customers.Create: " + err.Error())
+	}
+
+	existing, found, err := getCustomerByIdentification(customer.IdentificationNumber)
+	if err != nil {
+		return Customer{}, errors.New("customers.Create: " + err.Error())
+	}
+	if found {
+		return Customer{}, errors.New("customers.Create: Customer already exists. " + existing.CustomerNumber)
+	}
+
+	customer, err = saveCustomer(customer)
+	if err != nil {
+		return Customer{}, errors.New("customers.Create: " + err.Error())
+	}
+
+	return customer, nil
+}
+
+// Update changes a customer's details, and the copy of them held against each of their accounts
+func Update(customer Customer) (Customer, error) {
+	customer.IdentificationNumber = strings.TrimSpace(customer.IdentificationNumber)
+	err := validateCustomer(customer)
+	if err != nil {
+		return Customer{}, errors.New("customers.Update: " + err.Error())
+	}
+
+	existing, found, err := getCustomerByIdentification(customer.IdentificationNumber)
+	if err != nil {
+		return Customer{}, errors.New("customers.Update: " + err.Error())
+	}
+	if found && existing.CustomerNumber != customer.CustomerNumber {
+		return Customer{}, errors.New("customers.Update: Identification number belongs to customer " + existing.CustomerNumber)
+	}
+
+	err = updateCustomer(customer)
+	if err != nil {
+		return Customer{}, errors.New("customers.Update: " + err.Error())
+	}
+
+	return Get(customer.CustomerNumber)
+}
+
+// Get returns a customer by customer number
+func Get(customerNumber string) (Customer, error) {
+	customer, found, err := getCustomer(strings.TrimSpace(customerNumber))
+	if err != nil {
+		return Customer{}, errors.New("customers.Get: " + err.Error())
+	}
+	if !found {
+		return Customer{}, errors.New("customers.Get: Customer not found")
+	}
+	return customer, nil
+}
+
+// FindByIdentification looks up the customer holding an identification number
+func FindByIdentification(identificationNumber string) (customer Customer, found bool, err error) {
+	customer, found, err = getCustomerByIdentification(strings.TrimSpace(identificationNumber))
+	if err != nil {
+		return Customer{}, false, errors.New("customers.FindByIdentification: " + err.Error())
+	}
+	return
+}
+
+// ForAccount returns the customer who owns an account
+func ForAccount(accountNumber string) (Customer, error) {
+	customerNumber, err := getAccountCustomerNumber(accountNumber)
+	if err != nil {
+		return Customer{}, errors.New("customers.ForAccount: " + err.Error())
+	}
+	if customerNumber == "" {
+		return Customer{}, errors.New("customers.ForAccount: Account " + accountNumber + " does not belong to a customer")
+	}
+	return Get(customerNumber)
+}
+
+// Accounts lists the accounts a customer owns or is a joint holder of
+func Accounts(customerNumber string) ([]Account, error) {
+	accounts, err := getCustomerAccounts(customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.Accounts: " + err.Error())
+	}
+	return accounts, nil
+}
+
+// AddKYCDocument files an identity document against a customer
+func AddKYCDocument(document KYCDocument) (KYCDocument, error) {
+	document.DocumentType = strings.TrimSpace(document.DocumentType)
+	document.DocumentNumber = strings.TrimSpace(document.DocumentNumber)
+	if document.DocumentType == "" || document.DocumentNumber == "" {
+		return KYCDocument{}, errors.New("customers.AddKYCDocument: Document type and number are required")
+	}
+	if document.ExpiryDate != "" {
+		_, err := time.Parse("2006-01-02", document.ExpiryDate)
+		if err != nil {
+			return KYCDocument{}, errors.New("customers.AddKYCDocument: Expiry date must be YYYY-MM-DD")
+		}
+	}
+
+	_, err := Get(document.CustomerNumber)
+	if err != nil {
+		return KYCDocument{}, errors.New("customers.AddKYCDocument: " + err.Error())
+	}
+
+	document.ID, err = saveKYCDocument(document)
+	if err != nil {
+		return KYCDocument{}, errors.New("customers.AddKYCDocument: " + err.Error())
+	}
+
+	return document, nil
+}
+
+// KYCDocuments lists the identity documents on file for a customer
+func KYCDocuments(customerNumber string) ([]KYCDocument, error) {
+	documents, err := getKYCDocuments(customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.KYCDocuments: " + err.Error())
+	}
+	return documents, nil
+}
+
+// ApproveKYC records that the customer's identity has been checked and moves the accounts they
+// own out of PendingKYC into Active. The customer needs at least one KYC document on file.
+func ApproveKYC(customerNumber string, actor string) ([]lifecycle.StatusChange, error) {
+	documents, err := KYCDocuments(customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.ApproveKYC: " + err.Error())
+	}
+	if len(documents) == 0 {
+		return nil, errors.New("customers.ApproveKYC: No KYC documents on file for customer " + customerNumber)
+	}
+
+	accounts, err := Accounts(customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.ApproveKYC: " + err.Error())
+	}
+	changes := make([]lifecycle.StatusChange, 0)
+	for _, account := range accounts {
+		if account.Role != RolePrimary || account.Status != lifecycle.PendingKYC {
+			continue
+		}
+		change, err := lifecycle.Transition(account.AccountNumber, lifecycle.Active, "KYC approved", actor)
+		if err != nil {
+			return changes, errors.New("customers.ApproveKYC: " + err.Error())
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// Beneficiaries lists the beneficiaries a customer has saved
+func Beneficiaries(customerNumber string) ([]data.Beneficiary, error) {
+	beneficiaries, err := getBeneficiaries(customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.Beneficiaries: " + err.Error())
+	}
+	return beneficiaries, nil
+}
+
+// AddBeneficiary saves a beneficiary for a customer
+func AddBeneficiary(customerNumber string, beneficiary data.Beneficiary) error {
+	err := saveBeneficiary(customerNumber, beneficiary)
+	if err != nil {
+		return errors.New("customers.AddBeneficiary: " + err.Error())
+	}
+	return nil
+}
+
+// Lookup returns a customer's file: their details, accounts, KYC documents and beneficiaries
+func Lookup(customerNumber string) (File, error) {
+	customer, err := Get(customerNumber)
+	if err != nil {
+		return File{}, errors.New("customers.Lookup: " + err.Error())
+	}
+
+	file := File{Customer: customer}
+	file.Accounts, err = Accounts(customer.CustomerNumber)
+	if err != nil {
+		return File{}, errors.New("customers.Lookup: " + err.Error())
+	}
+	file.KYCDocuments, err = KYCDocuments(customer.CustomerNumber)
+	if err != nil {
+		return File{}, errors.New("customers.Lookup: " + err.Error())
+	}
+	file.Beneficiaries, err = Beneficiaries(customer.CustomerNumber)
+	if err != nil {
+		return File{}, errors.New("customers.Lookup: " + err.Error())
+	}
+
+	return file, nil
+}
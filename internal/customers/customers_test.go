@@ -0,0 +1,36 @@
+package customers
+
+import (
+	"testing"
+)
+
+func TestFormatCustomerNumber(t *testing.T) {
+	cases := map[int64]string{
+		1:         "C000000001",
+		4821:      "C000004821",
+		999999999: "C999999999",
+	}
+	for id, want := range cases {
+		if got := FormatCustomerNumber(id); got != want {
+			t.Errorf("FormatCustomerNumber does not pass. Looking for %v, got %v for %v", want, got, id)
+		}
+	}
+}
+
+func TestValidateCustomer(t *testing.T) {
+	customer := Customer{GivenName: "Kyle", FamilyName: "Redelinghuys", IdentificationNumber: "190001011234098"}
+	if err := validateCustomer(customer); err != nil {
+		t.Errorf("validateCustomer does not pass. Looking for %v, got %v", nil, err)
+	}
+
+	missing := []Customer{
+		{FamilyName: "Redelinghuys", IdentificationNumber: "190001011234098"},
+		{GivenName: "Kyle", IdentificationNumber: "190001011234098"},
+		{GivenName: "Kyle", FamilyName: "Redelinghuys", IdentificationNumber: "  "},
+	}
+	for _, c := range missing {
+		if err := validateCustomer(c); err == nil {
+			t.Errorf("validateCustomer does not pass. Looking for an error, got %v for %+v", nil, c)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package customers
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/data"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const customerColumns = "`id`, `customerNumber`, `givenName`, `familyName`, `dateOfBirth`, IFNULL(`identificationNumber`, ''), `identificationType`, `country`, `contactNumber1`, `contactNumber2`, `emailAddress`, `addressLine1`, `addressLine2`, `addressLine3`, `postalCode`, `image`, `timestamp`"
+
+const kycDocumentColumns = "`documentId`, `customerNumber`, `documentType`, `documentNumber`, IFNULL(`documentImagePath`, ''), IFNULL(`expiryDate`, '')"
+
+// saveCustomer inserts the customer and gives it the customer number built from its ID
+func saveCustomer(customer Customer) (Customer, error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return Customer{}, errors.New("customers.saveCustomer: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	insertStatement := "INSERT INTO customers (`givenName`, `familyName`, `dateOfBirth`, `identificationNumber`, `identificationType`, `country`, `contactNumber1`, `contactNumber2`, `emailAddress`, `addressLine1`, `addressLine2`, `addressLine3`, `postalCode`, `image`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := tx.Exec(insertStatement, customer.GivenName, customer.FamilyName, customer.DateOfBirth, customer.IdentificationNumber, customer.IdentificationType, customer.Country,
+		customer.ContactNumber1, customer.ContactNumber2, customer.EmailAddress, customer.AddressLine1, customer.AddressLine2, customer.AddressLine3, customer.PostalCode, customer.Image)
+	if err != nil {
+		return Customer{}, errors.New("customers.saveCustomer: " + err.Error())
+	}
+	customer.ID, err = res.LastInsertId()
+	if err != nil {
+		return Customer{}, errors.New("customers.saveCustomer: " + err.Error())
+	}
+
+	customer.CustomerNumber = FormatCustomerNumber(customer.ID)
+	_, err = tx.Exec("UPDATE customers SET `customerNumber` = ? WHERE `id` = ?", customer.CustomerNumber, customer.ID)
+	if err != nil {
+		return Customer{}, errors.New("customers.saveCustomer: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Customer{}, errors.New("customers.saveCustomer: " + err.Error())
+	}
+	customer.Timestamp = time.Now()
+
+	return customer, nil
+}
+
+// updateCustomer updates the customer and the holder details kept against each of their accounts
+func updateCustomer(customer Customer) error {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("customers.updateCustomer: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE customers SET `givenName` = ?, `familyName` = ?, `dateOfBirth` = ?, `identificationNumber` = ?, `identificationType` = ?, `country` = ?, `contactNumber1` = ?, `contactNumber2` = ?, `emailAddress` = ?, `addressLine1` = ?, `addressLine2` = ?, `addressLine3` = ?, `postalCode` = ? WHERE `customerNumber` = ?",
+		customer.GivenName, customer.FamilyName, customer.DateOfBirth, customer.IdentificationNumber, customer.IdentificationType, customer.Country,
+		customer.ContactNumber1, customer.ContactNumber2, customer.EmailAddress, customer.AddressLine1, customer.AddressLine2, customer.AddressLine3, customer.PostalCode, customer.CustomerNumber)
+	if err != nil {
+		return errors.New("customers.updateCustomer: " + err.Error())
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("customers.updateCustomer: " + err.Error())
+	}
+	if rows == 0 {
+		_, found, err := getCustomer(customer.CustomerNumber)
+		if err != nil {
+			return errors.New("customers.updateCustomer: " + err.Error())
+		}
+		if !found {
+			return errors.New("customers.updateCustomer: Customer not found")
+		}
+	}
+
+	_, err = tx.Exec("UPDATE accounts_meta m INNER JOIN accounts a ON a.`accountNumber` = m.`accountNumber` SET m.`accountHolderGivenName` = ?, m.`accountHolderFamilyName` = ?, m.`accountHolderDateOfBirth` = ?, m.`accountHolderIdentificationNumber` = ?, m.`accountHolderIdentificationType` = ?, m.`country` = ?, "+
+		"m.`accountHolderContactNumber1` = ?, m.`accountHolderContactNumber2` = ?, m.`accountHolderEmailAddress` = ?, m.`accountHolderAddressLine1` = ?, m.`accountHolderAddressLine2` = ?, m.`accountHolderAddressLine3` = ?, m.`accountHolderPostalCode` = ? WHERE a.`customerNumber` = ?",
+		customer.GivenName, customer.FamilyName, customer.DateOfBirth, customer.IdentificationNumber, customer.IdentificationType, customer.Country,
+		customer.ContactNumber1, customer.ContactNumber2, customer.EmailAddress, customer.AddressLine1, customer.AddressLine2, customer.AddressLine3, customer.PostalCode, customer.CustomerNumber)
+	if err != nil {
+		return errors.New("customers.updateCustomer: " + err.Error())
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET `accountHolderName` = ? WHERE `customerNumber` = ?", customer.FamilyName+","+customer.GivenName, customer.CustomerNumber)
+	if err != nil {
+		return errors.New("customers.updateCustomer: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("customers.updateCustomer: " + err.Error())
+	}
+	return nil
+}
+
+func getCustomer(customerNumber string) (customer Customer, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+customerColumns+" FROM `customers` WHERE `customerNumber` = ?", customerNumber)
+	if err != nil {
+		return Customer{}, false, errors.New("customers.getCustomer: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanCustomer(rows)
+}
+
+func getCustomerByIdentification(identificationNumber string) (customer Customer, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+customerColumns+" FROM `customers` WHERE `identificationNumber` = ?", identificationNumber)
+	if err != nil {
+		return Customer{}, false, errors.New("customers.getCustomerByIdentification: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanCustomer(rows)
+}
+
+func scanCustomer(rows *sql.Rows) (customer Customer, found bool, err error) {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Customer{}, false, errors.New("customers.scanCustomer: " + err.Error())
+		}
+		return Customer{}, false, nil
+	}
+
+	var timestamp string
+	err = rows.Scan(&customer.ID, &customer.CustomerNumber, &customer.GivenName, &customer.FamilyName, &customer.DateOfBirth, &customer.IdentificationNumber, &customer.IdentificationType, &customer.Country,
+		&customer.ContactNumber1, &customer.ContactNumber2, &customer.EmailAddress, &customer.AddressLine1, &customer.AddressLine2, &customer.AddressLine3, &customer.PostalCode, &customer.Image, &timestamp)
+	if err != nil {
+		return Customer{}, false, errors.New("customers.scanCustomer: " + err.Error())
+	}
+	if customer.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+		return Customer{}, false, errors.New("customers.scanCustomer: " + err.Error())
+	}
+
+	return customer, true, nil
+}
+
+func getAccountCustomerNumber(accountNumber string) (customerNumber string, err error) {
+	err = Config.Db.QueryRow("SELECT IFNULL(`customerNumber`, '') FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&customerNumber)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("customers.getAccountCustomerNumber: Account " + accountNumber + " not found")
+		}
+		return "", errors.New("customers.getAccountCustomerNumber: " + err.Error())
+	}
+	return
+}
+
+func getCustomerAccounts(customerNumber string) (accounts []Account, err error) {
+	// Accounts the customer owns, followed by the joint accounts they are linked to
+	query := "SELECT a.`accountNumber`, a.`bankNumber`, a.`currencyCode`, IFNULL(a.`primaryAccountNumber`, ''), a.`status`, a.`availableBalance`, IF(a.`customerNumber` = ?, 'primary', h.`role`) " +
+		"FROM `accounts` a LEFT JOIN `account_holders` h ON h.`accountNumber` = a.`accountNumber` AND h.`customerNumber` = ? " +
+		"WHERE a.`customerNumber` = ? OR h.`id` IS NOT NULL ORDER BY a.`customerNumber` = ? DESC, a.`id`"
+	rows, err := Config.Db.Query(query, customerNumber, customerNumber, customerNumber, customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.getCustomerAccounts: " + err.Error())
+	}
+	defer rows.Close()
+
+	accounts = make([]Account, 0)
+	for rows.Next() {
+		var account Account
+		if err := rows.Scan(&account.AccountNumber, &account.BankNumber, &account.CurrencyCode, &account.PrimaryAccountNumber, &account.Status, &account.AvailableBalance, &account.Role); err != nil {
+			return nil, errors.New("customers.getCustomerAccounts: " + err.Error())
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("customers.getCustomerAccounts: " + err.Error())
+	}
+
+	return accounts, nil
+}
+
+func saveKYCDocument(document KYCDocument) (id int64, err error) {
+	insertStatement := "INSERT INTO kyc_documents (`customerNumber`, `documentType`, `documentNumber`, `documentImagePath`, `expiryDate`) VALUES(?, ?, ?, ?, NULLIF(?, ''))"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("customers.saveKYCDocument: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(document.CustomerNumber, document.DocumentType, document.DocumentNumber, document.DocumentImagePath, document.ExpiryDate)
+	if err != nil {
+		return 0, errors.New("customers.saveKYCDocument: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("customers.saveKYCDocument: " + err.Error())
+	}
+
+	return
+}
+
+func getKYCDocuments(customerNumber string) (documents []KYCDocument, err error) {
+	rows, err := Config.Db.Query("SELECT "+kycDocumentColumns+" FROM `kyc_documents` WHERE `customerNumber` = ? ORDER BY `documentId` DESC", customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.getKYCDocuments: " + err.Error())
+	}
+	defer rows.Close()
+
+	documents = make([]KYCDocument, 0)
+	for rows.Next() {
+		var document KYCDocument
+		if err := rows.Scan(&document.ID, &document.CustomerNumber, &document.DocumentType, &document.DocumentNumber, &document.DocumentImagePath, &document.ExpiryDate); err != nil {
+			return nil, errors.New("customers.getKYCDocuments: " + err.Error())
+		}
+		documents = append(documents, document)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("customers.getKYCDocuments: " + err.Error())
+	}
+
+	return documents, nil
+}
+
+func saveBeneficiary(customerNumber string, beneficiary data.Beneficiary) error {
+	insertStatement := "INSERT INTO beneficiaries (`customerNumber`, `fullName`, `bankName`, `bankAccountNumber`, `bankRoutingNumber`, `swiftCode`) VALUES (?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("customers.saveBeneficiary: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(customerNumber, beneficiary.FullName, beneficiary.BankName, beneficiary.BankAccountNumber, beneficiary.BankRoutingNumber, beneficiary.SwiftCode)
+	if err != nil {
+		return errors.New("customers.saveBeneficiary: " + err.Error())
+	}
+	return nil
+}
+
+func getBeneficiaries(customerNumber string) (beneficiaries []data.Beneficiary, err error) {
+	rows, err := Config.Db.Query("SELECT `beneficiaryId`, IFNULL(`fullName`, ''), IFNULL(`bankName`, ''), IFNULL(`bankAccountNumber`, ''), IFNULL(`bankRoutingNumber`, ''), IFNULL(`swiftCode`, '') FROM `beneficiaries` WHERE `customerNumber` = ? ORDER BY `beneficiaryId`", customerNumber)
+	if err != nil {
+		return nil, errors.New("customers.getBeneficiaries: " + err.Error())
+	}
+	defer rows.Close()
+
+	beneficiaries = make([]data.Beneficiary, 0)
+	for rows.Next() {
+		var beneficiary data.Beneficiary
+		if err := rows.Scan(&beneficiary.BeneficiaryID, &beneficiary.FullName, &beneficiary.BankName, &beneficiary.BankAccountNumber, &beneficiary.BankRoutingNumber, &beneficiary.SwiftCode); err != nil {
+			return nil, errors.New("customers.getBeneficiaries: " + err.Error())
+		}
+		beneficiaries = append(beneficiaries, beneficiary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("customers.getBeneficiaries: " + err.Error())
+	}
+
+	return beneficiaries, nil
+}
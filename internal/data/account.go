@@ -23,6 +23,11 @@ type NewAccountRequest struct {
 	AccountNumber                   string `json:"accountNumber"`
 	Image                           string `json:"image"`
 	Country                         string `json:"country"`
+	CurrencyCode                    string `json:"currencyCode"`
+}
+
+type CurrencyAccountData struct {
+	CurrencyCode string `json:"currencyCode"`
 }
 
 type AccountID struct {
@@ -46,6 +51,127 @@ type DepositInitiationData struct {
 	AccountNumber string `json:"accountNumber"`
 	Amount        string `json:"amount"`
 }
+type PaymentRequestData struct {
+	RequesterAccountNumber string `json:"requesterAccountNumber"`
+	PayerAccountNumber     string `json:"payerAccountNumber"`
+	Amount                 string `json:"amount"`
+	Reason                 string `json:"reason"`
+	ExpiresInHours         int    `json:"expiresInHours"`
+}
+type PaymentRequestActionData struct {
+	AccountNumber string `json:"accountNumber"`
+	RequestID     int64  `json:"requestId"`
+}
+type PotData struct {
+	AccountNumber string `json:"accountNumber"`
+	PotID         int64  `json:"potId"`
+	Name          string `json:"name"`
+	TargetAmount  string `json:"targetAmount"`
+	TargetDate    string `json:"targetDate"`
+	RoundUpTo     string `json:"roundUpTo"`
+}
+type PotTransferData struct {
+	AccountNumber string `json:"accountNumber"`
+	PotID         int64  `json:"potId"`
+	Amount        string `json:"amount"`
+}
+type TermDepositData struct {
+	AccountNumber  string `json:"accountNumber"`
+	ProductID      int64  `json:"productId"`
+	Amount         string `json:"amount"`
+	RolloverOption string `json:"rolloverOption"`
+}
+type TermDepositActionData struct {
+	DepositNumber  string `json:"depositNumber"`
+	RolloverOption string `json:"rolloverOption"`
+	Reason         string `json:"reason"`
+}
+type OverdraftApplicationData struct {
+	AccountNumber string `json:"accountNumber"`
+	Limit         string `json:"limit"`
+	Reason        string `json:"reason"`
+}
+type OverdraftActionData struct {
+	OverdraftID int64  `json:"overdraftId"`
+	Reason      string `json:"reason"`
+}
+type LoanApplicationData struct {
+	AccountNumber string `json:"accountNumber"`
+	Amount        string `json:"amount"`
+	TenorMonths   int    `json:"tenorMonths"`
+	Purpose       string `json:"purpose"`
+}
+type LoanActionData struct {
+	LoanNumber string `json:"loanNumber"`
+	Amount     string `json:"amount"`
+	Reason     string `json:"reason"`
+}
+type MandatePaymentActionData struct {
+	PaymentID int64 `json:"paymentId"`
+}
+type CashPickupActionData struct {
+	SendersAccountNumber string `json:"sendersAccountNumber"`
+	PickupCode           string `json:"pickupCode"`
+}
+type CashPickupRedeemData struct {
+	PickupCode           string `json:"pickupCode"`
+	Pin                  string `json:"pin"`
+	IdentificationType   string `json:"identificationType"`
+	IdentificationNumber string `json:"identificationNumber"`
+	LocationID           int64  `json:"locationId"`
+}
+type AgentCashInData struct {
+	CustomerAccountNumber string `json:"customerAccountNumber"`
+	Amount                string `json:"amount"`
+	LocationID            int64  `json:"locationId"`
+}
+type AgentCashOutData struct {
+	AgentCode             string `json:"agentCode"`
+	CustomerAccountNumber string `json:"customerAccountNumber"`
+	Amount                string `json:"amount"`
+	LocationID            int64  `json:"locationId"`
+}
+type AgentTopUpData struct {
+	Amount    string `json:"amount"`
+	Reference string `json:"reference"`
+}
+type AgentSettlementData struct {
+	Date string `json:"date"`
+}
+type AgentLocationsData struct {
+	City string `json:"city"`
+}
+type MerchantQRData struct {
+	MerchantAccountNumber string `json:"merchantAccountNumber"`
+	MerchantName          string `json:"merchantName"`
+	MerchantCity          string `json:"merchantCity"`
+	Type                  string `json:"type"`
+	Amount                string `json:"amount"`
+	Reference             string `json:"reference"`
+}
+type MerchantQRReferenceData struct {
+	MerchantAccountNumber string `json:"merchantAccountNumber"`
+	Reference             string `json:"reference"`
+	Size                  int    `json:"size"`
+}
+type MerchantQRPaymentData struct {
+	PayerAccountNumber string `json:"payerAccountNumber"`
+	Payload            string `json:"payload"`
+	Amount             string `json:"amount"`
+}
+type MerchantReconciliationData struct {
+	MerchantAccountNumber string `json:"merchantAccountNumber"`
+	From                  string `json:"from"`
+	To                    string `json:"to"`
+}
+type FXQuoteData struct {
+	SourceAccountNumber string `json:"sourceAccountNumber"`
+	TargetAccountNumber string `json:"targetAccountNumber"`
+	Amount              string `json:"amount"`
+}
+type FXAcceptQuoteData struct {
+	Reference string `json:"reference"`
+}
 
 type AccountDetails struct {
 	FirstName     string `json:"firstName"`
@@ -1,7 +1,13 @@
 package data
 
 import (
+	"regexp"
+
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/iban"
 	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
+	"github.com/ebitezion/backend-framework/internal/usacctgen"
 	"github.com/ebitezion/backend-framework/internal/validator"
 )
 
@@ -41,6 +47,8 @@ func ValidateAuthLoginData(v *validator.Validator, data *AuthLoginData) {
 
 }
 
+var ibanCountryRX = regexp.MustCompile("^[A-Za-z]{2}$")
+
 // ValidateBeneficiaryData validates a given Beneficiary struct
 func ValidateBeneficiaryData(v *validator.Validator, data *Beneficiary) {
 	// General validation
@@ -49,6 +57,22 @@ func ValidateBeneficiaryData(v *validator.Validator, data *Beneficiary) {
 	v.Check(data.BankAccountNumber != "", "bankAccountNumber", "must be provided")
 	v.Check(data.BankRoutingNumber != "", "bankRoutingNumber", "must be provided")
 	v.Check(data.SwiftCode != "", "swiftCode", "must be provided")
+	v.Check(data.SwiftCode == "" || iban.ValidateBIC(data.SwiftCode) == nil, "swiftCode", "must be a valid BIC")
+
+	// Account numbers starting with a country code are IBANs
+	if len(data.BankAccountNumber) > 2 && validator.Matches(data.BankAccountNumber[:2], ibanCountryRX) {
+		v.Check(iban.Validate(data.BankAccountNumber) == nil, "bankAccountNumber", "must be a valid IBAN")
+	}
+	// A 6 digit routing number is a UK sort code, the account must pass modulus checking
+	if _, _, err := ukaccountgen.NormalizeUKAccount(data.BankRoutingNumber, "00000000"); err == nil && data.BankAccountNumber != "" {
+		err := ukaccountgen.ValidateUKAccount(data.BankRoutingNumber, data.BankAccountNumber)
+		v.Check(err == nil, "bankAccountNumber", "must be a valid UK account number for the sort code")
+	}
+	// A 9 digit routing number is a US ABA routing number
+	if len(data.BankRoutingNumber) == usacctgen.ROUTING_NUMBER_LENGTH {
+		err := usacctgen.ValidateRoutingNumber(data.BankRoutingNumber)
+		v.Check(err == nil, "bankRoutingNumber", "must be a valid ABA routing number")
+	}
 }
 
 // ValidateAuthCreateData validates a given AuthCreateData struct
@@ -74,6 +98,114 @@ func ValidateDepositInitiationData(v *validator.Validator, data *DepositInitiati
 	v.Check(data.Amount != "", "amount", "must be provided")
 }
 
+// ValidatePaymentRequestData validates a given PaymentRequestData struct
+func ValidatePaymentRequestData(v *validator.Validator, data *PaymentRequestData) {
+	// General validation
+	v.Check(data.RequesterAccountNumber != "", "requesterAccountNumber", "must be provided")
+	v.Check(data.PayerAccountNumber != "", "payerAccountNumber", "must be provided")
+	v.Check(data.Amount != "", "amount", "must be provided")
+	v.Check(data.Reason != "", "reason", "must be provided")
+	v.Check(data.ExpiresInHours >= 0, "expiresInHours", "must not be negative")
+}
+
+// ValidatePaymentRequestActionData validates a given PaymentRequestActionData struct
+func ValidatePaymentRequestActionData(v *validator.Validator, data *PaymentRequestActionData) {
+	// General validation
+	v.Check(data.AccountNumber != "", "accountNumber", "must be provided")
+	v.Check(data.RequestID > 0, "requestId", "must be provided")
+}
+
+// ValidatePotData validates a given PotData struct
+func ValidatePotData(v *validator.Validator, data *PotData) {
+	v.Check(data.AccountNumber != "", "accountNumber", "must be provided")
+	v.Check(data.Name != "", "name", "must be provided")
+}
+
+// ValidatePotTransferData validates a given PotTransferData struct
+func ValidatePotTransferData(v *validator.Validator, data *PotTransferData) {
+	v.Check(data.AccountNumber != "", "accountNumber", "must be provided")
+	v.Check(data.PotID > 0, "potId", "must be provided")
+	v.Check(data.Amount != "", "amount", "must be provided")
+}
+
+// ValidateTermDepositData validates a given TermDepositData struct
+func ValidateTermDepositData(v *validator.Validator, data *TermDepositData) {
+	v.Check(data.AccountNumber != "", "accountNumber", "must be provided")
+	v.Check(data.ProductID > 0, "productId", "must be provided")
+	v.Check(data.Amount != "", "amount", "must be provided")
+	v.Check(data.RolloverOption == "" || validator.In(data.RolloverOption, "none", "principal", "principal_and_interest"), "rolloverOption", "must be none, principal or principal_and_interest")
+}
+
+// ValidateTermDepositActionData validates a given TermDepositActionData struct
+func ValidateTermDepositActionData(v *validator.Validator, data *TermDepositActionData) {
+	v.Check(data.DepositNumber != "", "depositNumber", "must be provided")
+}
+
+// ValidateOverdraftApplicationData validates a given OverdraftApplicationData struct
+func ValidateOverdraftApplicationData(v *validator.Validator, data *OverdraftApplicationData) {
+	v.Check(data.AccountNumber != "", "accountNumber", "must be provided")
+	v.Check(data.Limit != "", "limit", "must be provided")
+	v.Check(len(data.Reason) <= 255, "reason", "must not be more than 255 characters")
+}
+
+// ValidateOverdraftActionData validates a given OverdraftActionData struct
+func ValidateOverdraftActionData(v *validator.Validator, data *OverdraftActionData) {
+	v.Check(data.OverdraftID > 0, "overdraftId", "must be provided")
+	v.Check(len(data.Reason) <= 255, "reason", "must not be more than 255 characters")
+}
+
+// ValidateLoanApplicationData validates a given LoanApplicationData struct
+func ValidateLoanApplicationData(v *validator.Validator, data *LoanApplicationData) {
+	v.Check(data.AccountNumber != "", "accountNumber", "must be provided")
+	v.Check(data.Amount != "", "amount", "must be provided")
+	v.Check(data.TenorMonths > 0, "tenorMonths", "must be provided")
+	v.Check(len(data.Purpose) <= 255, "purpose", "must not be more than 255 characters")
+}
+
+// ValidateLoanActionData validates a given LoanActionData struct
+func ValidateLoanActionData(v *validator.Validator, data *LoanActionData) {
+	v.Check(data.LoanNumber != "", "loanNumber", "must be provided")
+	v.Check(len(data.Reason) <= 255, "reason", "must not be more than 255 characters")
+}
+
+// ValidateMandatePaymentActionData validates a given MandatePaymentActionData struct
+func ValidateMandatePaymentActionData(v *validator.Validator, data *MandatePaymentActionData) {
+	v.Check(data.PaymentID > 0, "paymentId", "must be provided")
+}
+
+// ValidateMerchantQRData validates a given MerchantQRData struct
+func ValidateMerchantQRData(v *validator.Validator, data *MerchantQRData) {
+	// General validation
+	v.Check(data.MerchantAccountNumber != "", "merchantAccountNumber", "must be provided")
+	v.Check(data.MerchantName != "", "merchantName", "must be provided")
+	v.Check(validator.In(data.Type, "static", "dynamic"), "type", "must be static or dynamic")
+	v.Check(data.Type != "dynamic" || data.Amount != "", "amount", "must be provided for dynamic codes")
+	v.Check(len(data.Reference) <= 25, "reference", "must not be more than 25 characters")
+}
+
+// ValidateMerchantQRReferenceData validates a given MerchantQRReferenceData struct
+func ValidateMerchantQRReferenceData(v *validator.Validator, data *MerchantQRReferenceData) {
+	// General validation
+	v.Check(data.MerchantAccountNumber != "", "merchantAccountNumber", "must be provided")
+	v.Check(data.Reference != "", "reference", "must be provided")
+	v.Check(data.Size >= 0 && data.Size <= 1024, "size", "must be between 0 and 1024")
+}
+
+// ValidateMerchantQRPaymentData validates a given MerchantQRPaymentData struct
+func ValidateMerchantQRPaymentData(v *validator.Validator, data *MerchantQRPaymentData) {
+	// General validation
+	v.Check(data.PayerAccountNumber != "", "payerAccountNumber", "must be provided")
+	v.Check(data.Payload != "", "payload", "must be provided")
+}
+
+// ValidateMerchantReconciliationData validates a given MerchantReconciliationData struct
+func ValidateMerchantReconciliationData(v *validator.Validator, data *MerchantReconciliationData) {
+	// General validation
+	v.Check(data.MerchantAccountNumber != "", "merchantAccountNumber", "must be provided")
+	v.Check(data.From != "", "from", "must be provided")
+	v.Check(data.To != "", "to", "must be provided")
+}
+
 // ValidateUser validates a given User struct
 func ValidateUser(v *validator.Validator, data *User) {
 	// General validation
@@ -97,11 +229,77 @@ func ValidateNewAccountRequestData(v *validator.Validator, data *NewAccountReque
 	v.Check(data.AccountHolderPostalCode != "", "accountHolderPostalCode", "must be provided")
 	v.Check(data.AccountHolderIdentificationType != "", "accountHolderIdentificationType", "must be provided")
 	v.Check(data.Country != "", "country", "must be provided")
+	v.Check(data.CurrencyCode == "" || currency.IsSupported(data.CurrencyCode), "currencyCode", "is not a supported currency")
+
+}
 
+// ValidateCurrencyAccountData validates a given CurrencyAccountData struct
+func ValidateCurrencyAccountData(v *validator.Validator, data *CurrencyAccountData) {
+	// General validation
+	v.Check(currency.IsSupported(data.CurrencyCode), "currencyCode", "is not a supported currency")
 }
 func ValidateCashPickupData(v *validator.Validator, data *payments.CashPickup) {
 	// General validation
+	v.Check(data.SendersAccountNumber != "", "sendersAccountNumber", "must be provided")
+	v.Check(data.FirstName != "", "firstName", "must be provided")
+	v.Check(data.LastName != "", "lastName", "must be provided")
+	v.Check(data.Amount.IsPositive(), "amount", "must be greater than zero")
+	v.Check(data.BVN != "" || data.NIN != "", "bvn", "bvn or nin must be provided")
+	v.Check(data.BVN == "" || len(data.BVN) == 11, "bvn", "must be 11 digits")
+	v.Check(data.NIN == "" || len(data.NIN) == 11, "nin", "must be 11 digits")
+	v.Check(data.Currency == "" || data.Currency == payments.CASH_PICKUP_DEFAULT_CURRENCY, "currency", "must be "+payments.CASH_PICKUP_DEFAULT_CURRENCY)
+}
+
+// ValidateCashPickupActionData validates a given CashPickupActionData struct
+func ValidateCashPickupActionData(v *validator.Validator, data *CashPickupActionData) {
+	// General validation
+	v.Check(data.SendersAccountNumber != "", "sendersAccountNumber", "must be provided")
+}
 
+// ValidateCashPickupRedeemData validates a given CashPickupRedeemData struct
+func ValidateCashPickupRedeemData(v *validator.Validator, data *CashPickupRedeemData) {
+	// General validation
+	v.Check(data.PickupCode != "", "pickupCode", "must be provided")
+	v.Check(data.Pin != "", "pin", "must be provided")
+	v.Check(validator.In(data.IdentificationType, payments.CashPickupIdentificationBVN, payments.CashPickupIdentificationNIN), "identificationType", "must be bvn or nin")
+	v.Check(data.IdentificationNumber != "", "identificationNumber", "must be provided")
+}
+
+// ValidateAgentCashInData validates a given AgentCashInData struct
+func ValidateAgentCashInData(v *validator.Validator, data *AgentCashInData) {
+	// General validation
+	v.Check(data.CustomerAccountNumber != "", "customerAccountNumber", "must be provided")
+	v.Check(data.Amount != "", "amount", "must be provided")
+}
+
+// ValidateAgentCashOutData validates a given AgentCashOutData struct
+func ValidateAgentCashOutData(v *validator.Validator, data *AgentCashOutData) {
+	// General validation
+	v.Check(data.AgentCode != "", "agentCode", "must be provided")
+	v.Check(data.CustomerAccountNumber != "", "customerAccountNumber", "must be provided")
+	v.Check(data.Amount != "", "amount", "must be provided")
+}
+
+// ValidateAgentTopUpData validates a given AgentTopUpData struct
+func ValidateAgentTopUpData(v *validator.Validator, data *AgentTopUpData) {
+	// General validation
+	v.Check(data.Amount != "", "amount", "must be provided")
+	v.Check(data.Reference != "", "reference", "must be provided")
+}
+
+// ValidateFXQuoteData validates a given FXQuoteData struct
+func ValidateFXQuoteData(v *validator.Validator, data *FXQuoteData) {
+	// General validation
+	v.Check(data.SourceAccountNumber != "", "sourceAccountNumber", "must be provided")
+	v.Check(data.TargetAccountNumber != "", "targetAccountNumber", "must be provided")
+	v.Check(data.SourceAccountNumber != data.TargetAccountNumber, "targetAccountNumber", "must be a different account")
+	v.Check(data.Amount != "", "amount", "must be provided")
+}
+
+// ValidateFXAcceptQuoteData validates a given FXAcceptQuoteData struct
+func ValidateFXAcceptQuoteData(v *validator.Validator, data *FXAcceptQuoteData) {
+	// General validation
+	v.Check(data.Reference != "", "reference", "must be provided")
 }
 func ValidateProofOfAddress(v *validator.Validator, data *ProofOfAddress) {
 	// General validation
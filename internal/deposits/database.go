@@ -0,0 +1,408 @@
+package deposits
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+// productRate is the product's rate in effect on a date, zero when none is
+const productRate = "IFNULL((SELECT r.`rate` FROM `deposit_product_rates` r WHERE r.`productId` = p.`id` AND r.`effectiveFrom` <= ? ORDER BY r.`effectiveFrom` DESC, r.`id` DESC LIMIT 1), 0)"
+
+const productColumns = "p.`id`, p.`code`, p.`name`, p.`currencyCode`, p.`termDays`, p.`minAmount`, p.`maxAmount`, p.`breakPenaltyRate`, " + productRate + ", p.`status`, p.`timestamp`"
+
+const depositColumns = "d.`id`, d.`depositNumber`, d.`accountNumber`, d.`productId`, IFNULL(p.`name`, ''), d.`currencyCode`, d.`principal`, d.`rate`, d.`termDays`, d.`startDate`, d.`maturityDate`, " +
+	"d.`accruedInterest`, d.`accruedThrough`, d.`rolloverOption`, d.`status`, d.`interestSettled`, d.`interestPaid`, d.`penalty`, d.`rolledOverFrom`, d.`rolledOverTo`, d.`actor`, d.`reason`, IFNULL(d.`closedAt`, ''), d.`timestamp`"
+
+// saveProduct inserts a product with its opening rate, in effect from today
+func saveProduct(product Product, creator string) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("deposits.saveProduct: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO deposit_products (`code`, `name`, `currencyCode`, `termDays`, `minAmount`, `maxAmount`, `breakPenaltyRate`, `status`, `creator`) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		product.Code, product.Name, product.CurrencyCode, product.TermDays, product.MinAmount, product.MaxAmount, product.BreakPenaltyRate, product.Status, creator)
+	if err != nil {
+		return 0, errors.New("deposits.saveProduct: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("deposits.saveProduct: " + err.Error())
+	}
+
+	_, err = tx.Exec("INSERT INTO deposit_product_rates (`productId`, `rate`, `effectiveFrom`, `creator`) VALUES(?, ?, ?, ?)",
+		id, product.Rate, time.Now().Format(DATE_LAYOUT), creator)
+	if err != nil {
+		return 0, errors.New("deposits.saveProduct: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("deposits.saveProduct: " + err.Error())
+	}
+
+	return id, nil
+}
+
+func saveRate(rate Rate) (id int64, err error) {
+	insertStatement := "INSERT INTO deposit_product_rates (`productId`, `rate`, `effectiveFrom`, `creator`) VALUES(?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("deposits.saveRate: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(rate.ProductID, rate.Rate, rate.EffectiveFrom, rate.Creator)
+	if err != nil {
+		return 0, errors.New("deposits.saveRate: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("deposits.saveRate: " + err.Error())
+	}
+	return id, nil
+}
+
+func getRates(productID int64) (rates []Rate, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `productId`, `rate`, `effectiveFrom`, `creator`, `timestamp` FROM `deposit_product_rates` WHERE `productId` = ? ORDER BY `effectiveFrom` DESC, `id` DESC", productID)
+	if err != nil {
+		return nil, errors.New("deposits.getRates: " + err.Error())
+	}
+	defer rows.Close()
+
+	rates = make([]Rate, 0)
+	for rows.Next() {
+		var rate Rate
+		var timestamp string
+		if err := rows.Scan(&rate.ID, &rate.ProductID, &rate.Rate, &rate.EffectiveFrom, &rate.Creator, &timestamp); err != nil {
+			return nil, errors.New("deposits.getRates: " + err.Error())
+		}
+		rate.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("deposits.getRates: " + err.Error())
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("deposits.getRates: " + err.Error())
+	}
+
+	return rates, nil
+}
+
+// getProduct returns a product with its rate on date
+func getProduct(id int64, date string) (product Product, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+productColumns+" FROM `deposit_products` p WHERE p.`id` = ?", date, id)
+	if err != nil {
+		return Product{}, false, errors.New("deposits.getProduct: " + err.Error())
+	}
+	defer rows.Close()
+
+	products, err := scanProducts(rows)
+	if err != nil {
+		return Product{}, false, errors.New("deposits.getProduct: " + err.Error())
+	}
+	if len(products) == 0 {
+		return Product{}, false, nil
+	}
+
+	return products[0], true, nil
+}
+
+func getProducts(date string) (products []Product, err error) {
+	rows, err := Config.Db.Query("SELECT "+productColumns+" FROM `deposit_products` p WHERE p.`status` = ? ORDER BY p.`currencyCode`, p.`termDays`", date, ProductActive)
+	if err != nil {
+		return nil, errors.New("deposits.getProducts: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanProducts(rows)
+}
+
+func scanProducts(rows *sql.Rows) (products []Product, err error) {
+	products = make([]Product, 0)
+	for rows.Next() {
+		var product Product
+		var timestamp string
+		if err := rows.Scan(&product.ID, &product.Code, &product.Name, &product.CurrencyCode, &product.TermDays, &product.MinAmount, &product.MaxAmount,
+			&product.BreakPenaltyRate, &product.Rate, &product.Status, &timestamp); err != nil {
+			return nil, errors.New("deposits.scanProducts: " + err.Error())
+		}
+		product.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("deposits.scanProducts: " + err.Error())
+		}
+		products = append(products, product)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("deposits.scanProducts: " + err.Error())
+	}
+
+	return products, nil
+}
+
+func updateProductStatus(id int64, status string) (err error) {
+	res, err := Config.Db.Exec("UPDATE deposit_products SET `status` = ? WHERE `id` = ?", status, id)
+	if err != nil {
+		return errors.New("deposits.updateProductStatus: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("deposits.updateProductStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("deposits.updateProductStatus: Product not found or already " + status)
+	}
+	return nil
+}
+
+// saveDeposit inserts a deposit and gives it its number
+func saveDeposit(deposit Deposit) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("deposits.saveDeposit: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	id, err = insertDeposit(tx, deposit)
+	if err != nil {
+		return 0, errors.New("deposits.saveDeposit: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("deposits.saveDeposit: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// saveRollover inserts the deposit rolled over from another and records it on that deposit
+// together, so a deposit can't be rolled over twice or left without the one it rolled into
+func saveRollover(fromID int64, deposit Deposit) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("deposits.saveRollover: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	id, err = insertDeposit(tx, deposit)
+	if err != nil {
+		return 0, errors.New("deposits.saveRollover: " + err.Error())
+	}
+
+	res, err := tx.Exec("UPDATE term_deposits SET `rolledOverTo` = ? WHERE `id` = ? AND `rolledOverTo` = ''", FormatDepositNumber(id), fromID)
+	if err != nil {
+		return 0, errors.New("deposits.saveRollover: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.New("deposits.saveRollover: " + err.Error())
+	}
+	if affected == 0 {
+		return 0, errors.New("deposits.saveRollover: Deposit was already rolled over")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("deposits.saveRollover: " + err.Error())
+	}
+
+	return id, nil
+}
+
+func insertDeposit(tx *sql.Tx, deposit Deposit) (id int64, err error) {
+	insertStatement := "INSERT INTO term_deposits (`accountNumber`, `productId`, `currencyCode`, `principal`, `rate`, `termDays`, `startDate`, `maturityDate`, `accruedInterest`, `accruedThrough`, `rolloverOption`, `status`, `rolledOverFrom`, `actor`, `reason`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, '')"
+	res, err := tx.Exec(insertStatement, deposit.AccountNumber, deposit.ProductID, deposit.CurrencyCode, deposit.Principal, deposit.Rate, deposit.TermDays,
+		deposit.StartDate, deposit.MaturityDate, deposit.AccruedThrough, deposit.RolloverOption, deposit.Status, deposit.RolledOverFrom, deposit.Actor)
+	if err != nil {
+		return 0, errors.New("deposits.insertDeposit: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("deposits.insertDeposit: " + err.Error())
+	}
+
+	_, err = tx.Exec("UPDATE term_deposits SET `depositNumber` = ? WHERE `id` = ?", FormatDepositNumber(id), id)
+	if err != nil {
+		return 0, errors.New("deposits.insertDeposit: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// deleteDeposit removes a deposit whose principal was never moved
+func deleteDeposit(id int64) (err error) {
+	_, err = Config.Db.Exec("DELETE FROM term_deposits WHERE `id` = ? AND `accruedInterest` = 0", id)
+	if err != nil {
+		return errors.New("deposits.deleteDeposit: " + err.Error())
+	}
+	return nil
+}
+
+func getDeposit(depositNumber string) (deposit Deposit, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+depositColumns+" FROM `term_deposits` d LEFT JOIN `deposit_products` p ON p.`id` = d.`productId` WHERE d.`depositNumber` = ?", depositNumber)
+	if err != nil {
+		return Deposit{}, false, errors.New("deposits.getDeposit: " + err.Error())
+	}
+	defer rows.Close()
+
+	deposits, err := scanDeposits(rows)
+	if err != nil {
+		return Deposit{}, false, errors.New("deposits.getDeposit: " + err.Error())
+	}
+	if len(deposits) == 0 {
+		return Deposit{}, false, nil
+	}
+
+	return deposits[0], true, nil
+}
+
+func getAccountDeposits(accountNumber string) (deposits []Deposit, err error) {
+	rows, err := Config.Db.Query("SELECT "+depositColumns+" FROM `term_deposits` d LEFT JOIN `deposit_products` p ON p.`id` = d.`productId` WHERE d.`accountNumber` = ? ORDER BY d.`id` DESC", accountNumber)
+	if err != nil {
+		return nil, errors.New("deposits.getAccountDeposits: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanDeposits(rows)
+}
+
+func getDepositsByStatus(statuses ...string) (deposits []Deposit, err error) {
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	rows, err := Config.Db.Query("SELECT "+depositColumns+" FROM `term_deposits` d LEFT JOIN `deposit_products` p ON p.`id` = d.`productId` WHERE d.`status` IN ("+placeholders+") ORDER BY d.`maturityDate`, d.`id`", args...)
+	if err != nil {
+		return nil, errors.New("deposits.getDepositsByStatus: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanDeposits(rows)
+}
+
+func scanDeposits(rows *sql.Rows) (deposits []Deposit, err error) {
+	deposits = make([]Deposit, 0)
+	for rows.Next() {
+		var deposit Deposit
+		var timestamp string
+		if err := rows.Scan(&deposit.ID, &deposit.DepositNumber, &deposit.AccountNumber, &deposit.ProductID, &deposit.ProductName, &deposit.CurrencyCode,
+			&deposit.Principal, &deposit.Rate, &deposit.TermDays, &deposit.StartDate, &deposit.MaturityDate, &deposit.AccruedInterest, &deposit.AccruedThrough,
+			&deposit.RolloverOption, &deposit.Status, &deposit.InterestSettled, &deposit.InterestPaid, &deposit.Penalty, &deposit.RolledOverFrom,
+			&deposit.RolledOverTo, &deposit.Actor, &deposit.Reason, &deposit.ClosedAt, &timestamp); err != nil {
+			return nil, errors.New("deposits.scanDeposits: " + err.Error())
+		}
+		deposit.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("deposits.scanDeposits: " + err.Error())
+		}
+		deposits = append(deposits, deposit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("deposits.scanDeposits: " + err.Error())
+	}
+
+	return deposits, nil
+}
+
+// saveAccruals records a day's interest for each date and moves the deposit's accrued total
+// on. The deposit must still be accrued through from, so two runs can't accrue the same days.
+func saveAccruals(id int64, from string, dates []string, daily decimal.Decimal) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("deposits.saveAccruals: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	for _, date := range dates {
+		_, err = tx.Exec("INSERT INTO term_deposit_accruals (`depositId`, `accrualDate`, `amount`) VALUES(?, ?, ?)", id, date, daily)
+		if err != nil {
+			return errors.New("deposits.saveAccruals: " + err.Error())
+		}
+	}
+
+	total := daily.Mul(decimal.NewFromInt(int64(len(dates))))
+	res, err := tx.Exec("UPDATE term_deposits SET `accruedInterest` = `accruedInterest` + ?, `accruedThrough` = ? WHERE `id` = ? AND `accruedThrough` = ? AND `status` = ?",
+		total, dates[len(dates)-1], id, from, DepositActive)
+	if err != nil {
+		return errors.New("deposits.saveAccruals: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("deposits.saveAccruals: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("deposits.saveAccruals: Deposit was accrued or settled in the meantime")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("deposits.saveAccruals: " + err.Error())
+	}
+
+	return nil
+}
+
+// saveSettledInterest records the interest moved into the pool for a deposit claimed with status
+func saveSettledInterest(id int64, status string, interest decimal.Decimal, penalty decimal.Decimal) (err error) {
+	res, err := Config.Db.Exec("UPDATE term_deposits SET `interestSettled` = 1, `interestPaid` = ?, `penalty` = ? WHERE `id` = ? AND `status` = ? AND `interestSettled` = 0",
+		interest, penalty, id, status)
+	if err != nil {
+		return errors.New("deposits.saveSettledInterest: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("deposits.saveSettledInterest: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("deposits.saveSettledInterest: Deposit interest was already settled")
+	}
+	return nil
+}
+
+// updateDepositStatus moves a deposit from one status to another, failing if it isn't in
+// the from status. Reaching a final status sets the closing date, any other status clears it.
+func updateDepositStatus(id int64, from string, to string, actor string, reason string) (err error) {
+	updateStatement := "UPDATE term_deposits SET `status` = ?, `actor` = IF(? = '', `actor`, ?), `reason` = ?, `closedAt` = IF(? IN (?, ?, ?), NULL, CURDATE()) WHERE `id` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("deposits.updateDepositStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, actor, actor, reason, to, DepositActive, DepositMaturing, DepositBreaking, id, from)
+	if err != nil {
+		return errors.New("deposits.updateDepositStatus: " + err.Error())
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("deposits.updateDepositStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("deposits.updateDepositStatus: Deposit is no longer " + from)
+	}
+
+	return nil
+}
+
+func updateRollover(id int64, rolloverOption string) (err error) {
+	_, err = Config.Db.Exec("UPDATE term_deposits SET `rolloverOption` = ? WHERE `id` = ? AND `status` = ?", rolloverOption, id, DepositActive)
+	if err != nil {
+		return errors.New("deposits.updateRollover: " + err.Error())
+	}
+	return nil
+}
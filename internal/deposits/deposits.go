@@ -0,0 +1,795 @@
+package deposits
+
+/*
+Fixed-term deposits
+
+A term deposit locks an amount from a customer's current account for a product's term at the
+product's rate on the day it's booked. The principal moves to the term deposit pool account
+for the currency (TERM_DEPOSIT_ACCOUNT_NUMBER_<CODE>) with a PAIN 1001 posting and stays
+there until the deposit ends.
+
+Interest accrues daily, actual/365: each day earns principal x rate / 365. Accruals are kept
+to six decimal places and only rounded to the currency's minor units when the interest is
+paid, out of the bank's interest account for the currency
+(TERM_DEPOSIT_INTEREST_ACCOUNT_NUMBER_<CODE>), into the pool.
+
+On maturity the deposit is settled by its rollover option:
+
+	none                    principal and interest are paid to the current account
+	principal               interest is paid out, the principal is booked for another term
+	principal_and_interest  principal and interest are booked together for another term
+
+Rolled over deposits take the product's rate on the maturity date.
+
+Breaking a deposit early pays the principal back with the interest accrued so far, less the
+product's early-break penalty: the percentage of the accrued interest that is forfeited.
+
+A deposit is claimed as maturing or breaking before anything is posted for it, so the
+accrual run and a break can't both settle it. The interest, the rolled over deposit and the
+payout are each made once, and a settlement that fails part way is finished by the next
+accrual run.
+
+active -> maturing -> matured | rolled_over
+active -> breaking -> broken
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DepositActive     = "active"
+	DepositMaturing   = "maturing"
+	DepositBreaking   = "breaking"
+	DepositMatured    = "matured"
+	DepositRolledOver = "rolled_over"
+	DepositBroken     = "broken"
+
+	RolloverNone                 = "none"
+	RolloverPrincipal            = "principal"
+	RolloverPrincipalAndInterest = "principal_and_interest"
+
+	ProductActive    = "active"
+	ProductWithdrawn = "withdrawn"
+
+	DAYS_IN_YEAR          = 365
+	MAX_TERM_DAYS         = 3650
+	ACCRUAL_PLACES        = 6
+	DATE_LAYOUT           = "2006-01-02"
+	DEPOSIT_NUMBER_PREFIX = "TD"
+	SYSTEM_ACTOR          = "system"
+
+	poolAccountPrefix     = "TERM_DEPOSIT_ACCOUNT_NUMBER_"
+	interestAccountPrefix = "TERM_DEPOSIT_INTEREST_ACCOUNT_NUMBER_"
+)
+
+var Config configuration.Configuration
+
+// settlementMutex serialises settlements so a deposit isn't finished by the accrual run while
+// it's still being settled
+var settlementMutex sync.Mutex
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Product is a term deposit the bank offers
+type Product struct {
+	ID               int64           `json:"id"`
+	Code             string          `json:"code"`
+	Name             string          `json:"name"`
+	CurrencyCode     string          `json:"currencyCode"`
+	TermDays         int             `json:"termDays"`
+	MinAmount        decimal.Decimal `json:"minAmount"`
+	MaxAmount        decimal.Decimal `json:"maxAmount"`
+	BreakPenaltyRate decimal.Decimal `json:"breakPenaltyRate"`
+	Rate             decimal.Decimal `json:"rate"`
+	Status           string          `json:"status"`
+	Timestamp        time.Time       `json:"timestamp"`
+}
+
+// Rate is a product's annual interest rate, as a percentage, from a date
+type Rate struct {
+	ID            int64           `json:"id"`
+	ProductID     int64           `json:"productId"`
+	Rate          decimal.Decimal `json:"rate"`
+	EffectiveFrom string          `json:"effectiveFrom"`
+	Creator       string          `json:"creator"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// Deposit is an amount locked for a term
+type Deposit struct {
+	ID              int64           `json:"-"`
+	DepositNumber   string          `json:"depositNumber"`
+	AccountNumber   string          `json:"accountNumber"`
+	ProductID       int64           `json:"productId"`
+	ProductName     string          `json:"productName"`
+	CurrencyCode    string          `json:"currencyCode"`
+	Principal       decimal.Decimal `json:"principal"`
+	Rate            decimal.Decimal `json:"rate"`
+	TermDays        int             `json:"termDays"`
+	StartDate       string          `json:"startDate"`
+	MaturityDate    string          `json:"maturityDate"`
+	AccruedInterest decimal.Decimal `json:"accruedInterest"`
+	AccruedThrough  string          `json:"accruedThrough"`
+	RolloverOption  string          `json:"rolloverOption"`
+	Status          string          `json:"status"`
+	InterestSettled bool            `json:"-"`
+	InterestPaid    decimal.Decimal `json:"interestPaid"`
+	Penalty         decimal.Decimal `json:"penalty"`
+	RolledOverFrom  string          `json:"rolledOverFrom,omitempty"`
+	RolledOverTo    string          `json:"rolledOverTo,omitempty"`
+	Actor           string          `json:"actor"`
+	Reason          string          `json:"reason,omitempty"`
+	ClosedAt        string          `json:"closedAt,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+}
+
+// Run is what an accrual run did
+type Run struct {
+	Accrued int `json:"accrued"`
+	Matured int `json:"matured"`
+}
+
+// FormatDepositNumber is the number customers see for a deposit ID
+func FormatDepositNumber(id int64) string {
+	return fmt.Sprintf("%s%08d", DEPOSIT_NUMBER_PREFIX, id)
+}
+
+// ValidRollover reports whether option is a known rollover option
+func ValidRollover(option string) bool {
+	switch option {
+	case RolloverNone, RolloverPrincipal, RolloverPrincipalAndInterest:
+		return true
+	}
+	return false
+}
+
+// CreateProduct adds a product with its opening rate
+func CreateProduct(product Product, creator string) (Product, error) {
+	product.Code = strings.ToUpper(strings.TrimSpace(product.Code))
+	product.Name = strings.TrimSpace(product.Name)
+	product.CurrencyCode = strings.ToUpper(strings.TrimSpace(product.CurrencyCode))
+	product.Status = ProductActive
+
+	err := validateProduct(product)
+	if err != nil {
+		return Product{}, errors.New("deposits.CreateProduct: " + err.Error())
+	}
+
+	product.ID, err = saveProduct(product, creator)
+	if err != nil {
+		return Product{}, errors.New("deposits.CreateProduct: " + err.Error())
+	}
+	product.Timestamp = time.Now()
+
+	return product, nil
+}
+
+// SetRate changes a product's rate from a date, today when none is given. Deposits keep the
+// rate they were booked at.
+func SetRate(productID int64, rate string, effectiveFrom string, creator string) (Rate, error) {
+	product, err := GetProduct(productID)
+	if err != nil {
+		return Rate{}, errors.New("deposits.SetRate: " + err.Error())
+	}
+
+	newRate := Rate{ProductID: product.ID, EffectiveFrom: strings.TrimSpace(effectiveFrom), Creator: creator}
+	newRate.Rate, err = decimal.NewFromString(strings.TrimSpace(rate))
+	if err != nil {
+		return Rate{}, errors.New("deposits.SetRate: Rate is not a valid number")
+	}
+	if err := validateRate(newRate.Rate); err != nil {
+		return Rate{}, errors.New("deposits.SetRate: " + err.Error())
+	}
+	if newRate.EffectiveFrom == "" {
+		newRate.EffectiveFrom = time.Now().Format(DATE_LAYOUT)
+	}
+	if _, err := time.Parse(DATE_LAYOUT, newRate.EffectiveFrom); err != nil {
+		return Rate{}, errors.New("deposits.SetRate: Effective date must be in the format YYYY-MM-DD")
+	}
+
+	newRate.ID, err = saveRate(newRate)
+	if err != nil {
+		return Rate{}, errors.New("deposits.SetRate: " + err.Error())
+	}
+	newRate.Timestamp = time.Now()
+
+	return newRate, nil
+}
+
+// Products lists the products on offer with today's rates
+func Products() ([]Product, error) {
+	products, err := getProducts(time.Now().Format(DATE_LAYOUT))
+	if err != nil {
+		return nil, errors.New("deposits.Products: " + err.Error())
+	}
+	return products, nil
+}
+
+// GetProduct returns a product with today's rate
+func GetProduct(id int64) (Product, error) {
+	product, found, err := getProduct(id, time.Now().Format(DATE_LAYOUT))
+	if err != nil {
+		return Product{}, errors.New("deposits.GetProduct: " + err.Error())
+	}
+	if !found {
+		return Product{}, errors.New("deposits.GetProduct: Product " + strconv.FormatInt(id, 10) + " not found")
+	}
+	return product, nil
+}
+
+// WithdrawProduct stops a product being offered. Deposits already booked run to maturity and
+// roll over as before.
+func WithdrawProduct(id int64) error {
+	err := updateProductStatus(id, ProductWithdrawn)
+	if err != nil {
+		return errors.New("deposits.WithdrawProduct: " + err.Error())
+	}
+	return nil
+}
+
+// Rates lists a product's rates, the latest first
+func Rates(productID int64) ([]Rate, error) {
+	rates, err := getRates(productID)
+	if err != nil {
+		return nil, errors.New("deposits.Rates: " + err.Error())
+	}
+	return rates, nil
+}
+
+// Open books a deposit from a current account. The amount is moved to the pool account
+// straight away.
+func Open(accountNumber string, productID int64, amount string, rolloverOption string, actor string) (Deposit, error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	rolloverOption = strings.TrimSpace(rolloverOption)
+	if rolloverOption == "" {
+		rolloverOption = RolloverNone
+	}
+	if !ValidRollover(rolloverOption) {
+		return Deposit{}, errors.New("deposits.Open: Rollover option must be " + RolloverNone + ", " + RolloverPrincipal + " or " + RolloverPrincipalAndInterest)
+	}
+
+	product, err := GetProduct(productID)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+	if product.Status != ProductActive {
+		return Deposit{}, errors.New("deposits.Open: " + product.Name + " is no longer offered")
+	}
+	if !product.Rate.IsPositive() {
+		return Deposit{}, errors.New("deposits.Open: " + product.Name + " has no rate today")
+	}
+
+	currencyCode, err := payments.AccountCurrency(accountNumber)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+	if currencyCode != product.CurrencyCode {
+		return Deposit{}, errors.New("deposits.Open: " + product.Name + " is held in " + product.CurrencyCode + ", the account is in " + currencyCode)
+	}
+	err = payments.RequireSingleSignature(accountNumber)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+
+	principal, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: Could not convert amount to decimal. " + err.Error())
+	}
+	err = checkAmount(product, principal)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+
+	pool, err := systemAccount(poolAccountPrefix, currencyCode)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+
+	deposit := newDeposit(product, accountNumber, principal, rolloverOption, time.Now(), actor)
+	deposit.ID, err = saveDeposit(deposit)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+	deposit.DepositNumber = FormatDepositNumber(deposit.ID)
+
+	_, err = payments.ProcessPAIN([]string{"", "pain", "1001", accountNumber + "@", pool + "@", principal.String(), "Term deposit " + deposit.DepositNumber, actor})
+	if err != nil {
+		// Nothing was moved, the deposit was never booked
+		if deleteErr := deleteDeposit(deposit.ID); deleteErr != nil {
+			return Deposit{}, errors.New("deposits.Open: " + err.Error() + ". " + deleteErr.Error())
+		}
+		return Deposit{}, errors.New("deposits.Open: " + err.Error())
+	}
+	deposit.Timestamp = time.Now()
+
+	return deposit, nil
+}
+
+// Get returns a deposit by its number
+func Get(depositNumber string) (Deposit, error) {
+	deposit, found, err := getDeposit(strings.TrimSpace(depositNumber))
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Get: " + err.Error())
+	}
+	if !found {
+		return Deposit{}, errors.New("deposits.Get: Deposit " + depositNumber + " not found")
+	}
+	return deposit, nil
+}
+
+// ForAccount lists the deposits booked from an account, the latest first
+func ForAccount(accountNumber string) ([]Deposit, error) {
+	deposits, err := getAccountDeposits(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return nil, errors.New("deposits.ForAccount: " + err.Error())
+	}
+	return deposits, nil
+}
+
+// ActiveForAccount counts the account's deposits that haven't ended yet
+func ActiveForAccount(accountNumber string) (int, error) {
+	deposits, err := ForAccount(accountNumber)
+	if err != nil {
+		return 0, errors.New("deposits.ActiveForAccount: " + err.Error())
+	}
+	active := 0
+	for _, deposit := range deposits {
+		switch deposit.Status {
+		case DepositActive, DepositMaturing, DepositBreaking:
+			active++
+		}
+	}
+	return active, nil
+}
+
+// SetRollover changes what happens to an active deposit on maturity
+func SetRollover(depositNumber string, rolloverOption string) (Deposit, error) {
+	rolloverOption = strings.TrimSpace(rolloverOption)
+	if !ValidRollover(rolloverOption) {
+		return Deposit{}, errors.New("deposits.SetRollover: Rollover option must be " + RolloverNone + ", " + RolloverPrincipal + " or " + RolloverPrincipalAndInterest)
+	}
+	deposit, err := Get(depositNumber)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.SetRollover: " + err.Error())
+	}
+	if deposit.Status != DepositActive {
+		return Deposit{}, errors.New("deposits.SetRollover: Deposit is " + deposit.Status)
+	}
+
+	err = updateRollover(deposit.ID, rolloverOption)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.SetRollover: " + err.Error())
+	}
+	deposit.RolloverOption = rolloverOption
+
+	return deposit, nil
+}
+
+// RunAccruals accrues interest on every active deposit up to today and settles the ones that
+// have matured, finishing any settlement left part way. One deposit failing doesn't stop the
+// rest.
+func RunAccruals() (run Run, err error) {
+	today := time.Now().Format(DATE_LAYOUT)
+	open, err := getDepositsByStatus(DepositActive, DepositMaturing, DepositBreaking)
+	if err != nil {
+		return Run{}, errors.New("deposits.RunAccruals: " + err.Error())
+	}
+
+	failures := []string{}
+	for _, deposit := range open {
+		switch deposit.Status {
+		case DepositBreaking:
+			_, err = breakDeposit(deposit.DepositNumber)
+			if err != nil {
+				failures = append(failures, deposit.DepositNumber+": "+err.Error())
+			}
+			continue
+		case DepositActive:
+			accrued, err := accrue(&deposit, today)
+			if err != nil {
+				failures = append(failures, deposit.DepositNumber+": "+err.Error())
+				continue
+			}
+			if accrued {
+				run.Accrued++
+			}
+			if deposit.MaturityDate > today {
+				continue
+			}
+		}
+
+		_, err = mature(deposit)
+		if err != nil {
+			failures = append(failures, deposit.DepositNumber+": "+err.Error())
+			continue
+		}
+		run.Matured++
+	}
+
+	if len(failures) > 0 {
+		return run, errors.New("deposits.RunAccruals: " + strings.Join(failures, "; "))
+	}
+	return run, nil
+}
+
+// Break ends a deposit before maturity. The principal is paid back with the interest accrued
+// so far less the product's early-break penalty. A break that failed part way is finished.
+func Break(depositNumber string, reason string, actor string) (Deposit, error) {
+	deposit, err := Get(depositNumber)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Break: " + err.Error())
+	}
+
+	switch deposit.Status {
+	case DepositActive:
+		today := time.Now().Format(DATE_LAYOUT)
+		if deposit.MaturityDate <= today {
+			return Deposit{}, errors.New("deposits.Break: Deposit has matured and will be settled by the accrual run")
+		}
+		_, err = accrue(&deposit, today)
+		if err != nil {
+			return Deposit{}, errors.New("deposits.Break: " + err.Error())
+		}
+		// Claim the deposit before anything is posted so the accrual run leaves it alone
+		err = updateDepositStatus(deposit.ID, DepositActive, DepositBreaking, actor, reason)
+		if err != nil {
+			return Deposit{}, errors.New("deposits.Break: " + err.Error())
+		}
+	case DepositBreaking:
+	default:
+		return Deposit{}, errors.New("deposits.Break: Deposit is " + deposit.Status)
+	}
+
+	deposit, err = breakDeposit(depositNumber)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.Break: " + err.Error())
+	}
+
+	return deposit, nil
+}
+
+// BreakPenalty is the share of the interest forfeited when a deposit is broken
+func BreakPenalty(currencyCode string, interest decimal.Decimal, penaltyRate decimal.Decimal) decimal.Decimal {
+	if !interest.IsPositive() || !penaltyRate.IsPositive() {
+		return decimal.Zero
+	}
+	penalty := currency.Round(currencyCode, interest.Mul(penaltyRate).Div(decimal.NewFromInt(100)))
+	if penalty.GreaterThan(interest) {
+		return interest
+	}
+	return penalty
+}
+
+// DailyInterest is a day's interest on principal at an annual rate, actual/365
+func DailyInterest(principal decimal.Decimal, rate decimal.Decimal) decimal.Decimal {
+	return principal.Mul(rate).Div(decimal.NewFromInt(100 * DAYS_IN_YEAR)).Round(ACCRUAL_PLACES)
+}
+
+// accrualDates are the days after accruedThrough up to and including through
+func accrualDates(accruedThrough string, through string) ([]string, error) {
+	from, err := time.Parse(DATE_LAYOUT, accruedThrough)
+	if err != nil {
+		return nil, errors.New("deposits.accrualDates: " + err.Error())
+	}
+	to, err := time.Parse(DATE_LAYOUT, through)
+	if err != nil {
+		return nil, errors.New("deposits.accrualDates: " + err.Error())
+	}
+
+	dates := []string{}
+	for day := from.AddDate(0, 0, 1); !day.After(to); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format(DATE_LAYOUT))
+	}
+	return dates, nil
+}
+
+// accrue adds a day's interest for each day since the deposit was last accrued, up to today
+// or its maturity date if that's sooner
+func accrue(deposit *Deposit, today string) (bool, error) {
+	through := today
+	if deposit.MaturityDate < through {
+		through = deposit.MaturityDate
+	}
+	dates, err := accrualDates(deposit.AccruedThrough, through)
+	if err != nil {
+		return false, errors.New("deposits.accrue: " + err.Error())
+	}
+	if len(dates) == 0 {
+		return false, nil
+	}
+
+	daily := DailyInterest(deposit.Principal, deposit.Rate)
+	err = saveAccruals(deposit.ID, deposit.AccruedThrough, dates, daily)
+	if err != nil {
+		return false, errors.New("deposits.accrue: " + err.Error())
+	}
+	deposit.AccruedInterest = deposit.AccruedInterest.Add(daily.Mul(decimal.NewFromInt(int64(len(dates)))))
+	deposit.AccruedThrough = through
+
+	return true, nil
+}
+
+// mature settles a deposit that has reached its maturity date by its rollover option.
+// Deposits whose product is no longer offered, or has no rate, are paid out instead.
+func mature(deposit Deposit) (Deposit, error) {
+	settlementMutex.Lock()
+	defer settlementMutex.Unlock()
+
+	// Claim the deposit before anything is posted so a break can't settle it as well
+	if deposit.Status == DepositActive {
+		err := updateDepositStatus(deposit.ID, DepositActive, DepositMaturing, SYSTEM_ACTOR, "")
+		if err != nil {
+			return Deposit{}, errors.New("deposits.mature: " + err.Error())
+		}
+	}
+	deposit, err := claimed(deposit.DepositNumber, DepositMaturing)
+	if err != nil || deposit.Status != DepositMaturing {
+		return deposit, err
+	}
+	if !deposit.InterestSettled {
+		deposit.InterestPaid = currency.Round(deposit.CurrencyCode, deposit.AccruedInterest)
+	}
+
+	if deposit.RolloverOption != RolloverNone && deposit.RolledOverTo == "" {
+		product, found, err := getProduct(deposit.ProductID, deposit.MaturityDate)
+		if err != nil {
+			return Deposit{}, errors.New("deposits.mature: " + err.Error())
+		}
+		if !found || product.Status != ProductActive || !product.Rate.IsPositive() {
+			deposit.RolloverOption = RolloverNone
+		}
+	}
+
+	err = settleInterest(&deposit)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.mature: " + err.Error())
+	}
+
+	status := DepositMatured
+	payout := deposit.Principal.Add(deposit.InterestPaid)
+	switch deposit.RolloverOption {
+	case RolloverPrincipal:
+		status = DepositRolledOver
+		payout = deposit.InterestPaid
+		err = rollover(&deposit, deposit.Principal)
+	case RolloverPrincipalAndInterest:
+		status = DepositRolledOver
+		payout = decimal.Zero
+		err = rollover(&deposit, deposit.Principal.Add(deposit.InterestPaid))
+	}
+	if err != nil {
+		return Deposit{}, errors.New("deposits.mature: " + err.Error())
+	}
+
+	err = payOut(&deposit, status, payout)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.mature: " + err.Error())
+	}
+
+	return deposit, nil
+}
+
+// breakDeposit settles a deposit claimed for breaking. Nothing accrues after the claim, so
+// the interest is what had accrued when it was broken.
+func breakDeposit(depositNumber string) (Deposit, error) {
+	settlementMutex.Lock()
+	defer settlementMutex.Unlock()
+
+	deposit, err := claimed(depositNumber, DepositBreaking)
+	if err != nil || deposit.Status != DepositBreaking {
+		return deposit, err
+	}
+
+	if !deposit.InterestSettled {
+		product, err := GetProduct(deposit.ProductID)
+		if err != nil {
+			return Deposit{}, errors.New("deposits.breakDeposit: " + err.Error())
+		}
+		interest := currency.Round(deposit.CurrencyCode, deposit.AccruedInterest)
+		deposit.Penalty = BreakPenalty(deposit.CurrencyCode, interest, product.BreakPenaltyRate)
+		deposit.InterestPaid = interest.Sub(deposit.Penalty)
+	}
+
+	err = settleInterest(&deposit)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.breakDeposit: " + err.Error())
+	}
+	err = payOut(&deposit, DepositBroken, deposit.Principal.Add(deposit.InterestPaid))
+	if err != nil {
+		return Deposit{}, errors.New("deposits.breakDeposit: " + err.Error())
+	}
+
+	return deposit, nil
+}
+
+// claimed reloads a deposit under the settlement lock, so a settlement picked up part way
+// sees what was already done. A deposit settled in the meantime is returned as it is.
+func claimed(depositNumber string, status string) (Deposit, error) {
+	deposit, err := Get(depositNumber)
+	if err != nil {
+		return Deposit{}, errors.New("deposits.claimed: " + err.Error())
+	}
+	switch deposit.Status {
+	case status, DepositMatured, DepositRolledOver, DepositBroken:
+		return deposit, nil
+	}
+	return Deposit{}, errors.New("deposits.claimed: Deposit is " + deposit.Status)
+}
+
+// settleInterest moves the deposit's interest from the interest account into the pool, once
+func settleInterest(deposit *Deposit) error {
+	if deposit.InterestSettled {
+		return nil
+	}
+
+	if deposit.InterestPaid.IsPositive() {
+		pool, err := systemAccount(poolAccountPrefix, deposit.CurrencyCode)
+		if err != nil {
+			return errors.New("deposits.settleInterest: " + err.Error())
+		}
+		interestAccount, err := systemAccount(interestAccountPrefix, deposit.CurrencyCode)
+		if err != nil {
+			return errors.New("deposits.settleInterest: " + err.Error())
+		}
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", interestAccount + "@", pool + "@", deposit.InterestPaid.String(), "Term deposit " + deposit.DepositNumber + " interest", deposit.Actor})
+		if err != nil {
+			return errors.New("deposits.settleInterest: " + err.Error())
+		}
+	}
+	err := saveSettledInterest(deposit.ID, deposit.Status, deposit.InterestPaid, deposit.Penalty)
+	if err != nil {
+		return errors.New("deposits.settleInterest: Interest paid but not recorded. " + err.Error())
+	}
+	deposit.InterestSettled = true
+
+	return nil
+}
+
+// payOut moves a claimed deposit to its final status and pays payout from the pool to the
+// current account. A failed payout hands the deposit back to its claim to be retried.
+func payOut(deposit *Deposit, status string, payout decimal.Decimal) error {
+	claim := deposit.Status
+	err := updateDepositStatus(deposit.ID, claim, status, "", deposit.Reason)
+	if err != nil {
+		return errors.New("deposits.payOut: " + err.Error())
+	}
+
+	if payout.IsPositive() {
+		pool, err := systemAccount(poolAccountPrefix, deposit.CurrencyCode)
+		if err == nil {
+			_, err = payments.ProcessPAIN([]string{"", "pain", "1001", pool + "@", deposit.AccountNumber + "@", payout.String(), "Term deposit " + deposit.DepositNumber + " " + strings.ReplaceAll(status, "_", " "), deposit.Actor})
+		}
+		if err != nil {
+			if revertErr := updateDepositStatus(deposit.ID, status, claim, "", deposit.Reason); revertErr != nil {
+				return errors.New("deposits.payOut: " + err.Error() + ". " + revertErr.Error())
+			}
+			return errors.New("deposits.payOut: " + err.Error())
+		}
+	}
+
+	deposit.Status = status
+	deposit.ClosedAt = time.Now().Format(DATE_LAYOUT)
+	return nil
+}
+
+// rollover books principal for another term of the deposit's product, at the product's rate
+// on the maturity date. The money is already in the pool so nothing is posted. A deposit
+// that was already rolled over isn't booked again.
+func rollover(deposit *Deposit, principal decimal.Decimal) error {
+	if deposit.RolledOverTo != "" {
+		return nil
+	}
+
+	product, found, err := getProduct(deposit.ProductID, deposit.MaturityDate)
+	if err != nil {
+		return errors.New("deposits.rollover: " + err.Error())
+	}
+	if !found || !product.Rate.IsPositive() {
+		return errors.New("deposits.rollover: The product has no rate on " + deposit.MaturityDate)
+	}
+
+	start, err := time.Parse(DATE_LAYOUT, deposit.MaturityDate)
+	if err != nil {
+		return errors.New("deposits.rollover: " + err.Error())
+	}
+	next := newDeposit(product, deposit.AccountNumber, principal, deposit.RolloverOption, start, SYSTEM_ACTOR)
+	next.RolledOverFrom = deposit.DepositNumber
+
+	next.ID, err = saveRollover(deposit.ID, next)
+	if err != nil {
+		return errors.New("deposits.rollover: " + err.Error())
+	}
+	deposit.RolledOverTo = FormatDepositNumber(next.ID)
+
+	return nil
+}
+
+func newDeposit(product Product, accountNumber string, principal decimal.Decimal, rolloverOption string, start time.Time, actor string) Deposit {
+	startDate := start.Format(DATE_LAYOUT)
+	return Deposit{
+		AccountNumber:   accountNumber,
+		ProductID:       product.ID,
+		ProductName:     product.Name,
+		CurrencyCode:    product.CurrencyCode,
+		Principal:       principal,
+		Rate:            product.Rate,
+		TermDays:        product.TermDays,
+		StartDate:       startDate,
+		MaturityDate:    start.AddDate(0, 0, product.TermDays).Format(DATE_LAYOUT),
+		AccruedInterest: decimal.Zero,
+		AccruedThrough:  startDate,
+		RolloverOption:  rolloverOption,
+		Status:          DepositActive,
+		InterestPaid:    decimal.Zero,
+		Penalty:         decimal.Zero,
+		Actor:           actor,
+	}
+}
+
+func validateProduct(product Product) error {
+	if product.Code == "" || product.Name == "" {
+		return errors.New("deposits.validateProduct: Code and name must be provided")
+	}
+	if !currency.IsSupported(product.CurrencyCode) {
+		return errors.New("deposits.validateProduct: Currency " + product.CurrencyCode + " is not supported")
+	}
+	if product.TermDays < 1 || product.TermDays > MAX_TERM_DAYS {
+		return errors.New("deposits.validateProduct: Term must be between 1 and " + strconv.Itoa(MAX_TERM_DAYS) + " days")
+	}
+	if product.MinAmount.IsNegative() || product.MaxAmount.IsNegative() {
+		return errors.New("deposits.validateProduct: Amount limits must not be negative")
+	}
+	if product.MaxAmount.IsPositive() && product.MaxAmount.LessThan(product.MinAmount) {
+		return errors.New("deposits.validateProduct: Maximum amount must not be less than the minimum")
+	}
+	if product.BreakPenaltyRate.IsNegative() || product.BreakPenaltyRate.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("deposits.validateProduct: Early-break penalty must be between 0 and 100 percent")
+	}
+	return validateRate(product.Rate)
+}
+
+func validateRate(rate decimal.Decimal) error {
+	if !rate.IsPositive() || rate.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("deposits.validateRate: Rate must be more than 0 and at most 100 percent")
+	}
+	return nil
+}
+
+// checkAmount checks a principal is within the product's limits and fits its currency
+func checkAmount(product Product, principal decimal.Decimal) error {
+	if !principal.IsPositive() {
+		return errors.New("deposits.checkAmount: Amount must be greater than zero")
+	}
+	if !currency.Round(product.CurrencyCode, principal).Equal(principal) {
+		return errors.New("deposits.checkAmount: Amount has more decimal places than " + product.CurrencyCode + " allows")
+	}
+	if principal.LessThan(product.MinAmount) {
+		return errors.New("deposits.checkAmount: The minimum for " + product.Name + " is " + currency.Format(product.CurrencyCode, product.MinAmount))
+	}
+	if product.MaxAmount.IsPositive() && principal.GreaterThan(product.MaxAmount) {
+		return errors.New("deposits.checkAmount: The maximum for " + product.Name + " is " + currency.Format(product.CurrencyCode, product.MaxAmount))
+	}
+	return nil
+}
+
+func systemAccount(prefix string, currencyCode string) (string, error) {
+	accountNumber := strings.TrimSpace(os.Getenv(prefix + currencyCode))
+	if accountNumber == "" {
+		return "", errors.New("deposits.systemAccount: " + prefix + currencyCode + " is not configured")
+	}
+	return accountNumber, nil
+}
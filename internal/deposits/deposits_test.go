@@ -0,0 +1,137 @@
+package deposits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDailyInterest(t *testing.T) {
+	cases := []struct {
+		principal string
+		rate      string
+		daily     string
+	}{
+		{"365000", "10", "100"},
+		{"1000", "5", "0.136986"},
+		{"250000.50", "12.5", "85.616610"},
+		{"0", "10", "0"},
+	}
+	for _, c := range cases {
+		daily := DailyInterest(decimal.RequireFromString(c.principal), decimal.RequireFromString(c.rate))
+		if !daily.Equal(decimal.RequireFromString(c.daily)) {
+			t.Errorf("DailyInterest does not pass. Looking for %v, got %v for %v at %v", c.daily, daily, c.principal, c.rate)
+		}
+	}
+}
+
+func TestBreakPenalty(t *testing.T) {
+	cases := []struct {
+		interest string
+		rate     string
+		penalty  string
+	}{
+		{"100", "50", "50"},
+		{"33.33", "25", "8.33"},
+		{"100", "100", "100"},
+		{"100", "0", "0"},
+		{"0", "50", "0"},
+	}
+	for _, c := range cases {
+		penalty := BreakPenalty("NGN", decimal.RequireFromString(c.interest), decimal.RequireFromString(c.rate))
+		if !penalty.Equal(decimal.RequireFromString(c.penalty)) {
+			t.Errorf("BreakPenalty does not pass. Looking for %v, got %v for %v at %v", c.penalty, penalty, c.interest, c.rate)
+		}
+	}
+}
+
+func TestAccrualDates(t *testing.T) {
+	dates, err := accrualDates("2024-02-27", "2024-03-01")
+	if err != nil {
+		t.Fatalf("accrualDates does not pass. Looking for %v, got %v", nil, err)
+	}
+	expected := []string{"2024-02-28", "2024-02-29", "2024-03-01"}
+	if len(dates) != len(expected) {
+		t.Fatalf("accrualDates does not pass. Looking for %v, got %v", expected, dates)
+	}
+	for i := range expected {
+		if dates[i] != expected[i] {
+			t.Errorf("accrualDates does not pass. Looking for %v, got %v", expected, dates)
+		}
+	}
+
+	dates, err = accrualDates("2024-03-01", "2024-03-01")
+	if err != nil || len(dates) != 0 {
+		t.Errorf("accrualDates does not pass. Looking for no dates, got %v, %v", dates, err)
+	}
+}
+
+func TestNewDeposit(t *testing.T) {
+	product := Product{ID: 3, Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 90, Rate: decimal.RequireFromString("8.5")}
+	deposit := newDeposit(product, "0123456789", decimal.RequireFromString("50000"), RolloverPrincipal, time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC), "tester")
+
+	if deposit.StartDate != "2024-01-15" || deposit.AccruedThrough != "2024-01-15" {
+		t.Errorf("newDeposit does not pass. Looking for a start of %v, got %v accrued through %v", "2024-01-15", deposit.StartDate, deposit.AccruedThrough)
+	}
+	if deposit.MaturityDate != "2024-04-14" {
+		t.Errorf("newDeposit does not pass. Looking for maturity on %v, got %v", "2024-04-14", deposit.MaturityDate)
+	}
+	if !deposit.Rate.Equal(product.Rate) || deposit.Status != DepositActive {
+		t.Errorf("newDeposit does not pass. Looking for an active deposit at %v, got %v at %v", product.Rate, deposit.Status, deposit.Rate)
+	}
+
+	// A full term's accruals add up to the simple interest for the term
+	dates, _ := accrualDates(deposit.StartDate, deposit.MaturityDate)
+	interest := DailyInterest(deposit.Principal, deposit.Rate).Mul(decimal.NewFromInt(int64(len(dates)))).Round(2)
+	if len(dates) != 90 || !interest.Equal(decimal.RequireFromString("1047.95")) {
+		t.Errorf("newDeposit does not pass. Looking for 90 days and %v, got %v days and %v", "1047.95", len(dates), interest)
+	}
+}
+
+func TestCheckAmount(t *testing.T) {
+	product := Product{Name: "Fixed 30", CurrencyCode: "NGN", MinAmount: decimal.RequireFromString("1000"), MaxAmount: decimal.RequireFromString("5000")}
+	cases := []struct {
+		amount string
+		valid  bool
+	}{
+		{"1000", true},
+		{"5000", true},
+		{"999.99", false},
+		{"5000.01", false},
+		{"2000.001", false},
+		{"0", false},
+	}
+	for _, c := range cases {
+		err := checkAmount(product, decimal.RequireFromString(c.amount))
+		if (err == nil) != c.valid {
+			t.Errorf("checkAmount does not pass. Looking for valid %v, got %v for %v", c.valid, err, c.amount)
+		}
+	}
+
+	product.MaxAmount = decimal.Zero
+	if err := checkAmount(product, decimal.RequireFromString("1000000")); err != nil {
+		t.Errorf("checkAmount does not pass. Looking for %v, got %v with no maximum", nil, err)
+	}
+}
+
+func TestValidateProduct(t *testing.T) {
+	valid := Product{Code: "FD90", Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 90, BreakPenaltyRate: decimal.RequireFromString("50"), Rate: decimal.RequireFromString("8.5")}
+	if err := validateProduct(valid); err != nil {
+		t.Errorf("validateProduct does not pass. Looking for %v, got %v", nil, err)
+	}
+
+	invalid := []Product{
+		{Code: "", Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 90, Rate: decimal.RequireFromString("8.5")},
+		{Code: "FD90", Name: "Fixed 90", CurrencyCode: "XXX", TermDays: 90, Rate: decimal.RequireFromString("8.5")},
+		{Code: "FD90", Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 0, Rate: decimal.RequireFromString("8.5")},
+		{Code: "FD90", Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 90, Rate: decimal.Zero},
+		{Code: "FD90", Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 90, Rate: decimal.RequireFromString("8.5"), BreakPenaltyRate: decimal.RequireFromString("101")},
+		{Code: "FD90", Name: "Fixed 90", CurrencyCode: "NGN", TermDays: 90, Rate: decimal.RequireFromString("8.5"), MinAmount: decimal.RequireFromString("500"), MaxAmount: decimal.RequireFromString("100")},
+	}
+	for _, product := range invalid {
+		if err := validateProduct(product); err == nil {
+			t.Errorf("validateProduct does not pass. Looking for an error, got %v for %+v", err, product)
+		}
+	}
+}
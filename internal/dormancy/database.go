@@ -0,0 +1,166 @@
+package dormancy
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const noticeColumns = "`id`, `accountNumber`, `lastActivity`, `dormantFrom`, `notifiedTo`, `timestamp`"
+
+// lastActivityQuery is each customer account's last customer-initiated activity: a payment
+// it sent that the system didn't post, a deposit into it, or being made Active (opening,
+// KYC approval, reactivation). Accounts with no activity count from when they were opened.
+const lastActivityQuery = "SELECT a.`accountNumber`, a.`status`, a.`accountHolderName`, a.`currencyCode`, a.`accountBalance`, " +
+	"GREATEST(a.`timestamp`, " +
+	"COALESCE((SELECT MAX(t.`timestamp`) FROM `transactions` t WHERE t.`senderAccountNumber` = a.`accountNumber` AND t.`senderBankNumber` = '' AND t.`initiator` <> 'system'), a.`timestamp`), " +
+	"COALESCE((SELECT MAX(t.`timestamp`) FROM `transactions` t WHERE t.`receiverAccountNumber` = a.`accountNumber` AND t.`receiverBankNumber` = '' AND t.`type` = 1000), a.`timestamp`), " +
+	"COALESCE((SELECT MAX(c.`timestamp`) FROM `account_status_changes` c WHERE c.`accountNumber` = a.`accountNumber` AND c.`toStatus` = 'Active'), a.`timestamp`)) AS `lastActivity` " +
+	"FROM `accounts` a WHERE EXISTS (SELECT 1 FROM `accounts_meta` m WHERE m.`accountNumber` = a.`accountNumber`)"
+
+// getInactiveAccounts lists the active customer accounts with no activity since before
+func getInactiveAccounts(before time.Time) (inactive []Inactivity, err error) {
+	rows, err := Config.Db.Query("SELECT `accountNumber`, `lastActivity` FROM ("+lastActivityQuery+") activity WHERE `status` = ? AND `lastActivity` < ? ORDER BY `lastActivity`",
+		lifecycle.Active, before.Format(SQL_TIME_LAYOUT))
+	if err != nil {
+		return nil, errors.New("dormancy.getInactiveAccounts: " + err.Error())
+	}
+	defer rows.Close()
+
+	inactive = make([]Inactivity, 0)
+	for rows.Next() {
+		var account Inactivity
+		var lastActivity string
+		if err := rows.Scan(&account.AccountNumber, &lastActivity); err != nil {
+			return nil, errors.New("dormancy.getInactiveAccounts: " + err.Error())
+		}
+		if account.LastActivity, err = time.Parse(SQL_TIME_LAYOUT, lastActivity); err != nil {
+			return nil, errors.New("dormancy.getInactiveAccounts: " + err.Error())
+		}
+		inactive = append(inactive, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("dormancy.getInactiveAccounts: " + err.Error())
+	}
+
+	return inactive, nil
+}
+
+// getDormantAccounts lists the dormant accounts with when they went dormant
+func getDormantAccounts() (dormant []DormantAccount, err error) {
+	rows, err := Config.Db.Query("SELECT activity.`accountNumber`, activity.`accountHolderName`, activity.`currencyCode`, activity.`accountBalance`, activity.`lastActivity`, "+
+		"(SELECT MAX(c.`timestamp`) FROM `account_status_changes` c WHERE c.`accountNumber` = activity.`accountNumber` AND c.`toStatus` = ?) "+
+		"FROM ("+lastActivityQuery+") activity WHERE activity.`status` = ? ORDER BY activity.`currencyCode`, activity.`accountNumber`",
+		lifecycle.Dormant, lifecycle.Dormant)
+	if err != nil {
+		return nil, errors.New("dormancy.getDormantAccounts: " + err.Error())
+	}
+	defer rows.Close()
+
+	dormant = make([]DormantAccount, 0)
+	for rows.Next() {
+		var account DormantAccount
+		var lastActivity string
+		var dormantSince sql.NullString
+		if err := rows.Scan(&account.AccountNumber, &account.AccountHolderName, &account.CurrencyCode, &account.Balance, &lastActivity, &dormantSince); err != nil {
+			return nil, errors.New("dormancy.getDormantAccounts: " + err.Error())
+		}
+		if account.LastActivity, err = time.Parse(SQL_TIME_LAYOUT, lastActivity); err != nil {
+			return nil, errors.New("dormancy.getDormantAccounts: " + err.Error())
+		}
+		if dormantSince.Valid {
+			if account.DormantSince, err = time.Parse(SQL_TIME_LAYOUT, dormantSince.String); err != nil {
+				return nil, errors.New("dormancy.getDormantAccounts: " + err.Error())
+			}
+		}
+		dormant = append(dormant, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("dormancy.getDormantAccounts: " + err.Error())
+	}
+
+	return dormant, nil
+}
+
+func saveNotice(notice Notice) (id int64, err error) {
+	insertStatement := "INSERT INTO dormancy_notices (`accountNumber`, `lastActivity`, `dormantFrom`, `notifiedTo`) VALUES(?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("dormancy.saveNotice: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(notice.AccountNumber, notice.LastActivity.Format(SQL_TIME_LAYOUT), notice.DormantFrom.Format(SQL_TIME_LAYOUT), notice.NotifiedTo)
+	if err != nil {
+		return 0, errors.New("dormancy.saveNotice: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("dormancy.saveNotice: " + err.Error())
+	}
+
+	return
+}
+
+// getNotice finds the notice sent for an account's current spell of inactivity
+func getNotice(accountNumber string, lastActivity time.Time) (notice Notice, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+noticeColumns+" FROM `dormancy_notices` WHERE `accountNumber` = ? AND `lastActivity` = ?", accountNumber, lastActivity.Format(SQL_TIME_LAYOUT))
+	if err != nil {
+		return Notice{}, false, errors.New("dormancy.getNotice: " + err.Error())
+	}
+	defer rows.Close()
+
+	notices, err := scanNotices(rows)
+	if err != nil {
+		return Notice{}, false, errors.New("dormancy.getNotice: " + err.Error())
+	}
+	if len(notices) == 0 {
+		return Notice{}, false, nil
+	}
+
+	return notices[0], true, nil
+}
+
+func getNotices(accountNumber string) (notices []Notice, err error) {
+	rows, err := Config.Db.Query("SELECT "+noticeColumns+" FROM `dormancy_notices` WHERE `accountNumber` = ? ORDER BY `id` DESC", accountNumber)
+	if err != nil {
+		return nil, errors.New("dormancy.getNotices: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanNotices(rows)
+}
+
+func scanNotices(rows *sql.Rows) (notices []Notice, err error) {
+	notices = make([]Notice, 0)
+	for rows.Next() {
+		var notice Notice
+		var lastActivity, dormantFrom, timestamp string
+		if err := rows.Scan(&notice.ID, &notice.AccountNumber, &lastActivity, &dormantFrom, &notice.NotifiedTo, &timestamp); err != nil {
+			return nil, errors.New("dormancy.scanNotices: " + err.Error())
+		}
+		if notice.LastActivity, err = time.Parse(SQL_TIME_LAYOUT, lastActivity); err != nil {
+			return nil, errors.New("dormancy.scanNotices: " + err.Error())
+		}
+		if notice.DormantFrom, err = time.Parse(SQL_TIME_LAYOUT, dormantFrom); err != nil {
+			return nil, errors.New("dormancy.scanNotices: " + err.Error())
+		}
+		if notice.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("dormancy.scanNotices: " + err.Error())
+		}
+		notices = append(notices, notice)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("dormancy.scanNotices: " + err.Error())
+	}
+
+	return notices, nil
+}
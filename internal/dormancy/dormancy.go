@@ -0,0 +1,272 @@
+package dormancy
+
+/*
+Dormancy
+
+A customer account with no customer-initiated activity for the dormancy period
+(DORMANCY_MONTHS, 12 by default) is moved to Dormant. Customer-initiated activity is:
+
+	- a payment the account sent, other than one the system posted (fees, refunds, sweeps)
+	- a deposit into the account (pain 1000)
+
+Credits from others, fees and interest don't keep an account active. Accounts without
+holder details (fees, suspense and position accounts) are never made dormant.
+
+The holder is sent a notice DORMANCY_NOTICE_PERIOD before the account goes dormant. The
+account only goes dormant once the notice period has passed since the notice, so an account
+that was inactive before this job ran still gets its full notice.
+
+A dormant account takes credits but no debits. It goes back to Active only through
+Reactivate, which re-verifies the holder's identity against their KYC details.
+
+Dormant balances are reported by Report for unclaimed funds returns.
+*/
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/accounts"
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/ebitezion/backend-framework/internal/notifications"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DORMANCY_MONTHS_ENV     = "DORMANCY_MONTHS"
+	DEFAULT_DORMANCY_MONTHS = 12
+	DORMANCY_NOTICE_PERIOD  = 30 * 24 * time.Hour
+
+	// The actor recorded on changes made by the dormancy job
+	SYSTEM_ACTOR = "system"
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// notify sends the dormancy notice to the holder
+var notify = func(to string, subject string, body string) error {
+	return notifications.NewNotificationService().SendEmail(to, subject, body)
+}
+
+// Inactivity is an active customer account's last customer-initiated activity
+type Inactivity struct {
+	AccountNumber string
+	LastActivity  time.Time
+}
+
+// Notice is the warning sent to a holder before their account goes dormant
+type Notice struct {
+	ID            int64     `json:"id"`
+	AccountNumber string    `json:"accountNumber"`
+	LastActivity  time.Time `json:"lastActivity"`
+	DormantFrom   time.Time `json:"dormantFrom"`
+	NotifiedTo    string    `json:"notifiedTo"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Run is what one dormancy check did
+type Run struct {
+	Notified    int64 `json:"notified"`
+	MadeDormant int64 `json:"madeDormant"`
+}
+
+// DormantAccount is a dormant account's balance for unclaimed funds reporting. Balance is the
+// ledger balance, the customer's own funds without any overdraft limit.
+type DormantAccount struct {
+	AccountNumber     string          `json:"accountNumber"`
+	AccountHolderName string          `json:"accountHolderName"`
+	CurrencyCode      string          `json:"currencyCode"`
+	Balance           decimal.Decimal `json:"balance"`
+	LastActivity      time.Time       `json:"lastActivity"`
+	DormantSince      time.Time       `json:"dormantSince"`
+}
+
+// DormantReport lists the dormant accounts with their total balance per currency
+type DormantReport struct {
+	Accounts []DormantAccount           `json:"accounts"`
+	Totals   map[string]decimal.Decimal `json:"totals"`
+	AsAt     time.Time                  `json:"asAt"`
+}
+
+// Period is the number of months without activity before an account goes dormant
+func Period() int {
+	months, err := strconv.Atoi(strings.TrimSpace(os.Getenv(DORMANCY_MONTHS_ENV)))
+	if err != nil || months < 1 {
+		return DEFAULT_DORMANCY_MONTHS
+	}
+	return months
+}
+
+// dormantFrom is when an account last active at lastActivity goes dormant
+func dormantFrom(lastActivity time.Time, months int) time.Time {
+	return lastActivity.AddDate(0, months, 0)
+}
+
+// dueDate is when an account goes dormant given when its holder was notified.
+// It is never before the end of the notice period.
+func dueDate(lastActivity time.Time, notifiedAt time.Time, months int) time.Time {
+	due := dormantFrom(lastActivity, months)
+	if noticeEnds := notifiedAt.Add(DORMANCY_NOTICE_PERIOD); noticeEnds.After(due) {
+		return noticeEnds
+	}
+	return due
+}
+
+// CheckDormancy notifies the holders of accounts about to go dormant and moves accounts
+// whose notice has run out to Dormant. Accounts that fail are retried on the next run.
+func CheckDormancy() (run Run, err error) {
+	now := time.Now()
+	months := Period()
+
+	// Accounts inactive long enough to be within the notice period of going dormant
+	inactive, err := getInactiveAccounts(now.Add(DORMANCY_NOTICE_PERIOD).AddDate(0, -months, 0))
+	if err != nil {
+		return Run{}, errors.New("dormancy.CheckDormancy: " + err.Error())
+	}
+
+	var failures []string
+	for _, account := range inactive {
+		notice, found, err := getNotice(account.AccountNumber, account.LastActivity)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		if !found {
+			err = sendNotice(account, months)
+			if err != nil {
+				failures = append(failures, err.Error())
+				continue
+			}
+			run.Notified++
+			continue
+		}
+
+		if now.Before(dueDate(account.LastActivity, notice.Timestamp, months)) {
+			continue
+		}
+
+		_, err = lifecycle.Transition(account.AccountNumber, lifecycle.Dormant, "No customer activity since "+account.LastActivity.Format("2006-01-02"), SYSTEM_ACTOR)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		run.MadeDormant++
+	}
+	if len(failures) > 0 {
+		return run, errors.New("dormancy.CheckDormancy: " + strings.Join(failures, "; "))
+	}
+
+	return run, nil
+}
+
+// sendNotice warns the holder and records the notice. Holders with no email address on
+// file are recorded as notified so their account can still go dormant.
+func sendNotice(account Inactivity, months int) error {
+	holder, err := accounts.FetchAccountMeta(account.AccountNumber)
+	if err != nil {
+		return errors.New("dormancy.sendNotice: " + err.Error())
+	}
+
+	notice := Notice{
+		AccountNumber: account.AccountNumber,
+		LastActivity:  account.LastActivity,
+		DormantFrom:   dueDate(account.LastActivity, time.Now(), months),
+		NotifiedTo:    strings.TrimSpace(holder.EmailAddress),
+	}
+
+	if notice.NotifiedTo != "" {
+		body := "Dear " + holder.GivenName + ",\r\n\r\n" +
+			"There has been no activity on your account " + account.AccountNumber + " since " + account.LastActivity.Format("2 January 2006") + ". " +
+			"Unless you use it before " + notice.DormantFrom.Format("2 January 2006") + " it will become dormant. " +
+			"You will still receive payments into a dormant account, but you won't be able to make payments from it until you reactivate it and verify your identity."
+		err = notify(notice.NotifiedTo, "Your account is about to become dormant", body)
+		if err != nil {
+			return errors.New("dormancy.sendNotice: " + err.Error())
+		}
+	}
+
+	_, err = saveNotice(notice)
+	if err != nil {
+		return errors.New("dormancy.sendNotice: Holder notified but the notice was not recorded. " + err.Error())
+	}
+
+	return nil
+}
+
+// Reactivate moves a dormant account back to Active once the holder's identity has been
+// re-verified against the identification number and date of birth on their KYC details
+func Reactivate(accountNumber string, identificationNumber string, dateOfBirth string, reason string, actor string) (lifecycle.StatusChange, error) {
+	status, err := lifecycle.Status(accountNumber)
+	if err != nil {
+		return lifecycle.StatusChange{}, errors.New("dormancy.Reactivate: " + err.Error())
+	}
+	if status != lifecycle.Dormant {
+		return lifecycle.StatusChange{}, errors.New("dormancy.Reactivate: Account is " + status + ", not " + lifecycle.Dormant)
+	}
+
+	holder, err := accounts.FetchAccountMeta(accountNumber)
+	if err != nil {
+		return lifecycle.StatusChange{}, errors.New("dormancy.Reactivate: " + err.Error())
+	}
+	if !identityMatches(*holder, identificationNumber, dateOfBirth) {
+		return lifecycle.StatusChange{}, errors.New("dormancy.Reactivate: Identity could not be verified against the holder's KYC details")
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "Reactivated"
+	}
+	change, err := lifecycle.Transition(accountNumber, lifecycle.Active, reason+" (KYC re-verified)", actor)
+	if err != nil {
+		return lifecycle.StatusChange{}, errors.New("dormancy.Reactivate: " + err.Error())
+	}
+
+	return change, nil
+}
+
+func identityMatches(holder accounts.AccountHolderDetails, identificationNumber string, dateOfBirth string) bool {
+	identificationNumber = strings.TrimSpace(identificationNumber)
+	dateOfBirth = strings.TrimSpace(dateOfBirth)
+	if identificationNumber == "" || dateOfBirth == "" {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(holder.IdentificationNumber), identificationNumber) &&
+		strings.TrimSpace(holder.DateOfBirth) == dateOfBirth
+}
+
+// Report lists dormant accounts and their balances for unclaimed funds reporting
+func Report() (DormantReport, error) {
+	dormant, err := getDormantAccounts()
+	if err != nil {
+		return DormantReport{}, errors.New("dormancy.Report: " + err.Error())
+	}
+
+	report := DormantReport{
+		Accounts: dormant,
+		Totals:   make(map[string]decimal.Decimal),
+		AsAt:     time.Now(),
+	}
+	for _, account := range dormant {
+		report.Totals[account.CurrencyCode] = report.Totals[account.CurrencyCode].Add(account.Balance)
+	}
+
+	return report, nil
+}
+
+// Notices lists the dormancy notices sent for an account, most recent first
+func Notices(accountNumber string) ([]Notice, error) {
+	notices, err := getNotices(accountNumber)
+	if err != nil {
+		return nil, errors.New("dormancy.Notices: " + err.Error())
+	}
+	return notices, nil
+}
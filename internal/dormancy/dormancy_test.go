@@ -0,0 +1,63 @@
+package dormancy
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/accounts"
+)
+
+func TestPeriod(t *testing.T) {
+	cases := map[string]int{"": DEFAULT_DORMANCY_MONTHS, "6": 6, "0": DEFAULT_DORMANCY_MONTHS, "-3": DEFAULT_DORMANCY_MONTHS, "twelve": DEFAULT_DORMANCY_MONTHS}
+	defer os.Setenv(DORMANCY_MONTHS_ENV, os.Getenv(DORMANCY_MONTHS_ENV))
+
+	for value, want := range cases {
+		os.Setenv(DORMANCY_MONTHS_ENV, value)
+		if got := Period(); got != want {
+			t.Errorf("Period does not pass. Looking for %v, got %v for %q", want, got, value)
+		}
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	lastActivity := time.Date(2023, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	// Notified well ahead, the account goes dormant when the period runs out
+	notifiedAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	if got := dueDate(lastActivity, notifiedAt, 12); !got.Equal(want) {
+		t.Errorf("dueDate does not pass. Looking for %v, got %v", want, got)
+	}
+
+	// Notified late, the holder still gets the full notice period
+	notifiedAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	want = notifiedAt.Add(DORMANCY_NOTICE_PERIOD)
+	if got := dueDate(lastActivity, notifiedAt, 12); !got.Equal(want) {
+		t.Errorf("dueDate does not pass. Looking for %v, got %v", want, got)
+	}
+}
+
+func TestIdentityMatches(t *testing.T) {
+	holder := accounts.AccountHolderDetails{IdentificationNumber: "A1234567", DateOfBirth: "1990-04-01"}
+
+	if !identityMatches(holder, " a1234567 ", "1990-04-01") {
+		t.Errorf("identityMatches does not pass. Looking for %v, got %v", true, false)
+	}
+
+	refused := [][]string{
+		{"A1234568", "1990-04-01"},
+		{"A1234567", "1990-04-02"},
+		{"", ""},
+		{"A1234567", ""},
+	}
+	for _, c := range refused {
+		if identityMatches(holder, c[0], c[1]) {
+			t.Errorf("identityMatches does not pass. Looking for %v, got %v for %v", false, true, c)
+		}
+	}
+
+	if identityMatches(accounts.AccountHolderDetails{}, "", "") {
+		t.Errorf("identityMatches does not pass. Looking for %v, got %v for empty KYC details", false, true)
+	}
+}
@@ -0,0 +1,161 @@
+package fx
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/shopspring/decimal"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+func saveQuote(quote Quote) (id int64, err error) {
+	insertStatement := "INSERT INTO fx_quotes (`reference`, `sourceAccountNumber`, `targetAccountNumber`, `sellCurrency`, `buyCurrency`, `sellAmount`, `buyAmount`, `midRate`, `rate`, `spread`, `spreadRevenue`, `rateSnapshotId`, `status`, `initiator`, `expiresAt`, `timestamp`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("fx.saveQuote: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(quote.Reference, quote.SourceAccountNumber, quote.TargetAccountNumber, quote.SellCurrency, quote.BuyCurrency, quote.SellAmount, quote.BuyAmount, quote.MidRate, quote.Rate, quote.Spread, quote.SpreadRevenue, quote.RateSnapshotID, quote.Status, quote.Initiator, quote.ExpiresAt, quote.Timestamp)
+	if err != nil {
+		return 0, errors.New("fx.saveQuote: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("fx.saveQuote: " + err.Error())
+	}
+
+	return
+}
+
+const quoteColumns = "`id`, `reference`, `sourceAccountNumber`, `targetAccountNumber`, `sellCurrency`, `buyCurrency`, `sellAmount`, `buyAmount`, `midRate`, `rate`, `spread`, `spreadRevenue`, IFNULL(`rateSnapshotId`, 0), `status`, `initiator`, `expiresAt`, `timestamp`"
+
+func getQuoteByReference(reference string) (quote Quote, err error) {
+	rows, err := Config.Db.Query("SELECT "+quoteColumns+" FROM `fx_quotes` WHERE `reference` = ?", reference)
+	if err != nil {
+		return Quote{}, errors.New("fx.getQuoteByReference: " + err.Error())
+	}
+	defer rows.Close()
+
+	quotes, err := scanQuotes(rows)
+	if err != nil {
+		return Quote{}, errors.New("fx.getQuoteByReference: " + err.Error())
+	}
+	if len(quotes) == 0 {
+		return Quote{}, errors.New("fx.getQuoteByReference: Quote not found")
+	}
+
+	return quotes[0], nil
+}
+
+// getQuotes returns the quotes in a status created between start and end
+func getQuotes(status string, start time.Time, end time.Time) (quotes []Quote, err error) {
+	rows, err := Config.Db.Query("SELECT "+quoteColumns+" FROM `fx_quotes` WHERE `status` = ? AND `timestamp` >= ? AND `timestamp` < ? ORDER BY `timestamp` DESC", status, start, end)
+	if err != nil {
+		return nil, errors.New("fx.getQuotes: " + err.Error())
+	}
+	defer rows.Close()
+
+	quotes, err = scanQuotes(rows)
+	if err != nil {
+		return nil, errors.New("fx.getQuotes: " + err.Error())
+	}
+
+	return quotes, nil
+}
+
+func scanQuotes(rows *sql.Rows) (quotes []Quote, err error) {
+	quotes = make([]Quote, 0)
+	for rows.Next() {
+		var quote Quote
+		var expiresAt, timestamp string
+		err := rows.Scan(&quote.ID, &quote.Reference, &quote.SourceAccountNumber, &quote.TargetAccountNumber, &quote.SellCurrency, &quote.BuyCurrency, &quote.SellAmount, &quote.BuyAmount, &quote.MidRate, &quote.Rate, &quote.Spread, &quote.SpreadRevenue, &quote.RateSnapshotID, &quote.Status, &quote.Initiator, &expiresAt, &timestamp)
+		if err != nil {
+			return nil, errors.New("fx.scanQuotes: " + err.Error())
+		}
+		if quote.ExpiresAt, err = time.Parse(SQL_TIME_LAYOUT, expiresAt); err != nil {
+			return nil, errors.New("fx.scanQuotes: " + err.Error())
+		}
+		if quote.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("fx.scanQuotes: " + err.Error())
+		}
+		quotes = append(quotes, quote)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("fx.scanQuotes: " + err.Error())
+	}
+
+	return quotes, nil
+}
+
+// getPairMargin returns treasury's margin for the pair, entered in either direction
+func getPairMargin(sellCurrency string, buyCurrency string) (margin decimal.NullDecimal, err error) {
+	row := Config.Db.QueryRow("SELECT `margin` FROM `fx_manual_rates` WHERE (`baseCurrency` = ? AND `quoteCurrency` = ?) OR (`baseCurrency` = ? AND `quoteCurrency` = ?) ORDER BY `baseCurrency` = ? DESC LIMIT 1", sellCurrency, buyCurrency, buyCurrency, sellCurrency, sellCurrency)
+	err = row.Scan(&margin)
+	if err == sql.ErrNoRows {
+		return decimal.NullDecimal{}, nil
+	}
+	if err != nil {
+		return decimal.NullDecimal{}, errors.New("fx.getPairMargin: " + err.Error())
+	}
+
+	return margin, nil
+}
+
+// acceptQuote moves an open, unexpired quote to accepted. It fails if another request got there first.
+func acceptQuote(reference string) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE fx_quotes SET `status` = ?, `acceptedAt` = ?, `updated_at` = ? WHERE `reference` = ? AND `status` = ? AND `expiresAt` > ?")
+	if err != nil {
+		return errors.New("fx.acceptQuote: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	now := time.Now().UTC()
+	res, err := stmtUpd.Exec(QuoteAccepted, now, now, reference, QuoteOpen, now)
+	if err != nil {
+		return errors.New("fx.acceptQuote: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("fx.acceptQuote: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("fx.acceptQuote: Quote is no longer open")
+	}
+
+	return nil
+}
+
+func updateQuoteStatus(reference string, from string, to string) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE fx_quotes SET `status` = ?, `updated_at` = ? WHERE `reference` = ? AND `status` = ?")
+	if err != nil {
+		return errors.New("fx.updateQuoteStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, time.Now().UTC(), reference, from)
+	if err != nil {
+		return errors.New("fx.updateQuoteStatus: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("fx.updateQuoteStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("fx.updateQuoteStatus: Quote is no longer " + from)
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package fx
+
+/*
+Cross-currency transfers
+
+A customer asks for a quote to move money between two of their own accounts held in
+different currencies. The quote locks the customer rate (the mid rate less the spread)
+and the amount the target account will receive until it expires. Accepting the quote
+posts two legs through the FX position accounts, one per currency:
+
+	source account  -> position account (sell currency)  sell amount
+	position account (buy currency) -> target account    buy amount
+
+The sell leg is a customer credit transfer (PAIN 1), so it carries the transfer fee and the
+source account's checks. The buy leg is an internal posting. When the buy leg fails the
+sell amount is handed back; a quote whose hand back also fails is left in reconcile with
+only the sell leg posted, for operations to settle by hand.
+
+The difference between the mid rate and the customer rate is the spread revenue, kept in
+the buy currency position. The spread is treasury's margin for the pair when one has been
+set, FX_SPREAD otherwise. Position accounts are configured per currency with
+FX_POSITION_ACCOUNT_NUMBER_<CODE>, e.g. FX_POSITION_ACCOUNT_NUMBER_GBP.
+
+open -> accepted | expired | failed | reconcile
+*/
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/converter"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	QuoteOpen      = "open"
+	QuoteAccepted  = "accepted"
+	QuoteExpired   = "expired"
+	QuoteFailed    = "failed"
+	QuoteReconcile = "reconcile"
+
+	FX_QUOTE_TTL            = 60 * time.Second
+	FX_SPREAD               = 0.015 // 1.5%
+	FX_RATE_PLACES          = 8
+	FX_REFERENCE_PREFIX     = "FX"
+	FX_REFERENCE_DIGITS     = 12
+	fxPositionAccountPrefix = "FX_POSITION_ACCOUNT_NUMBER_"
+)
+
+type Quote struct {
+	ID                  int64           `json:"id"`
+	Reference           string          `json:"reference"`
+	SourceAccountNumber string          `json:"sourceAccountNumber"`
+	TargetAccountNumber string          `json:"targetAccountNumber"`
+	SellCurrency        string          `json:"sellCurrency"`
+	BuyCurrency         string          `json:"buyCurrency"`
+	SellAmount          decimal.Decimal `json:"sellAmount"`
+	BuyAmount           decimal.Decimal `json:"buyAmount"`
+	MidRate             decimal.Decimal `json:"midRate"`
+	Rate                decimal.Decimal `json:"rate"`
+	Spread              decimal.Decimal `json:"spread"`
+	SpreadRevenue       decimal.Decimal `json:"spreadRevenue"`
+	RateSnapshotID      int64           `json:"rateSnapshotId"`
+	Status              string          `json:"status"`
+	Initiator           string          `json:"-"`
+	ExpiresAt           time.Time       `json:"expiresAt"`
+	Timestamp           time.Time       `json:"timestamp"`
+}
+
+// RequestQuote prices a transfer of amount (in the source account's currency) from source to target.
+// The token user must hold both accounts.
+func RequestQuote(token string, source string, target string, amount string) (quote Quote, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	for _, accountNumber := range []string{source, target} {
+		holder, err := payments.IsAccountHolder(tokenUser, accountNumber)
+		if err != nil {
+			return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+		}
+		if !holder {
+			return Quote{}, errors.New("fx.RequestQuote: Account " + accountNumber + " not valid")
+		}
+	}
+
+	sellCurrency, err := payments.AccountCurrency(source)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	buyCurrency, err := payments.AccountCurrency(target)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	if sellCurrency == buyCurrency {
+		return Quote{}, errors.New("fx.RequestQuote: Both accounts are held in " + sellCurrency + ", use a transfer instead")
+	}
+
+	sell, err := currency.Get(sellCurrency)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	buy, err := currency.Get(buyCurrency)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	sellAmount, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: Could not convert amount to decimal. " + err.Error())
+	}
+	if !sellAmount.IsPositive() {
+		return Quote{}, errors.New("fx.RequestQuote: Amount must be greater than zero")
+	}
+	if !sell.ValidAmount(sellAmount) {
+		return Quote{}, errors.New("fx.RequestQuote: Amount has more decimal places than " + sell.Code + " allows")
+	}
+
+	conversion, err := converter.Convert(sell.Code, buy.Code, sellAmount)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+
+	spread, err := pairSpread(sell.Code, buy.Code)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+
+	quote, err = priceQuote(sell, buy, sellAmount, conversion.Rate, spread)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	quote.RateSnapshotID = conversion.SnapshotID
+	quote.Reference, err = newReference()
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+	quote.SourceAccountNumber = source
+	quote.TargetAccountNumber = target
+	quote.Status = QuoteOpen
+	quote.Initiator = tokenUser
+	quote.ExpiresAt = time.Now().UTC().Add(FX_QUOTE_TTL).Truncate(time.Second)
+	quote.Timestamp = time.Now().UTC().Truncate(time.Second)
+
+	quote.ID, err = saveQuote(quote)
+	if err != nil {
+		return Quote{}, errors.New("fx.RequestQuote: " + err.Error())
+	}
+
+	return quote, nil
+}
+
+// AcceptQuote executes an open quote at its locked rate. The token user must be the user who requested it.
+func AcceptQuote(token string, reference string) (quote Quote, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+
+	quote, err = getQuoteByReference(reference)
+	if err != nil {
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+	if quote.Initiator != tokenUser {
+		return Quote{}, errors.New("fx.AcceptQuote: Quote not found")
+	}
+	if quote.Status != QuoteOpen {
+		return Quote{}, errors.New("fx.AcceptQuote: Quote is " + quote.Status)
+	}
+	if time.Now().UTC().After(quote.ExpiresAt) {
+		_ = updateQuoteStatus(quote.Reference, QuoteOpen, QuoteExpired)
+		return Quote{}, errors.New("fx.AcceptQuote: Quote has expired, request a new quote")
+	}
+	err = payments.RequireSingleSignature(quote.SourceAccountNumber)
+	if err != nil {
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+
+	sellPosition, err := positionAccount(quote.SellCurrency)
+	if err != nil {
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+	buyPosition, err := positionAccount(quote.BuyCurrency)
+	if err != nil {
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+
+	// Claim the quote first so it can only be executed once
+	err = acceptQuote(quote.Reference)
+	if err != nil {
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+
+	narration := "FX " + quote.Reference + " " + quote.SellCurrency + "/" + quote.BuyCurrency + " @ " + quote.Rate.String()
+	_, err = payments.ProcessPAIN([]string{token, "pain", "1", quote.SourceAccountNumber + "@", sellPosition + "@", quote.SellAmount.String(), narration, quote.SourceAccountNumber})
+	if err != nil {
+		if statusErr := updateQuoteStatus(quote.Reference, QuoteAccepted, QuoteFailed); statusErr != nil {
+			return Quote{}, errors.New("fx.AcceptQuote: " + err.Error() + ". " + statusErr.Error())
+		}
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+
+	_, err = payments.ProcessPAIN([]string{token, "pain", "1001", buyPosition + "@", quote.TargetAccountNumber + "@", quote.BuyAmount.String(), narration, "system"})
+	if err != nil {
+		// The customer has sold but not bought, hand the sell leg back
+		_, refundErr := payments.ProcessPAIN([]string{token, "pain", "1001", sellPosition + "@", quote.SourceAccountNumber + "@", quote.SellAmount.String(), narration + " reversal", "system"})
+		if refundErr != nil {
+			if statusErr := updateQuoteStatus(quote.Reference, QuoteAccepted, QuoteReconcile); statusErr != nil {
+				return Quote{}, errors.New("fx.AcceptQuote: " + err.Error() + ". " + refundErr.Error() + ". " + statusErr.Error())
+			}
+			return Quote{}, errors.New("fx.AcceptQuote: " + err.Error() + ". " + refundErr.Error() + ". Quote " + quote.Reference + " needs reconciling")
+		}
+		if statusErr := updateQuoteStatus(quote.Reference, QuoteAccepted, QuoteFailed); statusErr != nil {
+			return Quote{}, errors.New("fx.AcceptQuote: " + err.Error() + ". " + statusErr.Error())
+		}
+		return Quote{}, errors.New("fx.AcceptQuote: " + err.Error())
+	}
+
+	quote.Status = QuoteAccepted
+	return quote, nil
+}
+
+// AcceptedQuotes returns the FX transactions executed between start and end, with the
+// rate, spread and rate snapshot each one used
+func AcceptedQuotes(start time.Time, end time.Time) (quotes []Quote, err error) {
+	quotes, err = getQuotes(QuoteAccepted, start, end)
+	if err != nil {
+		return nil, errors.New("fx.AcceptedQuotes: " + err.Error())
+	}
+
+	return quotes, nil
+}
+
+// pairSpread is treasury's margin for the pair, or FX_SPREAD when none has been set
+func pairSpread(sellCurrency string, buyCurrency string) (decimal.Decimal, error) {
+	margin, err := getPairMargin(sellCurrency, buyCurrency)
+	if err != nil {
+		return decimal.Zero, errors.New("fx.pairSpread: " + err.Error())
+	}
+	if margin.Valid {
+		return margin.Decimal, nil
+	}
+	return decimal.NewFromFloat(FX_SPREAD), nil
+}
+
+// priceQuote applies the spread to the mid rate and works out what the customer receives.
+// The buy amount is rounded down to the buy currency's minor units so the bank never pays
+// out more than the customer rate allows.
+func priceQuote(sell currency.Currency, buy currency.Currency, sellAmount decimal.Decimal, midRate decimal.Decimal, spread decimal.Decimal) (Quote, error) {
+	if !midRate.IsPositive() {
+		return Quote{}, errors.New("fx.priceQuote: Invalid rate " + midRate.String())
+	}
+	if spread.IsNegative() || spread.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return Quote{}, errors.New("fx.priceQuote: Invalid spread " + spread.String())
+	}
+
+	midRate = midRate.Round(FX_RATE_PLACES)
+	rate := midRate.Mul(decimal.NewFromInt(1).Sub(spread)).Round(FX_RATE_PLACES)
+	buyAmount := sellAmount.Mul(rate).Truncate(buy.MinorUnits)
+	if !buyAmount.IsPositive() {
+		return Quote{}, errors.New("fx.priceQuote: Amount is too small to convert")
+	}
+	spreadRevenue := buy.Round(sellAmount.Mul(midRate)).Sub(buyAmount)
+
+	return Quote{
+		SellCurrency:  sell.Code,
+		BuyCurrency:   buy.Code,
+		SellAmount:    sellAmount,
+		BuyAmount:     buyAmount,
+		MidRate:       midRate,
+		Rate:          rate,
+		Spread:        spread,
+		SpreadRevenue: spreadRevenue,
+	}, nil
+}
+
+func positionAccount(currencyCode string) (string, error) {
+	env := fxPositionAccountPrefix + currencyCode
+	account := strings.TrimSpace(os.Getenv(env))
+	if account == "" {
+		return "", errors.New("fx.positionAccount: " + env + " is not configured")
+	}
+	return account, nil
+}
+
+func newReference() (string, error) {
+	reference := FX_REFERENCE_PREFIX
+	for i := 0; i < FX_REFERENCE_DIGITS; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", errors.New("fx.newReference: " + err.Error())
+		}
+		reference += n.String()
+	}
+	return reference, nil
+}
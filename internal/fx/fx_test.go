@@ -0,0 +1,63 @@
+package fx
+
+import (
+	"testing"
+
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/shopspring/decimal"
+)
+
+func TestPriceQuote(t *testing.T) {
+	ngn, _ := currency.Get("NGN")
+	gbp, _ := currency.Get("GBP")
+
+	// 100,000 NGN at a mid rate of 0.0005 GBP with a 1% spread
+	quote, err := priceQuote(ngn, gbp, decimal.NewFromInt(100000), decimal.RequireFromString("0.0005"), decimal.RequireFromString("0.01"))
+	if err != nil {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", nil, err)
+	}
+	if !quote.Rate.Equal(decimal.RequireFromString("0.000495")) {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "0.000495", quote.Rate)
+	}
+	if !quote.BuyAmount.Equal(decimal.RequireFromString("49.5")) {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "49.5", quote.BuyAmount)
+	}
+	if !quote.SpreadRevenue.Equal(decimal.RequireFromString("0.5")) {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "0.5", quote.SpreadRevenue)
+	}
+	if quote.SellCurrency != "NGN" || quote.BuyCurrency != "GBP" {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "NGN/GBP", quote.SellCurrency+"/"+quote.BuyCurrency)
+	}
+}
+
+func TestPriceQuoteRoundsDown(t *testing.T) {
+	gbp, _ := currency.Get("GBP")
+	ngn, _ := currency.Get("NGN")
+
+	// 10 GBP at 1999.999 with a 1.5% spread is 19699.99015, the customer receives 19699.99
+	quote, err := priceQuote(gbp, ngn, decimal.NewFromInt(10), decimal.RequireFromString("1999.999"), decimal.RequireFromString("0.015"))
+	if err != nil {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", nil, err)
+	}
+	if !quote.BuyAmount.Equal(decimal.RequireFromString("19699.99")) {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "19699.99", quote.BuyAmount)
+	}
+	if !quote.SpreadRevenue.Equal(decimal.RequireFromString("300")) {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "300", quote.SpreadRevenue)
+	}
+}
+
+func TestPriceQuoteInvalid(t *testing.T) {
+	ngn, _ := currency.Get("NGN")
+	gbp, _ := currency.Get("GBP")
+
+	_, err := priceQuote(ngn, gbp, decimal.NewFromInt(100), decimal.Zero, decimal.RequireFromString("0.01"))
+	if err == nil {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "error", err)
+	}
+	// Too small to buy a penny
+	_, err = priceQuote(ngn, gbp, decimal.NewFromInt(1), decimal.RequireFromString("0.0005"), decimal.RequireFromString("0.01"))
+	if err == nil {
+		t.Errorf("priceQuote does not pass. Looking for %v, got %v", "error", err)
+	}
+}
@@ -0,0 +1,259 @@
+package iban
+
+/*
+International Bank Account Numbers
+
+An IBAN is a country code, two check digits and the country's BBAN (basic bank account
+number). Each country fixes the BBAN's length and layout, written in the SWIFT registry
+notation: 4!a6!n8!n is 4 letters, 6 digits then 8 digits (c allows letters and digits).
+
+The check digits are 98 less the remainder, mod 97, of the BBAN followed by the country
+code and 00, with letters counted as 10 for A to 35 for Z. A valid IBAN rearranged the
+same way leaves a remainder of 1.
+
+Our own accounts are GB IBANs built from IBAN_BANK_CODE (the 4 letter bank code, the
+first four letters of our BIC), the sort code in UK_SORT_CODE and the 8 digit account
+number. BANK_BIC is our BIC.
+*/
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	BANK_CODE_ENV = "IBAN_BANK_CODE"
+	BIC_ENV       = "BANK_BIC"
+	SORT_CODE_ENV = "UK_SORT_CODE"
+)
+
+// Structure is a country's IBAN length and BBAN layout
+type Structure struct {
+	Length int
+	BBAN   string
+}
+
+// Structures from the SWIFT IBAN registry
+var Structures = map[string]Structure{
+	"AD": {24, "4!n4!n12!c"},
+	"AE": {23, "3!n16!n"},
+	"AT": {20, "5!n11!n"},
+	"BE": {16, "3!n7!n2!n"},
+	"BG": {22, "4!a4!n2!n8!c"},
+	"CH": {21, "5!n12!c"},
+	"CY": {28, "3!n5!n16!c"},
+	"CZ": {24, "4!n6!n10!n"},
+	"DE": {22, "8!n10!n"},
+	"DK": {18, "4!n9!n1!n"},
+	"EE": {20, "2!n2!n11!n1!n"},
+	"ES": {24, "4!n4!n1!n1!n10!n"},
+	"FI": {18, "3!n11!n"},
+	"FR": {27, "5!n5!n11!c2!n"},
+	"GB": {22, "4!a6!n8!n"},
+	"GI": {23, "4!a15!c"},
+	"GR": {27, "3!n4!n16!c"},
+	"HR": {21, "7!n10!n"},
+	"HU": {28, "3!n4!n1!n15!n1!n"},
+	"IE": {22, "4!a6!n8!n"},
+	"IS": {26, "4!n2!n6!n10!n"},
+	"IT": {27, "1!a5!n5!n12!c"},
+	"LI": {21, "5!n12!c"},
+	"LT": {20, "5!n11!n"},
+	"LU": {20, "3!n13!c"},
+	"LV": {21, "4!a13!c"},
+	"MC": {27, "5!n5!n11!c2!n"},
+	"MT": {31, "4!a5!n18!c"},
+	"NL": {18, "4!a10!n"},
+	"NO": {15, "4!n6!n1!n"},
+	"PL": {28, "8!n16!n"},
+	"PT": {25, "4!n4!n11!n2!n"},
+	"RO": {24, "4!a16!c"},
+	"SA": {24, "2!n18!c"},
+	"SE": {24, "3!n16!n1!n"},
+	"SI": {19, "5!n8!n2!n"},
+	"SK": {24, "4!n6!n10!n"},
+	"SM": {27, "1!a5!n5!n12!c"},
+}
+
+// Build returns the IBAN for a country's BBAN
+func Build(countryCode string, bban string) (string, error) {
+	countryCode = strings.ToUpper(countryCode)
+	bban = Normalize(bban)
+	structure, ok := Structures[countryCode]
+	if !ok {
+		return "", errors.New("iban.Build: IBANs are not supported for " + countryCode)
+	}
+	if !matchesBBAN(structure.BBAN, bban) {
+		return "", errors.New("iban.Build: BBAN does not match the " + countryCode + " layout " + structure.BBAN)
+	}
+
+	checkDigits := 98 - mod97(bban+countryCode+"00")
+	if checkDigits < 10 {
+		return countryCode + "0" + strconv.Itoa(checkDigits) + bban, nil
+	}
+	return countryCode + strconv.Itoa(checkDigits) + bban, nil
+}
+
+// BuildGB returns the GB IBAN for a bank code, sort code and account number
+func BuildGB(bankCode string, sortCode string, accountNumber string) (string, error) {
+	sortCode = strings.ReplaceAll(sortCode, "-", "")
+	iban, err := Build("GB", strings.ToUpper(bankCode)+Normalize(sortCode)+accountNumber)
+	if err != nil {
+		return "", errors.New("iban.BuildGB: " + err.Error())
+	}
+	return iban, nil
+}
+
+// ForAccount returns the IBAN for one of our account numbers. It's empty when no bank code
+// is configured and for numbers issued before UK account numbers were 8 digits.
+func ForAccount(accountNumber string) (string, error) {
+	bankCode := strings.TrimSpace(os.Getenv(BANK_CODE_ENV))
+	if bankCode == "" || len(accountNumber) != 8 {
+		return "", nil
+	}
+	iban, err := BuildGB(bankCode, os.Getenv(SORT_CODE_ENV), accountNumber)
+	if err != nil {
+		return "", errors.New("iban.ForAccount: " + err.Error())
+	}
+	return iban, nil
+}
+
+// BankBIC returns our BIC
+func BankBIC() string {
+	return strings.ToUpper(strings.TrimSpace(os.Getenv(BIC_ENV)))
+}
+
+// Validate checks an IBAN's country, length, BBAN layout and check digits.
+// Spaces are ignored and lower case letters accepted.
+func Validate(iban string) error {
+	iban = Normalize(iban)
+	if len(iban) < 4 {
+		return errors.New("iban.Validate: IBAN is too short")
+	}
+	countryCode := iban[:2]
+	structure, ok := Structures[countryCode]
+	if !ok {
+		return errors.New("iban.Validate: IBANs are not supported for " + countryCode)
+	}
+	if len(iban) != structure.Length {
+		return errors.New("iban.Validate: " + countryCode + " IBANs are " + strconv.Itoa(structure.Length) + " characters")
+	}
+	if !isDigits(iban[2:4]) || !matchesBBAN(structure.BBAN, iban[4:]) {
+		return errors.New("iban.Validate: IBAN does not match the " + countryCode + " layout")
+	}
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return errors.New("iban.Validate: Invalid IBAN check digits")
+	}
+
+	return nil
+}
+
+// Normalize strips spaces and upper cases an IBAN
+func Normalize(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(iban), " ", ""))
+}
+
+// Format groups an IBAN in fours for print
+func Format(iban string) string {
+	iban = Normalize(iban)
+	var formatted strings.Builder
+	for i, c := range iban {
+		if i > 0 && i%4 == 0 {
+			formatted.WriteByte(' ')
+		}
+		formatted.WriteRune(c)
+	}
+	return formatted.String()
+}
+
+// ValidateBIC checks a BIC is a 4 letter bank code, 2 letter country code, 2 character
+// location and an optional 3 character branch code
+func ValidateBIC(bic string) error {
+	bic = strings.ToUpper(strings.TrimSpace(bic))
+	if len(bic) != 8 && len(bic) != 11 {
+		return errors.New("iban.ValidateBIC: BIC must be 8 or 11 characters")
+	}
+	if !isLetters(bic[:6]) || !isAlphanumeric(bic[6:]) {
+		return errors.New("iban.ValidateBIC: Invalid BIC " + bic)
+	}
+
+	return nil
+}
+
+// matchesBBAN checks a BBAN against a registry layout such as 4!a6!n8!n
+func matchesBBAN(layout string, bban string) bool {
+	position := 0
+	for layout != "" {
+		end := strings.Index(layout, "!")
+		if end < 1 || end+1 >= len(layout) {
+			return false
+		}
+		length, err := strconv.Atoi(layout[:end])
+		if err != nil || position+length > len(bban) {
+			return false
+		}
+		part := bban[position : position+length]
+		switch layout[end+1] {
+		case 'n':
+			if !isDigits(part) {
+				return false
+			}
+		case 'a':
+			if !isLetters(part) {
+				return false
+			}
+		case 'c':
+			if !isAlphanumeric(part) {
+				return false
+			}
+		default:
+			return false
+		}
+		position += length
+		layout = layout[end+2:]
+	}
+	return position == len(bban)
+}
+
+// mod97 returns the remainder of the number formed by replacing each letter with 10 to 35
+func mod97(s string) int {
+	remainder := 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		}
+	}
+	return remainder
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetters(s string) bool {
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
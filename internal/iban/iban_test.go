@@ -0,0 +1,92 @@
+package iban
+
+import (
+	"testing"
+)
+
+func TestBuildGB(t *testing.T) {
+	// Example from the SWIFT IBAN registry
+	for _, sortCode := range []string{"601613", "60-16-13"} {
+		iban, err := BuildGB("nwbk", sortCode, "31926819")
+		if err != nil || iban != "GB29NWBK60161331926819" {
+			t.Errorf("BuildGB does not pass. Looking for %v, got %v (%v)", "GB29NWBK60161331926819", iban, err)
+		}
+	}
+
+	_, err := BuildGB("NWBK", "601613", "3192681")
+	if err == nil {
+		t.Errorf("BuildGB does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	cases := [][]string{
+		{"DE", "370400440532013000", "DE89370400440532013000"},
+		{"FR", "20041010050500013M02606", "FR1420041010050500013M02606"},
+		{"NL", "ABNA0417164300", "NL91ABNA0417164300"},
+		{"BE", "539007547034", "BE68539007547034"},
+	}
+	for _, c := range cases {
+		iban, err := Build(c[0], c[1])
+		if err != nil || iban != c[2] {
+			t.Errorf("Build does not pass. Looking for %v, got %v (%v)", c[2], iban, err)
+		}
+	}
+
+	if _, err := Build("NG", "0123456789"); err == nil {
+		t.Errorf("Build does not pass. Looking for %v, got %v", "error", err)
+	}
+	if _, err := Build("NL", "0417164300ABNA"); err == nil {
+		t.Errorf("Build does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"GB29NWBK60161331926819",
+		"GB29 NWBK 6016 1331 9268 19",
+		"gb29nwbk60161331926819",
+		"DE89370400440532013000",
+		"NO9386011117947",
+		"MT84MALT011000012345MTLCAST001S",
+	}
+	for _, iban := range valid {
+		if err := Validate(iban); err != nil {
+			t.Errorf("Validate does not pass. Looking for %v, got %v for %v", nil, err, iban)
+		}
+	}
+
+	invalid := []string{
+		"GB28NWBK60161331926819",
+		"GB29NWBK6016133192681",
+		"GB29NWBK601613319268190",
+		"GB291WBK60161331926819",
+		"XX29NWBK60161331926819",
+		"GB",
+	}
+	for _, iban := range invalid {
+		if err := Validate(iban); err == nil {
+			t.Errorf("Validate does not pass. Looking for %v, got %v for %v", "error", err, iban)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	formatted := Format("GB29NWBK60161331926819")
+	if formatted != "GB29 NWBK 6016 1331 9268 19" {
+		t.Errorf("Format does not pass. Looking for %v, got %v", "GB29 NWBK 6016 1331 9268 19", formatted)
+	}
+}
+
+func TestValidateBIC(t *testing.T) {
+	for _, bic := range []string{"NWBKGB2L", "DEUTDEFF500", "deutdeff"} {
+		if err := ValidateBIC(bic); err != nil {
+			t.Errorf("ValidateBIC does not pass. Looking for %v, got %v for %v", nil, err, bic)
+		}
+	}
+	for _, bic := range []string{"NWBKGB2", "NWBK1B2L", "DEUTDEFF50", "DEUTDEFF5_0"} {
+		if err := ValidateBIC(bic); err == nil {
+			t.Errorf("ValidateBIC does not pass. Looking for %v, got %v for %v", "error", err, bic)
+		}
+	}
+}
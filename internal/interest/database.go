@@ -0,0 +1,465 @@
+package interest
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const capitalisationColumns = "`id`, `accountNumber`, `period`, `currencyCode`, `grossInterest`, `withholdingTax`, `netInterest`, `interestPosted`, `taxPosted`, `status`, `timestamp`"
+
+// saveProduct inserts a product with its opening rate table
+func saveProduct(product Product, creator string) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("interest.saveProduct: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO interest_products (`code`, `name`, `currencyCode`, `withholdingTaxRate`, `status`, `creator`) VALUES(?, ?, ?, ?, ?, ?)",
+		product.Code, product.Name, product.CurrencyCode, product.WithholdingTaxRate, product.Status, creator)
+	if err != nil {
+		return 0, errors.New("interest.saveProduct: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("interest.saveProduct: " + err.Error())
+	}
+
+	for _, tier := range product.Tiers {
+		_, err = tx.Exec("INSERT INTO interest_product_tiers (`productId`, `minBalance`, `rate`, `effectiveFrom`, `creator`) VALUES(?, ?, ?, ?, ?)",
+			id, tier.MinBalance, tier.Rate, tier.EffectiveFrom, creator)
+		if err != nil {
+			return 0, errors.New("interest.saveProduct: " + err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("interest.saveProduct: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// saveTiers replaces whatever table the product had from the same date
+func saveTiers(productID int64, tiers []Tier, creator string) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("interest.saveTiers: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM interest_product_tiers WHERE `productId` = ? AND `effectiveFrom` = ?", productID, tiers[0].EffectiveFrom)
+	if err != nil {
+		return errors.New("interest.saveTiers: " + err.Error())
+	}
+	for _, tier := range tiers {
+		_, err = tx.Exec("INSERT INTO interest_product_tiers (`productId`, `minBalance`, `rate`, `effectiveFrom`, `creator`) VALUES(?, ?, ?, ?, ?)",
+			productID, tier.MinBalance, tier.Rate, tier.EffectiveFrom, creator)
+		if err != nil {
+			return errors.New("interest.saveTiers: " + err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("interest.saveTiers: " + err.Error())
+	}
+
+	return nil
+}
+
+// getTiers returns the rate table in effect for a product on date, lowest tier first
+func getTiers(productID int64, date string) (tiers []Tier, err error) {
+	query := "SELECT `minBalance`, `rate`, `effectiveFrom` FROM `interest_product_tiers` WHERE `productId` = ? AND `effectiveFrom` = " +
+		"(SELECT MAX(`effectiveFrom`) FROM `interest_product_tiers` WHERE `productId` = ? AND `effectiveFrom` <= ?) ORDER BY `minBalance`"
+	rows, err := Config.Db.Query(query, productID, productID, date)
+	if err != nil {
+		return nil, errors.New("interest.getTiers: " + err.Error())
+	}
+	defer rows.Close()
+
+	tiers = make([]Tier, 0)
+	for rows.Next() {
+		var tier Tier
+		if err := rows.Scan(&tier.MinBalance, &tier.Rate, &tier.EffectiveFrom); err != nil {
+			return nil, errors.New("interest.getTiers: " + err.Error())
+		}
+		tiers = append(tiers, tier)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("interest.getTiers: " + err.Error())
+	}
+
+	return tiers, nil
+}
+
+func getProduct(id int64) (product Product, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `code`, `name`, `currencyCode`, `withholdingTaxRate`, `status`, `timestamp` FROM `interest_products` WHERE `id` = ?", id)
+	if err != nil {
+		return Product{}, false, errors.New("interest.getProduct: " + err.Error())
+	}
+	defer rows.Close()
+
+	products, err := scanProducts(rows)
+	if err != nil {
+		return Product{}, false, errors.New("interest.getProduct: " + err.Error())
+	}
+	if len(products) == 0 {
+		return Product{}, false, nil
+	}
+
+	return products[0], true, nil
+}
+
+func getProducts() (products []Product, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `code`, `name`, `currencyCode`, `withholdingTaxRate`, `status`, `timestamp` FROM `interest_products` WHERE `status` = ? ORDER BY `currencyCode`, `code`", ProductActive)
+	if err != nil {
+		return nil, errors.New("interest.getProducts: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanProducts(rows)
+}
+
+func scanProducts(rows *sql.Rows) (products []Product, err error) {
+	products = make([]Product, 0)
+	for rows.Next() {
+		var product Product
+		var timestamp string
+		if err := rows.Scan(&product.ID, &product.Code, &product.Name, &product.CurrencyCode, &product.WithholdingTaxRate, &product.Status, &timestamp); err != nil {
+			return nil, errors.New("interest.scanProducts: " + err.Error())
+		}
+		product.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("interest.scanProducts: " + err.Error())
+		}
+		products = append(products, product)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("interest.scanProducts: " + err.Error())
+	}
+
+	return products, nil
+}
+
+func updateProductStatus(id int64, status string) (err error) {
+	res, err := Config.Db.Exec("UPDATE interest_products SET `status` = ? WHERE `id` = ?", status, id)
+	if err != nil {
+		return errors.New("interest.updateProductStatus: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("interest.updateProductStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("interest.updateProductStatus: Product not found or already " + status)
+	}
+	return nil
+}
+
+func saveEnrolment(accountNumber string, productID int64, creator string) (err error) {
+	insertStatement := "INSERT INTO interest_accounts (`accountNumber`, `productId`, `creator`) VALUES(?, ?, ?) ON DUPLICATE KEY UPDATE `productId` = VALUES(`productId`), `creator` = VALUES(`creator`)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("interest.saveEnrolment: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(accountNumber, productID, creator)
+	if err != nil {
+		return errors.New("interest.saveEnrolment: " + err.Error())
+	}
+	return nil
+}
+
+func deleteEnrolment(accountNumber string) (err error) {
+	_, err = Config.Db.Exec("DELETE FROM interest_accounts WHERE `accountNumber` = ?", accountNumber)
+	if err != nil {
+		return errors.New("interest.deleteEnrolment: " + err.Error())
+	}
+	return nil
+}
+
+func getEnrolment(accountNumber string) (productID int64, found bool, err error) {
+	err = Config.Db.QueryRow("SELECT `productId` FROM `interest_accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, errors.New("interest.getEnrolment: " + err.Error())
+	}
+	return productID, true, nil
+}
+
+// getEnrolments lists the enrolled accounts with their ledger balances
+func getEnrolments() (enrolments []enrolment, err error) {
+	rows, err := Config.Db.Query("SELECT ia.`accountNumber`, ia.`productId`, a.`currencyCode`, a.`accountBalance` FROM `interest_accounts` ia JOIN `accounts` a ON a.`accountNumber` = ia.`accountNumber` ORDER BY ia.`id`")
+	if err != nil {
+		return nil, errors.New("interest.getEnrolments: " + err.Error())
+	}
+	defer rows.Close()
+
+	enrolments = make([]enrolment, 0)
+	for rows.Next() {
+		var enrolled enrolment
+		if err := rows.Scan(&enrolled.AccountNumber, &enrolled.ProductID, &enrolled.CurrencyCode, &enrolled.Balance); err != nil {
+			return nil, errors.New("interest.getEnrolments: " + err.Error())
+		}
+		enrolments = append(enrolments, enrolled)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("interest.getEnrolments: " + err.Error())
+	}
+
+	return enrolments, nil
+}
+
+// saveAccrual records a day's interest, updating an accrual already made for the day as long
+// as it hasn't been capitalised. Nothing is added to a month that has already been capitalised.
+func saveAccrual(accrual Accrual) (saved bool, err error) {
+	insertStatement := "INSERT INTO interest_accruals (`accountNumber`, `productId`, `accrualDate`, `balance`, `rate`, `amount`) " +
+		"SELECT ?, ?, ?, ?, ?, ? FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM `interest_capitalisations` WHERE `accountNumber` = ? AND `period` = DATE_FORMAT(?, '%Y-%m')) " +
+		"ON DUPLICATE KEY UPDATE `productId` = IF(`capitalisationId` IS NULL, VALUES(`productId`), `productId`), " +
+		"`balance` = IF(`capitalisationId` IS NULL, VALUES(`balance`), `balance`), " +
+		"`rate` = IF(`capitalisationId` IS NULL, VALUES(`rate`), `rate`), " +
+		"`amount` = IF(`capitalisationId` IS NULL, VALUES(`amount`), `amount`)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return false, errors.New("interest.saveAccrual: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(accrual.AccountNumber, accrual.ProductID, accrual.AccrualDate, accrual.Balance, accrual.Rate, accrual.Amount, accrual.AccountNumber, accrual.AccrualDate)
+	if err != nil {
+		return false, errors.New("interest.saveAccrual: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.New("interest.saveAccrual: " + err.Error())
+	}
+
+	return affected > 0, nil
+}
+
+func getAccruals(accountNumber string, from string, to string) (accruals []Accrual, err error) {
+	rows, err := Config.Db.Query("SELECT `accountNumber`, `productId`, `accrualDate`, `balance`, `rate`, `amount`, IFNULL(`capitalisationId`, 0) FROM `interest_accruals` WHERE `accountNumber` = ? AND `accrualDate` BETWEEN ? AND ? ORDER BY `accrualDate`",
+		accountNumber, from, to)
+	if err != nil {
+		return nil, errors.New("interest.getAccruals: " + err.Error())
+	}
+	defer rows.Close()
+
+	accruals = make([]Accrual, 0)
+	for rows.Next() {
+		var accrual Accrual
+		if err := rows.Scan(&accrual.AccountNumber, &accrual.ProductID, &accrual.AccrualDate, &accrual.Balance, &accrual.Rate, &accrual.Amount, &accrual.CapitalisationID); err != nil {
+			return nil, errors.New("interest.getAccruals: " + err.Error())
+		}
+		accruals = append(accruals, accrual)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("interest.getAccruals: " + err.Error())
+	}
+
+	return accruals, nil
+}
+
+// getLastRunDate is the last day interest was accrued, empty before the first run
+func getLastRunDate() (date string, err error) {
+	err = Config.Db.QueryRow("SELECT IFNULL(MAX(`runDate`), '') FROM `interest_accrual_runs` WHERE `missed` = 0").Scan(&date)
+	if err != nil {
+		return "", errors.New("interest.getLastRunDate: " + err.Error())
+	}
+	return date, nil
+}
+
+// saveAccrualRun records the day's run and the days before it that were missed
+func saveAccrualRun(date string, missed []string) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("interest.saveAccrualRun: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	for _, day := range missed {
+		_, err = tx.Exec("INSERT INTO interest_accrual_runs (`runDate`, `missed`) VALUES(?, 1) ON DUPLICATE KEY UPDATE `id` = `id`", day)
+		if err != nil {
+			return errors.New("interest.saveAccrualRun: " + err.Error())
+		}
+	}
+	_, err = tx.Exec("INSERT INTO interest_accrual_runs (`runDate`, `missed`) VALUES(?, 0) ON DUPLICATE KEY UPDATE `id` = `id`", date)
+	if err != nil {
+		return errors.New("interest.saveAccrualRun: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("interest.saveAccrualRun: " + err.Error())
+	}
+
+	return nil
+}
+
+// getAccountsDue lists the accounts with accruals up to through still to capitalise, or a
+// capitalisation still to post
+func getAccountsDue(through string) (accountNumbers []string, err error) {
+	query := "SELECT DISTINCT `accountNumber` FROM `interest_accruals` WHERE `capitalisationId` IS NULL AND `accrualDate` <= ? " +
+		"UNION SELECT `accountNumber` FROM `interest_capitalisations` WHERE `status` = ?"
+	return queryStrings("interest.getAccountsDue", query, through, CapitalisationPending)
+}
+
+// getDuePeriods lists the months (YYYY-MM) an account has accruals up to through still to capitalise
+func getDuePeriods(accountNumber string, through string) (periods []string, err error) {
+	query := "SELECT DISTINCT DATE_FORMAT(`accrualDate`, '%Y-%m') AS `period` FROM `interest_accruals` WHERE `accountNumber` = ? AND `capitalisationId` IS NULL AND `accrualDate` <= ? ORDER BY `period`"
+	return queryStrings("interest.getDuePeriods", query, accountNumber, through)
+}
+
+func queryStrings(caller string, query string, args ...interface{}) (values []string, err error) {
+	rows, err := Config.Db.Query(query, args...)
+	if err != nil {
+		return nil, errors.New(caller + ": " + err.Error())
+	}
+	defer rows.Close()
+
+	values = make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, errors.New(caller + ": " + err.Error())
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(caller + ": " + err.Error())
+	}
+
+	return values, nil
+}
+
+// saveCapitalisation records a month's capitalisation as pending, claiming the month's
+// accruals up to periodEnd for it, and works out the interest and tax to post
+func saveCapitalisation(accountNumber string, period string, periodEnd string) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	var currencyCode string
+	err = tx.QueryRow("SELECT `currencyCode` FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&currencyCode)
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+
+	res, err := tx.Exec("INSERT INTO interest_capitalisations (`accountNumber`, `period`, `currencyCode`, `status`) VALUES(?, ?, ?, ?)",
+		accountNumber, period, currencyCode, CapitalisationPending)
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+
+	_, err = tx.Exec("UPDATE interest_accruals SET `capitalisationId` = ? WHERE `accountNumber` = ? AND `capitalisationId` IS NULL AND `accrualDate` BETWEEN ? AND ?",
+		id, accountNumber, period+"-01", periodEnd)
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+
+	// The tax rate is the one of the product the account was last accruing under
+	var accrued, taxRate decimal.Decimal
+	query := "SELECT IFNULL(SUM(a.`amount`), 0), IFNULL((SELECT p.`withholdingTaxRate` FROM `interest_accruals` l JOIN `interest_products` p ON p.`id` = l.`productId` " +
+		"WHERE l.`capitalisationId` = ? ORDER BY l.`accrualDate` DESC LIMIT 1), 0) FROM `interest_accruals` a WHERE a.`capitalisationId` = ?"
+	err = tx.QueryRow(query, id, id).Scan(&accrued, &taxRate)
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+	gross, tax, net := splitInterest(currencyCode, accrued, taxRate)
+
+	_, err = tx.Exec("UPDATE interest_capitalisations SET `grossInterest` = ?, `withholdingTax` = ?, `netInterest` = ? WHERE `id` = ?", gross, tax, net, id)
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("interest.saveCapitalisation: " + err.Error())
+	}
+
+	return nil
+}
+
+func getPendingCapitalisations(accountNumber string) (capitalisations []Capitalisation, err error) {
+	rows, err := Config.Db.Query("SELECT "+capitalisationColumns+" FROM `interest_capitalisations` WHERE `accountNumber` = ? AND `status` = ? ORDER BY `period`", accountNumber, CapitalisationPending)
+	if err != nil {
+		return nil, errors.New("interest.getPendingCapitalisations: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanCapitalisations(rows)
+}
+
+func getCapitalisations(accountNumber string) (capitalisations []Capitalisation, err error) {
+	rows, err := Config.Db.Query("SELECT "+capitalisationColumns+" FROM `interest_capitalisations` WHERE `accountNumber` = ? ORDER BY `period` DESC", accountNumber)
+	if err != nil {
+		return nil, errors.New("interest.getCapitalisations: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanCapitalisations(rows)
+}
+
+func scanCapitalisations(rows *sql.Rows) (capitalisations []Capitalisation, err error) {
+	capitalisations = make([]Capitalisation, 0)
+	for rows.Next() {
+		var capitalisation Capitalisation
+		var timestamp string
+		if err := rows.Scan(&capitalisation.ID, &capitalisation.AccountNumber, &capitalisation.Period, &capitalisation.CurrencyCode, &capitalisation.GrossInterest,
+			&capitalisation.WithholdingTax, &capitalisation.NetInterest, &capitalisation.InterestPosted, &capitalisation.TaxPosted, &capitalisation.Status, &timestamp); err != nil {
+			return nil, errors.New("interest.scanCapitalisations: " + err.Error())
+		}
+		capitalisation.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("interest.scanCapitalisations: " + err.Error())
+		}
+		capitalisations = append(capitalisations, capitalisation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("interest.scanCapitalisations: " + err.Error())
+	}
+
+	return capitalisations, nil
+}
+
+// updateCapitalisationPosted marks the interest or the tax of a capitalisation as posted
+func updateCapitalisationPosted(id int64, column string) (err error) {
+	if column != "interestPosted" && column != "taxPosted" {
+		return errors.New("interest.updateCapitalisationPosted: Unknown posting " + column)
+	}
+	_, err = Config.Db.Exec("UPDATE interest_capitalisations SET `"+column+"` = 1 WHERE `id` = ?", id)
+	if err != nil {
+		return errors.New("interest.updateCapitalisationPosted: " + err.Error())
+	}
+	return nil
+}
+
+func updateCapitalisationStatus(id int64, status string) (err error) {
+	_, err = Config.Db.Exec("UPDATE interest_capitalisations SET `status` = ? WHERE `id` = ?", status, id)
+	if err != nil {
+		return errors.New("interest.updateCapitalisationStatus: " + err.Error())
+	}
+	return nil
+}
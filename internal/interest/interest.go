@@ -0,0 +1,649 @@
+package interest
+
+/*
+Savings interest
+
+Accounts earn interest once they're enrolled in an interest product. A product has a rate
+table tiered by balance: each tier gives the annual rate, as a percentage, paid on the whole
+ledger balance when it's at least the tier's minimum. Balances below the lowest tier earn
+nothing. A new rate table can be set from a date; accruals use the table in effect on the day.
+
+Every day each enrolled account accrues balance x rate / 365 (actual/365), kept to six decimal
+places in interest_accruals. The accrual is taken on the ledger balance when the run happens:
+the background job re-runs the current day's accruals, so the day's figure follows the balance
+until the day ends, and accruals that have been capitalised are never changed.
+
+Only the current day can be accrued, since the ledger balance is only known as it stands now.
+Days the run didn't happen on are not accrued later: the next run records them as missed in
+interest_accrual_runs and reports them, so they can be put right by hand.
+
+After a month ends its accruals are capitalised: their total is rounded to the currency's
+minor units, withholding tax at the product's rate is taken off, and the net interest is
+posted from the bank's interest expense account for the currency
+(INTEREST_EXPENSE_ACCOUNT_NUMBER_<CODE>) to the account with a PAIN 1001 posting. The tax
+goes from the same expense account to WITHHOLDING_TAX_ACCOUNT_NUMBER_<CODE>. Each
+capitalisation is recorded in interest_capitalisations before anything is posted, so one
+that fails part way is resumed without posting twice.
+*/
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ProductActive    = "active"
+	ProductWithdrawn = "withdrawn"
+
+	CapitalisationPending = "pending"
+	CapitalisationPosted  = "posted"
+
+	DAYS_IN_YEAR   = 365
+	ACCRUAL_PLACES = 6
+	DATE_LAYOUT    = "2006-01-02"
+	PERIOD_LAYOUT  = "2006-01"
+	SYSTEM_ACTOR   = "system"
+
+	expenseAccountPrefix = "INTEREST_EXPENSE_ACCOUNT_NUMBER_"
+	taxAccountPrefix     = "WITHHOLDING_TAX_ACCOUNT_NUMBER_"
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Product is a savings interest product
+type Product struct {
+	ID                 int64           `json:"id"`
+	Code               string          `json:"code"`
+	Name               string          `json:"name"`
+	CurrencyCode       string          `json:"currencyCode"`
+	WithholdingTaxRate decimal.Decimal `json:"withholdingTaxRate"`
+	Status             string          `json:"status"`
+	Tiers              []Tier          `json:"tiers"`
+	Timestamp          time.Time       `json:"timestamp"`
+}
+
+// Tier is the annual rate paid on balances of at least MinBalance
+type Tier struct {
+	MinBalance    decimal.Decimal `json:"minBalance"`
+	Rate          decimal.Decimal `json:"rate"`
+	EffectiveFrom string          `json:"effectiveFrom"`
+}
+
+// Accrual is a day's interest on an account
+type Accrual struct {
+	AccountNumber    string          `json:"accountNumber"`
+	ProductID        int64           `json:"productId"`
+	AccrualDate      string          `json:"accrualDate"`
+	Balance          decimal.Decimal `json:"balance"`
+	Rate             decimal.Decimal `json:"rate"`
+	Amount           decimal.Decimal `json:"amount"`
+	CapitalisationID int64           `json:"capitalisationId,omitempty"`
+}
+
+// Capitalisation is a month's accruals paid to an account
+type Capitalisation struct {
+	ID             int64           `json:"id"`
+	AccountNumber  string          `json:"accountNumber"`
+	Period         string          `json:"period"`
+	CurrencyCode   string          `json:"currencyCode"`
+	GrossInterest  decimal.Decimal `json:"grossInterest"`
+	WithholdingTax decimal.Decimal `json:"withholdingTax"`
+	NetInterest    decimal.Decimal `json:"netInterest"`
+	InterestPosted bool            `json:"-"`
+	TaxPosted      bool            `json:"-"`
+	Status         string          `json:"status"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// Summary is what an account earns and has been paid
+type Summary struct {
+	AccountNumber    string           `json:"accountNumber"`
+	Product          *Product         `json:"product"`
+	AccruedThisMonth decimal.Decimal  `json:"accruedThisMonth"`
+	Capitalisations  []Capitalisation `json:"capitalisations"`
+}
+
+// Run is what an accrual run did, with the days since the last run that weren't accrued
+type Run struct {
+	Date        string   `json:"date"`
+	Accrued     int      `json:"accrued"`
+	Capitalised int      `json:"capitalised"`
+	Missed      []string `json:"missed,omitempty"`
+}
+
+// enrolment is an account earning interest, with its ledger balance now
+type enrolment struct {
+	AccountNumber string
+	ProductID     int64
+	CurrencyCode  string
+	Balance       decimal.Decimal
+}
+
+// CreateProduct adds a product with its opening rate table, in effect from today
+func CreateProduct(product Product, creator string) (Product, error) {
+	product.Code = strings.ToUpper(strings.TrimSpace(product.Code))
+	product.Name = strings.TrimSpace(product.Name)
+	product.CurrencyCode = strings.ToUpper(strings.TrimSpace(product.CurrencyCode))
+	product.Status = ProductActive
+
+	err := validateProduct(product)
+	if err != nil {
+		return Product{}, errors.New("interest.CreateProduct: " + err.Error())
+	}
+	product.Tiers = sortTiers(product.Tiers, time.Now().Format(DATE_LAYOUT))
+
+	product.ID, err = saveProduct(product, creator)
+	if err != nil {
+		return Product{}, errors.New("interest.CreateProduct: " + err.Error())
+	}
+	product.Timestamp = time.Now()
+
+	return product, nil
+}
+
+// SetTiers replaces a product's rate table from a date, today when none is given
+func SetTiers(productID int64, tiers []Tier, effectiveFrom string, creator string) (Product, error) {
+	product, err := GetProduct(productID)
+	if err != nil {
+		return Product{}, errors.New("interest.SetTiers: " + err.Error())
+	}
+
+	effectiveFrom = strings.TrimSpace(effectiveFrom)
+	if effectiveFrom == "" {
+		effectiveFrom = time.Now().Format(DATE_LAYOUT)
+	}
+	if _, err := time.Parse(DATE_LAYOUT, effectiveFrom); err != nil {
+		return Product{}, errors.New("interest.SetTiers: Effective date must be in the format YYYY-MM-DD")
+	}
+	err = validateTiers(tiers)
+	if err != nil {
+		return Product{}, errors.New("interest.SetTiers: " + err.Error())
+	}
+	tiers = sortTiers(tiers, effectiveFrom)
+
+	err = saveTiers(product.ID, tiers, creator)
+	if err != nil {
+		return Product{}, errors.New("interest.SetTiers: " + err.Error())
+	}
+
+	// Only show the new table once it's in effect
+	if effectiveFrom <= time.Now().Format(DATE_LAYOUT) {
+		product.Tiers = tiers
+	}
+	return product, nil
+}
+
+// Products lists the products on offer with today's rate tables
+func Products() ([]Product, error) {
+	products, err := getProducts()
+	if err != nil {
+		return nil, errors.New("interest.Products: " + err.Error())
+	}
+	today := time.Now().Format(DATE_LAYOUT)
+	for i := range products {
+		products[i].Tiers, err = getTiers(products[i].ID, today)
+		if err != nil {
+			return nil, errors.New("interest.Products: " + err.Error())
+		}
+	}
+	return products, nil
+}
+
+// GetProduct returns a product with today's rate table
+func GetProduct(id int64) (Product, error) {
+	product, found, err := getProduct(id)
+	if err != nil {
+		return Product{}, errors.New("interest.GetProduct: " + err.Error())
+	}
+	if !found {
+		return Product{}, errors.New("interest.GetProduct: Product " + strconv.FormatInt(id, 10) + " not found")
+	}
+	product.Tiers, err = getTiers(product.ID, time.Now().Format(DATE_LAYOUT))
+	if err != nil {
+		return Product{}, errors.New("interest.GetProduct: " + err.Error())
+	}
+	return product, nil
+}
+
+// WithdrawProduct stops a product taking new accounts. Enrolled accounts keep earning.
+func WithdrawProduct(id int64) error {
+	err := updateProductStatus(id, ProductWithdrawn)
+	if err != nil {
+		return errors.New("interest.WithdrawProduct: " + err.Error())
+	}
+	return nil
+}
+
+// Enrol starts an account earning interest under a product, or moves it to another one.
+// The product must be held in the account's currency.
+func Enrol(accountNumber string, productID int64, creator string) (Product, error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	product, err := GetProduct(productID)
+	if err != nil {
+		return Product{}, errors.New("interest.Enrol: " + err.Error())
+	}
+	if product.Status != ProductActive {
+		return Product{}, errors.New("interest.Enrol: " + product.Name + " is no longer offered")
+	}
+
+	currencyCode, err := payments.AccountCurrency(accountNumber)
+	if err != nil {
+		return Product{}, errors.New("interest.Enrol: " + err.Error())
+	}
+	if currencyCode != product.CurrencyCode {
+		return Product{}, errors.New("interest.Enrol: " + product.Name + " is held in " + product.CurrencyCode + ", the account is in " + currencyCode)
+	}
+
+	err = saveEnrolment(accountNumber, product.ID, creator)
+	if err != nil {
+		return Product{}, errors.New("interest.Enrol: " + err.Error())
+	}
+	return product, nil
+}
+
+// AccountProduct returns the product an account earns interest under, if any
+func AccountProduct(accountNumber string) (product Product, found bool, err error) {
+	productID, found, err := getEnrolment(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return Product{}, false, errors.New("interest.AccountProduct: " + err.Error())
+	}
+	if !found {
+		return Product{}, false, nil
+	}
+	product, err = GetProduct(productID)
+	if err != nil {
+		return Product{}, false, errors.New("interest.AccountProduct: " + err.Error())
+	}
+	return product, true, nil
+}
+
+// SettleAccount capitalises everything the account has accrued, including the current month,
+// and stops it earning interest. Used when the account is closed.
+func SettleAccount(accountNumber string) (settled decimal.Decimal, err error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	capitalisations, err := capitaliseAccount(accountNumber, time.Now().Format(DATE_LAYOUT))
+	if err != nil {
+		return decimal.Zero, errors.New("interest.SettleAccount: " + err.Error())
+	}
+	settled = decimal.Zero
+	for _, capitalisation := range capitalisations {
+		settled = settled.Add(capitalisation.NetInterest)
+	}
+
+	err = deleteEnrolment(accountNumber)
+	if err != nil {
+		return settled, errors.New("interest.SettleAccount: " + err.Error())
+	}
+	return settled, nil
+}
+
+// AccountSummary returns the account's product, the interest accrued this month and the
+// interest paid so far
+func AccountSummary(accountNumber string) (Summary, error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	summary := Summary{AccountNumber: accountNumber}
+
+	product, found, err := AccountProduct(accountNumber)
+	if err != nil {
+		return Summary{}, errors.New("interest.AccountSummary: " + err.Error())
+	}
+	if found {
+		summary.Product = &product
+	}
+
+	now := time.Now()
+	accruals, err := getAccruals(accountNumber, now.Format(PERIOD_LAYOUT)+"-01", now.Format(DATE_LAYOUT))
+	if err != nil {
+		return Summary{}, errors.New("interest.AccountSummary: " + err.Error())
+	}
+	summary.AccruedThisMonth = decimal.Zero
+	for _, accrual := range accruals {
+		if accrual.CapitalisationID == 0 {
+			summary.AccruedThisMonth = summary.AccruedThisMonth.Add(accrual.Amount)
+		}
+	}
+
+	summary.Capitalisations, err = Capitalisations(accountNumber)
+	if err != nil {
+		return Summary{}, errors.New("interest.AccountSummary: " + err.Error())
+	}
+	return summary, nil
+}
+
+// Accruals lists an account's accruals between from and to (YYYY-MM-DD, inclusive)
+func Accruals(accountNumber string, from string, to string) ([]Accrual, error) {
+	if _, err := time.Parse(DATE_LAYOUT, from); err != nil {
+		return nil, errors.New("interest.Accruals: From date must be in the format YYYY-MM-DD")
+	}
+	if _, err := time.Parse(DATE_LAYOUT, to); err != nil {
+		return nil, errors.New("interest.Accruals: To date must be in the format YYYY-MM-DD")
+	}
+	accruals, err := getAccruals(strings.TrimSpace(accountNumber), from, to)
+	if err != nil {
+		return nil, errors.New("interest.Accruals: " + err.Error())
+	}
+	return accruals, nil
+}
+
+// Capitalisations lists the interest paid to an account, the latest first
+func Capitalisations(accountNumber string) ([]Capitalisation, error) {
+	capitalisations, err := getCapitalisations(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return nil, errors.New("interest.Capitalisations: " + err.Error())
+	}
+	return capitalisations, nil
+}
+
+// RunAccruals accrues today's interest for every enrolled account and capitalises the months
+// that ended before today. Days since the last run are recorded as missed. One account
+// failing doesn't stop the rest.
+func RunAccruals() (run Run, err error) {
+	day := time.Now()
+	date := day.Format(DATE_LAYOUT)
+	run.Date = date
+
+	lastRun, err := getLastRunDate()
+	if err != nil {
+		return Run{}, errors.New("interest.RunAccruals: " + err.Error())
+	}
+	run.Missed, err = missedDates(lastRun, date)
+	if err != nil {
+		return Run{}, errors.New("interest.RunAccruals: " + err.Error())
+	}
+	err = saveAccrualRun(date, run.Missed)
+	if err != nil {
+		return Run{}, errors.New("interest.RunAccruals: " + err.Error())
+	}
+
+	enrolments, err := getEnrolments()
+	if err != nil {
+		return Run{}, errors.New("interest.RunAccruals: " + err.Error())
+	}
+
+	failures := []string{}
+	tiers := map[int64][]Tier{}
+	for _, enrolled := range enrolments {
+		productTiers, ok := tiers[enrolled.ProductID]
+		if !ok {
+			productTiers, err = getTiers(enrolled.ProductID, date)
+			if err != nil {
+				failures = append(failures, enrolled.AccountNumber+": "+err.Error())
+				continue
+			}
+			tiers[enrolled.ProductID] = productTiers
+		}
+
+		accrual, ok := accrue(enrolled, productTiers, date)
+		if !ok {
+			continue
+		}
+		// Today's accrual follows the balance until the day ends
+		saved, err := saveAccrual(accrual)
+		if err != nil {
+			failures = append(failures, enrolled.AccountNumber+": "+err.Error())
+			continue
+		}
+		if saved {
+			run.Accrued++
+		}
+	}
+
+	// Months that ended before today are paid
+	through := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1).Format(DATE_LAYOUT)
+	due, err := getAccountsDue(through)
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+	for _, accountNumber := range due {
+		capitalisations, err := capitaliseAccount(accountNumber, through)
+		run.Capitalised += len(capitalisations)
+		if err != nil {
+			failures = append(failures, accountNumber+": "+err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return run, errors.New("interest.RunAccruals: " + strings.Join(failures, "; "))
+	}
+	return run, nil
+}
+
+// TierRate is the rate the tiers pay on a balance: the rate of the highest tier the balance
+// reaches, zero below the lowest one. Tiers must be sorted by minimum balance.
+func TierRate(tiers []Tier, balance decimal.Decimal) decimal.Decimal {
+	rate := decimal.Zero
+	for _, tier := range tiers {
+		if balance.LessThan(tier.MinBalance) {
+			break
+		}
+		rate = tier.Rate
+	}
+	return rate
+}
+
+// DailyInterest is a day's interest on a balance at an annual rate, actual/365
+func DailyInterest(balance decimal.Decimal, rate decimal.Decimal) decimal.Decimal {
+	return balance.Mul(rate).Div(decimal.NewFromInt(100 * DAYS_IN_YEAR)).Round(ACCRUAL_PLACES)
+}
+
+// WithholdingTax is the tax taken from gross interest at a percentage rate
+func WithholdingTax(currencyCode string, gross decimal.Decimal, taxRate decimal.Decimal) decimal.Decimal {
+	if !gross.IsPositive() || !taxRate.IsPositive() {
+		return decimal.Zero
+	}
+	return currency.Round(currencyCode, gross.Mul(taxRate).Div(decimal.NewFromInt(100)))
+}
+
+// accrue works out an account's interest for a day. Accounts that earn nothing that day
+// get no accrual.
+func accrue(enrolled enrolment, tiers []Tier, date string) (Accrual, bool) {
+	if !enrolled.Balance.IsPositive() {
+		return Accrual{}, false
+	}
+	rate := TierRate(tiers, enrolled.Balance)
+	amount := DailyInterest(enrolled.Balance, rate)
+	if !amount.IsPositive() {
+		return Accrual{}, false
+	}
+	return Accrual{
+		AccountNumber: enrolled.AccountNumber,
+		ProductID:     enrolled.ProductID,
+		AccrualDate:   date,
+		Balance:       enrolled.Balance,
+		Rate:          rate,
+		Amount:        amount,
+	}, true
+}
+
+// missedDates are the days after lastRun and before date. There are none before the first run.
+func missedDates(lastRun string, date string) ([]string, error) {
+	if lastRun == "" {
+		return nil, nil
+	}
+	from, err := time.Parse(DATE_LAYOUT, lastRun)
+	if err != nil {
+		return nil, errors.New("interest.missedDates: " + err.Error())
+	}
+	to, err := time.Parse(DATE_LAYOUT, date)
+	if err != nil {
+		return nil, errors.New("interest.missedDates: " + err.Error())
+	}
+
+	var missed []string
+	for day := from.AddDate(0, 0, 1); day.Before(to); day = day.AddDate(0, 0, 1) {
+		missed = append(missed, day.Format(DATE_LAYOUT))
+	}
+	return missed, nil
+}
+
+// capitaliseAccount pays an account's accruals up to through, one capitalisation per month,
+// and finishes any capitalisation a previous run left part way
+func capitaliseAccount(accountNumber string, through string) ([]Capitalisation, error) {
+	periods, err := getDuePeriods(accountNumber, through)
+	if err != nil {
+		return nil, errors.New("interest.capitaliseAccount: " + err.Error())
+	}
+	for _, period := range periods {
+		periodEnd := endOfPeriod(period)
+		if periodEnd > through {
+			periodEnd = through
+		}
+		err = saveCapitalisation(accountNumber, period, periodEnd)
+		if err != nil {
+			return nil, errors.New("interest.capitaliseAccount: " + err.Error())
+		}
+	}
+
+	pending, err := getPendingCapitalisations(accountNumber)
+	if err != nil {
+		return nil, errors.New("interest.capitaliseAccount: " + err.Error())
+	}
+	posted := make([]Capitalisation, 0)
+	for _, capitalisation := range pending {
+		capitalisation, err = postCapitalisation(capitalisation)
+		if err != nil {
+			return posted, errors.New("interest.capitaliseAccount: " + err.Error())
+		}
+		posted = append(posted, capitalisation)
+	}
+	return posted, nil
+}
+
+// postCapitalisation posts the net interest to the account and the tax to the tax account,
+// skipping whichever a previous attempt already posted
+func postCapitalisation(capitalisation Capitalisation) (Capitalisation, error) {
+	expenseAccount, err := systemAccount(expenseAccountPrefix, capitalisation.CurrencyCode)
+	if err != nil {
+		return Capitalisation{}, errors.New("interest.postCapitalisation: " + err.Error())
+	}
+	narration := "Interest for " + periodName(capitalisation.Period)
+
+	if !capitalisation.InterestPosted {
+		if capitalisation.NetInterest.IsPositive() {
+			_, err = payments.ProcessPAIN([]string{"", "pain", "1001", expenseAccount + "@", capitalisation.AccountNumber + "@", capitalisation.NetInterest.String(), narration, SYSTEM_ACTOR})
+			if err != nil {
+				return Capitalisation{}, errors.New("interest.postCapitalisation: " + err.Error())
+			}
+		}
+		err = updateCapitalisationPosted(capitalisation.ID, "interestPosted")
+		if err != nil {
+			return Capitalisation{}, errors.New("interest.postCapitalisation: Interest posted but not recorded. " + err.Error())
+		}
+		capitalisation.InterestPosted = true
+	}
+
+	if !capitalisation.TaxPosted {
+		if capitalisation.WithholdingTax.IsPositive() {
+			taxAccount, err := systemAccount(taxAccountPrefix, capitalisation.CurrencyCode)
+			if err != nil {
+				return Capitalisation{}, errors.New("interest.postCapitalisation: " + err.Error())
+			}
+			_, err = payments.ProcessPAIN([]string{"", "pain", "1001", expenseAccount + "@", taxAccount + "@", capitalisation.WithholdingTax.String(), "Withholding tax on interest for " + periodName(capitalisation.Period) + ", " + capitalisation.AccountNumber, SYSTEM_ACTOR})
+			if err != nil {
+				return Capitalisation{}, errors.New("interest.postCapitalisation: " + err.Error())
+			}
+		}
+		err = updateCapitalisationPosted(capitalisation.ID, "taxPosted")
+		if err != nil {
+			return Capitalisation{}, errors.New("interest.postCapitalisation: Tax posted but not recorded. " + err.Error())
+		}
+		capitalisation.TaxPosted = true
+	}
+
+	err = updateCapitalisationStatus(capitalisation.ID, CapitalisationPosted)
+	if err != nil {
+		return Capitalisation{}, errors.New("interest.postCapitalisation: " + err.Error())
+	}
+	capitalisation.Status = CapitalisationPosted
+	return capitalisation, nil
+}
+
+// splitInterest rounds a period's accruals to the currency and takes off withholding tax
+func splitInterest(currencyCode string, accrued decimal.Decimal, taxRate decimal.Decimal) (gross decimal.Decimal, tax decimal.Decimal, net decimal.Decimal) {
+	gross = currency.Round(currencyCode, accrued)
+	tax = WithholdingTax(currencyCode, gross, taxRate)
+	return gross, tax, gross.Sub(tax)
+}
+
+func endOfPeriod(period string) string {
+	start, err := time.Parse(PERIOD_LAYOUT, period)
+	if err != nil {
+		return period
+	}
+	return start.AddDate(0, 1, -1).Format(DATE_LAYOUT)
+}
+
+func periodName(period string) string {
+	start, err := time.Parse(PERIOD_LAYOUT, period)
+	if err != nil {
+		return period
+	}
+	return start.Format("January 2006")
+}
+
+func validateProduct(product Product) error {
+	if product.Code == "" || product.Name == "" {
+		return errors.New("interest.validateProduct: Code and name must be provided")
+	}
+	if !currency.IsSupported(product.CurrencyCode) {
+		return errors.New("interest.validateProduct: Currency " + product.CurrencyCode + " is not supported")
+	}
+	if product.WithholdingTaxRate.IsNegative() || product.WithholdingTaxRate.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("interest.validateProduct: Withholding tax must be between 0 and 100 percent")
+	}
+	return validateTiers(product.Tiers)
+}
+
+func validateTiers(tiers []Tier) error {
+	if len(tiers) == 0 {
+		return errors.New("interest.validateTiers: At least one tier must be provided")
+	}
+	seen := map[string]bool{}
+	for _, tier := range tiers {
+		if tier.MinBalance.IsNegative() {
+			return errors.New("interest.validateTiers: Tier minimum balances must not be negative")
+		}
+		if tier.Rate.IsNegative() || tier.Rate.GreaterThan(decimal.NewFromInt(100)) {
+			return errors.New("interest.validateTiers: Tier rates must be between 0 and 100 percent")
+		}
+		if seen[tier.MinBalance.String()] {
+			return errors.New("interest.validateTiers: Two tiers start at " + tier.MinBalance.String())
+		}
+		seen[tier.MinBalance.String()] = true
+	}
+	return nil
+}
+
+// sortTiers orders tiers by minimum balance and dates them
+func sortTiers(tiers []Tier, effectiveFrom string) []Tier {
+	sorted := make([]Tier, len(tiers))
+	copy(sorted, tiers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].MinBalance.LessThan(sorted[j].MinBalance)
+	})
+	for i := range sorted {
+		sorted[i].EffectiveFrom = effectiveFrom
+	}
+	return sorted
+}
+
+func systemAccount(prefix string, currencyCode string) (string, error) {
+	accountNumber := strings.TrimSpace(os.Getenv(prefix + currencyCode))
+	if accountNumber == "" {
+		return "", errors.New("interest.systemAccount: " + prefix + currencyCode + " is not configured")
+	}
+	return accountNumber, nil
+}
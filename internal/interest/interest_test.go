@@ -0,0 +1,143 @@
+package interest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func testTiers() []Tier {
+	return sortTiers([]Tier{
+		{MinBalance: decimal.RequireFromString("100000"), Rate: decimal.RequireFromString("6")},
+		{MinBalance: decimal.RequireFromString("1000"), Rate: decimal.RequireFromString("2")},
+		{MinBalance: decimal.RequireFromString("10000"), Rate: decimal.RequireFromString("4")},
+	}, "2024-01-01")
+}
+
+func TestTierRate(t *testing.T) {
+	cases := []struct {
+		balance string
+		rate    string
+	}{
+		{"999.99", "0"},
+		{"1000", "2"},
+		{"9999.99", "2"},
+		{"10000", "4"},
+		{"250000", "6"},
+		{"-50", "0"},
+	}
+	tiers := testTiers()
+	for _, c := range cases {
+		rate := TierRate(tiers, decimal.RequireFromString(c.balance))
+		if !rate.Equal(decimal.RequireFromString(c.rate)) {
+			t.Errorf("TierRate does not pass. Looking for %v, got %v for %v", c.rate, rate, c.balance)
+		}
+	}
+}
+
+func TestDailyInterest(t *testing.T) {
+	cases := []struct {
+		balance string
+		rate    string
+		daily   string
+	}{
+		{"36500", "4", "4"},
+		{"1000", "2", "0.054795"},
+		{"12345.67", "4", "1.352950"},
+	}
+	for _, c := range cases {
+		daily := DailyInterest(decimal.RequireFromString(c.balance), decimal.RequireFromString(c.rate))
+		if !daily.Equal(decimal.RequireFromString(c.daily)) {
+			t.Errorf("DailyInterest does not pass. Looking for %v, got %v for %v at %v", c.daily, daily, c.balance, c.rate)
+		}
+	}
+}
+
+func TestAccrue(t *testing.T) {
+	tiers := testTiers()
+
+	accrual, ok := accrue(enrolment{AccountNumber: "0123456789", ProductID: 2, Balance: decimal.RequireFromString("36500")}, tiers, "2024-03-05")
+	if !ok || !accrual.Rate.Equal(decimal.NewFromInt(4)) || !accrual.Amount.Equal(decimal.NewFromInt(4)) || accrual.AccrualDate != "2024-03-05" {
+		t.Errorf("accrue does not pass. Looking for 4 at 4%%, got %+v", accrual)
+	}
+
+	for _, balance := range []string{"0", "-100", "500"} {
+		if accrual, ok := accrue(enrolment{Balance: decimal.RequireFromString(balance)}, tiers, "2024-03-05"); ok {
+			t.Errorf("accrue does not pass. Looking for no accrual, got %+v for %v", accrual, balance)
+		}
+	}
+}
+
+func TestSplitInterest(t *testing.T) {
+	cases := []struct {
+		accrued string
+		taxRate string
+		gross   string
+		tax     string
+		net     string
+	}{
+		{"123.456789", "10", "123.46", "12.35", "111.11"},
+		{"100", "0", "100", "0", "100"},
+		{"0.004", "10", "0", "0", "0"},
+	}
+	for _, c := range cases {
+		gross, tax, net := splitInterest("NGN", decimal.RequireFromString(c.accrued), decimal.RequireFromString(c.taxRate))
+		if !gross.Equal(decimal.RequireFromString(c.gross)) || !tax.Equal(decimal.RequireFromString(c.tax)) || !net.Equal(decimal.RequireFromString(c.net)) {
+			t.Errorf("splitInterest does not pass. Looking for %v, %v, %v, got %v, %v, %v for %v", c.gross, c.tax, c.net, gross, tax, net, c.accrued)
+		}
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	cases := map[string]string{
+		"2024-02": "2024-02-29",
+		"2023-02": "2023-02-28",
+		"2024-12": "2024-12-31",
+	}
+	for period, end := range cases {
+		if endOfPeriod(period) != end {
+			t.Errorf("endOfPeriod does not pass. Looking for %v, got %v for %v", end, endOfPeriod(period), period)
+		}
+	}
+	if periodName("2024-03") != "March 2024" {
+		t.Errorf("periodName does not pass. Looking for %v, got %v", "March 2024", periodName("2024-03"))
+	}
+}
+
+func TestValidateTiers(t *testing.T) {
+	if err := validateTiers(testTiers()); err != nil {
+		t.Errorf("validateTiers does not pass. Looking for %v, got %v", nil, err)
+	}
+
+	invalid := [][]Tier{
+		{},
+		{{MinBalance: decimal.RequireFromString("-1"), Rate: decimal.NewFromInt(2)}},
+		{{MinBalance: decimal.Zero, Rate: decimal.NewFromInt(101)}},
+		{{MinBalance: decimal.Zero, Rate: decimal.NewFromInt(1)}, {MinBalance: decimal.RequireFromString("0.00"), Rate: decimal.NewFromInt(2)}},
+	}
+	for _, tiers := range invalid {
+		if err := validateTiers(tiers); err == nil {
+			t.Errorf("validateTiers does not pass. Looking for an error, got %v for %+v", err, tiers)
+		}
+	}
+}
+
+func TestMissedDates(t *testing.T) {
+	cases := []struct {
+		lastRun string
+		date    string
+		missed  []string
+	}{
+		{"", "2024-03-05", nil},
+		{"2024-03-05", "2024-03-05", nil},
+		{"2024-03-04", "2024-03-05", nil},
+		{"2024-02-28", "2024-03-02", []string{"2024-02-29", "2024-03-01"}},
+	}
+	for _, c := range cases {
+		missed, err := missedDates(c.lastRun, c.date)
+		if err != nil || !reflect.DeepEqual(missed, c.missed) {
+			t.Errorf("missedDates does not pass. Looking for %v, got %v (%v) for %v to %v", c.missed, missed, err, c.lastRun, c.date)
+		}
+	}
+}
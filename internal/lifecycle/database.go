@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const statusChangeColumns = "`id`, `accountNumber`, `fromStatus`, `toStatus`, `reason`, `actor`, `timestamp`"
+
+func getStatus(accountNumber string) (status string, err error) {
+	err = Config.Db.QueryRow("SELECT `status` FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("lifecycle.getStatus: Account " + accountNumber + " not found")
+		}
+		return "", errors.New("lifecycle.getStatus: " + err.Error())
+	}
+	return
+}
+
+// saveTransition locks the account row, checks the move is allowed from its current state,
+// then updates the state and records the change together
+func saveTransition(change StatusChange) (StatusChange, error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return StatusChange{}, errors.New("lifecycle.saveTransition: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT `status` FROM `accounts` WHERE `accountNumber` = ? FOR UPDATE", change.AccountNumber).Scan(&change.FromStatus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return StatusChange{}, errors.New("lifecycle.saveTransition: Account " + change.AccountNumber + " not found")
+		}
+		return StatusChange{}, errors.New("lifecycle.saveTransition: " + err.Error())
+	}
+	if !CanTransition(change.FromStatus, change.ToStatus) {
+		return StatusChange{}, errors.New("lifecycle.saveTransition: Account cannot move from " + change.FromStatus + " to " + change.ToStatus)
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET `status` = ? WHERE `accountNumber` = ?", change.ToStatus, change.AccountNumber)
+	if err != nil {
+		return StatusChange{}, errors.New("lifecycle.saveTransition: " + err.Error())
+	}
+
+	res, err := tx.Exec("INSERT INTO account_status_changes (`accountNumber`, `fromStatus`, `toStatus`, `reason`, `actor`) VALUES(?, ?, ?, ?, ?)",
+		change.AccountNumber, change.FromStatus, change.ToStatus, change.Reason, change.Actor)
+	if err != nil {
+		return StatusChange{}, errors.New("lifecycle.saveTransition: " + err.Error())
+	}
+	change.ID, err = res.LastInsertId()
+	if err != nil {
+		return StatusChange{}, errors.New("lifecycle.saveTransition: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return StatusChange{}, errors.New("lifecycle.saveTransition: " + err.Error())
+	}
+	change.Timestamp = time.Now()
+
+	return change, nil
+}
+
+func getStatusChanges(accountNumber string) (changes []StatusChange, err error) {
+	rows, err := Config.Db.Query("SELECT "+statusChangeColumns+" FROM `account_status_changes` WHERE `accountNumber` = ? ORDER BY `id` DESC", accountNumber)
+	if err != nil {
+		return nil, errors.New("lifecycle.getStatusChanges: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanStatusChanges(rows)
+}
+
+func scanStatusChanges(rows *sql.Rows) (changes []StatusChange, err error) {
+	changes = make([]StatusChange, 0)
+	for rows.Next() {
+		var change StatusChange
+		var timestamp string
+		if err := rows.Scan(&change.ID, &change.AccountNumber, &change.FromStatus, &change.ToStatus, &change.Reason, &change.Actor, &timestamp); err != nil {
+			return nil, errors.New("lifecycle.scanStatusChanges: " + err.Error())
+		}
+		if change.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("lifecycle.scanStatusChanges: " + err.Error())
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("lifecycle.scanStatusChanges: " + err.Error())
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,164 @@
+package lifecycle
+
+/*
+Account lifecycle
+
+An account is always in one of these states:
+
+	PendingKYC   opened, waiting on identity checks. No postings.
+	Active       normal operation.
+	Dormant      no customer activity for the dormancy period. Credits only.
+	Frozen       no postings at all, e.g. under investigation.
+	DebitFrozen  credits only, e.g. a court order on the funds.
+	CreditFrozen debits only, e.g. while the account is being closed.
+	PostNoDebit  credits only, placed by the bank until the customer resolves an issue.
+	Closed       final, no postings and no further changes.
+
+Moves between states must follow Transitions, and each one is recorded in
+account_status_changes with its reason, the user who made it and when.
+*/
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+)
+
+const (
+	PendingKYC   = "PendingKYC"
+	Active       = "Active"
+	Dormant      = "Dormant"
+	Frozen       = "Frozen"
+	DebitFrozen  = "DebitFrozen"
+	CreditFrozen = "CreditFrozen"
+	PostNoDebit  = "PostNoDebit"
+	Closed       = "Closed"
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Transitions are the states each state can move to
+var Transitions = map[string][]string{
+	PendingKYC:   {Active, Closed},
+	Active:       {Dormant, Frozen, DebitFrozen, CreditFrozen, PostNoDebit, Closed},
+	Dormant:      {Active, Frozen, DebitFrozen, CreditFrozen, PostNoDebit, Closed},
+	Frozen:       {Active, DebitFrozen, CreditFrozen, PostNoDebit},
+	DebitFrozen:  {Active, Frozen, CreditFrozen, PostNoDebit},
+	CreditFrozen: {Active, Frozen, DebitFrozen, PostNoDebit, Closed},
+	PostNoDebit:  {Active, Frozen, DebitFrozen, CreditFrozen},
+	Closed:       {},
+}
+
+// StatusChange is a recorded move between states
+type StatusChange struct {
+	ID            int64     `json:"id"`
+	AccountNumber string    `json:"accountNumber"`
+	FromStatus    string    `json:"fromStatus"`
+	ToStatus      string    `json:"toStatus"`
+	Reason        string    `json:"reason"`
+	Actor         string    `json:"actor"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// IsStatus checks a status is one of the lifecycle states
+func IsStatus(status string) bool {
+	_, ok := Transitions[status]
+	return ok
+}
+
+// CanTransition checks the lifecycle allows moving from one state to another
+func CanTransition(from string, to string) bool {
+	for _, allowed := range Transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanDebit checks whether an account in the state can be debited
+func CanDebit(status string) bool {
+	return status == Active || status == CreditFrozen
+}
+
+// CanCredit checks whether an account in the state can be credited
+func CanCredit(status string) bool {
+	switch status {
+	case Active, Dormant, DebitFrozen, PostNoDebit:
+		return true
+	}
+	return false
+}
+
+// Transition moves an account to a new state, recording why and who moved it
+func Transition(accountNumber string, to string, reason string, actor string) (StatusChange, error) {
+	change := StatusChange{
+		AccountNumber: accountNumber,
+		ToStatus:      to,
+		Reason:        strings.TrimSpace(reason),
+		Actor:         actor,
+	}
+	if !IsStatus(to) {
+		return StatusChange{}, errors.New("lifecycle.Transition: Unknown status " + to)
+	}
+	if change.Reason == "" {
+		return StatusChange{}, errors.New("lifecycle.Transition: Reason cannot be empty")
+	}
+	if change.Actor == "" {
+		return StatusChange{}, errors.New("lifecycle.Transition: Actor cannot be empty")
+	}
+
+	change, err := saveTransition(change)
+	if err != nil {
+		return StatusChange{}, errors.New("lifecycle.Transition: " + err.Error())
+	}
+	return change, nil
+}
+
+// Status returns an account's current state
+func Status(accountNumber string) (string, error) {
+	status, err := getStatus(accountNumber)
+	if err != nil {
+		return "", errors.New("lifecycle.Status: " + err.Error())
+	}
+	return status, nil
+}
+
+// History lists an account's state changes, most recent first
+func History(accountNumber string) ([]StatusChange, error) {
+	changes, err := getStatusChanges(accountNumber)
+	if err != nil {
+		return nil, errors.New("lifecycle.History: " + err.Error())
+	}
+	return changes, nil
+}
+
+// CheckDebit returns an error when the account's state doesn't allow debits
+func CheckDebit(accountNumber string) error {
+	status, err := getStatus(accountNumber)
+	if err != nil {
+		return errors.New("lifecycle.CheckDebit: " + err.Error())
+	}
+	if !CanDebit(status) {
+		return errors.New("lifecycle.CheckDebit: Account " + accountNumber + " is " + status + " and cannot be debited")
+	}
+	return nil
+}
+
+// CheckCredit returns an error when the account's state doesn't allow credits
+func CheckCredit(accountNumber string) error {
+	status, err := getStatus(accountNumber)
+	if err != nil {
+		return errors.New("lifecycle.CheckCredit: " + err.Error())
+	}
+	if !CanCredit(status) {
+		return errors.New("lifecycle.CheckCredit: Account " + accountNumber + " is " + status + " and cannot be credited")
+	}
+	return nil
+}
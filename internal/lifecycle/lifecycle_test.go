@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	allowed := [][]string{
+		{PendingKYC, Active},
+		{Active, Dormant},
+		{Active, PostNoDebit},
+		{Dormant, Active},
+		{Frozen, Active},
+		{DebitFrozen, CreditFrozen},
+		{Active, Closed},
+		{CreditFrozen, Closed},
+	}
+	for _, c := range allowed {
+		if !CanTransition(c[0], c[1]) {
+			t.Errorf("CanTransition does not pass. Looking for %v, got %v for %v to %v", true, false, c[0], c[1])
+		}
+	}
+
+	refused := [][]string{
+		{Closed, Active},
+		{PendingKYC, Dormant},
+		{Frozen, Closed},
+		{PostNoDebit, Closed},
+		{Active, Active},
+		{Active, "Deactivated"},
+	}
+	for _, c := range refused {
+		if CanTransition(c[0], c[1]) {
+			t.Errorf("CanTransition does not pass. Looking for %v, got %v for %v to %v", false, true, c[0], c[1])
+		}
+	}
+}
+
+func TestPostingRules(t *testing.T) {
+	cases := []struct {
+		status string
+		debit  bool
+		credit bool
+	}{
+		{PendingKYC, false, false},
+		{Active, true, true},
+		{Dormant, false, true},
+		{Frozen, false, false},
+		{DebitFrozen, false, true},
+		{CreditFrozen, true, false},
+		{PostNoDebit, false, true},
+		{Closed, false, false},
+	}
+	for _, c := range cases {
+		if CanDebit(c.status) != c.debit {
+			t.Errorf("CanDebit does not pass. Looking for %v, got %v for %v", c.debit, !c.debit, c.status)
+		}
+		if CanCredit(c.status) != c.credit {
+			t.Errorf("CanCredit does not pass. Looking for %v, got %v for %v", c.credit, !c.credit, c.status)
+		}
+	}
+}
+
+func TestEveryTransitionTargetIsAStatus(t *testing.T) {
+	for from, targets := range Transitions {
+		for _, to := range targets {
+			if !IsStatus(to) {
+				t.Errorf("Transitions does not pass. Looking for a status, got %v from %v", to, from)
+			}
+		}
+	}
+}
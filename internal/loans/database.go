@@ -0,0 +1,465 @@
+package loans
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+// The repayment flags set as each of its postings is made
+const (
+	repaymentCollected      = "collected"
+	repaymentInterestPosted = "interestPosted"
+	repaymentFeePosted      = "feePosted"
+)
+
+const loanColumns = "`id`, `loanNumber`, `accountNumber`, `currencyCode`, `requestedAmount`, `requestedTenorMonths`, `purpose`, `amount`, `rate`, `tenorMonths`, `method`, `lateFee`, " +
+	"`status`, `reason`, `applicant`, `approver`, `closedBy`, IFNULL(`approvedAt`, ''), IFNULL(`disbursedAt`, ''), IFNULL(`closedAt`, ''), `timestamp`"
+
+const instalmentColumns = "`id`, `loanId`, `instalmentNumber`, `dueDate`, `principalDue`, `interestDue`, `feeDue`, `principalPaid`, `interestPaid`, `feePaid`, `status`, IFNULL(`paidAt`, '')"
+
+const repaymentColumns = "`id`, `loanId`, `amount`, `principal`, `interest`, `fee`, `repaymentDate`, `source`, `collected`, `interestPosted`, `feePosted`, `status`, `actor`, `timestamp`"
+
+// saveLoan inserts an application and gives it its number
+func saveLoan(loan Loan) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("loans.saveLoan: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	insertStatement := "INSERT INTO loans (`accountNumber`, `currencyCode`, `requestedAmount`, `requestedTenorMonths`, `purpose`, `method`, `status`, `applicant`) VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := tx.Exec(insertStatement, loan.AccountNumber, loan.CurrencyCode, loan.RequestedAmount, loan.RequestedTenorMonths, loan.Purpose, loan.Method, loan.Status, loan.Applicant)
+	if err != nil {
+		return 0, errors.New("loans.saveLoan: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("loans.saveLoan: " + err.Error())
+	}
+
+	_, err = tx.Exec("UPDATE loans SET `loanNumber` = ? WHERE `id` = ?", FormatLoanNumber(id), id)
+	if err != nil {
+		return 0, errors.New("loans.saveLoan: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("loans.saveLoan: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// approveLoan records an application's approved terms
+func approveLoan(loan Loan) (err error) {
+	updateStatement := "UPDATE loans SET `status` = ?, `amount` = ?, `rate` = ?, `tenorMonths` = ?, `method` = ?, `lateFee` = ?, `approver` = ?, `approvedAt` = CURDATE() WHERE `id` = ? AND `status` = ?"
+	res, err := Config.Db.Exec(updateStatement, LoanApproved, loan.Amount, loan.Rate, loan.TenorMonths, loan.Method, loan.LateFee, loan.Approver, loan.ID, LoanApplied)
+	if err != nil {
+		return errors.New("loans.approveLoan: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("loans.approveLoan: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("loans.approveLoan: Loan is no longer " + LoanApplied)
+	}
+	return nil
+}
+
+// activateLoan claims an approved loan for disbursal and saves its schedule
+func activateLoan(id int64, instalments []Instalment) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("loans.activateLoan: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE loans SET `status` = ?, `disbursedAt` = CURDATE() WHERE `id` = ? AND `status` = ?", LoanActive, id, LoanApproved)
+	if err != nil {
+		return errors.New("loans.activateLoan: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("loans.activateLoan: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("loans.activateLoan: Loan is no longer " + LoanApproved)
+	}
+
+	for _, instalment := range instalments {
+		_, err = tx.Exec("INSERT INTO loan_instalments (`loanId`, `instalmentNumber`, `dueDate`, `principalDue`, `interestDue`, `status`) VALUES(?, ?, ?, ?, ?, ?)",
+			id, instalment.Number, instalment.DueDate, instalment.PrincipalDue, instalment.InterestDue, instalment.Status)
+		if err != nil {
+			return errors.New("loans.activateLoan: " + err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("loans.activateLoan: " + err.Error())
+	}
+	return nil
+}
+
+// deactivateLoan hands a loan whose disbursal failed back to approved and drops its schedule
+func deactivateLoan(id int64) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("loans.deactivateLoan: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM loan_instalments WHERE `loanId` = ?", id)
+	if err != nil {
+		return errors.New("loans.deactivateLoan: " + err.Error())
+	}
+	_, err = tx.Exec("UPDATE loans SET `status` = ?, `disbursedAt` = NULL WHERE `id` = ? AND `status` = ?", LoanApproved, id, LoanActive)
+	if err != nil {
+		return errors.New("loans.deactivateLoan: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("loans.deactivateLoan: " + err.Error())
+	}
+	return nil
+}
+
+// closeLoan moves a loan from one status to a closed one, failing if it isn't in the from status
+func closeLoan(id int64, from string, to string, reason string, actor string) (err error) {
+	updateStatement := "UPDATE loans SET `status` = ?, `reason` = IF(? = '', `reason`, ?), `closedBy` = ?, `closedAt` = CURDATE() WHERE `id` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("loans.closeLoan: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, reason, reason, actor, id, from)
+	if err != nil {
+		return errors.New("loans.closeLoan: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("loans.closeLoan: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("loans.closeLoan: Loan is no longer " + from)
+	}
+	return nil
+}
+
+func cancelApplications(accountNumber string, reason string, actor string) (cancelled int64, err error) {
+	res, err := Config.Db.Exec("UPDATE loans SET `status` = ?, `reason` = ?, `closedBy` = ?, `closedAt` = CURDATE() WHERE `accountNumber` = ? AND `status` = ?",
+		LoanCancelled, reason, actor, accountNumber, LoanApplied)
+	if err != nil {
+		return 0, errors.New("loans.cancelApplications: " + err.Error())
+	}
+	cancelled, err = res.RowsAffected()
+	if err != nil {
+		return 0, errors.New("loans.cancelApplications: " + err.Error())
+	}
+	return cancelled, nil
+}
+
+func getLoan(loanNumber string) (loan Loan, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+loanColumns+" FROM `loans` WHERE `loanNumber` = ?", loanNumber)
+	if err != nil {
+		return Loan{}, false, errors.New("loans.getLoan: " + err.Error())
+	}
+	defer rows.Close()
+
+	matches, err := scanLoans(rows)
+	if err != nil {
+		return Loan{}, false, errors.New("loans.getLoan: " + err.Error())
+	}
+	if len(matches) == 0 {
+		return Loan{}, false, nil
+	}
+
+	return matches[0], true, nil
+}
+
+func getAccountLoans(accountNumber string) (accountLoans []Loan, err error) {
+	rows, err := Config.Db.Query("SELECT "+loanColumns+" FROM `loans` WHERE `accountNumber` = ? ORDER BY `id` DESC", accountNumber)
+	if err != nil {
+		return nil, errors.New("loans.getAccountLoans: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanLoans(rows)
+}
+
+func getLoansByStatus(status string) (statusLoans []Loan, err error) {
+	rows, err := Config.Db.Query("SELECT "+loanColumns+" FROM `loans` WHERE `status` = ? ORDER BY `id`", status)
+	if err != nil {
+		return nil, errors.New("loans.getLoansByStatus: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanLoans(rows)
+}
+
+// countAccountLoans counts an account's loans in any of the statuses
+func countAccountLoans(accountNumber string, statuses ...string) (count int, err error) {
+	query := "SELECT COUNT(*) FROM `loans` WHERE `accountNumber` = ? AND `status` IN (?" + repeatPlaceholders(len(statuses)-1) + ")"
+	args := []interface{}{accountNumber}
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+
+	err = Config.Db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.New("loans.countAccountLoans: " + err.Error())
+	}
+	return count, nil
+}
+
+func repeatPlaceholders(n int) string {
+	placeholders := ""
+	for i := 0; i < n; i++ {
+		placeholders += ", ?"
+	}
+	return placeholders
+}
+
+func scanLoans(rows *sql.Rows) (scanned []Loan, err error) {
+	scanned = make([]Loan, 0)
+	for rows.Next() {
+		var loan Loan
+		var timestamp string
+		if err := rows.Scan(&loan.ID, &loan.LoanNumber, &loan.AccountNumber, &loan.CurrencyCode, &loan.RequestedAmount, &loan.RequestedTenorMonths, &loan.Purpose,
+			&loan.Amount, &loan.Rate, &loan.TenorMonths, &loan.Method, &loan.LateFee, &loan.Status, &loan.Reason, &loan.Applicant, &loan.Approver, &loan.ClosedBy,
+			&loan.ApprovedAt, &loan.DisbursedAt, &loan.ClosedAt, &timestamp); err != nil {
+			return nil, errors.New("loans.scanLoans: " + err.Error())
+		}
+		loan.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("loans.scanLoans: " + err.Error())
+		}
+		scanned = append(scanned, loan)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("loans.scanLoans: " + err.Error())
+	}
+
+	return scanned, nil
+}
+
+func getInstalments(loanID int64) (instalments []Instalment, err error) {
+	rows, err := Config.Db.Query("SELECT "+instalmentColumns+" FROM `loan_instalments` WHERE `loanId` = ? ORDER BY `instalmentNumber`", loanID)
+	if err != nil {
+		return nil, errors.New("loans.getInstalments: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanInstalments(rows)
+}
+
+func scanInstalments(rows *sql.Rows) (instalments []Instalment, err error) {
+	instalments = make([]Instalment, 0)
+	for rows.Next() {
+		var instalment Instalment
+		if err := rows.Scan(&instalment.ID, &instalment.LoanID, &instalment.Number, &instalment.DueDate, &instalment.PrincipalDue, &instalment.InterestDue, &instalment.FeeDue,
+			&instalment.PrincipalPaid, &instalment.InterestPaid, &instalment.FeePaid, &instalment.Status, &instalment.PaidAt); err != nil {
+			return nil, errors.New("loans.scanInstalments: " + err.Error())
+		}
+		instalments = append(instalments, instalment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("loans.scanInstalments: " + err.Error())
+	}
+
+	return instalments, nil
+}
+
+// chargeLateFees charges the late fee on a loan's unpaid instalments due before a date that
+// haven't been charged one yet
+func chargeLateFees(loanID int64, lateFee decimal.Decimal, dueBefore string) (charged int64, err error) {
+	res, err := Config.Db.Exec("UPDATE loan_instalments SET `feeDue` = ? WHERE `loanId` = ? AND `status` = ? AND `dueDate` < ? AND `feeDue` = 0",
+		lateFee, loanID, InstalmentPending, dueBefore)
+	if err != nil {
+		return 0, errors.New("loans.chargeLateFees: " + err.Error())
+	}
+	charged, err = res.RowsAffected()
+	if err != nil {
+		return 0, errors.New("loans.chargeLateFees: " + err.Error())
+	}
+	return charged, nil
+}
+
+// availableBalance is what the account can pay without going into its overdraft
+func availableBalance(accountNumber string) (balance decimal.Decimal, err error) {
+	err = Config.Db.QueryRow("SELECT `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&balance)
+	if err != nil {
+		return decimal.Zero, errors.New("loans.availableBalance: " + err.Error())
+	}
+	return balance, nil
+}
+
+func saveRepayment(repayment Repayment) (id int64, err error) {
+	insertStatement := "INSERT INTO loan_repayments (`loanId`, `amount`, `principal`, `interest`, `fee`, `repaymentDate`, `source`, `status`, `actor`) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("loans.saveRepayment: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(repayment.LoanID, repayment.Amount, repayment.Principal, repayment.Interest, repayment.Fee, repayment.RepaymentDate, repayment.Source, repayment.Status, repayment.Actor)
+	if err != nil {
+		return 0, errors.New("loans.saveRepayment: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("loans.saveRepayment: " + err.Error())
+	}
+	return id, nil
+}
+
+// deleteRepayment removes a repayment that was never collected
+func deleteRepayment(id int64) (err error) {
+	_, err = Config.Db.Exec("DELETE FROM loan_repayments WHERE `id` = ? AND `collected` = 0", id)
+	if err != nil {
+		return errors.New("loans.deleteRepayment: " + err.Error())
+	}
+	return nil
+}
+
+func getPendingRepayment(loanID int64) (repayment Repayment, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+repaymentColumns+" FROM `loan_repayments` WHERE `loanId` = ? AND `status` = ? ORDER BY `id` LIMIT 1", loanID, RepaymentPending)
+	if err != nil {
+		return Repayment{}, false, errors.New("loans.getPendingRepayment: " + err.Error())
+	}
+	defer rows.Close()
+
+	repayments, err := scanRepayments(rows)
+	if err != nil {
+		return Repayment{}, false, errors.New("loans.getPendingRepayment: " + err.Error())
+	}
+	if len(repayments) == 0 {
+		return Repayment{}, false, nil
+	}
+
+	return repayments[0], true, nil
+}
+
+func getRepayments(loanID int64) (repayments []Repayment, err error) {
+	rows, err := Config.Db.Query("SELECT "+repaymentColumns+" FROM `loan_repayments` WHERE `loanId` = ? ORDER BY `id` DESC", loanID)
+	if err != nil {
+		return nil, errors.New("loans.getRepayments: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanRepayments(rows)
+}
+
+func scanRepayments(rows *sql.Rows) (repayments []Repayment, err error) {
+	repayments = make([]Repayment, 0)
+	for rows.Next() {
+		var repayment Repayment
+		var timestamp string
+		if err := rows.Scan(&repayment.ID, &repayment.LoanID, &repayment.Amount, &repayment.Principal, &repayment.Interest, &repayment.Fee, &repayment.RepaymentDate,
+			&repayment.Source, &repayment.Collected, &repayment.InterestPosted, &repayment.FeePosted, &repayment.Status, &repayment.Actor, &timestamp); err != nil {
+			return nil, errors.New("loans.scanRepayments: " + err.Error())
+		}
+		repayment.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("loans.scanRepayments: " + err.Error())
+		}
+		repayments = append(repayments, repayment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("loans.scanRepayments: " + err.Error())
+	}
+
+	return repayments, nil
+}
+
+// updateRepaymentFlag marks one of a pending repayment's postings as made
+func updateRepaymentFlag(id int64, flag string) (err error) {
+	switch flag {
+	case repaymentCollected, repaymentInterestPosted, repaymentFeePosted:
+	default:
+		return errors.New("loans.updateRepaymentFlag: Unknown flag " + flag)
+	}
+
+	res, err := Config.Db.Exec("UPDATE loan_repayments SET `"+flag+"` = 1 WHERE `id` = ? AND `status` = ? AND `"+flag+"` = 0", id, RepaymentPending)
+	if err != nil {
+		return errors.New("loans.updateRepaymentFlag: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("loans.updateRepaymentFlag: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("loans.updateRepaymentFlag: Repayment was already " + flag)
+	}
+	return nil
+}
+
+// applyRepayment pays a collected repayment into the loan's unpaid instalments and marks it
+// posted. The loan is repaid once nothing is left unpaid.
+func applyRepayment(repayment Repayment, date string) (repaid bool, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT "+instalmentColumns+" FROM `loan_instalments` WHERE `loanId` = ? AND `status` = ? ORDER BY `instalmentNumber` FOR UPDATE", repayment.LoanID, InstalmentPending)
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+	instalments, err := scanInstalments(rows)
+	rows.Close()
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+
+	for _, instalment := range applyAllocation(instalments, repayment.Principal, repayment.Interest, repayment.Fee, date) {
+		_, err = tx.Exec("UPDATE loan_instalments SET `principalPaid` = ?, `interestPaid` = ?, `feePaid` = ?, `status` = ?, `paidAt` = NULLIF(?, '') WHERE `id` = ?",
+			instalment.PrincipalPaid, instalment.InterestPaid, instalment.FeePaid, instalment.Status, instalment.PaidAt, instalment.ID)
+		if err != nil {
+			return false, errors.New("loans.applyRepayment: " + err.Error())
+		}
+	}
+
+	res, err := tx.Exec("UPDATE loan_repayments SET `status` = ? WHERE `id` = ? AND `status` = ?", RepaymentPosted, repayment.ID, RepaymentPending)
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+	if affected == 0 {
+		return false, errors.New("loans.applyRepayment: Repayment was already posted")
+	}
+
+	var unpaid int
+	err = tx.QueryRow("SELECT COUNT(*) FROM `loan_instalments` WHERE `loanId` = ? AND `status` = ?", repayment.LoanID, InstalmentPending).Scan(&unpaid)
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+	if unpaid == 0 {
+		_, err = tx.Exec("UPDATE loans SET `status` = ?, `closedAt` = CURDATE() WHERE `id` = ? AND `status` = ?", LoanRepaid, repayment.LoanID, LoanActive)
+		if err != nil {
+			return false, errors.New("loans.applyRepayment: " + err.Error())
+		}
+		repaid = true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, errors.New("loans.applyRepayment: " + err.Error())
+	}
+	return repaid, nil
+}
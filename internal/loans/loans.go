@@ -0,0 +1,970 @@
+package loans
+
+/*
+Loans
+
+A customer applies for a loan on one of their accounts with the amount and tenor (months)
+they'd like. The bank approves it with an amount, an annual rate, a tenor, a repayment method
+and a late fee, or declines it. An approved loan is disbursed straight away from the loan
+portfolio account for the currency (LOAN_PORTFOLIO_ACCOUNT_NUMBER_<CODE>) to the account with
+a PAIN 1001 posting. A loan that couldn't be disbursed stays approved and can be disbursed
+again.
+
+	applied  -> approved | declined | cancelled
+	approved -> active | cancelled
+	active   -> repaid
+
+The amortisation schedule is fixed when the loan is disbursed, one instalment a month from
+the disbursal date:
+
+	reducing_balance  equal instalments, each month's interest is balance x rate / 12
+	flat              equal principal and interest, each month's interest is amount x rate / 12
+
+Amounts are rounded to the currency's minor units, the last instalment takes whatever
+principal is left.
+
+Instalments are collected from the account when they fall due, as much as its available
+balance can pay without going into its overdraft, with a PAIN 1001 posting to the portfolio account. The interest and late fees in it are then moved
+to LOAN_INTEREST_ACCOUNT_NUMBER_<CODE> and LOAN_FEES_ACCOUNT_NUMBER_<CODE>. A repayment is
+recorded before it's collected and each posting is flagged as it's made, so a repayment that
+fails part way is finished by the next run without posting anything twice.
+
+Repayments pay late fees first, then interest, then principal, oldest instalment first. An
+instalment unpaid after its due date is in arrears, and once it's more than
+LATE_FEE_GRACE_DAYS overdue it is charged the loan's late fee, once. The customer can also
+pay ahead of the collection run, up to the next instalment to fall due. Later instalments
+can't be paid early, since their scheduled interest is for months the money is still lent.
+Repayments are made one at a time, so a customer's repayment and the collection run can't
+both pay the same instalments.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	LoanApplied   = "applied"
+	LoanApproved  = "approved"
+	LoanDeclined  = "declined"
+	LoanCancelled = "cancelled"
+	LoanActive    = "active"
+	LoanRepaid    = "repaid"
+
+	MethodReducingBalance = "reducing_balance"
+	MethodFlat            = "flat"
+
+	InstalmentPending = "pending"
+	InstalmentPaid    = "paid"
+
+	RepaymentPending = "pending"
+	RepaymentPosted  = "posted"
+
+	SourceAuto   = "auto"
+	SourceManual = "manual"
+
+	MAX_TENOR_MONTHS       = 60
+	LATE_FEE_GRACE_DAYS    = 3
+	DATE_LAYOUT            = "2006-01-02"
+	SYSTEM_ACTOR           = "system"
+	portfolioAccountPrefix = "LOAN_PORTFOLIO_ACCOUNT_NUMBER_"
+	interestAccountPrefix  = "LOAN_INTEREST_ACCOUNT_NUMBER_"
+	feesAccountPrefix      = "LOAN_FEES_ACCOUNT_NUMBER_"
+)
+
+var Config configuration.Configuration
+
+// repaymentMutex is held from reading a loan's instalments until the repayment is posted, so
+// two repayments at once can't both be allocated to what is owed
+var repaymentMutex sync.Mutex
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Loan is a loan applied for on an account, with its approved terms
+type Loan struct {
+	ID                   int64           `json:"id"`
+	LoanNumber           string          `json:"loanNumber"`
+	AccountNumber        string          `json:"accountNumber"`
+	CurrencyCode         string          `json:"currencyCode"`
+	RequestedAmount      decimal.Decimal `json:"requestedAmount"`
+	RequestedTenorMonths int             `json:"requestedTenorMonths"`
+	Purpose              string          `json:"purpose,omitempty"`
+	Amount               decimal.Decimal `json:"amount"`
+	Rate                 decimal.Decimal `json:"rate"`
+	TenorMonths          int             `json:"tenorMonths"`
+	Method               string          `json:"method"`
+	LateFee              decimal.Decimal `json:"lateFee"`
+	Status               string          `json:"status"`
+	Reason               string          `json:"reason,omitempty"`
+	Applicant            string          `json:"applicant"`
+	Approver             string          `json:"approver,omitempty"`
+	ClosedBy             string          `json:"closedBy,omitempty"`
+	ApprovedAt           string          `json:"approvedAt,omitempty"`
+	DisbursedAt          string          `json:"disbursedAt,omitempty"`
+	ClosedAt             string          `json:"closedAt,omitempty"`
+	Timestamp            time.Time       `json:"timestamp"`
+}
+
+// Instalment is one month of a loan's amortisation schedule and what has been paid of it
+type Instalment struct {
+	ID            int64           `json:"id"`
+	LoanID        int64           `json:"loanId"`
+	Number        int             `json:"number"`
+	DueDate       string          `json:"dueDate"`
+	PrincipalDue  decimal.Decimal `json:"principalDue"`
+	InterestDue   decimal.Decimal `json:"interestDue"`
+	FeeDue        decimal.Decimal `json:"feeDue"`
+	PrincipalPaid decimal.Decimal `json:"principalPaid"`
+	InterestPaid  decimal.Decimal `json:"interestPaid"`
+	FeePaid       decimal.Decimal `json:"feePaid"`
+	Status        string          `json:"status"`
+	Overdue       bool            `json:"overdue"`
+	PaidAt        string          `json:"paidAt,omitempty"`
+}
+
+// Repayment is an amount taken from the account towards a loan and how it was split
+type Repayment struct {
+	ID             int64           `json:"id"`
+	LoanID         int64           `json:"loanId"`
+	Amount         decimal.Decimal `json:"amount"`
+	Principal      decimal.Decimal `json:"principal"`
+	Interest       decimal.Decimal `json:"interest"`
+	Fee            decimal.Decimal `json:"fee"`
+	RepaymentDate  string          `json:"repaymentDate"`
+	Source         string          `json:"source"`
+	Collected      bool            `json:"-"`
+	InterestPosted bool            `json:"-"`
+	FeePosted      bool            `json:"-"`
+	Status         string          `json:"status"`
+	Actor          string          `json:"actor"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// Statement is a loan with its schedule, its repayments and what is owed on a date
+type Statement struct {
+	Date                 string          `json:"date"`
+	Loan                 Loan            `json:"loan"`
+	PrincipalOutstanding decimal.Decimal `json:"principalOutstanding"`
+	InterestOutstanding  decimal.Decimal `json:"interestOutstanding"`
+	FeesOutstanding      decimal.Decimal `json:"feesOutstanding"`
+	TotalOutstanding     decimal.Decimal `json:"totalOutstanding"`
+	ArrearsAmount        decimal.Decimal `json:"arrearsAmount"`
+	DaysInArrears        int             `json:"daysInArrears"`
+	NextDueDate          string          `json:"nextDueDate,omitempty"`
+	NextDueAmount        decimal.Decimal `json:"nextDueAmount"`
+	Instalments          []Instalment    `json:"instalments"`
+	Repayments           []Repayment     `json:"repayments"`
+}
+
+// Run is what a repayment run did
+type Run struct {
+	Collected int `json:"collected"`
+	LateFees  int `json:"lateFees"`
+	Repaid    int `json:"repaid"`
+}
+
+// Outstanding is what is left to pay of the instalment
+func (i Instalment) Outstanding() decimal.Decimal {
+	return i.PrincipalDue.Sub(i.PrincipalPaid).Add(i.InterestDue.Sub(i.InterestPaid)).Add(i.FeeDue.Sub(i.FeePaid))
+}
+
+// FormatLoanNumber is the customer-facing number of a loan
+func FormatLoanNumber(id int64) string {
+	return fmt.Sprintf("LN%08d", id)
+}
+
+// ValidMethod reports whether method is a repayment method
+func ValidMethod(method string) bool {
+	switch method {
+	case MethodReducingBalance, MethodFlat:
+		return true
+	}
+	return false
+}
+
+// Apply asks for a loan of amount over tenorMonths to be paid into an account
+func Apply(accountNumber string, amount string, tenorMonths int, purpose string, applicant string) (Loan, error) {
+	loan := Loan{
+		AccountNumber:        strings.TrimSpace(accountNumber),
+		RequestedTenorMonths: tenorMonths,
+		Purpose:              strings.TrimSpace(purpose),
+		Method:               MethodReducingBalance,
+		Status:               LoanApplied,
+		Applicant:            applicant,
+	}
+
+	var err error
+	loan.CurrencyCode, err = payments.AccountCurrency(loan.AccountNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Apply: " + err.Error())
+	}
+	active, err := payments.CheckIfAccountIsActive(loan.AccountNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Apply: " + err.Error())
+	}
+	if !active {
+		return Loan{}, errors.New("loans.Apply: Account " + loan.AccountNumber + " is not active")
+	}
+	loan.RequestedAmount, err = parseAmount(loan.CurrencyCode, "Amount", amount)
+	if err != nil {
+		return Loan{}, errors.New("loans.Apply: " + err.Error())
+	}
+	err = validateTerms(loan.RequestedAmount, decimal.Zero, loan.RequestedTenorMonths, loan.Method)
+	if err != nil {
+		return Loan{}, errors.New("loans.Apply: " + err.Error())
+	}
+
+	applied, err := countAccountLoans(loan.AccountNumber, LoanApplied)
+	if err != nil {
+		return Loan{}, errors.New("loans.Apply: " + err.Error())
+	}
+	if applied > 0 {
+		return Loan{}, errors.New("loans.Apply: The account already has a loan application waiting for a decision")
+	}
+
+	loan.ID, err = saveLoan(loan)
+	if err != nil {
+		return Loan{}, errors.New("loans.Apply: " + err.Error())
+	}
+	loan.LoanNumber = FormatLoanNumber(loan.ID)
+	loan.Timestamp = time.Now()
+
+	return loan, nil
+}
+
+// Approve grants an application and disburses it. The amount and tenor default to what was
+// asked for and the method to reducing_balance. A loan approved but not disbursed can be
+// disbursed again with Disburse.
+func Approve(loanNumber string, amount string, rate string, tenorMonths int, method string, lateFee string, approver string) (Loan, error) {
+	loan, err := Get(loanNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Approve: " + err.Error())
+	}
+	if loan.Status != LoanApplied {
+		return Loan{}, errors.New("loans.Approve: Loan is " + loan.Status)
+	}
+
+	// Staff can't approve credit on their own accounts
+	holder, err := payments.IsAccountHolder(approver, loan.AccountNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Approve: " + err.Error())
+	}
+	if approver == "" || holder {
+		return Loan{}, errors.New("loans.Approve: Approver not valid")
+	}
+
+	loan.Amount = loan.RequestedAmount
+	if strings.TrimSpace(amount) != "" {
+		loan.Amount, err = parseAmount(loan.CurrencyCode, "Amount", amount)
+		if err != nil {
+			return Loan{}, errors.New("loans.Approve: " + err.Error())
+		}
+	}
+	loan.TenorMonths = loan.RequestedTenorMonths
+	if tenorMonths != 0 {
+		loan.TenorMonths = tenorMonths
+	}
+	loan.Method = MethodReducingBalance
+	if strings.TrimSpace(method) != "" {
+		loan.Method = strings.TrimSpace(method)
+	}
+	loan.Rate, err = decimal.NewFromString(strings.TrimSpace(rate))
+	if err != nil {
+		return Loan{}, errors.New("loans.Approve: Rate is not a valid number")
+	}
+	loan.LateFee, err = parseAmount(loan.CurrencyCode, "Late fee", lateFee)
+	if err != nil {
+		return Loan{}, errors.New("loans.Approve: " + err.Error())
+	}
+	err = validateTerms(loan.Amount, loan.Rate, loan.TenorMonths, loan.Method)
+	if err != nil {
+		return Loan{}, errors.New("loans.Approve: " + err.Error())
+	}
+	loan.Approver = approver
+
+	err = approveLoan(loan)
+	if err != nil {
+		return Loan{}, errors.New("loans.Approve: " + err.Error())
+	}
+	loan.Status = LoanApproved
+	loan.ApprovedAt = time.Now().Format(DATE_LAYOUT)
+
+	disbursed, err := disburse(loan, approver)
+	if err != nil {
+		return loan, errors.New("loans.Approve: Loan approved but not disbursed. " + err.Error())
+	}
+	return disbursed, nil
+}
+
+// Disburse pays an approved loan into its account and starts its schedule
+func Disburse(loanNumber string, actor string) (Loan, error) {
+	loan, err := Get(loanNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Disburse: " + err.Error())
+	}
+	if loan.Status != LoanApproved {
+		return Loan{}, errors.New("loans.Disburse: Loan is " + loan.Status)
+	}
+
+	loan, err = disburse(loan, actor)
+	if err != nil {
+		return Loan{}, errors.New("loans.Disburse: " + err.Error())
+	}
+	return loan, nil
+}
+
+// Decline turns down an application
+func Decline(loanNumber string, reason string, actor string) (Loan, error) {
+	loan, err := Get(loanNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Decline: " + err.Error())
+	}
+	if loan.Status != LoanApplied {
+		return Loan{}, errors.New("loans.Decline: Loan is " + loan.Status)
+	}
+
+	err = closeLoan(loan.ID, LoanApplied, LoanDeclined, strings.TrimSpace(reason), actor)
+	if err != nil {
+		return Loan{}, errors.New("loans.Decline: " + err.Error())
+	}
+	loan.Status = LoanDeclined
+	loan.Reason = strings.TrimSpace(reason)
+	loan.ClosedBy = actor
+	loan.ClosedAt = time.Now().Format(DATE_LAYOUT)
+
+	return loan, nil
+}
+
+// Cancel withdraws an application or an approved loan that hasn't been disbursed
+func Cancel(loanNumber string, reason string, actor string) (Loan, error) {
+	loan, err := Get(loanNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Cancel: " + err.Error())
+	}
+	if loan.Status != LoanApplied && loan.Status != LoanApproved {
+		return Loan{}, errors.New("loans.Cancel: Loan is " + loan.Status)
+	}
+
+	err = closeLoan(loan.ID, loan.Status, LoanCancelled, strings.TrimSpace(reason), actor)
+	if err != nil {
+		return Loan{}, errors.New("loans.Cancel: " + err.Error())
+	}
+	loan.Status = LoanCancelled
+	loan.Reason = strings.TrimSpace(reason)
+	loan.ClosedBy = actor
+	loan.ClosedAt = time.Now().Format(DATE_LAYOUT)
+
+	return loan, nil
+}
+
+// CancelAccountApplications cancels the loans applied for on an account that haven't been
+// decided, used when the account is closed
+func CancelAccountApplications(accountNumber string, actor string) (cancelled int64, err error) {
+	cancelled, err = cancelApplications(strings.TrimSpace(accountNumber), "Account closed", actor)
+	if err != nil {
+		return 0, errors.New("loans.CancelAccountApplications: " + err.Error())
+	}
+	return cancelled, nil
+}
+
+// Get returns a loan by its number
+func Get(loanNumber string) (Loan, error) {
+	loanNumber = strings.ToUpper(strings.TrimSpace(loanNumber))
+	loan, found, err := getLoan(loanNumber)
+	if err != nil {
+		return Loan{}, errors.New("loans.Get: " + err.Error())
+	}
+	if !found {
+		return Loan{}, errors.New("loans.Get: Loan " + loanNumber + " not found")
+	}
+	return loan, nil
+}
+
+// ForAccount lists the loans applied for on an account, the latest first
+func ForAccount(accountNumber string) ([]Loan, error) {
+	accountLoans, err := getAccountLoans(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return nil, errors.New("loans.ForAccount: " + err.Error())
+	}
+	return accountLoans, nil
+}
+
+// Loans lists the loans in a status, applications waiting for a decision when no status is given
+func Loans(status string) ([]Loan, error) {
+	status = strings.TrimSpace(status)
+	if status == "" {
+		status = LoanApplied
+	}
+	statusLoans, err := getLoansByStatus(status)
+	if err != nil {
+		return nil, errors.New("loans.Loans: " + err.Error())
+	}
+	return statusLoans, nil
+}
+
+// OutstandingForAccount counts the loans on an account that are approved or being repaid
+func OutstandingForAccount(accountNumber string) (int, error) {
+	count, err := countAccountLoans(strings.TrimSpace(accountNumber), LoanApproved, LoanActive)
+	if err != nil {
+		return 0, errors.New("loans.OutstandingForAccount: " + err.Error())
+	}
+	return count, nil
+}
+
+// LoanStatement returns a loan's schedule and repayments with what is owed and in arrears today
+func LoanStatement(loanNumber string) (Statement, error) {
+	loan, err := Get(loanNumber)
+	if err != nil {
+		return Statement{}, errors.New("loans.LoanStatement: " + err.Error())
+	}
+	instalments, err := getInstalments(loan.ID)
+	if err != nil {
+		return Statement{}, errors.New("loans.LoanStatement: " + err.Error())
+	}
+	repayments, err := getRepayments(loan.ID)
+	if err != nil {
+		return Statement{}, errors.New("loans.LoanStatement: " + err.Error())
+	}
+
+	return buildStatement(loan, instalments, repayments, time.Now().Format(DATE_LAYOUT)), nil
+}
+
+// Repay pays an amount off an active loan from its account, at most what is owed up to the
+// next instalment to fall due
+func Repay(loanNumber string, amount string, actor string) (Repayment, error) {
+	loan, err := Get(loanNumber)
+	if err != nil {
+		return Repayment{}, errors.New("loans.Repay: " + err.Error())
+	}
+	if loan.Status != LoanActive {
+		return Repayment{}, errors.New("loans.Repay: Loan is " + loan.Status)
+	}
+	err = payments.RequireSingleSignature(loan.AccountNumber)
+	if err != nil {
+		return Repayment{}, errors.New("loans.Repay: " + err.Error())
+	}
+	paid, err := parseAmount(loan.CurrencyCode, "Amount", amount)
+	if err != nil {
+		return Repayment{}, errors.New("loans.Repay: " + err.Error())
+	}
+	if !paid.IsPositive() {
+		return Repayment{}, errors.New("loans.Repay: Amount must be greater than zero")
+	}
+
+	repaymentMutex.Lock()
+	defer repaymentMutex.Unlock()
+
+	// A repayment left part way is finished first so the instalments are up to date
+	_, err = resumeRepayment(loan)
+	if err != nil {
+		return Repayment{}, errors.New("loans.Repay: " + err.Error())
+	}
+
+	instalments, err := getInstalments(loan.ID)
+	if err != nil {
+		return Repayment{}, errors.New("loans.Repay: " + err.Error())
+	}
+	payable := repayable(instalments, time.Now().Format(DATE_LAYOUT))
+	owed := decimal.Zero
+	for _, instalment := range payable {
+		owed = owed.Add(instalment.Outstanding())
+	}
+	if !owed.IsPositive() {
+		return Repayment{}, errors.New("loans.Repay: Nothing is owed on the loan")
+	}
+	if paid.GreaterThan(owed) {
+		return Repayment{}, errors.New("loans.Repay: Only " + currency.Format(loan.CurrencyCode, owed) + " can be repaid now, the instalments due up to " + payable[len(payable)-1].DueDate)
+	}
+
+	repayment, _, err := repay(loan, payable, paid, SourceManual, actor)
+	if err != nil {
+		return Repayment{}, errors.New("loans.Repay: " + err.Error())
+	}
+	return repayment, nil
+}
+
+// RunRepayments finishes repayments left part way, collects the instalments that have fallen
+// due on every active loan and charges late fees on instalments in arrears. One loan failing
+// doesn't stop the rest.
+func RunRepayments() (run Run, err error) {
+	now := time.Now()
+	today := now.Format(DATE_LAYOUT)
+	lateBefore := now.AddDate(0, 0, -LATE_FEE_GRACE_DAYS).Format(DATE_LAYOUT)
+
+	activeLoans, err := getLoansByStatus(LoanActive)
+	if err != nil {
+		return Run{}, errors.New("loans.RunRepayments: " + err.Error())
+	}
+
+	failures := []string{}
+	for _, loan := range activeLoans {
+		err = runRepayment(loan, today, lateBefore, &run)
+		if err != nil {
+			failures = append(failures, loan.LoanNumber+": "+err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return run, errors.New("loans.RunRepayments: " + strings.Join(failures, "; "))
+	}
+	return run, nil
+}
+
+// runRepayment finishes the loan's repayment left part way, collects what has fallen due and
+// charges late fees, holding the repayment lock so a customer's repayment can't run alongside
+func runRepayment(loan Loan, today string, lateBefore string, run *Run) error {
+	repaymentMutex.Lock()
+	defer repaymentMutex.Unlock()
+
+	repaid, err := resumeRepayment(loan)
+	if err != nil {
+		return err
+	}
+	if repaid {
+		run.Repaid++
+		return nil
+	}
+
+	collected, repaid, err := collectDue(loan, today)
+	if collected {
+		run.Collected++
+	}
+	if repaid {
+		run.Repaid++
+		return err
+	}
+
+	if loan.LateFee.IsPositive() {
+		charged, feeErr := chargeLateFees(loan.ID, loan.LateFee, lateBefore)
+		if feeErr != nil {
+			if err != nil {
+				return errors.New(err.Error() + "; " + feeErr.Error())
+			}
+			return feeErr
+		}
+		run.LateFees += int(charged)
+	}
+	return err
+}
+
+// Schedule is the amortisation schedule of a loan disbursed on start
+func Schedule(currencyCode string, method string, principal decimal.Decimal, rate decimal.Decimal, tenorMonths int, start time.Time) []Instalment {
+	monthlyRate := rate.Div(decimal.NewFromInt(1200))
+	instalments := make([]Instalment, 0, tenorMonths)
+	if tenorMonths <= 0 {
+		return instalments
+	}
+
+	flatPrincipal := currency.Round(currencyCode, principal.Div(decimal.NewFromInt(int64(tenorMonths))))
+	flatInterest := currency.Round(currencyCode, principal.Mul(monthlyRate))
+	payment := Payment(currencyCode, principal, rate, tenorMonths)
+
+	balance := principal
+	for number := 1; number <= tenorMonths; number++ {
+		instalment := Instalment{
+			Number:        number,
+			DueDate:       DueDate(start, number),
+			FeeDue:        decimal.Zero,
+			PrincipalPaid: decimal.Zero,
+			InterestPaid:  decimal.Zero,
+			FeePaid:       decimal.Zero,
+			Status:        InstalmentPending,
+		}
+		if method == MethodFlat {
+			instalment.InterestDue = flatInterest
+			instalment.PrincipalDue = flatPrincipal
+		} else {
+			instalment.InterestDue = currency.Round(currencyCode, balance.Mul(monthlyRate))
+			instalment.PrincipalDue = payment.Sub(instalment.InterestDue)
+		}
+		if number == tenorMonths || instalment.PrincipalDue.GreaterThan(balance) {
+			instalment.PrincipalDue = balance
+		}
+		balance = balance.Sub(instalment.PrincipalDue)
+		instalments = append(instalments, instalment)
+	}
+
+	return instalments
+}
+
+// Payment is the monthly instalment that pays off principal over tenorMonths at an annual
+// rate on a reducing balance, rounded to the currency's minor units
+func Payment(currencyCode string, principal decimal.Decimal, rate decimal.Decimal, tenorMonths int) decimal.Decimal {
+	months := decimal.NewFromInt(int64(tenorMonths))
+	monthlyRate := rate.Div(decimal.NewFromInt(1200))
+	if !monthlyRate.IsPositive() {
+		return currency.Round(currencyCode, principal.Div(months))
+	}
+
+	growth := decimal.NewFromInt(1)
+	for i := 0; i < tenorMonths; i++ {
+		growth = growth.Mul(decimal.NewFromInt(1).Add(monthlyRate))
+	}
+	return currency.Round(currencyCode, principal.Mul(monthlyRate).Mul(growth).Div(growth.Sub(decimal.NewFromInt(1))))
+}
+
+// DueDate is the date months after start, on the same day of the month or the month's last
+// day when it's shorter
+func DueDate(start time.Time, months int) string {
+	year, month, day := start.Date()
+	first := time.Date(year, month+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC).Format(DATE_LAYOUT)
+}
+
+// disburse claims an approved loan, saves its schedule and pays it out from the portfolio
+// account. If the payment fails the loan goes back to approved.
+func disburse(loan Loan, actor string) (Loan, error) {
+	portfolio, err := systemAccount(portfolioAccountPrefix, loan.CurrencyCode)
+	if err != nil {
+		return Loan{}, errors.New("loans.disburse: " + err.Error())
+	}
+
+	now := time.Now()
+	instalments := Schedule(loan.CurrencyCode, loan.Method, loan.Amount, loan.Rate, loan.TenorMonths, now)
+	err = activateLoan(loan.ID, instalments)
+	if err != nil {
+		return Loan{}, errors.New("loans.disburse: " + err.Error())
+	}
+
+	_, err = payments.ProcessPAIN([]string{"", "pain", "1001", portfolio + "@", loan.AccountNumber + "@", loan.Amount.String(), "Loan " + loan.LoanNumber + " disbursement", actor})
+	if err != nil {
+		if revertErr := deactivateLoan(loan.ID); revertErr != nil {
+			return Loan{}, errors.New("loans.disburse: " + err.Error() + ". " + revertErr.Error())
+		}
+		return Loan{}, errors.New("loans.disburse: " + err.Error())
+	}
+
+	loan.Status = LoanActive
+	loan.DisbursedAt = now.Format(DATE_LAYOUT)
+	return loan, nil
+}
+
+// collectDue takes what the account can pay of the instalments due on or before today
+func collectDue(loan Loan, today string) (collected bool, repaid bool, err error) {
+	instalments, err := getInstalments(loan.ID)
+	if err != nil {
+		return false, false, errors.New("loans.collectDue: " + err.Error())
+	}
+	due := decimal.Zero
+	dueInstalments := make([]Instalment, 0)
+	for _, instalment := range instalments {
+		if instalment.Status == InstalmentPending && instalment.DueDate <= today {
+			due = due.Add(instalment.Outstanding())
+			dueInstalments = append(dueInstalments, instalment)
+		}
+	}
+	if !due.IsPositive() {
+		return false, false, nil
+	}
+
+	available, err := availableBalance(loan.AccountNumber)
+	if err != nil {
+		return false, false, errors.New("loans.collectDue: " + err.Error())
+	}
+	amount := decimal.Min(due, currency.Round(loan.CurrencyCode, available))
+	if !amount.IsPositive() {
+		return false, false, nil
+	}
+
+	_, repaid, err = repay(loan, dueInstalments, amount, SourceAuto, SYSTEM_ACTOR)
+	if err != nil {
+		return false, false, errors.New("loans.collectDue: " + err.Error())
+	}
+	return true, repaid, nil
+}
+
+// repayable are the unpaid instalments that have fallen due by today and the next one after
+// them, oldest first
+func repayable(instalments []Instalment, today string) []Instalment {
+	payable := make([]Instalment, 0)
+	for _, instalment := range instalments {
+		if instalment.Status != InstalmentPending {
+			continue
+		}
+		payable = append(payable, instalment)
+		if instalment.DueDate > today {
+			break
+		}
+	}
+	return payable
+}
+
+// repay splits amount over the instalments, records the repayment and posts it
+func repay(loan Loan, instalments []Instalment, amount decimal.Decimal, source string, actor string) (Repayment, bool, error) {
+	repayment := Repayment{
+		LoanID:        loan.ID,
+		Amount:        amount,
+		RepaymentDate: time.Now().Format(DATE_LAYOUT),
+		Source:        source,
+		Status:        RepaymentPending,
+		Actor:         actor,
+	}
+	repayment.Principal, repayment.Interest, repayment.Fee = allocate(instalments, amount)
+
+	var err error
+	repayment.ID, err = saveRepayment(repayment)
+	if err != nil {
+		return Repayment{}, false, errors.New("loans.repay: " + err.Error())
+	}
+
+	repaid, err := postRepayment(&repayment, loan)
+	if err != nil {
+		return Repayment{}, false, errors.New("loans.repay: " + err.Error())
+	}
+	return repayment, repaid, nil
+}
+
+// resumeRepayment finishes the loan's repayment that failed part way, if there is one
+func resumeRepayment(loan Loan) (repaid bool, err error) {
+	repayment, found, err := getPendingRepayment(loan.ID)
+	if err != nil {
+		return false, errors.New("loans.resumeRepayment: " + err.Error())
+	}
+	if !found {
+		return false, nil
+	}
+
+	repaid, err = postRepayment(&repayment, loan)
+	if err != nil {
+		return false, errors.New("loans.resumeRepayment: " + err.Error())
+	}
+	return repaid, nil
+}
+
+// postRepayment collects a repayment from the account into the portfolio account, moves the
+// interest and fees in it to the income accounts and pays the instalments. Each step is
+// flagged as it's done so it is never posted twice. A repayment that couldn't be collected
+// is dropped.
+func postRepayment(repayment *Repayment, loan Loan) (repaid bool, err error) {
+	portfolio, err := systemAccount(portfolioAccountPrefix, loan.CurrencyCode)
+	if err != nil {
+		return false, errors.New("loans.postRepayment: " + err.Error())
+	}
+
+	if !repayment.Collected {
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", loan.AccountNumber + "@", portfolio + "@", repayment.Amount.String(), "Loan " + loan.LoanNumber + " repayment", repayment.Actor})
+		if err != nil {
+			if deleteErr := deleteRepayment(repayment.ID); deleteErr != nil {
+				return false, errors.New("loans.postRepayment: " + err.Error() + ". " + deleteErr.Error())
+			}
+			return false, errors.New("loans.postRepayment: " + err.Error())
+		}
+		err = updateRepaymentFlag(repayment.ID, repaymentCollected)
+		if err != nil {
+			return false, errors.New("loans.postRepayment: Repayment collected but not recorded. " + err.Error())
+		}
+		repayment.Collected = true
+	}
+
+	if repayment.Interest.IsPositive() && !repayment.InterestPosted {
+		interestAccount, err := systemAccount(interestAccountPrefix, loan.CurrencyCode)
+		if err != nil {
+			return false, errors.New("loans.postRepayment: " + err.Error())
+		}
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", portfolio + "@", interestAccount + "@", repayment.Interest.String(), "Loan " + loan.LoanNumber + " interest", repayment.Actor})
+		if err != nil {
+			return false, errors.New("loans.postRepayment: " + err.Error())
+		}
+		err = updateRepaymentFlag(repayment.ID, repaymentInterestPosted)
+		if err != nil {
+			return false, errors.New("loans.postRepayment: Interest posted but not recorded. " + err.Error())
+		}
+		repayment.InterestPosted = true
+	}
+
+	if repayment.Fee.IsPositive() && !repayment.FeePosted {
+		feesAccount, err := systemAccount(feesAccountPrefix, loan.CurrencyCode)
+		if err != nil {
+			return false, errors.New("loans.postRepayment: " + err.Error())
+		}
+		_, err = payments.ProcessPAIN([]string{"", "pain", "1001", portfolio + "@", feesAccount + "@", repayment.Fee.String(), "Loan " + loan.LoanNumber + " late fees", repayment.Actor})
+		if err != nil {
+			return false, errors.New("loans.postRepayment: " + err.Error())
+		}
+		err = updateRepaymentFlag(repayment.ID, repaymentFeePosted)
+		if err != nil {
+			return false, errors.New("loans.postRepayment: Fees posted but not recorded. " + err.Error())
+		}
+		repayment.FeePosted = true
+	}
+
+	repaid, err = applyRepayment(*repayment, time.Now().Format(DATE_LAYOUT))
+	if err != nil {
+		return false, errors.New("loans.postRepayment: " + err.Error())
+	}
+	repayment.Status = RepaymentPosted
+	return repaid, nil
+}
+
+// allocate splits amount over the instalments in order, each one's fee first, then its
+// interest, then its principal
+func allocate(instalments []Instalment, amount decimal.Decimal) (principal decimal.Decimal, interest decimal.Decimal, fee decimal.Decimal) {
+	principal, interest, fee = decimal.Zero, decimal.Zero, decimal.Zero
+	remaining := amount
+	for _, instalment := range instalments {
+		take := decimal.Min(remaining, instalment.FeeDue.Sub(instalment.FeePaid))
+		fee = fee.Add(take)
+		remaining = remaining.Sub(take)
+
+		take = decimal.Min(remaining, instalment.InterestDue.Sub(instalment.InterestPaid))
+		interest = interest.Add(take)
+		remaining = remaining.Sub(take)
+
+		take = decimal.Min(remaining, instalment.PrincipalDue.Sub(instalment.PrincipalPaid))
+		principal = principal.Add(take)
+		remaining = remaining.Sub(take)
+
+		if !remaining.IsPositive() {
+			break
+		}
+	}
+	return principal, interest, fee
+}
+
+// applyAllocation pays a repayment's principal, interest and fee into the instalments,
+// oldest first, and returns the instalments that changed. Splitting it this way lands each
+// amount where allocate took it from.
+func applyAllocation(instalments []Instalment, principal decimal.Decimal, interest decimal.Decimal, fee decimal.Decimal, date string) []Instalment {
+	changed := make(map[int]bool)
+	for i := range instalments {
+		take := decimal.Min(fee, instalments[i].FeeDue.Sub(instalments[i].FeePaid))
+		if take.IsPositive() {
+			instalments[i].FeePaid = instalments[i].FeePaid.Add(take)
+			fee = fee.Sub(take)
+			changed[i] = true
+		}
+		take = decimal.Min(interest, instalments[i].InterestDue.Sub(instalments[i].InterestPaid))
+		if take.IsPositive() {
+			instalments[i].InterestPaid = instalments[i].InterestPaid.Add(take)
+			interest = interest.Sub(take)
+			changed[i] = true
+		}
+		take = decimal.Min(principal, instalments[i].PrincipalDue.Sub(instalments[i].PrincipalPaid))
+		if take.IsPositive() {
+			instalments[i].PrincipalPaid = instalments[i].PrincipalPaid.Add(take)
+			principal = principal.Sub(take)
+			changed[i] = true
+		}
+	}
+
+	updated := make([]Instalment, 0, len(changed))
+	for i := range instalments {
+		if !changed[i] {
+			continue
+		}
+		if !instalments[i].Outstanding().IsPositive() {
+			instalments[i].Status = InstalmentPaid
+			instalments[i].PaidAt = date
+		}
+		updated = append(updated, instalments[i])
+	}
+	return updated
+}
+
+// buildStatement totals what is owed on a loan on date and marks the instalments in arrears
+func buildStatement(loan Loan, instalments []Instalment, repayments []Repayment, date string) Statement {
+	statement := Statement{
+		Date:                 date,
+		Loan:                 loan,
+		PrincipalOutstanding: decimal.Zero,
+		InterestOutstanding:  decimal.Zero,
+		FeesOutstanding:      decimal.Zero,
+		TotalOutstanding:     decimal.Zero,
+		ArrearsAmount:        decimal.Zero,
+		NextDueAmount:        decimal.Zero,
+		Instalments:          instalments,
+		Repayments:           repayments,
+	}
+
+	oldestOverdue := ""
+	for i, instalment := range statement.Instalments {
+		if instalment.Status != InstalmentPending {
+			continue
+		}
+		statement.PrincipalOutstanding = statement.PrincipalOutstanding.Add(instalment.PrincipalDue.Sub(instalment.PrincipalPaid))
+		statement.InterestOutstanding = statement.InterestOutstanding.Add(instalment.InterestDue.Sub(instalment.InterestPaid))
+		statement.FeesOutstanding = statement.FeesOutstanding.Add(instalment.FeeDue.Sub(instalment.FeePaid))
+
+		if instalment.DueDate < date {
+			statement.Instalments[i].Overdue = true
+			statement.ArrearsAmount = statement.ArrearsAmount.Add(instalment.Outstanding())
+			if oldestOverdue == "" {
+				oldestOverdue = instalment.DueDate
+			}
+		} else if statement.NextDueDate == "" {
+			statement.NextDueDate = instalment.DueDate
+			statement.NextDueAmount = instalment.Outstanding()
+		}
+	}
+	statement.TotalOutstanding = statement.PrincipalOutstanding.Add(statement.InterestOutstanding).Add(statement.FeesOutstanding)
+
+	if oldestOverdue != "" {
+		due, dueErr := time.Parse(DATE_LAYOUT, oldestOverdue)
+		on, onErr := time.Parse(DATE_LAYOUT, date)
+		if dueErr == nil && onErr == nil {
+			statement.DaysInArrears = int(on.Sub(due).Hours() / 24)
+		}
+	}
+
+	return statement
+}
+
+// validateTerms checks a loan's amount, rate, tenor and method
+func validateTerms(amount decimal.Decimal, rate decimal.Decimal, tenorMonths int, method string) error {
+	if !amount.IsPositive() {
+		return errors.New("loans.validateTerms: Amount must be greater than zero")
+	}
+	if rate.IsNegative() || rate.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("loans.validateTerms: Rate must be between 0 and 100 percent")
+	}
+	if tenorMonths < 1 || tenorMonths > MAX_TENOR_MONTHS {
+		return errors.New("loans.validateTerms: Tenor must be between 1 and " + strconv.Itoa(MAX_TENOR_MONTHS) + " months")
+	}
+	if !ValidMethod(method) {
+		return errors.New("loans.validateTerms: Method must be reducing_balance or flat")
+	}
+	return nil
+}
+
+// parseAmount reads an optional amount, empty is zero. It must fit the currency's minor units.
+func parseAmount(currencyCode string, field string, value string) (decimal.Decimal, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, errors.New("loans.parseAmount: " + field + " is not a valid amount")
+	}
+	if amount.IsNegative() {
+		return decimal.Zero, errors.New("loans.parseAmount: " + field + " must not be negative")
+	}
+	if !currency.Round(currencyCode, amount).Equal(amount) {
+		return decimal.Zero, errors.New("loans.parseAmount: " + field + " has more decimal places than " + currencyCode + " allows")
+	}
+	return amount, nil
+}
+
+func systemAccount(prefix string, currencyCode string) (string, error) {
+	accountNumber := strings.TrimSpace(os.Getenv(prefix + currencyCode))
+	if accountNumber == "" {
+		return "", errors.New("loans.systemAccount: " + prefix + currencyCode + " is not configured")
+	}
+	return accountNumber, nil
+}
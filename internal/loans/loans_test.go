@@ -0,0 +1,190 @@
+package loans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPayment(t *testing.T) {
+	cases := []struct {
+		principal string
+		rate      string
+		months    int
+		payment   string
+	}{
+		{"10000", "12", 12, "888.49"},
+		{"500000", "24", 6, "89262.91"},
+		{"1200", "0", 12, "100"},
+	}
+	for _, c := range cases {
+		payment := Payment("NGN", decimal.RequireFromString(c.principal), decimal.RequireFromString(c.rate), c.months)
+		if !payment.Equal(decimal.RequireFromString(c.payment)) {
+			t.Errorf("Payment does not pass. Looking for %v, got %v for %v at %v over %v months", c.payment, payment, c.principal, c.rate, c.months)
+		}
+	}
+}
+
+func TestScheduleReducingBalance(t *testing.T) {
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	instalments := Schedule("NGN", MethodReducingBalance, decimal.NewFromInt(10000), decimal.NewFromInt(12), 12, start)
+	if len(instalments) != 12 {
+		t.Fatalf("Schedule does not pass. Looking for 12 instalments, got %v", len(instalments))
+	}
+
+	first := instalments[0]
+	if first.DueDate != "2024-02-29" || !first.InterestDue.Equal(decimal.NewFromInt(100)) || !first.PrincipalDue.Equal(decimal.RequireFromString("788.49")) {
+		t.Errorf("Schedule does not pass. Got first instalment %+v", first)
+	}
+
+	principal := decimal.Zero
+	for i, instalment := range instalments {
+		principal = principal.Add(instalment.PrincipalDue)
+		if i < 11 && !instalment.PrincipalDue.Add(instalment.InterestDue).Equal(decimal.RequireFromString("888.49")) {
+			t.Errorf("Schedule does not pass. Instalment %v is %v", instalment.Number, instalment.PrincipalDue.Add(instalment.InterestDue))
+		}
+	}
+	if !principal.Equal(decimal.NewFromInt(10000)) {
+		t.Errorf("Schedule does not pass. Principal adds up to %v", principal)
+	}
+	if instalments[11].DueDate != "2025-01-31" {
+		t.Errorf("Schedule does not pass. Last instalment due %v", instalments[11].DueDate)
+	}
+}
+
+func TestScheduleFlat(t *testing.T) {
+	start := time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)
+	instalments := Schedule("NGN", MethodFlat, decimal.NewFromInt(1000), decimal.NewFromInt(12), 3, start)
+
+	principal := decimal.Zero
+	for _, instalment := range instalments {
+		if !instalment.InterestDue.Equal(decimal.NewFromInt(10)) {
+			t.Errorf("Schedule does not pass. Looking for flat interest of 10, got %v", instalment.InterestDue)
+		}
+		principal = principal.Add(instalment.PrincipalDue)
+	}
+	if !instalments[0].PrincipalDue.Equal(decimal.RequireFromString("333.33")) || !instalments[2].PrincipalDue.Equal(decimal.RequireFromString("333.34")) {
+		t.Errorf("Schedule does not pass. Got principal %v and %v", instalments[0].PrincipalDue, instalments[2].PrincipalDue)
+	}
+	if !principal.Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("Schedule does not pass. Principal adds up to %v", principal)
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	cases := []struct {
+		start  string
+		months int
+		due    string
+	}{
+		{"2024-01-15", 1, "2024-02-15"},
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2023-01-31", 1, "2023-02-28"},
+		{"2024-08-31", 4, "2024-12-31"},
+		{"2024-11-30", 3, "2025-02-28"},
+	}
+	for _, c := range cases {
+		start, _ := time.Parse(DATE_LAYOUT, c.start)
+		due := DueDate(start, c.months)
+		if due != c.due {
+			t.Errorf("DueDate does not pass. Looking for %v, got %v for %v plus %v months", c.due, due, c.start, c.months)
+		}
+	}
+}
+
+func testInstalments() []Instalment {
+	return []Instalment{
+		{ID: 1, Number: 1, DueDate: "2024-02-15", PrincipalDue: decimal.NewFromInt(90), InterestDue: decimal.NewFromInt(10), FeeDue: decimal.NewFromInt(5),
+			PrincipalPaid: decimal.Zero, InterestPaid: decimal.NewFromInt(10), FeePaid: decimal.Zero, Status: InstalmentPending},
+		{ID: 2, Number: 2, DueDate: "2024-03-15", PrincipalDue: decimal.NewFromInt(92), InterestDue: decimal.NewFromInt(8), FeeDue: decimal.Zero,
+			PrincipalPaid: decimal.Zero, InterestPaid: decimal.Zero, FeePaid: decimal.Zero, Status: InstalmentPending},
+		{ID: 3, Number: 3, DueDate: "2024-04-15", PrincipalDue: decimal.NewFromInt(95), InterestDue: decimal.NewFromInt(5), FeeDue: decimal.Zero,
+			PrincipalPaid: decimal.Zero, InterestPaid: decimal.Zero, FeePaid: decimal.Zero, Status: InstalmentPending},
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	principal, interest, fee := allocate(testInstalments(), decimal.NewFromInt(100))
+	if !fee.Equal(decimal.NewFromInt(5)) || !interest.Equal(decimal.NewFromInt(5)) || !principal.Equal(decimal.NewFromInt(90)) {
+		t.Errorf("allocate does not pass. Got principal %v, interest %v, fee %v", principal, interest, fee)
+	}
+}
+
+func TestApplyAllocation(t *testing.T) {
+	instalments := testInstalments()
+	principal, interest, fee := allocate(instalments, decimal.NewFromInt(110))
+
+	updated := applyAllocation(instalments, principal, interest, fee, "2024-03-20")
+	if len(updated) != 2 {
+		t.Fatalf("applyAllocation does not pass. Looking for 2 instalments changed, got %v", len(updated))
+	}
+	if updated[0].Status != InstalmentPaid || updated[0].PaidAt != "2024-03-20" || updated[0].Outstanding().IsPositive() {
+		t.Errorf("applyAllocation does not pass. First instalment is %+v", updated[0])
+	}
+	if updated[1].Status != InstalmentPending || !updated[1].InterestPaid.Equal(decimal.NewFromInt(8)) || !updated[1].PrincipalPaid.Equal(decimal.NewFromInt(7)) {
+		t.Errorf("applyAllocation does not pass. Second instalment is %+v", updated[1])
+	}
+}
+
+func TestRepayable(t *testing.T) {
+	instalments := testInstalments()
+	payable := repayable(instalments, "2024-02-20")
+	if len(payable) != 2 || payable[0].ID != 1 || payable[1].ID != 2 {
+		t.Errorf("repayable does not pass. Looking for instalments 1 and 2, got %+v", payable)
+	}
+
+	instalments[0].Status = InstalmentPaid
+	payable = repayable(instalments, "2024-01-20")
+	if len(payable) != 1 || payable[0].ID != 2 {
+		t.Errorf("repayable does not pass. Looking for instalment 2, got %+v", payable)
+	}
+
+	payable = repayable(instalments, "2024-05-01")
+	if len(payable) != 2 || payable[1].ID != 3 {
+		t.Errorf("repayable does not pass. Looking for instalments 2 and 3, got %+v", payable)
+	}
+}
+
+func TestBuildStatement(t *testing.T) {
+	loan := Loan{LoanNumber: "LN00000001", CurrencyCode: "NGN", Status: LoanActive}
+	statement := buildStatement(loan, testInstalments(), []Repayment{}, "2024-03-20")
+
+	if !statement.TotalOutstanding.Equal(decimal.NewFromInt(295)) || !statement.FeesOutstanding.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("buildStatement does not pass. Got total %v, fees %v", statement.TotalOutstanding, statement.FeesOutstanding)
+	}
+	if !statement.ArrearsAmount.Equal(decimal.NewFromInt(195)) || statement.DaysInArrears != 34 {
+		t.Errorf("buildStatement does not pass. Got arrears %v for %v days", statement.ArrearsAmount, statement.DaysInArrears)
+	}
+	if statement.NextDueDate != "2024-04-15" || !statement.NextDueAmount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("buildStatement does not pass. Got next due %v of %v", statement.NextDueDate, statement.NextDueAmount)
+	}
+	if !statement.Instalments[0].Overdue || !statement.Instalments[1].Overdue || statement.Instalments[2].Overdue {
+		t.Errorf("buildStatement does not pass. Overdue instalments are wrong")
+	}
+}
+
+func TestValidateTerms(t *testing.T) {
+	if err := validateTerms(decimal.NewFromInt(5000), decimal.NewFromInt(18), 12, MethodFlat); err != nil {
+		t.Errorf("validateTerms does not pass. Rejected valid terms: %v", err)
+	}
+
+	cases := []struct {
+		amount string
+		rate   string
+		months int
+		method string
+	}{
+		{"0", "18", 12, MethodFlat},
+		{"5000", "-1", 12, MethodFlat},
+		{"5000", "101", 12, MethodFlat},
+		{"5000", "18", 0, MethodFlat},
+		{"5000", "18", MAX_TENOR_MONTHS + 1, MethodFlat},
+		{"5000", "18", 12, "balloon"},
+	}
+	for _, c := range cases {
+		if err := validateTerms(decimal.RequireFromString(c.amount), decimal.RequireFromString(c.rate), c.months, c.method); err == nil {
+			t.Errorf("validateTerms does not pass. Accepted %+v", c)
+		}
+	}
+}
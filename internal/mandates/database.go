@@ -0,0 +1,139 @@
+package mandates
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+// getHolders returns the account's owner as primary holder followed by the customers linked
+// in account_holders. Accounts without an owner have no holders.
+func getHolders(accountNumber string) (holders []Holder, err error) {
+	holders = make([]Holder, 0)
+
+	var owner sql.NullString
+	var opened string
+	err = Config.Db.QueryRow("SELECT `customerNumber`, `timestamp` FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&owner, &opened)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("mandates.getHolders: Account " + accountNumber + " not found")
+		}
+		return nil, errors.New("mandates.getHolders: " + err.Error())
+	}
+	if !owner.Valid || owner.String == "" {
+		return holders, nil
+	}
+	openedAt, _ := time.Parse(SQL_TIME_LAYOUT, opened)
+	holders = append(holders, Holder{
+		AccountNumber:  accountNumber,
+		CustomerNumber: owner.String,
+		Role:           RolePrimary,
+		Timestamp:      openedAt,
+	})
+
+	rows, err := Config.Db.Query("SELECT `accountNumber`, `customerNumber`, `role`, `creator`, `timestamp` FROM `account_holders` WHERE `accountNumber` = ? ORDER BY `id`", accountNumber)
+	if err != nil {
+		return nil, errors.New("mandates.getHolders: " + err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var holder Holder
+		var timestamp string
+		if err := rows.Scan(&holder.AccountNumber, &holder.CustomerNumber, &holder.Role, &holder.Creator, &timestamp); err != nil {
+			return nil, errors.New("mandates.getHolders: " + err.Error())
+		}
+		holder.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("mandates.getHolders: " + err.Error())
+		}
+		holders = append(holders, holder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("mandates.getHolders: " + err.Error())
+	}
+
+	return holders, nil
+}
+
+func saveHolder(holder Holder) (err error) {
+	insertStatement := "INSERT INTO account_holders (`accountNumber`, `customerNumber`, `role`, `creator`) VALUES(?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("mandates.saveHolder: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(holder.AccountNumber, holder.CustomerNumber, holder.Role, holder.Creator)
+	if err != nil {
+		return errors.New("mandates.saveHolder: " + err.Error())
+	}
+	return
+}
+
+func deleteHolder(accountNumber string, customerNumber string) (err error) {
+	_, err = Config.Db.Exec("DELETE FROM account_holders WHERE `accountNumber` = ? AND `customerNumber` = ?", accountNumber, customerNumber)
+	if err != nil {
+		return errors.New("mandates.deleteHolder: " + err.Error())
+	}
+	return
+}
+
+// getMandate returns the account's mandate, any_one if none has been set
+func getMandate(accountNumber string) (mandate Mandate, err error) {
+	mandate.AccountNumber = accountNumber
+
+	var timestamp string
+	err = Config.Db.QueryRow("SELECT `rule`, `creator`, `timestamp` FROM `account_mandates` WHERE `accountNumber` = ?", accountNumber).Scan(&mandate.Rule, &mandate.Creator, &timestamp)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			mandate.Rule = RuleAnyOne
+			return mandate, nil
+		}
+		return Mandate{}, errors.New("mandates.getMandate: " + err.Error())
+	}
+	mandate.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+	if err != nil {
+		return Mandate{}, errors.New("mandates.getMandate: " + err.Error())
+	}
+	return
+}
+
+func saveMandate(accountNumber string, rule string, creator string) (err error) {
+	insertStatement := "INSERT INTO account_mandates (`accountNumber`, `rule`, `creator`) VALUES(?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE `rule` = VALUES(`rule`), `creator` = VALUES(`creator`), `timestamp` = CURRENT_TIMESTAMP"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("mandates.saveMandate: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(accountNumber, rule, creator)
+	if err != nil {
+		return errors.New("mandates.saveMandate: " + err.Error())
+	}
+	return
+}
+
+func customerExists(customerNumber string) (exists bool, err error) {
+	var count int
+	err = Config.Db.QueryRow("SELECT COUNT(*) FROM `customers` WHERE `customerNumber` = ?", customerNumber).Scan(&count)
+	if err != nil {
+		return false, errors.New("mandates.customerExists: " + err.Error())
+	}
+	return count > 0, nil
+}
+
+func getAccountCustomerNumber(accountNumber string) (customerNumber string, err error) {
+	err = Config.Db.QueryRow("SELECT IFNULL(`customerNumber`, '') FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&customerNumber)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", errors.New("mandates.getAccountCustomerNumber: " + err.Error())
+	}
+	return
+}
@@ -0,0 +1,241 @@
+package mandates
+
+/*
+Joint accounts and account mandates
+
+An account can be held by more than one customer. The customer who owns the account
+(accounts.customerNumber) is always its primary holder, further customers are linked
+in account_holders as secondary holders or signatories. Every holder can sign for the
+account.
+
+The account's mandate sets how many holders must sign a payment:
+
+	any_one  any single holder (the default for accounts without a mandate)
+	any_two  any two different holders
+	all      every holder on the account
+
+Payments from accounts that need more than one signature are held by payments until
+enough holders have approved them.
+*/
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+)
+
+const (
+	RolePrimary   = "primary"
+	RoleSecondary = "secondary"
+	RoleSignatory = "signatory"
+
+	RuleAnyOne = "any_one"
+	RuleAnyTwo = "any_two"
+	RuleAll    = "all"
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Holder is a customer linked to an account
+type Holder struct {
+	AccountNumber  string    `json:"accountNumber"`
+	CustomerNumber string    `json:"customerNumber"`
+	Role           string    `json:"role"`
+	Creator        string    `json:"creator,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Mandate is the signing rule for an account
+type Mandate struct {
+	AccountNumber     string    `json:"accountNumber"`
+	Rule              string    `json:"rule"`
+	RequiredApprovals int       `json:"requiredApprovals"`
+	Holders           []Holder  `json:"holders"`
+	Creator           string    `json:"creator,omitempty"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// ValidRule reports whether rule is a known signing rule
+func ValidRule(rule string) bool {
+	switch rule {
+	case RuleAnyOne, RuleAnyTwo, RuleAll:
+		return true
+	}
+	return false
+}
+
+// RequiredApprovals is the number of signatures a rule needs from an account with the given
+// number of holders
+func RequiredApprovals(rule string, holders int) int {
+	switch rule {
+	case RuleAnyTwo:
+		return 2
+	case RuleAll:
+		if holders < 1 {
+			return 1
+		}
+		return holders
+	}
+	return 1
+}
+
+// Holders lists everyone who holds the account, the primary holder first
+func Holders(accountNumber string) ([]Holder, error) {
+	holders, err := getHolders(accountNumber)
+	if err != nil {
+		return nil, errors.New("mandates.Holders: " + err.Error())
+	}
+	return holders, nil
+}
+
+// AddHolder links a further customer to the account as a secondary holder or signatory
+func AddHolder(accountNumber string, customerNumber string, role string, creator string) (Holder, error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	customerNumber = strings.TrimSpace(customerNumber)
+	if role != RoleSecondary && role != RoleSignatory {
+		return Holder{}, errors.New("mandates.AddHolder: Role must be " + RoleSecondary + " or " + RoleSignatory)
+	}
+
+	exists, err := customerExists(customerNumber)
+	if err != nil {
+		return Holder{}, errors.New("mandates.AddHolder: " + err.Error())
+	}
+	if !exists {
+		return Holder{}, errors.New("mandates.AddHolder: Customer not found")
+	}
+
+	holders, err := getHolders(accountNumber)
+	if err != nil {
+		return Holder{}, errors.New("mandates.AddHolder: " + err.Error())
+	}
+	if len(holders) == 0 {
+		return Holder{}, errors.New("mandates.AddHolder: Account " + accountNumber + " does not belong to a customer")
+	}
+	if _, found := findHolder(holders, customerNumber); found {
+		return Holder{}, errors.New("mandates.AddHolder: Customer already holds the account")
+	}
+
+	holder := Holder{
+		AccountNumber:  accountNumber,
+		CustomerNumber: customerNumber,
+		Role:           role,
+		Creator:        creator,
+	}
+	err = saveHolder(holder)
+	if err != nil {
+		return Holder{}, errors.New("mandates.AddHolder: " + err.Error())
+	}
+	holder.Timestamp = time.Now()
+
+	return holder, nil
+}
+
+// RemoveHolder unlinks a secondary holder or signatory from the account. The primary holder
+// can't be removed, and an any_two mandate must keep at least two holders.
+func RemoveHolder(accountNumber string, customerNumber string) error {
+	accountNumber = strings.TrimSpace(accountNumber)
+	customerNumber = strings.TrimSpace(customerNumber)
+
+	holders, err := getHolders(accountNumber)
+	if err != nil {
+		return errors.New("mandates.RemoveHolder: " + err.Error())
+	}
+	holder, found := findHolder(holders, customerNumber)
+	if !found {
+		return errors.New("mandates.RemoveHolder: Customer does not hold the account")
+	}
+	if holder.Role == RolePrimary {
+		return errors.New("mandates.RemoveHolder: The primary holder cannot be removed")
+	}
+
+	mandate, err := getMandate(accountNumber)
+	if err != nil {
+		return errors.New("mandates.RemoveHolder: " + err.Error())
+	}
+	if RequiredApprovals(mandate.Rule, len(holders)-1) > len(holders)-1 {
+		return errors.New("mandates.RemoveHolder: The account's " + mandate.Rule + " mandate needs more holders, change the mandate first")
+	}
+
+	err = deleteHolder(accountNumber, customerNumber)
+	if err != nil {
+		return errors.New("mandates.RemoveHolder: " + err.Error())
+	}
+	return nil
+}
+
+// Get returns the account's mandate with its holders and the signatures it needs
+func Get(accountNumber string) (Mandate, error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	mandate, err := getMandate(accountNumber)
+	if err != nil {
+		return Mandate{}, errors.New("mandates.Get: " + err.Error())
+	}
+	mandate.Holders, err = getHolders(accountNumber)
+	if err != nil {
+		return Mandate{}, errors.New("mandates.Get: " + err.Error())
+	}
+	mandate.RequiredApprovals = RequiredApprovals(mandate.Rule, len(mandate.Holders))
+
+	return mandate, nil
+}
+
+// Set changes the account's signing rule
+func Set(accountNumber string, rule string, creator string) (Mandate, error) {
+	accountNumber = strings.TrimSpace(accountNumber)
+	rule = strings.TrimSpace(rule)
+	if !ValidRule(rule) {
+		return Mandate{}, errors.New("mandates.Set: Rule must be " + RuleAnyOne + ", " + RuleAnyTwo + " or " + RuleAll)
+	}
+
+	holders, err := getHolders(accountNumber)
+	if err != nil {
+		return Mandate{}, errors.New("mandates.Set: " + err.Error())
+	}
+	if len(holders) == 0 {
+		return Mandate{}, errors.New("mandates.Set: Account " + accountNumber + " does not belong to a customer")
+	}
+	if RequiredApprovals(rule, len(holders)) > len(holders) {
+		return Mandate{}, errors.New("mandates.Set: The account does not have enough holders for " + rule)
+	}
+
+	err = saveMandate(accountNumber, rule, creator)
+	if err != nil {
+		return Mandate{}, errors.New("mandates.Set: " + err.Error())
+	}
+
+	return Get(accountNumber)
+}
+
+// Signatory returns the holder of the account a user signs as. Users log in with the account
+// number of one of their own accounts, so they sign as the customer that account belongs to.
+func Signatory(accountNumber string, user string) (holder Holder, found bool, err error) {
+	customerNumber, err := getAccountCustomerNumber(user)
+	if err != nil {
+		return Holder{}, false, errors.New("mandates.Signatory: " + err.Error())
+	}
+	if customerNumber == "" {
+		return Holder{}, false, nil
+	}
+
+	holders, err := getHolders(accountNumber)
+	if err != nil {
+		return Holder{}, false, errors.New("mandates.Signatory: " + err.Error())
+	}
+	holder, found = findHolder(holders, customerNumber)
+	return
+}
+
+func findHolder(holders []Holder, customerNumber string) (Holder, bool) {
+	for _, holder := range holders {
+		if holder.CustomerNumber == customerNumber {
+			return holder, true
+		}
+	}
+	return Holder{}, false
+}
@@ -0,0 +1,58 @@
+package mandates
+
+import (
+	"testing"
+)
+
+func TestRequiredApprovals(t *testing.T) {
+	cases := []struct {
+		rule     string
+		holders  int
+		required int
+	}{
+		{RuleAnyOne, 1, 1},
+		{RuleAnyOne, 3, 1},
+		{RuleAnyTwo, 2, 2},
+		{RuleAnyTwo, 4, 2},
+		{RuleAll, 1, 1},
+		{RuleAll, 3, 3},
+		{RuleAll, 0, 1},
+		{"", 3, 1},
+	}
+	for _, c := range cases {
+		required := RequiredApprovals(c.rule, c.holders)
+		if required != c.required {
+			t.Errorf("RequiredApprovals does not pass. Looking for %v, got %v for %v with %v holders", c.required, required, c.rule, c.holders)
+		}
+	}
+}
+
+func TestValidRule(t *testing.T) {
+	for _, rule := range []string{RuleAnyOne, RuleAnyTwo, RuleAll} {
+		if !ValidRule(rule) {
+			t.Errorf("ValidRule does not pass. Looking for %v, got %v for %v", true, false, rule)
+		}
+	}
+	for _, rule := range []string{"", "any one", "any_three", "ALL"} {
+		if ValidRule(rule) {
+			t.Errorf("ValidRule does not pass. Looking for %v, got %v for %v", false, true, rule)
+		}
+	}
+}
+
+func TestFindHolder(t *testing.T) {
+	holders := []Holder{
+		{AccountNumber: "065469", CustomerNumber: "C000000001", Role: RolePrimary},
+		{AccountNumber: "065469", CustomerNumber: "C000000002", Role: RoleSignatory},
+	}
+
+	holder, found := findHolder(holders, "C000000002")
+	if !found || holder.Role != RoleSignatory {
+		t.Errorf("findHolder does not pass. Looking for %v, got %v (found %v)", RoleSignatory, holder.Role, found)
+	}
+
+	_, found = findHolder(holders, "C000000003")
+	if found {
+		t.Errorf("findHolder does not pass. Looking for %v, got %v", false, found)
+	}
+}
@@ -0,0 +1,361 @@
+package merchantqr
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	CodeActive    = "active"
+	CodePaid      = "paid"
+	CodeCancelled = "cancelled"
+)
+
+const (
+	ReconciliationUnpaid   = "unpaid"
+	ReconciliationPartial  = "partial"
+	ReconciliationPaid     = "paid"
+	ReconciliationOverpaid = "overpaid"
+)
+
+// Code is a QR code issued to a merchant
+type Code struct {
+	ID                    int64           `json:"id"`
+	Reference             string          `json:"reference"`
+	MerchantAccountNumber string          `json:"merchantAccountNumber"`
+	MerchantName          string          `json:"merchantName"`
+	Type                  string          `json:"type"`
+	Amount                decimal.Decimal `json:"amount"`
+	Currency              string          `json:"currency"`
+	Payload               string          `json:"payload"`
+	Status                string          `json:"status"`
+	Timestamp             time.Time       `json:"timestamp"`
+}
+
+// Payment is a payment to the merchant made through PayCode against a QR code
+type Payment struct {
+	ID                 int64           `json:"id"`
+	PayerAccountNumber string          `json:"payerAccountNumber"`
+	Amount             decimal.Decimal `json:"amount"`
+	Reference          string          `json:"reference"`
+	Timestamp          time.Time       `json:"timestamp"`
+}
+
+type ReconciliationLine struct {
+	Reference      string          `json:"reference"`
+	Type           string          `json:"type"`
+	ExpectedAmount decimal.Decimal `json:"expectedAmount"`
+	PaidAmount     decimal.Decimal `json:"paidAmount"`
+	Status         string          `json:"status"`
+	Payments       []Payment       `json:"payments"`
+}
+
+type Reconciliation struct {
+	MerchantAccountNumber string               `json:"merchantAccountNumber"`
+	From                  time.Time            `json:"from"`
+	To                    time.Time            `json:"to"`
+	TotalReceived         decimal.Decimal      `json:"totalReceived"`
+	Lines                 []ReconciliationLine `json:"lines"`
+	Unmatched             []Payment            `json:"unmatched"`
+}
+
+// CreateCode issues a static or dynamic QR code for the merchant account.
+// The token user must be the merchant. Dynamic codes need an amount; static codes
+// may carry a fixed amount. An empty reference generates one.
+func CreateCode(token string, merchant string, merchantName string, merchantCity string, codeType string, amount string, reference string) (code Code, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, merchant)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+	}
+	if !holder {
+		return Code{}, errors.New("merchantqr.CreateCode: Merchant not valid")
+	}
+
+	active, err := payments.CheckIfAccountIsActive(merchant)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+	}
+	if !active {
+		return Code{}, errors.New("merchantqr.CreateCode: Merchant Account Not valid")
+	}
+	// Codes are issued in the currency the merchant account is held in
+	currencyCode, err := payments.AccountCurrency(merchant)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+	}
+
+	amountDecimal := decimal.Zero
+	if strings.TrimSpace(amount) != "" {
+		amountDecimal, err = decimal.NewFromString(strings.TrimSpace(amount))
+		if err != nil {
+			return Code{}, errors.New("merchantqr.CreateCode: Could not convert amount to decimal. " + err.Error())
+		}
+		if amountDecimal.IsNegative() {
+			return Code{}, errors.New("merchantqr.CreateCode: Amount cannot be negative")
+		}
+	}
+
+	if reference == "" {
+		reference, err = NewReference()
+		if err != nil {
+			return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+		}
+	}
+
+	payload := Payload{
+		Type:          codeType,
+		AccountNumber: merchant,
+		MerchantName:  merchantName,
+		MerchantCity:  merchantCity,
+		Currency:      currencyCode,
+		Amount:        amountDecimal,
+		Reference:     reference,
+	}
+	encoded, err := payload.Encode()
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+	}
+
+	code = Code{
+		Reference:             reference,
+		MerchantAccountNumber: merchant,
+		MerchantName:          truncate(merchantName, 25),
+		Type:                  codeType,
+		Amount:                amountDecimal,
+		Currency:              currencyCode,
+		Payload:               encoded,
+		Status:                CodeActive,
+		Timestamp:             time.Now(),
+	}
+
+	code.ID, err = saveCode(code)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CreateCode: " + err.Error())
+	}
+
+	return code, nil
+}
+
+// GetCode returns a code issued to the merchant
+func GetCode(merchant string, reference string) (code Code, err error) {
+	code, err = getCode(reference)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.GetCode: " + err.Error())
+	}
+	if code.MerchantAccountNumber != merchant {
+		return Code{}, errors.New("merchantqr.GetCode: QR code not found")
+	}
+
+	return code, nil
+}
+
+// CancelCode stops an active code from accepting further payments.
+// The token user must be the merchant.
+func CancelCode(token string, merchant string, reference string) (code Code, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CancelCode: " + err.Error())
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, merchant)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CancelCode: " + err.Error())
+	}
+	if !holder {
+		return Code{}, errors.New("merchantqr.CancelCode: Merchant not valid")
+	}
+
+	code, err = GetCode(merchant, reference)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CancelCode: " + err.Error())
+	}
+
+	err = updateCodeStatus(reference, CodeActive, CodeCancelled)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.CancelCode: " + err.Error())
+	}
+
+	code.Status = CodeCancelled
+	return code, nil
+}
+
+// PayCode parses a scanned payload and pays the merchant with a PAIN 1 credit transfer
+// from the payer. The stored code is authoritative for the merchant account and amount;
+// amount is only used for static codes without a fixed amount. The token user must be the payer.
+func PayCode(token string, payer string, rawPayload string, amount string) (code Code, response string, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+	}
+	holder, err := payments.IsAccountHolder(tokenUser, payer)
+	if err != nil {
+		return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+	}
+	if !holder {
+		return Code{}, "", errors.New("merchantqr.PayCode: Payer not valid")
+	}
+	err = payments.RequireSingleSignature(payer)
+	if err != nil {
+		return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+	}
+
+	payload, err := Parse(rawPayload)
+	if err != nil {
+		return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+	}
+
+	code, err = getCode(payload.Reference)
+	if err != nil {
+		return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+	}
+	if code.MerchantAccountNumber != payload.AccountNumber || code.Type != payload.Type || !code.Amount.Equal(payload.Amount) {
+		return Code{}, "", errors.New("merchantqr.PayCode: Payload does not match the issued QR code")
+	}
+	if code.Status != CodeActive {
+		return Code{}, "", errors.New("merchantqr.PayCode: QR code is " + code.Status)
+	}
+	if code.MerchantAccountNumber == payer {
+		return Code{}, "", errors.New("merchantqr.PayCode: Cannot pay own QR code")
+	}
+
+	amountDecimal := code.Amount
+	if !amountDecimal.IsPositive() {
+		amountDecimal, err = decimal.NewFromString(strings.TrimSpace(amount))
+		if err != nil {
+			return Code{}, "", errors.New("merchantqr.PayCode: Could not convert amount to decimal. " + err.Error())
+		}
+		if !amountDecimal.IsPositive() {
+			return Code{}, "", errors.New("merchantqr.PayCode: Amount must be greater than zero")
+		}
+	}
+
+	// Claim dynamic codes first so they cannot be paid twice
+	if code.Type == DynamicCode {
+		err = updateCodeStatus(code.Reference, CodeActive, CodePaid)
+		if err != nil {
+			return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+		}
+	}
+
+	// Record the payment against the code before it is made, reconciliation reads these
+	// records rather than the narration the payer's transfer carries
+	paymentID, err := saveQRPayment(code.Reference, code.MerchantAccountNumber, payer, amountDecimal)
+	if err == nil {
+		response, err = payments.ProcessPAIN([]string{token, "pain", "1", payer + "@", code.MerchantAccountNumber + "@", amountDecimal.String(), Narration(code.Reference), payer})
+		if err != nil {
+			if removeErr := removeQRPayment(paymentID); removeErr != nil {
+				err = errors.New(err.Error() + ". " + removeErr.Error())
+			}
+		}
+	}
+	if err != nil {
+		if code.Type == DynamicCode {
+			if revertErr := updateCodeStatus(code.Reference, CodePaid, CodeActive); revertErr != nil {
+				return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error() + ". " + revertErr.Error())
+			}
+		}
+		return Code{}, "", errors.New("merchantqr.PayCode: " + err.Error())
+	}
+
+	if code.Type == DynamicCode {
+		code.Status = CodePaid
+	}
+	code.Amount = amountDecimal
+	return code, response, nil
+}
+
+// Reconcile matches the merchant's QR payments in the period to the codes they were made against
+func Reconcile(merchant string, from time.Time, to time.Time) (reconciliation Reconciliation, err error) {
+	if to.Before(from) {
+		return Reconciliation{}, errors.New("merchantqr.Reconcile: Period end is before its start")
+	}
+
+	codes, err := getCodesByMerchant(merchant, to)
+	if err != nil {
+		return Reconciliation{}, errors.New("merchantqr.Reconcile: " + err.Error())
+	}
+	qrPayments, err := getQRPayments(merchant, from, to)
+	if err != nil {
+		return Reconciliation{}, errors.New("merchantqr.Reconcile: " + err.Error())
+	}
+
+	reconciliation = reconcile(codes, qrPayments, from)
+	reconciliation.MerchantAccountNumber = merchant
+	reconciliation.From = from
+	reconciliation.To = to
+
+	return reconciliation, nil
+}
+
+// reconcile groups payments by reference. Codes appear if they were paid in the period
+// or are dynamic codes issued in it; payments with an unknown reference are unmatched.
+func reconcile(codes []Code, qrPayments []Payment, from time.Time) (reconciliation Reconciliation) {
+	byReference := make(map[string][]Payment)
+	reconciliation.TotalReceived = decimal.Zero
+	for _, payment := range qrPayments {
+		byReference[payment.Reference] = append(byReference[payment.Reference], payment)
+		reconciliation.TotalReceived = reconciliation.TotalReceived.Add(payment.Amount)
+	}
+
+	reconciliation.Lines = make([]ReconciliationLine, 0)
+	for _, code := range codes {
+		paid := byReference[code.Reference]
+		delete(byReference, code.Reference)
+		if len(paid) == 0 && (code.Type != DynamicCode || code.Timestamp.Before(from)) {
+			continue
+		}
+
+		line := ReconciliationLine{
+			Reference:      code.Reference,
+			Type:           code.Type,
+			ExpectedAmount: code.Amount,
+			PaidAmount:     decimal.Zero,
+			Payments:       make([]Payment, 0),
+		}
+		for _, payment := range paid {
+			line.PaidAmount = line.PaidAmount.Add(payment.Amount)
+			line.Payments = append(line.Payments, payment)
+		}
+		line.Status = reconciliationStatus(code, line.PaidAmount, len(paid))
+		reconciliation.Lines = append(reconciliation.Lines, line)
+	}
+
+	reconciliation.Unmatched = make([]Payment, 0)
+	for _, payment := range qrPayments {
+		if _, ok := byReference[payment.Reference]; ok {
+			reconciliation.Unmatched = append(reconciliation.Unmatched, payment)
+		}
+	}
+
+	return reconciliation
+}
+
+func reconciliationStatus(code Code, paid decimal.Decimal, count int) string {
+	if count == 0 {
+		return ReconciliationUnpaid
+	}
+	// Static codes are reusable, every payment at the fixed amount is expected
+	expected := code.Amount
+	if code.Type == StaticCode {
+		if !expected.IsPositive() {
+			return ReconciliationPaid
+		}
+		expected = expected.Mul(decimal.NewFromInt(int64(count)))
+	}
+
+	switch paid.Cmp(expected) {
+	case -1:
+		return ReconciliationPartial
+	case 1:
+		return ReconciliationOverpaid
+	}
+	return ReconciliationPaid
+}
@@ -0,0 +1,188 @@
+package merchantqr
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/shopspring/decimal"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+func saveCode(code Code) (id int64, err error) {
+	insertStatement := "INSERT INTO merchant_qr_codes (`reference`, `merchantAccountNumber`, `merchantName`, `type`, `amount`, `currency`, `payload`, `status`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("merchantqr.saveCode: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	// Static codes without a fixed amount store NULL
+	amount := decimal.NullDecimal{Decimal: code.Amount, Valid: code.Amount.IsPositive()}
+
+	res, err := stmtIns.Exec(code.Reference, code.MerchantAccountNumber, code.MerchantName, code.Type, amount, code.Currency, code.Payload, code.Status)
+	if err != nil {
+		return 0, errors.New("merchantqr.saveCode: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("merchantqr.saveCode: " + err.Error())
+	}
+
+	return
+}
+
+func getCode(reference string) (code Code, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `reference`, `merchantAccountNumber`, `merchantName`, `type`, `amount`, `currency`, `payload`, `status`, `timestamp` FROM `merchant_qr_codes` WHERE `reference` = ?", reference)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.getCode: " + err.Error())
+	}
+	defer rows.Close()
+
+	codes, err := scanCodes(rows)
+	if err != nil {
+		return Code{}, errors.New("merchantqr.getCode: " + err.Error())
+	}
+	if len(codes) == 0 {
+		return Code{}, errors.New("merchantqr.getCode: QR code not found")
+	}
+
+	return codes[0], nil
+}
+
+func getCodesByMerchant(merchant string, createdBefore time.Time) (codes []Code, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `reference`, `merchantAccountNumber`, `merchantName`, `type`, `amount`, `currency`, `payload`, `status`, `timestamp` FROM `merchant_qr_codes` WHERE `merchantAccountNumber` = ? AND `timestamp` <= ? ORDER BY `timestamp`", merchant, createdBefore.UTC())
+	if err != nil {
+		return nil, errors.New("merchantqr.getCodesByMerchant: " + err.Error())
+	}
+	defer rows.Close()
+
+	codes, err = scanCodes(rows)
+	if err != nil {
+		return nil, errors.New("merchantqr.getCodesByMerchant: " + err.Error())
+	}
+
+	return codes, nil
+}
+
+func scanCodes(rows *sql.Rows) (codes []Code, err error) {
+	codes = make([]Code, 0)
+	for rows.Next() {
+		var code Code
+		var amount decimal.NullDecimal
+		var timestamp string
+		if err := rows.Scan(&code.ID, &code.Reference, &code.MerchantAccountNumber, &code.MerchantName, &code.Type, &amount, &code.Currency, &code.Payload, &code.Status, &timestamp); err != nil {
+			return nil, errors.New("merchantqr.scanCodes: " + err.Error())
+		}
+		if amount.Valid {
+			code.Amount = amount.Decimal
+		}
+		if code.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("merchantqr.scanCodes: " + err.Error())
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("merchantqr.scanCodes: " + err.Error())
+	}
+
+	return codes, nil
+}
+
+// updateCodeStatus only moves a code that is still in the expected status,
+// so a dynamic code cannot be paid twice
+func updateCodeStatus(reference string, from string, to string) (err error) {
+	updateStatement := "UPDATE merchant_qr_codes SET `status` = ? WHERE `reference` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("merchantqr.updateCodeStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, reference, from)
+	if err != nil {
+		return errors.New("merchantqr.updateCodeStatus: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("merchantqr.updateCodeStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("merchantqr.updateCodeStatus: QR code is no longer " + from)
+	}
+
+	return nil
+}
+
+// saveQRPayment records a payment made through PayCode against the code it was made for
+func saveQRPayment(reference string, merchant string, payer string, amount decimal.Decimal) (id int64, err error) {
+	insertStatement := "INSERT INTO merchant_qr_payments (`reference`, `merchantAccountNumber`, `payerAccountNumber`, `amount`) VALUES(?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("merchantqr.saveQRPayment: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(reference, merchant, payer, amount)
+	if err != nil {
+		return 0, errors.New("merchantqr.saveQRPayment: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("merchantqr.saveQRPayment: " + err.Error())
+	}
+
+	return
+}
+
+// removeQRPayment drops the record of a payment that didn't go through
+func removeQRPayment(id int64) (err error) {
+	_, err = Config.Db.Exec("DELETE FROM merchant_qr_payments WHERE `id` = ?", id)
+	if err != nil {
+		return errors.New("merchantqr.removeQRPayment: " + err.Error())
+	}
+	return
+}
+
+// getQRPayments reads the payments made to the merchant through PayCode
+func getQRPayments(merchant string, from time.Time, to time.Time) (payments []Payment, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `payerAccountNumber`, `amount`, `reference`, `timestamp` FROM `merchant_qr_payments` WHERE `merchantAccountNumber` = ? AND `timestamp` BETWEEN ? AND ? ORDER BY `timestamp`", merchant, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, errors.New("merchantqr.getQRPayments: " + err.Error())
+	}
+	defer rows.Close()
+
+	payments = make([]Payment, 0)
+	for rows.Next() {
+		var payment Payment
+		var amount, timestamp string
+		if err := rows.Scan(&payment.ID, &payment.PayerAccountNumber, &amount, &payment.Reference, &timestamp); err != nil {
+			return nil, errors.New("merchantqr.getQRPayments: " + err.Error())
+		}
+		if payment.Amount, err = decimal.NewFromString(amount); err != nil {
+			return nil, errors.New("merchantqr.getQRPayments: " + err.Error())
+		}
+		if payment.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("merchantqr.getQRPayments: " + err.Error())
+		}
+		payments = append(payments, payment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("merchantqr.getQRPayments: " + err.Error())
+	}
+
+	return payments, nil
+}
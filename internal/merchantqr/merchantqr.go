@@ -0,0 +1,318 @@
+package merchantqr
+
+/*
+Merchant QR code payments
+
+Payloads follow the EMVCo merchant-presented QR layout: a flat list of
+ID(2 digits) + length(2 digits) + value fields, closed by a CRC16 (tag 63).
+
+00 - Payload format indicator ("01")
+01 - Point of initiation ("11" static, "12" dynamic)
+26 - Merchant account information
+	00 - Globally unique identifier (MERCHANT_ACCOUNT_GUID)
+	01 - Merchant account number
+52 - Merchant category code
+53 - Transaction currency (ISO 4217 numeric)
+54 - Transaction amount (optional on static codes)
+58 - Country code
+59 - Merchant name
+60 - Merchant city
+62 - Additional data
+	05 - Reference label
+63 - CRC16/CCITT-FALSE over the whole payload including "6304"
+
+Static codes are printed once and reused (the payer may enter the amount).
+Dynamic codes carry the amount and a unique reference and can only be paid once.
+*/
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/shopspring/decimal"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	MERCHANT_ACCOUNT_GUID    = "NG.NOUVEAU"
+	DEFAULT_CATEGORY_CODE    = "5999"
+	DEFAULT_COUNTRY_CODE     = "NG"
+	DEFAULT_CURRENCY         = "NGN"
+	DEFAULT_QR_IMAGE_SIZE    = 256
+	REFERENCE_LENGTH         = 12
+	NARRATION_PREFIX         = "QR:"
+	pointOfInitiationStatic  = "11"
+	pointOfInitiationDynamic = "12"
+)
+
+const (
+	StaticCode  = "static"
+	DynamicCode = "dynamic"
+)
+
+type Payload struct {
+	Type          string          `json:"type"`
+	AccountNumber string          `json:"accountNumber"`
+	MerchantName  string          `json:"merchantName"`
+	MerchantCity  string          `json:"merchantCity"`
+	CategoryCode  string          `json:"categoryCode"`
+	CountryCode   string          `json:"countryCode"`
+	Currency      string          `json:"currency"`
+	Amount        decimal.Decimal `json:"amount"`
+	Reference     string          `json:"reference"`
+}
+
+// Encode builds the EMVCo string for the payload
+func (p Payload) Encode() (payload string, err error) {
+	if p.AccountNumber == "" {
+		return "", errors.New("merchantqr.Encode: Account number cannot be empty")
+	}
+	if p.Reference == "" {
+		return "", errors.New("merchantqr.Encode: Reference cannot be empty")
+	}
+	if p.MerchantName == "" {
+		return "", errors.New("merchantqr.Encode: Merchant name cannot be empty")
+	}
+
+	pointOfInitiation := pointOfInitiationStatic
+	switch p.Type {
+	case StaticCode:
+	case DynamicCode:
+		pointOfInitiation = pointOfInitiationDynamic
+		if !p.Amount.IsPositive() {
+			return "", errors.New("merchantqr.Encode: Dynamic codes need an amount")
+		}
+	default:
+		return "", errors.New("merchantqr.Encode: Invalid code type " + p.Type)
+	}
+
+	currencyCode := p.Currency
+	if currencyCode == "" {
+		currencyCode = DEFAULT_CURRENCY
+	}
+	c, err := currency.Get(currencyCode)
+	if err != nil {
+		return "", errors.New("merchantqr.Encode: " + err.Error())
+	}
+	categoryCode := p.CategoryCode
+	if categoryCode == "" {
+		categoryCode = DEFAULT_CATEGORY_CODE
+	}
+	countryCode := p.CountryCode
+	if countryCode == "" {
+		countryCode = DEFAULT_COUNTRY_CODE
+	}
+
+	merchantAccount, err := tlv("00", MERCHANT_ACCOUNT_GUID)
+	if err != nil {
+		return "", errors.New("merchantqr.Encode: " + err.Error())
+	}
+	accountField, err := tlv("01", p.AccountNumber)
+	if err != nil {
+		return "", errors.New("merchantqr.Encode: " + err.Error())
+	}
+	merchantAccount += accountField
+
+	additionalData, err := tlv("05", p.Reference)
+	if err != nil {
+		return "", errors.New("merchantqr.Encode: " + err.Error())
+	}
+
+	fields := [][2]string{
+		{"00", "01"},
+		{"01", pointOfInitiation},
+		{"26", merchantAccount},
+		{"52", categoryCode},
+		{"53", c.NumericCode},
+	}
+	if p.Amount.IsPositive() {
+		fields = append(fields, [2]string{"54", c.Format(p.Amount)})
+	}
+	fields = append(fields,
+		[2]string{"58", countryCode},
+		[2]string{"59", truncate(p.MerchantName, 25)},
+	)
+	if p.MerchantCity != "" {
+		fields = append(fields, [2]string{"60", truncate(p.MerchantCity, 15)})
+	}
+	fields = append(fields, [2]string{"62", additionalData})
+
+	var sb strings.Builder
+	for _, field := range fields {
+		encoded, err := tlv(field[0], field[1])
+		if err != nil {
+			return "", errors.New("merchantqr.Encode: " + err.Error())
+		}
+		sb.WriteString(encoded)
+	}
+	sb.WriteString("6304")
+	sb.WriteString(fmt.Sprintf("%04X", crc16(sb.String())))
+
+	return sb.String(), nil
+}
+
+// Parse decodes and verifies an EMVCo string generated by Encode
+func Parse(payload string) (p Payload, err error) {
+	payload = strings.TrimSpace(payload)
+	if len(payload) < 8 {
+		return Payload{}, errors.New("merchantqr.Parse: Payload too short")
+	}
+
+	// The CRC covers everything up to and including "6304"
+	crcStart := len(payload) - 8
+	if payload[crcStart:crcStart+4] != "6304" {
+		return Payload{}, errors.New("merchantqr.Parse: Payload has no checksum")
+	}
+	expected := fmt.Sprintf("%04X", crc16(payload[:crcStart+4]))
+	if !strings.EqualFold(expected, payload[crcStart+4:]) {
+		return Payload{}, errors.New("merchantqr.Parse: Checksum mismatch")
+	}
+
+	fields, err := readTLV(payload[:crcStart])
+	if err != nil {
+		return Payload{}, errors.New("merchantqr.Parse: " + err.Error())
+	}
+
+	if fields["00"] != "01" {
+		return Payload{}, errors.New("merchantqr.Parse: Unsupported payload format")
+	}
+	switch fields["01"] {
+	case pointOfInitiationStatic:
+		p.Type = StaticCode
+	case pointOfInitiationDynamic:
+		p.Type = DynamicCode
+	default:
+		return Payload{}, errors.New("merchantqr.Parse: Invalid point of initiation")
+	}
+
+	merchantAccount, err := readTLV(fields["26"])
+	if err != nil {
+		return Payload{}, errors.New("merchantqr.Parse: " + err.Error())
+	}
+	if merchantAccount["00"] != MERCHANT_ACCOUNT_GUID {
+		return Payload{}, errors.New("merchantqr.Parse: QR code was not issued by this bank")
+	}
+	p.AccountNumber = merchantAccount["01"]
+	if p.AccountNumber == "" {
+		return Payload{}, errors.New("merchantqr.Parse: Merchant account missing")
+	}
+
+	c, err := currency.GetByNumericCode(fields["53"])
+	if err != nil {
+		return Payload{}, errors.New("merchantqr.Parse: " + err.Error())
+	}
+	p.Currency = c.Code
+
+	if amount, ok := fields["54"]; ok {
+		p.Amount, err = decimal.NewFromString(amount)
+		if err != nil {
+			return Payload{}, errors.New("merchantqr.Parse: Could not convert amount to decimal. " + err.Error())
+		}
+	}
+	if p.Type == DynamicCode && !p.Amount.IsPositive() {
+		return Payload{}, errors.New("merchantqr.Parse: Dynamic code has no amount")
+	}
+
+	additionalData, err := readTLV(fields["62"])
+	if err != nil {
+		return Payload{}, errors.New("merchantqr.Parse: " + err.Error())
+	}
+	p.Reference = additionalData["05"]
+	if p.Reference == "" {
+		return Payload{}, errors.New("merchantqr.Parse: Reference missing")
+	}
+
+	p.CategoryCode = fields["52"]
+	p.CountryCode = fields["58"]
+	p.MerchantName = fields["59"]
+	p.MerchantCity = fields["60"]
+
+	return p, nil
+}
+
+// PNG renders the payload as a QR code image
+func PNG(payload string, size int) ([]byte, error) {
+	if size <= 0 {
+		size = DEFAULT_QR_IMAGE_SIZE
+	}
+	png, err := qrcode.Encode(payload, qrcode.Medium, size)
+	if err != nil {
+		return nil, errors.New("merchantqr.PNG: " + err.Error())
+	}
+	return png, nil
+}
+
+// NewReference returns a random upper case reference for a new code
+func NewReference() (string, error) {
+	const letters = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	reference := make([]byte, REFERENCE_LENGTH)
+	for i := range reference {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			return "", errors.New("merchantqr.NewReference: " + err.Error())
+		}
+		reference[i] = letters[n.Int64()]
+	}
+	return string(reference), nil
+}
+
+// Narration is written on the PAIN transaction so the payer's statement shows the code paid
+func Narration(reference string) string {
+	return NARRATION_PREFIX + reference
+}
+
+func tlv(id string, value string) (string, error) {
+	if len(value) > 99 {
+		return "", errors.New("merchantqr.tlv: Field " + id + " is longer than 99 characters")
+	}
+	return fmt.Sprintf("%s%02d%s", id, len(value), value), nil
+}
+
+func readTLV(payload string) (fields map[string]string, err error) {
+	fields = make(map[string]string)
+	for i := 0; i < len(payload); {
+		if i+4 > len(payload) {
+			return nil, errors.New("merchantqr.readTLV: Truncated field header")
+		}
+		id := payload[i : i+2]
+		length, err := strconv.Atoi(payload[i+2 : i+4])
+		if err != nil {
+			return nil, errors.New("merchantqr.readTLV: Invalid length for field " + id)
+		}
+		i += 4
+		if i+length > len(payload) {
+			return nil, errors.New("merchantqr.readTLV: Truncated field " + id)
+		}
+		fields[id] = payload[i : i+length]
+		i += length
+	}
+	return fields, nil
+}
+
+// crc16 is CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF) as required by EMVCo
+func crc16(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
@@ -0,0 +1,164 @@
+package merchantqr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestEncodeParseDynamic(t *testing.T) {
+	payload := Payload{
+		Type:          DynamicCode,
+		AccountNumber: "115666",
+		MerchantName:  "Mama Put Kitchen",
+		MerchantCity:  "Lagos",
+		Amount:        decimal.NewFromFloat(1250.5),
+		Reference:     "ABC123",
+	}
+
+	encoded, err := payload.Encode()
+	if err != nil {
+		t.Fatalf("EncodeParseDynamic does not pass. Looking for %v, got %v", nil, err)
+	}
+
+	parsed, err := Parse(encoded)
+	if err != nil {
+		t.Fatalf("EncodeParseDynamic does not pass. Looking for %v, got %v", nil, err)
+	}
+	if parsed.Type != DynamicCode || parsed.AccountNumber != "115666" || parsed.Reference != "ABC123" {
+		t.Errorf("EncodeParseDynamic does not pass. Looking for %v, got %v", payload, parsed)
+	}
+	if !parsed.Amount.Equal(payload.Amount) {
+		t.Errorf("EncodeParseDynamic does not pass. Looking for %v, got %v", payload.Amount, parsed.Amount)
+	}
+	if parsed.Currency != DEFAULT_CURRENCY || parsed.MerchantCity != "Lagos" {
+		t.Errorf("EncodeParseDynamic does not pass. Looking for %v, got %v", payload, parsed)
+	}
+}
+
+func TestEncodeParseStatic(t *testing.T) {
+	payload := Payload{
+		Type:          StaticCode,
+		AccountNumber: "115666",
+		MerchantName:  "Mama Put Kitchen",
+		Reference:     "TILL1",
+	}
+
+	encoded, err := payload.Encode()
+	if err != nil {
+		t.Fatalf("EncodeParseStatic does not pass. Looking for %v, got %v", nil, err)
+	}
+
+	parsed, err := Parse(encoded)
+	if err != nil {
+		t.Fatalf("EncodeParseStatic does not pass. Looking for %v, got %v", nil, err)
+	}
+	if parsed.Type != StaticCode || !parsed.Amount.IsZero() {
+		t.Errorf("EncodeParseStatic does not pass. Looking for %v, got %v", payload, parsed)
+	}
+}
+
+func TestEncodeDynamicWithoutAmount(t *testing.T) {
+	payload := Payload{
+		Type:          DynamicCode,
+		AccountNumber: "115666",
+		MerchantName:  "Mama Put Kitchen",
+		Reference:     "ABC123",
+	}
+
+	_, err := payload.Encode()
+	if err == nil {
+		t.Errorf("EncodeDynamicWithoutAmount does not pass. Looking for %v, got %v", "error", nil)
+	}
+}
+
+func TestParseTampered(t *testing.T) {
+	payload := Payload{
+		Type:          DynamicCode,
+		AccountNumber: "115666",
+		MerchantName:  "Mama Put Kitchen",
+		Amount:        decimal.NewFromInt(100),
+		Reference:     "ABC123",
+	}
+	encoded, err := payload.Encode()
+	if err != nil {
+		t.Fatalf("ParseTampered does not pass. Looking for %v, got %v", nil, err)
+	}
+
+	// Change the merchant account without fixing the checksum
+	tampered := []byte(encoded)
+	for i := range tampered {
+		if tampered[i] == '5' {
+			tampered[i] = '6'
+			break
+		}
+	}
+
+	_, err = Parse(string(tampered))
+	if err == nil {
+		t.Errorf("ParseTampered does not pass. Looking for %v, got %v", "Checksum mismatch", nil)
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	// Check value for CRC-16/CCITT-FALSE
+	if crc := crc16("123456789"); crc != 0x29B1 {
+		t.Errorf("CRC16 does not pass. Looking for %X, got %X", 0x29B1, crc)
+	}
+}
+
+func TestNarration(t *testing.T) {
+	if narration := Narration("ABC123"); narration != "QR:ABC123" {
+		t.Errorf("Narration does not pass. Looking for %v, got %v", "QR:ABC123", narration)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	png, err := PNG("test", 0)
+	if err != nil {
+		t.Fatalf("PNG does not pass. Looking for %v, got %v", nil, err)
+	}
+	if len(png) < 8 || string(png[1:4]) != "PNG" {
+		t.Errorf("PNG does not pass. Looking for %v, got %v", "PNG header", png[:8])
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	codes := []Code{
+		{Reference: "DYN1", Type: DynamicCode, Amount: decimal.NewFromInt(100), Timestamp: from.Add(time.Hour)},
+		{Reference: "DYN2", Type: DynamicCode, Amount: decimal.NewFromInt(50), Timestamp: from.Add(time.Hour)},
+		{Reference: "TILL", Type: StaticCode, Amount: decimal.Zero, Timestamp: from.Add(-time.Hour)},
+		{Reference: "OLD", Type: DynamicCode, Amount: decimal.NewFromInt(10), Timestamp: from.Add(-time.Hour)},
+	}
+	qrPayments := []Payment{
+		{ID: 1, Reference: "DYN1", Amount: decimal.NewFromInt(100)},
+		{ID: 2, Reference: "TILL", Amount: decimal.NewFromInt(20)},
+		{ID: 3, Reference: "TILL", Amount: decimal.NewFromInt(30)},
+		{ID: 4, Reference: "UNKNOWN", Amount: decimal.NewFromInt(5)},
+	}
+
+	reconciliation := reconcile(codes, qrPayments, from)
+
+	if !reconciliation.TotalReceived.Equal(decimal.NewFromInt(155)) {
+		t.Errorf("Reconcile does not pass. Looking for %v, got %v", 155, reconciliation.TotalReceived)
+	}
+	if len(reconciliation.Lines) != 3 {
+		t.Fatalf("Reconcile does not pass. Looking for %v, got %v", 3, len(reconciliation.Lines))
+	}
+
+	expected := map[string]string{
+		"DYN1": ReconciliationPaid,
+		"DYN2": ReconciliationUnpaid,
+		"TILL": ReconciliationPaid,
+	}
+	for _, line := range reconciliation.Lines {
+		if expected[line.Reference] != line.Status {
+			t.Errorf("Reconcile does not pass. Looking for %v, got %v", expected[line.Reference], line.Status)
+		}
+	}
+	if len(reconciliation.Unmatched) != 1 || reconciliation.Unmatched[0].ID != 4 {
+		t.Errorf("Reconcile does not pass. Looking for %v, got %v", 1, reconciliation.Unmatched)
+	}
+}
@@ -0,0 +1,29 @@
+package nuban
+
+import (
+	"errors"
+)
+
+// nextSerial increments the bank's sequence and returns the new value.
+// LAST_INSERT_ID(expr) hands the value back on the same statement, so concurrent callers never see the same serial.
+func nextSerial(bankCode string) (serial int64, err error) {
+	insertStatement := "INSERT INTO nuban_sequences (`bankCode`, `lastSerial`) VALUES(?, LAST_INSERT_ID(1)) "
+	insertStatement += "ON DUPLICATE KEY UPDATE `lastSerial` = LAST_INSERT_ID(`lastSerial` + 1)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("nuban.nextSerial: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(bankCode)
+	if err != nil {
+		return 0, errors.New("nuban.nextSerial: " + err.Error())
+	}
+
+	serial, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("nuban.nextSerial: " + err.Error())
+	}
+
+	return serial, nil
+}
@@ -1,39 +1,143 @@
 package nuban
 
+/*
+Nigeria Uniform Bank Account Numbers
+
+A NUBAN is a 9 digit serial followed by a check digit. The check digit is calculated over
+the bank's code and the serial with the CBN weights 3, 7, 3 repeated:
+
+	check = 10 - (sum of weighted digits mod 10), with 10 becoming 0
+
+Deposit money banks have 3 digit codes, other institutions 6 digit codes. A 3 digit code is
+left padded with zeros to 6 digits, which weights it the same as the original scheme.
+
+The bank code is configured with NUBAN_BANK_CODE. Serials are allocated from a per bank
+sequence in nuban_sequences so no two accounts are given the same number.
+*/
+
 import (
-	"crypto/rand"
-	"math/big"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+)
+
+const (
+	SERIAL_LENGTH  = 9
+	NUBAN_LENGTH   = SERIAL_LENGTH + 1
+	MAX_SERIAL     = 999999999
+	BANK_CODE_ENV  = "NUBAN_BANK_CODE"
+	bankCodeLength = 6
 )
 
+var checkDigitWeights = []int{3, 7, 3}
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
 type NUBANGenerator struct {
+	BankCode string
 }
 
+// NewNUBANGenerator returns a generator for the bank code configured in NUBAN_BANK_CODE
 func NewNUBANGenerator() *NUBANGenerator {
-	return &NUBANGenerator{}
+	return &NUBANGenerator{BankCode: strings.TrimSpace(os.Getenv(BANK_CODE_ENV))}
 }
 
-func (n *NUBANGenerator) generateRandomNumber(length int) string {
-	var result string
+// GenerateNUBAN allocates the next serial for the bank and returns it with its check digit
+func (n *NUBANGenerator) GenerateNUBAN() (string, error) {
+	bankCode, err := normalizeBankCode(n.BankCode)
+	if err != nil {
+		return "", errors.New("nuban.GenerateNUBAN: " + err.Error())
+	}
+
+	serial, err := nextSerial(bankCode)
+	if err != nil {
+		return "", errors.New("nuban.GenerateNUBAN: " + err.Error())
+	}
+	if serial > MAX_SERIAL {
+		return "", errors.New("nuban.GenerateNUBAN: Serials exhausted for bank " + bankCode)
+	}
 
-	for i := 0; i < length; i++ {
-		num, _ := rand.Int(rand.Reader, big.NewInt(10))
-		result += num.String()
+	nuban, err := FromSerial(bankCode, serial)
+	if err != nil {
+		return "", errors.New("nuban.GenerateNUBAN: " + err.Error())
 	}
 
-	return result
+	return nuban, nil
 }
 
-func (n *NUBANGenerator) GenerateNUBAN() string {
-	bankCode := n.generateRandomNumber(3)
-	branchCode := n.generateRandomNumber(3)
-	accountNumber := n.generateRandomNumber(4)
+// FromSerial builds the NUBAN for a serial number
+func FromSerial(bankCode string, serial int64) (string, error) {
+	if serial < 0 || serial > MAX_SERIAL {
+		return "", errors.New("nuban.FromSerial: Serial out of range")
+	}
+	serialDigits := strconv.FormatInt(serial, 10)
+	serialDigits = strings.Repeat("0", SERIAL_LENGTH-len(serialDigits)) + serialDigits
 
-	return bankCode + branchCode + accountNumber
+	checkDigit, err := CheckDigit(bankCode, serialDigits)
+	if err != nil {
+		return "", errors.New("nuban.FromSerial: " + err.Error())
+	}
+
+	return serialDigits + strconv.Itoa(checkDigit), nil
 }
 
-//Test
-// func main() {
-// 	nubanGenerator := NewNUBANGenerator()
-// 	nuban := nubanGenerator.GenerateNUBAN()
-// 	fmt.Println("Generated NUBAN:", nuban)
-// }
+// CheckDigit calculates the CBN check digit for a 9 digit serial
+func CheckDigit(bankCode string, serial string) (int, error) {
+	bankCode, err := normalizeBankCode(bankCode)
+	if err != nil {
+		return 0, errors.New("nuban.CheckDigit: " + err.Error())
+	}
+	if len(serial) != SERIAL_LENGTH || !isDigits(serial) {
+		return 0, errors.New("nuban.CheckDigit: Serial must be " + strconv.Itoa(SERIAL_LENGTH) + " digits")
+	}
+
+	sum := 0
+	for i, digit := range bankCode + serial {
+		sum += int(digit-'0') * checkDigitWeights[i%len(checkDigitWeights)]
+	}
+
+	return (10 - sum%10) % 10, nil
+}
+
+// ValidateNUBAN checks an account number is a well formed NUBAN for the bank
+func ValidateNUBAN(bankCode string, accountNumber string) error {
+	if len(accountNumber) != NUBAN_LENGTH || !isDigits(accountNumber) {
+		return errors.New("nuban.ValidateNUBAN: Account number must be " + strconv.Itoa(NUBAN_LENGTH) + " digits")
+	}
+
+	checkDigit, err := CheckDigit(bankCode, accountNumber[:SERIAL_LENGTH])
+	if err != nil {
+		return errors.New("nuban.ValidateNUBAN: " + err.Error())
+	}
+	if int(accountNumber[SERIAL_LENGTH]-'0') != checkDigit {
+		return errors.New("nuban.ValidateNUBAN: Invalid check digit")
+	}
+
+	return nil
+}
+
+// normalizeBankCode left pads a 3 digit bank code to 6 digits
+func normalizeBankCode(bankCode string) (string, error) {
+	bankCode = strings.TrimSpace(bankCode)
+	if (len(bankCode) != 3 && len(bankCode) != bankCodeLength) || !isDigits(bankCode) {
+		return "", errors.New("Bank code must be 3 or 6 digits")
+	}
+
+	return strings.Repeat("0", bankCodeLength-len(bankCode)) + bankCode, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
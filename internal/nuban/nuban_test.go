@@ -0,0 +1,55 @@
+package nuban
+
+import (
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	// Worked example from the CBN NUBAN specification
+	checkDigit, err := CheckDigit("011", "000001457")
+	if err != nil || checkDigit != 9 {
+		t.Errorf("CheckDigit does not pass. Looking for %v, got %v (%v)", 9, checkDigit, err)
+	}
+
+	// A 3 digit code is weighted the same as its zero padded 6 digit form
+	padded, err := CheckDigit("000011", "000001457")
+	if err != nil || padded != checkDigit {
+		t.Errorf("CheckDigit does not pass. Looking for %v, got %v (%v)", checkDigit, padded, err)
+	}
+}
+
+func TestFromSerial(t *testing.T) {
+	nuban, err := FromSerial("011", 1457)
+	if err != nil || nuban != "0000014579" {
+		t.Errorf("FromSerial does not pass. Looking for %v, got %v (%v)", "0000014579", nuban, err)
+	}
+
+	_, err = FromSerial("011", MAX_SERIAL+1)
+	if err == nil {
+		t.Errorf("FromSerial does not pass. Looking for %v, got %v", "error", err)
+	}
+}
+
+func TestValidateNUBAN(t *testing.T) {
+	for serial := int64(1); serial < 200; serial += 7 {
+		nuban, err := FromSerial("999058", serial)
+		if err != nil {
+			t.Errorf("FromSerial does not pass. Looking for %v, got %v", nil, err)
+		}
+		if err := ValidateNUBAN("999058", nuban); err != nil {
+			t.Errorf("ValidateNUBAN does not pass. Looking for %v, got %v for %v", nil, err, nuban)
+		}
+	}
+
+	invalid := [][]string{
+		{"011", "0000014578"},
+		{"011", "000001457"},
+		{"011", "00000145a9"},
+		{"11", "0000014579"},
+	}
+	for _, c := range invalid {
+		if err := ValidateNUBAN(c[0], c[1]); err == nil {
+			t.Errorf("ValidateNUBAN does not pass. Looking for %v, got %v for %v", "error", err, c)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package overdrafts
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const facilityColumns = "f.`id`, f.`accountNumber`, f.`currencyCode`, f.`requestedLimit`, f.`approvedLimit`, f.`rate`, f.`arrangementFee`, IFNULL(f.`expiryDate`, ''), f.`status`, " +
+	"f.`reason`, f.`applicant`, f.`approver`, f.`closedBy`, IFNULL(f.`approvedAt`, ''), IFNULL(f.`closedAt`, ''), f.`timestamp`"
+
+const chargeColumns = "`id`, `facilityId`, `accountNumber`, `currencyCode`, `chargeType`, `chargeDate`, `balance`, `rate`, `amount`, `status`, `timestamp`"
+
+func saveFacility(facility Facility) (id int64, err error) {
+	insertStatement := "INSERT INTO overdraft_facilities (`accountNumber`, `currencyCode`, `requestedLimit`, `status`, `reason`, `applicant`) VALUES(?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("overdrafts.saveFacility: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(facility.AccountNumber, facility.CurrencyCode, facility.RequestedLimit, facility.Status, facility.Reason, facility.Applicant)
+	if err != nil {
+		return 0, errors.New("overdrafts.saveFacility: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("overdrafts.saveFacility: " + err.Error())
+	}
+	return id, nil
+}
+
+// approveFacility activates an application with its terms and sets the account's limit
+func approveFacility(facility Facility) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("overdrafts.approveFacility: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE overdraft_facilities SET `status` = ?, `approvedLimit` = ?, `rate` = ?, `arrangementFee` = ?, `expiryDate` = ?, `approver` = ?, `approvedAt` = CURDATE() WHERE `id` = ? AND `status` = ?",
+		FacilityActive, facility.Limit, facility.Rate, facility.ArrangementFee, facility.ExpiryDate, facility.Approver, facility.ID, FacilityApplied)
+	if err != nil {
+		return errors.New("overdrafts.approveFacility: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("overdrafts.approveFacility: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("overdrafts.approveFacility: Overdraft is no longer " + FacilityApplied)
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET `overdraft` = ? WHERE `accountNumber` = ?", facility.Limit, facility.AccountNumber)
+	if err != nil {
+		return errors.New("overdrafts.approveFacility: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("overdrafts.approveFacility: " + err.Error())
+	}
+	return nil
+}
+
+// amendFacility changes an active facility's limit and expiry date, and the account's limit with it
+func amendFacility(facility Facility) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("overdrafts.amendFacility: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE overdraft_facilities SET `approvedLimit` = ?, `expiryDate` = ?, `approver` = ? WHERE `id` = ? AND `status` = ?",
+		facility.Limit, facility.ExpiryDate, facility.Approver, facility.ID, FacilityActive)
+	if err != nil {
+		return errors.New("overdrafts.amendFacility: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("overdrafts.amendFacility: " + err.Error())
+	}
+	if affected == 0 {
+		// Nothing changed or it's no longer active, tell them apart
+		var status string
+		err = tx.QueryRow("SELECT `status` FROM overdraft_facilities WHERE `id` = ?", facility.ID).Scan(&status)
+		if err != nil {
+			return errors.New("overdrafts.amendFacility: " + err.Error())
+		}
+		if status != FacilityActive {
+			return errors.New("overdrafts.amendFacility: Overdraft is no longer " + FacilityActive)
+		}
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET `overdraft` = ? WHERE `accountNumber` = ?", facility.Limit, facility.AccountNumber)
+	if err != nil {
+		return errors.New("overdrafts.amendFacility: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("overdrafts.amendFacility: " + err.Error())
+	}
+	return nil
+}
+
+// closeFacility moves a facility from one status to a closed one, failing if it isn't in
+// the from status. Closing an active facility takes the account's limit to zero.
+func closeFacility(id int64, from string, to string, reason string, actor string) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("overdrafts.closeFacility: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	if from == FacilityActive {
+		_, err = tx.Exec("UPDATE accounts a JOIN overdraft_facilities f ON f.`accountNumber` = a.`accountNumber` SET a.`overdraft` = 0 WHERE f.`id` = ? AND f.`status` = ?", id, from)
+		if err != nil {
+			return errors.New("overdrafts.closeFacility: " + err.Error())
+		}
+	}
+
+	res, err := tx.Exec("UPDATE overdraft_facilities SET `status` = ?, `reason` = IF(? = '', `reason`, ?), `closedBy` = ?, `closedAt` = CURDATE() WHERE `id` = ? AND `status` = ?",
+		to, reason, reason, actor, id, from)
+	if err != nil {
+		return errors.New("overdrafts.closeFacility: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("overdrafts.closeFacility: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("overdrafts.closeFacility: Overdraft is no longer " + from)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("overdrafts.closeFacility: " + err.Error())
+	}
+	return nil
+}
+
+// expireFacilities expires the active facilities whose expiry date has come and takes
+// their accounts' limits to zero
+func expireFacilities(today string) (expired int, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("overdrafts.expireFacilities: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE accounts a JOIN overdraft_facilities f ON f.`accountNumber` = a.`accountNumber` SET a.`overdraft` = 0 WHERE f.`status` = ? AND f.`expiryDate` <= ?",
+		FacilityActive, today)
+	if err != nil {
+		return 0, errors.New("overdrafts.expireFacilities: " + err.Error())
+	}
+
+	res, err := tx.Exec("UPDATE overdraft_facilities SET `status` = ?, `closedBy` = ?, `closedAt` = CURDATE() WHERE `status` = ? AND `expiryDate` <= ?",
+		FacilityExpired, SYSTEM_ACTOR, FacilityActive, today)
+	if err != nil {
+		return 0, errors.New("overdrafts.expireFacilities: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.New("overdrafts.expireFacilities: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("overdrafts.expireFacilities: " + err.Error())
+	}
+	return int(affected), nil
+}
+
+func getFacility(id int64) (facility Facility, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+facilityColumns+" FROM `overdraft_facilities` f WHERE f.`id` = ?", id)
+	if err != nil {
+		return Facility{}, false, errors.New("overdrafts.getFacility: " + err.Error())
+	}
+	defer rows.Close()
+
+	facilities, err := scanFacilities(rows)
+	if err != nil {
+		return Facility{}, false, errors.New("overdrafts.getFacility: " + err.Error())
+	}
+	if len(facilities) == 0 {
+		return Facility{}, false, nil
+	}
+
+	return facilities[0], true, nil
+}
+
+// getOpenFacility returns the facility an account has applied for or holds
+func getOpenFacility(accountNumber string) (facility Facility, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+facilityColumns+" FROM `overdraft_facilities` f WHERE f.`accountNumber` = ? AND f.`status` IN (?, ?) ORDER BY f.`id` DESC LIMIT 1",
+		accountNumber, FacilityApplied, FacilityActive)
+	if err != nil {
+		return Facility{}, false, errors.New("overdrafts.getOpenFacility: " + err.Error())
+	}
+	defer rows.Close()
+
+	facilities, err := scanFacilities(rows)
+	if err != nil {
+		return Facility{}, false, errors.New("overdrafts.getOpenFacility: " + err.Error())
+	}
+	if len(facilities) == 0 {
+		return Facility{}, false, nil
+	}
+
+	return facilities[0], true, nil
+}
+
+func getAccountFacilities(accountNumber string) (facilities []Facility, err error) {
+	rows, err := Config.Db.Query("SELECT "+facilityColumns+" FROM `overdraft_facilities` f WHERE f.`accountNumber` = ? ORDER BY f.`id` DESC", accountNumber)
+	if err != nil {
+		return nil, errors.New("overdrafts.getAccountFacilities: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanFacilities(rows)
+}
+
+func getFacilitiesByStatus(status string) (facilities []Facility, err error) {
+	rows, err := Config.Db.Query("SELECT "+facilityColumns+" FROM `overdraft_facilities` f WHERE f.`status` = ? ORDER BY f.`id`", status)
+	if err != nil {
+		return nil, errors.New("overdrafts.getFacilitiesByStatus: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanFacilities(rows)
+}
+
+func scanFacilities(rows *sql.Rows) (facilities []Facility, err error) {
+	facilities = make([]Facility, 0)
+	for rows.Next() {
+		var facility Facility
+		var timestamp string
+		if err := rows.Scan(&facility.ID, &facility.AccountNumber, &facility.CurrencyCode, &facility.RequestedLimit, &facility.Limit, &facility.Rate,
+			&facility.ArrangementFee, &facility.ExpiryDate, &facility.Status, &facility.Reason, &facility.Applicant, &facility.Approver, &facility.ClosedBy,
+			&facility.ApprovedAt, &facility.ClosedAt, &timestamp); err != nil {
+			return nil, errors.New("overdrafts.scanFacilities: " + err.Error())
+		}
+		facility.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("overdrafts.scanFacilities: " + err.Error())
+		}
+		facilities = append(facilities, facility)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("overdrafts.scanFacilities: " + err.Error())
+	}
+
+	return facilities, nil
+}
+
+// getOverdrawnAccounts returns the accounts with a negative ledger balance and the
+// latest facility approved on each. Accounts overdrawn without ever having had a facility
+// aren't charged.
+func getOverdrawnAccounts() (accounts []overdrawn, err error) {
+	query := "SELECT " + facilityColumns + ", a.`accountBalance` FROM `accounts` a JOIN `overdraft_facilities` f ON f.`id` = " +
+		"(SELECT MAX(l.`id`) FROM `overdraft_facilities` l WHERE l.`accountNumber` = a.`accountNumber` AND l.`approvedAt` IS NOT NULL) " +
+		"WHERE a.`accountBalance` < 0 ORDER BY f.`id`"
+	rows, err := Config.Db.Query(query)
+	if err != nil {
+		return nil, errors.New("overdrafts.getOverdrawnAccounts: " + err.Error())
+	}
+	defer rows.Close()
+
+	accounts = make([]overdrawn, 0)
+	for rows.Next() {
+		var account overdrawn
+		var timestamp string
+		facility := &account.Facility
+		if err := rows.Scan(&facility.ID, &facility.AccountNumber, &facility.CurrencyCode, &facility.RequestedLimit, &facility.Limit, &facility.Rate,
+			&facility.ArrangementFee, &facility.ExpiryDate, &facility.Status, &facility.Reason, &facility.Applicant, &facility.Approver, &facility.ClosedBy,
+			&facility.ApprovedAt, &facility.ClosedAt, &timestamp, &account.Balance); err != nil {
+			return nil, errors.New("overdrafts.getOverdrawnAccounts: " + err.Error())
+		}
+		facility.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("overdrafts.getOverdrawnAccounts: " + err.Error())
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("overdrafts.getOverdrawnAccounts: " + err.Error())
+	}
+
+	return accounts, nil
+}
+
+func saveCharge(charge Charge) (id int64, err error) {
+	insertStatement := "INSERT INTO overdraft_charges (`facilityId`, `accountNumber`, `currencyCode`, `chargeType`, `chargeDate`, `balance`, `rate`, `amount`, `status`) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("overdrafts.saveCharge: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(charge.FacilityID, charge.AccountNumber, charge.CurrencyCode, charge.ChargeType, charge.ChargeDate, charge.Balance, charge.Rate, charge.Amount, charge.Status)
+	if err != nil {
+		return 0, errors.New("overdrafts.saveCharge: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("overdrafts.saveCharge: " + err.Error())
+	}
+	return id, nil
+}
+
+// getCharge returns the charge of a type recorded for a facility on a date, with a zero ID when there's none
+func getCharge(facilityID int64, chargeType string, chargeDate string) (charge Charge, err error) {
+	rows, err := Config.Db.Query("SELECT "+chargeColumns+" FROM `overdraft_charges` WHERE `facilityId` = ? AND `chargeType` = ? AND `chargeDate` = ?",
+		facilityID, chargeType, chargeDate)
+	if err != nil {
+		return Charge{}, errors.New("overdrafts.getCharge: " + err.Error())
+	}
+	defer rows.Close()
+
+	charges, err := scanCharges(rows)
+	if err != nil {
+		return Charge{}, errors.New("overdrafts.getCharge: " + err.Error())
+	}
+	if len(charges) == 0 {
+		return Charge{}, nil
+	}
+
+	return charges[0], nil
+}
+
+func getCharges(facilityID int64) (charges []Charge, err error) {
+	rows, err := Config.Db.Query("SELECT "+chargeColumns+" FROM `overdraft_charges` WHERE `facilityId` = ? ORDER BY `chargeDate` DESC, `id` DESC", facilityID)
+	if err != nil {
+		return nil, errors.New("overdrafts.getCharges: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanCharges(rows)
+}
+
+func getPendingCharges() (charges []Charge, err error) {
+	rows, err := Config.Db.Query("SELECT "+chargeColumns+" FROM `overdraft_charges` WHERE `status` = ? ORDER BY `id`", ChargePending)
+	if err != nil {
+		return nil, errors.New("overdrafts.getPendingCharges: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanCharges(rows)
+}
+
+func scanCharges(rows *sql.Rows) (charges []Charge, err error) {
+	charges = make([]Charge, 0)
+	for rows.Next() {
+		var charge Charge
+		var timestamp string
+		if err := rows.Scan(&charge.ID, &charge.FacilityID, &charge.AccountNumber, &charge.CurrencyCode, &charge.ChargeType, &charge.ChargeDate,
+			&charge.Balance, &charge.Rate, &charge.Amount, &charge.Status, &timestamp); err != nil {
+			return nil, errors.New("overdrafts.scanCharges: " + err.Error())
+		}
+		charge.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("overdrafts.scanCharges: " + err.Error())
+		}
+		charges = append(charges, charge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("overdrafts.scanCharges: " + err.Error())
+	}
+
+	return charges, nil
+}
+
+func updateChargePosted(id int64) (err error) {
+	res, err := Config.Db.Exec("UPDATE overdraft_charges SET `status` = ? WHERE `id` = ? AND `status` = ?", ChargePosted, id, ChargePending)
+	if err != nil {
+		return errors.New("overdrafts.updateChargePosted: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("overdrafts.updateChargePosted: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("overdrafts.updateChargePosted: Charge was already posted")
+	}
+	return nil
+}
@@ -0,0 +1,551 @@
+package overdrafts
+
+/*
+Overdraft facilities
+
+A customer applies for an overdraft on an account with the limit they'd like. The bank
+approves it with a limit, a debit interest rate, an arrangement fee and an expiry date, or
+declines it. An account has at most one facility applied for or active at a time.
+
+The approved limit is held in the account's overdraft column, which payments adds to the
+available balance when it checks a debit can be covered. The balances themselves only ever
+hold the customer's own money, so they go negative as the overdraft is used.
+
+	applied -> active | declined
+	active  -> expired | cancelled
+
+Charges are posted with PAIN 1004, which takes them even past the limit:
+
+  - the arrangement fee, once, when the facility is approved, to OVERDRAFT_FEES_ACCOUNT_NUMBER_<CODE>
+  - debit interest every day the ledger balance is negative, balance x rate / 365 rounded
+    to the currency's minor units, to OVERDRAFT_INTEREST_ACCOUNT_NUMBER_<CODE>
+
+Each charge is recorded in overdraft_charges before it's posted, one per facility, type and
+day, so a charge run can be repeated without charging twice. Interest keeps running at the
+facility's rate after it has expired or been cancelled until the account is back in credit.
+Interest is only charged for the current day, since the ledger balance is only known as it
+stands now; money held for payments that haven't posted isn't charged for.
+
+Facilities past their expiry date are expired by the charge run and the account's limit drops
+to zero. A limit can also be amended while the facility is active.
+*/
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/payments"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	FacilityApplied   = "applied"
+	FacilityActive    = "active"
+	FacilityDeclined  = "declined"
+	FacilityExpired   = "expired"
+	FacilityCancelled = "cancelled"
+
+	ChargeInterest       = "interest"
+	ChargeArrangementFee = "arrangement_fee"
+
+	ChargePending = "pending"
+	ChargePosted  = "posted"
+
+	DAYS_IN_YEAR    = 365
+	DATE_LAYOUT     = "2006-01-02"
+	MAX_TERM_MONTHS = 24
+	SYSTEM_ACTOR    = "system"
+
+	feesAccountPrefix     = "OVERDRAFT_FEES_ACCOUNT_NUMBER_"
+	interestAccountPrefix = "OVERDRAFT_INTEREST_ACCOUNT_NUMBER_"
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Facility is an overdraft applied for on an account
+type Facility struct {
+	ID             int64           `json:"id"`
+	AccountNumber  string          `json:"accountNumber"`
+	CurrencyCode   string          `json:"currencyCode"`
+	RequestedLimit decimal.Decimal `json:"requestedLimit"`
+	Limit          decimal.Decimal `json:"limit"`
+	Rate           decimal.Decimal `json:"rate"`
+	ArrangementFee decimal.Decimal `json:"arrangementFee"`
+	ExpiryDate     string          `json:"expiryDate,omitempty"`
+	Status         string          `json:"status"`
+	Reason         string          `json:"reason,omitempty"`
+	Applicant      string          `json:"applicant"`
+	Approver       string          `json:"approver,omitempty"`
+	ClosedBy       string          `json:"closedBy,omitempty"`
+	ApprovedAt     string          `json:"approvedAt,omitempty"`
+	ClosedAt       string          `json:"closedAt,omitempty"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// Charge is an arrangement fee or a day's interest taken from an overdrawn account
+type Charge struct {
+	ID            int64           `json:"id"`
+	FacilityID    int64           `json:"facilityId"`
+	AccountNumber string          `json:"accountNumber"`
+	CurrencyCode  string          `json:"currencyCode"`
+	ChargeType    string          `json:"chargeType"`
+	ChargeDate    string          `json:"chargeDate"`
+	Balance       decimal.Decimal `json:"balance"`
+	Rate          decimal.Decimal `json:"rate"`
+	Amount        decimal.Decimal `json:"amount"`
+	Status        string          `json:"status"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// Run is what a charge run did
+type Run struct {
+	Date     string `json:"date"`
+	Expired  int    `json:"expired"`
+	Fees     int    `json:"fees"`
+	Interest int    `json:"interest"`
+}
+
+// overdrawn is an account with a negative ledger balance and the facility it's charged under
+type overdrawn struct {
+	Facility Facility
+	Balance  decimal.Decimal
+}
+
+// Apply asks for an overdraft on an account
+func Apply(accountNumber string, requestedLimit string, reason string, applicant string) (Facility, error) {
+	facility := Facility{
+		AccountNumber: strings.TrimSpace(accountNumber),
+		Reason:        strings.TrimSpace(reason),
+		Applicant:     applicant,
+		Status:        FacilityApplied,
+	}
+
+	var err error
+	facility.CurrencyCode, err = payments.AccountCurrency(facility.AccountNumber)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Apply: " + err.Error())
+	}
+	active, err := payments.CheckIfAccountIsActive(facility.AccountNumber)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Apply: " + err.Error())
+	}
+	if !active {
+		return Facility{}, errors.New("overdrafts.Apply: Account " + facility.AccountNumber + " is not active")
+	}
+	facility.RequestedLimit, err = parseAmount(facility.CurrencyCode, "Requested limit", requestedLimit)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Apply: " + err.Error())
+	}
+	if !facility.RequestedLimit.IsPositive() {
+		return Facility{}, errors.New("overdrafts.Apply: Requested limit must be greater than zero")
+	}
+
+	open, found, err := getOpenFacility(facility.AccountNumber)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Apply: " + err.Error())
+	}
+	if found {
+		return Facility{}, errors.New("overdrafts.Apply: The account already has an overdraft " + open.Status)
+	}
+
+	facility.ID, err = saveFacility(facility)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Apply: " + err.Error())
+	}
+	facility.Limit = decimal.Zero
+	facility.Rate = decimal.Zero
+	facility.ArrangementFee = decimal.Zero
+	facility.Timestamp = time.Now()
+
+	return facility, nil
+}
+
+// Approve grants an overdraft with a limit, an annual debit interest rate, an arrangement
+// fee and an expiry date (YYYY-MM-DD). The limit is available straight away and the fee is
+// charged to the account.
+func Approve(id int64, limit string, rate string, arrangementFee string, expiryDate string, approver string) (Facility, error) {
+	facility, err := Get(id)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: " + err.Error())
+	}
+	if facility.Status != FacilityApplied {
+		return Facility{}, errors.New("overdrafts.Approve: Overdraft is " + facility.Status)
+	}
+
+	// Staff can't approve credit on their own accounts
+	holder, err := payments.IsAccountHolder(approver, facility.AccountNumber)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: " + err.Error())
+	}
+	if approver == "" || holder {
+		return Facility{}, errors.New("overdrafts.Approve: Approver not valid")
+	}
+
+	facility.Limit, err = parseAmount(facility.CurrencyCode, "Limit", limit)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: " + err.Error())
+	}
+	facility.ArrangementFee, err = parseAmount(facility.CurrencyCode, "Arrangement fee", arrangementFee)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: " + err.Error())
+	}
+	facility.Rate, err = decimal.NewFromString(strings.TrimSpace(rate))
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: Rate is not a valid number")
+	}
+	facility.ExpiryDate = strings.TrimSpace(expiryDate)
+	facility.Approver = approver
+	err = validateTerms(facility, time.Now())
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: " + err.Error())
+	}
+
+	err = approveFacility(facility)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Approve: " + err.Error())
+	}
+	facility.Status = FacilityActive
+	facility.ApprovedAt = time.Now().Format(DATE_LAYOUT)
+
+	// The facility stands even if the fee can't be posted now, the charge run retries it
+	if facility.ArrangementFee.IsPositive() {
+		charge := Charge{
+			FacilityID:    facility.ID,
+			AccountNumber: facility.AccountNumber,
+			CurrencyCode:  facility.CurrencyCode,
+			ChargeType:    ChargeArrangementFee,
+			ChargeDate:    facility.ApprovedAt,
+			Balance:       decimal.Zero,
+			Rate:          decimal.Zero,
+			Amount:        facility.ArrangementFee,
+		}
+		_, err = postCharge(charge, approver)
+		if err != nil {
+			return facility, errors.New("overdrafts.Approve: Overdraft approved but the arrangement fee was not charged. " + err.Error())
+		}
+	}
+
+	return facility, nil
+}
+
+// Decline turns down an application
+func Decline(id int64, reason string, approver string) (Facility, error) {
+	facility, err := Get(id)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Decline: " + err.Error())
+	}
+	if facility.Status != FacilityApplied {
+		return Facility{}, errors.New("overdrafts.Decline: Overdraft is " + facility.Status)
+	}
+
+	err = closeFacility(facility.ID, FacilityApplied, FacilityDeclined, strings.TrimSpace(reason), approver)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Decline: " + err.Error())
+	}
+	facility.Status = FacilityDeclined
+	facility.Reason = strings.TrimSpace(reason)
+	facility.ClosedBy = approver
+	facility.ClosedAt = time.Now().Format(DATE_LAYOUT)
+
+	return facility, nil
+}
+
+// Amend changes an active facility's limit and expiry date. A lower limit applies at once
+// even if the account is already overdrawn past it.
+func Amend(id int64, limit string, expiryDate string, approver string) (Facility, error) {
+	facility, err := Get(id)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Amend: " + err.Error())
+	}
+	if facility.Status != FacilityActive {
+		return Facility{}, errors.New("overdrafts.Amend: Overdraft is " + facility.Status)
+	}
+
+	facility.Limit, err = parseAmount(facility.CurrencyCode, "Limit", limit)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Amend: " + err.Error())
+	}
+	if strings.TrimSpace(expiryDate) != "" {
+		facility.ExpiryDate = strings.TrimSpace(expiryDate)
+	}
+	facility.Approver = approver
+	err = validateTerms(facility, time.Now())
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Amend: " + err.Error())
+	}
+
+	err = amendFacility(facility)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Amend: " + err.Error())
+	}
+	return facility, nil
+}
+
+// Cancel ends an application or an active facility. The account's limit drops to zero
+// straight away; an overdrawn account keeps being charged interest until it's back in credit.
+func Cancel(id int64, reason string, actor string) (Facility, error) {
+	facility, err := Get(id)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Cancel: " + err.Error())
+	}
+	if facility.Status != FacilityApplied && facility.Status != FacilityActive {
+		return Facility{}, errors.New("overdrafts.Cancel: Overdraft is " + facility.Status)
+	}
+
+	err = closeFacility(facility.ID, facility.Status, FacilityCancelled, strings.TrimSpace(reason), actor)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Cancel: " + err.Error())
+	}
+	facility.Status = FacilityCancelled
+	facility.Reason = strings.TrimSpace(reason)
+	facility.ClosedBy = actor
+	facility.ClosedAt = time.Now().Format(DATE_LAYOUT)
+
+	return facility, nil
+}
+
+// CancelAccountFacilities cancels whatever overdraft the account has applied for or holds,
+// used when the account is closed
+func CancelAccountFacilities(accountNumber string, actor string) error {
+	facility, found, err := getOpenFacility(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return errors.New("overdrafts.CancelAccountFacilities: " + err.Error())
+	}
+	if !found {
+		return nil
+	}
+	_, err = Cancel(facility.ID, "Account closed", actor)
+	if err != nil {
+		return errors.New("overdrafts.CancelAccountFacilities: " + err.Error())
+	}
+	return nil
+}
+
+// Get returns a facility by ID
+func Get(id int64) (Facility, error) {
+	facility, found, err := getFacility(id)
+	if err != nil {
+		return Facility{}, errors.New("overdrafts.Get: " + err.Error())
+	}
+	if !found {
+		return Facility{}, errors.New("overdrafts.Get: Overdraft " + strconv.FormatInt(id, 10) + " not found")
+	}
+	return facility, nil
+}
+
+// ForAccount lists the facilities applied for on an account, the latest first
+func ForAccount(accountNumber string) ([]Facility, error) {
+	facilities, err := getAccountFacilities(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return nil, errors.New("overdrafts.ForAccount: " + err.Error())
+	}
+	return facilities, nil
+}
+
+// Facilities lists the facilities in a status, applications waiting for a decision when
+// no status is given
+func Facilities(status string) ([]Facility, error) {
+	status = strings.TrimSpace(status)
+	if status == "" {
+		status = FacilityApplied
+	}
+	facilities, err := getFacilitiesByStatus(status)
+	if err != nil {
+		return nil, errors.New("overdrafts.Facilities: " + err.Error())
+	}
+	return facilities, nil
+}
+
+// Charges lists the charges taken under a facility, the latest first
+func Charges(facilityID int64) ([]Charge, error) {
+	charges, err := getCharges(facilityID)
+	if err != nil {
+		return nil, errors.New("overdrafts.Charges: " + err.Error())
+	}
+	return charges, nil
+}
+
+// RunCharges expires facilities past their expiry date, retries charges that couldn't be
+// posted and charges today's interest on every overdrawn account. A day already charged is
+// skipped. One account failing doesn't stop the rest.
+func RunCharges() (run Run, err error) {
+	today := time.Now().Format(DATE_LAYOUT)
+	run.Date = today
+	failures := []string{}
+
+	run.Expired, err = expireFacilities(today)
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	pending, err := getPendingCharges()
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+	for _, charge := range pending {
+		_, err = postCharge(charge, SYSTEM_ACTOR)
+		if err != nil {
+			failures = append(failures, charge.AccountNumber+": "+err.Error())
+			continue
+		}
+		if charge.ChargeType == ChargeArrangementFee {
+			run.Fees++
+		} else {
+			run.Interest++
+		}
+	}
+
+	accounts, err := getOverdrawnAccounts()
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+	for _, account := range accounts {
+		charge, ok := dailyInterestCharge(account, today)
+		if !ok {
+			continue
+		}
+		posted, err := postCharge(charge, SYSTEM_ACTOR)
+		if err != nil {
+			failures = append(failures, account.Facility.AccountNumber+": "+err.Error())
+			continue
+		}
+		if posted {
+			run.Interest++
+		}
+	}
+
+	if len(failures) > 0 {
+		return run, errors.New("overdrafts.RunCharges: " + strings.Join(failures, "; "))
+	}
+	return run, nil
+}
+
+// DailyInterest is a day's debit interest on an overdrawn balance at an annual rate,
+// actual/365, rounded to the currency's minor units
+func DailyInterest(currencyCode string, overdrawnBy decimal.Decimal, rate decimal.Decimal) decimal.Decimal {
+	if !overdrawnBy.IsPositive() || !rate.IsPositive() {
+		return decimal.Zero
+	}
+	return currency.Round(currencyCode, overdrawnBy.Mul(rate).Div(decimal.NewFromInt(100*DAYS_IN_YEAR)))
+}
+
+// dailyInterestCharge works out the day's interest for an overdrawn account. Nothing is
+// charged when it rounds to zero.
+func dailyInterestCharge(account overdrawn, date string) (Charge, bool) {
+	overdrawnBy := account.Balance.Neg()
+	amount := DailyInterest(account.Facility.CurrencyCode, overdrawnBy, account.Facility.Rate)
+	if !amount.IsPositive() {
+		return Charge{}, false
+	}
+	return Charge{
+		FacilityID:    account.Facility.ID,
+		AccountNumber: account.Facility.AccountNumber,
+		CurrencyCode:  account.Facility.CurrencyCode,
+		ChargeType:    ChargeInterest,
+		ChargeDate:    date,
+		Balance:       account.Balance,
+		Rate:          account.Facility.Rate,
+		Amount:        amount,
+	}, true
+}
+
+// postCharge records a charge as pending, unless it has been recorded before, and posts
+// it. A charge already posted is left alone and reported as not posted.
+func postCharge(charge Charge, initiator string) (bool, error) {
+	if charge.ID == 0 {
+		recorded, err := getCharge(charge.FacilityID, charge.ChargeType, charge.ChargeDate)
+		if err != nil {
+			return false, errors.New("overdrafts.postCharge: " + err.Error())
+		}
+		if recorded.ID != 0 {
+			charge = recorded
+		} else {
+			charge.Status = ChargePending
+			charge.ID, err = saveCharge(charge)
+			if err != nil {
+				return false, errors.New("overdrafts.postCharge: " + err.Error())
+			}
+		}
+	}
+	if charge.Status == ChargePosted {
+		return false, nil
+	}
+
+	prefix := interestAccountPrefix
+	narration := "Overdraft interest for " + charge.ChargeDate
+	if charge.ChargeType == ChargeArrangementFee {
+		prefix = feesAccountPrefix
+		narration = "Overdraft arrangement fee"
+	}
+	income, err := systemAccount(prefix, charge.CurrencyCode)
+	if err != nil {
+		return false, errors.New("overdrafts.postCharge: " + err.Error())
+	}
+
+	_, err = payments.ProcessPAIN([]string{"", "pain", "1004", charge.AccountNumber + "@", income + "@", charge.Amount.String(), narration, initiator})
+	if err != nil {
+		return false, errors.New("overdrafts.postCharge: " + err.Error())
+	}
+	err = updateChargePosted(charge.ID)
+	if err != nil {
+		return false, errors.New("overdrafts.postCharge: Charge posted but not recorded. " + err.Error())
+	}
+	return true, nil
+}
+
+// validateTerms checks an approved facility's limit, rate and expiry date
+func validateTerms(facility Facility, now time.Time) error {
+	if !facility.Limit.IsPositive() {
+		return errors.New("overdrafts.validateTerms: Limit must be greater than zero")
+	}
+	if facility.Rate.IsNegative() || facility.Rate.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("overdrafts.validateTerms: Rate must be between 0 and 100 percent")
+	}
+	expiry, err := time.Parse(DATE_LAYOUT, facility.ExpiryDate)
+	if err != nil {
+		return errors.New("overdrafts.validateTerms: Expiry date must be in the format YYYY-MM-DD")
+	}
+	if expiry.Format(DATE_LAYOUT) <= now.Format(DATE_LAYOUT) {
+		return errors.New("overdrafts.validateTerms: Expiry date must be in the future")
+	}
+	if expiry.After(now.AddDate(0, MAX_TERM_MONTHS, 0)) {
+		return errors.New("overdrafts.validateTerms: Expiry date must be within " + strconv.Itoa(MAX_TERM_MONTHS) + " months")
+	}
+	return nil
+}
+
+// parseAmount reads an optional amount, empty is zero. It must fit the currency's minor units.
+func parseAmount(currencyCode string, field string, value string) (decimal.Decimal, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, errors.New("overdrafts.parseAmount: " + field + " is not a valid amount")
+	}
+	if amount.IsNegative() {
+		return decimal.Zero, errors.New("overdrafts.parseAmount: " + field + " must not be negative")
+	}
+	if !currency.Round(currencyCode, amount).Equal(amount) {
+		return decimal.Zero, errors.New("overdrafts.parseAmount: " + field + " has more decimal places than " + currencyCode + " allows")
+	}
+	return amount, nil
+}
+
+func systemAccount(prefix string, currencyCode string) (string, error) {
+	accountNumber := strings.TrimSpace(os.Getenv(prefix + currencyCode))
+	if accountNumber == "" {
+		return "", errors.New("overdrafts.systemAccount: " + prefix + currencyCode + " is not configured")
+	}
+	return accountNumber, nil
+}
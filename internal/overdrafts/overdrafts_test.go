@@ -0,0 +1,82 @@
+package overdrafts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDailyInterest(t *testing.T) {
+	cases := []struct {
+		overdrawnBy string
+		rate        string
+		daily       string
+	}{
+		{"36500", "20", "20"},
+		{"1000", "18.5", "0.51"},
+		{"1", "10", "0"},
+		{"0", "20", "0"},
+		{"5000", "0", "0"},
+	}
+	for _, c := range cases {
+		daily := DailyInterest("NGN", decimal.RequireFromString(c.overdrawnBy), decimal.RequireFromString(c.rate))
+		if !daily.Equal(decimal.RequireFromString(c.daily)) {
+			t.Errorf("DailyInterest does not pass. Looking for %v, got %v for %v at %v", c.daily, daily, c.overdrawnBy, c.rate)
+		}
+	}
+}
+
+func TestDailyInterestCharge(t *testing.T) {
+	account := overdrawn{
+		Facility: Facility{ID: 3, AccountNumber: "0123456789", CurrencyCode: "NGN", Rate: decimal.NewFromInt(20), Status: FacilityExpired},
+		Balance:  decimal.RequireFromString("-36500"),
+	}
+	charge, ok := dailyInterestCharge(account, "2024-03-05")
+	if !ok || charge.FacilityID != 3 || charge.ChargeType != ChargeInterest || charge.ChargeDate != "2024-03-05" || !charge.Amount.Equal(decimal.NewFromInt(20)) {
+		t.Errorf("dailyInterestCharge does not pass. Got %+v", charge)
+	}
+
+	account.Balance = decimal.RequireFromString("-1")
+	if _, ok := dailyInterestCharge(account, "2024-03-05"); ok {
+		t.Errorf("dailyInterestCharge does not pass. Charged interest that rounds to zero")
+	}
+}
+
+func TestValidateTerms(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	valid := Facility{Limit: decimal.NewFromInt(50000), Rate: decimal.NewFromInt(22), ExpiryDate: "2025-03-05"}
+	if err := validateTerms(valid, now); err != nil {
+		t.Errorf("validateTerms does not pass. Rejected valid terms: %v", err)
+	}
+
+	cases := []Facility{
+		{Limit: decimal.Zero, Rate: decimal.NewFromInt(22), ExpiryDate: "2025-03-05"},
+		{Limit: decimal.NewFromInt(50000), Rate: decimal.NewFromInt(-1), ExpiryDate: "2025-03-05"},
+		{Limit: decimal.NewFromInt(50000), Rate: decimal.NewFromInt(101), ExpiryDate: "2025-03-05"},
+		{Limit: decimal.NewFromInt(50000), Rate: decimal.NewFromInt(22), ExpiryDate: "05/03/2025"},
+		{Limit: decimal.NewFromInt(50000), Rate: decimal.NewFromInt(22), ExpiryDate: "2024-03-05"},
+		{Limit: decimal.NewFromInt(50000), Rate: decimal.NewFromInt(22), ExpiryDate: "2026-03-06"},
+	}
+	for _, c := range cases {
+		if err := validateTerms(c, now); err == nil {
+			t.Errorf("validateTerms does not pass. Accepted %+v", c)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	amount, err := parseAmount("NGN", "Limit", " 2500.50 ")
+	if err != nil || !amount.Equal(decimal.RequireFromString("2500.5")) {
+		t.Errorf("parseAmount does not pass. Got %v, %v", amount, err)
+	}
+	amount, err = parseAmount("NGN", "Limit", "")
+	if err != nil || !amount.IsZero() {
+		t.Errorf("parseAmount does not pass. Empty should be zero, got %v, %v", amount, err)
+	}
+	for _, value := range []string{"abc", "-10", "10.555"} {
+		if _, err := parseAmount("NGN", "Limit", value); err == nil {
+			t.Errorf("parseAmount does not pass. Accepted %v", value)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package payments
+
+/*
+Cash pickup
+
+A customer sends cash to a recipient without an account. The sender is debited into
+the cash pickup suspense account and receives a one-time pickup code and PIN to share
+with the recipient. An agent pays the cash out after checking the PIN and the
+recipient's BVN or NIN, which moves the funds from suspense to the agent's float and the
+charge to the fees account. Pickups are only paid out through agents.PayOutCashPickup.
+Pickups that are cancelled by the sender or not collected before they expire are refunded in
+full from suspense. Expired pickups are refunded by the background job, until then they are
+reported as expired but not yet refunded.
+
+The pickup is sent in the sender account's currency and the charge is rounded to its minor units.
+
+pending -> redeemed | cancelled | expired
+*/
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	CashPickupPending   = "pending"
+	CashPickupRedeemed  = "redeemed"
+	CashPickupCancelled = "cancelled"
+	CashPickupExpired   = "expired"
+
+	CASH_PICKUP_TTL              = 7 * 24 * time.Hour
+	CASH_PICKUP_CHARGE           = 0.01 // 1%
+	CASH_PICKUP_CODE_LENGTH      = 10
+	CASH_PICKUP_PIN_LENGTH       = 6
+	CASH_PICKUP_MAX_PIN_ATTEMPTS = 3
+	CASH_PICKUP_DEFAULT_CURRENCY = "NGN"
+	CashPickupIdentificationBVN  = "bvn"
+	CashPickupIdentificationNIN  = "nin"
+	cashPickupSuspenseAccountEnv = "CASH_PICKUP_SUSPENSE_ACCOUNT_NUMBER"
+	cashPickupFeesAccountEnv     = "FEES_ACCOUNT_NUMBER"
+)
+
+// CreateCashPickup debits the sender into suspense and issues the pickup code and PIN.
+// The PIN is only returned here, only its hash is stored. The token user must be the sender.
+func CreateCashPickup(token string, pickup CashPickup) (created CashPickup, pin string, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, pickup.SendersAccountNumber)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+	if !holder {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: Sender not valid")
+	}
+	// The cash is collected against the debit, it can't wait for the other holders
+	err = RequireSingleSignature(pickup.SendersAccountNumber)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+	if !pickup.Amount.IsPositive() {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: Amount must be greater than zero")
+	}
+	if pickup.BVN == "" && pickup.NIN == "" {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: Recipient BVN or NIN must be provided")
+	}
+	senderCurrency, err := AccountCurrency(pickup.SendersAccountNumber)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+	if pickup.Currency == "" {
+		pickup.Currency = senderCurrency
+	}
+	if pickup.Currency != senderCurrency {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: Pickup currency " + pickup.Currency + " does not match the sender account currency " + senderCurrency)
+	}
+
+	suspense, err := cashPickupAccount(cashPickupSuspenseAccountEnv)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+
+	pickup.Charge = CashPickupCharge(pickup.Currency, pickup.Amount)
+	pickup.PickupCode, err = randomDigits(CASH_PICKUP_CODE_LENGTH)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+	pin, err = randomDigits(CASH_PICKUP_PIN_LENGTH)
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+	pickup.PinHash = hashCashPickupPin(pickup.PickupCode, pin)
+	pickup.PinAttempts = 0
+	pickup.Status = CashPickupPending
+	pickup.ExpiresAt = time.Now().Add(CASH_PICKUP_TTL)
+	pickup.RedeemedBy = ""
+
+	total := pickup.Amount.Add(pickup.Charge)
+	_, err = ProcessPAIN([]string{token, "pain", "1", pickup.SendersAccountNumber + "@", suspense + "@", total.String(), "Cash pickup " + pickup.PickupCode, pickup.SendersAccountNumber})
+	if err != nil {
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+
+	pickup.ID, err = saveCashPickup(pickup)
+	if err != nil {
+		// The sender has been debited but there is no pickup to collect, hand the money back
+		_, refundErr := ProcessPAIN([]string{token, "pain", "1001", suspense + "@", pickup.SendersAccountNumber + "@", total.String(), "Cash pickup " + pickup.PickupCode + " reversal", "system"})
+		if refundErr != nil {
+			return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error() + ". " + refundErr.Error())
+		}
+		return CashPickup{}, "", errors.New("payments.CreateCashPickup: " + err.Error())
+	}
+
+	return pickup, pin, nil
+}
+
+// CashPickupStatus returns a pickup created by the sender, or every pickup the sender
+// created when code is empty. The token user must be the sender. Pending pickups past their
+// expiry are reported as expired, the refund itself is left to the background job.
+func CashPickupStatus(token string, sender string, code string) (pickups []CashPickup, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return nil, errors.New("payments.CashPickupStatus: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, sender)
+	if err != nil {
+		return nil, errors.New("payments.CashPickupStatus: " + err.Error())
+	}
+	if !holder {
+		return nil, errors.New("payments.CashPickupStatus: Sender not valid")
+	}
+
+	now := time.Now()
+	if code == "" {
+		pickups, err = getCashPickupsBySender(sender)
+		if err != nil {
+			return nil, errors.New("payments.CashPickupStatus: " + err.Error())
+		}
+		for i := range pickups {
+			pickups[i] = cashPickupAsOf(pickups[i], now)
+		}
+		return pickups, nil
+	}
+
+	pickup, err := getCashPickupByCode(code)
+	if err != nil {
+		return nil, errors.New("payments.CashPickupStatus: " + err.Error())
+	}
+	if pickup.SendersAccountNumber != sender {
+		return nil, errors.New("payments.CashPickupStatus: Cash pickup not found")
+	}
+
+	return []CashPickup{cashPickupAsOf(pickup, now)}, nil
+}
+
+// FindCashPickup returns a pickup by its code
+func FindCashPickup(code string) (pickup CashPickup, err error) {
+	pickup, err = getCashPickupByCode(code)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.FindCashPickup: " + err.Error())
+	}
+
+	return pickup, nil
+}
+
+// RedeemCashPickup pays out a pending pickup into an agent's float, it is called by
+// agents.PayOutCashPickup. The token user must own the active agent whose float is
+// floatAccount and is recorded against the pickup. identificationType is bvn or nin.
+func RedeemCashPickup(token string, code string, pin string, identificationType string, identificationNumber string, floatAccount string) (pickup CashPickup, err error) {
+	agentOwner, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+	agent, err := isAgentFloat(agentOwner, floatAccount)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+	if !agent {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: Only agents can pay out cash pickups")
+	}
+
+	pickup, err = getCashPickupByCode(code)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+	if pickup.Status != CashPickupPending {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: Cash pickup is " + pickup.Status)
+	}
+	if time.Now().After(pickup.ExpiresAt) {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: Cash pickup has expired")
+	}
+	if pickup.PinAttempts >= CASH_PICKUP_MAX_PIN_ATTEMPTS {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: Too many incorrect PIN attempts, the sender must cancel the pickup")
+	}
+
+	expected := []byte(pickup.PinHash)
+	given := []byte(hashCashPickupPin(pickup.PickupCode, pin))
+	if subtle.ConstantTimeCompare(expected, given) != 1 {
+		if attemptErr := incrementCashPickupPinAttempts(pickup.PickupCode); attemptErr != nil {
+			return CashPickup{}, errors.New("payments.RedeemCashPickup: Incorrect PIN. " + attemptErr.Error())
+		}
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: Incorrect PIN")
+	}
+
+	if !cashPickupIdentityMatches(pickup, identificationType, identificationNumber) {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: Recipient identification does not match")
+	}
+
+	suspense, err := cashPickupAccount(cashPickupSuspenseAccountEnv)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+	fees, err := cashPickupAccount(cashPickupFeesAccountEnv)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+
+	// Claim the pickup first so the code cannot be paid out twice
+	err = markCashPickupRedeemed(pickup.PickupCode, agentOwner, time.Now())
+	if err != nil {
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+
+	_, err = ProcessPAIN([]string{token, "pain", "1001", suspense + "@", floatAccount + "@", pickup.Amount.String(), "Cash pickup " + pickup.PickupCode + " paid out", agentOwner})
+	if err != nil {
+		if revertErr := updateCashPickupStatus(pickup.PickupCode, CashPickupRedeemed, CashPickupPending); revertErr != nil {
+			return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error() + ". " + revertErr.Error())
+		}
+		return CashPickup{}, errors.New("payments.RedeemCashPickup: " + err.Error())
+	}
+
+	if pickup.Charge.IsPositive() {
+		_, err = ProcessPAIN([]string{token, "pain", "1001", suspense + "@", fees + "@", pickup.Charge.String(), "Cash pickup " + pickup.PickupCode + " charge", agentOwner})
+		if err != nil {
+			// The cash has been paid out, the charge stays in suspense for finance to sweep
+			return CashPickup{}, errors.New("payments.RedeemCashPickup: Cash paid out but charge not collected. " + err.Error())
+		}
+	}
+
+	pickup.Status = CashPickupRedeemed
+	pickup.RedeemedBy = agentOwner
+	return pickup, nil
+}
+
+// CancelCashPickup cancels a pending pickup and refunds the sender in full.
+// The token user must be the sender.
+func CancelCashPickup(token string, sender string, code string) (pickup CashPickup, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.CancelCashPickup: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, sender)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.CancelCashPickup: " + err.Error())
+	}
+	if !holder {
+		return CashPickup{}, errors.New("payments.CancelCashPickup: Sender not valid")
+	}
+
+	pickup, err = getCashPickupByCode(code)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.CancelCashPickup: " + err.Error())
+	}
+	if pickup.SendersAccountNumber != sender {
+		return CashPickup{}, errors.New("payments.CancelCashPickup: Cash pickup not found")
+	}
+
+	pickup, err = refundCashPickup(token, pickup, CashPickupCancelled)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.CancelCashPickup: " + err.Error())
+	}
+
+	return pickup, nil
+}
+
+// ExpireCashPickups refunds every pending pickup past its expiry. Pickups whose refund
+// fails are left pending and retried on the next run.
+func ExpireCashPickups() (expired int64, err error) {
+	pickups, err := getExpiredCashPickups(time.Now())
+	if err != nil {
+		return 0, errors.New("payments.ExpireCashPickups: " + err.Error())
+	}
+
+	var failures []string
+	for _, pickup := range pickups {
+		_, err = refundCashPickup("", pickup, CashPickupExpired)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		expired++
+	}
+	if len(failures) > 0 {
+		return expired, errors.New("payments.ExpireCashPickups: " + strings.Join(failures, "; "))
+	}
+
+	return expired, nil
+}
+
+// CancelAccountCashPickups cancels and refunds every pending pickup the account sent,
+// used when the account is closed
+func CancelAccountCashPickups(accountNumber string) (cancelled int64, err error) {
+	pickups, err := getCashPickupsBySender(accountNumber)
+	if err != nil {
+		return 0, errors.New("payments.CancelAccountCashPickups: " + err.Error())
+	}
+
+	for _, pickup := range pickups {
+		if pickup.Status != CashPickupPending {
+			continue
+		}
+		_, err = refundCashPickup("", pickup, CashPickupCancelled)
+		if err != nil {
+			return cancelled, errors.New("payments.CancelAccountCashPickups: " + err.Error())
+		}
+		cancelled++
+	}
+
+	return cancelled, nil
+}
+
+// CashPickupCharge is the charge for sending amount, rounded to the currency's minor units
+func CashPickupCharge(currencyCode string, amount decimal.Decimal) decimal.Decimal {
+	return currency.Round(currencyCode, amount.Mul(decimal.NewFromFloat(CASH_PICKUP_CHARGE)))
+}
+
+// cashPickupAsOf reports a pending pickup past its expiry as expired, ahead of its refund
+func cashPickupAsOf(pickup CashPickup, now time.Time) CashPickup {
+	if pickup.Status == CashPickupPending && now.After(pickup.ExpiresAt) {
+		pickup.Status = CashPickupExpired
+	}
+	return pickup
+}
+
+func refundCashPickup(token string, pickup CashPickup, status string) (CashPickup, error) {
+	suspense, err := cashPickupAccount(cashPickupSuspenseAccountEnv)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.refundCashPickup: " + err.Error())
+	}
+
+	// Claim the pickup first so it cannot be redeemed while the refund is made
+	err = updateCashPickupStatus(pickup.PickupCode, CashPickupPending, status)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.refundCashPickup: " + err.Error())
+	}
+
+	total := pickup.Amount.Add(pickup.Charge)
+	_, err = ProcessPAIN([]string{token, "pain", "1001", suspense + "@", pickup.SendersAccountNumber + "@", total.String(), "Cash pickup " + pickup.PickupCode + " refund", "system"})
+	if err != nil {
+		if revertErr := updateCashPickupStatus(pickup.PickupCode, status, CashPickupPending); revertErr != nil {
+			return CashPickup{}, errors.New("payments.refundCashPickup: " + err.Error() + ". " + revertErr.Error())
+		}
+		return CashPickup{}, errors.New("payments.refundCashPickup: " + err.Error())
+	}
+
+	pickup.Status = status
+	return pickup, nil
+}
+
+func cashPickupIdentityMatches(pickup CashPickup, identificationType string, identificationNumber string) bool {
+	identificationNumber = strings.TrimSpace(identificationNumber)
+	if identificationNumber == "" {
+		return false
+	}
+
+	switch strings.ToLower(identificationType) {
+	case CashPickupIdentificationBVN:
+		return pickup.BVN != "" && subtle.ConstantTimeCompare([]byte(pickup.BVN), []byte(identificationNumber)) == 1
+	case CashPickupIdentificationNIN:
+		return pickup.NIN != "" && subtle.ConstantTimeCompare([]byte(pickup.NIN), []byte(identificationNumber)) == 1
+	}
+	return false
+}
+
+// hashCashPickupPin salts the PIN with the pickup code so equal PINs do not share a hash
+func hashCashPickupPin(code string, pin string) string {
+	hasher := sha512.New()
+	hasher.Write([]byte(code + ":" + pin))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func cashPickupAccount(env string) (string, error) {
+	account := strings.TrimSpace(os.Getenv(env))
+	if account == "" {
+		return "", errors.New("payments.cashPickupAccount: " + env + " is not configured")
+	}
+	return account, nil
+}
+
+func randomDigits(length int) (string, error) {
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", errors.New("payments.randomDigits: " + err.Error())
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits), nil
+}
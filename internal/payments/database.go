@@ -1,7 +1,9 @@
 package payments
 
 import (
+	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/ebitezion/backend-framework/internal/configuration"
@@ -16,8 +18,8 @@ func SetConfig(config *configuration.Configuration) {
 
 func savePainTransaction(transaction PAINTrans) (err error) {
 	// Prepare statement for inserting data
-	insertStatement := "INSERT INTO transactions (`transaction`, `type`, `senderAccountNumber`, `senderBankNumber`, `receiverAccountNumber`, `receiverBankNumber`, `transactionAmount`, `feeAmount`,`narration`,`initiator`) "
-	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?,?,?)"
+	insertStatement := "INSERT INTO transactions (`transaction`, `type`, `senderAccountNumber`, `senderBankNumber`, `receiverAccountNumber`, `receiverBankNumber`, `currencyCode`, `transactionAmount`, `feeAmount`,`narration`,`initiator`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?,?,?)"
 	stmtIns, err := Config.Db.Prepare(insertStatement)
 	if err != nil {
 		return errors.New("payments.savePainTransaction: " + err.Error())
@@ -25,10 +27,10 @@ func savePainTransaction(transaction PAINTrans) (err error) {
 	defer stmtIns.Close() // Close the statement when we leave main() / the program terminates
 
 	// The feePerc is a percentage, convert to amount
-	feeAmount := transaction.Amount.Mul(transaction.Fee)
+	feeAmount := transaction.feeAmount()
 
 	_, err = stmtIns.Exec("pain", transaction.PainType, transaction.Sender.AccountNumber, transaction.Sender.BankNumber, transaction.Receiver.AccountNumber, transaction.Receiver.BankNumber,
-		transaction.Amount, feeAmount, transaction.Narration, transaction.Initiator)
+		transaction.Currency, transaction.Amount, feeAmount, transaction.Narration, transaction.Initiator)
 
 	if err != nil {
 		return errors.New("payments.savePainTransaction: " + err.Error())
@@ -48,7 +50,7 @@ func removePainTransaction(transaction PAINTrans) (err error) {
 	defer stmtDel.Close() // Close the statement when we leave main() / the program terminates
 
 	// The feePerc is a percentage, convert to amount
-	feeAmount := transaction.Amount.Mul(transaction.Fee)
+	feeAmount := transaction.feeAmount()
 
 	_, err = stmtDel.Exec("pain", transaction.PainType, transaction.Sender.AccountNumber, transaction.Sender.BankNumber, transaction.Receiver.AccountNumber, transaction.Receiver.BankNumber,
 		transaction.Amount, feeAmount)
@@ -66,7 +68,7 @@ func updateAccounts(transaction PAINTrans) (err error) {
 	sqlTime := int32(t.Unix())
 
 	// The feePerc is a percentage, convert to amount
-	feeAmount := transaction.Amount.Mul(transaction.Fee)
+	feeAmount := transaction.feeAmount()
 
 	switch transaction.PainType {
 	// Payment
@@ -88,6 +90,12 @@ func updateAccounts(transaction PAINTrans) (err error) {
 			return errors.New("payments.updateAccounts: " + err.Error())
 		}
 		break
+	case 1001, 1004:
+		err = processCreditInitiation(transaction, sqlTime, feeAmount)
+		if err != nil {
+			return errors.New("payments.updateAccounts: " + err.Error())
+		}
+		break
 	// Deposit
 	case 1000:
 		err = processDepositInitiation(transaction, sqlTime, feeAmount)
@@ -98,7 +106,7 @@ func updateAccounts(transaction PAINTrans) (err error) {
 	}
 	// Payment
 
-	err = updateBankHoldingAccount(feeAmount, sqlTime)
+	err = updateBankHoldingAccount(feeAmount, transaction.Currency, sqlTime)
 	if err != nil {
 		return errors.New("payments.updateAccounts: " + err.Error())
 	}
@@ -107,17 +115,18 @@ func updateAccounts(transaction PAINTrans) (err error) {
 
 }
 
-func updateBankHoldingAccount(feeAmount decimal.Decimal, sqlTime int32) (err error) {
+func updateBankHoldingAccount(feeAmount decimal.Decimal, currencyCode string, sqlTime int32) (err error) {
 	// Add fees to bank holding account
-	// Only one row in this account for now - only holds single holding bank's balance
-	updateBank := "UPDATE `bank_account` SET `balance` = (`balance` + ?), `timestamp` = ?"
+	// One row per currency - holds the holding bank's balance in that currency, created with the first fee
+	updateBank := "INSERT INTO `bank_account` (`currencyCode`, `balance`, `timestamp`) VALUES (?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE `balance` = (`balance` + VALUES(`balance`)), `timestamp` = VALUES(`timestamp`)"
 	stmtUpdBank, err := Config.Db.Prepare(updateBank)
 	if err != nil {
 		return errors.New("payments.updateBankHoldingAccount: " + err.Error())
 	}
 	defer stmtUpdBank.Close() // Close the statement when we leave main() / the program terminates
 
-	_, err = stmtUpdBank.Exec(feeAmount, sqlTime)
+	_, err = stmtUpdBank.Exec(currencyCode, feeAmount, sqlTime)
 
 	if err != nil {
 		return errors.New("payments.updateBankHoldingAccount: " + err.Error())
@@ -125,9 +134,67 @@ func updateBankHoldingAccount(feeAmount decimal.Decimal, sqlTime int32) (err err
 	return
 }
 
+// AccountCurrency returns the currency an account is held in
+func AccountCurrency(accountNumber string) (currencyCode string, err error) {
+	err = Config.Db.QueryRow("SELECT `currencyCode` FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&currencyCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("payments.AccountCurrency: Account " + accountNumber + " not found")
+		}
+		return "", errors.New("payments.AccountCurrency: " + err.Error())
+	}
+
+	return currencyCode, nil
+}
+
+// IsAccountHolder reports whether the account is the holder's own account or one of the
+// currency accounts opened under it
+func IsAccountHolder(holder string, accountNumber string) (bool, error) {
+	if holder == accountNumber {
+		return true, nil
+	}
+
+	var count int
+	err := Config.Db.QueryRow("SELECT COUNT(*) FROM `accounts` WHERE `accountNumber` = ? AND `primaryAccountNumber` = ?", accountNumber, holder).Scan(&count)
+	if err != nil {
+		return false, errors.New("payments.IsAccountHolder: " + err.Error())
+	}
+
+	return count > 0, nil
+}
+
+// saveFailedRoundUp records a debit whose round-up into the account's pot failed
+func saveFailedRoundUp(transaction PAINTrans, reason string) (err error) {
+	insertStatement := "INSERT INTO failed_round_ups (`accountNumber`, `painType`, `amount`, `narration`, `reason`) VALUES(?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("payments.saveFailedRoundUp: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(transaction.Sender.AccountNumber, transaction.PainType, transaction.Amount, transaction.Narration, reason)
+	if err != nil {
+		return errors.New("payments.saveFailedRoundUp: " + err.Error())
+	}
+	return
+}
+
+// isAgentFloat reports whether floatAccount is the float of an active agent the owner runs
+func isAgentFloat(owner string, floatAccount string) (bool, error) {
+	var count int
+	err := Config.Db.QueryRow("SELECT COUNT(*) FROM `agents` WHERE `ownerAccountNumber` = ? AND `floatAccountNumber` = ? AND `status` = 'active'", owner, floatAccount).Scan(&count)
+	if err != nil {
+		return false, errors.New("payments.isAgentFloat: " + err.Error())
+	}
+
+	return count > 0, nil
+}
+
+// checkBalance returns what the account can spend: its available balance plus the approved
+// overdraft limit. The overdraft column only ever holds the limit of an active facility.
 // @TODO Look at using accounts.getAccountDetails here
 func checkBalance(account AccountHolder) (balance decimal.Decimal, err error) {
-	rows, err := Config.Db.Query("SELECT `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", account.AccountNumber)
+	rows, err := Config.Db.Query("SELECT `availableBalance` + `overdraft` FROM `accounts` WHERE `accountNumber` = ?", account.AccountNumber)
 	if err != nil {
 		return decimal.NewFromFloat(0.), errors.New("payments.checkBalance: " + err.Error())
 	}
@@ -148,6 +215,15 @@ func checkBalance(account AccountHolder) (balance decimal.Decimal, err error) {
 	return
 }
 
+// checkOwnBalance returns the account's available balance without its overdraft limit
+func checkOwnBalance(account AccountHolder) (balance decimal.Decimal, err error) {
+	err = Config.Db.QueryRow("SELECT `availableBalance` FROM `accounts` WHERE `accountNumber` = ?", account.AccountNumber).Scan(&balance)
+	if err != nil {
+		return decimal.Zero, errors.New("payments.checkOwnBalance: " + err.Error())
+	}
+	return balance, nil
+}
+
 func processCreditInitiation(transaction PAINTrans, sqlTime int32, feeAmount decimal.Decimal) (err error) {
 	// Only update if account local
 	if transaction.Sender.BankNumber == "" {
@@ -216,3 +292,553 @@ func processDepositInitiation(transaction PAINTrans, sqlTime int32, feeAmount de
 	}
 	return
 }
+
+// SQL_TIME_LAYOUT is the layout of DATETIME columns as returned by the driver
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+func parseSQLTime(value string) (t time.Time, err error) {
+	t, err = time.Parse(SQL_TIME_LAYOUT, value)
+	if err != nil {
+		return time.Time{}, errors.New("payments.parseSQLTime: " + err.Error())
+	}
+	return
+}
+
+func savePaymentRequest(request PaymentRequest) (id int64, err error) {
+	insertStatement := "INSERT INTO payment_requests (`requesterAccountNumber`, `payerAccountNumber`, `amount`, `reason`, `status`, `expiresAt`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("payments.savePaymentRequest: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(request.RequesterAccountNumber, request.PayerAccountNumber, request.Amount, request.Reason, request.Status, request.ExpiresAt.UTC())
+	if err != nil {
+		return 0, errors.New("payments.savePaymentRequest: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("payments.savePaymentRequest: " + err.Error())
+	}
+
+	return
+}
+
+func getPaymentRequest(id int64) (request PaymentRequest, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `requesterAccountNumber`, `payerAccountNumber`, `amount`, `reason`, `status`, `expiresAt`, `timestamp` FROM `payment_requests` WHERE `id` = ?", id)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.getPaymentRequest: " + err.Error())
+	}
+	defer rows.Close()
+
+	requests, err := scanPaymentRequests(rows)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.getPaymentRequest: " + err.Error())
+	}
+	if len(requests) == 0 {
+		return PaymentRequest{}, errors.New("payments.getPaymentRequest: Payment request not found")
+	}
+
+	return requests[0], nil
+}
+
+func getPaymentRequestsByPayer(payer string, status string) (requests []PaymentRequest, err error) {
+	rows, err := Config.Db.Query("SELECT `id`, `requesterAccountNumber`, `payerAccountNumber`, `amount`, `reason`, `status`, `expiresAt`, `timestamp` FROM `payment_requests` WHERE `payerAccountNumber` = ? AND `status` = ? ORDER BY `timestamp` DESC", payer, status)
+	if err != nil {
+		return nil, errors.New("payments.getPaymentRequestsByPayer: " + err.Error())
+	}
+	defer rows.Close()
+
+	requests, err = scanPaymentRequests(rows)
+	if err != nil {
+		return nil, errors.New("payments.getPaymentRequestsByPayer: " + err.Error())
+	}
+
+	return requests, nil
+}
+
+func scanPaymentRequests(rows *sql.Rows) (requests []PaymentRequest, err error) {
+	requests = make([]PaymentRequest, 0)
+	for rows.Next() {
+		var request PaymentRequest
+		var expiresAt, timestamp string
+		if err := rows.Scan(&request.ID, &request.RequesterAccountNumber, &request.PayerAccountNumber, &request.Amount, &request.Reason, &request.Status, &expiresAt, &timestamp); err != nil {
+			return nil, errors.New("payments.scanPaymentRequests: " + err.Error())
+		}
+		if request.ExpiresAt, err = parseSQLTime(expiresAt); err != nil {
+			return nil, errors.New("payments.scanPaymentRequests: " + err.Error())
+		}
+		if request.Timestamp, err = parseSQLTime(timestamp); err != nil {
+			return nil, errors.New("payments.scanPaymentRequests: " + err.Error())
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("payments.scanPaymentRequests: " + err.Error())
+	}
+
+	return requests, nil
+}
+
+// updatePaymentRequestStatus only moves a request that is still in the expected status,
+// so concurrent accept/decline calls cannot both succeed
+func updatePaymentRequestStatus(id int64, from string, to string) (err error) {
+	updateStatement := "UPDATE payment_requests SET `status` = ? WHERE `id` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("payments.updatePaymentRequestStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, id, from)
+	if err != nil {
+		return errors.New("payments.updatePaymentRequestStatus: " + err.Error())
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("payments.updatePaymentRequestStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("payments.updatePaymentRequestStatus: Payment request is no longer " + from)
+	}
+
+	return
+}
+
+func expireStalePaymentRequests(now time.Time) (expired int64, err error) {
+	updateStatement := "UPDATE payment_requests SET `status` = ? WHERE `status` = ? AND `expiresAt` < ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return 0, errors.New("payments.expireStalePaymentRequests: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(PaymentRequestExpired, PaymentRequestPending, now.UTC())
+	if err != nil {
+		return 0, errors.New("payments.expireStalePaymentRequests: " + err.Error())
+	}
+
+	return res.RowsAffected()
+}
+
+// declineAccountPaymentRequests declines every pending request the account is party to
+func declineAccountPaymentRequests(accountNumber string) (declined int64, err error) {
+	updateStatement := "UPDATE payment_requests SET `status` = ? WHERE `status` = ? AND (`payerAccountNumber` = ? OR `requesterAccountNumber` = ?)"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return 0, errors.New("payments.declineAccountPaymentRequests: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(PaymentRequestDeclined, PaymentRequestPending, accountNumber, accountNumber)
+	if err != nil {
+		return 0, errors.New("payments.declineAccountPaymentRequests: " + err.Error())
+	}
+
+	return res.RowsAffected()
+}
+
+func saveCashPickup(pickup CashPickup) (id int64, err error) {
+	insertStatement := "INSERT INTO cash_pickup (`pickupCode`, `pinHash`, `pinAttempts`, `sendersAccountNumber`, `firstName`, `lastName`, `status`, `currency`, `reason`, `amount`, `charge`, `bvn`, `nin`, `expiresAt`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("payments.saveCashPickup: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(pickup.PickupCode, pickup.PinHash, pickup.PinAttempts, pickup.SendersAccountNumber, pickup.FirstName, pickup.LastName, pickup.Status, pickup.Currency, pickup.Reason, pickup.Amount, pickup.Charge, pickup.BVN, pickup.NIN, pickup.ExpiresAt.UTC())
+	if err != nil {
+		return 0, errors.New("payments.saveCashPickup: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("payments.saveCashPickup: " + err.Error())
+	}
+
+	return
+}
+
+const cashPickupColumns = "`id`, `pickupCode`, `pinHash`, `pinAttempts`, `sendersAccountNumber`, `firstName`, `lastName`, `status`, `currency`, `reason`, `amount`, `charge`, `bvn`, `nin`, `expiresAt`, IFNULL(`redeemedBy`, ''), `timestamp`, `updated_at`"
+
+func getCashPickupByCode(code string) (pickup CashPickup, err error) {
+	rows, err := Config.Db.Query("SELECT "+cashPickupColumns+" FROM `cash_pickup` WHERE `pickupCode` = ?", code)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.getCashPickupByCode: " + err.Error())
+	}
+	defer rows.Close()
+
+	pickups, err := scanCashPickups(rows)
+	if err != nil {
+		return CashPickup{}, errors.New("payments.getCashPickupByCode: " + err.Error())
+	}
+	if len(pickups) == 0 {
+		return CashPickup{}, errors.New("payments.getCashPickupByCode: Cash pickup not found")
+	}
+
+	return pickups[0], nil
+}
+
+func getCashPickupsBySender(sender string) (pickups []CashPickup, err error) {
+	rows, err := Config.Db.Query("SELECT "+cashPickupColumns+" FROM `cash_pickup` WHERE `sendersAccountNumber` = ? ORDER BY `timestamp` DESC", sender)
+	if err != nil {
+		return nil, errors.New("payments.getCashPickupsBySender: " + err.Error())
+	}
+	defer rows.Close()
+
+	pickups, err = scanCashPickups(rows)
+	if err != nil {
+		return nil, errors.New("payments.getCashPickupsBySender: " + err.Error())
+	}
+
+	return pickups, nil
+}
+
+func getExpiredCashPickups(now time.Time) (pickups []CashPickup, err error) {
+	rows, err := Config.Db.Query("SELECT "+cashPickupColumns+" FROM `cash_pickup` WHERE `status` = ? AND `expiresAt` < ?", CashPickupPending, now.UTC())
+	if err != nil {
+		return nil, errors.New("payments.getExpiredCashPickups: " + err.Error())
+	}
+	defer rows.Close()
+
+	pickups, err = scanCashPickups(rows)
+	if err != nil {
+		return nil, errors.New("payments.getExpiredCashPickups: " + err.Error())
+	}
+
+	return pickups, nil
+}
+
+func scanCashPickups(rows *sql.Rows) (pickups []CashPickup, err error) {
+	pickups = make([]CashPickup, 0)
+	for rows.Next() {
+		var pickup CashPickup
+		var expiresAt, updatedAt string
+		if err := rows.Scan(&pickup.ID, &pickup.PickupCode, &pickup.PinHash, &pickup.PinAttempts, &pickup.SendersAccountNumber, &pickup.FirstName, &pickup.LastName, &pickup.Status, &pickup.Currency, &pickup.Reason,
+			&pickup.Amount, &pickup.Charge, &pickup.BVN, &pickup.NIN, &expiresAt, &pickup.RedeemedBy, &pickup.Timestamp, &updatedAt); err != nil {
+			return nil, errors.New("payments.scanCashPickups: " + err.Error())
+		}
+		if pickup.ExpiresAt, err = parseSQLTime(expiresAt); err != nil {
+			return nil, errors.New("payments.scanCashPickups: " + err.Error())
+		}
+		if pickup.UpdatedAt, err = parseSQLTime(updatedAt); err != nil {
+			return nil, errors.New("payments.scanCashPickups: " + err.Error())
+		}
+		pickups = append(pickups, pickup)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("payments.scanCashPickups: " + err.Error())
+	}
+
+	return pickups, nil
+}
+
+// updateCashPickupStatus only moves a pickup that is still in the expected status,
+// so a pickup cannot be redeemed and refunded at the same time
+func updateCashPickupStatus(code string, from string, to string) (err error) {
+	updateStatement := "UPDATE cash_pickup SET `status` = ? WHERE `pickupCode` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("payments.updateCashPickupStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, code, from)
+	if err != nil {
+		return errors.New("payments.updateCashPickupStatus: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("payments.updateCashPickupStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("payments.updateCashPickupStatus: Cash pickup is no longer " + from)
+	}
+
+	return nil
+}
+
+func markCashPickupRedeemed(code string, agent string, redeemedAt time.Time) (err error) {
+	updateStatement := "UPDATE cash_pickup SET `status` = ?, `redeemedBy` = ?, `redeemedAt` = ? WHERE `pickupCode` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("payments.markCashPickupRedeemed: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(CashPickupRedeemed, agent, redeemedAt.UTC(), code, CashPickupPending)
+	if err != nil {
+		return errors.New("payments.markCashPickupRedeemed: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("payments.markCashPickupRedeemed: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("payments.markCashPickupRedeemed: Cash pickup is no longer " + CashPickupPending)
+	}
+
+	return nil
+}
+
+func incrementCashPickupPinAttempts(code string) (err error) {
+	updateStatement := "UPDATE cash_pickup SET `pinAttempts` = `pinAttempts` + 1 WHERE `pickupCode` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("payments.incrementCashPickupPinAttempts: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	_, err = stmtUpd.Exec(code)
+	if err != nil {
+		return errors.New("payments.incrementCashPickupPinAttempts: " + err.Error())
+	}
+
+	return nil
+}
+
+// saveMandatePayment records a held payment together with its initiator's approval
+func saveMandatePayment(payment MandatePayment) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("payments.saveMandatePayment: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	insertStatement := "INSERT INTO mandate_payments (`painType`, `senderAccountNumber`, `senderBankNumber`, `receiverAccountNumber`, `receiverBankNumber`, `amount`, `narration`, `rule`, `requiredApprovals`, `initiator`, `status`, `result`, `expiresAt`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, '', ?)"
+	res, err := tx.Exec(insertStatement, payment.PainType, payment.SenderAccountNumber, payment.SenderBankNumber, payment.ReceiverAccountNumber, payment.ReceiverBankNumber,
+		payment.Amount, payment.Narration, payment.Rule, payment.RequiredApprovals, payment.Initiator, payment.Status, payment.ExpiresAt.UTC())
+	if err != nil {
+		return 0, errors.New("payments.saveMandatePayment: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("payments.saveMandatePayment: " + err.Error())
+	}
+
+	_, err = tx.Exec("INSERT INTO mandate_payment_approvals (`paymentId`, `customerNumber`) VALUES(?, ?)", id, payment.Initiator)
+	if err != nil {
+		return 0, errors.New("payments.saveMandatePayment: " + err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("payments.saveMandatePayment: " + err.Error())
+	}
+
+	return id, nil
+}
+
+func saveMandatePaymentApproval(id int64, customerNumber string) (err error) {
+	insertStatement := "INSERT INTO mandate_payment_approvals (`paymentId`, `customerNumber`) VALUES(?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return errors.New("payments.saveMandatePaymentApproval: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	_, err = stmtIns.Exec(id, customerNumber)
+	if err != nil {
+		return errors.New("payments.saveMandatePaymentApproval: " + err.Error())
+	}
+	return
+}
+
+func getMandatePayment(id int64) (payment MandatePayment, err error) {
+	rows, err := Config.Db.Query("SELECT "+mandatePaymentColumns+" FROM `mandate_payments` WHERE `id` = ?", id)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.getMandatePayment: " + err.Error())
+	}
+	defer rows.Close()
+
+	payments, err := scanMandatePayments(rows)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.getMandatePayment: " + err.Error())
+	}
+	if len(payments) == 0 {
+		return MandatePayment{}, errors.New("payments.getMandatePayment: Payment not found")
+	}
+
+	return payments[0], nil
+}
+
+func getMandatePaymentsBySender(sender string, status string) (payments []MandatePayment, err error) {
+	rows, err := Config.Db.Query("SELECT "+mandatePaymentColumns+" FROM `mandate_payments` WHERE `senderAccountNumber` = ? AND `status` = ? ORDER BY `timestamp` DESC", sender, status)
+	if err != nil {
+		return nil, errors.New("payments.getMandatePaymentsBySender: " + err.Error())
+	}
+	defer rows.Close()
+
+	payments, err = scanMandatePayments(rows)
+	if err != nil {
+		return nil, errors.New("payments.getMandatePaymentsBySender: " + err.Error())
+	}
+
+	return payments, nil
+}
+
+const mandatePaymentColumns = "`id`, `painType`, `senderAccountNumber`, `senderBankNumber`, `receiverAccountNumber`, `receiverBankNumber`, `amount`, `narration`, `rule`, `requiredApprovals`, `initiator`, `status`, `result`, `expiresAt`, `timestamp`, " +
+	"(SELECT GROUP_CONCAT(`customerNumber` ORDER BY `id`) FROM `mandate_payment_approvals` WHERE `paymentId` = `mandate_payments`.`id`)"
+
+func scanMandatePayments(rows *sql.Rows) (payments []MandatePayment, err error) {
+	payments = make([]MandatePayment, 0)
+	for rows.Next() {
+		var payment MandatePayment
+		var expiresAt, timestamp string
+		var approvals sql.NullString
+		if err := rows.Scan(&payment.ID, &payment.PainType, &payment.SenderAccountNumber, &payment.SenderBankNumber, &payment.ReceiverAccountNumber, &payment.ReceiverBankNumber, &payment.Amount, &payment.Narration,
+			&payment.Rule, &payment.RequiredApprovals, &payment.Initiator, &payment.Status, &payment.Result, &expiresAt, &timestamp, &approvals); err != nil {
+			return nil, errors.New("payments.scanMandatePayments: " + err.Error())
+		}
+		if payment.ExpiresAt, err = parseSQLTime(expiresAt); err != nil {
+			return nil, errors.New("payments.scanMandatePayments: " + err.Error())
+		}
+		if payment.Timestamp, err = parseSQLTime(timestamp); err != nil {
+			return nil, errors.New("payments.scanMandatePayments: " + err.Error())
+		}
+		payment.Approvals = make([]string, 0)
+		if approvals.Valid && approvals.String != "" {
+			payment.Approvals = strings.Split(approvals.String, ",")
+		}
+		payments = append(payments, payment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("payments.scanMandatePayments: " + err.Error())
+	}
+
+	return payments, nil
+}
+
+// updateMandatePaymentStatus only moves a payment that is still in the expected status,
+// so concurrent approvals and rejections cannot both succeed
+func updateMandatePaymentStatus(id int64, from string, to string, result string) (err error) {
+	updateStatement := "UPDATE mandate_payments SET `status` = ?, `result` = ? WHERE `id` = ? AND `status` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return errors.New("payments.updateMandatePaymentStatus: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, result, id, from)
+	if err != nil {
+		return errors.New("payments.updateMandatePaymentStatus: " + err.Error())
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("payments.updateMandatePaymentStatus: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("payments.updateMandatePaymentStatus: Payment is no longer " + from)
+	}
+
+	return
+}
+
+func saveMandatePaymentResult(id int64, result string) (err error) {
+	_, err = Config.Db.Exec("UPDATE mandate_payments SET `result` = ? WHERE `id` = ?", result, id)
+	if err != nil {
+		return errors.New("payments.saveMandatePaymentResult: " + err.Error())
+	}
+	return
+}
+
+func expireStaleMandatePayments(now time.Time) (expired int64, err error) {
+	updateStatement := "UPDATE mandate_payments SET `status` = ? WHERE `status` = ? AND `expiresAt` < ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return 0, errors.New("payments.expireStaleMandatePayments: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(MandatePaymentExpired, MandatePaymentPending, now.UTC())
+	if err != nil {
+		return 0, errors.New("payments.expireStaleMandatePayments: " + err.Error())
+	}
+
+	return res.RowsAffected()
+}
+
+func rejectAccountMandatePayments(accountNumber string) (rejected int64, err error) {
+	updateStatement := "UPDATE mandate_payments SET `status` = ? WHERE `status` = ? AND `senderAccountNumber` = ?"
+	stmtUpd, err := Config.Db.Prepare(updateStatement)
+	if err != nil {
+		return 0, errors.New("payments.rejectAccountMandatePayments: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(MandatePaymentRejected, MandatePaymentPending, accountNumber)
+	if err != nil {
+		return 0, errors.New("payments.rejectAccountMandatePayments: " + err.Error())
+	}
+
+	return res.RowsAffected()
+}
+
+// savePotTransfer posts a move between an account's main balance and a pot. The ledger row,
+// the account's available balance and the pot balance change together. The ledger balance
+// doesn't move as the money stays in the account.
+func savePotTransfer(transaction PAINTrans, potID int64) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("payments.savePotTransfer: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	insertStatement := "INSERT INTO transactions (`transaction`, `type`, `senderAccountNumber`, `senderBankNumber`, `receiverAccountNumber`, `receiverBankNumber`, `currencyCode`, `transactionAmount`, `feeAmount`, `narration`, `initiator`, `potId`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = tx.Exec(insertStatement, "pain", transaction.PainType, transaction.Sender.AccountNumber, transaction.Sender.BankNumber, transaction.Receiver.AccountNumber, transaction.Receiver.BankNumber,
+		transaction.Currency, transaction.Amount, decimal.Zero, transaction.Narration, transaction.Initiator, potID)
+	if err != nil {
+		return errors.New("payments.savePotTransfer: " + err.Error())
+	}
+
+	// Into the pot the account must have the amount available, out of it the pot must
+	var accountUpdate, potUpdate sql.Result
+	if transaction.PainType == 1002 {
+		accountUpdate, err = tx.Exec("UPDATE accounts SET `availableBalance` = (`availableBalance` - ?) WHERE `accountNumber` = ? AND `availableBalance` >= ?", transaction.Amount, transaction.Sender.AccountNumber, transaction.Amount)
+		if err != nil {
+			return errors.New("payments.savePotTransfer: " + err.Error())
+		}
+		potUpdate, err = tx.Exec("UPDATE account_pots SET `balance` = (`balance` + ?) WHERE `id` = ? AND `status` = 'open'", transaction.Amount, potID)
+	} else {
+		accountUpdate, err = tx.Exec("UPDATE accounts SET `availableBalance` = (`availableBalance` + ?) WHERE `accountNumber` = ?", transaction.Amount, transaction.Sender.AccountNumber)
+		if err != nil {
+			return errors.New("payments.savePotTransfer: " + err.Error())
+		}
+		potUpdate, err = tx.Exec("UPDATE account_pots SET `balance` = (`balance` - ?) WHERE `id` = ? AND `status` = 'open' AND `balance` >= ?", transaction.Amount, potID, transaction.Amount)
+	}
+	if err != nil {
+		return errors.New("payments.savePotTransfer: " + err.Error())
+	}
+
+	affected, err := accountUpdate.RowsAffected()
+	if err != nil {
+		return errors.New("payments.savePotTransfer: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("payments.savePotTransfer: Insufficient funds available")
+	}
+	affected, err = potUpdate.RowsAffected()
+	if err != nil {
+		return errors.New("payments.savePotTransfer: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("payments.savePotTransfer: Pot is closed or holds less than the amount")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("payments.savePotTransfer: " + err.Error())
+	}
+
+	return nil
+}
@@ -23,7 +23,7 @@ func TestSavePainTransaction(t *testing.T) {
 	sender := AccountHolder{"accountNumSender", "bankNumSender"}
 	receiver := AccountHolder{"accountNumReceiver", "bankNumReceiver"}
 	narration := "CR"
-	trans := PAINTrans{101, sender, receiver, decimal.NewFromFloat(0.), decimal.NewFromFloat(0.), narration}
+	trans := PAINTrans{PainType: 101, Sender: sender, Receiver: receiver, Amount: decimal.NewFromFloat(0.), Fee: decimal.NewFromFloat(0.), Narration: narration}
 
 	err := savePainTransaction(trans)
 	if err != nil {
@@ -44,7 +44,7 @@ func BenchmarkSavePainTransaction(b *testing.B) {
 		sender := AccountHolder{"accountNumSender", "bankNumSender"}
 		receiver := AccountHolder{"accountNumReceiver", "bankNumReceiver"}
 		narration := "CR"
-		trans := PAINTrans{101, sender, receiver, decimal.NewFromFloat(0.), decimal.NewFromFloat(0.), narration}
+		trans := PAINTrans{PainType: 101, Sender: sender, Receiver: receiver, Amount: decimal.NewFromFloat(0.), Fee: decimal.NewFromFloat(0.), Narration: narration}
 
 		_ = savePainTransaction(trans)
 		_ = removePainTransaction(trans)
@@ -58,7 +58,7 @@ func TestUpdateHoldingAccount(t *testing.T) {
 	ti := time.Now()
 	sqlTime := int32(ti.Unix())
 
-	err := updateBankHoldingAccount(decimal.NewFromFloat(0.), sqlTime)
+	err := updateBankHoldingAccount(decimal.NewFromFloat(0.), "NGN", sqlTime)
 	if err != nil {
 		t.Errorf("DoUpdateHoldingAccount does not pass. Looking for %v, got %v", nil, err)
 	}
@@ -71,7 +71,7 @@ func BenchmarkUpdateHoldingAccount(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		ti := time.Now()
 		sqlTime := int32(ti.Unix())
-		_ = updateBankHoldingAccount(decimal.NewFromFloat(0.), sqlTime)
+		_ = updateBankHoldingAccount(decimal.NewFromFloat(0.), "NGN", sqlTime)
 	}
 }
 
@@ -0,0 +1,277 @@
+package payments
+
+/*
+Mandate payments
+
+A PAIN 1 credit or PAIN 9 debit transfer from an account whose mandate needs more than one
+signature is held rather than executed, and ProcessPAIN returns ErrPaymentHeld with the held
+payment's message. The holder who initiated it has signed, the transfer goes
+through once enough of the other holders approve it, as a credit or a debit like it was
+made. Any holder can reject it, and payments still waiting after MANDATE_PAYMENT_TTL expire.
+Debits that can't wait for the other holders, such as cash pickups and term deposit bookings,
+refuse these accounts up front with RequireSingleSignature.
+
+pending -> executed | failed | rejected | expired
+*/
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/mandates"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	MandatePaymentPending  = "pending"
+	MandatePaymentExecuted = "executed"
+	MandatePaymentFailed   = "failed"
+	MandatePaymentRejected = "rejected"
+	MandatePaymentExpired  = "expired"
+
+	// Holders have a week to approve a held payment
+	MANDATE_PAYMENT_TTL = 7 * 24 * time.Hour
+)
+
+// ErrPaymentHeld is returned with the held payment's message when a transfer is waiting on
+// the other account holders instead of being made
+var ErrPaymentHeld = errors.New("Payment is pending approval by the account holders")
+
+type MandatePayment struct {
+	ID                    int64           `json:"id"`
+	PainType              int64           `json:"painType"`
+	SenderAccountNumber   string          `json:"senderAccountNumber"`
+	SenderBankNumber      string          `json:"senderBankNumber"`
+	ReceiverAccountNumber string          `json:"receiverAccountNumber"`
+	ReceiverBankNumber    string          `json:"receiverBankNumber"`
+	Amount                decimal.Decimal `json:"amount"`
+	Narration             string          `json:"narration"`
+	Rule                  string          `json:"rule"`
+	RequiredApprovals     int             `json:"requiredApprovals"`
+	Initiator             string          `json:"initiator"`
+	Approvals             []string        `json:"approvals"`
+	Status                string          `json:"status"`
+	Result                string          `json:"result"`
+	ExpiresAt             time.Time       `json:"expiresAt"`
+	Timestamp             time.Time       `json:"timestamp"`
+}
+
+// holdForMandate saves the transfer as a pending mandate payment when the sender's mandate
+// needs more than one signature. The token user must hold the sending account, their
+// signature counts as the first approval. Returns an empty payment when the transfer can
+// go ahead straight away.
+func holdForMandate(token string, transaction PAINTrans) (payment MandatePayment, err error) {
+	mandate, err := mandates.Get(transaction.Sender.AccountNumber)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.holdForMandate: " + err.Error())
+	}
+	if mandate.RequiredApprovals <= 1 {
+		return MandatePayment{}, nil
+	}
+
+	signatory, err := mandateSignatory(token, transaction.Sender.AccountNumber)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.holdForMandate: " + err.Error())
+	}
+
+	payment = newMandatePayment(transaction, mandate, signatory.CustomerNumber, time.Now())
+	payment.ID, err = saveMandatePayment(payment)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.holdForMandate: " + err.Error())
+	}
+
+	return payment, nil
+}
+
+// newMandatePayment is the pending payment holding transaction, signed by its initiator
+func newMandatePayment(transaction PAINTrans, mandate mandates.Mandate, initiator string, now time.Time) MandatePayment {
+	return MandatePayment{
+		PainType:              transaction.PainType,
+		SenderAccountNumber:   transaction.Sender.AccountNumber,
+		SenderBankNumber:      transaction.Sender.BankNumber,
+		ReceiverAccountNumber: transaction.Receiver.AccountNumber,
+		ReceiverBankNumber:    transaction.Receiver.BankNumber,
+		Amount:                transaction.Amount,
+		Narration:             transaction.Narration,
+		Rule:                  mandate.Rule,
+		RequiredApprovals:     mandate.RequiredApprovals,
+		Initiator:             initiator,
+		Approvals:             []string{initiator},
+		Status:                MandatePaymentPending,
+		ExpiresAt:             now.Add(MANDATE_PAYMENT_TTL),
+	}
+}
+
+// mandatePaymentTransaction is the transfer an approved payment makes, of the PAIN type it was held as
+func mandatePaymentTransaction(payment MandatePayment) PAINTrans {
+	return PAINTrans{
+		PainType:  payment.PainType,
+		Sender:    AccountHolder{payment.SenderAccountNumber, payment.SenderBankNumber},
+		Receiver:  AccountHolder{payment.ReceiverAccountNumber, payment.ReceiverBankNumber},
+		Amount:    payment.Amount,
+		Fee:       decimal.NewFromFloat(TRANSACTION_FEE),
+		Narration: payment.Narration,
+		Initiator: payment.Initiator,
+	}
+}
+
+// RequireSingleSignature fails when payments from the account need more than one signature,
+// for debits that have to be made straight away
+func RequireSingleSignature(accountNumber string) error {
+	mandate, err := mandates.Get(accountNumber)
+	if err != nil {
+		return errors.New("payments.RequireSingleSignature: " + err.Error())
+	}
+	if mandate.RequiredApprovals > 1 {
+		return errors.New("payments.RequireSingleSignature: Payments from account " + accountNumber + " need approval by " + strconv.Itoa(mandate.RequiredApprovals) + " account holders")
+	}
+
+	return nil
+}
+
+// PendingMandatePayments lists the payments from an account waiting on its holders.
+// Stale payments are expired before the list is read.
+func PendingMandatePayments(accountNumber string) (payments []MandatePayment, err error) {
+	_, err = ExpireMandatePayments()
+	if err != nil {
+		return nil, errors.New("payments.PendingMandatePayments: " + err.Error())
+	}
+
+	payments, err = getMandatePaymentsBySender(accountNumber, MandatePaymentPending)
+	if err != nil {
+		return nil, errors.New("payments.PendingMandatePayments: " + err.Error())
+	}
+
+	return payments, nil
+}
+
+// ApproveMandatePayment adds the token user's signature to a pending payment, and executes
+// the transfer once the payment has all the approvals its mandate needs
+func ApproveMandatePayment(token string, id int64) (payment MandatePayment, err error) {
+	transactionMutex.Lock()
+	defer transactionMutex.Unlock()
+
+	payment, signatory, err := loadPendingMandatePayment(token, id)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.ApproveMandatePayment: " + err.Error())
+	}
+	for _, approver := range payment.Approvals {
+		if approver == signatory.CustomerNumber {
+			return MandatePayment{}, errors.New("payments.ApproveMandatePayment: Payment already approved by customer " + signatory.CustomerNumber)
+		}
+	}
+
+	err = saveMandatePaymentApproval(id, signatory.CustomerNumber)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.ApproveMandatePayment: " + err.Error())
+	}
+	payment.Approvals = append(payment.Approvals, signatory.CustomerNumber)
+	if len(payment.Approvals) < payment.RequiredApprovals {
+		return payment, nil
+	}
+
+	// Claim the payment first so a second approval cannot execute it twice
+	err = updateMandatePaymentStatus(id, MandatePaymentPending, MandatePaymentExecuted, "")
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.ApproveMandatePayment: " + err.Error())
+	}
+
+	transaction := mandatePaymentTransaction(payment)
+	transaction.Currency, err = transactionCurrency(transaction.Sender, transaction.Receiver, transaction.Amount)
+	if err == nil && transaction.PainType == 9 {
+		payment.Result, err = executeDebitTransfer(transaction)
+	} else if err == nil {
+		payment.Result, err = executeCreditTransfer(transaction)
+	}
+	if err != nil {
+		// The transfer can't go through as approved, e.g. the funds are no longer there
+		if updateErr := updateMandatePaymentStatus(id, MandatePaymentExecuted, MandatePaymentFailed, err.Error()); updateErr != nil {
+			return MandatePayment{}, errors.New("payments.ApproveMandatePayment: " + err.Error() + ". " + updateErr.Error())
+		}
+		return MandatePayment{}, errors.New("payments.ApproveMandatePayment: " + err.Error())
+	}
+
+	err = saveMandatePaymentResult(id, payment.Result)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.ApproveMandatePayment: " + err.Error())
+	}
+
+	payment.Status = MandatePaymentExecuted
+	return payment, nil
+}
+
+// RejectMandatePayment cancels a pending payment. Any holder of the sending account can reject it.
+func RejectMandatePayment(token string, id int64) (payment MandatePayment, err error) {
+	payment, _, err = loadPendingMandatePayment(token, id)
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.RejectMandatePayment: " + err.Error())
+	}
+
+	err = updateMandatePaymentStatus(id, MandatePaymentPending, MandatePaymentRejected, "")
+	if err != nil {
+		return MandatePayment{}, errors.New("payments.RejectMandatePayment: " + err.Error())
+	}
+
+	payment.Status = MandatePaymentRejected
+	return payment, nil
+}
+
+// ExpireMandatePayments moves every pending payment past its expiry to expired
+func ExpireMandatePayments() (expired int64, err error) {
+	expired, err = expireStaleMandatePayments(time.Now())
+	if err != nil {
+		return 0, errors.New("payments.ExpireMandatePayments: " + err.Error())
+	}
+
+	return expired, nil
+}
+
+// RejectAccountMandatePayments rejects every pending payment from the account,
+// used when the account is closed
+func RejectAccountMandatePayments(accountNumber string) (rejected int64, err error) {
+	rejected, err = rejectAccountMandatePayments(accountNumber)
+	if err != nil {
+		return 0, errors.New("payments.RejectAccountMandatePayments: " + err.Error())
+	}
+
+	return rejected, nil
+}
+
+func loadPendingMandatePayment(token string, id int64) (payment MandatePayment, signatory mandates.Holder, err error) {
+	payment, err = getMandatePayment(id)
+	if err != nil {
+		return MandatePayment{}, mandates.Holder{}, errors.New("payments.loadPendingMandatePayment: " + err.Error())
+	}
+	if payment.Status != MandatePaymentPending {
+		return MandatePayment{}, mandates.Holder{}, errors.New("payments.loadPendingMandatePayment: Payment " + strconv.FormatInt(id, 10) + " is " + payment.Status)
+	}
+	if time.Now().After(payment.ExpiresAt) {
+		return MandatePayment{}, mandates.Holder{}, errors.New("payments.loadPendingMandatePayment: Payment " + strconv.FormatInt(id, 10) + " has expired")
+	}
+
+	signatory, err = mandateSignatory(token, payment.SenderAccountNumber)
+	if err != nil {
+		return MandatePayment{}, mandates.Holder{}, errors.New("payments.loadPendingMandatePayment: " + err.Error())
+	}
+
+	return payment, signatory, nil
+}
+
+// mandateSignatory returns the holder of the account the token user signs as
+func mandateSignatory(token string, accountNumber string) (mandates.Holder, error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return mandates.Holder{}, errors.New("payments.mandateSignatory: " + err.Error())
+	}
+	signatory, found, err := mandates.Signatory(accountNumber, tokenUser)
+	if err != nil {
+		return mandates.Holder{}, errors.New("payments.mandateSignatory: " + err.Error())
+	}
+	if !found {
+		return mandates.Holder{}, errors.New("payments.mandateSignatory: User does not hold account " + accountNumber)
+	}
+
+	return signatory, nil
+}
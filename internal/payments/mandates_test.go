@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/mandates"
+	"github.com/shopspring/decimal"
+)
+
+func TestHeldDebitApprovedAsDebit(t *testing.T) {
+	debit := PAINTrans{
+		PainType:  9,
+		Sender:    AccountHolder{"accountNumSender", "bankNumSender"},
+		Receiver:  AccountHolder{"accountNumReceiver", "bankNumReceiver"},
+		Amount:    decimal.NewFromFloat(150.),
+		Fee:       decimal.NewFromFloat(TRANSACTION_FEE),
+		Narration: "DR",
+		Initiator: "C000000001",
+	}
+	mandate := mandates.Mandate{AccountNumber: "accountNumSender", Rule: "any_two", RequiredApprovals: 2}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	held := newMandatePayment(debit, mandate, "C000000001", now)
+	if held.PainType != 9 {
+		t.Errorf("NewMandatePayment does not pass. PAIN type. Looking for %v, got %v", 9, held.PainType)
+	}
+	if held.Status != MandatePaymentPending || len(held.Approvals) != 1 || held.RequiredApprovals != 2 {
+		t.Errorf("NewMandatePayment does not pass. Looking for %v with %v of %v approvals, got %v with %v of %v", MandatePaymentPending, 1, 2, held.Status, len(held.Approvals), held.RequiredApprovals)
+	}
+	if !held.ExpiresAt.Equal(now.Add(MANDATE_PAYMENT_TTL)) {
+		t.Errorf("NewMandatePayment does not pass. Expiry. Looking for %v, got %v", now.Add(MANDATE_PAYMENT_TTL), held.ExpiresAt)
+	}
+
+	approved := mandatePaymentTransaction(held)
+	if approved.PainType != 9 {
+		t.Errorf("MandatePaymentTransaction does not pass. PAIN type. Looking for %v, got %v", 9, approved.PainType)
+	}
+	if approved.Sender != debit.Sender || approved.Receiver != debit.Receiver {
+		t.Errorf("MandatePaymentTransaction does not pass. Parties. Looking for %v to %v, got %v to %v", debit.Sender, debit.Receiver, approved.Sender, approved.Receiver)
+	}
+	if !approved.Amount.Equal(debit.Amount) || approved.Narration != debit.Narration || approved.Initiator != debit.Initiator {
+		t.Errorf("MandatePaymentTransaction does not pass. Looking for %v %v by %v, got %v %v by %v", debit.Amount, debit.Narration, debit.Initiator, approved.Amount, approved.Narration, approved.Initiator)
+	}
+}
@@ -24,6 +24,10 @@ Payments mandates:
 
 #### Custom payments
 1000 - CustomerDepositInitiation (@FIXME Will need to implement this properly, for now we use it to demonstrate functionality)
+1001 - InternalTransferInitiation (fee free system postings between internal/suspense accounts, never exposed to customers)
+1002 - PotDepositInitiation (main balance to savings pot)
+1003 - PotWithdrawalInitiation (savings pot to main balance)
+1004 - ChargeInitiation (bank charges such as overdraft interest and fees, posted like 1001 but taken even past the available balance)
 
 */
 
@@ -37,6 +41,8 @@ import (
 	"time"
 
 	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
 	"github.com/ebitezion/backend-framework/internal/rbac_2"
 	"github.com/shopspring/decimal"
 )
@@ -57,23 +63,36 @@ type PAINTrans struct {
 	Fee       decimal.Decimal
 	Narration string
 	Initiator string
+	Currency  string
 }
+
+// feeAmount converts the fee percentage to an amount in the transaction's minor units
+func (transaction PAINTrans) feeAmount() decimal.Decimal {
+	return currency.Round(transaction.Currency, transaction.Amount.Mul(transaction.Fee))
+}
+
 type TransactionBatch struct {
 	Transactions []Transaction
 }
 type CashPickup struct {
-	SendersAccountNumber string    `json:"sendersAccountNumber"`
-	FirstName            string    `json:"firstName"`
-	LastName             string    `json:"lastName"`
-	Status               string    `json:"status"`
-	Currency             string    `json:"currency"`
-	Reason               string    `json:"reason"`
-	Amount               float64   `json:"amount"`
-	Charge               float64   `json:"charge"`
-	Timestamp            string    `json:"timestamp"`
-	BVN                  string    `json:"bvn"`
-	NIN                  string    `json:"nin"`
-	UpdatedAt            time.Time `json:"updated_at"`
+	ID                   int64           `json:"id"`
+	PickupCode           string          `json:"pickupCode"`
+	SendersAccountNumber string          `json:"sendersAccountNumber"`
+	FirstName            string          `json:"firstName"`
+	LastName             string          `json:"lastName"`
+	Status               string          `json:"status"`
+	Currency             string          `json:"currency"`
+	Reason               string          `json:"reason"`
+	Amount               decimal.Decimal `json:"amount"`
+	Charge               decimal.Decimal `json:"charge"`
+	Timestamp            string          `json:"timestamp"`
+	BVN                  string          `json:"bvn"`
+	NIN                  string          `json:"nin"`
+	ExpiresAt            time.Time       `json:"expiresAt"`
+	RedeemedBy           string          `json:"redeemedBy"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+	PinHash              string          `json:"-"`
+	PinAttempts          int             `json:"-"`
 }
 
 type Transaction struct {
@@ -187,6 +206,9 @@ func ProcessPAIN(data []string) (result string, err error) {
 		}
 
 		result, err = painCreditTransferInitiation(painType, data)
+		if errors.Is(err, ErrPaymentHeld) {
+			return result, err
+		}
 		if err != nil {
 			return "", errors.New("payments.ProcessPAIN: " + err.Error())
 		}
@@ -198,6 +220,9 @@ func ProcessPAIN(data []string) (result string, err error) {
 		}
 
 		result, err = painDebitTransferInitiation(painType, data)
+		if errors.Is(err, ErrPaymentHeld) {
+			return result, err
+		}
 		if err != nil {
 			return "", errors.New("payments.ProcessPAIN: " + err.Error())
 		}
@@ -237,6 +262,26 @@ func ProcessPAIN(data []string) (result string, err error) {
 			return "", errors.New("payments.ProcessPAIN: " + err.Error())
 		}
 		break
+	case 1001, 1004:
+		//token~pain~type~sender~receiver~amount~narration~initiator
+		if len(data) < 8 {
+			return "", errors.New("payments.ProcessPAIN: Not all data is present. Run pain~help to check for needed PAIN data")
+		}
+		result, err = painInternalTransferInitiation(painType, data)
+		if err != nil {
+			return "", errors.New("payments.ProcessPAIN: " + err.Error())
+		}
+		break
+	case 1002, 1003:
+		//token~pain~type~accountNumber~potID~amount
+		if len(data) < 6 {
+			return "", errors.New("payments.ProcessPAIN: Not all data is present. Run pain~help to check for needed PAIN data")
+		}
+		result, err = painPotTransferInitiation(painType, data)
+		if err != nil {
+			return "", errors.New("payments.ProcessPAIN: " + err.Error())
+		}
+		break
 
 	}
 
@@ -283,23 +328,49 @@ func painCreditTransferInitiation(painType int64, data []string) (result string,
 	}
 
 	Narration := data[6]
-	Initiator := data[7]
-	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator}
+	Initiator := ""
+	if len(data) > 7 {
+		Initiator = data[7]
+	}
+	currencyCode, err := transactionCurrency(sender, receiver, transactionAmountDecimal)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
+	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator, currencyCode}
+
+	// Accounts whose mandate needs more than one signature hold the payment for approval
+	held, err := holdForMandate(data[0], transaction)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
+	if held.ID != 0 {
+		return "Payment " + strconv.FormatInt(held.ID, 10) + " is pending approval by the account holders", ErrPaymentHeld
+	}
+
+	result, err = executeCreditTransfer(transaction)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
 
+	return
+}
+
+// executeCreditTransfer checks the sender can cover the amount and saves the transfer
+func executeCreditTransfer(transaction PAINTrans) (result string, err error) {
 	// Checks for transaction (avail balance, accounts open, etc)
 	balanceAvailable, err := checkBalance(transaction.Sender)
 	if err != nil {
-		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+		return "", errors.New("payments.executeCreditTransfer: " + err.Error())
 	}
 	// Comparing decimals results in -1 if <
 	if balanceAvailable.Cmp(transaction.Amount) == -1 {
-		return "", errors.New("payments.painCreditTransferInitiation: Insufficient funds available")
+		return "", errors.New("payments.executeCreditTransfer: Insufficient funds available")
 	}
 
 	// Save transaction
 	result, err = processPAINTransaction(transaction)
 	if err != nil {
-		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+		return "", errors.New("payments.executeCreditTransfer: " + err.Error())
 	}
 
 	return
@@ -343,7 +414,11 @@ func painFullAccessDepositInitiation(painType int64, data []string) (result stri
 
 	Narration := data[6]
 	Initiator := data[7]
-	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator}
+	currencyCode, err := transactionCurrency(sender, receiver, transactionAmountDecimal)
+	if err != nil {
+		return "", errors.New("payments.painFullAccessDepositInitiation: " + err.Error())
+	}
+	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator, currencyCode}
 
 	// Checks for transaction (avail balance, accounts open, etc)
 	balanceAvailable, err := checkBalance(transaction.Sender)
@@ -406,7 +481,11 @@ func painFullAccessTransferInitiation(painType int64, data []string) (result str
 
 	Narration := data[6]
 	Initiator := data[7]
-	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator}
+	currencyCode, err := transactionCurrency(sender, receiver, transactionAmountDecimal)
+	if err != nil {
+		return "", errors.New("payments.painFullAccessTransferInitiation: " + err.Error())
+	}
+	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator, currencyCode}
 
 	// Checks for transaction (avail balance, accounts open, etc)
 	balanceAvailable, err := checkBalance(transaction.Sender)
@@ -449,28 +528,113 @@ func painDebitTransferInitiation(painType int64, data []string) (result string,
 	if err != nil {
 		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
 	}
-	if tokenUser != sender.AccountNumber {
+	holder, err := IsAccountHolder(tokenUser, sender.AccountNumber)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
+	if !holder {
 		return "", errors.New("payments.painCreditTransferInitiation: Sender not valid")
 	}
 	Narration := data[6]
 	Initiator := data[7]
 
-	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator}
+	currencyCode, err := transactionCurrency(sender, receiver, transactionAmountDecimal)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
+	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator, currencyCode}
+
+	held, err := holdForMandate(data[0], transaction)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
+	if held.ID != 0 {
+		return "Payment " + strconv.FormatInt(held.ID, 10) + " is pending approval by the account holders", ErrPaymentHeld
+	}
 
+	result, err = executeDebitTransfer(transaction)
+	if err != nil {
+		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+	}
+
+	return
+}
+
+// executeDebitTransfer checks the sender's balance covers the amount and saves the debit
+func executeDebitTransfer(transaction PAINTrans) (result string, err error) {
 	// Checks for transaction (avail balance, accounts open, etc)
 	balanceAvailable, err := checkBalance(transaction.Sender)
 	if err != nil {
-		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+		return "", errors.New("payments.executeDebitTransfer: " + err.Error())
 	}
 	// Comparing decimals results in -1 if <
 	if balanceAvailable.Cmp(transaction.Amount) == -1 {
-		return "", errors.New("payments.painCreditTransferInitiation: Insufficient funds available")
+		return "", errors.New("payments.executeDebitTransfer: Insufficient funds available")
 	}
 
 	// Save transaction
 	result, err = processPAINTransaction(transaction)
 	if err != nil {
-		return "", errors.New("payments.painCreditTransferInitiation: " + err.Error())
+		return "", errors.New("payments.executeDebitTransfer: " + err.Error())
+	}
+
+	return
+}
+
+// painInternalTransferInitiation moves funds between accounts without charging a fee.
+// It is used for system postings such as cash pickup suspense movements, and with 1004 for
+// bank charges.
+func painInternalTransferInitiation(painType int64, data []string) (result string, err error) {
+	sender, err := parseAccountHolder(data[3])
+	if err != nil {
+		return "", errors.New("payments.painInternalTransferInitiation: " + err.Error())
+	}
+	receiver, err := parseAccountHolder(data[4])
+	if err != nil {
+		return "", errors.New("payments.painInternalTransferInitiation: " + err.Error())
+	}
+
+	for _, accountNumber := range []string{sender.AccountNumber, receiver.AccountNumber} {
+		exists, err := CheckIfAccountNumberExists(accountNumber)
+		if err != nil {
+			return "", errors.New("payments.painInternalTransferInitiation: " + err.Error())
+		}
+		if !exists {
+			return "", errors.New("payments.painInternalTransferInitiation: Account " + accountNumber + " Not valid")
+		}
+	}
+
+	trAmt := strings.TrimRight(data[5], "\x00")
+	transactionAmountDecimal, err := decimal.NewFromString(trAmt)
+	if err != nil {
+		return "", errors.New("payments.painInternalTransferInitiation: Could not convert transaction amount to decimal. " + err.Error())
+	}
+	if !transactionAmountDecimal.IsPositive() {
+		return "", errors.New("payments.painInternalTransferInitiation: Amount must be greater than zero")
+	}
+
+	Narration := data[6]
+	Initiator := data[7]
+	currencyCode, err := transactionCurrency(sender, receiver, transactionAmountDecimal)
+	if err != nil {
+		return "", errors.New("payments.painInternalTransferInitiation: " + err.Error())
+	}
+	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.Zero, Narration, Initiator, currencyCode}
+
+	// Charges are owed whatever the balance, they may take the account over its limit
+	if painType != 1004 {
+		balanceAvailable, err := checkBalance(transaction.Sender)
+		if err != nil {
+			return "", errors.New("payments.painInternalTransferInitiation: " + err.Error())
+		}
+		if balanceAvailable.Cmp(transaction.Amount) == -1 {
+			return "", errors.New("payments.painInternalTransferInitiation: Insufficient funds available")
+		}
+	}
+
+	result, err = processPAINTransaction(transaction)
+	if err != nil {
+		return "", errors.New("payments.painInternalTransferInitiation: " + err.Error())
 	}
 
 	return
@@ -478,6 +642,12 @@ func painDebitTransferInitiation(painType int64, data []string) (result string,
 func processPAINTransaction(transaction PAINTrans) (result string, err error) {
 	// Test: pain~1~1b2ca241-0373-4610-abad-da7b06c50a7b@~181ac0ae-45cb-461d-b740-15ce33e4612f@~20
 
+	// Enforce the lifecycle rules before anything is posted
+	err = checkAccountStates(transaction)
+	if err != nil {
+		return "", errors.New("payments.processPAINTransaction: " + err.Error())
+	}
+
 	// Save in transaction table
 	err = savePainTransaction(transaction)
 	if err != nil {
@@ -491,7 +661,17 @@ func processPAINTransaction(transaction PAINTrans) (result string, err error) {
 		return "", errors.New("payments.processPAINTransaction: " + err.Error())
 	}
 
-	return
+	// The payment has gone through, a round-up that fails doesn't undo it. It is recorded in
+	// failed_round_ups to be reconciled, and only reported if that fails too.
+	roundUpErr := roundUpDebit(transaction)
+	if roundUpErr != nil {
+		err = saveFailedRoundUp(transaction, roundUpErr.Error())
+		if err != nil {
+			result = "Round-up not made. " + roundUpErr.Error() + ". " + err.Error()
+		}
+	}
+
+	return result, nil
 }
 func processExternalPAINTransaction(transaction PAINTrans) (result string, err error) {
 	// Test: pain~1~1b2ca241-0373-4610-abad-da7b06c50a7b@~181ac0ae-45cb-461d-b740-15ce33e4612f@~20
@@ -499,6 +679,12 @@ func processExternalPAINTransaction(transaction PAINTrans) (result string, err e
 	//external api to actually transfer the money
 
 	// verification of payment
+	// Enforce the lifecycle rules before anything is posted
+	err = checkAccountStates(transaction)
+	if err != nil {
+		return "", errors.New("payments.processPAINTransaction: " + err.Error())
+	}
+
 	// Save in transaction table
 	err = savePainTransaction(transaction)
 	if err != nil {
@@ -515,6 +701,25 @@ func processExternalPAINTransaction(transaction PAINTrans) (result string, err e
 	return
 }
 
+// checkAccountStates checks the sender can be debited and the receiver credited in their
+// lifecycle states. Deposits don't debit the sender, and accounts held at other banks are
+// theirs to police.
+func checkAccountStates(transaction PAINTrans) error {
+	if transaction.PainType != 1000 && transaction.Sender.BankNumber == "" {
+		err := lifecycle.CheckDebit(transaction.Sender.AccountNumber)
+		if err != nil {
+			return errors.New("payments.checkAccountStates: " + err.Error())
+		}
+	}
+	if transaction.Receiver.BankNumber == "" {
+		err := lifecycle.CheckCredit(transaction.Receiver.AccountNumber)
+		if err != nil {
+			return errors.New("payments.checkAccountStates: " + err.Error())
+		}
+	}
+	return nil
+}
+
 func parseAccountHolder(account string) (accountHolder AccountHolder, err error) {
 	accountStr := strings.Split(account, "@")
 
@@ -527,6 +732,41 @@ func parseAccountHolder(account string) (accountHolder AccountHolder, err error)
 	return
 }
 
+// transactionCurrency returns the currency both local accounts are held in. Transfers between
+// accounts in different currencies are rejected, they must go through an FX conversion instead.
+// The amount must fit in the currency's minor units.
+func transactionCurrency(sender AccountHolder, receiver AccountHolder, amount decimal.Decimal) (currencyCode string, err error) {
+	currencyCode = currency.DEFAULT_CURRENCY
+	local := []AccountHolder{}
+	if sender.BankNumber == "" {
+		local = append(local, sender)
+	}
+	if receiver.BankNumber == "" {
+		local = append(local, receiver)
+	}
+
+	for i, account := range local {
+		accountCurrency, err := AccountCurrency(account.AccountNumber)
+		if err != nil {
+			return "", errors.New("payments.transactionCurrency: " + err.Error())
+		}
+		if i > 0 && accountCurrency != currencyCode {
+			return "", errors.New("payments.transactionCurrency: Currency mismatch, " + currencyCode + " account cannot pay a " + accountCurrency + " account")
+		}
+		currencyCode = accountCurrency
+	}
+
+	c, err := currency.Get(currencyCode)
+	if err != nil {
+		return "", errors.New("payments.transactionCurrency: " + err.Error())
+	}
+	if !c.ValidAmount(amount) {
+		return "", errors.New("payments.transactionCurrency: Amount has more decimal places than " + c.Code + " allows")
+	}
+
+	return currencyCode, nil
+}
+
 func customerDepositInitiation(painType int64, data []string) (result string, err error) {
 	// Validate input
 	// Sender is bank
@@ -562,7 +802,11 @@ func customerDepositInitiation(painType int64, data []string) (result string, er
 	if err != nil {
 		return "", errors.New("payments.customerDepositInitiation: " + err.Error())
 	}
-	if tokenUser != sender.AccountNumber {
+	holder, err := IsAccountHolder(tokenUser, sender.AccountNumber)
+	if err != nil {
+		return "", errors.New("payments.customerDepositInitiation: " + err.Error())
+	}
+	if !holder {
 		return "", errors.New("payments.customerDepositInitiation: Sender not valid")
 	}
 	Narration := data[6]
@@ -570,7 +814,11 @@ func customerDepositInitiation(painType int64, data []string) (result string, er
 	// Issue deposit
 	// @TODO This flow show be fixed. Maybe have banks approve deposits before initiation, or
 	// immediate approval below a certain amount subject to rate limiting
-	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator}
+	currencyCode, err := transactionCurrency(sender, receiver, transactionAmountDecimal)
+	if err != nil {
+		return "", errors.New("payments.customerDepositInitiation: " + err.Error())
+	}
+	transaction := PAINTrans{painType, sender, receiver, transactionAmountDecimal, decimal.NewFromFloat(TRANSACTION_FEE), Narration, Initiator, currencyCode}
 	// Save transaction
 	result, err = processPAINTransaction(transaction)
 	if err != nil {
@@ -0,0 +1,212 @@
+package payments
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/ebitezion/backend-framework/internal/lifecycle"
+	"github.com/ebitezion/backend-framework/internal/pots"
+	"github.com/shopspring/decimal"
+)
+
+// painPotTransferInitiation moves money between an account's main balance and one of its
+// pots, 1002 into the pot and 1003 back out. The token user must hold the account.
+// Format: token~pain~1002|1003~accountNumber~potID~amount
+func painPotTransferInitiation(painType int64, data []string) (result string, err error) {
+	tokenUser, err := appauth.GetUserFromToken(data[0])
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: " + err.Error())
+	}
+	accountNumber := strings.TrimSpace(data[3])
+	holder, err := IsAccountHolder(tokenUser, accountNumber)
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: " + err.Error())
+	}
+	if !holder {
+		return "", errors.New("payments.painPotTransferInitiation: Account not valid")
+	}
+
+	potID, err := strconv.ParseInt(strings.TrimSpace(data[4]), 10, 64)
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: Pot not valid")
+	}
+	pot, err := pots.Get(potID)
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: " + err.Error())
+	}
+	if pot.AccountNumber != accountNumber {
+		return "", errors.New("payments.painPotTransferInitiation: Pot does not belong to account " + accountNumber)
+	}
+
+	trAmt := strings.TrimRight(data[5], "\x00")
+	amount, err := decimal.NewFromString(trAmt)
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: Could not convert transaction amount to decimal. " + err.Error())
+	}
+
+	// Ring-fencing money is a debit of the main balance, releasing it a credit
+	if painType == 1002 {
+		err = lifecycle.CheckDebit(accountNumber)
+		if err == nil {
+			err = RequireSingleSignature(accountNumber)
+		}
+	} else {
+		err = lifecycle.CheckCredit(accountNumber)
+	}
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: " + err.Error())
+	}
+
+	narration := potNarration(painType, pot, "")
+	err = postPotTransfer(painType, pot, amount, narration, tokenUser)
+	if err != nil {
+		return "", errors.New("payments.painPotTransferInitiation: " + err.Error())
+	}
+
+	result = narration
+	return
+}
+
+// roundUpDebit moves the round-up on a customer's card or transfer debit into the account's
+// round-up pot. Round-ups are best effort, one that can't be covered is skipped.
+func roundUpDebit(transaction PAINTrans) error {
+	if transaction.Sender.BankNumber != "" {
+		return nil
+	}
+	switch transaction.PainType {
+	case 1, 9, 13:
+	default:
+		return nil
+	}
+
+	pot, found, err := pots.RoundUpPot(transaction.Sender.AccountNumber)
+	if err != nil {
+		return errors.New("payments.roundUpDebit: " + err.Error())
+	}
+	if !found {
+		return nil
+	}
+	amount := pots.RoundUpAmount(transaction.Amount, pot.RoundUpTo)
+	if !amount.IsPositive() {
+		return nil
+	}
+	// Round-ups only come out of the customer's own money, never the overdraft
+	available, err := checkOwnBalance(transaction.Sender)
+	if err != nil {
+		return errors.New("payments.roundUpDebit: " + err.Error())
+	}
+	if available.LessThan(amount) {
+		return nil
+	}
+
+	err = postPotTransfer(1002, pot, amount, potNarration(1002, pot, "Round-up"), transaction.Initiator)
+	if err != nil {
+		return errors.New("payments.roundUpDebit: " + err.Error())
+	}
+	return nil
+}
+
+// ClosePot moves whatever is left in a pot back to the main balance and closes it.
+// The token user must hold the pot's account.
+func ClosePot(token string, potID int64) (pot pots.Pot, err error) {
+	transactionMutex.Lock()
+	defer transactionMutex.Unlock()
+
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return pots.Pot{}, errors.New("payments.ClosePot: " + err.Error())
+	}
+	pot, err = pots.Get(potID)
+	if err != nil {
+		return pots.Pot{}, errors.New("payments.ClosePot: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, pot.AccountNumber)
+	if err != nil {
+		return pots.Pot{}, errors.New("payments.ClosePot: " + err.Error())
+	}
+	if !holder {
+		return pots.Pot{}, errors.New("payments.ClosePot: Account not valid")
+	}
+
+	pot, err = emptyPot(pot, tokenUser)
+	if err != nil {
+		return pots.Pot{}, errors.New("payments.ClosePot: " + err.Error())
+	}
+	return pot, nil
+}
+
+// EmptyAccountPots moves every pot balance back to the main balance and closes the pots,
+// used when the account is closed
+func EmptyAccountPots(accountNumber string, actor string) (emptied decimal.Decimal, err error) {
+	transactionMutex.Lock()
+	defer transactionMutex.Unlock()
+
+	accountPots, err := pots.ForAccount(accountNumber)
+	if err != nil {
+		return decimal.Zero, errors.New("payments.EmptyAccountPots: " + err.Error())
+	}
+
+	emptied = decimal.Zero
+	for _, pot := range accountPots {
+		balance := pot.Balance
+		_, err = emptyPot(pot, actor)
+		if err != nil {
+			return emptied, errors.New("payments.EmptyAccountPots: " + err.Error())
+		}
+		emptied = emptied.Add(balance)
+	}
+	return emptied, nil
+}
+
+func emptyPot(pot pots.Pot, initiator string) (pots.Pot, error) {
+	if pot.Status != pots.PotOpen {
+		return pots.Pot{}, errors.New("payments.emptyPot: Pot is " + pot.Status)
+	}
+	if pot.Balance.IsPositive() {
+		err := postPotTransfer(1003, pot, pot.Balance, potNarration(1003, pot, "Pot closed"), initiator)
+		if err != nil {
+			return pots.Pot{}, errors.New("payments.emptyPot: " + err.Error())
+		}
+		pot.Balance = decimal.Zero
+	}
+
+	err := pots.Close(pot.ID)
+	if err != nil {
+		return pots.Pot{}, errors.New("payments.emptyPot: " + err.Error())
+	}
+	pot.Status = pots.PotClosed
+	pot.RoundUpTo = decimal.Zero
+	return pot, nil
+}
+
+// postPotTransfer checks the amount fits the account's currency and posts the move
+func postPotTransfer(painType int64, pot pots.Pot, amount decimal.Decimal, narration string, initiator string) error {
+	if !amount.IsPositive() {
+		return errors.New("payments.postPotTransfer: Amount must be greater than zero")
+	}
+	account := AccountHolder{pot.AccountNumber, ""}
+	currencyCode, err := transactionCurrency(account, account, amount)
+	if err != nil {
+		return errors.New("payments.postPotTransfer: " + err.Error())
+	}
+
+	transaction := PAINTrans{painType, account, account, amount, decimal.Zero, narration, initiator, currencyCode}
+	err = savePotTransfer(transaction, pot.ID)
+	if err != nil {
+		return errors.New("payments.postPotTransfer: " + err.Error())
+	}
+	return nil
+}
+
+func potNarration(painType int64, pot pots.Pot, reason string) string {
+	narration := "Moved to pot " + pot.Name
+	if painType == 1003 {
+		narration = "Moved from pot " + pot.Name
+	}
+	if reason != "" {
+		narration = reason + ": " + narration
+	}
+	return narration
+}
@@ -0,0 +1,241 @@
+package payments
+
+/*
+Payment requests (request-to-pay)
+
+A customer (the requester) asks another customer (the payer) for an amount. The payer
+can accept the request, which executes a PAIN 1 credit transfer from the payer to the
+requester, decline it, or let it expire.
+
+pending -> accepted | declined | expired
+*/
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/appauth"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	PaymentRequestPending  = "pending"
+	PaymentRequestAccepted = "accepted"
+	PaymentRequestDeclined = "declined"
+	PaymentRequestExpired  = "expired"
+
+	// Requests without an explicit expiry are valid for three days
+	PAYMENT_REQUEST_DEFAULT_TTL = 72 * time.Hour
+	PAYMENT_REQUEST_MAX_TTL     = 30 * 24 * time.Hour
+)
+
+type PaymentRequest struct {
+	ID                     int64           `json:"id"`
+	RequesterAccountNumber string          `json:"requesterAccountNumber"`
+	PayerAccountNumber     string          `json:"payerAccountNumber"`
+	Amount                 decimal.Decimal `json:"amount"`
+	Reason                 string          `json:"reason"`
+	Status                 string          `json:"status"`
+	ExpiresAt              time.Time       `json:"expiresAt"`
+	Timestamp              time.Time       `json:"timestamp"`
+}
+
+// CreatePaymentRequest records a new pending request from requester to payer.
+// The token user must be the requester.
+func CreatePaymentRequest(token string, requester string, payer string, amount string, reason string, ttl time.Duration) (request PaymentRequest, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, requester)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: " + err.Error())
+	}
+	if !holder {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Requester not valid")
+	}
+	if requester == payer {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Cannot request payment from own account")
+	}
+
+	amountDecimal, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Could not convert amount to decimal. " + err.Error())
+	}
+	if !amountDecimal.IsPositive() {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Amount must be greater than zero")
+	}
+
+	if ttl <= 0 {
+		ttl = PAYMENT_REQUEST_DEFAULT_TTL
+	}
+	if ttl > PAYMENT_REQUEST_MAX_TTL {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Expiry too far in the future")
+	}
+
+	exists, err := CheckIfAccountIsActive(requester)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: " + err.Error())
+	}
+	if !exists {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Requesters Account Not valid")
+	}
+	exists, err = CheckIfAccountNumberExists(payer)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: " + err.Error())
+	}
+	if !exists {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: Payers Account Not valid")
+	}
+
+	request = PaymentRequest{
+		RequesterAccountNumber: requester,
+		PayerAccountNumber:     payer,
+		Amount:                 amountDecimal,
+		Reason:                 reason,
+		Status:                 PaymentRequestPending,
+		ExpiresAt:              time.Now().Add(ttl),
+	}
+
+	request.ID, err = savePaymentRequest(request)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.CreatePaymentRequest: " + err.Error())
+	}
+
+	return request, nil
+}
+
+// PendingPaymentRequests lists the requests awaiting the payer's decision.
+// Stale requests are expired before the list is read. The token user must be the payer.
+func PendingPaymentRequests(token string, payer string) (requests []PaymentRequest, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return nil, errors.New("payments.PendingPaymentRequests: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, payer)
+	if err != nil {
+		return nil, errors.New("payments.PendingPaymentRequests: " + err.Error())
+	}
+	if !holder {
+		return nil, errors.New("payments.PendingPaymentRequests: Payer not valid")
+	}
+
+	_, err = ExpirePaymentRequests()
+	if err != nil {
+		return nil, errors.New("payments.PendingPaymentRequests: " + err.Error())
+	}
+
+	requests, err = getPaymentRequestsByPayer(payer, PaymentRequestPending)
+	if err != nil {
+		return nil, errors.New("payments.PendingPaymentRequests: " + err.Error())
+	}
+
+	return requests, nil
+}
+
+// AcceptPaymentRequest pays a pending request with a PAIN 1 credit transfer from the
+// payer to the requester. The token user must be the payer.
+func AcceptPaymentRequest(token string, id int64, payer string) (request PaymentRequest, err error) {
+	request, err = loadPaymentRequestForPayer(token, id, payer)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.AcceptPaymentRequest: " + err.Error())
+	}
+
+	err = RequireSingleSignature(request.PayerAccountNumber)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.AcceptPaymentRequest: " + err.Error())
+	}
+
+	// Claim the request first so a second accept cannot pay it twice
+	err = updatePaymentRequestStatus(id, PaymentRequestPending, PaymentRequestAccepted)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.AcceptPaymentRequest: " + err.Error())
+	}
+
+	narration := "Payment request " + strconv.FormatInt(request.ID, 10)
+	if request.Reason != "" {
+		narration += ": " + request.Reason
+	}
+
+	_, err = ProcessPAIN([]string{token, "pain", "1", request.PayerAccountNumber + "@", request.RequesterAccountNumber + "@", request.Amount.String(), narration, request.PayerAccountNumber})
+	if err != nil {
+		// Hand the request back to the payer so it can be retried
+		if revertErr := updatePaymentRequestStatus(id, PaymentRequestAccepted, PaymentRequestPending); revertErr != nil {
+			return PaymentRequest{}, errors.New("payments.AcceptPaymentRequest: " + err.Error() + ". " + revertErr.Error())
+		}
+		return PaymentRequest{}, errors.New("payments.AcceptPaymentRequest: " + err.Error())
+	}
+
+	request.Status = PaymentRequestAccepted
+	return request, nil
+}
+
+// DeclinePaymentRequest rejects a pending request. The token user must be the payer.
+func DeclinePaymentRequest(token string, id int64, payer string) (request PaymentRequest, err error) {
+	request, err = loadPaymentRequestForPayer(token, id, payer)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.DeclinePaymentRequest: " + err.Error())
+	}
+
+	err = updatePaymentRequestStatus(id, PaymentRequestPending, PaymentRequestDeclined)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.DeclinePaymentRequest: " + err.Error())
+	}
+
+	request.Status = PaymentRequestDeclined
+	return request, nil
+}
+
+// ExpirePaymentRequests moves every pending request past its expiry to expired
+func ExpirePaymentRequests() (expired int64, err error) {
+	expired, err = expireStalePaymentRequests(time.Now())
+	if err != nil {
+		return 0, errors.New("payments.ExpirePaymentRequests: " + err.Error())
+	}
+
+	return expired, nil
+}
+
+// DeclineAccountPaymentRequests declines every pending request to or from the account,
+// used when the account is closed
+func DeclineAccountPaymentRequests(accountNumber string) (declined int64, err error) {
+	declined, err = declineAccountPaymentRequests(accountNumber)
+	if err != nil {
+		return 0, errors.New("payments.DeclineAccountPaymentRequests: " + err.Error())
+	}
+
+	return declined, nil
+}
+
+func loadPaymentRequestForPayer(token string, id int64, payer string) (request PaymentRequest, err error) {
+	tokenUser, err := appauth.GetUserFromToken(token)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: " + err.Error())
+	}
+	holder, err := IsAccountHolder(tokenUser, payer)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: " + err.Error())
+	}
+	if !holder {
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: Payer not valid")
+	}
+
+	request, err = getPaymentRequest(id)
+	if err != nil {
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: " + err.Error())
+	}
+	if request.PayerAccountNumber != payer {
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: Payment request not found")
+	}
+	if request.Status != PaymentRequestPending {
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: Payment request is " + request.Status)
+	}
+	if time.Now().After(request.ExpiresAt) {
+		_, _ = ExpirePaymentRequests()
+		return PaymentRequest{}, errors.New("payments.loadPaymentRequestForPayer: Payment request has expired")
+	}
+
+	return request, nil
+}
@@ -0,0 +1,153 @@
+package pots
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const potColumns = "`id`, `accountNumber`, `name`, `currencyCode`, `balance`, `targetAmount`, IFNULL(`targetDate`, ''), `roundUpTo`, `status`, `timestamp`"
+
+func getAccountCurrency(accountNumber string) (currencyCode string, err error) {
+	err = Config.Db.QueryRow("SELECT `currencyCode` FROM `accounts` WHERE `accountNumber` = ?", accountNumber).Scan(&currencyCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("pots.getAccountCurrency: Account " + accountNumber + " not found")
+		}
+		return "", errors.New("pots.getAccountCurrency: " + err.Error())
+	}
+	return
+}
+
+// savePot inserts a new pot. Only one pot per account collects round-ups, so turning them on
+// here turns them off for the account's other pots.
+func savePot(pot Pot) (id int64, err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return 0, errors.New("pots.savePot: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO account_pots (`accountNumber`, `name`, `currencyCode`, `balance`, `targetAmount`, `targetDate`, `roundUpTo`, `status`) VALUES(?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)",
+		pot.AccountNumber, pot.Name, pot.CurrencyCode, pot.Balance, pot.TargetAmount, pot.TargetDate, pot.RoundUpTo, pot.Status)
+	if err != nil {
+		return 0, errors.New("pots.savePot: " + err.Error())
+	}
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("pots.savePot: " + err.Error())
+	}
+
+	if pot.RoundUpTo.IsPositive() {
+		_, err = tx.Exec("UPDATE account_pots SET `roundUpTo` = 0 WHERE `accountNumber` = ? AND `id` <> ?", pot.AccountNumber, id)
+		if err != nil {
+			return 0, errors.New("pots.savePot: " + err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.New("pots.savePot: " + err.Error())
+	}
+
+	return id, nil
+}
+
+// updatePot saves a pot's details. Its balance is only ever changed by payments.
+func updatePot(pot Pot) (err error) {
+	tx, err := Config.Db.Begin()
+	if err != nil {
+		return errors.New("pots.updatePot: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE account_pots SET `name` = ?, `targetAmount` = ?, `targetDate` = NULLIF(?, ''), `roundUpTo` = ? WHERE `id` = ?",
+		pot.Name, pot.TargetAmount, pot.TargetDate, pot.RoundUpTo, pot.ID)
+	if err != nil {
+		return errors.New("pots.updatePot: " + err.Error())
+	}
+
+	if pot.RoundUpTo.IsPositive() {
+		_, err = tx.Exec("UPDATE account_pots SET `roundUpTo` = 0 WHERE `accountNumber` = ? AND `id` <> ?", pot.AccountNumber, pot.ID)
+		if err != nil {
+			return errors.New("pots.updatePot: " + err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("pots.updatePot: " + err.Error())
+	}
+
+	return nil
+}
+
+func getPot(id int64) (pot Pot, found bool, err error) {
+	rows, err := Config.Db.Query("SELECT "+potColumns+" FROM `account_pots` WHERE `id` = ?", id)
+	if err != nil {
+		return Pot{}, false, errors.New("pots.getPot: " + err.Error())
+	}
+	defer rows.Close()
+
+	pots, err := scanPots(rows)
+	if err != nil {
+		return Pot{}, false, errors.New("pots.getPot: " + err.Error())
+	}
+	if len(pots) == 0 {
+		return Pot{}, false, nil
+	}
+
+	return pots[0], true, nil
+}
+
+func getAccountPots(accountNumber string) (pots []Pot, err error) {
+	rows, err := Config.Db.Query("SELECT "+potColumns+" FROM `account_pots` WHERE `accountNumber` = ? AND `status` = ? ORDER BY `id`", accountNumber, PotOpen)
+	if err != nil {
+		return nil, errors.New("pots.getAccountPots: " + err.Error())
+	}
+	defer rows.Close()
+
+	return scanPots(rows)
+}
+
+func scanPots(rows *sql.Rows) (pots []Pot, err error) {
+	pots = make([]Pot, 0)
+	for rows.Next() {
+		var pot Pot
+		var timestamp string
+		if err := rows.Scan(&pot.ID, &pot.AccountNumber, &pot.Name, &pot.CurrencyCode, &pot.Balance, &pot.TargetAmount, &pot.TargetDate, &pot.RoundUpTo, &pot.Status, &timestamp); err != nil {
+			return nil, errors.New("pots.scanPots: " + err.Error())
+		}
+		pot.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp)
+		if err != nil {
+			return nil, errors.New("pots.scanPots: " + err.Error())
+		}
+		pots = append(pots, pot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("pots.scanPots: " + err.Error())
+	}
+
+	return pots, nil
+}
+
+// closePot only closes a pot that is open and empty
+func closePot(id int64) (err error) {
+	res, err := Config.Db.Exec("UPDATE account_pots SET `status` = ?, `roundUpTo` = 0 WHERE `id` = ? AND `status` = ? AND `balance` = 0", PotClosed, id, PotOpen)
+	if err != nil {
+		return errors.New("pots.closePot: " + err.Error())
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("pots.closePot: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("pots.closePot: Pot is not open or still holds money")
+	}
+
+	return nil
+}
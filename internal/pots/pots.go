@@ -0,0 +1,261 @@
+package pots
+
+/*
+Savings pots
+
+A pot is a named sub-balance ring-fenced inside an account. Money in a pot still belongs to
+the account and counts towards its ledger balance (accountBalance), but it is taken out of the
+available balance so it can't be spent until it's moved back.
+
+Moves between the main balance and a pot are PAIN 1002 (into the pot) and 1003 (out of the
+pot) ledger postings made by payments, which also keeps the pot balances here up to date.
+
+A pot can have a target amount and date to save towards, and one pot per account can collect
+round-ups: after each card or transfer debit the amount needed to round the debit up to the
+next multiple of the pot's round-up value is moved into the pot. A round-up that fails doesn't
+undo the debit, it is recorded in failed_round_ups to be reconciled.
+*/
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	PotOpen   = "open"
+	PotClosed = "closed"
+
+	DATE_LAYOUT     = "2006-01-02"
+	MAX_NAME_LENGTH = 50
+)
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// Pot is a named sub-balance of an account
+type Pot struct {
+	ID            int64           `json:"id"`
+	AccountNumber string          `json:"accountNumber"`
+	Name          string          `json:"name"`
+	CurrencyCode  string          `json:"currencyCode"`
+	Balance       decimal.Decimal `json:"balance"`
+	TargetAmount  decimal.Decimal `json:"targetAmount"`
+	TargetDate    string          `json:"targetDate,omitempty"`
+	RoundUpTo     decimal.Decimal `json:"roundUpTo"`
+	Status        string          `json:"status"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// Create opens a new, empty pot under an account. The target amount, target date and
+// round-up value are optional. Giving the pot a round-up value stops the account's other
+// pots collecting round-ups.
+func Create(accountNumber string, name string, targetAmount string, targetDate string, roundUpTo string) (Pot, error) {
+	pot := Pot{
+		AccountNumber: strings.TrimSpace(accountNumber),
+		Name:          strings.TrimSpace(name),
+		TargetDate:    strings.TrimSpace(targetDate),
+		Balance:       decimal.Zero,
+		Status:        PotOpen,
+	}
+
+	var err error
+	pot.CurrencyCode, err = getAccountCurrency(pot.AccountNumber)
+	if err != nil {
+		return Pot{}, errors.New("pots.Create: " + err.Error())
+	}
+	pot.TargetAmount, err = parseAmount(pot.CurrencyCode, "Target amount", targetAmount)
+	if err != nil {
+		return Pot{}, errors.New("pots.Create: " + err.Error())
+	}
+	pot.RoundUpTo, err = parseAmount(pot.CurrencyCode, "Round-up value", roundUpTo)
+	if err != nil {
+		return Pot{}, errors.New("pots.Create: " + err.Error())
+	}
+	err = validatePot(pot, time.Now())
+	if err != nil {
+		return Pot{}, errors.New("pots.Create: " + err.Error())
+	}
+
+	err = checkNameFree(pot)
+	if err != nil {
+		return Pot{}, errors.New("pots.Create: " + err.Error())
+	}
+
+	pot.ID, err = savePot(pot)
+	if err != nil {
+		return Pot{}, errors.New("pots.Create: " + err.Error())
+	}
+	pot.Timestamp = time.Now()
+
+	return pot, nil
+}
+
+// Update changes a pot's name, target and round-up value. Round-ups move to this pot when
+// it's given a round-up value.
+func Update(id int64, name string, targetAmount string, targetDate string, roundUpTo string) (Pot, error) {
+	pot, err := Get(id)
+	if err != nil {
+		return Pot{}, errors.New("pots.Update: " + err.Error())
+	}
+	if pot.Status != PotOpen {
+		return Pot{}, errors.New("pots.Update: Pot is " + pot.Status)
+	}
+
+	pot.Name = strings.TrimSpace(name)
+	pot.TargetDate = strings.TrimSpace(targetDate)
+	pot.TargetAmount, err = parseAmount(pot.CurrencyCode, "Target amount", targetAmount)
+	if err != nil {
+		return Pot{}, errors.New("pots.Update: " + err.Error())
+	}
+	pot.RoundUpTo, err = parseAmount(pot.CurrencyCode, "Round-up value", roundUpTo)
+	if err != nil {
+		return Pot{}, errors.New("pots.Update: " + err.Error())
+	}
+	err = validatePot(pot, time.Now())
+	if err != nil {
+		return Pot{}, errors.New("pots.Update: " + err.Error())
+	}
+
+	err = checkNameFree(pot)
+	if err != nil {
+		return Pot{}, errors.New("pots.Update: " + err.Error())
+	}
+
+	err = updatePot(pot)
+	if err != nil {
+		return Pot{}, errors.New("pots.Update: " + err.Error())
+	}
+
+	return pot, nil
+}
+
+// Get returns a pot by ID
+func Get(id int64) (Pot, error) {
+	pot, found, err := getPot(id)
+	if err != nil {
+		return Pot{}, errors.New("pots.Get: " + err.Error())
+	}
+	if !found {
+		return Pot{}, errors.New("pots.Get: Pot " + strconv.FormatInt(id, 10) + " not found")
+	}
+	return pot, nil
+}
+
+// ForAccount lists an account's open pots
+func ForAccount(accountNumber string) ([]Pot, error) {
+	pots, err := getAccountPots(strings.TrimSpace(accountNumber))
+	if err != nil {
+		return nil, errors.New("pots.ForAccount: " + err.Error())
+	}
+	return pots, nil
+}
+
+// RoundUpPot returns the account's pot that collects round-ups, if it has one
+func RoundUpPot(accountNumber string) (pot Pot, found bool, err error) {
+	pots, err := getAccountPots(accountNumber)
+	if err != nil {
+		return Pot{}, false, errors.New("pots.RoundUpPot: " + err.Error())
+	}
+	for _, pot := range pots {
+		if pot.RoundUpTo.IsPositive() {
+			return pot, true, nil
+		}
+	}
+	return Pot{}, false, nil
+}
+
+// Close marks an empty pot closed. Payments moves any balance back to the account first.
+func Close(id int64) error {
+	err := closePot(id)
+	if err != nil {
+		return errors.New("pots.Close: " + err.Error())
+	}
+	return nil
+}
+
+// RoundUpAmount is what a debit of amount needs to reach the next multiple of roundUpTo.
+// Debits that are already a multiple round up by nothing.
+func RoundUpAmount(amount decimal.Decimal, roundUpTo decimal.Decimal) decimal.Decimal {
+	if !roundUpTo.IsPositive() || !amount.IsPositive() {
+		return decimal.Zero
+	}
+	remainder := amount.Mod(roundUpTo)
+	if remainder.IsZero() {
+		return decimal.Zero
+	}
+	return roundUpTo.Sub(remainder)
+}
+
+// Progress is the share of the target amount saved so far, from 0 to 1. Pots without a
+// target have no progress.
+func (pot Pot) Progress() decimal.Decimal {
+	if !pot.TargetAmount.IsPositive() {
+		return decimal.Zero
+	}
+	progress := pot.Balance.Div(pot.TargetAmount)
+	if progress.GreaterThan(decimal.NewFromInt(1)) {
+		return decimal.NewFromInt(1)
+	}
+	return progress.Round(4)
+}
+
+func validatePot(pot Pot, now time.Time) error {
+	if pot.Name == "" {
+		return errors.New("pots.validatePot: Name must be provided")
+	}
+	if len(pot.Name) > MAX_NAME_LENGTH {
+		return errors.New("pots.validatePot: Name must not be more than " + strconv.Itoa(MAX_NAME_LENGTH) + " characters")
+	}
+	if pot.TargetDate != "" {
+		targetDate, err := time.Parse(DATE_LAYOUT, pot.TargetDate)
+		if err != nil {
+			return errors.New("pots.validatePot: Target date must be in the format YYYY-MM-DD")
+		}
+		if !targetDate.After(now) {
+			return errors.New("pots.validatePot: Target date must be in the future")
+		}
+	}
+	return nil
+}
+
+// parseAmount reads an optional amount, empty is zero. It must fit the currency's minor units.
+func parseAmount(currencyCode string, field string, value string) (decimal.Decimal, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, errors.New("pots.parseAmount: " + field + " is not a valid amount")
+	}
+	if amount.IsNegative() {
+		return decimal.Zero, errors.New("pots.parseAmount: " + field + " must not be negative")
+	}
+	if !currency.Round(currencyCode, amount).Equal(amount) {
+		return decimal.Zero, errors.New("pots.parseAmount: " + field + " has more decimal places than " + currencyCode + " allows")
+	}
+	return amount, nil
+}
+
+// checkNameFree refuses a second open pot with the same name under the account
+func checkNameFree(pot Pot) error {
+	pots, err := getAccountPots(pot.AccountNumber)
+	if err != nil {
+		return errors.New("pots.checkNameFree: " + err.Error())
+	}
+	for _, other := range pots {
+		if other.ID != pot.ID && strings.EqualFold(other.Name, pot.Name) {
+			return errors.New("pots.checkNameFree: The account already has a pot called " + other.Name)
+		}
+	}
+	return nil
+}
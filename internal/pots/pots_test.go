@@ -0,0 +1,90 @@
+package pots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundUpAmount(t *testing.T) {
+	cases := []struct {
+		amount    string
+		roundUpTo string
+		roundUp   string
+	}{
+		{"3.40", "1", "0.6"},
+		{"12.01", "5", "2.99"},
+		{"20", "10", "0"},
+		{"0.99", "1", "0.01"},
+		{"7", "0", "0"},
+		{"0", "1", "0"},
+	}
+	for _, c := range cases {
+		roundUp := RoundUpAmount(decimal.RequireFromString(c.amount), decimal.RequireFromString(c.roundUpTo))
+		if !roundUp.Equal(decimal.RequireFromString(c.roundUp)) {
+			t.Errorf("RoundUpAmount does not pass. Looking for %v, got %v for %v to %v", c.roundUp, roundUp, c.amount, c.roundUpTo)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	cases := []struct {
+		balance  string
+		target   string
+		progress string
+	}{
+		{"25", "100", "0.25"},
+		{"150", "100", "1"},
+		{"10", "0", "0"},
+		{"0", "30", "0"},
+	}
+	for _, c := range cases {
+		pot := Pot{Balance: decimal.RequireFromString(c.balance), TargetAmount: decimal.RequireFromString(c.target)}
+		if !pot.Progress().Equal(decimal.RequireFromString(c.progress)) {
+			t.Errorf("Progress does not pass. Looking for %v, got %v for %v of %v", c.progress, pot.Progress(), c.balance, c.target)
+		}
+	}
+}
+
+func TestValidatePot(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	valid := []Pot{
+		{Name: "Holiday"},
+		{Name: "Rent", TargetDate: "2024-12-31"},
+	}
+	for _, pot := range valid {
+		if err := validatePot(pot, now); err != nil {
+			t.Errorf("validatePot does not pass. Looking for %v, got %v for %+v", nil, err, pot)
+		}
+	}
+
+	invalid := []Pot{
+		{Name: ""},
+		{Name: "A name that is far too long to be the name of a savings pot"},
+		{Name: "Rent", TargetDate: "31/12/2024"},
+		{Name: "Rent", TargetDate: "2024-01-31"},
+	}
+	for _, pot := range invalid {
+		if err := validatePot(pot, now); err == nil {
+			t.Errorf("validatePot does not pass. Looking for an error, got %v for %+v", err, pot)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	amount, err := parseAmount("NGN", "Target amount", "")
+	if err != nil || !amount.IsZero() {
+		t.Errorf("parseAmount does not pass. Looking for %v, got %v (%v)", 0, amount, err)
+	}
+	amount, err = parseAmount("NGN", "Target amount", "250.50")
+	if err != nil || !amount.Equal(decimal.RequireFromString("250.50")) {
+		t.Errorf("parseAmount does not pass. Looking for %v, got %v (%v)", "250.50", amount, err)
+	}
+	for _, value := range []string{"abc", "-5", "1.005"} {
+		if _, err := parseAmount("NGN", "Target amount", value); err == nil {
+			t.Errorf("parseAmount does not pass. Looking for an error, got %v for %v", err, value)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package rbac_2
 
 import (
+	"database/sql"
 	"errors"
 	"sync"
 
@@ -11,6 +12,9 @@ import (
 // Role represents a user role
 type Role string
 
+// CustomerRole is the role of everyone in accounts_auth who hasn't been given a staff role
+const CustomerRole Role = "customer"
+
 // Privilege represents a specific action a user can perform
 type Privilege string
 
@@ -88,34 +92,29 @@ func (r *RBAC) AddUser(user User) error {
 	return nil
 }
 
-// GetUserByUsername retrieves a user by username from the RBAC system
+// GetUserByUsername retrieves a user by username from the RBAC system. Users that weren't
+// added to it are looked up in accounts_auth by the account number their token was issued for.
 func (r *RBAC) GetUserByUsername(username string) (User, error) {
 	r.mutex.RLock()
-	defer r.mutex.RUnlock()
-
-	// user, exists := r.Users[username]
-	// if !exists {
-	// 	return User{}, errors.New("user not found")
-	// }
-	type Request struct {
-		Username string
-		Role     string
+	user, exists := r.Users[username]
+	r.mutex.RUnlock()
+	if exists {
+		return user, nil
+	}
+	if Config.Db == nil {
+		return User{}, errors.New("rbac_2.GetUserByUsername: user not found")
 	}
 
-	// query := "SELECT Username, Role FROM `accounts_auth` WHERE `username` = ?"
-
-	// ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	// defer cancel()
-
-	// //err := Config.Db.QueryRowContext(ctx, query, username).Scan(&accountNumber)
-	// if err != nil {
-	// 	if errors.Is(err, sql.ErrNoRows) {
-	// 		return errors.New("accounts.GetUserByUsername: Account not found")
-	// 	}
-	// 	return err
-	// }
+	var role string
+	err := Config.Db.QueryRow("SELECT `role` FROM `accounts_auth` WHERE `accountNumber` = ?", username).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, errors.New("rbac_2.GetUserByUsername: user not found")
+		}
+		return User{}, errors.New("rbac_2.GetUserByUsername: " + err.Error())
+	}
 
-	return User{}, nil
+	return User{Username: username, Role: Role(role)}, nil
 }
 
 // UpdateUser updates an existing user in the RBAC system and the database
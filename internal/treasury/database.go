@@ -0,0 +1,184 @@
+package treasury
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+	"github.com/ebitezion/backend-framework/internal/converter"
+)
+
+const SQL_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+const rateChangeColumns = "`id`, `baseCurrency`, `quoteCurrency`, `rate`, `margin`, `effectiveAt`, `status`, `maker`, IFNULL(`checker`, ''), IFNULL(`reviewNote`, ''), IFNULL(`appliedAt`, ''), `timestamp`"
+
+func saveRateChange(change RateChange) (id int64, err error) {
+	insertStatement := "INSERT INTO fx_rate_changes (`baseCurrency`, `quoteCurrency`, `rate`, `margin`, `effectiveAt`, `status`, `maker`, `timestamp`) "
+	insertStatement += "VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("treasury.saveRateChange: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(change.BaseCurrency, change.QuoteCurrency, change.Rate, change.Margin, change.EffectiveAt, change.Status, change.Maker, change.Timestamp)
+	if err != nil {
+		return 0, errors.New("treasury.saveRateChange: " + err.Error())
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("treasury.saveRateChange: " + err.Error())
+	}
+
+	return
+}
+
+func getRateChange(id int64) (RateChange, error) {
+	rows, err := Config.Db.Query("SELECT "+rateChangeColumns+" FROM `fx_rate_changes` WHERE `id` = ?", id)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.getRateChange: " + err.Error())
+	}
+	defer rows.Close()
+
+	changes, err := scanRateChanges(rows)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.getRateChange: " + err.Error())
+	}
+	if len(changes) == 0 {
+		return RateChange{}, errors.New("treasury.getRateChange: Rate change not found")
+	}
+
+	return changes[0], nil
+}
+
+func getRateChanges(status string) (changes []RateChange, err error) {
+	rows, err := Config.Db.Query("SELECT "+rateChangeColumns+" FROM `fx_rate_changes` WHERE ? = '' OR `status` = ? ORDER BY `timestamp` DESC, `id` DESC", status, status)
+	if err != nil {
+		return nil, errors.New("treasury.getRateChanges: " + err.Error())
+	}
+	defer rows.Close()
+
+	changes, err = scanRateChanges(rows)
+	if err != nil {
+		return nil, errors.New("treasury.getRateChanges: " + err.Error())
+	}
+
+	return changes, nil
+}
+
+// getDueRateChanges returns approved changes effective at or before now, oldest first so later changes win
+func getDueRateChanges(now time.Time) (changes []RateChange, err error) {
+	rows, err := Config.Db.Query("SELECT "+rateChangeColumns+" FROM `fx_rate_changes` WHERE `status` = ? AND `effectiveAt` <= ? ORDER BY `effectiveAt`, `id`", ChangeApproved, now)
+	if err != nil {
+		return nil, errors.New("treasury.getDueRateChanges: " + err.Error())
+	}
+	defer rows.Close()
+
+	changes, err = scanRateChanges(rows)
+	if err != nil {
+		return nil, errors.New("treasury.getDueRateChanges: " + err.Error())
+	}
+
+	return changes, nil
+}
+
+func scanRateChanges(rows *sql.Rows) (changes []RateChange, err error) {
+	changes = make([]RateChange, 0)
+	for rows.Next() {
+		var change RateChange
+		var effectiveAt, appliedAt, timestamp string
+		err := rows.Scan(&change.ID, &change.BaseCurrency, &change.QuoteCurrency, &change.Rate, &change.Margin, &effectiveAt, &change.Status, &change.Maker, &change.Checker, &change.ReviewNote, &appliedAt, &timestamp)
+		if err != nil {
+			return nil, errors.New("treasury.scanRateChanges: " + err.Error())
+		}
+		if change.EffectiveAt, err = time.Parse(SQL_TIME_LAYOUT, effectiveAt); err != nil {
+			return nil, errors.New("treasury.scanRateChanges: " + err.Error())
+		}
+		if appliedAt != "" {
+			applied, err := time.Parse(SQL_TIME_LAYOUT, appliedAt)
+			if err != nil {
+				return nil, errors.New("treasury.scanRateChanges: " + err.Error())
+			}
+			change.AppliedAt = &applied
+		}
+		if change.Timestamp, err = time.Parse(SQL_TIME_LAYOUT, timestamp); err != nil {
+			return nil, errors.New("treasury.scanRateChanges: " + err.Error())
+		}
+		change.BuyRate, change.SellRate = converter.BuySellRates(change.Rate, change.Margin)
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("treasury.scanRateChanges: " + err.Error())
+	}
+
+	return changes, nil
+}
+
+func reviewRateChange(id int64, from string, to string, checker string, note string) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE fx_rate_changes SET `status` = ?, `checker` = NULLIF(?, ''), `reviewNote` = NULLIF(?, ''), `updated_at` = ? WHERE `id` = ? AND `status` = ?")
+	if err != nil {
+		return errors.New("treasury.reviewRateChange: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	res, err := stmtUpd.Exec(to, checker, note, time.Now().UTC(), id, from)
+	if err != nil {
+		return errors.New("treasury.reviewRateChange: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("treasury.reviewRateChange: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("treasury.reviewRateChange: Change is no longer " + from)
+	}
+
+	return nil
+}
+
+func markRateChangeApplied(id int64) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE fx_rate_changes SET `status` = ?, `appliedAt` = ?, `updated_at` = ? WHERE `id` = ? AND `status` = ?")
+	if err != nil {
+		return errors.New("treasury.markRateChangeApplied: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	now := time.Now().UTC()
+	res, err := stmtUpd.Exec(ChangeApplied, now, now, id, ChangeApproved)
+	if err != nil {
+		return errors.New("treasury.markRateChangeApplied: " + err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("treasury.markRateChangeApplied: " + err.Error())
+	}
+	if affected == 0 {
+		return errors.New("treasury.markRateChangeApplied: Change is no longer " + ChangeApproved)
+	}
+
+	return nil
+}
+
+func revertRateChangeApplied(id int64) (err error) {
+	stmtUpd, err := Config.Db.Prepare("UPDATE fx_rate_changes SET `status` = ?, `appliedAt` = NULL, `updated_at` = ? WHERE `id` = ? AND `status` = ?")
+	if err != nil {
+		return errors.New("treasury.revertRateChangeApplied: " + err.Error())
+	}
+	defer stmtUpd.Close()
+
+	_, err = stmtUpd.Exec(ChangeApproved, time.Now().UTC(), id, ChangeApplied)
+	if err != nil {
+		return errors.New("treasury.revertRateChangeApplied: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,386 @@
+package treasury
+
+/*
+Treasury
+
+Treasury staff manage the manual FX rate table and the margin charged on each currency
+pair. Every change goes through maker-checker: one member of staff proposes it and a
+different one approves or rejects it. Approved changes take effect at their effective
+time, straight away when it has already passed, otherwise when the background job next
+runs. Each step is written to the audit log. Conversions only use the manual table when
+manual is listed in EXCHANGE_RATE_PROVIDERS, where it overrides the upstream rates pair by pair.
+
+A change is entered either as a mid rate and margin, or as the bank's buy and sell rates
+for one unit of the base currency, from which the mid rate and margin are worked out.
+
+pending -> approved -> applied
+pending -> rejected | cancelled
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebitezion/backend-framework/internal/audit"
+	"github.com/ebitezion/backend-framework/internal/converter"
+	"github.com/ebitezion/backend-framework/internal/currency"
+	"github.com/ebitezion/backend-framework/internal/fx"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ChangePending   = "pending"
+	ChangeApproved  = "approved"
+	ChangeApplied   = "applied"
+	ChangeRejected  = "rejected"
+	ChangeCancelled = "cancelled"
+
+	MAX_MARGIN           = 0.2 // 20%
+	MARGIN_PLACES        = 8
+	EFFECTIVE_AT_LAYOUT  = "2006-01-02T15:04"
+	auditEntityRate      = "fx_rate_change"
+	auditActionProposed  = "proposed"
+	auditActionApproved  = "approved"
+	auditActionRejected  = "rejected"
+	auditActionCancelled = "cancelled"
+	auditActionApplied   = "applied"
+	systemActor          = "system"
+)
+
+type RateChange struct {
+	ID            int64           `json:"id"`
+	BaseCurrency  string          `json:"baseCurrency"`
+	QuoteCurrency string          `json:"quoteCurrency"`
+	Rate          decimal.Decimal `json:"rate"`
+	Margin        decimal.Decimal `json:"margin"`
+	BuyRate       decimal.Decimal `json:"buyRate"`
+	SellRate      decimal.Decimal `json:"sellRate"`
+	EffectiveAt   time.Time       `json:"effectiveAt"`
+	Status        string          `json:"status"`
+	Maker         string          `json:"maker"`
+	Checker       string          `json:"checker"`
+	ReviewNote    string          `json:"reviewNote"`
+	AppliedAt     *time.Time      `json:"appliedAt,omitempty"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// NewRateChange validates a change entered as rate and margin, or as buyRate and sellRate.
+// effectiveAt is YYYY-MM-DDTHH:MM in UTC, the change takes effect on approval when it is empty.
+func NewRateChange(base string, quote string, rate string, margin string, buyRate string, sellRate string, effectiveAt string) (change RateChange, err error) {
+	baseCurrency, err := currency.Get(base)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.NewRateChange: " + err.Error())
+	}
+	quoteCurrency, err := currency.Get(quote)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.NewRateChange: " + err.Error())
+	}
+	if baseCurrency.Code == quoteCurrency.Code {
+		return RateChange{}, errors.New("treasury.NewRateChange: Base and quote currencies must differ")
+	}
+	change.BaseCurrency = baseCurrency.Code
+	change.QuoteCurrency = quoteCurrency.Code
+
+	if buyRate != "" || sellRate != "" {
+		if rate != "" || margin != "" {
+			return RateChange{}, errors.New("treasury.NewRateChange: Give either a rate and margin or buy and sell rates")
+		}
+		buy, err := parseRate(buyRate)
+		if err != nil {
+			return RateChange{}, errors.New("treasury.NewRateChange: Buy rate " + err.Error())
+		}
+		sell, err := parseRate(sellRate)
+		if err != nil {
+			return RateChange{}, errors.New("treasury.NewRateChange: Sell rate " + err.Error())
+		}
+		if !buy.LessThanOrEqual(sell) {
+			return RateChange{}, errors.New("treasury.NewRateChange: Buy rate cannot be above the sell rate")
+		}
+		// The mid rate sits halfway and the margin is the distance to either side
+		change.Rate = buy.Add(sell).DivRound(decimal.NewFromInt(2), converter.CONVERSION_RATE_PLACES)
+		change.Margin = sell.Sub(buy).DivRound(sell.Add(buy), MARGIN_PLACES)
+	} else {
+		change.Rate, err = parseRate(rate)
+		if err != nil {
+			return RateChange{}, errors.New("treasury.NewRateChange: Rate " + err.Error())
+		}
+		change.Margin = decimal.NewFromFloat(fx.FX_SPREAD)
+		if margin != "" {
+			change.Margin, err = decimal.NewFromString(strings.TrimSpace(margin))
+			if err != nil {
+				return RateChange{}, errors.New("treasury.NewRateChange: Could not convert margin to decimal. " + err.Error())
+			}
+			change.Margin = change.Margin.Round(MARGIN_PLACES)
+		}
+	}
+	if change.Margin.IsNegative() || change.Margin.GreaterThanOrEqual(decimal.NewFromFloat(MAX_MARGIN)) {
+		return RateChange{}, errors.New("treasury.NewRateChange: Margin must be at least 0 and below " + strconv.FormatFloat(MAX_MARGIN, 'f', -1, 64))
+	}
+	change.BuyRate, change.SellRate = converter.BuySellRates(change.Rate, change.Margin)
+
+	change.EffectiveAt = time.Now().UTC().Truncate(time.Second)
+	if effectiveAt != "" {
+		change.EffectiveAt, err = time.Parse(EFFECTIVE_AT_LAYOUT, strings.TrimSpace(effectiveAt))
+		if err != nil {
+			return RateChange{}, errors.New("treasury.NewRateChange: Effective time must be YYYY-MM-DDTHH:MM. " + err.Error())
+		}
+	}
+
+	return change, nil
+}
+
+// ProposeRateChange records a change for a different member of staff to approve
+func ProposeRateChange(maker string, change RateChange) (RateChange, error) {
+	if maker == "" {
+		return RateChange{}, errors.New("treasury.ProposeRateChange: Maker must be known")
+	}
+	change.Maker = maker
+	change.Status = ChangePending
+	change.Timestamp = time.Now().UTC().Truncate(time.Second)
+
+	id, err := saveRateChange(change)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.ProposeRateChange: " + err.Error())
+	}
+	change.ID = id
+
+	err = recordAudit(maker, auditActionProposed, change)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.ProposeRateChange: " + err.Error())
+	}
+
+	return change, nil
+}
+
+// ApproveRateChange approves a pending change and applies it if it is already effective.
+// The checker cannot be the maker.
+func ApproveRateChange(checker string, id int64, note string) (change RateChange, err error) {
+	change, err = getRateChange(id)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.ApproveRateChange: " + err.Error())
+	}
+	if change.Status != ChangePending {
+		return RateChange{}, errors.New("treasury.ApproveRateChange: Change is " + change.Status)
+	}
+	if checker == "" || checker == change.Maker {
+		return RateChange{}, errors.New("treasury.ApproveRateChange: A change must be approved by someone other than its maker")
+	}
+
+	err = reviewRateChange(id, ChangePending, ChangeApproved, checker, note)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.ApproveRateChange: " + err.Error())
+	}
+	change.Status = ChangeApproved
+	change.Checker = checker
+	change.ReviewNote = note
+
+	err = recordAudit(checker, auditActionApproved, change)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.ApproveRateChange: " + err.Error())
+	}
+
+	if !change.EffectiveAt.After(time.Now().UTC()) {
+		change, err = applyRateChange(change)
+		if err != nil {
+			return RateChange{}, errors.New("treasury.ApproveRateChange: " + err.Error())
+		}
+	}
+
+	return change, nil
+}
+
+// RejectRateChange rejects a pending change. The checker cannot be the maker, who cancels instead.
+func RejectRateChange(checker string, id int64, note string) (change RateChange, err error) {
+	change, err = getRateChange(id)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.RejectRateChange: " + err.Error())
+	}
+	if change.Status != ChangePending {
+		return RateChange{}, errors.New("treasury.RejectRateChange: Change is " + change.Status)
+	}
+	if checker == "" || checker == change.Maker {
+		return RateChange{}, errors.New("treasury.RejectRateChange: A change must be rejected by someone other than its maker")
+	}
+
+	err = reviewRateChange(id, ChangePending, ChangeRejected, checker, note)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.RejectRateChange: " + err.Error())
+	}
+	change.Status = ChangeRejected
+	change.Checker = checker
+	change.ReviewNote = note
+
+	err = recordAudit(checker, auditActionRejected, change)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.RejectRateChange: " + err.Error())
+	}
+
+	return change, nil
+}
+
+// CancelRateChange withdraws a pending change. Only its maker can cancel it.
+func CancelRateChange(maker string, id int64) (change RateChange, err error) {
+	change, err = getRateChange(id)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.CancelRateChange: " + err.Error())
+	}
+	if change.Maker != maker {
+		return RateChange{}, errors.New("treasury.CancelRateChange: Only the maker can cancel a change")
+	}
+	if change.Status != ChangePending {
+		return RateChange{}, errors.New("treasury.CancelRateChange: Change is " + change.Status)
+	}
+
+	err = reviewRateChange(id, ChangePending, ChangeCancelled, "", "")
+	if err != nil {
+		return RateChange{}, errors.New("treasury.CancelRateChange: " + err.Error())
+	}
+	change.Status = ChangeCancelled
+
+	err = recordAudit(maker, auditActionCancelled, change)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.CancelRateChange: " + err.Error())
+	}
+
+	return change, nil
+}
+
+// ApplyDueRateChanges applies approved changes whose effective time has passed. It is run by the background jobs.
+func ApplyDueRateChanges() (applied int, err error) {
+	changes, err := getDueRateChanges(time.Now().UTC())
+	if err != nil {
+		return 0, errors.New("treasury.ApplyDueRateChanges: " + err.Error())
+	}
+
+	failures := make([]string, 0)
+	for _, change := range changes {
+		_, err := applyRateChange(change)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		applied++
+	}
+	if len(failures) > 0 {
+		return applied, errors.New("treasury.ApplyDueRateChanges: " + strings.Join(failures, "; "))
+	}
+
+	return applied, nil
+}
+
+// RateChanges lists changes in a status, or every change when status is empty, newest first
+func RateChanges(status string) (changes []RateChange, err error) {
+	changes, err = getRateChanges(status)
+	if err != nil {
+		return nil, errors.New("treasury.RateChanges: " + err.Error())
+	}
+
+	return changes, nil
+}
+
+// CurrentRates returns the manual rates and margins in force
+func CurrentRates() (rates []converter.ManualRate, err error) {
+	rates, err = converter.ManualRateProvider{Db: Config.Db}.AllRates()
+	if err != nil {
+		return nil, errors.New("treasury.CurrentRates: " + err.Error())
+	}
+
+	return rates, nil
+}
+
+// FXTransactions returns the FX transactions executed between start and end with the rate each one used
+func FXTransactions(start time.Time, end time.Time) (quotes []fx.Quote, err error) {
+	quotes, err = fx.AcceptedQuotes(start, end)
+	if err != nil {
+		return nil, errors.New("treasury.FXTransactions: " + err.Error())
+	}
+
+	return quotes, nil
+}
+
+// AuditTrail returns the audit entries for a change, or for every change when id is 0
+func AuditTrail(id int64) (entries []audit.Entry, err error) {
+	entityID := ""
+	if id != 0 {
+		entityID = strconv.FormatInt(id, 10)
+	}
+	entries, err = audit.Entries(auditEntityRate, entityID)
+	if err != nil {
+		return nil, errors.New("treasury.AuditTrail: " + err.Error())
+	}
+
+	return entries, nil
+}
+
+// applyRateChange writes an approved change to the manual rate table.
+// The change is claimed first so it is only applied once.
+func applyRateChange(change RateChange) (RateChange, error) {
+	err := markRateChangeApplied(change.ID)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.applyRateChange: " + err.Error())
+	}
+
+	err = converter.ManualRateProvider{Db: Config.Db}.SetRate(converter.ManualRate{
+		BaseCurrency:  change.BaseCurrency,
+		QuoteCurrency: change.QuoteCurrency,
+		Rate:          change.Rate,
+		Margin:        decimal.NewNullDecimal(change.Margin),
+		SetBy:         change.Checker,
+	})
+	if err != nil {
+		// Leave it approved so the next run tries again
+		if revertErr := revertRateChangeApplied(change.ID); revertErr != nil {
+			return RateChange{}, errors.New("treasury.applyRateChange: " + err.Error() + ". " + revertErr.Error())
+		}
+		return RateChange{}, errors.New("treasury.applyRateChange: " + err.Error())
+	}
+	appliedAt := time.Now().UTC().Truncate(time.Second)
+	change.Status = ChangeApplied
+	change.AppliedAt = &appliedAt
+
+	// Cross rates through either currency are cached too
+	bases := make([]string, 0)
+	for _, c := range currency.All() {
+		bases = append(bases, c.Code)
+	}
+	err = converter.ClearCachedRates(bases)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.applyRateChange: " + err.Error())
+	}
+
+	err = recordAudit(systemActor, auditActionApplied, change)
+	if err != nil {
+		return RateChange{}, errors.New("treasury.applyRateChange: " + err.Error())
+	}
+
+	return change, nil
+}
+
+func recordAudit(actor string, action string, change RateChange) error {
+	details, err := json.Marshal(change)
+	if err != nil {
+		return errors.New("treasury.recordAudit: " + err.Error())
+	}
+	err = audit.Record(actor, action, auditEntityRate, strconv.FormatInt(change.ID, 10), string(details))
+	if err != nil {
+		return errors.New("treasury.recordAudit: " + err.Error())
+	}
+	return nil
+}
+
+func parseRate(rate string) (decimal.Decimal, error) {
+	if rate == "" {
+		return decimal.Zero, errors.New("must be provided")
+	}
+	rateDecimal, err := decimal.NewFromString(strings.TrimSpace(rate))
+	if err != nil {
+		return decimal.Zero, errors.New("could not be converted to decimal. " + err.Error())
+	}
+	if !rateDecimal.IsPositive() {
+		return decimal.Zero, errors.New("must be greater than zero")
+	}
+	return rateDecimal.Round(converter.CONVERSION_RATE_PLACES), nil
+}
@@ -0,0 +1,60 @@
+package treasury
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNewRateChangeFromRate(t *testing.T) {
+	change, err := NewRateChange("gbp", "ngn", "2000", "0.01", "", "", "2030-01-02T09:30")
+	if err != nil {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", nil, err)
+	}
+	if change.BaseCurrency != "GBP" || change.QuoteCurrency != "NGN" {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", "GBP/NGN", change.BaseCurrency+"/"+change.QuoteCurrency)
+	}
+	if !change.BuyRate.Equal(decimal.NewFromInt(1980)) || !change.SellRate.Equal(decimal.NewFromInt(2020)) {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", "1980/2020", change.BuyRate.String()+"/"+change.SellRate.String())
+	}
+	expected := time.Date(2030, 1, 2, 9, 30, 0, 0, time.UTC)
+	if !change.EffectiveAt.Equal(expected) {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", expected, change.EffectiveAt)
+	}
+}
+
+func TestNewRateChangeFromBuySell(t *testing.T) {
+	change, err := NewRateChange("GBP", "NGN", "", "", "1980", "2020", "")
+	if err != nil {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", nil, err)
+	}
+	if !change.Rate.Equal(decimal.NewFromInt(2000)) {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", 2000, change.Rate)
+	}
+	if !change.Margin.Equal(decimal.RequireFromString("0.01")) {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", "0.01", change.Margin)
+	}
+	if time.Since(change.EffectiveAt) > time.Minute {
+		t.Errorf("NewRateChange does not pass. Looking for %v, got %v", "now", change.EffectiveAt)
+	}
+}
+
+func TestNewRateChangeInvalid(t *testing.T) {
+	cases := [][]string{
+		{"GBP", "GBP", "1", "0.01", "", "", ""},
+		{"GBP", "XYZ", "1", "0.01", "", "", ""},
+		{"GBP", "NGN", "", "", "", "", ""},
+		{"GBP", "NGN", "-5", "0.01", "", "", ""},
+		{"GBP", "NGN", "2000", "0.5", "", "", ""},
+		{"GBP", "NGN", "2000", "", "1980", "2020", ""},
+		{"GBP", "NGN", "", "", "2020", "1980", ""},
+		{"GBP", "NGN", "2000", "0.01", "", "", "tomorrow"},
+	}
+	for _, c := range cases {
+		_, err := NewRateChange(c[0], c[1], c[2], c[3], c[4], c[5], c[6])
+		if err == nil {
+			t.Errorf("NewRateChange does not pass. Looking for %v, got %v for %v", "error", err, c)
+		}
+	}
+}
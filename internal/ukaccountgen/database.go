@@ -0,0 +1,32 @@
+package ukaccountgen
+
+import (
+	"errors"
+)
+
+// nextSerial increments the sort code's sequence and returns the new value.
+// LAST_INSERT_ID(expr) hands the value back on the same statement, so concurrent callers never see the same serial.
+func nextSerial(sortCode string) (serial int64, err error) {
+	insertStatement := "INSERT INTO uk_account_sequences (`sortCode`, `lastSerial`) VALUES(?, LAST_INSERT_ID(1)) "
+	insertStatement += "ON DUPLICATE KEY UPDATE `lastSerial` = LAST_INSERT_ID(`lastSerial` + 1)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("ukaccountgen.nextSerial: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(sortCode)
+	if err != nil {
+		return 0, errors.New("ukaccountgen.nextSerial: " + err.Error())
+	}
+
+	serial, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("ukaccountgen.nextSerial: " + err.Error())
+	}
+	if serial > MAX_SERIAL {
+		return 0, errors.New("ukaccountgen.nextSerial: Serials exhausted for sort code " + sortCode)
+	}
+
+	return serial, nil
+}
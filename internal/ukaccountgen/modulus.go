@@ -0,0 +1,398 @@
+package ukaccountgen
+
+/*
+UK modulus checking
+
+Sort code and account number pairs are checked against the Vocalink weight table
+(valacdos.txt). Each row covers a range of sort codes and gives the method, one weight
+per digit of the sort code and account number (u v w x y z a b c d e f g h), and an
+optional exception code:
+
+	089000 089999 MOD10 0 0 0 0 0 0 7 1 3 7 1 3 7 1
+	202959 202959 DBLAL 2 1 2 1 2 1 2 1 2 1 2 1 2 1 1
+
+MOD10 and MOD11 sum the weighted digits and pass when the total divides by 10 or 11.
+DBLAL sums the digits of each weighted digit and passes when the total divides by 10.
+Sort codes without a row can't be checked and are treated as valid. Where a sort code
+has two rows both must pass, except for the exception pairs that allow either.
+
+The table is read from UK_MODULUS_WEIGHTS_FILE and exception 5's sort code
+substitutions (scsubtab.txt) from UK_MODULUS_SUBSTITUTES_FILE. Vocalink publish both
+files, keep them up to date; valacdos.sample.txt is only an extract for development.
+*/
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	MethodMOD10 = "MOD10"
+	MethodMOD11 = "MOD11"
+	MethodDBLAL = "DBLAL"
+
+	SORT_CODE_LENGTH      = 6
+	ACCOUNT_NUMBER_LENGTH = 8
+	WEIGHTS_FILE_ENV      = "UK_MODULUS_WEIGHTS_FILE"
+	SUBSTITUTES_FILE_ENV  = "UK_MODULUS_SUBSTITUTES_FILE"
+)
+
+// Positions of the digits in the sort code and account number
+const (
+	posA = 6
+	posB = 7
+	posC = 8
+	posG = 12
+	posH = 13
+)
+
+type WeightRow struct {
+	Start     string
+	End       string
+	Method    string
+	Weights   [14]int
+	Exception int
+}
+
+type WeightTable struct {
+	Rows []WeightRow
+	// Substitutes maps sort codes to the sort code used in their place for exception 5
+	Substitutes map[string]string
+}
+
+var (
+	weightTable      *WeightTable
+	weightTableMutex sync.Mutex
+)
+
+// SetWeightTable replaces the table loaded from UK_MODULUS_WEIGHTS_FILE
+func SetWeightTable(table WeightTable) {
+	weightTableMutex.Lock()
+	defer weightTableMutex.Unlock()
+	weightTable = &table
+}
+
+// ValidateUKAccount checks a sort code and account number against the configured weight table
+func ValidateUKAccount(sortCode string, accountNumber string) error {
+	table, err := configuredWeightTable()
+	if err != nil {
+		return errors.New("ukaccountgen.ValidateUKAccount: " + err.Error())
+	}
+
+	return table.Validate(sortCode, accountNumber)
+}
+
+func configuredWeightTable() (WeightTable, error) {
+	weightTableMutex.Lock()
+	defer weightTableMutex.Unlock()
+
+	if weightTable == nil {
+		table, err := LoadWeightTable(os.Getenv(WEIGHTS_FILE_ENV), os.Getenv(SUBSTITUTES_FILE_ENV))
+		if err != nil {
+			return WeightTable{}, err
+		}
+		weightTable = &table
+	}
+	return *weightTable, nil
+}
+
+// LoadWeightTable reads the weight table and, when a path is given, the exception 5 substitutions
+func LoadWeightTable(weightsPath string, substitutesPath string) (WeightTable, error) {
+	if weightsPath == "" {
+		return WeightTable{}, errors.New("ukaccountgen.LoadWeightTable: " + WEIGHTS_FILE_ENV + " is not configured")
+	}
+	file, err := os.Open(weightsPath)
+	if err != nil {
+		return WeightTable{}, errors.New("ukaccountgen.LoadWeightTable: " + err.Error())
+	}
+	defer file.Close()
+
+	table, err := ParseWeightTable(file)
+	if err != nil {
+		return WeightTable{}, errors.New("ukaccountgen.LoadWeightTable: " + err.Error())
+	}
+
+	if substitutesPath != "" {
+		substitutes, err := os.Open(substitutesPath)
+		if err != nil {
+			return WeightTable{}, errors.New("ukaccountgen.LoadWeightTable: " + err.Error())
+		}
+		defer substitutes.Close()
+
+		table.Substitutes, err = ParseSubstitutes(substitutes)
+		if err != nil {
+			return WeightTable{}, errors.New("ukaccountgen.LoadWeightTable: " + err.Error())
+		}
+	}
+
+	return table, nil
+}
+
+// ParseWeightTable reads rows in the valacdos.txt layout. Blank lines and lines starting with # are skipped.
+func ParseWeightTable(r io.Reader) (WeightTable, error) {
+	table := WeightTable{Rows: make([]WeightRow, 0)}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 17 && len(fields) != 18 {
+			return WeightTable{}, errors.New("ukaccountgen.ParseWeightTable: Line " + strconv.Itoa(line) + " has " + strconv.Itoa(len(fields)) + " fields")
+		}
+
+		row := WeightRow{Start: fields[0], End: fields[1], Method: strings.ToUpper(fields[2])}
+		if !isSortCode(row.Start) || !isSortCode(row.End) {
+			return WeightTable{}, errors.New("ukaccountgen.ParseWeightTable: Line " + strconv.Itoa(line) + " has an invalid sort code range")
+		}
+		if row.Method != MethodMOD10 && row.Method != MethodMOD11 && row.Method != MethodDBLAL {
+			return WeightTable{}, errors.New("ukaccountgen.ParseWeightTable: Line " + strconv.Itoa(line) + " has unknown method " + row.Method)
+		}
+		for i := range row.Weights {
+			weight, err := strconv.Atoi(fields[3+i])
+			if err != nil {
+				return WeightTable{}, errors.New("ukaccountgen.ParseWeightTable: Line " + strconv.Itoa(line) + ": " + err.Error())
+			}
+			row.Weights[i] = weight
+		}
+		if len(fields) == 18 {
+			exception, err := strconv.Atoi(fields[17])
+			if err != nil {
+				return WeightTable{}, errors.New("ukaccountgen.ParseWeightTable: Line " + strconv.Itoa(line) + ": " + err.Error())
+			}
+			row.Exception = exception
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return WeightTable{}, errors.New("ukaccountgen.ParseWeightTable: " + err.Error())
+	}
+
+	return table, nil
+}
+
+// ParseSubstitutes reads the scsubtab.txt layout, a sort code and its substitute per line
+func ParseSubstitutes(r io.Reader) (map[string]string, error) {
+	substitutes := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 || !isSortCode(fields[0]) || !isSortCode(fields[1]) {
+			return nil, errors.New("ukaccountgen.ParseSubstitutes: Invalid line " + scanner.Text())
+		}
+		substitutes[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("ukaccountgen.ParseSubstitutes: " + err.Error())
+	}
+
+	return substitutes, nil
+}
+
+// Validate checks a sort code and account number. Dashes and spaces are ignored and
+// 6 or 7 digit account numbers are padded with leading zeros.
+func (t WeightTable) Validate(sortCode string, accountNumber string) error {
+	sortCode, accountNumber, err := NormalizeUKAccount(sortCode, accountNumber)
+	if err != nil {
+		return errors.New("ukaccountgen.Validate: " + err.Error())
+	}
+
+	rows := t.rowsFor(sortCode)
+	digits := toDigits(sortCode + accountNumber)
+	valid := true
+	switch len(rows) {
+	case 0:
+		// Not covered by the table, the number can't be checked
+	case 1:
+		valid = t.check(rows[0], digits)
+	default:
+		first := t.check(rows[0], digits)
+		switch rows[0].Exception {
+		case 2, 10, 12:
+			// Paired with 9, 11 and 13, the account is valid if either check passes
+			valid = first || t.check(rows[1], digits)
+		default:
+			valid = first && t.check(rows[1], digits)
+		}
+	}
+	if !valid {
+		return errors.New("ukaccountgen.Validate: " + sortCode + " " + accountNumber + " fails modulus checking")
+	}
+
+	return nil
+}
+
+// NormalizeUKAccount strips separators and pads short account numbers
+func NormalizeUKAccount(sortCode string, accountNumber string) (string, string, error) {
+	sortCode = strings.NewReplacer("-", "", " ", "").Replace(sortCode)
+	accountNumber = strings.NewReplacer("-", "", " ", "").Replace(accountNumber)
+	if !isSortCode(sortCode) {
+		return "", "", errors.New("Sort code must be " + strconv.Itoa(SORT_CODE_LENGTH) + " digits")
+	}
+	if len(accountNumber) == 6 || len(accountNumber) == 7 {
+		accountNumber = strings.Repeat("0", ACCOUNT_NUMBER_LENGTH-len(accountNumber)) + accountNumber
+	}
+	if len(accountNumber) != ACCOUNT_NUMBER_LENGTH || !isDigits(accountNumber) {
+		return "", "", errors.New("Account number must be " + strconv.Itoa(ACCOUNT_NUMBER_LENGTH) + " digits")
+	}
+
+	return sortCode, accountNumber, nil
+}
+
+// rowsFor returns the rows covering the sort code, at most two
+func (t WeightTable) rowsFor(sortCode string) []WeightRow {
+	rows := make([]WeightRow, 0, 2)
+	for _, row := range t.Rows {
+		if sortCode >= row.Start && sortCode <= row.End {
+			rows = append(rows, row)
+			if len(rows) == 2 {
+				break
+			}
+		}
+	}
+	return rows
+}
+
+// check runs one row's check with its exception applied
+func (t WeightTable) check(row WeightRow, digits [14]int) bool {
+	weights := row.Weights
+
+	switch row.Exception {
+	case 2:
+		if digits[posA] != 0 {
+			if digits[posG] != 9 {
+				weights = [14]int{0, 0, 1, 2, 5, 3, 6, 4, 8, 7, 10, 9, 3, 1}
+			} else {
+				weights = [14]int{0, 0, 0, 0, 0, 0, 0, 0, 8, 7, 10, 9, 3, 1}
+			}
+		}
+	case 3:
+		if digits[posC] == 6 || digits[posC] == 9 {
+			return true
+		}
+	case 5:
+		if row.Method == MethodMOD11 {
+			if substitute, ok := t.Substitutes[sortCodeOf(digits)]; ok {
+				digits = withSortCode(digits, substitute)
+			}
+		}
+	case 6:
+		// Foreign currency accounts can't be checked
+		if digits[posA] >= 4 && digits[posA] <= 8 && digits[posG] == digits[posH] {
+			return true
+		}
+	case 7:
+		if digits[posG] == 9 {
+			zeroSortCodeWeights(&weights)
+		}
+	case 8:
+		digits = withSortCode(digits, "090126")
+	case 9:
+		digits = withSortCode(digits, "309634")
+	case 10:
+		if (digits[posA] == 0 || digits[posA] == 9) && digits[posB] == 9 && digits[posG] == 9 {
+			zeroSortCodeWeights(&weights)
+		}
+	}
+
+	total := weightedTotal(row.Method, weights, digits)
+
+	switch {
+	case row.Exception == 1 && row.Method == MethodDBLAL:
+		total += 27
+	case row.Exception == 4 && row.Method == MethodMOD11:
+		return total%11 == digits[posG]*10+digits[posH]
+	case row.Exception == 5 && row.Method == MethodMOD11:
+		remainder := total % 11
+		switch remainder {
+		case 0:
+			return digits[posG] == 0
+		case 1:
+			return false
+		}
+		return 11-remainder == digits[posG]
+	case row.Exception == 5 && row.Method == MethodDBLAL:
+		return (10-total%10)%10 == digits[posH]
+	}
+
+	if row.Method == MethodMOD11 {
+		if total%11 == 0 {
+			return true
+		}
+		// Exception 14, the last digit may be a suffix that's not part of the account number
+		if row.Exception == 14 && (digits[posH] == 0 || digits[posH] == 1 || digits[posH] == 9) {
+			shifted := digits
+			copy(shifted[posA+1:], digits[posA:posH])
+			shifted[posA] = 0
+			return weightedTotal(row.Method, weights, shifted)%11 == 0
+		}
+		return false
+	}
+
+	return total%10 == 0
+}
+
+func weightedTotal(method string, weights [14]int, digits [14]int) int {
+	total := 0
+	for i, digit := range digits {
+		product := digit * weights[i]
+		if method == MethodDBLAL {
+			product = product/10 + product%10
+		}
+		total += product
+	}
+	return total
+}
+
+func zeroSortCodeWeights(weights *[14]int) {
+	for i := 0; i <= posB; i++ {
+		weights[i] = 0
+	}
+}
+
+func withSortCode(digits [14]int, sortCode string) [14]int {
+	for i, digit := range toDigits(sortCode) {
+		if i < SORT_CODE_LENGTH {
+			digits[i] = digit
+		}
+	}
+	return digits
+}
+
+func sortCodeOf(digits [14]int) string {
+	var sortCode strings.Builder
+	for _, digit := range digits[:SORT_CODE_LENGTH] {
+		sortCode.WriteString(strconv.Itoa(digit))
+	}
+	return sortCode.String()
+}
+
+func toDigits(s string) (digits [14]int) {
+	for i := 0; i < len(s) && i < len(digits); i++ {
+		digits[i] = int(s[i] - '0')
+	}
+	return digits
+}
+
+func isSortCode(s string) bool {
+	return len(s) == SORT_CODE_LENGTH && isDigits(s)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
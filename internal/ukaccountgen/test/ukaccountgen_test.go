@@ -1,25 +1,107 @@
 package test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/ebitezion/backend-framework/internal/ukaccountgen"
 )
 
-func TestGenerateUKAccountNumber(t *testing.T) {
-	generator := ukaccountgen.New()
-	accountNumber := generator.GenerateUKAccountNumber()
+func loadTable(t *testing.T) ukaccountgen.WeightTable {
+	table, err := ukaccountgen.LoadWeightTable("../valacdos.sample.txt", "")
+	if err != nil {
+		t.Fatalf("LoadWeightTable does not pass. Looking for %v, got %v", nil, err)
+	}
+	return table
+}
+
+func TestValidateStandardChecks(t *testing.T) {
+	table := loadTable(t)
+
+	// Worked examples from the Vocalink specification for each method
+	valid := [][]string{
+		{"089999", "66374958"}, // MOD10
+		{"107999", "88837491"}, // MOD11
+		{"202959", "63748472"}, // DBLAL
+		{"20-29-59", "6374 8472"},
+		{"123456", "12345678"}, // not in the table, can't be checked
+	}
+	for _, c := range valid {
+		if err := table.Validate(c[0], c[1]); err != nil {
+			t.Errorf("Validate does not pass. Looking for %v, got %v for %v", nil, err, c)
+		}
+	}
+
+	invalid := [][]string{
+		{"089999", "66374959"},
+		{"107999", "88837490"},
+		{"202959", "63748473"},
+		{"20295", "63748472"},
+		{"202959", "6374847a"},
+	}
+	for _, c := range invalid {
+		if err := table.Validate(c[0], c[1]); err == nil {
+			t.Errorf("Validate does not pass. Looking for %v, got %v for %v", "error", err, c)
+		}
+	}
+}
+
+func TestValidateExceptions(t *testing.T) {
+	rows := strings.Join([]string{
+		// Exception 1 adds 27 to the DBLAL total
+		"111111 111111 DBLAL 0 0 0 0 0 0 0 0 0 0 0 0 2 1 1",
+		// Two rows that must both pass, MOD10 over h and MOD11 over g
+		"222222 222222 MOD10 0 0 0 0 0 0 0 0 0 0 0 0 0 1",
+		"222222 222222 MOD11 0 0 0 0 0 0 0 0 0 0 0 0 1 0",
+		// Exceptions 12 and 13 pass when either row does
+		"333333 333333 MOD10 0 0 0 0 0 0 0 0 0 0 0 0 0 1 12",
+		"333333 333333 MOD11 0 0 0 0 0 0 0 0 0 0 0 0 1 0 13",
+	}, "\n")
+	table, err := ukaccountgen.ParseWeightTable(strings.NewReader(rows))
+	if err != nil {
+		t.Fatalf("ParseWeightTable does not pass. Looking for %v, got %v", nil, err)
+	}
 
-	// Check if the generated account number is exactly 6 characters long
-	if len(accountNumber) != 6 {
-		t.Errorf("Generated account number is not 6 characters long. Got: %s", accountNumber)
+	// g=1, h=1: DBLAL total 2+1+27 = 30
+	if err := table.Validate("111111", "00000011"); err != nil {
+		t.Errorf("Validate does not pass. Looking for %v, got %v", nil, err)
+	}
+	if err := table.Validate("111111", "00000012"); err == nil {
+		t.Errorf("Validate does not pass. Looking for %v, got %v", "error", err)
 	}
 
-	// Check if the generated account number consists only of digits
-	for _, char := range accountNumber {
-		if char < '0' || char > '9' {
-			t.Errorf("Generated account number contains non-digit characters. Got: %s", accountNumber)
-			break
+	// g=0, h=0 passes both rows, g=1, h=0 only passes MOD10
+	for _, sortCode := range []string{"222222", "333333"} {
+		if err := table.Validate(sortCode, "00000000"); err != nil {
+			t.Errorf("Validate does not pass. Looking for %v, got %v for %v", nil, err, sortCode)
 		}
 	}
+	if err := table.Validate("222222", "00000010"); err == nil {
+		t.Errorf("Validate does not pass. Looking for %v, got %v", "error", err)
+	}
+	if err := table.Validate("333333", "00000010"); err != nil {
+		t.Errorf("Validate does not pass. Looking for %v, got %v", nil, err)
+	}
+}
+
+func TestFromSerial(t *testing.T) {
+	table := loadTable(t)
+
+	for serial := int64(1); serial < 500; serial++ {
+		accountNumber, err := table.FromSerial("040075", serial)
+		if err != nil {
+			// MOD11 serials needing a check digit of 10 are skipped
+			continue
+		}
+		if len(accountNumber) != ukaccountgen.ACCOUNT_NUMBER_LENGTH {
+			t.Errorf("FromSerial does not pass. Looking for %v digits, got %v", ukaccountgen.ACCOUNT_NUMBER_LENGTH, accountNumber)
+		}
+		if err := table.Validate("040075", accountNumber); err != nil {
+			t.Errorf("FromSerial does not pass. Looking for %v, got %v for %v", nil, err, accountNumber)
+		}
+	}
+
+	if _, err := table.FromSerial("040075", ukaccountgen.MAX_SERIAL+1); err == nil {
+		t.Errorf("FromSerial does not pass. Looking for %v, got %v", "error", err)
+	}
 }
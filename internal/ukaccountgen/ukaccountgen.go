@@ -1,28 +1,88 @@
 package ukaccountgen
 
+/*
+UK account issuance
+
+Account numbers are 8 digits issued under the sort code configured in UK_SORT_CODE. The
+first 7 digits are a serial taken from a per sort code sequence in uk_account_sequences,
+the last digit is chosen so the pair passes modulus checking. When no last digit passes
+(e.g. a MOD11 check digit of 10) the serial is skipped.
+*/
+
 import (
-	"crypto/rand"
-	"math/big"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
+)
+
+const (
+	SERIAL_LENGTH = ACCOUNT_NUMBER_LENGTH - 1
+	MAX_SERIAL    = 9999999
+	SORT_CODE_ENV = "UK_SORT_CODE"
 )
 
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
 type UKAccountGenerator struct {
+	SortCode string
 }
 
+// New returns a generator for the sort code configured in UK_SORT_CODE
 func New() *UKAccountGenerator {
-	return &UKAccountGenerator{}
+	return &UKAccountGenerator{SortCode: strings.TrimSpace(os.Getenv(SORT_CODE_ENV))}
 }
 
-func (u *UKAccountGenerator) generateRandomNumber(length int) string {
-	var result string
+// GenerateUKAccountNumber allocates the next account number under the generator's sort code
+func (u *UKAccountGenerator) GenerateUKAccountNumber() (string, error) {
+	if !isSortCode(u.SortCode) {
+		return "", errors.New("ukaccountgen.GenerateUKAccountNumber: " + SORT_CODE_ENV + " must be " + strconv.Itoa(SORT_CODE_LENGTH) + " digits")
+	}
+	table, err := configuredWeightTable()
+	if err != nil {
+		return "", errors.New("ukaccountgen.GenerateUKAccountNumber: " + err.Error())
+	}
+
+	for {
+		serial, err := nextSerial(u.SortCode)
+		if err != nil {
+			return "", errors.New("ukaccountgen.GenerateUKAccountNumber: " + err.Error())
+		}
 
-	for i := 0; i < length; i++ {
-		num, _ := rand.Int(rand.Reader, big.NewInt(10))
-		result += num.String()
+		accountNumber, err := table.FromSerial(u.SortCode, serial)
+		if err == errNoCheckDigit {
+			continue
+		}
+		if err != nil {
+			return "", errors.New("ukaccountgen.GenerateUKAccountNumber: " + err.Error())
+		}
+
+		return accountNumber, nil
 	}
-	return result
 }
 
-func (u *UKAccountGenerator) GenerateUKAccountNumber() string {
-	// Generating a random 6-digit account number
-	return u.generateRandomNumber(6)
+var errNoCheckDigit = errors.New("ukaccountgen.FromSerial: No check digit passes for this serial")
+
+// FromSerial returns the account number for a 7 digit serial under the sort code
+func (t WeightTable) FromSerial(sortCode string, serial int64) (string, error) {
+	if serial < 0 || serial > MAX_SERIAL {
+		return "", errors.New("ukaccountgen.FromSerial: Serial out of range")
+	}
+	serialDigits := strconv.FormatInt(serial, 10)
+	serialDigits = strings.Repeat("0", SERIAL_LENGTH-len(serialDigits)) + serialDigits
+
+	for checkDigit := 0; checkDigit <= 9; checkDigit++ {
+		accountNumber := serialDigits + strconv.Itoa(checkDigit)
+		if t.Validate(sortCode, accountNumber) == nil {
+			return accountNumber, nil
+		}
+	}
+
+	return "", errNoCheckDigit
 }
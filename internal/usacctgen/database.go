@@ -0,0 +1,32 @@
+package usacctgen
+
+import (
+	"errors"
+)
+
+// nextSerial increments the routing number's sequence and returns the new value.
+// LAST_INSERT_ID(expr) hands the value back on the same statement, so concurrent callers never see the same serial.
+func nextSerial(routingNumber string) (serial int64, err error) {
+	insertStatement := "INSERT INTO us_account_sequences (`routingNumber`, `lastSerial`) VALUES(?, LAST_INSERT_ID(1)) "
+	insertStatement += "ON DUPLICATE KEY UPDATE `lastSerial` = LAST_INSERT_ID(`lastSerial` + 1)"
+	stmtIns, err := Config.Db.Prepare(insertStatement)
+	if err != nil {
+		return 0, errors.New("usacctgen.nextSerial: " + err.Error())
+	}
+	defer stmtIns.Close()
+
+	res, err := stmtIns.Exec(routingNumber)
+	if err != nil {
+		return 0, errors.New("usacctgen.nextSerial: " + err.Error())
+	}
+
+	serial, err = res.LastInsertId()
+	if err != nil {
+		return 0, errors.New("usacctgen.nextSerial: " + err.Error())
+	}
+	if serial > MAX_SERIAL {
+		return 0, errors.New("usacctgen.nextSerial: Serials exhausted for routing number " + routingNumber)
+	}
+
+	return serial, nil
+}
@@ -1,37 +1,140 @@
 package usacctgen
 
+/*
+US account issuance
+
+US account details are a 9 digit ABA routing number and an account number. Accounts are
+issued under the routing number configured in US_ROUTING_NUMBER. Account numbers are a 9
+digit serial from a per routing number sequence in us_account_sequences followed by a Luhn
+check digit, so mistyped numbers are caught before a payment is posted.
+
+Routing numbers carry their own check digit, the weighted sum of the 9 digits with weights
+3, 7, 1 repeated must divide by 10.
+*/
+
 import (
-	"crypto/rand"
-	"math/big"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ebitezion/backend-framework/internal/configuration"
 )
 
+const (
+	ROUTING_NUMBER_LENGTH = 9
+	SERIAL_LENGTH         = 9
+	ACCOUNT_NUMBER_LENGTH = SERIAL_LENGTH + 1
+	MAX_SERIAL            = 999999999
+	ROUTING_NUMBER_ENV    = "US_ROUTING_NUMBER"
+)
+
+var routingNumberWeights = []int{3, 7, 1}
+
+var Config configuration.Configuration
+
+func SetConfig(config *configuration.Configuration) {
+	Config = *config
+}
+
+// USAccount is a routing number and account number pair
+type USAccount struct {
+	RoutingNumber string `json:"routingNumber"`
+	AccountNumber string `json:"accountNumber"`
+}
+
 type USAccountGenerator struct {
+	RoutingNumber string
 }
 
+// NewUSAccountGenerator returns a generator for the routing number configured in US_ROUTING_NUMBER
 func NewUSAccountGenerator() *USAccountGenerator {
-	return &USAccountGenerator{}
+	return &USAccountGenerator{RoutingNumber: strings.TrimSpace(os.Getenv(ROUTING_NUMBER_ENV))}
+}
+
+// GenerateUSAccountNumber allocates the next account number under the generator's routing number
+func (u *USAccountGenerator) GenerateUSAccountNumber() (USAccount, error) {
+	err := ValidateRoutingNumber(u.RoutingNumber)
+	if err != nil {
+		return USAccount{}, errors.New("usacctgen.GenerateUSAccountNumber: " + ROUTING_NUMBER_ENV + " " + err.Error())
+	}
+
+	serial, err := nextSerial(u.RoutingNumber)
+	if err != nil {
+		return USAccount{}, errors.New("usacctgen.GenerateUSAccountNumber: " + err.Error())
+	}
+
+	accountNumber, err := FromSerial(serial)
+	if err != nil {
+		return USAccount{}, errors.New("usacctgen.GenerateUSAccountNumber: " + err.Error())
+	}
+
+	return USAccount{RoutingNumber: u.RoutingNumber, AccountNumber: accountNumber}, nil
 }
 
-func (u *USAccountGenerator) generateRandomNumber(length int) string {
-	var result string
+// FromSerial returns the account number for a serial, the serial followed by its Luhn check digit
+func FromSerial(serial int64) (string, error) {
+	if serial < 0 || serial > MAX_SERIAL {
+		return "", errors.New("usacctgen.FromSerial: Serial out of range")
+	}
+	serialDigits := strconv.FormatInt(serial, 10)
+	serialDigits = strings.Repeat("0", SERIAL_LENGTH-len(serialDigits)) + serialDigits
 
-	for i := 0; i < length; i++ {
-		num, _ := rand.Int(rand.Reader, big.NewInt(10))
-		result += num.String()
+	return serialDigits + strconv.Itoa(luhnCheckDigit(serialDigits)), nil
+}
+
+// ValidateAccountNumber checks an account number issued by FromSerial
+func ValidateAccountNumber(accountNumber string) error {
+	if len(accountNumber) != ACCOUNT_NUMBER_LENGTH || !isDigits(accountNumber) {
+		return errors.New("usacctgen.ValidateAccountNumber: Account number must be " + strconv.Itoa(ACCOUNT_NUMBER_LENGTH) + " digits")
+	}
+	if luhnCheckDigit(accountNumber[:SERIAL_LENGTH]) != int(accountNumber[SERIAL_LENGTH]-'0') {
+		return errors.New("usacctgen.ValidateAccountNumber: Invalid check digit")
 	}
 
-	return result
+	return nil
 }
 
-func (u *USAccountGenerator) GenerateUSAccountNumber() string {
-	//routingNumber := u.generateRandomNumber(9)  // Example: 9-digit routing number
-	accountNumber := u.generateRandomNumber(10) // Example: 10-digit account number
-	return accountNumber                        //routingNumber + accountNumber
+// ValidateRoutingNumber checks the ABA routing number checksum
+func ValidateRoutingNumber(routingNumber string) error {
+	if len(routingNumber) != ROUTING_NUMBER_LENGTH || !isDigits(routingNumber) {
+		return errors.New("usacctgen.ValidateRoutingNumber: Routing number must be " + strconv.Itoa(ROUTING_NUMBER_LENGTH) + " digits")
+	}
+
+	sum := 0
+	for i, digit := range routingNumber {
+		sum += int(digit-'0') * routingNumberWeights[i%len(routingNumberWeights)]
+	}
+	if sum%10 != 0 {
+		return errors.New("usacctgen.ValidateRoutingNumber: Invalid routing number checksum")
+	}
+
+	return nil
+}
+
+// luhnCheckDigit doubles every second digit from the right, the check digit brings the total to a multiple of 10
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return (10 - sum%10) % 10
 }
 
-//Test
-// func main() {
-// 	usAccountGenerator := NewUSAccountGenerator()
-// 	usAccount := usAccountGenerator.GenerateUSAccountNumber()
-// 	fmt.Println("Generated US Account Number:", usAccount)
-// }
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
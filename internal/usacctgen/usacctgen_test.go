@@ -0,0 +1,45 @@
+package usacctgen
+
+import (
+	"testing"
+)
+
+func TestValidateRoutingNumber(t *testing.T) {
+	// Published routing numbers
+	for _, routingNumber := range []string{"011000015", "021000021", "026009593", "111000025"} {
+		if err := ValidateRoutingNumber(routingNumber); err != nil {
+			t.Errorf("ValidateRoutingNumber does not pass. Looking for %v, got %v for %v", nil, err, routingNumber)
+		}
+	}
+
+	for _, routingNumber := range []string{"021000022", "02100002", "0210000210", "02100002a"} {
+		if err := ValidateRoutingNumber(routingNumber); err == nil {
+			t.Errorf("ValidateRoutingNumber does not pass. Looking for %v, got %v for %v", "error", err, routingNumber)
+		}
+	}
+}
+
+func TestFromSerial(t *testing.T) {
+	// 000000001: 1 doubled is 2, the check digit brings it to 10
+	accountNumber, err := FromSerial(1)
+	if err != nil || accountNumber != "0000000018" {
+		t.Errorf("FromSerial does not pass. Looking for %v, got %v (%v)", "0000000018", accountNumber, err)
+	}
+
+	for serial := int64(1); serial < 1000; serial += 13 {
+		accountNumber, err := FromSerial(serial)
+		if err != nil {
+			t.Errorf("FromSerial does not pass. Looking for %v, got %v", nil, err)
+		}
+		if err := ValidateAccountNumber(accountNumber); err != nil {
+			t.Errorf("ValidateAccountNumber does not pass. Looking for %v, got %v for %v", nil, err, accountNumber)
+		}
+	}
+
+	if err := ValidateAccountNumber("0000000017"); err == nil {
+		t.Errorf("ValidateAccountNumber does not pass. Looking for %v, got %v", "error", err)
+	}
+	if _, err := FromSerial(MAX_SERIAL + 1); err == nil {
+		t.Errorf("FromSerial does not pass. Looking for %v, got %v", "error", err)
+	}
+}
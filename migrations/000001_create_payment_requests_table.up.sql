@@ -0,0 +1,18 @@
+--
+-- Table structure for table `payment_requests`
+--
+
+CREATE TABLE IF NOT EXISTS `payment_requests` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `requesterAccountNumber` char(36) NOT NULL,
+  `payerAccountNumber` char(36) NOT NULL,
+  `amount` decimal(20,2) NOT NULL,
+  `reason` text NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'pending',
+  `expiresAt` datetime NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `payment_requests_payer_status` (`payerAccountNumber`, `status`),
+  KEY `payment_requests_requester` (`requesterAccountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
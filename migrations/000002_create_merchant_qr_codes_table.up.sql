@@ -0,0 +1,20 @@
+--
+-- Table structure for table `merchant_qr_codes`
+--
+
+CREATE TABLE IF NOT EXISTS `merchant_qr_codes` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `reference` varchar(32) NOT NULL,
+  `merchantAccountNumber` char(36) NOT NULL,
+  `merchantName` varchar(25) NOT NULL,
+  `type` varchar(10) NOT NULL,
+  `amount` decimal(20,2) DEFAULT NULL,
+  `currency` char(3) NOT NULL DEFAULT 'NGN',
+  `payload` text NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'active',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `merchant_qr_codes_reference` (`reference`),
+  KEY `merchant_qr_codes_merchant` (`merchantAccountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
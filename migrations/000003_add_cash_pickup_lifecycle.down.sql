@@ -0,0 +1,16 @@
+ALTER TABLE `cash_pickup`
+  DROP KEY `cash_pickup_sender`,
+  DROP KEY `cash_pickup_status_expires`,
+  DROP KEY `cash_pickup_code`,
+  DROP `redeemedAt`,
+  DROP `redeemedBy`,
+  DROP `expiresAt`,
+  DROP `pinAttempts`,
+  DROP `pinHash`,
+  DROP `pickupCode`,
+  MODIFY `charge` float NOT NULL,
+  MODIFY `amount` float NOT NULL,
+  MODIFY `currency` text NOT NULL,
+  MODIFY `status` text NOT NULL,
+  MODIFY `id` int(11) NOT NULL,
+  DROP PRIMARY KEY;
@@ -0,0 +1,20 @@
+--
+-- Cash pickup lifecycle: one-time code, hashed PIN, expiry and redemption details
+--
+
+ALTER TABLE `cash_pickup`
+  MODIFY `id` int(11) NOT NULL AUTO_INCREMENT,
+  ADD PRIMARY KEY (`id`),
+  MODIFY `status` varchar(20) NOT NULL DEFAULT 'pending',
+  MODIFY `currency` char(3) NOT NULL DEFAULT 'NGN',
+  MODIFY `amount` decimal(20,2) NOT NULL,
+  MODIFY `charge` decimal(20,2) NOT NULL DEFAULT 0.00,
+  ADD `pickupCode` varchar(10) NOT NULL AFTER `id`,
+  ADD `pinHash` char(128) NOT NULL AFTER `pickupCode`,
+  ADD `pinAttempts` int(11) NOT NULL DEFAULT 0 AFTER `pinHash`,
+  ADD `expiresAt` datetime NOT NULL AFTER `nin`,
+  ADD `redeemedBy` char(36) DEFAULT NULL AFTER `expiresAt`,
+  ADD `redeemedAt` datetime DEFAULT NULL AFTER `redeemedBy`,
+  ADD UNIQUE KEY `cash_pickup_code` (`pickupCode`),
+  ADD KEY `cash_pickup_status_expires` (`status`, `expiresAt`),
+  ADD KEY `cash_pickup_sender` (`sendersAccountNumber`);
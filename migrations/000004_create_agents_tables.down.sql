@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `agent_float_topups`;
+DROP TABLE IF EXISTS `agent_transactions`;
+DROP TABLE IF EXISTS `agent_commission_rules`;
+DROP TABLE IF EXISTS `agent_locations`;
+DROP TABLE IF EXISTS `agents`;
@@ -0,0 +1,95 @@
+--
+-- Table structure for table `agents`
+--
+
+CREATE TABLE IF NOT EXISTS `agents` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `agentCode` varchar(10) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `ownerAccountNumber` char(36) NOT NULL,
+  `floatAccountNumber` char(36) NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'active',
+  `cashInDailyLimit` decimal(20,2) NOT NULL,
+  `cashOutDailyLimit` decimal(20,2) NOT NULL,
+  `creator` text NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `agents_code` (`agentCode`),
+  UNIQUE KEY `agents_owner` (`ownerAccountNumber`),
+  UNIQUE KEY `agents_float` (`floatAccountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `agent_locations`
+--
+
+CREATE TABLE IF NOT EXISTS `agent_locations` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `agentId` int(11) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `address` text NOT NULL,
+  `city` varchar(100) NOT NULL,
+  `state` varchar(100) NOT NULL,
+  `latitude` decimal(10,7) DEFAULT NULL,
+  `longitude` decimal(10,7) DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `agent_locations_agent` (`agentId`),
+  KEY `agent_locations_city` (`city`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `agent_commission_rules`
+-- Rules with a NULL agentId are the defaults for every agent
+--
+
+CREATE TABLE IF NOT EXISTS `agent_commission_rules` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `agentId` int(11) DEFAULT NULL,
+  `operation` varchar(20) NOT NULL,
+  `percentage` decimal(7,4) NOT NULL DEFAULT 0.0000,
+  `flatAmount` decimal(20,2) NOT NULL DEFAULT 0.00,
+  `maxAmount` decimal(20,2) NOT NULL DEFAULT 0.00,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `agent_commission_rules_agent_operation` (`agentId`, `operation`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `agent_transactions`
+--
+
+CREATE TABLE IF NOT EXISTS `agent_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `agentId` int(11) NOT NULL,
+  `locationId` int(11) DEFAULT NULL,
+  `operation` varchar(20) NOT NULL,
+  `customerAccountNumber` char(36) NOT NULL,
+  `amount` decimal(20,2) NOT NULL,
+  `commission` decimal(20,2) NOT NULL DEFAULT 0.00,
+  `reference` varchar(64) NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `agent_transactions_agent_date` (`agentId`, `timestamp`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `agent_float_topups`
+--
+
+CREATE TABLE IF NOT EXISTS `agent_float_topups` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `agentId` int(11) NOT NULL,
+  `amount` decimal(20,2) NOT NULL,
+  `reference` varchar(64) NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'pending',
+  `reviewer` text DEFAULT NULL,
+  `reviewNote` text DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `agent_float_topups_agent_status` (`agentId`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
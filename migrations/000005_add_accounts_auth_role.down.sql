@@ -0,0 +1,2 @@
+ALTER TABLE `accounts_auth`
+  DROP `role`;
@@ -0,0 +1,7 @@
+--
+-- Staff roles: back-office actions are checked against the role of the user holding the token.
+-- Everyone is a customer until they are given a staff role (admin, operations, credit or treasury).
+--
+
+ALTER TABLE `accounts_auth`
+  ADD `role` varchar(20) NOT NULL DEFAULT 'customer';
@@ -0,0 +1,20 @@
+DELETE FROM `bank_account` WHERE `currencyCode` <> 'NGN';
+
+ALTER TABLE `bank_account`
+  DROP KEY `bank_account_currency`,
+  DROP `currencyCode`,
+  MODIFY `balance` float NOT NULL;
+
+ALTER TABLE `transactions`
+  DROP `currencyCode`,
+  MODIFY `transactionAmount` float NOT NULL,
+  MODIFY `feeAmount` float NOT NULL;
+
+ALTER TABLE `accounts`
+  DROP KEY `accounts_account_number`,
+  DROP KEY `accounts_primary_account_number`,
+  DROP `currencyCode`,
+  DROP `primaryAccountNumber`,
+  MODIFY `accountBalance` float NOT NULL,
+  MODIFY `overdraft` float NOT NULL,
+  MODIFY `availableBalance` float NOT NULL;
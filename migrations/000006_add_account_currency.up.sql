@@ -0,0 +1,28 @@
+--
+-- Multi-currency accounts: every account, transaction and fee holding balance carries a currency.
+-- Amounts move from float to decimal so balances keep exact minor units.
+--
+
+ALTER TABLE `accounts`
+  ADD `currencyCode` char(3) NOT NULL DEFAULT 'NGN' AFTER `accountHolderName`,
+  ADD `primaryAccountNumber` char(36) DEFAULT NULL AFTER `currencyCode`,
+  MODIFY `accountBalance` decimal(20,4) NOT NULL,
+  MODIFY `overdraft` decimal(20,4) NOT NULL,
+  MODIFY `availableBalance` decimal(20,4) NOT NULL,
+  ADD KEY `accounts_account_number` (`accountNumber`),
+  ADD KEY `accounts_primary_account_number` (`primaryAccountNumber`);
+
+ALTER TABLE `transactions`
+  ADD `currencyCode` char(3) NOT NULL DEFAULT 'NGN' AFTER `receiverBankNumber`,
+  MODIFY `transactionAmount` decimal(20,4) NOT NULL,
+  MODIFY `feeAmount` decimal(20,4) NOT NULL;
+
+ALTER TABLE `bank_account`
+  ADD `currencyCode` char(3) NOT NULL DEFAULT 'NGN' AFTER `id`,
+  MODIFY `balance` decimal(20,4) NOT NULL,
+  ADD UNIQUE KEY `bank_account_currency` (`currencyCode`);
+
+INSERT INTO `bank_account` (`currencyCode`, `balance`, `timestamp`) VALUES
+('GBP', 0, UNIX_TIMESTAMP()),
+('EUR', 0, UNIX_TIMESTAMP()),
+('USD', 0, UNIX_TIMESTAMP());
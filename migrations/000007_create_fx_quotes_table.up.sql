@@ -0,0 +1,30 @@
+--
+-- Table structure for table `fx_quotes`
+-- A quote locks the rate between two of a customer's accounts until it expires.
+-- Accepted quotes record both legs posted through the FX position accounts.
+--
+
+CREATE TABLE IF NOT EXISTS `fx_quotes` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `reference` varchar(20) NOT NULL,
+  `sourceAccountNumber` char(36) NOT NULL,
+  `targetAccountNumber` char(36) NOT NULL,
+  `sellCurrency` char(3) NOT NULL,
+  `buyCurrency` char(3) NOT NULL,
+  `sellAmount` decimal(20,4) NOT NULL,
+  `buyAmount` decimal(20,4) NOT NULL,
+  `midRate` decimal(20,10) NOT NULL,
+  `rate` decimal(20,10) NOT NULL,
+  `spread` decimal(7,4) NOT NULL,
+  `spreadRevenue` decimal(20,4) NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'open',
+  `initiator` char(36) NOT NULL,
+  `expiresAt` datetime NOT NULL,
+  `acceptedAt` datetime DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `fx_quotes_reference` (`reference`),
+  KEY `fx_quotes_source` (`sourceAccountNumber`),
+  KEY `fx_quotes_status_expires` (`status`, `expiresAt`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
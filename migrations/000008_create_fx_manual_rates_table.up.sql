@@ -0,0 +1,16 @@
+--
+-- Table structure for table `fx_manual_rates`
+-- Treasury-managed rates used by the manual rate provider, one row per currency pair
+--
+
+CREATE TABLE IF NOT EXISTS `fx_manual_rates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `baseCurrency` char(3) NOT NULL,
+  `quoteCurrency` char(3) NOT NULL,
+  `rate` decimal(20,10) NOT NULL,
+  `setBy` text NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `fx_manual_rates_pair` (`baseCurrency`, `quoteCurrency`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `fx_rate_snapshot_rates`;
+DROP TABLE IF EXISTS `fx_rate_snapshots`;
@@ -0,0 +1,29 @@
+--
+-- Table structure for table `fx_rate_snapshots`
+-- One row for every set of rates fetched from a rate provider
+--
+
+CREATE TABLE IF NOT EXISTS `fx_rate_snapshots` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `provider` varchar(100) NOT NULL,
+  `baseCurrency` char(3) NOT NULL,
+  `rateDate` date NOT NULL,
+  `fetchedAt` datetime NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `fx_rate_snapshots_date` (`rateDate`, `fetchedAt`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `fx_rate_snapshot_rates`
+-- The rate for one unit of the snapshot's base currency in each currency
+--
+
+CREATE TABLE IF NOT EXISTS `fx_rate_snapshot_rates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `snapshotId` int(11) NOT NULL,
+  `currencyCode` varchar(10) NOT NULL,
+  `rate` decimal(30,12) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `fx_rate_snapshot_rates_currency` (`snapshotId`, `currencyCode`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
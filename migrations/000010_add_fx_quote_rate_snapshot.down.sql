@@ -0,0 +1,3 @@
+ALTER TABLE `fx_quotes`
+  DROP KEY `fx_quotes_rate_snapshot`,
+  DROP COLUMN `rateSnapshotId`;
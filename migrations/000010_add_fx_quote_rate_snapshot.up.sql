@@ -0,0 +1,7 @@
+--
+-- Record the rate snapshot each FX quote was priced from so it can be reproduced
+--
+
+ALTER TABLE `fx_quotes`
+  ADD `rateSnapshotId` int(11) DEFAULT NULL AFTER `spreadRevenue`,
+  ADD KEY `fx_quotes_rate_snapshot` (`rateSnapshotId`);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS `fx_rate_changes`;
+
+ALTER TABLE `fx_quotes`
+  MODIFY `spread` decimal(7,4) NOT NULL;
+
+ALTER TABLE `fx_manual_rates`
+  DROP COLUMN `margin`;
+
+DROP TABLE IF EXISTS `audit_logs`;
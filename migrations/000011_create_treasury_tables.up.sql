@@ -0,0 +1,52 @@
+--
+-- Table structure for table `audit_logs`
+-- Append-only record of staff actions
+--
+
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `actor` char(36) NOT NULL,
+  `action` varchar(50) NOT NULL,
+  `entity` varchar(50) NOT NULL,
+  `entityId` varchar(50) NOT NULL,
+  `details` text NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `audit_logs_entity` (`entity`, `entityId`),
+  KEY `audit_logs_actor` (`actor`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Margin charged on each manual rate pair, the default FX spread applies when NULL
+--
+
+ALTER TABLE `fx_manual_rates`
+  ADD `margin` decimal(10,8) DEFAULT NULL AFTER `rate`;
+
+ALTER TABLE `fx_quotes`
+  MODIFY `spread` decimal(10,8) NOT NULL;
+
+--
+-- Table structure for table `fx_rate_changes`
+-- Manual rate and margin changes, proposed by a maker and approved by a different checker.
+-- Approved changes are applied at effectiveAt, or straight away when it has passed.
+--
+
+CREATE TABLE IF NOT EXISTS `fx_rate_changes` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `baseCurrency` char(3) NOT NULL,
+  `quoteCurrency` char(3) NOT NULL,
+  `rate` decimal(20,10) NOT NULL,
+  `margin` decimal(10,8) NOT NULL,
+  `effectiveAt` datetime NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'pending',
+  `maker` char(36) NOT NULL,
+  `checker` char(36) DEFAULT NULL,
+  `reviewNote` text DEFAULT NULL,
+  `appliedAt` datetime DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `fx_rate_changes_status_effective` (`status`, `effectiveAt`),
+  KEY `fx_rate_changes_pair` (`baseCurrency`, `quoteCurrency`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,11 @@
+--
+-- Table structure for table `nuban_sequences`
+-- Last NUBAN serial allocated per bank code, incremented with LAST_INSERT_ID(expr) so allocation is atomic
+--
+
+CREATE TABLE IF NOT EXISTS `nuban_sequences` (
+  `bankCode` char(6) NOT NULL,
+  `lastSerial` bigint(20) NOT NULL,
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`bankCode`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
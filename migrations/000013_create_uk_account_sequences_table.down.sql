@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `uk_account_sequences`;
@@ -0,0 +1,11 @@
+--
+-- Table structure for table `uk_account_sequences`
+-- Last UK account number serial allocated per sort code, incremented with LAST_INSERT_ID(expr) so allocation is atomic
+--
+
+CREATE TABLE IF NOT EXISTS `uk_account_sequences` (
+  `sortCode` char(6) NOT NULL,
+  `lastSerial` bigint(20) NOT NULL,
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`sortCode`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `us_account_sequences`;
+
+ALTER TABLE `accounts_meta_usa`
+  DROP INDEX `accounts_meta_usa_account`,
+  DROP INDEX `accounts_meta_usa_number`,
+  MODIFY `us_bank_number` text NOT NULL,
+  MODIFY `sort_code` text NOT NULL,
+  MODIFY `accounts_id` text NOT NULL;
@@ -0,0 +1,23 @@
+--
+-- US account details issued to USD accounts, the routing number is kept in `sort_code`
+-- and the account's own number in `accounts_id`
+--
+
+ALTER TABLE `accounts_meta_usa`
+  MODIFY `us_bank_number` varchar(17) NOT NULL,
+  MODIFY `sort_code` char(9) NOT NULL,
+  MODIFY `accounts_id` char(36) NOT NULL,
+  ADD UNIQUE KEY `accounts_meta_usa_number` (`sort_code`, `us_bank_number`),
+  ADD UNIQUE KEY `accounts_meta_usa_account` (`accounts_id`);
+
+--
+-- Table structure for table `us_account_sequences`
+-- Last US account number serial allocated per routing number, incremented with LAST_INSERT_ID(expr) so allocation is atomic
+--
+
+CREATE TABLE IF NOT EXISTS `us_account_sequences` (
+  `routingNumber` char(9) NOT NULL,
+  `lastSerial` bigint(20) NOT NULL,
+  `updated_at` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`routingNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `account_number_reservations`;
+DROP TABLE IF EXISTS `account_numbers`;
@@ -0,0 +1,41 @@
+--
+-- Table structure for table `account_numbers`
+-- Registry of every account number issued, the number is the key so it's unique across schemes
+--
+
+CREATE TABLE IF NOT EXISTS `account_numbers` (
+  `number` varchar(34) NOT NULL,
+  `scheme` varchar(10) NOT NULL,
+  `purpose` varchar(100) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`number`),
+  KEY `account_numbers_scheme` (`scheme`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Numbers issued before the registry
+--
+
+INSERT IGNORE INTO `account_numbers` (`number`, `scheme`, `purpose`)
+  SELECT `accountNumber`, 'uk', 'account' FROM `accounts`;
+INSERT IGNORE INTO `account_numbers` (`number`, `scheme`, `purpose`)
+  SELECT `bankNumber`, 'nuban', 'account' FROM `accounts` WHERE `bankNumber` <> '';
+INSERT IGNORE INTO `account_numbers` (`number`, `scheme`, `purpose`)
+  SELECT `us_bank_number`, 'us', 'us account' FROM `accounts_meta_usa`;
+
+--
+-- Table structure for table `account_number_reservations`
+-- Ranges held back from general allocation for system and vanity accounts
+--
+
+CREATE TABLE IF NOT EXISTS `account_number_reservations` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `scheme` varchar(10) NOT NULL,
+  `rangeStart` varchar(34) NOT NULL,
+  `rangeEnd` varchar(34) NOT NULL,
+  `purpose` varchar(100) NOT NULL,
+  `creator` varchar(100) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `account_number_reservations_scheme` (`scheme`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
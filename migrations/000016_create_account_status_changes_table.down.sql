@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `account_status_changes`;
+
+ALTER TABLE `accounts`
+  MODIFY `status` text NOT NULL DEFAULT 'Active';
+
+UPDATE `accounts` SET `status` = 'Deactivated' WHERE `status` = 'Frozen';
@@ -0,0 +1,25 @@
+--
+-- Account lifecycle states, blocked accounts were 'Deactivated' and are now 'Frozen'
+--
+
+UPDATE `accounts` SET `status` = 'Frozen' WHERE `status` = 'Deactivated';
+
+ALTER TABLE `accounts`
+  MODIFY `status` varchar(20) NOT NULL DEFAULT 'Active';
+
+--
+-- Table structure for table `account_status_changes`
+-- Every lifecycle change with its reason and the user who made it
+--
+
+CREATE TABLE IF NOT EXISTS `account_status_changes` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `fromStatus` varchar(20) NOT NULL,
+  `toStatus` varchar(20) NOT NULL,
+  `reason` varchar(255) NOT NULL,
+  `actor` varchar(100) NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `account_status_changes_account` (`accountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,25 @@
+--
+-- Table structure for table `account_closures`
+-- One row per closed account, written as the closure runs so a failed closure can be resumed
+--
+
+CREATE TABLE IF NOT EXISTS `account_closures` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `accountHolderName` text NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `status` varchar(20) NOT NULL,
+  `reason` varchar(255) NOT NULL,
+  `actor` varchar(100) NOT NULL,
+  `closingBalance` decimal(20,4) NOT NULL DEFAULT 0,
+  `feeAmount` decimal(20,4) NOT NULL DEFAULT 0,
+  `feeCharged` tinyint(1) NOT NULL DEFAULT 0,
+  `sweptAmount` decimal(20,4) NOT NULL DEFAULT 0,
+  `sweptTo` char(36) NOT NULL DEFAULT '',
+  `cashPickupsRefunded` int(11) NOT NULL DEFAULT 0,
+  `requestsDeclined` int(11) NOT NULL DEFAULT 0,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  `closedAt` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `account_closures_account` (`accountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
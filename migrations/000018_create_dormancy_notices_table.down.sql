@@ -0,0 +1,5 @@
+ALTER TABLE `transactions`
+  DROP KEY `transactions_sender_account_number`,
+  DROP KEY `transactions_receiver_account_number`;
+
+DROP TABLE IF EXISTS `dormancy_notices`;
@@ -0,0 +1,23 @@
+--
+-- Table structure for table `dormancy_notices`
+-- The notices sent to holders before their account goes dormant, one per spell of inactivity
+--
+
+CREATE TABLE IF NOT EXISTS `dormancy_notices` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `lastActivity` datetime NOT NULL,
+  `dormantFrom` datetime NOT NULL,
+  `notifiedTo` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `dormancy_notices_account_activity` (`accountNumber`, `lastActivity`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Customer activity is looked up per account on every dormancy check
+--
+
+ALTER TABLE `transactions`
+  ADD KEY `transactions_sender_account_number` (`senderAccountNumber`, `timestamp`),
+  ADD KEY `transactions_receiver_account_number` (`receiverAccountNumber`, `timestamp`);
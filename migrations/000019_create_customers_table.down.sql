@@ -0,0 +1,14 @@
+ALTER TABLE `beneficiaries`
+  DROP KEY `beneficiaries_customer_number`,
+  DROP `customerNumber`;
+
+ALTER TABLE `kyc_documents`
+  DROP KEY `kyc_documents_customer_number`,
+  DROP `customerNumber`,
+  MODIFY `documentType` enum('passport','national ID') DEFAULT NULL;
+
+ALTER TABLE `accounts`
+  DROP KEY `accounts_customer_number`,
+  DROP `customerNumber`;
+
+DROP TABLE IF EXISTS `customers`;
@@ -0,0 +1,90 @@
+--
+-- Table structure for table `customers`
+-- The customer information file, one row per person however many accounts they hold
+--
+
+CREATE TABLE IF NOT EXISTS `customers` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `customerNumber` char(10) DEFAULT NULL,
+  `givenName` text NOT NULL,
+  `familyName` text NOT NULL,
+  `dateOfBirth` varchar(20) NOT NULL DEFAULT '',
+  `identificationNumber` varchar(50) DEFAULT NULL,
+  `identificationType` varchar(50) NOT NULL DEFAULT '',
+  `country` varchar(50) NOT NULL DEFAULT '',
+  `contactNumber1` varchar(50) NOT NULL DEFAULT '',
+  `contactNumber2` varchar(50) NOT NULL DEFAULT '',
+  `emailAddress` varchar(255) NOT NULL DEFAULT '',
+  `addressLine1` text NOT NULL,
+  `addressLine2` text NOT NULL,
+  `addressLine3` text NOT NULL,
+  `postalCode` varchar(20) NOT NULL DEFAULT '',
+  `image` longtext NOT NULL,
+  `migratedFrom` char(36) DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `customers_customer_number` (`customerNumber`),
+  UNIQUE KEY `customers_identification_number` (`identificationNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- One customer for each identification number in accounts_meta, taken from its first row.
+-- Rows without an identification number each become their own customer.
+--
+
+INSERT INTO `customers` (`givenName`, `familyName`, `dateOfBirth`, `identificationNumber`, `identificationType`, `country`, `contactNumber1`, `contactNumber2`, `emailAddress`, `addressLine1`, `addressLine2`, `addressLine3`, `postalCode`, `image`, `migratedFrom`, `timestamp`)
+SELECT m.`accountHolderGivenName`, m.`accountHolderFamilyName`, m.`accountHolderDateOfBirth`, NULLIF(TRIM(m.`accountHolderIdentificationNumber`), ''), m.`accountHolderIdentificationType`, m.`country`,
+  m.`accountHolderContactNumber1`, IFNULL(m.`accountHolderContactNumber2`, ''), m.`accountHolderEmailAddress`, m.`accountHolderAddressLine1`, IFNULL(m.`accountHolderAddressLine2`, ''), IFNULL(m.`accountHolderAddressLine3`, ''),
+  m.`accountHolderPostalCode`, m.`image`, m.`accountNumber`, m.`timestamp`
+FROM `accounts_meta` m
+INNER JOIN (
+  SELECT MIN(`id`) AS `id` FROM `accounts_meta`
+  GROUP BY IF(TRIM(`accountHolderIdentificationNumber`) = '', `accountNumber`, TRIM(`accountHolderIdentificationNumber`))
+) first ON first.`id` = m.`id`
+ORDER BY m.`id`;
+
+UPDATE `customers` SET `customerNumber` = CONCAT('C', LPAD(`id`, 9, '0'));
+
+--
+-- Accounts belong to a customer
+--
+
+ALTER TABLE `accounts`
+  ADD `customerNumber` char(10) DEFAULT NULL AFTER `bankNumber`,
+  ADD KEY `accounts_customer_number` (`customerNumber`);
+
+UPDATE `accounts` a
+INNER JOIN `accounts_meta` m ON m.`accountNumber` = a.`accountNumber`
+INNER JOIN `customers` c ON c.`identificationNumber` = NULLIF(TRIM(m.`accountHolderIdentificationNumber`), '')
+SET a.`customerNumber` = c.`customerNumber`;
+
+UPDATE `accounts` a
+INNER JOIN `customers` c ON c.`migratedFrom` = a.`accountNumber`
+SET a.`customerNumber` = c.`customerNumber`
+WHERE a.`customerNumber` IS NULL;
+
+UPDATE `accounts` a
+INNER JOIN `accounts` p ON p.`accountNumber` = a.`primaryAccountNumber`
+SET a.`customerNumber` = p.`customerNumber`
+WHERE a.`customerNumber` IS NULL;
+
+ALTER TABLE `customers`
+  DROP `migratedFrom`;
+
+--
+-- KYC documents and beneficiaries belong to the customer rather than an account
+--
+
+ALTER TABLE `kyc_documents`
+  ADD `customerNumber` char(10) DEFAULT NULL AFTER `userId`,
+  MODIFY `documentType` varchar(50) DEFAULT NULL,
+  ADD KEY `kyc_documents_customer_number` (`customerNumber`);
+
+ALTER TABLE `beneficiaries`
+  ADD `customerNumber` char(10) DEFAULT NULL AFTER `userId`,
+  ADD KEY `beneficiaries_customer_number` (`customerNumber`);
+
+-- Beneficiaries were saved against the id of the holder's account
+UPDATE `beneficiaries` b
+INNER JOIN `accounts` a ON a.`id` = b.`userId`
+SET b.`customerNumber` = a.`customerNumber`;
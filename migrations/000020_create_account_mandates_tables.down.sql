@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `mandate_payment_approvals`;
+DROP TABLE IF EXISTS `mandate_payments`;
+DROP TABLE IF EXISTS `account_mandates`;
+DROP TABLE IF EXISTS `account_holders`;
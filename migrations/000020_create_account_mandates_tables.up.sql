@@ -0,0 +1,70 @@
+--
+-- Table structure for table `account_holders`
+-- Customers linked to an account besides its owner, who is always the primary holder
+--
+
+CREATE TABLE IF NOT EXISTS `account_holders` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `customerNumber` char(10) NOT NULL,
+  `role` enum('secondary','signatory') NOT NULL,
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `account_holders_account_customer` (`accountNumber`, `customerNumber`),
+  KEY `account_holders_customer_number` (`customerNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `account_mandates`
+-- The signing rule for an account, accounts without a row need any one holder
+--
+
+CREATE TABLE IF NOT EXISTS `account_mandates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `rule` enum('any_one','any_two','all') NOT NULL DEFAULT 'any_one',
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `account_mandates_account_number` (`accountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `mandate_payments`
+-- Payments held until enough of the sending account's holders have approved them
+--
+
+CREATE TABLE IF NOT EXISTS `mandate_payments` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `senderAccountNumber` char(36) NOT NULL,
+  `senderBankNumber` char(36) NOT NULL DEFAULT '',
+  `receiverAccountNumber` char(36) NOT NULL,
+  `receiverBankNumber` char(36) NOT NULL DEFAULT '',
+  `amount` decimal(20,2) NOT NULL,
+  `narration` text NOT NULL,
+  `rule` enum('any_one','any_two','all') NOT NULL,
+  `requiredApprovals` int(11) NOT NULL,
+  `initiator` char(10) NOT NULL,
+  `status` enum('pending','executed','rejected','failed','expired') NOT NULL DEFAULT 'pending',
+  `result` text NOT NULL,
+  `expiresAt` datetime NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `mandate_payments_sender_status` (`senderAccountNumber`, `status`),
+  KEY `mandate_payments_status_expires` (`status`, `expiresAt`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `mandate_payment_approvals`
+-- One row per holder who has signed a held payment
+--
+
+CREATE TABLE IF NOT EXISTS `mandate_payment_approvals` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `paymentId` int(11) NOT NULL,
+  `customerNumber` char(10) NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `mandate_payment_approvals_payment_customer` (`paymentId`, `customerNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,5 @@
+ALTER TABLE `transactions`
+  DROP KEY `transactions_pot_id`,
+  DROP `potId`;
+
+DROP TABLE IF EXISTS `account_pots`;
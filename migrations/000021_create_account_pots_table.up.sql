@@ -0,0 +1,28 @@
+--
+-- Table structure for table `account_pots`
+-- Savings pots, named sub-balances ring-fenced inside an account
+--
+
+CREATE TABLE IF NOT EXISTS `account_pots` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `name` varchar(50) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `balance` decimal(20,2) NOT NULL DEFAULT 0,
+  `targetAmount` decimal(20,2) NOT NULL DEFAULT 0,
+  `targetDate` date DEFAULT NULL,
+  `roundUpTo` decimal(20,2) NOT NULL DEFAULT 0,
+  `status` enum('open','closed') NOT NULL DEFAULT 'open',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `account_pots_account_status` (`accountNumber`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Moves between an account's main balance and its pots are posted to the ledger
+-- against the pot they touch
+--
+
+ALTER TABLE `transactions`
+  ADD `potId` int(11) DEFAULT NULL,
+  ADD KEY `transactions_pot_id` (`potId`);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `term_deposit_accruals`;
+DROP TABLE IF EXISTS `term_deposits`;
+DROP TABLE IF EXISTS `deposit_product_rates`;
+DROP TABLE IF EXISTS `deposit_products`;
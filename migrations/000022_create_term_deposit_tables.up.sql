@@ -0,0 +1,86 @@
+--
+-- Table structure for table `deposit_products`
+-- The term deposits the bank offers
+--
+
+CREATE TABLE IF NOT EXISTS `deposit_products` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `code` varchar(20) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `termDays` int(11) NOT NULL,
+  `minAmount` decimal(20,2) NOT NULL DEFAULT 0,
+  `maxAmount` decimal(20,2) NOT NULL DEFAULT 0,
+  `breakPenaltyRate` decimal(5,2) NOT NULL DEFAULT 0,
+  `status` enum('active','withdrawn') NOT NULL DEFAULT 'active',
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `deposit_products_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `deposit_product_rates`
+-- A product's annual rate from a date, the latest one in effect applies to new deposits
+--
+
+CREATE TABLE IF NOT EXISTS `deposit_product_rates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `productId` int(11) NOT NULL,
+  `rate` decimal(7,4) NOT NULL,
+  `effectiveFrom` date NOT NULL,
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `deposit_product_rates_product_effective` (`productId`, `effectiveFrom`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `term_deposits`
+-- Amounts locked from a current account for a product's term at the rate of the day
+--
+
+CREATE TABLE IF NOT EXISTS `term_deposits` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `depositNumber` char(10) NOT NULL DEFAULT '',
+  `accountNumber` char(36) NOT NULL,
+  `productId` int(11) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `principal` decimal(20,2) NOT NULL,
+  `rate` decimal(7,4) NOT NULL,
+  `termDays` int(11) NOT NULL,
+  `startDate` date NOT NULL,
+  `maturityDate` date NOT NULL,
+  `accruedInterest` decimal(20,6) NOT NULL DEFAULT 0,
+  `accruedThrough` date NOT NULL,
+  `rolloverOption` enum('none','principal','principal_and_interest') NOT NULL DEFAULT 'none',
+  `status` enum('active','matured','rolled_over','broken') NOT NULL DEFAULT 'active',
+  `interestSettled` tinyint(1) NOT NULL DEFAULT 0,
+  `interestPaid` decimal(20,2) NOT NULL DEFAULT 0,
+  `penalty` decimal(20,2) NOT NULL DEFAULT 0,
+  `rolledOverFrom` char(10) NOT NULL DEFAULT '',
+  `rolledOverTo` char(10) NOT NULL DEFAULT '',
+  `actor` varchar(255) NOT NULL DEFAULT '',
+  `reason` text NOT NULL,
+  `closedAt` date DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `term_deposits_deposit_number` (`depositNumber`),
+  KEY `term_deposits_account_number` (`accountNumber`),
+  KEY `term_deposits_status_maturity` (`status`, `maturityDate`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `term_deposit_accruals`
+-- One row per deposit per day of interest accrued
+--
+
+CREATE TABLE IF NOT EXISTS `term_deposit_accruals` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `depositId` int(11) NOT NULL,
+  `accrualDate` date NOT NULL,
+  `amount` decimal(20,6) NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `term_deposit_accruals_deposit_date` (`depositId`, `accrualDate`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `interest_capitalisations`;
+DROP TABLE IF EXISTS `interest_accruals`;
+DROP TABLE IF EXISTS `interest_accounts`;
+DROP TABLE IF EXISTS `interest_product_tiers`;
+DROP TABLE IF EXISTS `interest_products`;
@@ -0,0 +1,91 @@
+--
+-- Table structure for table `interest_products`
+-- Savings interest products, the withholding tax rate is a percentage of gross interest
+--
+
+CREATE TABLE IF NOT EXISTS `interest_products` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `code` varchar(20) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `withholdingTaxRate` decimal(5,2) NOT NULL DEFAULT 0,
+  `status` enum('active','withdrawn') NOT NULL DEFAULT 'active',
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `interest_products_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `interest_product_tiers`
+-- A product's rate table from a date, one row per balance tier
+--
+
+CREATE TABLE IF NOT EXISTS `interest_product_tiers` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `productId` int(11) NOT NULL,
+  `minBalance` decimal(20,2) NOT NULL DEFAULT 0,
+  `rate` decimal(7,4) NOT NULL,
+  `effectiveFrom` date NOT NULL,
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `interest_product_tiers_product_effective_balance` (`productId`, `effectiveFrom`, `minBalance`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `interest_accounts`
+-- The accounts earning interest and the product they earn it under
+--
+
+CREATE TABLE IF NOT EXISTS `interest_accounts` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `productId` int(11) NOT NULL,
+  `creator` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `interest_accounts_account_number` (`accountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `interest_accruals`
+-- One row per account per day of interest accrued, linked to its capitalisation once paid
+--
+
+CREATE TABLE IF NOT EXISTS `interest_accruals` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `productId` int(11) NOT NULL,
+  `accrualDate` date NOT NULL,
+  `balance` decimal(20,4) NOT NULL,
+  `rate` decimal(7,4) NOT NULL,
+  `amount` decimal(20,6) NOT NULL,
+  `capitalisationId` int(11) DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `interest_accruals_account_date` (`accountNumber`, `accrualDate`),
+  KEY `interest_accruals_capitalisation_date` (`capitalisationId`, `accrualDate`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `interest_capitalisations`
+-- A month's accruals paid to an account, net of withholding tax
+--
+
+CREATE TABLE IF NOT EXISTS `interest_capitalisations` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `period` char(7) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `grossInterest` decimal(20,2) NOT NULL DEFAULT 0,
+  `withholdingTax` decimal(20,2) NOT NULL DEFAULT 0,
+  `netInterest` decimal(20,2) NOT NULL DEFAULT 0,
+  `interestPosted` tinyint(1) NOT NULL DEFAULT 0,
+  `taxPosted` tinyint(1) NOT NULL DEFAULT 0,
+  `status` enum('pending','posted') NOT NULL DEFAULT 'pending',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `interest_capitalisations_account_period` (`accountNumber`, `period`),
+  KEY `interest_capitalisations_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `overdraft_charges`;
+DROP TABLE IF EXISTS `overdraft_facilities`;
@@ -0,0 +1,48 @@
+--
+-- Table structure for table `overdraft_facilities`
+-- Overdrafts applied for on accounts. The approved limit of the active facility is copied to accounts.overdraft.
+--
+
+CREATE TABLE IF NOT EXISTS `overdraft_facilities` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `requestedLimit` decimal(20,4) NOT NULL DEFAULT 0,
+  `approvedLimit` decimal(20,4) NOT NULL DEFAULT 0,
+  `rate` decimal(7,4) NOT NULL DEFAULT 0,
+  `arrangementFee` decimal(20,4) NOT NULL DEFAULT 0,
+  `expiryDate` date DEFAULT NULL,
+  `status` enum('applied','active','declined','expired','cancelled') NOT NULL DEFAULT 'applied',
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `applicant` varchar(255) NOT NULL DEFAULT '',
+  `approver` varchar(255) NOT NULL DEFAULT '',
+  `closedBy` varchar(255) NOT NULL DEFAULT '',
+  `approvedAt` date DEFAULT NULL,
+  `closedAt` date DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `overdraft_facilities_account_number` (`accountNumber`),
+  KEY `overdraft_facilities_status_expiry` (`status`, `expiryDate`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `overdraft_charges`
+-- Arrangement fees and daily debit interest, recorded pending before they're posted
+--
+
+CREATE TABLE IF NOT EXISTS `overdraft_charges` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `facilityId` int(11) NOT NULL,
+  `accountNumber` char(36) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `chargeType` enum('interest','arrangement_fee') NOT NULL,
+  `chargeDate` date NOT NULL,
+  `balance` decimal(20,4) NOT NULL DEFAULT 0,
+  `rate` decimal(7,4) NOT NULL DEFAULT 0,
+  `amount` decimal(20,4) NOT NULL,
+  `status` enum('pending','posted') NOT NULL DEFAULT 'pending',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `overdraft_charges_facility_type_date` (`facilityId`, `chargeType`, `chargeDate`),
+  KEY `overdraft_charges_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
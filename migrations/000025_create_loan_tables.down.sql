@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `loan_repayments`;
+DROP TABLE IF EXISTS `loan_instalments`;
+DROP TABLE IF EXISTS `loans`;
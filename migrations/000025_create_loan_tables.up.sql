@@ -0,0 +1,80 @@
+--
+-- Table structure for table `loans`
+-- Loans applied for on accounts, with the terms they were approved on
+--
+
+CREATE TABLE IF NOT EXISTS `loans` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `loanNumber` varchar(20) NOT NULL DEFAULT '',
+  `accountNumber` char(36) NOT NULL,
+  `currencyCode` char(3) NOT NULL,
+  `requestedAmount` decimal(20,4) NOT NULL,
+  `requestedTenorMonths` int(11) NOT NULL,
+  `purpose` varchar(255) NOT NULL DEFAULT '',
+  `amount` decimal(20,4) NOT NULL DEFAULT 0,
+  `rate` decimal(7,4) NOT NULL DEFAULT 0,
+  `tenorMonths` int(11) NOT NULL DEFAULT 0,
+  `method` enum('reducing_balance','flat') NOT NULL DEFAULT 'reducing_balance',
+  `lateFee` decimal(20,4) NOT NULL DEFAULT 0,
+  `status` enum('applied','approved','declined','cancelled','active','repaid') NOT NULL DEFAULT 'applied',
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `applicant` varchar(255) NOT NULL DEFAULT '',
+  `approver` varchar(255) NOT NULL DEFAULT '',
+  `closedBy` varchar(255) NOT NULL DEFAULT '',
+  `approvedAt` date DEFAULT NULL,
+  `disbursedAt` date DEFAULT NULL,
+  `closedAt` date DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `loans_loan_number` (`loanNumber`),
+  KEY `loans_account_number` (`accountNumber`),
+  KEY `loans_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `loan_instalments`
+-- A loan's amortisation schedule, fixed when it's disbursed, and what has been paid of each instalment
+--
+
+CREATE TABLE IF NOT EXISTS `loan_instalments` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `loanId` int(11) NOT NULL,
+  `instalmentNumber` int(11) NOT NULL,
+  `dueDate` date NOT NULL,
+  `principalDue` decimal(20,4) NOT NULL,
+  `interestDue` decimal(20,4) NOT NULL,
+  `feeDue` decimal(20,4) NOT NULL DEFAULT 0,
+  `principalPaid` decimal(20,4) NOT NULL DEFAULT 0,
+  `interestPaid` decimal(20,4) NOT NULL DEFAULT 0,
+  `feePaid` decimal(20,4) NOT NULL DEFAULT 0,
+  `status` enum('pending','paid') NOT NULL DEFAULT 'pending',
+  `paidAt` date DEFAULT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `loan_instalments_loan_number` (`loanId`, `instalmentNumber`),
+  KEY `loan_instalments_status_due` (`status`, `dueDate`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Table structure for table `loan_repayments`
+-- Amounts taken towards loans, split into principal, interest and fees, with a flag for each posting made
+--
+
+CREATE TABLE IF NOT EXISTS `loan_repayments` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `loanId` int(11) NOT NULL,
+  `amount` decimal(20,4) NOT NULL,
+  `principal` decimal(20,4) NOT NULL DEFAULT 0,
+  `interest` decimal(20,4) NOT NULL DEFAULT 0,
+  `fee` decimal(20,4) NOT NULL DEFAULT 0,
+  `repaymentDate` date NOT NULL,
+  `source` enum('auto','manual') NOT NULL DEFAULT 'auto',
+  `collected` tinyint(1) NOT NULL DEFAULT 0,
+  `interestPosted` tinyint(1) NOT NULL DEFAULT 0,
+  `feePosted` tinyint(1) NOT NULL DEFAULT 0,
+  `status` enum('pending','posted') NOT NULL DEFAULT 'pending',
+  `actor` varchar(255) NOT NULL DEFAULT '',
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `loan_repayments_loan_status` (`loanId`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
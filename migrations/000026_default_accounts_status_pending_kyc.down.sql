@@ -0,0 +1,2 @@
+ALTER TABLE `accounts`
+  MODIFY `status` varchar(20) NOT NULL DEFAULT 'Active';
@@ -0,0 +1,6 @@
+--
+-- Accounts open waiting on KYC and become Active when the customer's KYC is approved
+--
+
+ALTER TABLE `accounts`
+  MODIFY `status` varchar(20) NOT NULL DEFAULT 'PendingKYC';
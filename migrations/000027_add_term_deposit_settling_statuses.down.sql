@@ -0,0 +1,2 @@
+ALTER TABLE `term_deposits`
+  MODIFY `status` enum('active','matured','rolled_over','broken') NOT NULL DEFAULT 'active';
@@ -0,0 +1,6 @@
+--
+-- Deposits are claimed as maturing or breaking before anything is posted for them
+--
+
+ALTER TABLE `term_deposits`
+  MODIFY `status` enum('active','maturing','breaking','matured','rolled_over','broken') NOT NULL DEFAULT 'active';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `interest_accrual_runs`;
@@ -0,0 +1,13 @@
+--
+-- Table structure for table `interest_accrual_runs`
+-- One row per day interest was accrued, and one per day the run was missed
+--
+
+CREATE TABLE IF NOT EXISTS `interest_accrual_runs` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `runDate` date NOT NULL,
+  `missed` tinyint(1) NOT NULL DEFAULT 0,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `interest_accrual_runs_run_date` (`runDate`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+ALTER TABLE `mandate_payments`
+  DROP `painType`;
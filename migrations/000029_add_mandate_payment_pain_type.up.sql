@@ -0,0 +1,6 @@
+--
+-- Held payments keep their PAIN type so an approved debit is made as a debit
+--
+
+ALTER TABLE `mandate_payments`
+  ADD `painType` int(11) NOT NULL DEFAULT 1 AFTER `id`;
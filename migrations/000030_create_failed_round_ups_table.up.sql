@@ -0,0 +1,16 @@
+--
+-- Table structure for table `failed_round_ups`
+-- Debits that went through but whose round-up into the account's pot failed, to be reconciled
+--
+
+CREATE TABLE IF NOT EXISTS `failed_round_ups` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `accountNumber` char(36) NOT NULL,
+  `painType` int(11) NOT NULL,
+  `amount` decimal(20,2) NOT NULL,
+  `narration` text NOT NULL,
+  `reason` text NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `failed_round_ups_account` (`accountNumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
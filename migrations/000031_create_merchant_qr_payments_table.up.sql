@@ -0,0 +1,16 @@
+--
+-- Table structure for table `merchant_qr_payments`
+-- Payments made through a merchant's QR code, recorded against the code so reconciliation
+-- doesn't rely on the transfer's narration
+--
+
+CREATE TABLE IF NOT EXISTS `merchant_qr_payments` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `reference` varchar(32) NOT NULL,
+  `merchantAccountNumber` char(36) NOT NULL,
+  `payerAccountNumber` char(36) NOT NULL,
+  `amount` decimal(20,2) NOT NULL,
+  `timestamp` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `merchant_qr_payments_merchant_timestamp` (`merchantAccountNumber`, `timestamp`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;